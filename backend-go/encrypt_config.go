@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+// runEncryptConfig 执行 encrypt-config 子命令：加密现有配置文件及历史备份中的 API Key
+// 用法: claude-proxy encrypt-config [-config .config/config.json]（主密钥来自 CONFIG_ENCRYPTION_KEY 或 CONFIG_ENCRYPTION_KEY_FILE）
+func runEncryptConfig(args []string) int {
+	fs := flag.NewFlagSet("encrypt-config", flag.ExitOnError)
+	configFile := fs.String("config", ".config/config.json", "配置文件路径")
+	_ = fs.Parse(args)
+
+	masterKey, err := config.LoadMasterKeyFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取主密钥失败: %v\n", err)
+		return 1
+	}
+
+	backups, err := config.EncryptConfigFile(*configFile, masterKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加密配置失败: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("配置文件 %s 中的 API Key 已加密，另加密了 %d 个历史备份\n", *configFile, backups)
+	fmt.Println("请妥善保管主密钥：丢失后将无法解密配置中的 API Key")
+	return 0
+}
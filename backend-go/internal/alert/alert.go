@@ -0,0 +1,251 @@
+// Package alert 将渠道与 Key 的状态变化推送到 Webhook
+//
+// 熔断、渠道降级、全部渠道失败等事件原本只有一行日志。Manager 接收这些事件，按配置
+// （alerts）做去重与限流后异步投递到通用 JSON / Slack / 飞书 / 钉钉 Webhook。
+package alert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/httpclient"
+)
+
+// 告警级别
+const (
+	LevelInfo     = "info"
+	LevelWarning  = "warning"
+	LevelCritical = "critical"
+)
+
+const (
+	queueSize       = 256              // 待发送事件队列长度，队列满时丢弃新事件
+	deliveryTimeout = 10 * time.Second // 单次 Webhook 投递超时
+	rateWindow      = time.Minute      // 限流窗口
+)
+
+// Event 告警事件
+type Event struct {
+	Type    string                 `json:"type"`
+	Level   string                 `json:"level"`
+	Kind    string                 `json:"kind,omitempty"` // 入口类型：messages/responses/gemini/chat
+	Channel string                 `json:"channel,omitempty"`
+	BaseURL string                 `json:"baseUrl,omitempty"`
+	KeyMask string                 `json:"keyMask,omitempty"`
+	Message string                 `json:"message"`
+	Time    time.Time              `json:"time"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// dedupKey 相同类型、相同对象的事件在去重窗口内只发送一次
+func (e *Event) dedupKey() string {
+	return strings.Join([]string{e.Type, e.Kind, e.Channel, e.BaseURL, e.KeyMask}, "|")
+}
+
+// Manager 告警事件的去重、限流与异步投递
+type Manager struct {
+	cfgManager *config.ConfigManager
+	client     *http.Client
+	queue      chan Event
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time   // dedupKey -> 最近一次入队时间
+	sentLog  map[string][]time.Time // 目标名称 -> 限流窗口内的发送时间
+	now      func() time.Time       // 便于测试替换
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewManager 创建告警管理器（需调用 Start 启动投递循环）
+func NewManager(cfgManager *config.ConfigManager) *Manager {
+	return &Manager{
+		cfgManager: cfgManager,
+		client:     httpclient.GetManager().GetStandardClient(deliveryTimeout, false),
+		queue:      make(chan Event, queueSize),
+		lastSent:   make(map[string]time.Time),
+		sentLog:    make(map[string][]time.Time),
+		now:        time.Now,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Start 启动后台投递循环
+func (m *Manager) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for {
+			select {
+			case e := <-m.queue:
+				m.dispatch(e)
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止投递循环（队列中未发送的事件将被丢弃）
+func (m *Manager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+	})
+	m.wg.Wait()
+}
+
+// Emit 提交告警事件：告警未启用、无目标或处于去重窗口内时直接忽略，不会阻塞调用方
+func (m *Manager) Emit(e Event) {
+	alertCfg := m.cfgManager.GetAlertConfig()
+	if !alertCfg.Enabled || len(alertCfg.Targets) == 0 {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = m.now()
+	}
+
+	if !m.passDedup(e, time.Duration(alertCfg.DedupWindow())*time.Second) {
+		return
+	}
+
+	select {
+	case m.queue <- e:
+	default:
+		log.Printf("[Alert] 告警队列已满，丢弃事件: %s %s", e.Type, e.Message)
+	}
+}
+
+// SendTest 立即向指定目标发送一条测试事件（不经过去重与限流），返回投递错误
+func (m *Manager) SendTest(ctx context.Context, target config.AlertTarget) error {
+	return m.deliver(ctx, target, Event{
+		Type:    "test",
+		Level:   LevelInfo,
+		Message: "这是一条测试告警，收到即表示 Webhook 配置正确",
+		Time:    m.now(),
+	})
+}
+
+// passDedup 检查去重窗口，通过时记录本次时间
+func (m *Manager) passDedup(e Event, window time.Duration) bool {
+	if window <= 0 {
+		return true
+	}
+	key := e.dedupKey()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if last, ok := m.lastSent[key]; ok && now.Sub(last) < window {
+		return false
+	}
+	m.lastSent[key] = now
+
+	// 顺带清理过期记录，避免 map 无限增长
+	for k, t := range m.lastSent {
+		if now.Sub(t) >= window {
+			delete(m.lastSent, k)
+		}
+	}
+	return true
+}
+
+// allowTarget 检查目标的每分钟发送限额，通过时记录本次发送
+func (m *Manager) allowTarget(name string, limit int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	sent := m.sentLog[name]
+	kept := sent[:0]
+	for _, t := range sent {
+		if now.Sub(t) < rateWindow {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) >= limit {
+		m.sentLog[name] = kept
+		return false
+	}
+	m.sentLog[name] = append(kept, now)
+	return true
+}
+
+// dispatch 将事件投递到所有订阅了该事件的目标（使用投递时的最新配置）
+func (m *Manager) dispatch(e Event) {
+	alertCfg := m.cfgManager.GetAlertConfig()
+	if !alertCfg.Enabled {
+		return
+	}
+	for _, target := range alertCfg.Targets {
+		if target.Disabled || !target.Subscribes(e.Type) {
+			continue
+		}
+		if !m.allowTarget(target.Name, alertCfg.RateLimit()) {
+			log.Printf("[Alert] 目标 %s 超过发送频率限制，丢弃事件: %s", target.Name, e.Type)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		if err := m.deliver(ctx, target, e); err != nil {
+			log.Printf("[Alert] 推送到 %s 失败: %v", target.Name, err)
+		}
+		cancel()
+	}
+}
+
+// deliver 按目标类型格式化事件并发送
+func (m *Manager) deliver(ctx context.Context, target config.AlertTarget, e Event) error {
+	targetURL, body, err := buildPayload(target, e, m.now())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// ============== 全局实例 ==============
+
+var (
+	defaultMu      sync.RWMutex
+	defaultManager *Manager
+)
+
+// SetDefault 设置全局告警管理器（供未持有 Manager 引用的调用点使用）
+func SetDefault(m *Manager) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultManager = m
+}
+
+// Emit 通过全局告警管理器提交事件；未设置全局实例时忽略
+func Emit(e Event) {
+	defaultMu.RLock()
+	m := defaultManager
+	defaultMu.RUnlock()
+	if m != nil {
+		m.Emit(e)
+	}
+}
@@ -0,0 +1,194 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+)
+
+// webhookRecorder 本地 Webhook 替身，记录收到的请求
+type webhookRecorder struct {
+	mu       sync.Mutex
+	requests []recordedRequest
+	received chan struct{}
+}
+
+type recordedRequest struct {
+	Path  string
+	Query string
+	Body  map[string]interface{}
+}
+
+func newWebhookServer(t *testing.T) (*httptest.Server, *webhookRecorder) {
+	t.Helper()
+	rec := &webhookRecorder{received: make(chan struct{}, 64)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		json.Unmarshal(data, &body)
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, recordedRequest{Path: r.URL.Path, Query: r.URL.RawQuery, Body: body})
+		rec.mu.Unlock()
+		rec.received <- struct{}{}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, rec
+}
+
+func (r *webhookRecorder) wait(t *testing.T, n int) []recordedRequest {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.received:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for webhook %d/%d", i+1, n)
+		}
+	}
+	// 确认没有多余的请求
+	select {
+	case <-r.received:
+		t.Fatalf("received more than %d webhooks", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]recordedRequest(nil), r.requests...)
+}
+
+func newTestManager(t *testing.T, alerts *config.AlertConfig) *Manager {
+	t.Helper()
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	data, _ := json.Marshal(config.Config{Alerts: alerts})
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	cfgManager, err := config.NewConfigManager(configFile)
+	if err != nil {
+		t.Fatalf("创建配置管理器失败: %v", err)
+	}
+	t.Cleanup(func() { cfgManager.Close() })
+
+	m := NewManager(cfgManager)
+	m.Start()
+	t.Cleanup(m.Stop)
+	return m
+}
+
+func TestManager_DedupAndSubscriptions(t *testing.T) {
+	srv, rec := newWebhookServer(t)
+	m := newTestManager(t, &config.AlertConfig{
+		Enabled: true,
+		Targets: []config.AlertTarget{
+			{Name: "all", Type: config.AlertTargetGeneric, URL: srv.URL + "/all"},
+			{Name: "critical", Type: config.AlertTargetGeneric, URL: srv.URL + "/critical", Events: []string{config.AlertEventAllChannelsFailed}},
+			{Name: "off", Type: config.AlertTargetGeneric, URL: srv.URL + "/off", Disabled: true},
+		},
+	})
+
+	circuit := Event{Type: config.AlertEventKeyCircuitOpen, Level: LevelWarning, Kind: "messages", BaseURL: "https://a", KeyMask: "sk-***1"}
+	m.Emit(circuit)
+	m.Emit(circuit) // 去重窗口内重复事件被忽略
+	other := circuit
+	other.KeyMask = "sk-***2"
+	m.Emit(other)
+	m.Emit(Event{Type: config.AlertEventAllChannelsFailed, Level: LevelCritical, Kind: "messages"})
+
+	reqs := rec.wait(t, 4)
+	paths := map[string]int{}
+	for _, r := range reqs {
+		paths[r.Path]++
+	}
+	if paths["/all"] != 3 || paths["/critical"] != 1 || paths["/off"] != 0 {
+		t.Fatalf("unexpected deliveries: %v", paths)
+	}
+	if reqs[0].Body["type"] != config.AlertEventKeyCircuitOpen || reqs[0].Body["keyMask"] != "sk-***1" {
+		t.Fatalf("generic payload should be the event itself: %v", reqs[0].Body)
+	}
+}
+
+func TestManager_RateLimitPerTarget(t *testing.T) {
+	srv, rec := newWebhookServer(t)
+	m := newTestManager(t, &config.AlertConfig{
+		Enabled:            true,
+		DedupSeconds:       -1,
+		RateLimitPerMinute: 2,
+		Targets:            []config.AlertTarget{{Name: "slack", Type: config.AlertTargetSlack, URL: srv.URL}},
+	})
+
+	for i := 0; i < 5; i++ {
+		m.Emit(Event{Type: config.AlertEventQuotaExhausted, Level: LevelWarning, Message: "quota"})
+	}
+	reqs := rec.wait(t, 2)
+	if text, _ := reqs[0].Body["text"].(string); !strings.Contains(text, "quota_exhausted") {
+		t.Fatalf("unexpected slack payload: %v", reqs[0].Body)
+	}
+}
+
+func TestManager_CircuitHookAndDisabled(t *testing.T) {
+	srv, rec := newWebhookServer(t)
+	m := newTestManager(t, &config.AlertConfig{
+		Enabled: true,
+		Targets: []config.AlertTarget{{Name: "generic", Type: config.AlertTargetGeneric, URL: srv.URL}},
+	})
+
+	m.CircuitHook("messages")(metrics.CircuitEvent{BaseURL: "https://a", KeyMask: "sk-***1", Reason: metrics.CircuitReasonTimeout})
+	reqs := rec.wait(t, 1)
+	if reqs[0].Body["type"] != config.AlertEventKeyRecovered || reqs[0].Body["kind"] != "messages" {
+		t.Fatalf("unexpected recovered payload: %v", reqs[0].Body)
+	}
+
+	if err := m.cfgManager.SetAlertConfig(&config.AlertConfig{Targets: m.cfgManager.GetAlertConfig().Targets}); err != nil {
+		t.Fatalf("SetAlertConfig: %v", err)
+	}
+	m.Emit(Event{Type: config.AlertEventAllChannelsFailed})
+	rec.wait(t, 0)
+}
+
+func TestBuildPayload_Formats(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	e := Event{Type: config.AlertEventKeyCircuitOpen, Level: LevelWarning, Channel: "ch-a", Message: "broken", Time: now}
+
+	_, body, _ := buildPayload(config.AlertTarget{Type: config.AlertTargetFeishu, URL: "https://open.feishu.cn/hook", Secret: "s"}, e, now)
+	var feishu map[string]interface{}
+	json.Unmarshal(body, &feishu)
+	if feishu["msg_type"] != "text" || feishu["timestamp"] != "1700000000" || feishu["sign"] != feishuSign("1700000000", "s") {
+		t.Fatalf("unexpected feishu payload: %s", body)
+	}
+	if text := feishu["content"].(map[string]interface{})["text"].(string); !strings.Contains(text, "渠道: ch-a") {
+		t.Fatalf("feishu text should include channel: %q", text)
+	}
+
+	targetURL, body, _ := buildPayload(config.AlertTarget{Type: config.AlertTargetDingTalk, URL: "https://oapi.dingtalk.com/robot/send?access_token=x", Secret: "s"}, e, now)
+	if !strings.HasPrefix(targetURL, "https://oapi.dingtalk.com/robot/send?access_token=x&timestamp=1700000000000&sign=") {
+		t.Fatalf("unexpected dingtalk url: %s", targetURL)
+	}
+	if !strings.Contains(string(body), `"msgtype":"text"`) {
+		t.Fatalf("unexpected dingtalk payload: %s", body)
+	}
+}
+
+func TestSendTest_ReportsHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("bad token"))
+	}))
+	defer srv.Close()
+
+	m := newTestManager(t, nil)
+	err := m.SendTest(context.Background(), config.AlertTarget{Name: "x", Type: config.AlertTargetGeneric, URL: srv.URL})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected HTTP 403 error, got %v", err)
+	}
+}
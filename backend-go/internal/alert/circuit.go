@@ -0,0 +1,53 @@
+package alert
+
+import (
+	"fmt"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+)
+
+// circuitReasonLabels 退出熔断原因的中文描述
+var circuitReasonLabels = map[string]string{
+	metrics.CircuitReasonRequestSuccess: "请求成功",
+	metrics.CircuitReasonTimeout:        "熔断超时自动恢复",
+	metrics.CircuitReasonReset:          "手动重置或探测成功",
+}
+
+// CircuitHook 返回用于 MetricsManager.SetCircuitHook 的回调，将 Key 熔断状态变化转换为告警事件
+// 渠道名称按 BaseURL 从当前配置中查找；事件中只包含 Key 的掩码
+func (m *Manager) CircuitHook(kind string) func(metrics.CircuitEvent) {
+	return func(ce metrics.CircuitEvent) {
+		e := Event{
+			Kind:    kind,
+			Channel: m.channelNameForBaseURL(kind, ce.BaseURL),
+			BaseURL: ce.BaseURL,
+			KeyMask: ce.KeyMask,
+			Details: map[string]interface{}{"reason": ce.Reason},
+		}
+		if ce.Open {
+			e.Type = config.AlertEventKeyCircuitOpen
+			e.Level = LevelWarning
+			e.Message = fmt.Sprintf("Key 进入熔断状态（失败率: %.1f%%）", ce.FailureRate*100)
+			e.Details["failureRate"] = ce.FailureRate
+		} else {
+			e.Type = config.AlertEventKeyRecovered
+			e.Level = LevelInfo
+			e.Message = "Key 已退出熔断状态（" + circuitReasonLabels[ce.Reason] + "）"
+		}
+		m.Emit(e)
+	}
+}
+
+// channelNameForBaseURL 按 BaseURL 查找渠道名称（多个渠道共用 BaseURL 时返回第一个）
+func (m *Manager) channelNameForBaseURL(kind, baseURL string) string {
+	cfg := m.cfgManager.GetConfig()
+	for _, upstream := range cfg.UpstreamsForKind(kind) {
+		for _, u := range upstream.GetAllBaseURLs() {
+			if u == baseURL {
+				return upstream.Name
+			}
+		}
+	}
+	return ""
+}
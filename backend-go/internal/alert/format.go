@@ -0,0 +1,100 @@
+package alert
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+// levelLabels 文本消息中的级别标签
+var levelLabels = map[string]string{
+	LevelInfo:     "信息",
+	LevelWarning:  "警告",
+	LevelCritical: "严重",
+}
+
+// formatText 将事件渲染为聊天机器人使用的纯文本
+func formatText(e Event) string {
+	label := levelLabels[e.Level]
+	if label == "" {
+		label = e.Level
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[Claude Proxy %s] %s\n%s", label, e.Type, e.Message)
+	if e.Kind != "" {
+		fmt.Fprintf(&sb, "\n入口: %s", e.Kind)
+	}
+	if e.Channel != "" {
+		fmt.Fprintf(&sb, "\n渠道: %s", e.Channel)
+	}
+	if e.BaseURL != "" {
+		fmt.Fprintf(&sb, "\nBaseURL: %s", e.BaseURL)
+	}
+	if e.KeyMask != "" {
+		fmt.Fprintf(&sb, "\nKey: %s", e.KeyMask)
+	}
+	fmt.Fprintf(&sb, "\n时间: %s", e.Time.Format(time.RFC3339))
+	return sb.String()
+}
+
+// buildPayload 按目标类型构建请求地址与请求体
+func buildPayload(target config.AlertTarget, e Event, now time.Time) (string, []byte, error) {
+	var payload interface{}
+	targetURL := target.URL
+
+	switch target.Type {
+	case config.AlertTargetSlack:
+		payload = map[string]interface{}{"text": formatText(e)}
+	case config.AlertTargetFeishu:
+		msg := map[string]interface{}{
+			"msg_type": "text",
+			"content":  map[string]interface{}{"text": formatText(e)},
+		}
+		if target.Secret != "" {
+			ts := strconv.FormatInt(now.Unix(), 10)
+			msg["timestamp"] = ts
+			msg["sign"] = feishuSign(ts, target.Secret)
+		}
+		payload = msg
+	case config.AlertTargetDingTalk:
+		payload = map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]interface{}{"content": formatText(e)},
+		}
+		if target.Secret != "" {
+			ts := strconv.FormatInt(now.UnixMilli(), 10)
+			sep := "?"
+			if strings.Contains(targetURL, "?") {
+				sep = "&"
+			}
+			targetURL += sep + "timestamp=" + ts + "&sign=" + url.QueryEscape(dingTalkSign(ts, target.Secret))
+		}
+	default:
+		payload = e
+	}
+
+	body, err := json.Marshal(payload)
+	return targetURL, body, err
+}
+
+// feishuSign 飞书签名：以 "timestamp\nsecret" 为密钥对空串做 HmacSHA256 后 Base64
+func feishuSign(timestamp, secret string) string {
+	h := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// dingTalkSign 钉钉签名：以 secret 为密钥对 "timestamp\nsecret" 做 HmacSHA256 后 Base64
+func dingTalkSign(timestamp, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,67 @@
+// Package audit 提供管理操作审计日志（SQLite 持久化，只追加 + 查询）
+// 每个产生配置差异的成功管理写操作记录一条：操作者（管理员凭据指纹）、时间、来源 IP、操作、目标渠道，
+// 以及操作前后的配置差异（API Key 等敏感值已掩码）；后台自动变更以 system 操作者记录。
+package audit
+
+import (
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+// Entry 一条管理操作审计记录
+type Entry struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// 操作者
+	Actor     string `json:"actor"`               // admin（PROXY_ACCESS_KEY）或 system（后台自动操作）
+	KeyID     string `json:"keyId"`               // 凭据指纹（sha256 前缀），用于区分轮换前后的管理员密钥
+	SourceIP  string `json:"sourceIp"`            // 来源 IP
+	UserAgent string `json:"userAgent,omitempty"` // 客户端标识
+
+	// 操作
+	Method     string `json:"method"`
+	Path       string `json:"path"`   // 实际请求路径（路径中的 API Key 已掩码）；系统操作为空
+	Action     string `json:"action"` // 路由模板，如 PATCH /api/messages/channels/:id/status；系统操作为变更来源，如 health-probe
+	StatusCode int    `json:"statusCode"`
+
+	// 目标渠道（非渠道操作为空）
+	Kind        string `json:"kind,omitempty"` // messages/responses/gemini/chat
+	ChannelID   string `json:"channelId,omitempty"`
+	ChannelName string `json:"channelName,omitempty"`
+
+	// 操作前后的配置差异（current 为操作前，target 为操作后）
+	Channels []config.ConfigChannelChange `json:"channels"`
+	Settings []config.ConfigFieldChange   `json:"settings"`
+}
+
+// Filter 查询条件（零值字段不参与过滤）
+type Filter struct {
+	Actor   string
+	KeyID   string
+	Kind    string
+	Channel string // 匹配渠道标识或名称（含差异中涉及的渠道）
+	Action  string // 操作模糊匹配
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+	Offset  int
+}
+
+const (
+	defaultQueryLimit = 50
+	maxQueryLimit     = 500
+)
+
+// normalize 修正分页参数
+func (f *Filter) normalize() {
+	if f.Limit <= 0 {
+		f.Limit = defaultQueryLimit
+	} else if f.Limit > maxQueryLimit {
+		f.Limit = maxQueryLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+}
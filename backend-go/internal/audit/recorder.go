@@ -0,0 +1,229 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 操作者类型
+const (
+	ActorAdmin  = "admin"  // 管理员凭据（PROXY_ACCESS_KEY）
+	ActorSystem = "system" // 后台自动操作（健康探测、Key 自动禁用/降级、配置热重载）
+)
+
+// unattributedAction 无法归属到管理请求或系统来源的配置变更
+const unattributedAction = "unattributed"
+
+// channelKinds 管理 API 路径中的入口类型前缀（/api/<kind>/channels/...）
+var channelKinds = map[string]bool{
+	"messages":  true,
+	"responses": true,
+	"gemini":    true,
+	"chat":      true,
+}
+
+// nonConfigRoutes 不修改配置的管理写操作（触发探测、刷新余额、测试告警、清理请求日志），
+// 不参与串行化，避免耗时的网络操作阻塞其他管理写操作；期间产生的后台变更按系统来源记录。
+var nonConfigRoutes = map[string]bool{
+	"POST /api/health-probe/run":     true,
+	"POST /api/balance/refresh":      true,
+	"POST /api/settings/alerts/test": true,
+	"DELETE /api/requests":           true,
+}
+
+// Recorder 管理操作审计采集器
+// 订阅 ConfigManager 的配置变更：管理请求期间的变更在请求成功后合并为一条记录，
+// 带来源标记的后台变更直接记为系统操作。无实际差异的操作不记录。
+type Recorder struct {
+	store      *SQLiteStore
+	cfgManager *config.ConfigManager
+
+	// 串行化修改配置的管理写操作，保证请求期间的管理变更只来自当前请求
+	mu sync.Mutex
+
+	// 当前管理请求收集到的配置变更（配置变更回调中写入）
+	pendingMu sync.Mutex
+	inRequest bool
+	pending   []config.ConfigChange
+}
+
+// NewRecorder 创建审计采集器
+func NewRecorder(store *SQLiteStore, cfgManager *config.ConfigManager) *Recorder {
+	r := &Recorder{store: store, cfgManager: cfgManager}
+	if store != nil && cfgManager != nil {
+		cfgManager.SetChangeHook(r.onConfigChange)
+	}
+	return r
+}
+
+// Store 返回底层存储（用于查询 API）；Recorder 为 nil 时返回 nil
+func (r *Recorder) Store() *SQLiteStore {
+	if r == nil {
+		return nil
+	}
+	return r.store
+}
+
+// Middleware 记录管理写操作（GET/HEAD/OPTIONS、失败的请求与无配置差异的请求不记录）
+// Recorder 为 nil（未启用审计日志）时直接放行。
+func (r *Recorder) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if r == nil || r.store == nil || !isMutatingMethod(c.Request.Method) ||
+			nonConfigRoutes[c.Request.Method+" "+c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.beginRequest()
+		c.Next()
+		changes := r.endRequest()
+		if c.Writer.Status() >= http.StatusBadRequest || len(changes) == 0 {
+			return
+		}
+
+		before, after := &changes[0].Before, &changes[len(changes)-1].After
+		entry := Entry{
+			ID:         uuid.NewString(),
+			Timestamp:  time.Now(),
+			Actor:      ActorAdmin,
+			KeyID:      credentialFingerprint(middleware.RequestAPIKey(c)),
+			SourceIP:   c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			Method:     c.Request.Method,
+			Path:       maskedPath(c),
+			Action:     c.Request.Method + " " + c.FullPath(),
+			StatusCode: c.Writer.Status(),
+		}
+		entry.Channels, entry.Settings = config.DiffConfigs(before, after)
+		if len(entry.Channels) == 0 && len(entry.Settings) == 0 {
+			return
+		}
+		entry.Kind, entry.ChannelID, entry.ChannelName = resolveTarget(c, before, entry.Channels)
+		r.write(entry)
+	}
+}
+
+// beginRequest 开始收集当前管理请求的配置变更
+func (r *Recorder) beginRequest() {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	r.inRequest = true
+	r.pending = nil
+}
+
+// endRequest 结束收集并返回当前管理请求的配置变更
+func (r *Recorder) endRequest() []config.ConfigChange {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	changes := r.pending
+	r.inRequest = false
+	r.pending = nil
+	return changes
+}
+
+// onConfigChange 配置变更回调（在配置锁内调用，不得回调 ConfigManager）
+// 无来源标记的变更归属于进行中的管理请求；带来源标记或无进行中请求的变更记为系统操作。
+func (r *Recorder) onConfigChange(change config.ConfigChange) {
+	r.pendingMu.Lock()
+	if change.Source == "" && r.inRequest {
+		r.pending = append(r.pending, change)
+		r.pendingMu.Unlock()
+		return
+	}
+	r.pendingMu.Unlock()
+
+	entry := Entry{
+		ID:        uuid.NewString(),
+		Timestamp: time.Now(),
+		Actor:     ActorSystem,
+		Action:    change.Source,
+	}
+	if entry.Action == "" {
+		entry.Action = unattributedAction
+	}
+	entry.Channels, entry.Settings = config.DiffConfigs(&change.Before, &change.After)
+	if len(entry.Channels) == 0 && len(entry.Settings) == 0 {
+		return
+	}
+	if len(entry.Channels) == 1 {
+		entry.Kind, entry.ChannelID, entry.ChannelName = entry.Channels[0].Kind, entry.Channels[0].ID, entry.Channels[0].Name
+	}
+	r.write(entry)
+}
+
+// write 写入一条审计记录（失败仅记录日志）
+func (r *Recorder) write(entry Entry) {
+	if err := r.store.Add(entry); err != nil {
+		log.Printf("[Audit-Write] 警告: 写入审计日志失败 (%s): %v", entry.Action, err)
+	}
+}
+
+// isMutatingMethod 是否为写操作
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// credentialFingerprint 凭据指纹（sha256 前 12 位），不保存凭据本身
+func credentialFingerprint(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// maskedPath 返回掩码路径参数中 API Key 后的请求路径
+func maskedPath(c *gin.Context) string {
+	path := c.Request.URL.Path
+	if key := c.Param("apiKey"); key != "" {
+		path = strings.Replace(path, key, utils.MaskAPIKey(key), 1)
+	}
+	return path
+}
+
+// resolveTarget 解析操作的目标渠道
+// 渠道路由（/api/<kind>/channels/:id/...）按操作前的配置解析；新增渠道等无路径标识的操作取唯一变更的渠道
+func resolveTarget(c *gin.Context, before *config.Config, changes []config.ConfigChannelChange) (kind, id, name string) {
+	segments := strings.Split(strings.TrimPrefix(c.Request.URL.Path, "/api/"), "/")
+	if len(segments) < 2 || !channelKinds[segments[0]] || segments[1] != "channels" {
+		return "", "", ""
+	}
+	kind = segments[0]
+
+	if ref := c.Param("id"); ref != "" {
+		upstreams := before.UpstreamsForKind(kind)
+		for i := range upstreams {
+			if upstreams[i].ID == ref {
+				return kind, upstreams[i].ID, upstreams[i].Name
+			}
+		}
+		if index, err := strconv.Atoi(ref); err == nil && index >= 0 && index < len(upstreams) {
+			return kind, upstreams[index].ID, upstreams[index].Name
+		}
+		return kind, ref, ""
+	}
+
+	if len(changes) == 1 {
+		return kind, changes[0].ID, changes[0].Name
+	}
+	return kind, "", ""
+}
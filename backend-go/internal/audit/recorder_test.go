@@ -0,0 +1,199 @@
+package audit
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+const testChannelKey = "sk-audit-secret-0123456789"
+
+func setupAuditTest(t *testing.T) (*gin.Engine, *SQLiteStore, *config.ConfigManager) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"upstream": [], "loadBalance": "failover"}`), 0644); err != nil {
+		t.Fatalf("写入初始配置失败: %v", err)
+	}
+	cfgManager, err := config.NewConfigManager(configPath)
+	if err != nil {
+		t.Fatalf("初始化配置管理器失败: %v", err)
+	}
+	t.Cleanup(func() { cfgManager.Close() })
+
+	store, err := NewSQLiteStore(filepath.Join(dir, "audit.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	channelID := func(c *gin.Context) string {
+		id, _ := cfgManager.ResolveChannelID("messages", c.Param("id"))
+		return id
+	}
+	r := gin.New()
+	api := r.Group("/api", NewRecorder(store, cfgManager).Middleware())
+	api.GET("/messages/channels", func(c *gin.Context) { c.JSON(200, cfgManager.GetConfig().Upstream) })
+	api.POST("/messages/channels", func(c *gin.Context) {
+		err := cfgManager.AddUpstream(config.UpstreamConfig{
+			Name: c.Query("name"), ServiceType: "claude", BaseURL: "https://a.example.com", APIKeys: []string{testChannelKey},
+		})
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"success": true})
+	})
+	api.PATCH("/messages/channels/:id/status", func(c *gin.Context) {
+		if err := cfgManager.SetChannelStatus(channelID(c), c.Query("status")); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"success": true})
+	})
+	api.DELETE("/messages/channels/:id/keys/:apiKey", func(c *gin.Context) {
+		if err := cfgManager.RemoveAPIKey(channelID(c), c.Param("apiKey")); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"success": true})
+	})
+	return r, store, cfgManager
+}
+
+func doRequest(t *testing.T, r *gin.Engine, method, path string) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("x-api-key", "admin-secret")
+	req.RemoteAddr = "10.0.0.8:4567"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRecorder_RecordsAdminChanges(t *testing.T) {
+	r, store, _ := setupAuditTest(t)
+
+	doRequest(t, r, http.MethodPost, "/api/messages/channels?name=primary")
+	doRequest(t, r, http.MethodGet, "/api/messages/channels")
+	doRequest(t, r, http.MethodPatch, "/api/messages/channels/0/status?status=suspended")
+	if code := doRequest(t, r, http.MethodPatch, "/api/messages/channels/0/status?status=bogus"); code != 400 {
+		t.Fatalf("invalid status should fail, got %d", code)
+	}
+	doRequest(t, r, http.MethodDelete, "/api/messages/channels/0/keys/"+testChannelKey)
+
+	// 配置热重载可能额外产生系统记录，这里只检查管理操作
+	items, total, err := store.List(Filter{Actor: ActorAdmin})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	// GET 与失败的请求不记录
+	if total != 3 || len(items) != 3 {
+		t.Fatalf("expected 3 audit entries, got %d: %+v", total, items)
+	}
+
+	add, status, removeKey := items[2], items[1], items[0]
+	if add.Action != "POST /api/messages/channels" || add.ChannelName != "primary" || add.ChannelID == "" ||
+		len(add.Channels) != 1 || add.Channels[0].Change != config.ChannelChangeAdded {
+		t.Fatalf("unexpected add entry: %+v", add)
+	}
+	if status.Actor != ActorAdmin || status.KeyID != credentialFingerprint("admin-secret") || status.SourceIP != "10.0.0.8" {
+		t.Fatalf("unexpected actor fields: %+v", status)
+	}
+	fields := status.Channels[0].Fields
+	if status.ChannelName != "primary" || len(fields) != 1 || fields[0].Field != "status" ||
+		fields[0].Current != "active" || fields[0].Target != "suspended" {
+		t.Fatalf("status change should record before/after: %+v", status)
+	}
+	if strings.Contains(removeKey.Path, testChannelKey) || removeKey.Action != "DELETE /api/messages/channels/:id/keys/:apiKey" {
+		t.Fatalf("key in path must be masked: %+v", removeKey)
+	}
+
+	// 按渠道名称/标识查询
+	if _, n, _ := store.List(Filter{Actor: ActorAdmin, Channel: "primary"}); n != 3 {
+		t.Fatalf("channel filter by name = %d, want 3", n)
+	}
+	if _, n, _ := store.List(Filter{Channel: add.ChannelID, Action: "status"}); n != 1 {
+		t.Fatalf("channel+action filter = %d, want 1", n)
+	}
+	if _, n, _ := store.List(Filter{Channel: "prim"}); n != 0 {
+		t.Fatalf("channel filter should match whole names, got %d", n)
+	}
+}
+
+func TestRecorder_NeverStoresPlaintextKeys(t *testing.T) {
+	r, store, _ := setupAuditTest(t)
+	doRequest(t, r, http.MethodPost, "/api/messages/channels?name=primary")
+	doRequest(t, r, http.MethodDelete, "/api/messages/channels/0/keys/"+testChannelKey)
+
+	db, err := sql.Open("sqlite", store.dbPath)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT path, changes, key_id FROM audit_logs")
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		var path, changes, keyID string
+		if err := rows.Scan(&path, &changes, &keyID); err != nil {
+			t.Fatalf("扫描失败: %v", err)
+		}
+		for _, v := range []string{path, changes, keyID} {
+			if strings.Contains(v, testChannelKey) || strings.Contains(v, "admin-secret") {
+				t.Fatalf("audit log must not contain secrets: %s", v)
+			}
+		}
+		count++
+	}
+	if _, n, _ := store.List(Filter{Actor: ActorAdmin}); count < 2 || n != 2 {
+		t.Fatalf("expected 2 admin rows, got %d of %d", n, count)
+	}
+}
+
+func TestRecorder_SkipsNoopAndAttributesBackgroundChanges(t *testing.T) {
+	r, store, cfgManager := setupAuditTest(t)
+	doRequest(t, r, http.MethodPost, "/api/messages/channels?name=primary")
+
+	// 状态未变化的写操作不产生记录
+	if code := doRequest(t, r, http.MethodPatch, "/api/messages/channels/0/status?status=active"); code != 200 {
+		t.Fatalf("status update failed: %d", code)
+	}
+	if _, n, _ := store.List(Filter{Actor: ActorAdmin}); n != 1 {
+		t.Fatalf("no-op change should not be recorded, got %d entries", n)
+	}
+
+	// 后台自动禁用 Key 记为系统操作
+	_, err := cfgManager.DisableAPIKey("messages", "", config.DisabledAPIKey{
+		Key: testChannelKey, Reason: config.DisabledReasonInvalidKey, DisabledAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("DisableAPIKey() error = %v", err)
+	}
+
+	items, total, err := store.List(Filter{Actor: ActorSystem, Action: config.ChangeSourceKeyAutoDisable})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if total != 1 || items[0].Action != config.ChangeSourceKeyAutoDisable || items[0].KeyID != "" ||
+		items[0].Kind != "messages" || items[0].ChannelName != "primary" {
+		t.Fatalf("unexpected system entry: %+v", items)
+	}
+	if _, n, _ := store.List(Filter{Actor: ActorAdmin}); n != 1 {
+		t.Fatalf("background change must not be attributed to admin, got %d admin entries", n)
+	}
+}
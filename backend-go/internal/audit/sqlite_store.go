@@ -0,0 +1,239 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore 审计日志 SQLite 存储
+// 只追加、不提供删除；管理操作频率低，写入同步完成，保证接口返回时记录已落盘。
+type SQLiteStore struct {
+	db     *sql.DB
+	dbPath string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewSQLiteStore 创建审计日志存储
+func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
+	if dbPath == "" {
+		dbPath = ".config/audit.db"
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+	}
+
+	dsn := dbPath + "?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+
+	if err := initSchema(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化数据库 schema 失败: %w", err)
+	}
+
+	log.Printf("[Audit-Init] 管理操作审计日志已初始化: %s", dbPath)
+	return &SQLiteStore{db: db, dbPath: dbPath}, nil
+}
+
+// initSchema 初始化表结构
+// channel_refs 保存目标渠道与差异中涉及渠道的标识/名称（形如 |id|name|），用于按渠道查询
+func initSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_logs (
+			id TEXT PRIMARY KEY,
+			timestamp INTEGER NOT NULL,
+			actor TEXT NOT NULL DEFAULT '',
+			key_id TEXT NOT NULL DEFAULT '',
+			source_ip TEXT NOT NULL DEFAULT '',
+			user_agent TEXT NOT NULL DEFAULT '',
+			method TEXT NOT NULL DEFAULT '',
+			path TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL DEFAULT '',
+			status_code INTEGER NOT NULL DEFAULT 0,
+			kind TEXT NOT NULL DEFAULT '',
+			channel_id TEXT NOT NULL DEFAULT '',
+			channel_name TEXT NOT NULL DEFAULT '',
+			channel_refs TEXT NOT NULL DEFAULT '',
+			changes TEXT NOT NULL DEFAULT ''
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_audit_logs_timestamp
+			ON audit_logs(timestamp);
+	`)
+	return err
+}
+
+// auditChanges changes 列的 JSON 结构
+type auditChanges struct {
+	Channels []config.ConfigChannelChange `json:"channels"`
+	Settings []config.ConfigFieldChange   `json:"settings"`
+}
+
+// Add 追加一条审计记录
+func (s *SQLiteStore) Add(e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("审计日志存储已关闭")
+	}
+
+	changes, err := json.Marshal(auditChanges{Channels: e.Channels, Settings: e.Settings})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO audit_logs
+		(id, timestamp, actor, key_id, source_ip, user_agent, method, path, action, status_code,
+		 kind, channel_id, channel_name, channel_refs, changes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		e.ID, e.Timestamp.UnixMilli(), e.Actor, e.KeyID, e.SourceIP, e.UserAgent, e.Method, e.Path, e.Action, e.StatusCode,
+		e.Kind, e.ChannelID, e.ChannelName, channelRefs(&e), string(changes),
+	)
+	return err
+}
+
+// channelRefs 汇总记录涉及的渠道标识与名称
+func channelRefs(e *Entry) string {
+	refs := []string{e.ChannelID, e.ChannelName}
+	for _, ch := range e.Channels {
+		refs = append(refs, ch.ID, ch.Name)
+	}
+
+	var b strings.Builder
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if ref == "" || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		b.WriteString("|" + ref)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return b.String() + "|"
+}
+
+// List 按条件分页查询（按时间倒序），返回当前页与总数
+func (s *SQLiteStore) List(filter Filter) ([]Entry, int, error) {
+	filter.normalize()
+	where, args := buildWhere(filter)
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM audit_logs"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, timestamp, actor, key_id, source_ip, user_agent, method, path, action, status_code,
+		       kind, channel_id, channel_name, changes
+		FROM audit_logs`+where+" ORDER BY timestamp DESC, rowid DESC LIMIT ? OFFSET ?",
+		append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := make([]Entry, 0)
+	for rows.Next() {
+		var e Entry
+		var ts int64
+		var changes string
+		if err := rows.Scan(&e.ID, &ts, &e.Actor, &e.KeyID, &e.SourceIP, &e.UserAgent, &e.Method, &e.Path, &e.Action,
+			&e.StatusCode, &e.Kind, &e.ChannelID, &e.ChannelName, &changes); err != nil {
+			return nil, 0, err
+		}
+		e.Timestamp = time.UnixMilli(ts)
+
+		var parsed auditChanges
+		if changes != "" {
+			if err := json.Unmarshal([]byte(changes), &parsed); err != nil {
+				return nil, 0, fmt.Errorf("解析配置差异失败: %w", err)
+			}
+		}
+		e.Channels, e.Settings = parsed.Channels, parsed.Settings
+		if e.Channels == nil {
+			e.Channels = []config.ConfigChannelChange{}
+		}
+		if e.Settings == nil {
+			e.Settings = []config.ConfigFieldChange{}
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+// buildWhere 根据过滤条件构建 WHERE 子句
+func buildWhere(f Filter) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if f.Actor != "" {
+		conds = append(conds, "actor = ?")
+		args = append(args, f.Actor)
+	}
+	if f.KeyID != "" {
+		conds = append(conds, "key_id = ?")
+		args = append(args, f.KeyID)
+	}
+	if f.Kind != "" {
+		conds = append(conds, "kind = ?")
+		args = append(args, f.Kind)
+	}
+	if f.Channel != "" {
+		conds = append(conds, `channel_refs LIKE ? ESCAPE '\'`)
+		args = append(args, "%|"+escapeLike(f.Channel)+"|%")
+	}
+	if f.Action != "" {
+		conds = append(conds, `action LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(f.Action)+"%")
+	}
+	if !f.Since.IsZero() {
+		conds = append(conds, "timestamp >= ?")
+		args = append(args, f.Since.UnixMilli())
+	}
+	if !f.Until.IsZero() {
+		conds = append(conds, "timestamp < ?")
+		args = append(args, f.Until.UnixMilli())
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Close 关闭存储
+func (s *SQLiteStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.db.Close()
+}
@@ -0,0 +1,259 @@
+// Package balance 定期查询中转渠道（one-api/new-api 等）各 Key 的剩余额度
+//
+// 余额结果保存在对应入口的 MetricsManager 中随渠道指标一起返回；剩余额度首次低于渠道阈值时
+// 通过 DeprioritizeAPIKey 将 Key 移到末尾，避免余额即将耗尽的 Key 继续承担主要流量。
+package balance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/alert"
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+)
+
+const (
+	checkInterval = time.Minute      // 检查是否有渠道到达查询周期的频率
+	queryTimeout  = 15 * time.Second // 单次余额查询超时
+)
+
+// checkKinds 参与余额查询的入口类型
+var checkKinds = []scheduler.ChannelKind{
+	scheduler.ChannelKindMessages,
+	scheduler.ChannelKindResponses,
+	scheduler.ChannelKindGemini,
+	scheduler.ChannelKindChat,
+}
+
+// Result 单个 Key 的查询结果
+type Result struct {
+	Kind          string             `json:"kind"`
+	ChannelID     string             `json:"channelId"`    // 渠道唯一标识（索引会随排序/删除变化）
+	ChannelIndex  int                `json:"channelIndex"` // 查询时的渠道索引
+	ChannelName   string             `json:"channelName"`
+	BaseURL       string             `json:"baseUrl"` // 查询的 BaseURL（多个 BaseURL 指向同一中转站时只查询第一个，结果共用）
+	Balance       metrics.KeyBalance `json:"balance"`
+	Deprioritized bool               `json:"deprioritized,omitempty"` // 本次查询触发了降低优先级
+}
+
+// Checker 中转渠道余额定期查询
+type Checker struct {
+	cfgManager *config.ConfigManager
+	scheduler  *scheduler.ChannelScheduler
+
+	mu          sync.Mutex
+	lastChecked map[string]time.Time // kind|渠道标识 -> 最近一次查询时间
+
+	runMu    sync.Mutex // 保证同一时间只有一个查询周期
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewChecker 创建余额查询器（需调用 Start 启动后台循环）
+func NewChecker(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) *Checker {
+	return &Checker{
+		cfgManager:  cfgManager,
+		scheduler:   sch,
+		lastChecked: make(map[string]time.Time),
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// Start 启动后台查询循环（是否查询由各渠道 balanceCheck.enabled 控制，支持热更新）
+func (ch *Checker) Start() {
+	ch.wg.Add(1)
+	go func() {
+		defer ch.wg.Done()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithCancel(context.Background())
+				go func() {
+					select {
+					case <-ch.stopCh:
+						cancel()
+					case <-ctx.Done():
+					}
+				}()
+				ch.RunOnce(ctx, false)
+				cancel()
+			case <-ch.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台查询循环
+func (ch *Checker) Stop() {
+	ch.stopOnce.Do(func() {
+		close(ch.stopCh)
+	})
+	ch.wg.Wait()
+}
+
+// RunOnce 查询所有启用了余额查询且到达查询周期的渠道；force 为 true 时忽略查询周期
+func (ch *Checker) RunOnce(ctx context.Context, force bool) []Result {
+	ch.runMu.Lock()
+	defer ch.runMu.Unlock()
+
+	cfg := ch.cfgManager.GetConfig()
+	now := time.Now()
+
+	var results []Result
+	for _, kind := range checkKinds {
+		metricsManager := ch.scheduler.GetMetricsManager(kind)
+		for i, upstream := range cfg.UpstreamsForKind(string(kind)) {
+			check := upstream.BalanceCheck
+			if check == nil || !check.Enabled || len(upstream.APIKeys) == 0 {
+				continue
+			}
+			baseURLs := upstream.GetAllBaseURLs()
+			if len(baseURLs) == 0 {
+				continue
+			}
+
+			channelKey := string(kind) + "|" + upstream.ID
+			if !force && !ch.due(channelKey, now, time.Duration(check.Interval())*time.Second) {
+				continue
+			}
+
+			for _, apiKey := range upstream.APIKeys {
+				if ctx.Err() != nil {
+					return results
+				}
+				results = append(results, ch.checkKey(ctx, metricsManager, kind, i, &upstream, baseURLs, apiKey)...)
+			}
+			ch.markChecked(channelKey, now)
+		}
+	}
+
+	if len(results) > 0 {
+		failed := 0
+		for _, r := range results {
+			if r.Balance.Error != "" {
+				failed++
+			}
+		}
+		log.Printf("[Balance] 余额查询完成: %d 个 Key, %d 个查询失败", len(results), failed)
+	}
+	return results
+}
+
+// checkKey 在渠道的每个中转站（按 BaseURL 推导的查询地址去重）上查询 Key 的余额并写入对应 BaseURL 的指标；
+// 任一中转站的余额首次低于阈值时降低 Key 优先级（每个周期最多一次）
+func (ch *Checker) checkKey(ctx context.Context, metricsManager *metrics.MetricsManager, kind scheduler.ChannelKind, index int, upstream *config.UpstreamConfig, baseURLs []string, apiKey string) []Result {
+	check := upstream.BalanceCheck
+	keyMask := utils.MaskAPIKey(apiKey)
+
+	// 查询地址 -> 共用该地址余额的 BaseURL（保持配置顺序）
+	var roots []string
+	urlsByRoot := make(map[string][]string)
+	for _, baseURL := range baseURLs {
+		root := siteRoot(baseURL, check)
+		if _, ok := urlsByRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		urlsByRoot[root] = append(urlsByRoot[root], baseURL)
+	}
+
+	var results []Result
+	var lowResult *Result
+	for _, root := range roots {
+		if ctx.Err() != nil {
+			break
+		}
+		urls := urlsByRoot[root]
+		result := Result{Kind: string(kind), ChannelID: upstream.ID, ChannelIndex: index, ChannelName: upstream.Name, BaseURL: urls[0]}
+
+		queryCtx, cancel := context.WithTimeout(ctx, queryTimeout)
+		quota, err := queryBalance(queryCtx, upstream, check, root, apiKey)
+		cancel()
+
+		balance := metrics.KeyBalance{KeyMask: keyMask, CheckedAt: time.Now()}
+		if err != nil {
+			balance.Error = err.Error()
+			log.Printf("[Balance] 渠道 [%d] %s (%s) Key %s 余额查询失败: %v", index, upstream.Name, root, keyMask, err)
+			for _, baseURL := range urls {
+				metricsManager.SetKeyBalance(baseURL, apiKey, balance)
+			}
+			if stored := metricsManager.GetKeyBalance(urls[0], apiKey); stored != nil {
+				result.Balance = *stored
+			} else {
+				result.Balance = balance
+			}
+			results = append(results, result)
+			continue
+		}
+
+		balance.Remaining = quota.Remaining
+		balance.Used = quota.Used
+		balance.Total = quota.Total
+		balance.Unlimited = quota.Unlimited
+		balance.Low = !quota.Unlimited && check.Threshold > 0 && quota.Remaining < check.Threshold
+
+		// 只在余额从正常变为低于阈值时降级一次，避免多个低余额 Key 每个周期互相轮换
+		previous := metricsManager.GetKeyBalance(urls[0], apiKey)
+		becameLow := balance.Low && (previous == nil || !previous.Low)
+		for _, baseURL := range urls {
+			metricsManager.SetKeyBalance(baseURL, apiKey, balance)
+		}
+		result.Balance = balance
+		results = append(results, result)
+		if becameLow && lowResult == nil {
+			lowResult = &results[len(results)-1]
+		}
+	}
+
+	if lowResult != nil {
+		lowResult.Deprioritized = ch.deprioritizeLowKey(kind, upstream, lowResult.BaseURL, apiKey, lowResult.Balance.Remaining)
+	}
+	return results
+}
+
+// deprioritizeLowKey 降低低余额 Key 的优先级并发送告警，返回是否降级成功
+func (ch *Checker) deprioritizeLowKey(kind scheduler.ChannelKind, upstream *config.UpstreamConfig, baseURL, apiKey string, remaining float64) bool {
+	check := upstream.BalanceCheck
+	keyMask := utils.MaskAPIKey(apiKey)
+
+	deprioritized := true
+	if err := ch.cfgManager.DeprioritizeAPIKey(string(kind), upstream.ID, apiKey); err != nil {
+		log.Printf("[Balance] 警告: 降低 Key %s 优先级失败: %v", keyMask, err)
+		deprioritized = false
+	}
+	log.Printf("[Balance] 渠道 %s Key %s 剩余额度 %.2f 低于阈值 %.2f，已降低优先级",
+		upstream.Name, keyMask, remaining, check.Threshold)
+	alert.Emit(alert.Event{
+		Type:    config.AlertEventLowBalance,
+		Level:   alert.LevelWarning,
+		Kind:    string(kind),
+		Channel: upstream.Name,
+		BaseURL: baseURL,
+		KeyMask: keyMask,
+		Message: fmt.Sprintf("Key 剩余额度 %.2f 低于阈值 %.2f", remaining, check.Threshold),
+		Details: map[string]interface{}{"remaining": remaining, "threshold": check.Threshold},
+	})
+	return deprioritized
+}
+
+func (ch *Checker) due(channelKey string, now time.Time, interval time.Duration) bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	last, ok := ch.lastChecked[channelKey]
+	return !ok || now.Sub(last) >= interval
+}
+
+func (ch *Checker) markChecked(channelKey string, now time.Time) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.lastChecked[channelKey] = now
+}
@@ -0,0 +1,213 @@
+package balance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/warmup"
+)
+
+func newTestChecker(t *testing.T, cfg config.Config) (*Checker, *config.ConfigManager) {
+	t.Helper()
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	data, _ := json.Marshal(cfg)
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	cfgManager, err := config.NewConfigManager(configFile)
+	if err != nil {
+		t.Fatalf("创建配置管理器失败: %v", err)
+	}
+	t.Cleanup(func() { cfgManager.Close() })
+
+	sch := scheduler.NewChannelScheduler(cfgManager,
+		metrics.NewMetricsManager(), metrics.NewMetricsManager(), metrics.NewMetricsManager(), metrics.NewMetricsManager(),
+		session.NewTraceAffinityManager(), warmup.NewURLManager(time.Second, 3))
+	return NewChecker(cfgManager, sch), cfgManager
+}
+
+// newRelayServer 模拟 one-api/new-api 中转站的余额接口
+func newRelayServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/v1/dashboard/billing/subscription":
+			switch key {
+			case "Bearer rich":
+				w.Write([]byte(`{"hard_limit_usd": 50}`))
+			case "Bearer poor":
+				w.Write([]byte(`{"hard_limit_usd": 10}`))
+			case "Bearer unlimited":
+				w.Write([]byte(`{"hard_limit_usd": 100000000}`))
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":{"message":"invalid token"}}`))
+			}
+		case "/v1/dashboard/billing/usage":
+			if r.URL.Query().Get("start_date") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if key == "Bearer poor" {
+				w.Write([]byte(`{"total_usage": 950}`)) // 9.5 USD
+				return
+			}
+			w.Write([]byte(`{"total_usage": 1000}`))
+		case "/api/usage/token":
+			w.Write([]byte(`{"code":true,"data":{"total_granted":5000000,"total_used":1000000,"total_available":4000000,"unlimited_quota":false}}`))
+		case "/custom/balance":
+			w.Write([]byte(`{"data":{"balance":"12.5"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChecker_OpenAIBillingAndThreshold(t *testing.T) {
+	srv := newRelayServer(t)
+	checker, cfgManager := newTestChecker(t, config.Config{
+		Upstream: []config.UpstreamConfig{{
+			// 与 relay 共用 Key 但未启用余额查询的渠道，不应被调整 Key 顺序
+			Name:        "shared",
+			ServiceType: "claude",
+			BaseURL:     "https://shared.example.com",
+			APIKeys:     []string{"poor", "rich"},
+			Status:      "active",
+		}, {
+			Name:         "relay",
+			ServiceType:  "claude",
+			BaseURL:      srv.URL + "/v1",
+			APIKeys:      []string{"poor", "rich", "unlimited", "bad"},
+			Status:       "active",
+			BalanceCheck: &config.BalanceCheckConfig{Enabled: true, Threshold: 1},
+		}},
+	})
+
+	results := checker.RunOnce(context.Background(), false)
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %+v", results)
+	}
+	poor, rich, unlimited, bad := results[0], results[1], results[2], results[3]
+	if !poor.Balance.Low || !poor.Deprioritized || poor.Balance.Remaining != 0.5 {
+		t.Fatalf("poor key should be low and deprioritized: %+v", poor)
+	}
+	if rich.Balance.Low || rich.Balance.Remaining != 40 || rich.Balance.Total != 50 || rich.Balance.Used != 10 {
+		t.Fatalf("unexpected rich balance: %+v", rich.Balance)
+	}
+	if !unlimited.Balance.Unlimited || unlimited.Balance.Low {
+		t.Fatalf("unexpected unlimited balance: %+v", unlimited.Balance)
+	}
+	if bad.Balance.Error == "" {
+		t.Fatalf("invalid key should record an error: %+v", bad.Balance)
+	}
+
+	cfg := cfgManager.GetConfig()
+	if keys := cfg.Upstream[1].APIKeys; keys[len(keys)-1] != "poor" {
+		t.Fatalf("poor key should be moved to the end: %v", keys)
+	}
+	if keys := cfg.Upstream[0].APIKeys; keys[0] != "poor" {
+		t.Fatalf("only the checked channel should be reordered: %v", keys)
+	}
+
+	// 周期未到不再查询；强制刷新时仍低于阈值的 Key 不重复降级
+	if again := checker.RunOnce(context.Background(), false); len(again) != 0 {
+		t.Fatalf("channel should not be checked before its interval: %+v", again)
+	}
+	for _, r := range checker.RunOnce(context.Background(), true) {
+		if r.Deprioritized {
+			t.Fatalf("already low key should not be deprioritized again: %+v", r)
+		}
+	}
+
+	// 余额随渠道指标返回
+	mm := checker.scheduler.GetMetricsManager(scheduler.ChannelKindMessages)
+	resp := mm.ToResponseMultiURL(0, []string{srv.URL + "/v1"}, []string{"rich"}, 0)
+	if len(resp.KeyBalances) != 1 || resp.KeyBalances[0].Remaining != 40 {
+		t.Fatalf("dashboard should include key balances: %+v", resp.KeyBalances)
+	}
+}
+
+func TestChecker_NewAPITokenAndCustom(t *testing.T) {
+	srv := newRelayServer(t)
+	checker, _ := newTestChecker(t, config.Config{
+		ChatUpstream: []config.UpstreamConfig{
+			{
+				Name: "newapi", ServiceType: "openai", BaseURL: srv.URL, APIKeys: []string{"k1"}, Status: "active",
+				BalanceCheck: &config.BalanceCheckConfig{Enabled: true, Type: config.BalanceCheckNewAPIToken},
+			},
+			{
+				Name: "custom", ServiceType: "openai", BaseURL: srv.URL, APIKeys: []string{"k2"}, Status: "active",
+				BalanceCheck: &config.BalanceCheckConfig{Enabled: true, Type: config.BalanceCheckCustom, URL: srv.URL + "/custom/balance", RemainingField: "data.balance"},
+			},
+			{
+				Name: "disabled-check", ServiceType: "openai", BaseURL: srv.URL, APIKeys: []string{"k3"}, Status: "active",
+			},
+		},
+	})
+
+	results := checker.RunOnce(context.Background(), false)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if b := results[0].Balance; b.Remaining != 8 || b.Used != 2 || b.Total != 10 {
+		t.Fatalf("unexpected new-api balance: %+v", b)
+	}
+	if b := results[1].Balance; b.Remaining != 12.5 {
+		t.Fatalf("unexpected custom balance: %+v", b)
+	}
+}
+
+func TestChecker_QueriesEachRelayOfMultiURLChannel(t *testing.T) {
+	primary, secondary := newRelayServer(t), newRelayServer(t)
+	checker, _ := newTestChecker(t, config.Config{
+		Upstream: []config.UpstreamConfig{{
+			Name:        "relay",
+			ServiceType: "claude",
+			// 前两个 BaseURL 指向同一中转站，只查询一次
+			BaseURLs:     []string{primary.URL + "/v1", primary.URL, secondary.URL + "/v1"},
+			APIKeys:      []string{"rich"},
+			Status:       "active",
+			BalanceCheck: &config.BalanceCheckConfig{Enabled: true},
+		}},
+	})
+
+	results := checker.RunOnce(context.Background(), false)
+	if len(results) != 2 || results[0].BaseURL != primary.URL+"/v1" || results[1].BaseURL != secondary.URL+"/v1" {
+		t.Fatalf("expected one result per relay, got %+v", results)
+	}
+	mm := checker.scheduler.GetMetricsManager(scheduler.ChannelKindMessages)
+	for _, baseURL := range []string{primary.URL + "/v1", primary.URL, secondary.URL + "/v1"} {
+		if b := mm.GetKeyBalance(baseURL, "rich"); b == nil || b.Remaining != 40 {
+			t.Fatalf("balance for %s should be recorded: %+v", baseURL, b)
+		}
+	}
+}
+
+func TestSiteRoot(t *testing.T) {
+	tests := []struct {
+		baseURL, url, want string
+	}{
+		{"https://relay.example.com/v1", "", "https://relay.example.com"},
+		{"https://relay.example.com/", "", "https://relay.example.com"},
+		{"https://relay.example.com/openai#", "", "https://relay.example.com/openai"},
+		{"https://relay.example.com/v1", "https://billing.example.com", "https://billing.example.com"},
+	}
+	for _, tt := range tests {
+		if got := siteRoot(tt.baseURL, &config.BalanceCheckConfig{URL: tt.url}); got != tt.want {
+			t.Errorf("siteRoot(%q, %q) = %q, want %q", tt.baseURL, tt.url, got, tt.want)
+		}
+	}
+}
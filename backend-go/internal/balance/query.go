@@ -0,0 +1,185 @@
+package balance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/httpclient"
+)
+
+// versionPattern 匹配 baseURL 末尾的版本号（/v1, /v2, /v1beta 等）
+var versionPattern = regexp.MustCompile(`/v\d+[a-z]*$`)
+
+// unlimitedHardLimit one-api/new-api 对无限额度令牌返回的 hard_limit_usd
+const unlimitedHardLimit = 100000000
+
+// quotaResult 一次余额查询的结果（USD）
+type quotaResult struct {
+	Remaining float64
+	Used      float64
+	Total     float64
+	Unlimited bool
+}
+
+// siteRoot 由 BaseURL 推导中转站根地址（去掉末尾的 #、/ 与版本号）；配置了查询地址时使用查询地址
+func siteRoot(baseURL string, check *config.BalanceCheckConfig) string {
+	root := check.URL
+	if root == "" {
+		root = baseURL
+	}
+	root = strings.TrimSuffix(root, "#")
+	root = strings.TrimSuffix(root, "/")
+	return versionPattern.ReplaceAllString(root, "")
+}
+
+// queryBalance 按渠道配置的查询方式查询单个 Key 在中转站 root 上的余额
+func queryBalance(ctx context.Context, upstream *config.UpstreamConfig, check *config.BalanceCheckConfig, root, apiKey string) (*quotaResult, error) {
+	switch check.CheckType() {
+	case config.BalanceCheckNewAPIToken:
+		return queryNewAPIToken(ctx, upstream, check, root, apiKey)
+	case config.BalanceCheckCustom:
+		return queryCustom(ctx, upstream, check, apiKey)
+	default:
+		return queryOpenAIBilling(ctx, upstream, root, apiKey)
+	}
+}
+
+// queryOpenAIBilling 通过 OpenAI 兼容的 billing 接口查询：剩余 = hard_limit_usd - total_usage/100
+func queryOpenAIBilling(ctx context.Context, upstream *config.UpstreamConfig, root, apiKey string) (*quotaResult, error) {
+	var subscription struct {
+		HardLimitUSD float64 `json:"hard_limit_usd"`
+	}
+	if err := getJSON(ctx, upstream, root+"/v1/dashboard/billing/subscription", apiKey, &subscription); err != nil {
+		return nil, err
+	}
+	if subscription.HardLimitUSD >= unlimitedHardLimit {
+		return &quotaResult{Unlimited: true}, nil
+	}
+
+	now := time.Now()
+	usageURL := fmt.Sprintf("%s/v1/dashboard/billing/usage?start_date=%s&end_date=%s", root,
+		now.AddDate(0, 0, -99).Format("2006-01-02"), now.AddDate(0, 0, 1).Format("2006-01-02"))
+	var usage struct {
+		TotalUsage float64 `json:"total_usage"` // 单位：美分
+	}
+	if err := getJSON(ctx, upstream, usageURL, apiKey, &usage); err != nil {
+		return nil, err
+	}
+
+	used := usage.TotalUsage / 100
+	return &quotaResult{
+		Remaining: subscription.HardLimitUSD - used,
+		Used:      used,
+		Total:     subscription.HardLimitUSD,
+	}, nil
+}
+
+// queryNewAPIToken 通过 new-api 的令牌用量接口查询（额度按 QuotaPerUnit 换算为 USD）
+func queryNewAPIToken(ctx context.Context, upstream *config.UpstreamConfig, check *config.BalanceCheckConfig, root, apiKey string) (*quotaResult, error) {
+	var resp struct {
+		Code    bool   `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			TotalGranted   float64 `json:"total_granted"`
+			TotalUsed      float64 `json:"total_used"`
+			TotalAvailable float64 `json:"total_available"`
+			UnlimitedQuota bool    `json:"unlimited_quota"`
+		} `json:"data"`
+	}
+	if err := getJSON(ctx, upstream, root+"/api/usage/token", apiKey, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Code {
+		return nil, fmt.Errorf("查询失败: %s", resp.Message)
+	}
+	if resp.Data.UnlimitedQuota {
+		return &quotaResult{Unlimited: true}, nil
+	}
+
+	divisor := check.UnitDivisor()
+	return &quotaResult{
+		Remaining: resp.Data.TotalAvailable / divisor,
+		Used:      resp.Data.TotalUsed / divisor,
+		Total:     resp.Data.TotalGranted / divisor,
+	}, nil
+}
+
+// queryCustom 请求自定义地址，按 remainingField 路径读取剩余额度
+func queryCustom(ctx context.Context, upstream *config.UpstreamConfig, check *config.BalanceCheckConfig, apiKey string) (*quotaResult, error) {
+	var body interface{}
+	if err := getJSON(ctx, upstream, check.URL, apiKey, &body); err != nil {
+		return nil, err
+	}
+	value, err := lookupNumber(body, check.RemainingField)
+	if err != nil {
+		return nil, err
+	}
+	return &quotaResult{Remaining: value / check.UnitDivisor()}, nil
+}
+
+// lookupNumber 按 . 分隔的路径读取 JSON 中的数值（支持数字字符串）
+func lookupNumber(body interface{}, path string) (float64, error) {
+	current := body
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("响应中不存在字段 %s", path)
+		}
+		if current, ok = obj[part]; !ok {
+			return 0, fmt.Errorf("响应中不存在字段 %s", path)
+		}
+	}
+	switch v := current.(type) {
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("字段 %s 不是数值: %q", path, v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("字段 %s 不是数值", path)
+	}
+}
+
+// getJSON 以 Bearer 认证发送 GET 请求并解析 JSON 响应
+func getJSON(ctx context.Context, upstream *config.UpstreamConfig, targetURL, apiKey string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	client := httpclient.GetManager().GetStandardClient(queryTimeout, upstream.InsecureSkipVerify)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet := strings.TrimSpace(string(data))
+		if len(snippet) > 200 {
+			snippet = snippet[:200]
+		}
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, snippet)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@
 	"sync"
 	"time"
 
+	"github.com/BenedictKing/claude-proxy/internal/pricing"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
 
 	"github.com/fsnotify/fsnotify"
@@ -15,21 +16,34 @@
 
 // UpstreamConfig 上游配置
 type UpstreamConfig struct {
+	ID                 string            `json:"id,omitempty"` // 渠道唯一标识（UUID），创建或加载配置时自动分配，不随排序/删除变化
 	BaseURL            string            `json:"baseUrl"`
 	BaseURLs           []string          `json:"baseUrls,omitempty"` // 多 BaseURL 支持（failover 模式）
 	APIKeys            []string          `json:"apiKeys"`
 	HistoricalAPIKeys  []string          `json:"historicalApiKeys,omitempty"` // 历史 API Key（用于统计聚合，换 Key 后保留旧 Key 的统计数据）
+	DisabledAPIKeys    []DisabledAPIKey  `json:"disabledApiKeys,omitempty"`   // 因永久性错误（Key 无效、账号封禁、余额不足）被自动禁用的 Key
 	ServiceType        string            `json:"serviceType"`                 // gemini, openai, claude
 	Name               string            `json:"name,omitempty"`
 	Description        string            `json:"description,omitempty"`
 	Website            string            `json:"website,omitempty"`
 	InsecureSkipVerify bool              `json:"insecureSkipVerify,omitempty"`
 	ModelMapping       map[string]string `json:"modelMapping,omitempty"`
+	// 支持的模型（精确名或 path.Match 通配符），为空表示支持全部模型；多渠道调度时仅选择服务请求模型的渠道
+	SupportedModels    []string `json:"supportedModels,omitempty"`
+	AutoDiscoverModels bool     `json:"autoDiscoverModels,omitempty"` // 启用后 DiscoveredModels 也参与匹配
+	DiscoveredModels   []string `json:"discoveredModels,omitempty"`   // 从上游 /models 拉取的模型列表
 	// 多渠道调度相关字段
 	Priority       int        `json:"priority"`                 // 渠道优先级（数字越小优先级越高，默认按索引）
 	Status         string     `json:"status"`                   // 渠道状态：active（正常）, suspended（暂停）, disabled（备用池）
 	PromotionUntil *time.Time `json:"promotionUntil,omitempty"` // 促销期截止时间，在此期间内优先使用此渠道（忽略trace亲和）
 	LowQuality     bool       `json:"lowQuality,omitempty"`     // 低质量渠道标记：启用后强制本地估算 token，偏差>5%时使用本地值
+	Weight         int        `json:"weight,omitempty"`         // 负载均衡权重（round-robin/random 策略使用，默认 1）
+	// 流式响应相邻数据块之间的最大间隔（秒），超时即中断并记为失败；0 表示使用全局 STREAM_IDLE_TIMEOUT
+	StreamIdleTimeout int `json:"streamIdleTimeout,omitempty"`
+	// 流式 failover 等待首个内容事件的超时（秒），长时间静默思考的渠道可调大；0 表示使用全局 STREAM_FIRST_CONTENT_TIMEOUT
+	StreamFirstContentTimeout int `json:"streamFirstContentTimeout,omitempty"`
+	// 中转渠道余额查询（定期查询各 Key 剩余额度，低于阈值时降低优先级）
+	BalanceCheck *BalanceCheckConfig `json:"balanceCheck,omitempty"`
 	// Gemini 特定配置
 	InjectDummyThoughtSignature bool `json:"injectDummyThoughtSignature,omitempty"` // 给空 thought_signature 注入 dummy 值（兼容 x666.me 等要求必须有该字段的 API）
 	StripThoughtSignature       bool `json:"stripThoughtSignature,omitempty"`       // 移除 thought_signature 字段（兼容旧版 Gemini API）
@@ -46,11 +60,20 @@ type UpstreamUpdate struct {
 	Website            *string           `json:"website"`
 	InsecureSkipVerify *bool             `json:"insecureSkipVerify"`
 	ModelMapping       map[string]string `json:"modelMapping"`
+	SupportedModels    []string          `json:"supportedModels"`
+	AutoDiscoverModels *bool             `json:"autoDiscoverModels"`
 	// 多渠道调度相关字段
 	Priority       *int       `json:"priority"`
 	Status         *string    `json:"status"`
 	PromotionUntil *time.Time `json:"promotionUntil"`
 	LowQuality     *bool      `json:"lowQuality"`
+	Weight         *int       `json:"weight"`
+	// 流式空闲超时（秒）
+	StreamIdleTimeout *int `json:"streamIdleTimeout"`
+	// 流式首个内容超时（秒）
+	StreamFirstContentTimeout *int `json:"streamFirstContentTimeout"`
+	// 余额查询配置
+	BalanceCheck *BalanceCheckConfig `json:"balanceCheck"`
 	// Gemini 特定配置
 	InjectDummyThoughtSignature *bool `json:"injectDummyThoughtSignature"`
 	StripThoughtSignature       *bool `json:"stripThoughtSignature"`
@@ -60,7 +83,7 @@ type UpstreamUpdate struct {
 type Config struct {
 	Upstream        []UpstreamConfig `json:"upstream"`
 	CurrentUpstream int              `json:"currentUpstream,omitempty"` // 已废弃：旧格式兼容用
-	LoadBalance     string           `json:"loadBalance"`               // round-robin, random, failover
+	LoadBalance     string           `json:"loadBalance"`               // failover, round-robin, random, least-inflight, lowest-latency
 
 	// Responses 接口专用配置（独立于 /v1/messages）
 	ResponsesUpstream        []UpstreamConfig `json:"responsesUpstream"`
@@ -71,8 +94,27 @@ type Config struct {
 	GeminiUpstream    []UpstreamConfig `json:"geminiUpstream"`
 	GeminiLoadBalance string           `json:"geminiLoadBalance"`
 
+	// Chat Completions 接口专用配置（OpenAI 兼容入口 /v1/chat/completions）
+	ChatUpstream    []UpstreamConfig `json:"chatUpstream"`
+	ChatLoadBalance string           `json:"chatLoadBalance"`
+
 	// Fuzzy 模式：启用时模糊处理错误，所有非 2xx 错误都尝试 failover
 	FuzzyModeEnabled bool `json:"fuzzyModeEnabled"`
+
+	// 多租户客户端密钥（与 PROXY_ACCESS_KEY 并存，后者仍作为管理员密钥）
+	Clients []ClientKey `json:"clients,omitempty"`
+
+	// 模型价格覆盖（美元/百万 Token，key 为模型名或 glob 模式；未覆盖的模型使用内置价格表）
+	ModelPrices map[string]pricing.ModelPrice `json:"modelPrices,omitempty"`
+
+	// 后台健康探测（主动探测暂停/熔断/空闲的渠道与 Key）
+	HealthProbe *HealthProbeConfig `json:"healthProbe,omitempty"`
+
+	// 告警通知（渠道/Key 状态变化推送到 Webhook）
+	Alerts *AlertConfig `json:"alerts,omitempty"`
+
+	// API Key 静态加密元数据（存在时文件中的 Key 均为密文，主密钥来自环境变量）
+	Encryption *EncryptionConfig `json:"encryption,omitempty"`
 }
 
 // FailedKey 失败密钥记录
@@ -92,6 +134,13 @@ type ConfigManager struct {
 	maxFailureCount int
 	stopChan        chan struct{} // 用于通知 goroutine 停止
 	closeOnce       sync.Once     // 确保 Close 只执行一次
+	masterKey       []byte        // API Key 加密主密钥（来自环境变量，可为空）
+	keyCipher       *keyCipher    // 当前配置的数据密钥（未启用加密时为 nil）
+
+	// 配置变更订阅（审计日志），见 config_changes.go
+	changeHook   ConfigChangeHook
+	savedConfig  Config // 最近一次持久化/加载后的配置快照，作为下一次变更的 before
+	changeSource string // 当前持锁变更的来源（空表示管理操作）
 }
 
 // ============== 核心共享方法 ==============
@@ -100,34 +149,75 @@ type ConfigManager struct {
 func (cm *ConfigManager) GetConfig() Config {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
+	return cloneConfig(&cm.config)
+}
 
+// cloneConfig 深拷贝配置
+func cloneConfig(cfg *Config) Config {
 	// 深拷贝整个 Config 结构体
-	cloned := cm.config
+	cloned := *cfg
 
 	// 深拷贝 Upstream slice
-	if cm.config.Upstream != nil {
-		cloned.Upstream = make([]UpstreamConfig, len(cm.config.Upstream))
-		for i := range cm.config.Upstream {
-			cloned.Upstream[i] = *cm.config.Upstream[i].Clone()
+	if cfg.Upstream != nil {
+		cloned.Upstream = make([]UpstreamConfig, len(cfg.Upstream))
+		for i := range cfg.Upstream {
+			cloned.Upstream[i] = *cfg.Upstream[i].Clone()
 		}
 	}
 
 	// 深拷贝 ResponsesUpstream slice
-	if cm.config.ResponsesUpstream != nil {
-		cloned.ResponsesUpstream = make([]UpstreamConfig, len(cm.config.ResponsesUpstream))
-		for i := range cm.config.ResponsesUpstream {
-			cloned.ResponsesUpstream[i] = *cm.config.ResponsesUpstream[i].Clone()
+	if cfg.ResponsesUpstream != nil {
+		cloned.ResponsesUpstream = make([]UpstreamConfig, len(cfg.ResponsesUpstream))
+		for i := range cfg.ResponsesUpstream {
+			cloned.ResponsesUpstream[i] = *cfg.ResponsesUpstream[i].Clone()
 		}
 	}
 
 	// 深拷贝 GeminiUpstream slice
-	if cm.config.GeminiUpstream != nil {
-		cloned.GeminiUpstream = make([]UpstreamConfig, len(cm.config.GeminiUpstream))
-		for i := range cm.config.GeminiUpstream {
-			cloned.GeminiUpstream[i] = *cm.config.GeminiUpstream[i].Clone()
+	if cfg.GeminiUpstream != nil {
+		cloned.GeminiUpstream = make([]UpstreamConfig, len(cfg.GeminiUpstream))
+		for i := range cfg.GeminiUpstream {
+			cloned.GeminiUpstream[i] = *cfg.GeminiUpstream[i].Clone()
+		}
+	}
+
+	// 深拷贝 ChatUpstream slice
+	if cfg.ChatUpstream != nil {
+		cloned.ChatUpstream = make([]UpstreamConfig, len(cfg.ChatUpstream))
+		for i := range cfg.ChatUpstream {
+			cloned.ChatUpstream[i] = *cfg.ChatUpstream[i].Clone()
+		}
+	}
+
+	// 深拷贝 Clients slice
+	if cfg.Clients != nil {
+		cloned.Clients = make([]ClientKey, len(cfg.Clients))
+		for i := range cfg.Clients {
+			cloned.Clients[i] = *cfg.Clients[i].Clone()
+		}
+	}
+
+	// 深拷贝 ModelPrices map
+	if cfg.ModelPrices != nil {
+		cloned.ModelPrices = make(map[string]pricing.ModelPrice, len(cfg.ModelPrices))
+		for k, v := range cfg.ModelPrices {
+			cloned.ModelPrices[k] = v
 		}
 	}
 
+	// 深拷贝 HealthProbe
+	if cfg.HealthProbe != nil {
+		cloned.HealthProbe = cfg.HealthProbe.Clone()
+	}
+
+	// 深拷贝 Alerts
+	if cfg.Alerts != nil {
+		cloned.Alerts = cfg.Alerts.Clone()
+	}
+
+	// 深拷贝 Encryption
+	cloned.Encryption = cfg.Encryption.Clone()
+
 	return cloned
 }
 
@@ -282,6 +372,13 @@ func (cm *ConfigManager) cleanupExpiredFailures() {
 
 // ============== Fuzzy 模式相关方法 ==============
 
+// GetLoadBalanceStrategy 获取指定入口类型（messages/responses/gemini/chat）的负载均衡策略
+func (cm *ConfigManager) GetLoadBalanceStrategy(kind string) string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.config.LoadBalanceForKind(kind)
+}
+
 // GetFuzzyModeEnabled 获取 Fuzzy 模式状态
 func (cm *ConfigManager) GetFuzzyModeEnabled() bool {
 	cm.mu.RLock()
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+)
+
+// ============== 告警通知配置 ==============
+
+// 告警默认参数
+const (
+	DefaultAlertDedupSeconds       = 300 // 相同事件的去重窗口（秒）
+	DefaultAlertRateLimitPerMinute = 20  // 每个目标每分钟最多发送次数
+	maxAlertDedupSeconds           = 86400
+	maxAlertRateLimitPerMinute     = 600
+	maxAlertTargets                = 20
+)
+
+// 告警目标类型
+const (
+	AlertTargetGeneric  = "generic"  // 通用 JSON（直接 POST 事件对象）
+	AlertTargetSlack    = "slack"    // Slack Incoming Webhook
+	AlertTargetFeishu   = "feishu"   // 飞书自定义机器人
+	AlertTargetDingTalk = "dingtalk" // 钉钉自定义机器人
+)
+
+// 告警事件类型
+const (
+	AlertEventChannelUnhealthy  = "channel_unhealthy"
+	AlertEventKeyCircuitOpen    = "key_circuit_open"
+	AlertEventKeyRecovered      = "key_recovered"
+	AlertEventAllChannelsFailed = "all_channels_failed"
+	AlertEventQuotaExhausted    = "quota_exhausted"
+	AlertEventKeyDisabled       = "key_disabled"
+	AlertEventLowBalance        = "low_balance" // 中转渠道 Key 剩余额度低于阈值（余额查询触发）
+)
+
+// AlertEventTypes 全部告警事件类型
+var AlertEventTypes = []string{
+	AlertEventChannelUnhealthy,
+	AlertEventKeyCircuitOpen,
+	AlertEventKeyRecovered,
+	AlertEventAllChannelsFailed,
+	AlertEventQuotaExhausted,
+	AlertEventKeyDisabled,
+	AlertEventLowBalance,
+}
+
+// AlertTarget 单个告警推送目标
+type AlertTarget struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`             // generic/slack/feishu/dingtalk
+	URL      string   `json:"url"`              // Webhook 地址
+	Secret   string   `json:"secret,omitempty"` // 飞书/钉钉机器人签名密钥（可选）
+	Events   []string `json:"events,omitempty"` // 订阅的事件类型；为空表示全部
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// Subscribes 目标是否订阅了该事件类型
+func (t *AlertTarget) Subscribes(eventType string) bool {
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, e := range t.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// AlertConfig 告警通知配置
+type AlertConfig struct {
+	Enabled            bool          `json:"enabled"`
+	DedupSeconds       int           `json:"dedupSeconds,omitempty"`       // 相同事件去重窗口，默认 300 秒；-1 表示不去重
+	RateLimitPerMinute int           `json:"rateLimitPerMinute,omitempty"` // 每个目标每分钟最多发送次数，默认 20
+	Targets            []AlertTarget `json:"targets,omitempty"`
+}
+
+// Clone 深拷贝告警配置
+func (a *AlertConfig) Clone() *AlertConfig {
+	if a == nil {
+		return nil
+	}
+	cloned := *a
+	if a.Targets != nil {
+		cloned.Targets = make([]AlertTarget, len(a.Targets))
+		for i, t := range a.Targets {
+			if t.Events != nil {
+				t.Events = append([]string(nil), t.Events...)
+			}
+			cloned.Targets[i] = t
+		}
+	}
+	return &cloned
+}
+
+// Redacted 返回掩码后的副本（Webhook URL 可能携带 token，签名密钥同样敏感），用于管理 API 响应
+func (a *AlertConfig) Redacted() *AlertConfig {
+	cloned := a.Clone()
+	if cloned == nil {
+		return nil
+	}
+	for i := range cloned.Targets {
+		cloned.Targets[i].URL = utils.MaskAPIKey(cloned.Targets[i].URL)
+		cloned.Targets[i].Secret = utils.MaskAPIKey(cloned.Targets[i].Secret)
+	}
+	return cloned
+}
+
+// DedupWindow 去重窗口（秒），返回 0 表示不去重
+func (a *AlertConfig) DedupWindow() int {
+	switch {
+	case a.DedupSeconds < 0:
+		return 0
+	case a.DedupSeconds == 0:
+		return DefaultAlertDedupSeconds
+	default:
+		return a.DedupSeconds
+	}
+}
+
+// RateLimit 每个目标每分钟最多发送次数
+func (a *AlertConfig) RateLimit() int {
+	if a.RateLimitPerMinute <= 0 {
+		return DefaultAlertRateLimitPerMinute
+	}
+	return a.RateLimitPerMinute
+}
+
+// Validate 验证告警配置
+func (a *AlertConfig) Validate() error {
+	if a.DedupSeconds < -1 || a.DedupSeconds > maxAlertDedupSeconds {
+		return &ConfigError{Message: fmt.Sprintf("无效的去重窗口: %d（有效范围 -1-%d 秒）", a.DedupSeconds, maxAlertDedupSeconds)}
+	}
+	if a.RateLimitPerMinute < 0 || a.RateLimitPerMinute > maxAlertRateLimitPerMinute {
+		return &ConfigError{Message: fmt.Sprintf("无效的发送频率限制: %d（有效范围 0-%d）", a.RateLimitPerMinute, maxAlertRateLimitPerMinute)}
+	}
+	if len(a.Targets) > maxAlertTargets {
+		return &ConfigError{Message: fmt.Sprintf("告警目标过多（最多 %d 个）", maxAlertTargets)}
+	}
+
+	names := make(map[string]bool, len(a.Targets))
+	for i, t := range a.Targets {
+		name := strings.TrimSpace(t.Name)
+		if name == "" {
+			return &ConfigError{Message: fmt.Sprintf("第 %d 个告警目标缺少名称", i+1)}
+		}
+		if names[name] {
+			return &ConfigError{Message: "告警目标名称重复: " + name}
+		}
+		names[name] = true
+
+		switch t.Type {
+		case AlertTargetGeneric, AlertTargetSlack, AlertTargetFeishu, AlertTargetDingTalk:
+		default:
+			return &ConfigError{Message: fmt.Sprintf("告警目标 %s 类型无效: %s", name, t.Type)}
+		}
+		u, err := url.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &ConfigError{Message: fmt.Sprintf("告警目标 %s 的 URL 无效: %s", name, t.URL)}
+		}
+		for _, e := range t.Events {
+			if !isAlertEventType(e) {
+				return &ConfigError{Message: fmt.Sprintf("告警目标 %s 订阅了未知事件: %s", name, e)}
+			}
+		}
+	}
+	return nil
+}
+
+func isAlertEventType(eventType string) bool {
+	for _, e := range AlertEventTypes {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAlertConfig 获取告警配置（未配置时返回默认值：关闭、无目标）
+func (cm *ConfigManager) GetAlertConfig() *AlertConfig {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.config.Alerts == nil {
+		return &AlertConfig{}
+	}
+	return cm.config.Alerts.Clone()
+}
+
+// SetAlertConfig 整体替换告警配置
+func (cm *ConfigManager) SetAlertConfig(alerts *AlertConfig) error {
+	if alerts == nil {
+		return &ConfigError{Message: "告警配置不能为空"}
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	alerts = alerts.Clone()
+	for i := range alerts.Targets {
+		resolved, err := cm.resolveAlertTargetLocked(alerts.Targets[i])
+		if err != nil {
+			return err
+		}
+		alerts.Targets[i] = resolved
+	}
+	if err := alerts.Validate(); err != nil {
+		return err
+	}
+
+	cm.config.Alerts = alerts
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+
+	status := "关闭"
+	if alerts.Enabled {
+		status = "启用"
+	}
+	log.Printf("[Config-Alerts] 告警通知已%s（%d 个目标）", status, len(alerts.Targets))
+	return nil
+}
+
+// ResolveAlertTarget 将目标中的掩码 URL/密钥还原为已保存同名目标的原值
+func (cm *ConfigManager) ResolveAlertTarget(target AlertTarget) (AlertTarget, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.resolveAlertTargetLocked(target)
+}
+
+// resolveAlertTargetLocked 前端回传的是 Redacted 掩码值时，按名称找回已保存的原值（调用方需持有锁）
+func (cm *ConfigManager) resolveAlertTargetLocked(target AlertTarget) (AlertTarget, error) {
+	return resolveMaskedAlertTarget(cm.config.Alerts, target)
+}
+
+// resolveMaskedAlertTarget 将目标中的掩码 URL/密钥还原为 saved 中同名目标的原值，无法还原时返回 *ConfigError
+func resolveMaskedAlertTarget(saved *AlertConfig, target AlertTarget) (AlertTarget, error) {
+	if !strings.Contains(target.URL, "***") && !strings.Contains(target.Secret, "***") {
+		return target, nil
+	}
+
+	var savedTarget *AlertTarget
+	if saved != nil {
+		for i := range saved.Targets {
+			if saved.Targets[i].Name == target.Name {
+				savedTarget = &saved.Targets[i]
+				break
+			}
+		}
+	}
+
+	restore := func(field, ref, stored string) (string, error) {
+		if !strings.Contains(ref, "***") {
+			return ref, nil
+		}
+		if savedTarget == nil || stored == "" || utils.MaskAPIKey(stored) != ref {
+			return "", &ConfigError{Message: fmt.Sprintf("告警目标 %s 的%s为掩码值且无法还原，请重新填写", target.Name, field)}
+		}
+		return stored, nil
+	}
+
+	var savedURL, savedSecret string
+	if savedTarget != nil {
+		savedURL, savedSecret = savedTarget.URL, savedTarget.Secret
+	}
+	var err error
+	if target.URL, err = restore(" URL ", target.URL, savedURL); err != nil {
+		return target, err
+	}
+	if target.Secret, err = restore("签名密钥", target.Secret, savedSecret); err != nil {
+		return target, err
+	}
+	return target, nil
+}
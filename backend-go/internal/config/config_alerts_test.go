@@ -0,0 +1,48 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAlertConfig_MaskedValuesRoundTrip(t *testing.T) {
+	cm := newTestConfigManager(t)
+
+	const hookURL = "https://open.feishu.cn/open-apis/bot/v2/hook/0123456789abcdef"
+	const secret = "feishu-sign-secret-value"
+	if err := cm.SetAlertConfig(&AlertConfig{
+		Enabled: true,
+		Targets: []AlertTarget{{Name: "ops", Type: AlertTargetFeishu, URL: hookURL, Secret: secret}},
+	}); err != nil {
+		t.Fatalf("SetAlertConfig 失败: %v", err)
+	}
+
+	redacted := cm.GetAlertConfig().Redacted()
+	if got := redacted.Targets[0]; strings.Contains(got.URL, "0123456789") || strings.Contains(got.Secret, "sign-secret") {
+		t.Fatalf("Redacted 未掩码敏感字段: %+v", got)
+	}
+
+	// 前端原样回传掩码值，应保留已保存的原值
+	redacted.RateLimitPerMinute = 5
+	if err := cm.SetAlertConfig(redacted); err != nil {
+		t.Fatalf("回传掩码值保存失败: %v", err)
+	}
+	saved := cm.GetAlertConfig()
+	if saved.Targets[0].URL != hookURL || saved.Targets[0].Secret != secret {
+		t.Fatalf("掩码值未还原: %+v", saved.Targets[0])
+	}
+	if saved.RateLimitPerMinute != 5 {
+		t.Fatalf("RateLimitPerMinute = %d, want 5", saved.RateLimitPerMinute)
+	}
+
+	// 无法对应到已保存目标的掩码值应拒绝
+	renamed := cm.GetAlertConfig().Redacted()
+	renamed.Targets[0].Name = "other"
+	err := cm.SetAlertConfig(renamed)
+	if _, ok := err.(*ConfigError); !ok {
+		t.Fatalf("无法还原的掩码值应返回 ConfigError, got %v", err)
+	}
+	if cm.GetAlertConfig().Targets[0].Name != "ops" {
+		t.Fatal("失败的保存不应修改配置")
+	}
+}
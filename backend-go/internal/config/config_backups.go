@@ -0,0 +1,168 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============== 配置备份历史 ==============
+
+const backupTimeLayout = "2006-01-02T15-04-05"
+
+// backupNamePattern 备份文件名格式（由 backupConfig 生成），同时用于防止路径穿越
+var backupNamePattern = regexp.MustCompile(`^config-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.json$`)
+
+// ErrBackupNotFound 备份文件不存在
+var ErrBackupNotFound = errors.New("备份不存在")
+
+// BackupInfo 配置备份文件信息
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	Size      int64     `json:"size"`
+}
+
+// BackupDiff 备份与当前配置的结构化差异
+type BackupDiff struct {
+	Backup   string                `json:"backup"`
+	Channels []ConfigChannelChange `json:"channels"`
+	Settings []ConfigFieldChange   `json:"settings"`         // 渠道列表以外的顶层配置
+	Errors   []string              `json:"errors,omitempty"` // 备份未通过校验的问题（存在时无法恢复）
+}
+
+// backupDir 返回备份目录（与配置文件同级的 backups 目录）
+func (cm *ConfigManager) backupDir() string {
+	return filepath.Join(filepath.Dir(cm.configFile), "backups")
+}
+
+// ListBackups 列出配置备份（按时间倒序）
+func (cm *ConfigManager) ListBackups() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(cm.backupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []BackupInfo{}, nil
+		}
+		return nil, err
+	}
+
+	backups := make([]BackupInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !backupNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		createdAt, err := time.ParseInLocation(backupTimeLayout,
+			strings.TrimSuffix(strings.TrimPrefix(entry.Name(), "config-"), ".json"), time.Local)
+		if err != nil {
+			createdAt = info.ModTime()
+		}
+		backups = append(backups, BackupInfo{Name: entry.Name(), CreatedAt: createdAt, Size: info.Size()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Name > backups[j].Name })
+	return backups, nil
+}
+
+// readBackup 读取并解析备份文件
+func (cm *ConfigManager) readBackup(name string) ([]byte, Config, error) {
+	var backup Config
+	if !backupNamePattern.MatchString(name) {
+		return nil, backup, &ConfigError{Message: "无效的备份名称: " + name}
+	}
+
+	data, err := os.ReadFile(filepath.Join(cm.backupDir(), name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, backup, ErrBackupNotFound
+		}
+		return nil, backup, err
+	}
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, backup, &ConfigError{Message: fmt.Sprintf("备份文件解析失败: %v", err)}
+	}
+
+	cm.mu.RLock()
+	backup, _, err = cm.decryptConfigLocked(backup)
+	cm.mu.RUnlock()
+	if err != nil {
+		return nil, backup, err
+	}
+	return data, backup, nil
+}
+
+// DiffBackup 计算备份相对当前配置的差异（描述恢复该备份会带来的变化）
+func (cm *ConfigManager) DiffBackup(name string) (*BackupDiff, error) {
+	data, backup, err := cm.readBackup(name)
+	if err != nil {
+		return nil, err
+	}
+	current := cm.GetConfig()
+	backup = prepareRestoredConfig(data, backup, &current)
+
+	channels, settings := DiffConfigs(&current, &backup)
+	return &BackupDiff{Backup: name, Channels: channels, Settings: settings, Errors: validateRestoredConfig(&backup)}, nil
+}
+
+// RestoreBackup 将备份恢复为当前配置
+// 备份需通过与导入相同的校验，否则返回列出问题的 ConfigError；
+// 通过 saveConfigLocked 写入（恢复前的配置会先被备份，可再次回滚），文件监听随后按常规流程热重载
+func (cm *ConfigManager) RestoreBackup(name string) error {
+	data, backup, err := cm.readBackup(name)
+	if err != nil {
+		return err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	previous := cm.config
+	restored := prepareRestoredConfig(data, backup, &previous)
+	if errs := validateRestoredConfig(&restored); len(errs) > 0 {
+		return &ConfigError{Message: "备份配置校验失败: " + strings.Join(errs, "; ")}
+	}
+	cm.config = restored
+	cm.ensureChannelIDs()
+	// 已启用 Key 加密时，恢复旧的明文备份后仍保持加密
+	if previous.Encryption != nil {
+		cm.config.Encryption = previous.Encryption
+	}
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		cm.config = previous
+		return err
+	}
+
+	log.Printf("[Config-Backup] 已从备份恢复配置: %s", name)
+	return nil
+}
+
+// validateRestoredConfig 按导入配置的校验规则检查待恢复的备份，返回问题列表
+func validateRestoredConfig(cfg *Config) []string {
+	errs := validateImportedConfig(cfg)
+	return append(errs, validateImportedSettings(cfg)...)
+}
+
+// prepareRestoredConfig 按加载配置时的流程（默认值、旧格式迁移、Key 自检）处理备份内容
+// 旧备份中没有标识的渠道按名称沿用当前渠道的标识，保持统计与亲和记录的连续性
+func prepareRestoredConfig(data []byte, backup Config, current *Config) Config {
+	for _, kind := range channelKinds {
+		adoptChannelIDs(backup.UpstreamsForKind(kind), current.UpstreamsForKind(kind))
+	}
+
+	scratch := &ConfigManager{config: backup}
+	scratch.applyConfigDefaults(data)
+	scratch.migrateOldFormat()
+	scratch.validateChannelKeys()
+	return scratch.config
+}
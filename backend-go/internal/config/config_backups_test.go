@@ -0,0 +1,191 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testBackupName = "config-2024-01-02T03-04-05.json"
+
+// setupBackupTest 创建当前配置（渠道 a、b）与一个旧格式备份（a 换了 Key、新增 c、没有 b，且渠道没有标识）
+func setupBackupTest(t *testing.T) *ConfigManager {
+	t.Helper()
+	dir := t.TempDir()
+	current := `{
+		"upstream": [
+			{"id": "id-a", "name": "a", "baseUrl": "https://a.example.com", "apiKeys": ["sk-current-aaaaaaaaaaaa"], "status": "active"},
+			{"id": "id-b", "name": "b", "baseUrl": "https://b.example.com", "apiKeys": ["sk-current-bbbbbbbbbbbb"], "status": "active"}
+		],
+		"loadBalance": "failover",
+		"fuzzyModeEnabled": true
+	}`
+	backup := `{
+		"upstream": [
+			{"name": "a", "baseUrl": "https://a.example.com", "apiKeys": ["sk-backup-aaaaaaaaaaaa"], "status": "active"},
+			{"name": "c", "baseUrl": "https://c.example.com", "apiKeys": ["sk-backup-cccccccccccc"], "status": "active"}
+		],
+		"loadBalance": "round-robin",
+		"fuzzyModeEnabled": true
+	}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(current), 0644); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "backups"), 0755); err != nil {
+		t.Fatalf("创建备份目录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "backups", testBackupName), []byte(backup), 0644); err != nil {
+		t.Fatalf("写入备份失败: %v", err)
+	}
+
+	cm, err := NewConfigManager(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatalf("初始化配置管理器失败: %v", err)
+	}
+	t.Cleanup(func() { cm.Close() })
+	return cm
+}
+
+func TestListBackups(t *testing.T) {
+	cm := setupBackupTest(t)
+	if err := os.WriteFile(filepath.Join(cm.backupDir(), "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("写入无关文件失败: %v", err)
+	}
+
+	backups, err := cm.ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups 失败: %v", err)
+	}
+	if len(backups) != 1 || backups[0].Name != testBackupName || backups[0].Size == 0 {
+		t.Fatalf("unexpected backups: %+v", backups)
+	}
+	if got := backups[0].CreatedAt.Format(backupTimeLayout); got != "2024-01-02T03-04-05" {
+		t.Fatalf("CreatedAt = %s", got)
+	}
+}
+
+func TestDiffBackup(t *testing.T) {
+	cm := setupBackupTest(t)
+
+	diff, err := cm.DiffBackup(testBackupName)
+	if err != nil {
+		t.Fatalf("DiffBackup 失败: %v", err)
+	}
+
+	changes := map[string]ConfigChannelChange{}
+	for _, ch := range diff.Channels {
+		changes[ch.Name] = ch
+	}
+	if len(changes) != 3 {
+		t.Fatalf("unexpected channel changes: %+v", diff.Channels)
+	}
+	if a := changes["a"]; a.Change != ChannelChangeChanged || a.ID != "id-a" || len(a.Fields) != 1 || a.Fields[0].Field != "apiKeys" {
+		t.Fatalf("channel a should only differ in apiKeys (id adopted by name): %+v", a)
+	}
+	if changes["b"].Change != ChannelChangeRemoved || changes["c"].Change != ChannelChangeAdded {
+		t.Fatalf("unexpected b/c changes: %+v", diff.Channels)
+	}
+	// responsesLoadBalance 未配置时沿用 loadBalance，按恢复后的实际效果比较
+	if len(diff.Settings) != 2 || diff.Settings[0].Field != "loadBalance" || diff.Settings[1].Field != "responsesLoadBalance" {
+		t.Fatalf("unexpected settings diff: %+v", diff.Settings)
+	}
+
+	data, _ := json.Marshal(diff)
+	if strings.Contains(string(data), "sk-backup-aaaaaaaaaaaa") || strings.Contains(string(data), "sk-current-aaaaaaaaaaaa") {
+		t.Fatalf("API keys must be masked in diff: %s", data)
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	cm := setupBackupTest(t)
+
+	if err := cm.RestoreBackup(testBackupName); err != nil {
+		t.Fatalf("RestoreBackup 失败: %v", err)
+	}
+
+	cfg := cm.GetConfig()
+	if len(cfg.Upstream) != 2 || cfg.Upstream[0].Name != "a" || cfg.Upstream[1].Name != "c" {
+		t.Fatalf("unexpected upstreams after restore: %+v", cfg.Upstream)
+	}
+	if cfg.Upstream[0].ID != "id-a" || cfg.Upstream[1].ID == "" {
+		t.Fatalf("restored channels should keep/receive IDs: %q, %q", cfg.Upstream[0].ID, cfg.Upstream[1].ID)
+	}
+	if cfg.Upstream[0].APIKeys[0] != "sk-backup-aaaaaaaaaaaa" || cfg.LoadBalance != "round-robin" {
+		t.Fatalf("backup content not restored: %+v", cfg)
+	}
+
+	// 写入配置文件，并在恢复前备份了当前配置（可再次回滚）
+	data, err := os.ReadFile(cm.configFile)
+	if err != nil {
+		t.Fatalf("读取配置文件失败: %v", err)
+	}
+	var saved Config
+	if err := json.Unmarshal(data, &saved); err != nil || len(saved.Upstream) != 2 || saved.Upstream[1].Name != "c" {
+		t.Fatalf("restored config should be persisted: %s", data)
+	}
+	backups, _ := cm.ListBackups()
+	if len(backups) != 2 {
+		t.Fatalf("restore should back up the previous config, got %+v", backups)
+	}
+}
+
+func TestRestoreBackup_RejectsInvalidBackup(t *testing.T) {
+	cm := setupBackupTest(t)
+
+	const invalidName = "config-2024-02-03T04-05-06.json"
+	invalid := `{
+		"upstream": [
+			{"name": "a", "baseUrl": "ftp://a.example.com", "apiKeys": ["sk-backup-aaaaaaaaaaaa"], "status": "active", "weight": -1}
+		],
+		"loadBalance": "fastest"
+	}`
+	if err := os.WriteFile(filepath.Join(cm.backupDir(), invalidName), []byte(invalid), 0644); err != nil {
+		t.Fatalf("写入备份失败: %v", err)
+	}
+
+	diff, err := cm.DiffBackup(invalidName)
+	if err != nil {
+		t.Fatalf("DiffBackup 失败: %v", err)
+	}
+	// responsesLoadBalance 未配置时沿用 loadBalance，按补默认值后的结果校验
+	if len(diff.Errors) != 4 {
+		t.Fatalf("diff should list baseUrl, weight and load balance problems: %+v", diff.Errors)
+	}
+
+	before := cm.GetConfig()
+	var cfgErr *ConfigError
+	if err := cm.RestoreBackup(invalidName); !errors.As(err, &cfgErr) {
+		t.Fatalf("RestoreBackup error = %v, want ConfigError", err)
+	}
+	for _, problem := range diff.Errors {
+		if !strings.Contains(cfgErr.Error(), problem) {
+			t.Fatalf("restore error should list %q: %v", problem, cfgErr)
+		}
+	}
+	if after := cm.GetConfig(); len(after.Upstream) != len(before.Upstream) || after.LoadBalance != before.LoadBalance {
+		t.Fatalf("rejected restore must not change the config: %+v", after)
+	}
+	if backups, _ := cm.ListBackups(); len(backups) != 2 {
+		t.Fatalf("rejected restore must not back up the current config, got %+v", backups)
+	}
+}
+
+func TestBackupNameValidation(t *testing.T) {
+	cm := setupBackupTest(t)
+
+	var cfgErr *ConfigError
+	for _, name := range []string{"../config.json", "config.json", "config-2024-01-02T03-04-05.json/../x"} {
+		if _, err := cm.DiffBackup(name); !errors.As(err, &cfgErr) {
+			t.Fatalf("DiffBackup(%q) error = %v, want ConfigError", name, err)
+		}
+		if err := cm.RestoreBackup(name); !errors.As(err, &cfgErr) {
+			t.Fatalf("RestoreBackup(%q) error = %v, want ConfigError", name, err)
+		}
+	}
+	if _, err := cm.DiffBackup("config-1999-01-01T00-00-00.json"); !errors.Is(err, ErrBackupNotFound) {
+		t.Fatalf("missing backup error = %v, want ErrBackupNotFound", err)
+	}
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ============== 中转渠道余额查询配置 ==============
+
+// 余额查询方式
+const (
+	BalanceCheckOpenAIBilling = "openai-billing" // /v1/dashboard/billing/subscription + usage（one-api/new-api 兼容）
+	BalanceCheckNewAPIToken   = "newapi-token"   // new-api /api/usage/token
+	BalanceCheckCustom        = "custom"         // 自定义地址 + 响应字段路径
+)
+
+// 余额查询默认参数
+const (
+	DefaultBalanceCheckInterval = 3600   // 查询周期（秒）
+	DefaultBalanceQuotaPerUnit  = 500000 // new-api 额度单位换算：500000 额度 = 1 USD
+	minBalanceCheckInterval     = 300
+)
+
+// BalanceCheckConfig 单个渠道的余额查询配置（按 Key 查询剩余额度）
+type BalanceCheckConfig struct {
+	Enabled bool   `json:"enabled"`
+	Type    string `json:"type,omitempty"` // openai-billing（默认）/newapi-token/custom
+	// 查询地址：openai-billing/newapi-token 为站点根地址，为空时由渠道 BaseURL 推导；custom 为完整地址（必填）
+	URL string `json:"url,omitempty"`
+	// custom：响应 JSON 中剩余额度字段路径（以 . 分隔，如 data.balance）
+	RemainingField string `json:"remainingField,omitempty"`
+	// 额度单位换算（原始值 / QuotaPerUnit = USD）；newapi-token 默认 500000，custom 默认 1
+	QuotaPerUnit    float64 `json:"quotaPerUnit,omitempty"`
+	IntervalSeconds int     `json:"intervalSeconds,omitempty"` // 查询周期，默认 3600 秒，最小 300 秒
+	Threshold       float64 `json:"threshold,omitempty"`       // 剩余额度（USD）低于该值时降低 Key 优先级；0 表示不处理
+}
+
+// Clone 拷贝余额查询配置
+func (b *BalanceCheckConfig) Clone() *BalanceCheckConfig {
+	if b == nil {
+		return nil
+	}
+	cloned := *b
+	return &cloned
+}
+
+// CheckType 查询方式（未配置时为 openai-billing）
+func (b *BalanceCheckConfig) CheckType() string {
+	if b.Type == "" {
+		return BalanceCheckOpenAIBilling
+	}
+	return b.Type
+}
+
+// Interval 查询周期（秒）
+func (b *BalanceCheckConfig) Interval() int {
+	if b.IntervalSeconds <= 0 {
+		return DefaultBalanceCheckInterval
+	}
+	return b.IntervalSeconds
+}
+
+// UnitDivisor 原始额度换算为 USD 的除数
+func (b *BalanceCheckConfig) UnitDivisor() float64 {
+	if b.QuotaPerUnit > 0 {
+		return b.QuotaPerUnit
+	}
+	if b.CheckType() == BalanceCheckNewAPIToken {
+		return DefaultBalanceQuotaPerUnit
+	}
+	return 1
+}
+
+// validateBalanceCheck 验证渠道余额查询配置
+func validateBalanceCheck(b *BalanceCheckConfig) error {
+	if b == nil {
+		return nil
+	}
+	switch b.CheckType() {
+	case BalanceCheckOpenAIBilling, BalanceCheckNewAPIToken:
+	case BalanceCheckCustom:
+		if b.URL == "" || strings.TrimSpace(b.RemainingField) == "" {
+			return &ConfigError{Message: "custom 余额查询需要配置 url 与 remainingField"}
+		}
+	default:
+		return &ConfigError{Message: "无效的余额查询方式: " + b.Type}
+	}
+	if b.URL != "" {
+		u, err := url.Parse(b.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &ConfigError{Message: "无效的余额查询地址: " + b.URL}
+		}
+	}
+	if b.IntervalSeconds != 0 && b.IntervalSeconds < minBalanceCheckInterval {
+		return &ConfigError{Message: fmt.Sprintf("余额查询周期不能小于 %d 秒", minBalanceCheckInterval)}
+	}
+	if b.QuotaPerUnit < 0 || b.Threshold < 0 {
+		return &ConfigError{Message: "余额查询的额度换算与阈值不能为负数"}
+	}
+	return nil
+}
@@ -79,7 +79,7 @@ func TestUpdateUpstream_BaseURLConsistency(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := cm.UpdateUpstream(0, tt.updates)
+			_, err := cm.UpdateUpstream(cm.GetConfig().Upstream[0].ID, tt.updates)
 			if err != nil {
 				t.Fatalf("UpdateUpstream 失败: %v", err)
 			}
@@ -138,7 +138,7 @@ func TestUpdateResponsesUpstream_BaseURLConsistency(t *testing.T) {
 
 	// 测试：只更新 baseUrl 时 baseUrls 应被清空
 	t.Run("只更新 baseUrl 时 baseUrls 应被清空", func(t *testing.T) {
-		_, err := cm.UpdateResponsesUpstream(0, UpstreamUpdate{
+		_, err := cm.UpdateResponsesUpstream(cm.GetConfig().ResponsesUpstream[0].ID, UpstreamUpdate{
 			BaseURL: strPtr("https://new.responses.com"),
 		})
 		if err != nil {
@@ -185,7 +185,7 @@ func TestUpdateGeminiUpstream_BaseURLConsistency(t *testing.T) {
 
 	// 测试：只更新 baseUrl 时 baseUrls 应被清空
 	t.Run("只更新 baseUrl 时 baseUrls 应被清空", func(t *testing.T) {
-		_, err := cm.UpdateGeminiUpstream(0, UpstreamUpdate{
+		_, err := cm.UpdateGeminiUpstream(cm.GetConfig().GeminiUpstream[0].ID, UpstreamUpdate{
 			BaseURL: strPtr("https://new.gemini.com"),
 		})
 		if err != nil {
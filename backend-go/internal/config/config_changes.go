@@ -0,0 +1,54 @@
+package config
+
+// ============== 配置变更订阅 ==============
+//
+// 每次成功持久化配置（以及配置文件热重载）后，以变更前后的配置快照通知订阅者。
+// 后台自动操作（健康探测恢复、Key 永久禁用、Key 降级、热重载）标记来源，
+// 使审计日志能区分管理操作与系统操作，而不依赖请求期间的全局快照。
+
+// 系统变更来源
+const (
+	ChangeSourceHealthProbe     = "health-probe"     // 健康探测自动恢复渠道
+	ChangeSourceKeyAutoDisable  = "key-auto-disable" // 上游返回 Key 永久失效，自动禁用
+	ChangeSourceKeyDeprioritize = "key-deprioritize" // 额度不足/余额不足，Key 自动降级
+	ChangeSourceConfigFile      = "config-file"      // 配置文件被外部修改后热重载
+)
+
+// ConfigChange 一次配置变更
+type ConfigChange struct {
+	Before Config
+	After  Config
+	Source string // 系统变更来源；为空表示由管理操作触发
+}
+
+// ConfigChangeHook 配置变更回调
+// 在持有配置锁时同步调用，实现不得回调 ConfigManager，且应尽快返回。
+type ConfigChangeHook func(change ConfigChange)
+
+// SetChangeHook 订阅配置变更（传入 nil 取消订阅）
+func (cm *ConfigManager) SetChangeHook(hook ConfigChangeHook) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.changeHook = hook
+	if hook != nil {
+		cm.savedConfig = cloneConfig(&cm.config)
+	}
+}
+
+// notifyChangeLocked 以最近一次快照为 before、当前配置为 after 通知订阅者（需持有锁）
+func (cm *ConfigManager) notifyChangeLocked() {
+	if cm.changeHook == nil {
+		return
+	}
+	after := cloneConfig(&cm.config)
+	before := cm.savedConfig
+	cm.savedConfig = after
+	cm.changeHook(ConfigChange{Before: before, After: cloneConfig(&after), Source: cm.changeSource})
+}
+
+// useChangeSourceLocked 将后续持锁变更标记为指定来源，返回恢复函数（需持有锁，通常配合 defer 使用）
+func (cm *ConfigManager) useChangeSourceLocked(source string) (restore func()) {
+	previous := cm.changeSource
+	cm.changeSource = source
+	return func() { cm.changeSource = previous }
+}
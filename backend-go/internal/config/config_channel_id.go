@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ============== 渠道唯一标识 ==============
+
+// allUpstreamListsLocked 返回所有入口的渠道列表（用于跨入口检查标识唯一性，需持有锁）
+func (cm *ConfigManager) allUpstreamListsLocked() map[string][]UpstreamConfig {
+	return map[string][]UpstreamConfig{
+		"Messages":  cm.config.Upstream,
+		"Responses": cm.config.ResponsesUpstream,
+		"Gemini":    cm.config.GeminiUpstream,
+		"Chat":      cm.config.ChatUpstream,
+	}
+}
+
+// newChannelIDLocked 为新渠道分配标识：沿用未被占用的请求标识，否则生成新的 UUID（需持有锁）
+func (cm *ConfigManager) newChannelIDLocked(requested string) string {
+	requested = strings.TrimSpace(requested)
+	if requested == "" {
+		return uuid.NewString()
+	}
+	for _, upstreams := range cm.allUpstreamListsLocked() {
+		for i := range upstreams {
+			if upstreams[i].ID == requested {
+				return uuid.NewString()
+			}
+		}
+	}
+	return requested
+}
+
+// ensureChannelIDs 为缺少标识或标识重复的渠道分配 UUID，返回是否有修改（需持有锁）
+func (cm *ConfigManager) ensureChannelIDs() bool {
+	seen := make(map[string]bool)
+	assigned := 0
+	// 固定顺序遍历，保证重复标识时保留先出现的渠道
+	for _, name := range []string{"Messages", "Responses", "Gemini", "Chat"} {
+		upstreams := cm.allUpstreamListsLocked()[name]
+		for i := range upstreams {
+			id := strings.TrimSpace(upstreams[i].ID)
+			if id == "" || seen[id] {
+				id = uuid.NewString()
+				assigned++
+				log.Printf("[Config-Migration] %s 渠道 [%d] %s 已分配标识: %s", name, i, upstreams[i].Name, id)
+			}
+			upstreams[i].ID = id
+			seen[id] = true
+		}
+	}
+	return assigned > 0
+}
+
+// ResolveChannelIndex 将路由中的渠道引用解析为当前索引
+// ref 优先按渠道标识（UUID）匹配，未匹配时兼容旧的数字索引
+// 注意：返回的索引在释放锁后可能因排序/删除而失效，修改渠道请使用 ResolveChannelID
+func (cm *ConfigManager) ResolveChannelIndex(kind, ref string) (int, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	return cm.resolveChannelRefLocked(kind, ref)
+}
+
+// ResolveChannelID 将路由中的渠道引用（标识或旧的数字索引）解析为渠道标识
+func (cm *ConfigManager) ResolveChannelID(kind, ref string) (string, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	index, err := cm.resolveChannelRefLocked(kind, ref)
+	if err != nil {
+		return "", err
+	}
+	return cm.config.UpstreamsForKind(kind)[index].ID, nil
+}
+
+// resolveChannelRefLocked 按标识或数字索引查找渠道（需持有锁）
+func (cm *ConfigManager) resolveChannelRefLocked(kind, ref string) (int, error) {
+	if index, err := cm.channelIndexLocked(kind, ref); err == nil {
+		return index, nil
+	}
+	upstreams := cm.config.UpstreamsForKind(kind)
+	if index, err := strconv.Atoi(ref); err == nil {
+		if index < 0 || index >= len(upstreams) {
+			return -1, fmt.Errorf("无效的上游索引: %d", index)
+		}
+		return index, nil
+	}
+	return -1, fmt.Errorf("未找到渠道: %s", ref)
+}
+
+// channelIndexLocked 按渠道标识查找当前索引（需持有锁）
+// 修改渠道的方法在同一临界区内解析标识，避免并发排序/删除使修改落到其他渠道
+func (cm *ConfigManager) channelIndexLocked(kind, channelID string) (int, error) {
+	if index := cm.config.ChannelIndexByID(kind, channelID); index >= 0 {
+		return index, nil
+	}
+	return -1, fmt.Errorf("未找到渠道: %s", channelID)
+}
+
+// GetChannelIndexByID 按渠道标识查找当前索引，未找到返回 -1
+func (cm *ConfigManager) GetChannelIndexByID(kind, id string) int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	return cm.config.ChannelIndexByID(kind, id)
+}
+
+// ChannelIndexByID 在配置快照中按渠道标识查找索引，未找到返回 -1
+// 只读场景先取 GetConfig 快照再查找，保证索引与快照中的渠道一致
+func (c *Config) ChannelIndexByID(kind, id string) int {
+	if id == "" {
+		return -1
+	}
+	upstreams := c.UpstreamsForKind(kind)
+	for i := range upstreams {
+		if upstreams[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureChannelIDs_Migration(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	raw := `{
+		"upstream": [
+			{"name": "a", "baseUrl": "https://a.example.com", "apiKeys": ["k1"], "status": "active"},
+			{"id": "fixed-id", "name": "b", "baseUrl": "https://b.example.com", "apiKeys": ["k2"], "status": "active"}
+		],
+		"chatUpstream": [
+			{"id": "fixed-id", "name": "dup", "baseUrl": "https://c.example.com", "apiKeys": ["k3"], "status": "active"}
+		]
+	}`
+	if err := os.WriteFile(configPath, []byte(raw), 0644); err != nil {
+		t.Fatalf("写入初始配置失败: %v", err)
+	}
+	cm, err := NewConfigManager(configPath)
+	if err != nil {
+		t.Fatalf("初始化配置管理器失败: %v", err)
+	}
+	defer cm.Close()
+
+	cfg := cm.GetConfig()
+	if cfg.Upstream[0].ID == "" || cfg.Upstream[1].ID != "fixed-id" {
+		t.Fatalf("unexpected messages IDs: %q, %q", cfg.Upstream[0].ID, cfg.Upstream[1].ID)
+	}
+	if id := cfg.ChatUpstream[0].ID; id == "" || id == "fixed-id" {
+		t.Fatalf("duplicate ID should be replaced, got %q", id)
+	}
+
+	// 迁移结果已写回配置文件
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("读取配置文件失败: %v", err)
+	}
+	var saved Config
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("解析配置文件失败: %v", err)
+	}
+	if saved.Upstream[0].ID != cfg.Upstream[0].ID || saved.ChatUpstream[0].ID != cfg.ChatUpstream[0].ID {
+		t.Fatal("assigned IDs should be persisted")
+	}
+}
+
+func TestResolveChannelIndex(t *testing.T) {
+	cm := newTestConfigManager(t)
+	for _, name := range []string{"a", "b", "c"} {
+		if err := cm.AddUpstream(UpstreamConfig{Name: name, ServiceType: "claude", BaseURL: "https://" + name + ".example.com", APIKeys: []string{"k"}}); err != nil {
+			t.Fatalf("AddUpstream 失败: %v", err)
+		}
+	}
+	cfg := cm.GetConfig()
+	idC := cfg.Upstream[2].ID
+	if idC == "" || idC == cfg.Upstream[0].ID {
+		t.Fatalf("AddUpstream should assign unique IDs: %+v", cfg.Upstream)
+	}
+
+	if idx, err := cm.ResolveChannelIndex("messages", idC); err != nil || idx != 2 {
+		t.Fatalf("ResolveChannelIndex(id) = %d, %v; want 2", idx, err)
+	}
+	// 兼容旧的数字索引
+	if idx, err := cm.ResolveChannelIndex("messages", "1"); err != nil || idx != 1 {
+		t.Fatalf("ResolveChannelIndex(\"1\") = %d, %v; want 1", idx, err)
+	}
+	if _, err := cm.ResolveChannelIndex("messages", "5"); err == nil {
+		t.Fatal("out of range index should fail")
+	}
+	if _, err := cm.ResolveChannelIndex("chat", idC); err == nil {
+		t.Fatal("ID of another kind should not resolve")
+	}
+
+	// 删除前面的渠道后，标识仍指向同一渠道
+	if _, err := cm.RemoveUpstream(cfg.Upstream[0].ID); err != nil {
+		t.Fatalf("RemoveUpstream 失败: %v", err)
+	}
+	if idx, err := cm.ResolveChannelIndex("messages", idC); err != nil || idx != 1 {
+		t.Fatalf("after removal ResolveChannelIndex(id) = %d, %v; want 1", idx, err)
+	}
+	if idx := cm.GetChannelIndexByID("messages", idC); idx != 1 {
+		t.Fatalf("GetChannelIndexByID = %d, want 1", idx)
+	}
+
+	// 更新渠道不改变标识
+	name := "renamed"
+	if _, err := cm.UpdateUpstream(idC, UpstreamUpdate{Name: &name}); err != nil {
+		t.Fatalf("UpdateUpstream 失败: %v", err)
+	}
+	if got := cm.GetConfig().Upstream[1]; got.ID != idC || got.Name != name {
+		t.Fatalf("update should land on channel %q, got %+v", idC, got)
+	}
+
+	// 修改类方法按标识定位渠道，旧的数字索引需先经 ResolveChannelID 转换
+	if id, err := cm.ResolveChannelID("messages", "1"); err != nil || id != idC {
+		t.Fatalf("ResolveChannelID(\"1\") = %q, %v; want %q", id, err, idC)
+	}
+	if err := cm.SetChannelStatus("1", "suspended"); err == nil {
+		t.Fatal("mutators should not accept numeric indexes")
+	}
+}
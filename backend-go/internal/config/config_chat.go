@@ -0,0 +1,530 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+)
+
+// ============== Chat 渠道方法 ==============
+
+// GetCurrentChatUpstream 获取当前 Chat 上游配置
+// 优先选择第一个 active 状态的渠道，若无则回退到第一个渠道
+func (cm *ConfigManager) GetCurrentChatUpstream() (*UpstreamConfig, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if len(cm.config.ChatUpstream) == 0 {
+		return nil, fmt.Errorf("未配置任何 Chat 渠道")
+	}
+
+	// 优先选择第一个 active 状态的渠道
+	for i := range cm.config.ChatUpstream {
+		status := cm.config.ChatUpstream[i].Status
+		if status == "" || status == "active" {
+			return &cm.config.ChatUpstream[i], nil
+		}
+	}
+
+	// 没有 active 渠道，回退到第一个渠道
+	return &cm.config.ChatUpstream[0], nil
+}
+
+// AddChatUpstream 添加 Chat 上游
+func (cm *ConfigManager) AddChatUpstream(upstream UpstreamConfig) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if err := validateSupportedModels(upstream.SupportedModels); err != nil {
+		return err
+	}
+	if err := validateWeight(upstream.Weight); err != nil {
+		return err
+	}
+	if err := validateStreamIdleTimeout(upstream.StreamIdleTimeout); err != nil {
+		return err
+	}
+	if err := validateStreamFirstContentTimeout(upstream.StreamFirstContentTimeout); err != nil {
+		return err
+	}
+	if err := validateBalanceCheck(upstream.BalanceCheck); err != nil {
+		return err
+	}
+
+	// 新建渠道默认设为 active
+	if upstream.Status == "" {
+		upstream.Status = "active"
+	}
+
+	// 分配渠道唯一标识
+	upstream.ID = cm.newChannelIDLocked(upstream.ID)
+
+	// 去重 API Keys 和 Base URLs
+	upstream.APIKeys = deduplicateStrings(upstream.APIKeys)
+	upstream.BaseURLs = deduplicateBaseURLs(upstream.BaseURLs)
+
+	cm.config.ChatUpstream = append(cm.config.ChatUpstream, upstream)
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+
+	log.Printf("[Config-Upstream] 已添加 Chat 上游: %s", upstream.Name)
+	return nil
+}
+
+// UpdateChatUpstream 更新 Chat 上游
+// 返回值：shouldResetMetrics 表示是否需要重置渠道指标（熔断状态）
+func (cm *ConfigManager) UpdateChatUpstream(channelID string, updates UpstreamUpdate) (shouldResetMetrics bool, err error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	index, err := cm.channelIndexLocked("chat", channelID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := validateSupportedModels(updates.SupportedModels); err != nil {
+		return false, err
+	}
+	if updates.Weight != nil {
+		if err := validateWeight(*updates.Weight); err != nil {
+			return false, err
+		}
+	}
+	if updates.StreamIdleTimeout != nil {
+		if err := validateStreamIdleTimeout(*updates.StreamIdleTimeout); err != nil {
+			return false, err
+		}
+	}
+	if updates.StreamFirstContentTimeout != nil {
+		if err := validateStreamFirstContentTimeout(*updates.StreamFirstContentTimeout); err != nil {
+			return false, err
+		}
+	}
+	if err := validateBalanceCheck(updates.BalanceCheck); err != nil {
+		return false, err
+	}
+
+	upstream := &cm.config.ChatUpstream[index]
+
+	if updates.Name != nil {
+		upstream.Name = *updates.Name
+	}
+	if updates.BaseURL != nil {
+		upstream.BaseURL = *updates.BaseURL
+		// 当 BaseURL 被更新且 BaseURLs 未被显式设置时，清空 BaseURLs 保持一致性
+		// 避免出现 baseUrl 和 baseUrls[0] 不一致的情况
+		if updates.BaseURLs == nil {
+			upstream.BaseURLs = nil
+		}
+	}
+	if updates.BaseURLs != nil {
+		upstream.BaseURLs = deduplicateBaseURLs(updates.BaseURLs)
+	}
+	if updates.ServiceType != nil {
+		upstream.ServiceType = *updates.ServiceType
+	}
+	if updates.Description != nil {
+		upstream.Description = *updates.Description
+	}
+	if updates.Website != nil {
+		upstream.Website = *updates.Website
+	}
+	if updates.APIKeys != nil {
+		// 记录被移除的 Key 到历史列表（用于统计聚合）
+		newKeys := make(map[string]bool)
+		for _, key := range updates.APIKeys {
+			newKeys[key] = true
+		}
+
+		// 找出被移除的 Key（在旧列表中但不在新列表中）
+		for _, key := range upstream.APIKeys {
+			if !newKeys[key] {
+				// 检查是否已在历史列表中
+				alreadyInHistory := false
+				for _, hk := range upstream.HistoricalAPIKeys {
+					if hk == key {
+						alreadyInHistory = true
+						break
+					}
+				}
+				if !alreadyInHistory {
+					upstream.HistoricalAPIKeys = append(upstream.HistoricalAPIKeys, key)
+					log.Printf("[Config-Upstream] Chat 渠道 [%s] %s: Key %s 已移入历史列表", channelID, upstream.Name, utils.MaskAPIKey(key))
+				}
+			}
+		}
+
+		// 如果新 Key 在历史列表中，从历史列表移除（换回来了）
+		var newHistoricalKeys []string
+		for _, hk := range upstream.HistoricalAPIKeys {
+			if !newKeys[hk] {
+				newHistoricalKeys = append(newHistoricalKeys, hk)
+			} else {
+				log.Printf("[Config-Upstream] Chat 渠道 [%s] %s: Key %s 已从历史列表恢复", channelID, upstream.Name, utils.MaskAPIKey(hk))
+			}
+		}
+		upstream.HistoricalAPIKeys = newHistoricalKeys
+		upstream.removeDisabledKeys(newKeys)
+
+		// 只有单 key 场景且 key 被更换时，才自动激活并重置熔断
+		if len(upstream.APIKeys) == 1 && len(updates.APIKeys) == 1 &&
+			upstream.APIKeys[0] != updates.APIKeys[0] {
+			shouldResetMetrics = true
+			if upstream.Status == "suspended" {
+				upstream.Status = "active"
+				log.Printf("[Config-Upstream] Chat 渠道 [%s] %s 已从暂停状态自动激活（单 key 更换）", channelID, upstream.Name)
+			}
+		}
+		upstream.APIKeys = deduplicateStrings(updates.APIKeys)
+	}
+	if updates.ModelMapping != nil {
+		upstream.ModelMapping = updates.ModelMapping
+	}
+	if updates.SupportedModels != nil {
+		upstream.SupportedModels = deduplicateStrings(updates.SupportedModels)
+	}
+	if updates.AutoDiscoverModels != nil {
+		upstream.AutoDiscoverModels = *updates.AutoDiscoverModels
+	}
+	if updates.InsecureSkipVerify != nil {
+		upstream.InsecureSkipVerify = *updates.InsecureSkipVerify
+	}
+	if updates.Priority != nil {
+		upstream.Priority = *updates.Priority
+	}
+	if updates.Status != nil {
+		upstream.Status = *updates.Status
+	}
+	if updates.PromotionUntil != nil {
+		upstream.PromotionUntil = updates.PromotionUntil
+	}
+	if updates.LowQuality != nil {
+		upstream.LowQuality = *updates.LowQuality
+	}
+	if updates.Weight != nil {
+		upstream.Weight = *updates.Weight
+	}
+	if updates.StreamIdleTimeout != nil {
+		upstream.StreamIdleTimeout = *updates.StreamIdleTimeout
+	}
+	if updates.StreamFirstContentTimeout != nil {
+		upstream.StreamFirstContentTimeout = *updates.StreamFirstContentTimeout
+	}
+	if updates.BalanceCheck != nil {
+		upstream.BalanceCheck = updates.BalanceCheck.Clone()
+	}
+	if updates.InjectDummyThoughtSignature != nil {
+		upstream.InjectDummyThoughtSignature = *updates.InjectDummyThoughtSignature
+	}
+	if updates.StripThoughtSignature != nil {
+		upstream.StripThoughtSignature = *updates.StripThoughtSignature
+	}
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return false, err
+	}
+
+	log.Printf("[Config-Upstream] 已更新 Chat 上游: [%s] %s", channelID, cm.config.ChatUpstream[index].Name)
+	return shouldResetMetrics, nil
+}
+
+// RemoveChatUpstream 删除 Chat 上游
+func (cm *ConfigManager) RemoveChatUpstream(channelID string) (*UpstreamConfig, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	index, err := cm.channelIndexLocked("chat", channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := cm.config.ChatUpstream[index]
+	cm.config.ChatUpstream = append(cm.config.ChatUpstream[:index], cm.config.ChatUpstream[index+1:]...)
+
+	// 清理被删除渠道的失败 key 冷却记录
+	cm.clearFailedKeysForUpstream(&removed, "Chat")
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Config-Upstream] 已删除 Chat 上游: %s", removed.Name)
+	return &removed, nil
+}
+
+// AddChatAPIKey 添加 Chat 上游的 API 密钥
+func (cm *ConfigManager) AddChatAPIKey(channelID, apiKey string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	index, err := cm.channelIndexLocked("chat", channelID)
+	if err != nil {
+		return err
+	}
+
+	// 检查密钥是否已存在
+	for _, key := range cm.config.ChatUpstream[index].APIKeys {
+		if key == apiKey {
+			return fmt.Errorf("API密钥已存在")
+		}
+	}
+
+	cm.config.ChatUpstream[index].APIKeys = append(cm.config.ChatUpstream[index].APIKeys, apiKey)
+
+	// 如果该 Key 在历史列表中，从历史列表移除（换回来了）
+	var newHistoricalKeys []string
+	for _, hk := range cm.config.ChatUpstream[index].HistoricalAPIKeys {
+		if hk != apiKey {
+			newHistoricalKeys = append(newHistoricalKeys, hk)
+		} else {
+			log.Printf("[Chat-Key] 上游 [%s] %s: Key %s 已从历史列表恢复", channelID, cm.config.ChatUpstream[index].Name, utils.MaskAPIKey(hk))
+		}
+	}
+	cm.config.ChatUpstream[index].HistoricalAPIKeys = newHistoricalKeys
+	cm.config.ChatUpstream[index].removeDisabledKeys(map[string]bool{apiKey: true})
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+
+	log.Printf("[Chat-Key] 已添加API密钥到 Chat 上游 [%s] %s", channelID, cm.config.ChatUpstream[index].Name)
+	return nil
+}
+
+// RemoveChatAPIKey 删除 Chat 上游的 API 密钥
+func (cm *ConfigManager) RemoveChatAPIKey(channelID, apiKey string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	index, err := cm.channelIndexLocked("chat", channelID)
+	if err != nil {
+		return err
+	}
+
+	// 查找并删除密钥
+	keys := cm.config.ChatUpstream[index].APIKeys
+	found := false
+	for i, key := range keys {
+		if key == apiKey {
+			cm.config.ChatUpstream[index].APIKeys = append(keys[:i], keys[i+1:]...)
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("API密钥不存在")
+	}
+
+	// 将被移除的 Key 添加到历史列表（用于统计聚合）
+	alreadyInHistory := false
+	for _, hk := range cm.config.ChatUpstream[index].HistoricalAPIKeys {
+		if hk == apiKey {
+			alreadyInHistory = true
+			break
+		}
+	}
+	if !alreadyInHistory {
+		cm.config.ChatUpstream[index].HistoricalAPIKeys = append(cm.config.ChatUpstream[index].HistoricalAPIKeys, apiKey)
+		log.Printf("[Chat-Key] 上游 [%s] %s: Key %s 已移入历史列表", channelID, cm.config.ChatUpstream[index].Name, utils.MaskAPIKey(apiKey))
+	}
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+
+	log.Printf("[Chat-Key] 已从 Chat 上游 [%s] %s 删除API密钥", channelID, cm.config.ChatUpstream[index].Name)
+	return nil
+}
+
+// GetNextChatAPIKey 获取下一个 Chat API 密钥（纯 failover 模式）
+func (cm *ConfigManager) GetNextChatAPIKey(upstream *UpstreamConfig, failedKeys map[string]bool) (string, error) {
+	return cm.GetNextAPIKey(upstream, failedKeys, "Chat")
+}
+
+// MoveChatAPIKeyToTop 将指定 Chat 渠道的 API 密钥移到最前面
+func (cm *ConfigManager) MoveChatAPIKeyToTop(channelID, apiKey string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	upstreamIndex, err := cm.channelIndexLocked("chat", channelID)
+	if err != nil {
+		return err
+	}
+
+	upstream := &cm.config.ChatUpstream[upstreamIndex]
+	index := -1
+	for i, key := range upstream.APIKeys {
+		if key == apiKey {
+			index = i
+			break
+		}
+	}
+
+	if index <= 0 {
+		return nil
+	}
+
+	upstream.APIKeys = append([]string{apiKey}, append(upstream.APIKeys[:index], upstream.APIKeys[index+1:]...)...)
+	return cm.saveConfigLocked(cm.config)
+}
+
+// MoveChatAPIKeyToBottom 将指定 Chat 渠道的 API 密钥移到最后面
+func (cm *ConfigManager) MoveChatAPIKeyToBottom(channelID, apiKey string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	upstreamIndex, err := cm.channelIndexLocked("chat", channelID)
+	if err != nil {
+		return err
+	}
+
+	upstream := &cm.config.ChatUpstream[upstreamIndex]
+	index := -1
+	for i, key := range upstream.APIKeys {
+		if key == apiKey {
+			index = i
+			break
+		}
+	}
+
+	if index == -1 || index == len(upstream.APIKeys)-1 {
+		return nil
+	}
+
+	upstream.APIKeys = append(upstream.APIKeys[:index], upstream.APIKeys[index+1:]...)
+	upstream.APIKeys = append(upstream.APIKeys, apiKey)
+	return cm.saveConfigLocked(cm.config)
+}
+
+// ReorderChatUpstreams 重新排序 Chat 渠道优先级
+// order 是渠道索引数组，按新的优先级顺序排列（只更新传入的渠道，支持部分排序）
+func (cm *ConfigManager) ReorderChatUpstreams(order []int) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if len(order) == 0 {
+		return fmt.Errorf("排序数组不能为空")
+	}
+
+	seen := make(map[int]bool)
+	for _, idx := range order {
+		if idx < 0 || idx >= len(cm.config.ChatUpstream) {
+			return fmt.Errorf("无效的渠道索引: %d", idx)
+		}
+		if seen[idx] {
+			return fmt.Errorf("重复的渠道索引: %d", idx)
+		}
+		seen[idx] = true
+	}
+
+	// 更新传入渠道的优先级（未传入的渠道保持原优先级不变）
+	// 注意：priority 从 1 开始，避免 omitempty 吞掉 0 值
+	for i, idx := range order {
+		cm.config.ChatUpstream[idx].Priority = i + 1
+	}
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+
+	log.Printf("[Config-Reorder] 已更新 Chat 渠道优先级顺序 (%d 个渠道)", len(order))
+	return nil
+}
+
+// SetChatChannelStatus 设置 Chat 渠道状态
+func (cm *ConfigManager) SetChatChannelStatus(channelID, status string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	index, err := cm.channelIndexLocked("chat", channelID)
+	if err != nil {
+		return err
+	}
+
+	// 状态值转为小写，支持大小写不敏感
+	status = strings.ToLower(status)
+	if status != "active" && status != "suspended" && status != "disabled" {
+		return fmt.Errorf("无效的状态: %s (允许值: active, suspended, disabled)", status)
+	}
+
+	cm.config.ChatUpstream[index].Status = status
+
+	// 暂停时清除促销期
+	if status == "suspended" && cm.config.ChatUpstream[index].PromotionUntil != nil {
+		cm.config.ChatUpstream[index].PromotionUntil = nil
+		log.Printf("[Config-Status] 已清除 Chat 渠道 [%s] %s 的促销期", channelID, cm.config.ChatUpstream[index].Name)
+	}
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+
+	log.Printf("[Config-Status] 已设置 Chat 渠道 [%s] %s 状态为: %s", channelID, cm.config.ChatUpstream[index].Name, status)
+	return nil
+}
+
+// SetChatChannelPromotion 设置 Chat 渠道促销期
+func (cm *ConfigManager) SetChatChannelPromotion(channelID string, duration time.Duration) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	index, err := cm.channelIndexLocked("chat", channelID)
+	if err != nil {
+		return err
+	}
+
+	if duration <= 0 {
+		cm.config.ChatUpstream[index].PromotionUntil = nil
+		log.Printf("[Config-Promotion] 已清除 Chat 渠道 [%s] %s 的促销期", channelID, cm.config.ChatUpstream[index].Name)
+	} else {
+		// 清除其他渠道的促销期（同一时间只允许一个促销渠道）
+		for i := range cm.config.ChatUpstream {
+			if i != index && cm.config.ChatUpstream[i].PromotionUntil != nil {
+				cm.config.ChatUpstream[i].PromotionUntil = nil
+			}
+		}
+		promotionEnd := time.Now().Add(duration)
+		cm.config.ChatUpstream[index].PromotionUntil = &promotionEnd
+		log.Printf("[Config-Promotion] 已设置 Chat 渠道 [%s] %s 进入促销期，截止: %s", channelID, cm.config.ChatUpstream[index].Name, promotionEnd.Format(time.RFC3339))
+	}
+
+	return cm.saveConfigLocked(cm.config)
+}
+
+// GetPromotedChatChannel 获取当前处于促销期的 Chat 渠道索引
+func (cm *ConfigManager) GetPromotedChatChannel() (int, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	for i, upstream := range cm.config.ChatUpstream {
+		if IsChannelInPromotion(&upstream) && GetChannelStatus(&upstream) == "active" {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// SetChatLoadBalance 设置 Chat 负载均衡策略
+func (cm *ConfigManager) SetChatLoadBalance(strategy string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if err := validateLoadBalanceStrategy(strategy); err != nil {
+		return err
+	}
+
+	cm.config.ChatLoadBalance = strategy
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+
+	log.Printf("[Config-LoadBalance] 已设置 Chat 负载均衡策略: %s", strategy)
+	return nil
+}
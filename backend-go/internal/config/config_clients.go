@@ -0,0 +1,392 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============== 多租户客户端密钥 ==============
+
+// clientSecretPrefix 自动生成的客户端密钥前缀
+const clientSecretPrefix = "sk-cp-"
+
+// ClientKey 代理访问客户端（多租户）
+// 只保存密钥的 SHA-256 摘要，明文仅在创建/轮换时返回一次。
+type ClientKey struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	KeyHash       string     `json:"keyHash"`                 // sha256(secret) 十六进制
+	KeyPrefix     string     `json:"keyPrefix,omitempty"`     // 明文前几位，仅用于展示
+	AllowedKinds  []string   `json:"allowedKinds,omitempty"`  // 允许的入口类型：messages/responses/gemini/chat，为空表示全部
+	AllowedModels []string   `json:"allowedModels,omitempty"` // 允许的模型（支持 * 通配符），为空表示全部
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+	Disabled      bool       `json:"disabled,omitempty"`
+	Description   string     `json:"description,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	// 配额限制（0 表示不限制）
+	DailyTokenLimit   int64 `json:"dailyTokenLimit,omitempty"`   // 每日 Token 上限（输入+输出）
+	MonthlyTokenLimit int64 `json:"monthlyTokenLimit,omitempty"` // 每月 Token 上限（输入+输出）
+	RPMLimit          int   `json:"rpmLimit,omitempty"`          // 每分钟请求数上限
+}
+
+// ClientKeyUpdate 用于部分更新 ClientKey
+type ClientKeyUpdate struct {
+	Name          *string    `json:"name"`
+	AllowedKinds  []string   `json:"allowedKinds"`
+	AllowedModels []string   `json:"allowedModels"`
+	ExpiresAt     *time.Time `json:"expiresAt"`
+	ClearExpiry   bool       `json:"clearExpiry"` // 为 true 时清除过期时间
+	Disabled      *bool      `json:"disabled"`
+	Description   *string    `json:"description"`
+	// 配额限制
+	DailyTokenLimit   *int64 `json:"dailyTokenLimit"`
+	MonthlyTokenLimit *int64 `json:"monthlyTokenLimit"`
+	RPMLimit          *int   `json:"rpmLimit"`
+}
+
+// Clone 深拷贝 ClientKey
+func (k *ClientKey) Clone() *ClientKey {
+	cloned := *k
+	if k.AllowedKinds != nil {
+		cloned.AllowedKinds = append([]string(nil), k.AllowedKinds...)
+	}
+	if k.AllowedModels != nil {
+		cloned.AllowedModels = append([]string(nil), k.AllowedModels...)
+	}
+	if k.ExpiresAt != nil {
+		t := *k.ExpiresAt
+		cloned.ExpiresAt = &t
+	}
+	return &cloned
+}
+
+// IsExpired 检查客户端密钥是否已过期
+func (k *ClientKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// AllowsKind 检查客户端是否允许访问指定入口类型
+func (k *ClientKey) AllowsKind(kind string) bool {
+	if len(k.AllowedKinds) == 0 {
+		return true
+	}
+	for _, allowed := range k.AllowedKinds {
+		if strings.EqualFold(allowed, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsModel 检查客户端是否允许使用指定模型（支持 path.Match 通配符）
+func (k *ClientKey) AllowsModel(model string) bool {
+	if len(k.AllowedModels) == 0 || model == "" {
+		return true
+	}
+	for _, pattern := range k.AllowedModels {
+		if pattern == model {
+			return true
+		}
+		if matched, err := path.Match(pattern, model); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// HasQuota 是否配置了任意配额限制
+func (k *ClientKey) HasQuota() bool {
+	return k.DailyTokenLimit > 0 || k.MonthlyTokenLimit > 0 || k.RPMLimit > 0
+}
+
+// validateClientLimits 校验配额限制（不允许负数）
+func validateClientLimits(daily, monthly int64, rpm int) error {
+	if daily < 0 || monthly < 0 || rpm < 0 {
+		return fmt.Errorf("无效的配额限制: 不能为负数")
+	}
+	return nil
+}
+
+// HashClientSecret 计算客户端密钥摘要
+func HashClientSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateClientSecret 生成随机客户端密钥
+func generateClientSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成客户端密钥失败: %w", err)
+	}
+	return clientSecretPrefix + hex.EncodeToString(buf), nil
+}
+
+// clientKeyPrefix 截取密钥展示前缀
+func clientKeyPrefix(secret string) string {
+	const visible = 10
+	if len(secret) <= visible {
+		return secret[:len(secret)/2] + "..."
+	}
+	return secret[:visible] + "..."
+}
+
+// validClientKinds 允许配置的入口类型
+var validClientKinds = map[string]bool{
+	"messages":  true,
+	"responses": true,
+	"gemini":    true,
+	"chat":      true,
+}
+
+// validateClientScopes 校验客户端的入口类型与模型通配符
+func validateClientScopes(kinds, models []string) error {
+	for _, kind := range kinds {
+		if !validClientKinds[strings.ToLower(kind)] {
+			return fmt.Errorf("无效的入口类型: %s (可选: messages, responses, gemini, chat)", kind)
+		}
+	}
+	for _, pattern := range models {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("无效的模型匹配规则: %s", pattern)
+		}
+	}
+	return nil
+}
+
+// findClientIndexLocked 按 ID 查找客户端（调用前需持有锁）
+func (cm *ConfigManager) findClientIndexLocked(id string) int {
+	for i := range cm.config.Clients {
+		if cm.config.Clients[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetClients 获取所有客户端（深拷贝）
+func (cm *ConfigManager) GetClients() []ClientKey {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	clients := make([]ClientKey, len(cm.config.Clients))
+	for i := range cm.config.Clients {
+		clients[i] = *cm.config.Clients[i].Clone()
+	}
+	return clients
+}
+
+// GetClient 按 ID 获取客户端
+func (cm *ConfigManager) GetClient(id string) (*ClientKey, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	idx := cm.findClientIndexLocked(id)
+	if idx < 0 {
+		return nil, fmt.Errorf("客户端不存在: %s", id)
+	}
+	return cm.config.Clients[idx].Clone(), nil
+}
+
+// AddClient 添加客户端
+// secret 为空时自动生成；返回创建后的客户端与明文密钥（仅此一次）
+func (cm *ConfigManager) AddClient(client ClientKey, secret string) (*ClientKey, string, error) {
+	client.Name = strings.TrimSpace(client.Name)
+	if client.Name == "" {
+		return nil, "", fmt.Errorf("客户端名称不能为空")
+	}
+	if err := validateClientScopes(client.AllowedKinds, client.AllowedModels); err != nil {
+		return nil, "", err
+	}
+	if err := validateClientLimits(client.DailyTokenLimit, client.MonthlyTokenLimit, client.RPMLimit); err != nil {
+		return nil, "", err
+	}
+
+	if secret == "" {
+		generated, err := generateClientSecret()
+		if err != nil {
+			return nil, "", err
+		}
+		secret = generated
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	hash := HashClientSecret(secret)
+	for _, existing := range cm.config.Clients {
+		if existing.KeyHash == hash {
+			return nil, "", fmt.Errorf("客户端密钥已存在")
+		}
+	}
+
+	client.ID = uuid.NewString()
+	client.KeyHash = hash
+	client.KeyPrefix = clientKeyPrefix(secret)
+	client.CreatedAt = time.Now()
+	client.AllowedKinds = deduplicateStrings(client.AllowedKinds)
+	client.AllowedModels = deduplicateStrings(client.AllowedModels)
+
+	cm.config.Clients = append(cm.config.Clients, client)
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return nil, "", err
+	}
+
+	log.Printf("[Config-Client] 已添加客户端: %s (%s)", client.Name, client.ID)
+	return client.Clone(), secret, nil
+}
+
+// UpdateClient 更新客户端
+func (cm *ConfigManager) UpdateClient(id string, updates ClientKeyUpdate) (*ClientKey, error) {
+	if err := validateClientScopes(updates.AllowedKinds, updates.AllowedModels); err != nil {
+		return nil, err
+	}
+	if (updates.DailyTokenLimit != nil && *updates.DailyTokenLimit < 0) ||
+		(updates.MonthlyTokenLimit != nil && *updates.MonthlyTokenLimit < 0) ||
+		(updates.RPMLimit != nil && *updates.RPMLimit < 0) {
+		return nil, fmt.Errorf("无效的配额限制: 不能为负数")
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	idx := cm.findClientIndexLocked(id)
+	if idx < 0 {
+		return nil, fmt.Errorf("客户端不存在: %s", id)
+	}
+
+	client := &cm.config.Clients[idx]
+	if updates.Name != nil {
+		name := strings.TrimSpace(*updates.Name)
+		if name == "" {
+			return nil, fmt.Errorf("客户端名称不能为空")
+		}
+		client.Name = name
+	}
+	if updates.AllowedKinds != nil {
+		client.AllowedKinds = deduplicateStrings(updates.AllowedKinds)
+	}
+	if updates.AllowedModels != nil {
+		client.AllowedModels = deduplicateStrings(updates.AllowedModels)
+	}
+	if updates.ClearExpiry {
+		client.ExpiresAt = nil
+	} else if updates.ExpiresAt != nil {
+		t := *updates.ExpiresAt
+		client.ExpiresAt = &t
+	}
+	if updates.Disabled != nil {
+		client.Disabled = *updates.Disabled
+	}
+	if updates.Description != nil {
+		client.Description = *updates.Description
+	}
+	if updates.DailyTokenLimit != nil {
+		client.DailyTokenLimit = *updates.DailyTokenLimit
+	}
+	if updates.MonthlyTokenLimit != nil {
+		client.MonthlyTokenLimit = *updates.MonthlyTokenLimit
+	}
+	if updates.RPMLimit != nil {
+		client.RPMLimit = *updates.RPMLimit
+	}
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Config-Client] 已更新客户端: %s (%s)", client.Name, client.ID)
+	return client.Clone(), nil
+}
+
+// RotateClientSecret 为客户端重新生成密钥，旧密钥立即失效
+func (cm *ConfigManager) RotateClientSecret(id string) (string, error) {
+	secret, err := generateClientSecret()
+	if err != nil {
+		return "", err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	idx := cm.findClientIndexLocked(id)
+	if idx < 0 {
+		return "", fmt.Errorf("客户端不存在: %s", id)
+	}
+
+	client := &cm.config.Clients[idx]
+	client.KeyHash = HashClientSecret(secret)
+	client.KeyPrefix = clientKeyPrefix(secret)
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return "", err
+	}
+
+	log.Printf("[Config-Client] 已轮换客户端密钥: %s (%s)", client.Name, client.ID)
+	return secret, nil
+}
+
+// RemoveClient 删除客户端
+func (cm *ConfigManager) RemoveClient(id string) (*ClientKey, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	idx := cm.findClientIndexLocked(id)
+	if idx < 0 {
+		return nil, fmt.Errorf("客户端不存在: %s", id)
+	}
+
+	removed := cm.config.Clients[idx]
+	cm.config.Clients = append(cm.config.Clients[:idx], cm.config.Clients[idx+1:]...)
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Config-Client] 已删除客户端: %s (%s)", removed.Name, removed.ID)
+	return &removed, nil
+}
+
+// HasClients 是否配置了客户端密钥
+func (cm *ConfigManager) HasClients() bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return len(cm.config.Clients) > 0
+}
+
+// AuthenticateClient 按明文密钥查找客户端
+// 返回 (nil, nil) 表示未匹配任何客户端；匹配但已禁用或过期时返回错误
+func (cm *ConfigManager) AuthenticateClient(secret string) (*ClientKey, error) {
+	if secret == "" {
+		return nil, nil
+	}
+	hash := []byte(HashClientSecret(secret))
+
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	for i := range cm.config.Clients {
+		client := &cm.config.Clients[i]
+		if subtle.ConstantTimeCompare([]byte(client.KeyHash), hash) != 1 {
+			continue
+		}
+		if client.Disabled {
+			return nil, fmt.Errorf("客户端 %s 已禁用", client.Name)
+		}
+		if client.IsExpired(time.Now()) {
+			return nil, fmt.Errorf("客户端 %s 已过期", client.Name)
+		}
+		return client.Clone(), nil
+	}
+	return nil, nil
+}
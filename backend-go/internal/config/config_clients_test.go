@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestConfigManager(t *testing.T) *ConfigManager {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"upstream": [], "loadBalance": "failover"}`), 0644); err != nil {
+		t.Fatalf("写入初始配置失败: %v", err)
+	}
+	cm, err := NewConfigManager(configPath)
+	if err != nil {
+		t.Fatalf("初始化配置管理器失败: %v", err)
+	}
+	t.Cleanup(func() { cm.Close() })
+	return cm
+}
+
+func TestClientKey_Lifecycle(t *testing.T) {
+	cm := newTestConfigManager(t)
+
+	client, secret, err := cm.AddClient(ClientKey{
+		Name:          "team-a",
+		AllowedKinds:  []string{"messages"},
+		AllowedModels: []string{"claude-*"},
+	}, "")
+	if err != nil {
+		t.Fatalf("AddClient 失败: %v", err)
+	}
+	if !strings.HasPrefix(secret, clientSecretPrefix) {
+		t.Fatalf("secret = %q, want prefix %q", secret, clientSecretPrefix)
+	}
+	if client.KeyHash != HashClientSecret(secret) {
+		t.Fatal("KeyHash 与明文密钥不匹配")
+	}
+
+	// 配置文件中不应出现明文密钥
+	data, _ := os.ReadFile(cm.configFile)
+	if strings.Contains(string(data), secret) {
+		t.Fatal("配置文件中包含明文客户端密钥")
+	}
+
+	got, err := cm.AuthenticateClient(secret)
+	if err != nil || got == nil || got.ID != client.ID {
+		t.Fatalf("AuthenticateClient = %+v, %v", got, err)
+	}
+	if !got.AllowsKind("messages") || got.AllowsKind("gemini") {
+		t.Fatal("AllowsKind 结果不符合预期")
+	}
+	if !got.AllowsModel("claude-sonnet-4") || got.AllowsModel("gpt-4o") {
+		t.Fatal("AllowsModel 结果不符合预期")
+	}
+
+	if other, err := cm.AuthenticateClient("unknown-secret"); other != nil || err != nil {
+		t.Fatalf("未知密钥应返回 (nil, nil)，got %+v, %v", other, err)
+	}
+
+	// 禁用后认证失败
+	disabled := true
+	if _, err := cm.UpdateClient(client.ID, ClientKeyUpdate{Disabled: &disabled}); err != nil {
+		t.Fatalf("UpdateClient 失败: %v", err)
+	}
+	if _, err := cm.AuthenticateClient(secret); err == nil {
+		t.Fatal("禁用的客户端应认证失败")
+	}
+
+	// 重新启用但已过期
+	enabled := false
+	past := time.Now().Add(-time.Minute)
+	if _, err := cm.UpdateClient(client.ID, ClientKeyUpdate{Disabled: &enabled, ExpiresAt: &past}); err != nil {
+		t.Fatalf("UpdateClient 失败: %v", err)
+	}
+	if _, err := cm.AuthenticateClient(secret); err == nil {
+		t.Fatal("过期的客户端应认证失败")
+	}
+
+	// 清除过期时间后轮换密钥，旧密钥失效
+	if _, err := cm.UpdateClient(client.ID, ClientKeyUpdate{ClearExpiry: true}); err != nil {
+		t.Fatalf("UpdateClient 失败: %v", err)
+	}
+	newSecret, err := cm.RotateClientSecret(client.ID)
+	if err != nil {
+		t.Fatalf("RotateClientSecret 失败: %v", err)
+	}
+	if old, _ := cm.AuthenticateClient(secret); old != nil {
+		t.Fatal("轮换后旧密钥仍然有效")
+	}
+	if got, err := cm.AuthenticateClient(newSecret); err != nil || got == nil {
+		t.Fatalf("轮换后新密钥认证失败: %v", err)
+	}
+
+	if _, err := cm.RemoveClient(client.ID); err != nil {
+		t.Fatalf("RemoveClient 失败: %v", err)
+	}
+	if cm.HasClients() {
+		t.Fatal("删除后仍存在客户端")
+	}
+}
+
+func TestAddClient_Validation(t *testing.T) {
+	cm := newTestConfigManager(t)
+
+	if _, _, err := cm.AddClient(ClientKey{Name: " "}, ""); err == nil {
+		t.Fatal("空名称应返回错误")
+	}
+	if _, _, err := cm.AddClient(ClientKey{Name: "x", AllowedKinds: []string{"unknown"}}, ""); err == nil {
+		t.Fatal("无效入口类型应返回错误")
+	}
+	if _, _, err := cm.AddClient(ClientKey{Name: "x", AllowedModels: []string{"["}}, ""); err == nil {
+		t.Fatal("无效模型匹配规则应返回错误")
+	}
+	if _, _, err := cm.AddClient(ClientKey{Name: "a"}, "same-secret"); err != nil {
+		t.Fatalf("AddClient 失败: %v", err)
+	}
+	if _, _, err := cm.AddClient(ClientKey{Name: "b"}, "same-secret"); err == nil {
+		t.Fatal("重复密钥应返回错误")
+	}
+}
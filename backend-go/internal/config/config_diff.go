@@ -0,0 +1,203 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+)
+
+// ============== 配置差异 ==============
+// 供备份恢复与配置导入共用，差异均以“当前配置 → 目标配置”的效果描述
+
+// channelKinds 渠道列表所属的入口类型
+var channelKinds = []string{"messages", "responses", "gemini", "chat"}
+
+// 渠道差异类型
+const (
+	ChannelChangeAdded   = "added"   // 仅存在于目标配置中，应用后新增
+	ChannelChangeRemoved = "removed" // 仅存在于当前配置中，应用后移除
+	ChannelChangeChanged = "changed" // 两边都存在但字段不同
+)
+
+// ConfigFieldChange 单个字段的差异（敏感字段已掩码）
+type ConfigFieldChange struct {
+	Field   string      `json:"field"`
+	Current interface{} `json:"current"`
+	Target  interface{} `json:"target"`
+}
+
+// ConfigChannelChange 渠道级差异
+type ConfigChannelChange struct {
+	Kind   string              `json:"kind"` // messages/responses/gemini/chat
+	ID     string              `json:"id,omitempty"`
+	Name   string              `json:"name"`
+	Change string              `json:"change"` // added/removed/changed
+	Fields []ConfigFieldChange `json:"fields,omitempty"`
+}
+
+// DiffConfigs 计算目标配置相对当前配置的渠道差异与顶层设置差异
+func DiffConfigs(current, target *Config) ([]ConfigChannelChange, []ConfigFieldChange) {
+	channels := []ConfigChannelChange{}
+	for _, kind := range channelKinds {
+		channels = append(channels,
+			diffUpstreamLists(kind, current.UpstreamsForKind(kind), target.UpstreamsForKind(kind))...)
+	}
+	return channels, diffJSONFields(toJSONMap(*current), toJSONMap(*target), settingsDiffIgnored)
+}
+
+// settingsDiffIgnored 顶层配置中不参与设置比较的字段（渠道列表单独比较，加密元数据始终沿用当前值）
+var settingsDiffIgnored = map[string]bool{
+	"encryption":               true,
+	"upstream":                 true,
+	"responsesUpstream":        true,
+	"geminiUpstream":           true,
+	"chatUpstream":             true,
+	"currentUpstream":          true,
+	"currentResponsesUpstream": true,
+}
+
+// matchUpstream 在 candidates 中查找与 up 对应的渠道：优先按标识，其次按名称（兼容没有标识的旧备份或手写配置）
+func matchUpstream(up *UpstreamConfig, candidates []UpstreamConfig, used map[int]bool) int {
+	if up.ID != "" {
+		for i := range candidates {
+			if !used[i] && candidates[i].ID == up.ID {
+				return i
+			}
+		}
+	}
+	for i := range candidates {
+		if !used[i] && candidates[i].Name == up.Name && (up.ID == "" || candidates[i].ID == "") {
+			return i
+		}
+	}
+	return -1
+}
+
+// adoptChannelIDs 为目标配置中缺少标识的渠道沿用当前同名渠道的标识
+func adoptChannelIDs(target, current []UpstreamConfig) {
+	used := make(map[int]bool)
+	for i := range target {
+		if target[i].ID != "" {
+			continue
+		}
+		if j := matchUpstream(&target[i], current, used); j >= 0 {
+			target[i].ID = current[j].ID
+			used[j] = true
+		}
+	}
+}
+
+// diffUpstreamLists 比较同一入口的当前渠道列表与目标渠道列表
+func diffUpstreamLists(kind string, current, target []UpstreamConfig) []ConfigChannelChange {
+	changes := []ConfigChannelChange{}
+	used := make(map[int]bool)
+
+	for i := range target {
+		up := &target[i]
+		j := matchUpstream(up, current, used)
+		if j < 0 {
+			changes = append(changes, ConfigChannelChange{Kind: kind, ID: up.ID, Name: up.Name, Change: ChannelChangeAdded})
+			continue
+		}
+		used[j] = true
+
+		fields := diffJSONFields(toJSONMap(current[j]), toJSONMap(*up), nil)
+		if len(fields) > 0 {
+			changes = append(changes, ConfigChannelChange{
+				Kind: kind, ID: current[j].ID, Name: current[j].Name, Change: ChannelChangeChanged, Fields: fields,
+			})
+		}
+	}
+
+	for j := range current {
+		if !used[j] {
+			changes = append(changes, ConfigChannelChange{Kind: kind, ID: current[j].ID, Name: current[j].Name, Change: ChannelChangeRemoved})
+		}
+	}
+	return changes
+}
+
+// toJSONMap 按 JSON 序列化结果把结构体转为 map，便于逐字段比较
+func toJSONMap(v interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return result
+	}
+	_ = json.Unmarshal(data, &result)
+	return result
+}
+
+// diffJSONFields 逐个顶层字段比较，返回按字段名排序的差异（敏感值已掩码）
+func diffJSONFields(current, target map[string]interface{}, ignored map[string]bool) []ConfigFieldChange {
+	keys := make(map[string]bool)
+	for k := range current {
+		keys[k] = true
+	}
+	for k := range target {
+		keys[k] = true
+	}
+
+	names := make([]string, 0, len(keys))
+	for k := range keys {
+		if !ignored[k] {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []ConfigFieldChange{}
+	for _, k := range names {
+		if reflect.DeepEqual(current[k], target[k]) {
+			continue
+		}
+		changes = append(changes, ConfigFieldChange{
+			Field:   k,
+			Current: maskSensitiveValue(k, current[k]),
+			Target:  maskSensitiveValue(k, target[k]),
+		})
+	}
+	return changes
+}
+
+// sensitiveFields 差异中需要掩码的字段（API Key、客户端密钥哈希、Webhook 签名密钥等）
+var sensitiveFields = map[string]bool{
+	"apiKeys":           true,
+	"historicalApiKeys": true,
+	"key":               true,
+	"keyHash":           true,
+	"secret":            true,
+}
+
+// maskSensitiveValue 递归掩码敏感字段；告警目标的 Webhook 地址通常内含令牌，一并掩码
+func maskSensitiveValue(field string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		if sensitiveFields[field] {
+			return utils.MaskAPIKey(val)
+		}
+		return val
+	case []interface{}:
+		masked := make([]interface{}, len(val))
+		for i, item := range val {
+			masked[i] = maskSensitiveValue(field, item)
+		}
+		return masked
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if k == "url" && field == "targets" {
+				if s, ok := item.(string); ok {
+					masked[k] = utils.MaskAPIKey(s)
+					continue
+				}
+			}
+			masked[k] = maskSensitiveValue(k, item)
+		}
+		return masked
+	default:
+		return v
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+)
+
+// ============== 永久失效 Key 的自动禁用 ==============
+
+// Key 被禁用的原因
+const (
+	DisabledReasonInvalidKey          = "invalid_key"          // Key 无效、已吊销
+	DisabledReasonAccountBanned       = "account_banned"       // 账号被封禁或停用
+	DisabledReasonInsufficientBalance = "insufficient_balance" // 余额不足或未开通计费
+)
+
+// DisabledAPIKey 因永久性错误被移出轮换的 API Key
+type DisabledAPIKey struct {
+	Key        string    `json:"key"`
+	Reason     string    `json:"reason"`               // DisabledReason*
+	Message    string    `json:"message,omitempty"`    // 上游返回的错误消息
+	StatusCode int       `json:"statusCode,omitempty"` // 上游返回的状态码
+	DisabledAt time.Time `json:"disabledAt"`
+}
+
+// InactiveAPIKeys 已不参与轮换但仍需统计聚合的 Key（历史 Key + 被禁用的 Key）
+func (u *UpstreamConfig) InactiveAPIKeys() []string {
+	keys := append([]string{}, u.HistoricalAPIKeys...)
+	for _, dk := range u.DisabledAPIKeys {
+		keys = append(keys, dk.Key)
+	}
+	return keys
+}
+
+// removeDisabledKeys 从禁用列表中移除重新加入 APIKeys 的 Key（手动换回视为重新启用）
+func (u *UpstreamConfig) removeDisabledKeys(keys map[string]bool) {
+	if len(u.DisabledAPIKeys) == 0 {
+		return
+	}
+	var remaining []DisabledAPIKey
+	for _, dk := range u.DisabledAPIKeys {
+		if keys[dk.Key] {
+			log.Printf("[Config-Key] 渠道 %s: Key %s 已重新加入，移出禁用列表", u.Name, utils.MaskAPIKey(dk.Key))
+			continue
+		}
+		remaining = append(remaining, dk)
+	}
+	u.DisabledAPIKeys = remaining
+}
+
+// upstreamsForKindLocked 返回入口类型对应的渠道切片（与 cm.config 共享底层数组，调用方需持有 cm.mu）
+func (cm *ConfigManager) upstreamsForKindLocked(kind string) []UpstreamConfig {
+	return cm.config.UpstreamsForKind(kind)
+}
+
+// DisabledKeyChannel 自动禁用 Key 时受影响的渠道
+type DisabledKeyChannel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// DisableAPIKey 将 Key 从 APIKeys 移入禁用列表
+// 作用于该入口类型下所有包含此 Key 且使用 baseURL 的渠道（baseURL 为空时不限制），返回受影响的渠道
+func (cm *ConfigManager) DisableAPIKey(kind, baseURL string, entry DisabledAPIKey) ([]DisabledKeyChannel, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	defer cm.useChangeSourceLocked(ChangeSourceKeyAutoDisable)()
+
+	if entry.DisabledAt.IsZero() {
+		entry.DisabledAt = time.Now()
+	}
+
+	var affected []DisabledKeyChannel
+	upstreams := cm.upstreamsForKindLocked(kind)
+	for i := range upstreams {
+		upstream := &upstreams[i]
+		if baseURL != "" && !containsString(upstream.GetAllBaseURLs(), baseURL) {
+			continue
+		}
+		keyIdx := -1
+		for j, key := range upstream.APIKeys {
+			if key == entry.Key {
+				keyIdx = j
+				break
+			}
+		}
+		if keyIdx < 0 {
+			continue
+		}
+
+		upstream.APIKeys = append(upstream.APIKeys[:keyIdx:keyIdx], upstream.APIKeys[keyIdx+1:]...)
+		upstream.DisabledAPIKeys = append(upstream.DisabledAPIKeys, entry)
+		affected = append(affected, DisabledKeyChannel{ID: upstream.ID, Name: upstream.Name})
+		log.Printf("[Config-Key] %s 渠道 [%s] %s: Key %s 因永久性错误已自动禁用（%s）",
+			kind, upstream.ID, upstream.Name, utils.MaskAPIKey(entry.Key), entry.Reason)
+		if len(upstream.APIKeys) == 0 {
+			log.Printf("[Config-Key] 警告: %s 渠道 [%s] %s 已没有可用的 API Key", kind, upstream.ID, upstream.Name)
+		}
+	}
+
+	if len(affected) == 0 {
+		return nil, nil
+	}
+	delete(cm.failedKeysCache, entry.Key)
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return nil, err
+	}
+	return affected, nil
+}
+
+// EnableDisabledAPIKey 将被禁用的 Key 重新加入渠道的 APIKeys 末尾
+func (cm *ConfigManager) EnableDisabledAPIKey(kind, channelID, apiKey string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	upstream, err := cm.disabledKeyUpstreamLocked(kind, channelID, apiKey)
+	if err != nil {
+		return err
+	}
+	upstream.removeDisabledKeys(map[string]bool{apiKey: true})
+	if !containsString(upstream.APIKeys, apiKey) {
+		upstream.APIKeys = append(upstream.APIKeys, apiKey)
+	}
+	delete(cm.failedKeysCache, apiKey)
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+	log.Printf("[Config-Key] %s 渠道 [%s] %s: Key %s 已重新启用", kind, channelID, upstream.Name, utils.MaskAPIKey(apiKey))
+	return nil
+}
+
+// DiscardDisabledAPIKey 从禁用列表删除 Key，并移入历史列表（保留统计聚合）
+func (cm *ConfigManager) DiscardDisabledAPIKey(kind, channelID, apiKey string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	upstream, err := cm.disabledKeyUpstreamLocked(kind, channelID, apiKey)
+	if err != nil {
+		return err
+	}
+	upstream.removeDisabledKeys(map[string]bool{apiKey: true})
+	if !containsString(upstream.HistoricalAPIKeys, apiKey) {
+		upstream.HistoricalAPIKeys = append(upstream.HistoricalAPIKeys, apiKey)
+	}
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+	log.Printf("[Config-Key] %s 渠道 [%s] %s: 被禁用的 Key %s 已删除并移入历史列表", kind, channelID, upstream.Name, utils.MaskAPIKey(apiKey))
+	return nil
+}
+
+// disabledKeyUpstreamLocked 查找包含指定禁用 Key 的渠道
+func (cm *ConfigManager) disabledKeyUpstreamLocked(kind, channelID, apiKey string) (*UpstreamConfig, error) {
+	index, err := cm.channelIndexLocked(kind, channelID)
+	if err != nil {
+		return nil, err
+	}
+	upstream := &cm.upstreamsForKindLocked(kind)[index]
+	for _, dk := range upstream.DisabledAPIKeys {
+		if dk.Key == apiKey {
+			return upstream, nil
+		}
+	}
+	return nil, fmt.Errorf("API密钥不在禁用列表中")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
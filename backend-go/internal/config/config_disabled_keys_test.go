@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestDisableAPIKey_Lifecycle(t *testing.T) {
+	cm := newTestConfigManager(t)
+	if err := cm.AddUpstream(UpstreamConfig{Name: "a", ServiceType: "claude", BaseURL: "https://a.example.com", APIKeys: []string{"k1", "k2"}}); err != nil {
+		t.Fatalf("AddUpstream 失败: %v", err)
+	}
+	if err := cm.AddUpstream(UpstreamConfig{Name: "b", ServiceType: "claude", BaseURL: "https://b.example.com", APIKeys: []string{"k1"}}); err != nil {
+		t.Fatalf("AddUpstream 失败: %v", err)
+	}
+
+	// 只禁用使用该 BaseURL 的渠道中的 Key
+	affected, err := cm.DisableAPIKey("messages", "https://a.example.com", DisabledAPIKey{Key: "k1", Reason: DisabledReasonInvalidKey, StatusCode: 401})
+	cfg := cm.GetConfig()
+	if err != nil || len(affected) != 1 || affected[0] != (DisabledKeyChannel{ID: cfg.Upstream[0].ID, Name: "a"}) {
+		t.Fatalf("DisableAPIKey = %+v, %v; want channel a", affected, err)
+	}
+	if keys := cfg.Upstream[0].APIKeys; len(keys) != 1 || keys[0] != "k2" {
+		t.Fatalf("APIKeys = %v, want [k2]", keys)
+	}
+	if dk := cfg.Upstream[0].DisabledAPIKeys; len(dk) != 1 || dk[0].Key != "k1" || dk[0].DisabledAt.IsZero() {
+		t.Fatalf("DisabledAPIKeys = %+v", dk)
+	}
+	if keys := cfg.Upstream[1].APIKeys; len(keys) != 1 {
+		t.Fatalf("channel b should be untouched: %v", keys)
+	}
+	if inactive := cfg.Upstream[0].InactiveAPIKeys(); len(inactive) != 1 || inactive[0] != "k1" {
+		t.Fatalf("InactiveAPIKeys = %v, want [k1]", inactive)
+	}
+
+	// 再次禁用不在 APIKeys 中的 Key 不产生变化
+	if affected, _ := cm.DisableAPIKey("messages", "https://a.example.com", DisabledAPIKey{Key: "k1"}); len(affected) != 0 {
+		t.Fatalf("disabling an already disabled key should be a no-op: %v", affected)
+	}
+
+	if err := cm.EnableDisabledAPIKey("messages", cfg.Upstream[0].ID, "k1"); err != nil {
+		t.Fatalf("EnableDisabledAPIKey 失败: %v", err)
+	}
+	cfg = cm.GetConfig()
+	if keys := cfg.Upstream[0].APIKeys; len(keys) != 2 || keys[1] != "k1" || len(cfg.Upstream[0].DisabledAPIKeys) != 0 {
+		t.Fatalf("after enable: keys=%v disabled=%v", keys, cfg.Upstream[0].DisabledAPIKeys)
+	}
+	if err := cm.EnableDisabledAPIKey("messages", cfg.Upstream[0].ID, "k1"); err == nil {
+		t.Fatal("enabling a key that is not disabled should fail")
+	}
+
+	// 删除禁用 Key 后移入历史列表
+	if _, err := cm.DisableAPIKey("messages", "", DisabledAPIKey{Key: "k2", Reason: DisabledReasonAccountBanned}); err != nil {
+		t.Fatalf("DisableAPIKey 失败: %v", err)
+	}
+	if err := cm.DiscardDisabledAPIKey("messages", cfg.Upstream[0].ID, "k2"); err != nil {
+		t.Fatalf("DiscardDisabledAPIKey 失败: %v", err)
+	}
+	cfg = cm.GetConfig()
+	if len(cfg.Upstream[0].DisabledAPIKeys) != 0 || len(cfg.Upstream[0].HistoricalAPIKeys) != 1 {
+		t.Fatalf("after discard: disabled=%v historical=%v", cfg.Upstream[0].DisabledAPIKeys, cfg.Upstream[0].HistoricalAPIKeys)
+	}
+}
+
+func TestAddAPIKey_RemovesFromDisabledList(t *testing.T) {
+	cm := newTestConfigManager(t)
+	if err := cm.AddChatUpstream(UpstreamConfig{Name: "c", ServiceType: "openai", BaseURL: "https://c.example.com", APIKeys: []string{"k1", "k2"}}); err != nil {
+		t.Fatalf("AddChatUpstream 失败: %v", err)
+	}
+	if _, err := cm.DisableAPIKey("chat", "", DisabledAPIKey{Key: "k1", Reason: DisabledReasonInsufficientBalance}); err != nil {
+		t.Fatalf("DisableAPIKey 失败: %v", err)
+	}
+	if err := cm.AddChatAPIKey(cm.GetConfig().ChatUpstream[0].ID, "k1"); err != nil {
+		t.Fatalf("AddChatAPIKey 失败: %v", err)
+	}
+	if dk := cm.GetConfig().ChatUpstream[0].DisabledAPIKeys; len(dk) != 0 {
+		t.Fatalf("re-added key should leave the disabled list: %+v", dk)
+	}
+}
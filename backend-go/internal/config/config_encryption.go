@@ -0,0 +1,549 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"golang.org/x/crypto/argon2"
+)
+
+// ============== API Key 静态加密 ==============
+//
+// 采用信封加密：随机生成的数据密钥（AES-256-GCM）加密各个 Key，数据密钥再由主密钥加密后保存在
+// config.json 的 encryption.wrappedKey 中。主密钥只来自环境变量或密钥文件，不会写入配置。
+// 主密钥为 32 字节 base64 时直接使用；为口令时经 Argon2id 派生，随机盐与参数保存在 encryption.kdf 中。
+// 内存中的配置始终为明文，仅在写入文件时加密、读取文件时解密。
+
+const (
+	// EnvConfigEncryptionKey 主密钥（32 字节的 base64，或任意口令，口令会经 Argon2id 派生）
+	EnvConfigEncryptionKey = "CONFIG_ENCRYPTION_KEY"
+	// EnvConfigEncryptionKeyFile 主密钥文件路径（内容格式同 CONFIG_ENCRYPTION_KEY）
+	EnvConfigEncryptionKeyFile = "CONFIG_ENCRYPTION_KEY_FILE"
+
+	encryptionVersion    = 1
+	encryptionAlgorithm  = "AES-256-GCM"
+	encryptedValuePrefix = "enc:v1:"
+
+	// 口令派生参数（Argon2id，RFC 9106 推荐的低内存配置）
+	kdfAlgorithmArgon2id = "argon2id"
+	kdfSaltSize          = 16
+	kdfTime              = 3
+	kdfMemoryKiB         = 64 * 1024
+	kdfThreads           = 4
+)
+
+// errMasterKeyRequired 配置已加密但未提供主密钥
+var errMasterKeyRequired = errors.New("配置中的 API Key 已加密，请设置 " + EnvConfigEncryptionKey + " 或 " + EnvConfigEncryptionKeyFile)
+
+// EncryptionConfig API Key 加密元数据
+type EncryptionConfig struct {
+	Version    int    `json:"version"`
+	Algorithm  string `json:"algorithm"`
+	WrappedKey string `json:"wrappedKey"` // 主密钥加密后的数据密钥（base64）
+	// KDF 主密钥为口令时的派生参数；主密钥为 32 字节 base64 时为空
+	KDF *KDFConfig `json:"kdf,omitempty"`
+}
+
+// KDFConfig 口令派生参数
+type KDFConfig struct {
+	Algorithm string `json:"algorithm"` // argon2id
+	Salt      string `json:"salt"`      // 随机盐（base64）
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memoryKiB"`
+	Threads   uint8  `json:"threads"`
+}
+
+// Clone 深拷贝加密元数据
+func (e *EncryptionConfig) Clone() *EncryptionConfig {
+	if e == nil {
+		return nil
+	}
+	cloned := *e
+	if e.KDF != nil {
+		kdf := *e.KDF
+		cloned.KDF = &kdf
+	}
+	return &cloned
+}
+
+// keyCipher 使用数据密钥加解密单个 Key
+type keyCipher struct {
+	aead       cipher.AEAD
+	wrappedKey string
+}
+
+// LoadMasterKeyFromEnv 从环境变量或密钥文件读取主密钥材料，均未设置时返回 nil
+// 返回值为原始内容（32 字节 base64 或口令），实际密钥在加解密时结合加密元数据派生
+func LoadMasterKeyFromEnv() ([]byte, error) {
+	if raw := strings.TrimSpace(os.Getenv(EnvConfigEncryptionKey)); raw != "" {
+		return []byte(raw), nil
+	}
+	path := strings.TrimSpace(os.Getenv(EnvConfigEncryptionKeyFile))
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取主密钥文件失败: %w", err)
+	}
+	raw := strings.TrimSpace(string(data))
+	if raw == "" {
+		return nil, fmt.Errorf("主密钥文件为空: %s", path)
+	}
+	return []byte(raw), nil
+}
+
+// rawMasterKey 主密钥材料为 32 字节的 base64 时返回解码后的密钥
+func rawMasterKey(secret []byte) ([]byte, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(string(secret))
+	if err != nil || len(decoded) != 32 {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// newKDFConfig 为口令生成新的随机盐与派生参数
+func newKDFConfig() (*KDFConfig, error) {
+	salt := make([]byte, kdfSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return &KDFConfig{
+		Algorithm: kdfAlgorithmArgon2id,
+		Salt:      base64.StdEncoding.EncodeToString(salt),
+		Time:      kdfTime,
+		MemoryKiB: kdfMemoryKiB,
+		Threads:   kdfThreads,
+	}, nil
+}
+
+// deriveMasterKey 由主密钥材料与派生参数得到 AES-256 主密钥
+func deriveMasterKey(secret []byte, kdf *KDFConfig) ([]byte, error) {
+	if kdf == nil {
+		if key, ok := rawMasterKey(secret); ok {
+			return key, nil
+		}
+		return nil, errors.New("配置使用 32 字节 base64 主密钥加密，当前主密钥格式不匹配")
+	}
+	if kdf.Algorithm != kdfAlgorithmArgon2id {
+		return nil, fmt.Errorf("不支持的主密钥派生算法: %s", kdf.Algorithm)
+	}
+	salt, err := base64.StdEncoding.DecodeString(kdf.Salt)
+	if err != nil || len(salt) < kdfSaltSize {
+		return nil, errors.New("主密钥派生参数中的盐无效")
+	}
+	if kdf.Time == 0 || kdf.MemoryKiB == 0 || kdf.Threads == 0 {
+		return nil, errors.New("主密钥派生参数无效")
+	}
+	return argon2.IDKey(secret, salt, kdf.Time, kdf.MemoryKiB, kdf.Threads, 32), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealBase64 加密并编码为 base64(nonce || ciphertext)
+func sealBase64(aead cipher.AEAD, plaintext []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// openBase64 解码并解密 sealBase64 的结果
+func openBase64(aead cipher.AEAD, encoded string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("密文长度无效")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// newKeyCipher 生成新的数据密钥，并用主密钥加密
+// 主密钥为口令时生成新的随机盐，经 Argon2id 派生
+func newKeyCipher(secret []byte) (*keyCipher, *EncryptionConfig, error) {
+	var kdf *KDFConfig
+	if _, ok := rawMasterKey(secret); !ok {
+		var err error
+		if kdf, err = newKDFConfig(); err != nil {
+			return nil, nil, err
+		}
+	}
+	masterKey, err := deriveMasterKey(secret, kdf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, nil, err
+	}
+	master, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := sealBase64(master, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc := &EncryptionConfig{Version: encryptionVersion, Algorithm: encryptionAlgorithm, WrappedKey: wrapped, KDF: kdf}
+	return &keyCipher{aead: aead, wrappedKey: wrapped}, enc, nil
+}
+
+// unwrapKeyCipher 用主密钥解出数据密钥
+func unwrapKeyCipher(secret []byte, enc *EncryptionConfig) (*keyCipher, error) {
+	if enc.Version != encryptionVersion || enc.Algorithm != encryptionAlgorithm {
+		return nil, fmt.Errorf("不支持的加密格式: version=%d, algorithm=%s", enc.Version, enc.Algorithm)
+	}
+	masterKey, err := deriveMasterKey(secret, enc.KDF)
+	if err != nil {
+		return nil, err
+	}
+	master, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := openBase64(master, enc.WrappedKey)
+	if err != nil {
+		return nil, errors.New("无法解密数据密钥，主密钥不正确或配置已损坏")
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &keyCipher{aead: aead, wrappedKey: enc.WrappedKey}, nil
+}
+
+// encrypt 加密单个 Key（空值与已加密的值保持不变）
+func (k *keyCipher) encrypt(value string) (string, error) {
+	if value == "" || strings.HasPrefix(value, encryptedValuePrefix) {
+		return value, nil
+	}
+	sealed, err := sealBase64(k.aead, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	return encryptedValuePrefix + sealed, nil
+}
+
+// decrypt 解密单个 Key；没有加密前缀的值视为明文（允许在已加密的配置中手工添加 Key，下次保存时加密）
+func (k *keyCipher) decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedValuePrefix) {
+		return value, nil
+	}
+	plaintext, err := openBase64(k.aead, strings.TrimPrefix(value, encryptedValuePrefix))
+	if err != nil {
+		return "", fmt.Errorf("API Key 解密失败: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// transformConfigKeys 对所有渠道的 apiKeys/historicalApiKeys/disabledApiKeys[].key 应用 fn，返回新的配置（不修改入参）
+func transformConfigKeys(cfg Config, fn func(string) (string, error)) (Config, error) {
+	transformList := func(keys []string) ([]string, error) {
+		if keys == nil {
+			return nil, nil
+		}
+		result := make([]string, len(keys))
+		for i, key := range keys {
+			v, err := fn(key)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = v
+		}
+		return result, nil
+	}
+
+	transformUpstreams := func(upstreams []UpstreamConfig) ([]UpstreamConfig, error) {
+		if upstreams == nil {
+			return nil, nil
+		}
+		result := make([]UpstreamConfig, len(upstreams))
+		for i := range upstreams {
+			up := *upstreams[i].Clone()
+			var err error
+			if up.APIKeys, err = transformList(up.APIKeys); err != nil {
+				return nil, err
+			}
+			if up.HistoricalAPIKeys, err = transformList(up.HistoricalAPIKeys); err != nil {
+				return nil, err
+			}
+			for j := range up.DisabledAPIKeys {
+				if up.DisabledAPIKeys[j].Key, err = fn(up.DisabledAPIKeys[j].Key); err != nil {
+					return nil, err
+				}
+			}
+			result[i] = up
+		}
+		return result, nil
+	}
+
+	var err error
+	if cfg.Upstream, err = transformUpstreams(cfg.Upstream); err != nil {
+		return cfg, err
+	}
+	if cfg.ResponsesUpstream, err = transformUpstreams(cfg.ResponsesUpstream); err != nil {
+		return cfg, err
+	}
+	if cfg.GeminiUpstream, err = transformUpstreams(cfg.GeminiUpstream); err != nil {
+		return cfg, err
+	}
+	if cfg.ChatUpstream, err = transformUpstreams(cfg.ChatUpstream); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// cipherForLocked 返回加密元数据对应的数据密钥（未加密时返回 nil，需持有锁）
+func (cm *ConfigManager) cipherForLocked(enc *EncryptionConfig) (*keyCipher, error) {
+	if enc == nil {
+		return nil, nil
+	}
+	if cm.keyCipher != nil && cm.keyCipher.wrappedKey == enc.WrappedKey {
+		return cm.keyCipher, nil
+	}
+	if cm.masterKey == nil {
+		return nil, errMasterKeyRequired
+	}
+	return unwrapKeyCipher(cm.masterKey, enc)
+}
+
+// decryptConfigLocked 解密从文件读取的配置（未加密的配置原样返回，需持有锁）
+func (cm *ConfigManager) decryptConfigLocked(cfg Config) (Config, *keyCipher, error) {
+	keyCipher, err := cm.cipherForLocked(cfg.Encryption)
+	if err != nil || keyCipher == nil {
+		return cfg, nil, err
+	}
+	decrypted, err := transformConfigKeys(cfg, keyCipher.decrypt)
+	if err != nil {
+		return cfg, nil, err
+	}
+	return decrypted, keyCipher, nil
+}
+
+// marshalConfigLocked 序列化待写入文件的配置，启用加密时加密所有 Key（需持有锁）
+func (cm *ConfigManager) marshalConfigLocked(cfg Config) ([]byte, error) {
+	if cfg.Encryption != nil {
+		keyCipher, err := cm.cipherForLocked(cfg.Encryption)
+		if err != nil {
+			return nil, err
+		}
+		if cfg, err = transformConfigKeys(cfg, keyCipher.encrypt); err != nil {
+			return nil, err
+		}
+		cm.keyCipher = keyCipher
+	}
+	return json.MarshalIndent(cfg, "", "  ")
+}
+
+// KeyEncryptionEnabled 配置文件中的 API Key 是否已加密
+func (cm *ConfigManager) KeyEncryptionEnabled() bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.config.Encryption != nil
+}
+
+// ============== 管理 API 中的 Key 展示 ==============
+
+// RedactAPIKeys 启用加密时返回掩码后的 Key 列表（用于管理 API 响应），否则原样返回
+func (cm *ConfigManager) RedactAPIKeys(keys []string) []string {
+	if keys == nil || !cm.KeyEncryptionEnabled() {
+		return keys
+	}
+	masked := make([]string, len(keys))
+	for i, key := range keys {
+		masked[i] = utils.MaskAPIKey(key)
+	}
+	return masked
+}
+
+// RedactDisabledAPIKeys 启用加密时返回 Key 已掩码的禁用记录
+func (cm *ConfigManager) RedactDisabledAPIKeys(keys []DisabledAPIKey) []DisabledAPIKey {
+	if keys == nil || !cm.KeyEncryptionEnabled() {
+		return keys
+	}
+	masked := append([]DisabledAPIKey(nil), keys...)
+	for i := range masked {
+		masked[i].Key = utils.MaskAPIKey(masked[i].Key)
+	}
+	return masked
+}
+
+// RedactUpstream 返回可用于管理 API 响应的渠道副本（启用加密时所有 Key 均已掩码）
+func (cm *ConfigManager) RedactUpstream(upstream *UpstreamConfig) *UpstreamConfig {
+	if upstream == nil {
+		return nil
+	}
+	cloned := upstream.Clone()
+	cloned.APIKeys = cm.RedactAPIKeys(cloned.APIKeys)
+	cloned.HistoricalAPIKeys = cm.RedactAPIKeys(cloned.HistoricalAPIKeys)
+	cloned.DisabledAPIKeys = cm.RedactDisabledAPIKeys(cloned.DisabledAPIKeys)
+	return cloned
+}
+
+// ResolveAPIKeyRef 将管理 API 传入的 Key 解析为渠道中的实际 Key
+// ref 可以是明文，也可以是 RedactAPIKeys 返回的掩码；掩码在渠道中没有唯一对应的 Key 时返回 *ConfigError，
+// 避免把掩码当作真实 Key 保存或删除
+func (cm *ConfigManager) ResolveAPIKeyRef(kind, channelID, ref string) (string, error) {
+	if !strings.Contains(ref, "***") {
+		return ref, nil
+	}
+
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	index, err := cm.channelIndexLocked(kind, channelID)
+	if err != nil {
+		return ref, nil
+	}
+	if key, ok := resolveMaskedKey(&cm.config.UpstreamsForKind(kind)[index], ref); ok {
+		return key, nil
+	}
+	return "", &ConfigError{Message: fmt.Sprintf("无法还原掩码 Key %s：渠道中没有唯一对应的 Key", ref)}
+}
+
+// ResolveAPIKeyRefs 批量解析 Key（用于整体更新渠道 apiKeys 时保留未修改的已掩码 Key）
+func (cm *ConfigManager) ResolveAPIKeyRefs(kind, channelID string, refs []string) ([]string, error) {
+	if refs == nil {
+		return nil, nil
+	}
+	resolved := make([]string, len(refs))
+	for i, ref := range refs {
+		key, err := cm.ResolveAPIKeyRef(kind, channelID, ref)
+		if err != nil {
+			return nil, err
+		}
+		resolved[i] = key
+	}
+	return resolved, nil
+}
+
+// resolveMaskedKey 在渠道的活跃 Key 与禁用 Key 中查找掩码唯一对应的 Key
+// 没有匹配或匹配到多个不同的 Key 时返回 false
+func resolveMaskedKey(upstream *UpstreamConfig, ref string) (string, bool) {
+	candidates := append([]string(nil), upstream.APIKeys...)
+	for _, dk := range upstream.DisabledAPIKeys {
+		candidates = append(candidates, dk.Key)
+	}
+
+	match := ""
+	for _, key := range candidates {
+		if key == ref {
+			return key, true
+		}
+		if utils.MaskAPIKey(key) == ref {
+			if match != "" && match != key {
+				return "", false
+			}
+			match = key
+		}
+	}
+	return match, match != ""
+}
+
+// ============== 迁移：加密现有配置 ==============
+
+// EncryptConfigFile 加密现有配置文件及其历史备份中的 API Key，返回被加密的备份数量
+// 配置已加密时只处理尚未加密的备份（可重复执行）
+func EncryptConfigFile(configFile string, masterKey []byte) (int, error) {
+	if len(masterKey) == 0 {
+		return 0, fmt.Errorf("未设置主密钥，请设置 %s 或 %s", EnvConfigEncryptionKey, EnvConfigEncryptionKeyFile)
+	}
+	if _, err := os.Stat(configFile); err != nil {
+		return 0, err
+	}
+
+	cm := &ConfigManager{
+		configFile:      configFile,
+		masterKey:       masterKey,
+		failedKeysCache: make(map[string]*FailedKey),
+	}
+	if err := cm.loadConfig(); err != nil {
+		return 0, err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cm.config.Encryption == nil {
+		keyCipher, enc, err := newKeyCipher(masterKey)
+		if err != nil {
+			return 0, err
+		}
+		cm.keyCipher = keyCipher
+		cm.config.Encryption = enc
+		if err := cm.saveConfigLocked(cm.config); err != nil {
+			return 0, err
+		}
+		log.Printf("[Config-Encryption] 配置文件中的 API Key 已加密: %s", configFile)
+	}
+
+	return cm.encryptBackupsLocked()
+}
+
+// encryptBackupsLocked 使用当前数据密钥加密所有未加密的备份文件（需持有锁）
+func (cm *ConfigManager) encryptBackupsLocked() (int, error) {
+	entries, err := os.ReadDir(cm.backupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	encrypted := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !backupNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(cm.backupDir(), entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return encrypted, err
+		}
+		var backup Config
+		if err := json.Unmarshal(data, &backup); err != nil {
+			log.Printf("[Config-Encryption] 警告: 跳过无法解析的备份 %s: %v", entry.Name(), err)
+			continue
+		}
+		if backup.Encryption != nil {
+			continue
+		}
+
+		backup.Encryption = cm.config.Encryption.Clone()
+		out, err := cm.marshalConfigLocked(backup)
+		if err != nil {
+			return encrypted, err
+		}
+		if err := os.WriteFile(path, out, 0644); err != nil {
+			return encrypted, err
+		}
+		encrypted++
+	}
+	return encrypted, nil
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPlainKey = "sk-plaintext-secret-0123456789"
+
+// assertNoPlaintextKey 检查文件中不包含明文 Key
+func assertNoPlaintextKey(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 %s 失败: %v", path, err)
+	}
+	if strings.Contains(string(data), testPlainKey) {
+		t.Fatalf("%s contains plaintext key:\n%s", path, data)
+	}
+}
+
+func TestKeyEncryption_NewConfigIsEncrypted(t *testing.T) {
+	t.Setenv(EnvConfigEncryptionKey, "correct horse battery staple")
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	cm, err := NewConfigManager(configPath)
+	if err != nil {
+		t.Fatalf("初始化配置管理器失败: %v", err)
+	}
+	defer cm.Close()
+	if !cm.KeyEncryptionEnabled() {
+		t.Fatal("new config should enable encryption when a master key is set")
+	}
+
+	if err := cm.AddUpstream(UpstreamConfig{Name: "a", ServiceType: "claude", BaseURL: "https://a.example.com", APIKeys: []string{testPlainKey}}); err != nil {
+		t.Fatalf("AddUpstream 失败: %v", err)
+	}
+	if got := cm.GetConfig().Upstream[0].APIKeys[0]; got != testPlainKey {
+		t.Fatalf("in-memory key should stay plaintext, got %q", got)
+	}
+	if err := cm.RemoveAPIKey(cm.GetConfig().Upstream[0].ID, testPlainKey); err != nil {
+		t.Fatalf("RemoveAPIKey 失败: %v", err)
+	}
+
+	// 配置文件与所有备份中都只有密文（删除的 Key 进入 historicalApiKeys，同样加密）
+	assertNoPlaintextKey(t, configPath)
+	backups, _ := cm.ListBackups()
+	if len(backups) == 0 {
+		t.Fatal("expected backups to be written")
+	}
+	for _, b := range backups {
+		assertNoPlaintextKey(t, filepath.Join(cm.backupDir(), b.Name))
+	}
+
+	// 重新加载后透明解密
+	reloaded, err := NewConfigManager(configPath)
+	if err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	defer reloaded.Close()
+	if got := reloaded.GetConfig().Upstream[0].HistoricalAPIKeys; len(got) != 1 || got[0] != testPlainKey {
+		t.Fatalf("historical keys should be decrypted on load, got %v", got)
+	}
+}
+
+func TestKeyEncryption_MigrateExistingConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	plain := `{
+		"upstream": [{"id": "id-a", "name": "a", "baseUrl": "https://a.example.com", "apiKeys": ["` + testPlainKey + `"], "status": "active",
+			"disabledApiKeys": [{"key": "sk-disabled-key-0123456789", "reason": "invalid_key", "disabledAt": "2024-01-01T00:00:00Z"}]}],
+		"loadBalance": "failover",
+		"fuzzyModeEnabled": true
+	}`
+	if err := os.WriteFile(configPath, []byte(plain), 0644); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "backups"), 0755); err != nil {
+		t.Fatalf("创建备份目录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "backups", testBackupName), []byte(plain), 0644); err != nil {
+		t.Fatalf("写入备份失败: %v", err)
+	}
+
+	masterKey := []byte("migration-master-key")
+	encrypted, err := EncryptConfigFile(configPath, masterKey)
+	if err != nil {
+		t.Fatalf("EncryptConfigFile 失败: %v", err)
+	}
+	if encrypted < 1 {
+		t.Fatalf("expected existing backups to be encrypted, got %d", encrypted)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "backups"))
+	for _, e := range entries {
+		assertNoPlaintextKey(t, filepath.Join(dir, "backups", e.Name()))
+	}
+	assertNoPlaintextKey(t, configPath)
+
+	// 可重复执行
+	if n, err := EncryptConfigFile(configPath, masterKey); err != nil || n != 0 {
+		t.Fatalf("second EncryptConfigFile = %d, %v; want 0, nil", n, err)
+	}
+
+	// 缺少主密钥或主密钥错误时拒绝加载
+	if _, err := NewConfigManager(configPath); err == nil {
+		t.Fatal("loading an encrypted config without master key should fail")
+	}
+	t.Setenv(EnvConfigEncryptionKey, "wrong-key")
+	if _, err := NewConfigManager(configPath); err == nil {
+		t.Fatal("loading an encrypted config with a wrong master key should fail")
+	}
+
+	t.Setenv(EnvConfigEncryptionKey, "migration-master-key")
+	cm, err := NewConfigManager(configPath)
+	if err != nil {
+		t.Fatalf("加载加密配置失败: %v", err)
+	}
+	defer cm.Close()
+	up := cm.GetConfig().Upstream[0]
+	if up.APIKeys[0] != testPlainKey || up.DisabledAPIKeys[0].Key != "sk-disabled-key-0123456789" {
+		t.Fatalf("keys should be decrypted: %+v", up)
+	}
+
+	// 加密后的备份仍可比较与恢复
+	if _, err := cm.DiffBackup(testBackupName); err != nil {
+		t.Fatalf("DiffBackup on encrypted backup failed: %v", err)
+	}
+	if err := cm.RestoreBackup(testBackupName); err != nil {
+		t.Fatalf("RestoreBackup on encrypted backup failed: %v", err)
+	}
+	if got := cm.GetConfig().Upstream[0].APIKeys[0]; got != testPlainKey {
+		t.Fatalf("restored key = %q", got)
+	}
+	assertNoPlaintextKey(t, configPath)
+}
+
+func TestKeyEncryption_HandEditedPlaintextKey(t *testing.T) {
+	t.Setenv(EnvConfigEncryptionKey, "hand-edit-key")
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cm, err := NewConfigManager(configPath)
+	if err != nil {
+		t.Fatalf("初始化配置管理器失败: %v", err)
+	}
+	defer cm.Close()
+
+	// 在已加密的配置中手工添加明文 Key，重载后可用，下次保存时被加密
+	cm.mu.Lock()
+	cm.config.Upstream = []UpstreamConfig{{ID: "id-a", Name: "a", BaseURL: "https://a.example.com", APIKeys: []string{testPlainKey}, Status: "active"}}
+	data, err := cm.marshalConfigLocked(Config{Upstream: cm.config.Upstream, Encryption: cm.config.Encryption})
+	cm.mu.Unlock()
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+	edited := strings.Replace(string(data), `"apiKeys": [`, `"apiKeys": ["sk-hand-added-key-0123456789",`, 1)
+	if err := os.WriteFile(configPath, []byte(edited), 0644); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	if err := cm.loadConfig(); err != nil {
+		t.Fatalf("重载失败: %v", err)
+	}
+	keys := cm.GetConfig().Upstream[0].APIKeys
+	if len(keys) != 2 || keys[0] != "sk-hand-added-key-0123456789" || keys[1] != testPlainKey {
+		t.Fatalf("unexpected keys after reload: %v", keys)
+	}
+	if err := cm.SaveConfig(); err != nil {
+		t.Fatalf("保存失败: %v", err)
+	}
+	saved, _ := os.ReadFile(configPath)
+	if strings.Contains(string(saved), "sk-hand-added-key-0123456789") {
+		t.Fatalf("hand-added key should be encrypted on save: %s", saved)
+	}
+}
+
+func TestRedactAndResolveAPIKeys(t *testing.T) {
+	cm := newTestConfigManager(t)
+	keys := []string{testPlainKey, "sk-other-key-9876543210"}
+	if err := cm.AddUpstream(UpstreamConfig{Name: "a", ServiceType: "claude", BaseURL: "https://a.example.com", APIKeys: keys}); err != nil {
+		t.Fatalf("AddUpstream 失败: %v", err)
+	}
+
+	// 未启用加密时原样返回
+	if got := cm.RedactAPIKeys(keys); got[0] != testPlainKey {
+		t.Fatalf("keys should not be masked without encryption: %v", got)
+	}
+
+	keyCipher, enc, err := newKeyCipher([]byte("redact-key"))
+	if err != nil {
+		t.Fatalf("newKeyCipher 失败: %v", err)
+	}
+	// 同时写回文件，避免 AddUpstream 触发的热重载覆盖手动启用的加密状态
+	cm.mu.Lock()
+	cm.masterKey, cm.keyCipher, cm.config.Encryption = []byte("redact-key"), keyCipher, enc
+	err = cm.saveConfigLocked(cm.config)
+	cm.mu.Unlock()
+	if err != nil {
+		t.Fatalf("保存加密配置失败: %v", err)
+	}
+
+	masked := cm.RedactAPIKeys(keys)
+	if masked[0] == testPlainKey || !strings.Contains(masked[0], "***") {
+		t.Fatalf("keys should be masked with encryption enabled: %v", masked)
+	}
+	if up := cm.RedactUpstream(&UpstreamConfig{APIKeys: keys, HistoricalAPIKeys: keys}); up.HistoricalAPIKeys[0] == testPlainKey {
+		t.Fatalf("RedactUpstream should mask historical keys: %+v", up)
+	}
+
+	channelID := cm.GetConfig().Upstream[0].ID
+	if got, err := cm.ResolveAPIKeyRef("messages", channelID, masked[1]); err != nil || got != keys[1] {
+		t.Fatalf("ResolveAPIKeyRef(mask) = %q, %v, want %q", got, err, keys[1])
+	}
+	if got, err := cm.ResolveAPIKeyRef("messages", channelID, "sk-new-key"); err != nil || got != "sk-new-key" {
+		t.Fatalf("plaintext refs should be returned unchanged, got %q, %v", got, err)
+	}
+	// 无法唯一还原的掩码不能被当作真实 Key
+	var cfgErr *ConfigError
+	if _, err := cm.ResolveAPIKeyRef("messages", channelID, "sk-unknown***0000"); !errors.As(err, &cfgErr) {
+		t.Fatalf("unresolvable mask should return ConfigError, got %v", err)
+	}
+	if _, err := cm.ResolveAPIKeyRefs("messages", channelID, []string{masked[0], "sk-unknown***0000"}); !errors.As(err, &cfgErr) {
+		t.Fatalf("ResolveAPIKeyRefs should reject unresolvable masks, got %v", err)
+	}
+	resolved, err := cm.ResolveAPIKeyRefs("messages", channelID, []string{masked[0], "sk-new-key"})
+	if err != nil || resolved[0] != testPlainKey || resolved[1] != "sk-new-key" {
+		t.Fatalf("ResolveAPIKeyRefs = %v", resolved)
+	}
+}
+
+func TestMasterKeyDerivation(t *testing.T) {
+	// 口令经 Argon2id 派生，每次生成新的随机盐
+	_, encA, err := newKeyCipher([]byte("passphrase"))
+	if err != nil {
+		t.Fatalf("newKeyCipher 失败: %v", err)
+	}
+	_, encB, _ := newKeyCipher([]byte("passphrase"))
+	if encA.KDF == nil || encA.KDF.Algorithm != kdfAlgorithmArgon2id || encA.KDF.Salt == encB.KDF.Salt {
+		t.Fatalf("passphrase should use argon2id with a random salt: %+v / %+v", encA.KDF, encB.KDF)
+	}
+	if _, err := unwrapKeyCipher([]byte("passphrase"), encA); err != nil {
+		t.Fatalf("unwrap with the same passphrase failed: %v", err)
+	}
+	if _, err := unwrapKeyCipher([]byte("wrong"), encA); err == nil {
+		t.Fatal("unwrap with a wrong passphrase should fail")
+	}
+
+	// 32 字节 base64 直接作为主密钥，不需要派生参数
+	rawKey := []byte("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	_, encRaw, err := newKeyCipher(rawKey)
+	if err != nil || encRaw.KDF != nil {
+		t.Fatalf("raw key should not use a KDF: %+v, %v", encRaw, err)
+	}
+	if _, err := unwrapKeyCipher(rawKey, encRaw); err != nil {
+		t.Fatalf("unwrap with raw key failed: %v", err)
+	}
+	if _, err := unwrapKeyCipher([]byte("passphrase"), encRaw); err == nil {
+		t.Fatal("passphrase must not unwrap a raw-key config")
+	}
+}
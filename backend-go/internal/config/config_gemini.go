@@ -38,11 +38,30 @@ func (cm *ConfigManager) AddGeminiUpstream(upstream UpstreamConfig) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
+	if err := validateSupportedModels(upstream.SupportedModels); err != nil {
+		return err
+	}
+	if err := validateWeight(upstream.Weight); err != nil {
+		return err
+	}
+	if err := validateStreamIdleTimeout(upstream.StreamIdleTimeout); err != nil {
+		return err
+	}
+	if err := validateStreamFirstContentTimeout(upstream.StreamFirstContentTimeout); err != nil {
+		return err
+	}
+	if err := validateBalanceCheck(upstream.BalanceCheck); err != nil {
+		return err
+	}
+
 	// 新建渠道默认设为 active
 	if upstream.Status == "" {
 		upstream.Status = "active"
 	}
 
+	// 分配渠道唯一标识
+	upstream.ID = cm.newChannelIDLocked(upstream.ID)
+
 	// 去重 API Keys 和 Base URLs
 	upstream.APIKeys = deduplicateStrings(upstream.APIKeys)
 	upstream.BaseURLs = deduplicateBaseURLs(upstream.BaseURLs)
@@ -59,12 +78,35 @@ func (cm *ConfigManager) AddGeminiUpstream(upstream UpstreamConfig) error {
 
 // UpdateGeminiUpstream 更新 Gemini 上游
 // 返回值：shouldResetMetrics 表示是否需要重置渠道指标（熔断状态）
-func (cm *ConfigManager) UpdateGeminiUpstream(index int, updates UpstreamUpdate) (shouldResetMetrics bool, err error) {
+func (cm *ConfigManager) UpdateGeminiUpstream(channelID string, updates UpstreamUpdate) (shouldResetMetrics bool, err error) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.GeminiUpstream) {
-		return false, fmt.Errorf("无效的 Gemini 上游索引: %d", index)
+	index, err := cm.channelIndexLocked("gemini", channelID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := validateSupportedModels(updates.SupportedModels); err != nil {
+		return false, err
+	}
+	if updates.Weight != nil {
+		if err := validateWeight(*updates.Weight); err != nil {
+			return false, err
+		}
+	}
+	if updates.StreamIdleTimeout != nil {
+		if err := validateStreamIdleTimeout(*updates.StreamIdleTimeout); err != nil {
+			return false, err
+		}
+	}
+	if updates.StreamFirstContentTimeout != nil {
+		if err := validateStreamFirstContentTimeout(*updates.StreamFirstContentTimeout); err != nil {
+			return false, err
+		}
+	}
+	if err := validateBalanceCheck(updates.BalanceCheck); err != nil {
+		return false, err
 	}
 
 	upstream := &cm.config.GeminiUpstream[index]
@@ -112,7 +154,7 @@ func (cm *ConfigManager) UpdateGeminiUpstream(index int, updates UpstreamUpdate)
 				}
 				if !alreadyInHistory {
 					upstream.HistoricalAPIKeys = append(upstream.HistoricalAPIKeys, key)
-					log.Printf("[Config-Upstream] Gemini 渠道 [%d] %s: Key %s 已移入历史列表", index, upstream.Name, utils.MaskAPIKey(key))
+					log.Printf("[Config-Upstream] Gemini 渠道 [%s] %s: Key %s 已移入历史列表", channelID, upstream.Name, utils.MaskAPIKey(key))
 				}
 			}
 		}
@@ -123,10 +165,11 @@ func (cm *ConfigManager) UpdateGeminiUpstream(index int, updates UpstreamUpdate)
 			if !newKeys[hk] {
 				newHistoricalKeys = append(newHistoricalKeys, hk)
 			} else {
-				log.Printf("[Config-Upstream] Gemini 渠道 [%d] %s: Key %s 已从历史列表恢复", index, upstream.Name, utils.MaskAPIKey(hk))
+				log.Printf("[Config-Upstream] Gemini 渠道 [%s] %s: Key %s 已从历史列表恢复", channelID, upstream.Name, utils.MaskAPIKey(hk))
 			}
 		}
 		upstream.HistoricalAPIKeys = newHistoricalKeys
+		upstream.removeDisabledKeys(newKeys)
 
 		// 只有单 key 场景且 key 被更换时，才自动激活并重置熔断
 		if len(upstream.APIKeys) == 1 && len(updates.APIKeys) == 1 &&
@@ -134,7 +177,7 @@ func (cm *ConfigManager) UpdateGeminiUpstream(index int, updates UpstreamUpdate)
 			shouldResetMetrics = true
 			if upstream.Status == "suspended" {
 				upstream.Status = "active"
-				log.Printf("[Config-Upstream] Gemini 渠道 [%d] %s 已从暂停状态自动激活（单 key 更换）", index, upstream.Name)
+				log.Printf("[Config-Upstream] Gemini 渠道 [%s] %s 已从暂停状态自动激活（单 key 更换）", channelID, upstream.Name)
 			}
 		}
 		upstream.APIKeys = deduplicateStrings(updates.APIKeys)
@@ -142,6 +185,12 @@ func (cm *ConfigManager) UpdateGeminiUpstream(index int, updates UpstreamUpdate)
 	if updates.ModelMapping != nil {
 		upstream.ModelMapping = updates.ModelMapping
 	}
+	if updates.SupportedModels != nil {
+		upstream.SupportedModels = deduplicateStrings(updates.SupportedModels)
+	}
+	if updates.AutoDiscoverModels != nil {
+		upstream.AutoDiscoverModels = *updates.AutoDiscoverModels
+	}
 	if updates.InsecureSkipVerify != nil {
 		upstream.InsecureSkipVerify = *updates.InsecureSkipVerify
 	}
@@ -157,6 +206,18 @@ func (cm *ConfigManager) UpdateGeminiUpstream(index int, updates UpstreamUpdate)
 	if updates.LowQuality != nil {
 		upstream.LowQuality = *updates.LowQuality
 	}
+	if updates.Weight != nil {
+		upstream.Weight = *updates.Weight
+	}
+	if updates.StreamIdleTimeout != nil {
+		upstream.StreamIdleTimeout = *updates.StreamIdleTimeout
+	}
+	if updates.StreamFirstContentTimeout != nil {
+		upstream.StreamFirstContentTimeout = *updates.StreamFirstContentTimeout
+	}
+	if updates.BalanceCheck != nil {
+		upstream.BalanceCheck = updates.BalanceCheck.Clone()
+	}
 	if updates.InjectDummyThoughtSignature != nil {
 		upstream.InjectDummyThoughtSignature = *updates.InjectDummyThoughtSignature
 	}
@@ -168,17 +229,18 @@ func (cm *ConfigManager) UpdateGeminiUpstream(index int, updates UpstreamUpdate)
 		return false, err
 	}
 
-	log.Printf("[Config-Upstream] 已更新 Gemini 上游: [%d] %s", index, cm.config.GeminiUpstream[index].Name)
+	log.Printf("[Config-Upstream] 已更新 Gemini 上游: [%s] %s", channelID, cm.config.GeminiUpstream[index].Name)
 	return shouldResetMetrics, nil
 }
 
 // RemoveGeminiUpstream 删除 Gemini 上游
-func (cm *ConfigManager) RemoveGeminiUpstream(index int) (*UpstreamConfig, error) {
+func (cm *ConfigManager) RemoveGeminiUpstream(channelID string) (*UpstreamConfig, error) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.GeminiUpstream) {
-		return nil, fmt.Errorf("无效的 Gemini 上游索引: %d", index)
+	index, err := cm.channelIndexLocked("gemini", channelID)
+	if err != nil {
+		return nil, err
 	}
 
 	removed := cm.config.GeminiUpstream[index]
@@ -196,12 +258,13 @@ func (cm *ConfigManager) RemoveGeminiUpstream(index int) (*UpstreamConfig, error
 }
 
 // AddGeminiAPIKey 添加 Gemini 上游的 API 密钥
-func (cm *ConfigManager) AddGeminiAPIKey(index int, apiKey string) error {
+func (cm *ConfigManager) AddGeminiAPIKey(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.GeminiUpstream) {
-		return fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("gemini", channelID)
+	if err != nil {
+		return err
 	}
 
 	// 检查密钥是否已存在
@@ -219,26 +282,28 @@ func (cm *ConfigManager) AddGeminiAPIKey(index int, apiKey string) error {
 		if hk != apiKey {
 			newHistoricalKeys = append(newHistoricalKeys, hk)
 		} else {
-			log.Printf("[Gemini-Key] 上游 [%d] %s: Key %s 已从历史列表恢复", index, cm.config.GeminiUpstream[index].Name, utils.MaskAPIKey(hk))
+			log.Printf("[Gemini-Key] 上游 [%s] %s: Key %s 已从历史列表恢复", channelID, cm.config.GeminiUpstream[index].Name, utils.MaskAPIKey(hk))
 		}
 	}
 	cm.config.GeminiUpstream[index].HistoricalAPIKeys = newHistoricalKeys
+	cm.config.GeminiUpstream[index].removeDisabledKeys(map[string]bool{apiKey: true})
 
 	if err := cm.saveConfigLocked(cm.config); err != nil {
 		return err
 	}
 
-	log.Printf("[Gemini-Key] 已添加API密钥到 Gemini 上游 [%d] %s", index, cm.config.GeminiUpstream[index].Name)
+	log.Printf("[Gemini-Key] 已添加API密钥到 Gemini 上游 [%s] %s", channelID, cm.config.GeminiUpstream[index].Name)
 	return nil
 }
 
 // RemoveGeminiAPIKey 删除 Gemini 上游的 API 密钥
-func (cm *ConfigManager) RemoveGeminiAPIKey(index int, apiKey string) error {
+func (cm *ConfigManager) RemoveGeminiAPIKey(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.GeminiUpstream) {
-		return fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("gemini", channelID)
+	if err != nil {
+		return err
 	}
 
 	// 查找并删除密钥
@@ -266,14 +331,14 @@ func (cm *ConfigManager) RemoveGeminiAPIKey(index int, apiKey string) error {
 	}
 	if !alreadyInHistory {
 		cm.config.GeminiUpstream[index].HistoricalAPIKeys = append(cm.config.GeminiUpstream[index].HistoricalAPIKeys, apiKey)
-		log.Printf("[Gemini-Key] 上游 [%d] %s: Key %s 已移入历史列表", index, cm.config.GeminiUpstream[index].Name, utils.MaskAPIKey(apiKey))
+		log.Printf("[Gemini-Key] 上游 [%s] %s: Key %s 已移入历史列表", channelID, cm.config.GeminiUpstream[index].Name, utils.MaskAPIKey(apiKey))
 	}
 
 	if err := cm.saveConfigLocked(cm.config); err != nil {
 		return err
 	}
 
-	log.Printf("[Gemini-Key] 已从 Gemini 上游 [%d] %s 删除API密钥", index, cm.config.GeminiUpstream[index].Name)
+	log.Printf("[Gemini-Key] 已从 Gemini 上游 [%s] %s 删除API密钥", channelID, cm.config.GeminiUpstream[index].Name)
 	return nil
 }
 
@@ -283,12 +348,13 @@ func (cm *ConfigManager) GetNextGeminiAPIKey(upstream *UpstreamConfig, failedKey
 }
 
 // MoveGeminiAPIKeyToTop 将指定 Gemini 渠道的 API 密钥移到最前面
-func (cm *ConfigManager) MoveGeminiAPIKeyToTop(upstreamIndex int, apiKey string) error {
+func (cm *ConfigManager) MoveGeminiAPIKeyToTop(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if upstreamIndex < 0 || upstreamIndex >= len(cm.config.GeminiUpstream) {
-		return fmt.Errorf("无效的上游索引: %d", upstreamIndex)
+	upstreamIndex, err := cm.channelIndexLocked("gemini", channelID)
+	if err != nil {
+		return err
 	}
 
 	upstream := &cm.config.GeminiUpstream[upstreamIndex]
@@ -309,12 +375,13 @@ func (cm *ConfigManager) MoveGeminiAPIKeyToTop(upstreamIndex int, apiKey string)
 }
 
 // MoveGeminiAPIKeyToBottom 将指定 Gemini 渠道的 API 密钥移到最后面
-func (cm *ConfigManager) MoveGeminiAPIKeyToBottom(upstreamIndex int, apiKey string) error {
+func (cm *ConfigManager) MoveGeminiAPIKeyToBottom(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if upstreamIndex < 0 || upstreamIndex >= len(cm.config.GeminiUpstream) {
-		return fmt.Errorf("无效的上游索引: %d", upstreamIndex)
+	upstreamIndex, err := cm.channelIndexLocked("gemini", channelID)
+	if err != nil {
+		return err
 	}
 
 	upstream := &cm.config.GeminiUpstream[upstreamIndex]
@@ -371,12 +438,13 @@ func (cm *ConfigManager) ReorderGeminiUpstreams(order []int) error {
 }
 
 // SetGeminiChannelStatus 设置 Gemini 渠道状态
-func (cm *ConfigManager) SetGeminiChannelStatus(index int, status string) error {
+func (cm *ConfigManager) SetGeminiChannelStatus(channelID, status string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.GeminiUpstream) {
-		return fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("gemini", channelID)
+	if err != nil {
+		return err
 	}
 
 	// 状态值转为小写，支持大小写不敏感
@@ -390,29 +458,30 @@ func (cm *ConfigManager) SetGeminiChannelStatus(index int, status string) error
 	// 暂停时清除促销期
 	if status == "suspended" && cm.config.GeminiUpstream[index].PromotionUntil != nil {
 		cm.config.GeminiUpstream[index].PromotionUntil = nil
-		log.Printf("[Config-Status] 已清除 Gemini 渠道 [%d] %s 的促销期", index, cm.config.GeminiUpstream[index].Name)
+		log.Printf("[Config-Status] 已清除 Gemini 渠道 [%s] %s 的促销期", channelID, cm.config.GeminiUpstream[index].Name)
 	}
 
 	if err := cm.saveConfigLocked(cm.config); err != nil {
 		return err
 	}
 
-	log.Printf("[Config-Status] 已设置 Gemini 渠道 [%d] %s 状态为: %s", index, cm.config.GeminiUpstream[index].Name, status)
+	log.Printf("[Config-Status] 已设置 Gemini 渠道 [%s] %s 状态为: %s", channelID, cm.config.GeminiUpstream[index].Name, status)
 	return nil
 }
 
 // SetGeminiChannelPromotion 设置 Gemini 渠道促销期
-func (cm *ConfigManager) SetGeminiChannelPromotion(index int, duration time.Duration) error {
+func (cm *ConfigManager) SetGeminiChannelPromotion(channelID string, duration time.Duration) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.GeminiUpstream) {
-		return fmt.Errorf("无效的 Gemini 上游索引: %d", index)
+	index, err := cm.channelIndexLocked("gemini", channelID)
+	if err != nil {
+		return err
 	}
 
 	if duration <= 0 {
 		cm.config.GeminiUpstream[index].PromotionUntil = nil
-		log.Printf("[Config-Promotion] 已清除 Gemini 渠道 [%d] %s 的促销期", index, cm.config.GeminiUpstream[index].Name)
+		log.Printf("[Config-Promotion] 已清除 Gemini 渠道 [%s] %s 的促销期", channelID, cm.config.GeminiUpstream[index].Name)
 	} else {
 		// 清除其他渠道的促销期（同一时间只允许一个促销渠道）
 		for i := range cm.config.GeminiUpstream {
@@ -422,7 +491,7 @@ func (cm *ConfigManager) SetGeminiChannelPromotion(index int, duration time.Dura
 		}
 		promotionEnd := time.Now().Add(duration)
 		cm.config.GeminiUpstream[index].PromotionUntil = &promotionEnd
-		log.Printf("[Config-Promotion] 已设置 Gemini 渠道 [%d] %s 进入促销期，截止: %s", index, cm.config.GeminiUpstream[index].Name, promotionEnd.Format(time.RFC3339))
+		log.Printf("[Config-Promotion] 已设置 Gemini 渠道 [%s] %s 进入促销期，截止: %s", channelID, cm.config.GeminiUpstream[index].Name, promotionEnd.Format(time.RFC3339))
 	}
 
 	return cm.saveConfigLocked(cm.config)
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"fmt"
+	"log"
+)
+
+// ============== 后台健康探测配置 ==============
+
+// 健康探测默认参数
+const (
+	DefaultHealthProbeInterval     = 300  // 探测周期（秒）
+	DefaultHealthProbeIdle         = 1800 // Key 空闲超过该时间才探测（秒）
+	DefaultHealthProbeTimeout      = 30   // 单次探测超时（秒）
+	DefaultHealthProbeMaxPerCycle  = 20   // 每个周期最多发送的探测请求数
+	DefaultHealthProbePrompt       = "ping"
+	minHealthProbeInterval         = 30
+	maxHealthProbeTimeout          = 120
+	maxHealthProbeMaxPerCycle      = 200
+	healthProbeMaxPromptCharacters = 1000
+)
+
+// defaultHealthProbeModels 各入口类型默认的探测模型（选择各家最便宜的模型）
+var defaultHealthProbeModels = map[string]string{
+	"messages":  "claude-3-5-haiku-20241022",
+	"responses": "gpt-4o-mini",
+	"gemini":    "gemini-2.0-flash",
+	"chat":      "gpt-4o-mini",
+}
+
+// HealthProbeTarget 单个入口类型的探测请求内容
+type HealthProbeTarget struct {
+	Model  string `json:"model,omitempty"`  // 探测模型（会经过渠道模型重定向）；为空时使用默认模型
+	Prompt string `json:"prompt,omitempty"` // 探测提示词；为空时使用 "ping"
+}
+
+// HealthProbeConfig 后台健康探测配置
+type HealthProbeConfig struct {
+	Enabled         bool `json:"enabled"`
+	IntervalSeconds int  `json:"intervalSeconds,omitempty"` // 探测周期，默认 300 秒，最小 30 秒
+	IdleSeconds     int  `json:"idleSeconds,omitempty"`     // Key 空闲超过该时间才探测，默认 1800 秒；-1 表示不探测空闲 Key
+	TimeoutSeconds  int  `json:"timeoutSeconds,omitempty"`  // 单次探测超时，默认 30 秒
+	MaxPerCycle     int  `json:"maxPerCycle,omitempty"`     // 每个周期最多探测次数，默认 20
+	AutoRestore     bool `json:"autoRestore"`               // 探测成功后自动将 suspended 渠道恢复为 active
+	// 按入口类型（messages/responses/gemini/chat）配置探测模型与提示词
+	Targets map[string]HealthProbeTarget `json:"targets,omitempty"`
+}
+
+// Clone 深拷贝健康探测配置
+func (h *HealthProbeConfig) Clone() *HealthProbeConfig {
+	if h == nil {
+		return nil
+	}
+	cloned := *h
+	if h.Targets != nil {
+		cloned.Targets = make(map[string]HealthProbeTarget, len(h.Targets))
+		for k, v := range h.Targets {
+			cloned.Targets[k] = v
+		}
+	}
+	return &cloned
+}
+
+// Interval 探测周期（秒）
+func (h *HealthProbeConfig) Interval() int {
+	if h.IntervalSeconds <= 0 {
+		return DefaultHealthProbeInterval
+	}
+	return h.IntervalSeconds
+}
+
+// Idle Key 空闲阈值（秒），返回 0 表示不探测空闲 Key
+func (h *HealthProbeConfig) Idle() int {
+	switch {
+	case h.IdleSeconds < 0:
+		return 0
+	case h.IdleSeconds == 0:
+		return DefaultHealthProbeIdle
+	default:
+		return h.IdleSeconds
+	}
+}
+
+// Timeout 单次探测超时（秒）
+func (h *HealthProbeConfig) Timeout() int {
+	if h.TimeoutSeconds <= 0 {
+		return DefaultHealthProbeTimeout
+	}
+	return h.TimeoutSeconds
+}
+
+// ProbesPerCycle 每个周期最多探测次数
+func (h *HealthProbeConfig) ProbesPerCycle() int {
+	if h.MaxPerCycle <= 0 {
+		return DefaultHealthProbeMaxPerCycle
+	}
+	return h.MaxPerCycle
+}
+
+// TargetForKind 获取入口类型的探测模型与提示词（未配置的字段使用默认值）
+func (h *HealthProbeConfig) TargetForKind(kind string) HealthProbeTarget {
+	target := h.Targets[kind]
+	if target.Model == "" {
+		target.Model = defaultHealthProbeModels[kind]
+	}
+	if target.Prompt == "" {
+		target.Prompt = DefaultHealthProbePrompt
+	}
+	return target
+}
+
+// Validate 验证健康探测配置
+func (h *HealthProbeConfig) Validate() error {
+	if h.IntervalSeconds != 0 && h.IntervalSeconds < minHealthProbeInterval {
+		return &ConfigError{Message: fmt.Sprintf("探测周期不能小于 %d 秒", minHealthProbeInterval)}
+	}
+	if h.IdleSeconds < -1 {
+		return &ConfigError{Message: fmt.Sprintf("无效的空闲阈值: %d", h.IdleSeconds)}
+	}
+	if h.TimeoutSeconds < 0 || h.TimeoutSeconds > maxHealthProbeTimeout {
+		return &ConfigError{Message: fmt.Sprintf("无效的探测超时: %d（有效范围 0-%d 秒）", h.TimeoutSeconds, maxHealthProbeTimeout)}
+	}
+	if h.MaxPerCycle < 0 || h.MaxPerCycle > maxHealthProbeMaxPerCycle {
+		return &ConfigError{Message: fmt.Sprintf("无效的单周期探测次数: %d（有效范围 0-%d）", h.MaxPerCycle, maxHealthProbeMaxPerCycle)}
+	}
+	for kind, target := range h.Targets {
+		if _, ok := defaultHealthProbeModels[kind]; !ok {
+			return &ConfigError{Message: "无效的探测入口类型: " + kind}
+		}
+		if len([]rune(target.Prompt)) > healthProbeMaxPromptCharacters {
+			return &ConfigError{Message: fmt.Sprintf("%s 探测提示词过长（最多 %d 字符）", kind, healthProbeMaxPromptCharacters)}
+		}
+	}
+	return nil
+}
+
+// GetHealthProbeConfig 获取健康探测配置（未配置时返回默认值：关闭、探测成功自动恢复）
+func (cm *ConfigManager) GetHealthProbeConfig() *HealthProbeConfig {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.config.HealthProbe == nil {
+		return &HealthProbeConfig{AutoRestore: true}
+	}
+	return cm.config.HealthProbe.Clone()
+}
+
+// SetHealthProbeConfig 整体替换健康探测配置
+func (cm *ConfigManager) SetHealthProbeConfig(probe *HealthProbeConfig) error {
+	if probe == nil {
+		return &ConfigError{Message: "健康探测配置不能为空"}
+	}
+	if err := probe.Validate(); err != nil {
+		return err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.config.HealthProbe = probe.Clone()
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+
+	status := "关闭"
+	if probe.Enabled {
+		status = "启用"
+	}
+	log.Printf("[Config-HealthProbe] 后台健康探测已%s（周期 %ds）", status, probe.Interval())
+	return nil
+}
+
+// RestoreSuspendedChannel 健康探测成功后将暂停的渠道恢复为 active（记为系统变更）
+// 按渠道标识定位；渠道已被删除或不处于 suspended 状态（如探测期间被手动修改）时返回 -1
+func (cm *ConfigManager) RestoreSuspendedChannel(kind, channelID string) (int, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	defer cm.useChangeSourceLocked(ChangeSourceHealthProbe)()
+
+	upstreams := cm.config.UpstreamsForKind(kind)
+	for i := range upstreams {
+		if upstreams[i].ID != channelID {
+			continue
+		}
+		if upstreams[i].Status != "suspended" {
+			return -1, nil
+		}
+		upstreams[i].Status = "active"
+		if err := cm.saveConfigLocked(cm.config); err != nil {
+			return -1, err
+		}
+		return i, nil
+	}
+	return -1, nil
+}
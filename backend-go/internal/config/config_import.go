@@ -0,0 +1,527 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/pricing"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// ============== 声明式配置导入/导出 ==============
+
+// 导入模式
+const (
+	ImportModeMerge   = "merge"   // 按标识/名称合并渠道，文档中出现的顶层设置覆盖当前值
+	ImportModeReplace = "replace" // 以文档整体替换当前配置
+)
+
+// 配置文档格式
+const (
+	ConfigFormatJSON = "json"
+	ConfigFormatYAML = "yaml"
+)
+
+// channelListFields 顶层配置中的渠道列表字段（合并模式下逐个渠道合并，而非整体覆盖）
+var channelListFields = map[string]string{
+	"upstream":          "messages",
+	"responsesUpstream": "responses",
+	"geminiUpstream":    "gemini",
+	"chatUpstream":      "chat",
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Mode   string // merge（默认）/replace
+	DryRun bool   // 仅校验并返回计划变更，不写入配置
+}
+
+// ImportResult 导入结果（dry-run 时为计划变更）
+type ImportResult struct {
+	Mode     string                `json:"mode"`
+	DryRun   bool                  `json:"dryRun"`
+	Applied  bool                  `json:"applied"`
+	Channels []ConfigChannelChange `json:"channels"`
+	Settings []ConfigFieldChange   `json:"settings"`
+	Warnings []string              `json:"warnings"`
+}
+
+// ValidationError 导入校验失败，包含全部问题而非仅第一个
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return "配置校验失败: " + strings.Join(e.Errors, "; ")
+}
+
+// NormalizeConfigFormat 解析格式参数（支持 Content-Type），无法识别时返回空字符串（按内容自动识别）
+func NormalizeConfigFormat(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch {
+	case format == "json" || strings.Contains(format, "/json"):
+		return ConfigFormatJSON
+	case format == "yaml" || format == "yml" || strings.Contains(format, "yaml"):
+		return ConfigFormatYAML
+	}
+	return ""
+}
+
+// ExportConfig 导出当前配置
+// redact 为 true 时 API Key、告警 Webhook URL/签名密钥与客户端 keyHash 以掩码导出（重新导入时按原配置还原）；
+// 启用 Key 加密时始终掩码，避免通过导出绕过加密取得明文 Key。
+// 加密元数据不导出：导出的文档与部署无关，导入时按目标实例的加密设置重新加密
+func (cm *ConfigManager) ExportConfig(redact bool) Config {
+	cfg := cm.GetConfig()
+	if cfg.Encryption != nil {
+		redact = true
+	}
+	cfg.Encryption = nil
+	if !redact {
+		return cfg
+	}
+
+	cfg, _ = transformConfigKeys(cfg, func(key string) (string, error) {
+		return utils.MaskAPIKey(key), nil
+	})
+	cfg.Alerts = cfg.Alerts.Redacted()
+	if cfg.Clients != nil {
+		clients := make([]ClientKey, len(cfg.Clients))
+		for i := range cfg.Clients {
+			clients[i] = *cfg.Clients[i].Clone()
+			clients[i].KeyHash = utils.MaskAPIKey(clients[i].KeyHash)
+		}
+		cfg.Clients = clients
+	}
+	return cfg
+}
+
+// MarshalConfigDocument 按指定格式序列化配置文档
+func MarshalConfigDocument(cfg Config, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil || format != ConfigFormatYAML {
+		return data, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// parseConfigDocument 解析 JSON/YAML 配置文档，返回规范化的 JSON 与顶层字段
+// 未知字段视为错误，避免拼写错误的配置被静默忽略
+func parseConfigDocument(data []byte, format string) ([]byte, map[string]json.RawMessage, Config, error) {
+	var cfg Config
+	if format == "" {
+		format = ConfigFormatYAML
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+			format = ConfigFormatJSON
+		}
+	}
+
+	if format == ConfigFormatYAML {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, nil, cfg, &ConfigError{Message: fmt.Sprintf("YAML 解析失败: %v", err)}
+		}
+		converted, err := json.Marshal(doc)
+		if err != nil {
+			return nil, nil, cfg, &ConfigError{Message: fmt.Sprintf("YAML 转换失败: %v", err)}
+		}
+		data = converted
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return nil, nil, cfg, &ConfigError{Message: "配置文档必须是对象"}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, nil, cfg, &ConfigError{Message: fmt.Sprintf("配置文档解析失败: %v", err)}
+	}
+	return data, fields, cfg, nil
+}
+
+// ImportConfig 导入声明式配置（JSON/YAML）
+// 先在副本上合并、补默认值并完整校验，全部通过后才替换当前配置；DryRun 只返回计划变更
+func (cm *ConfigManager) ImportConfig(data []byte, format string, opts ImportOptions) (*ImportResult, error) {
+	if opts.Mode == "" {
+		opts.Mode = ImportModeMerge
+	}
+	if opts.Mode != ImportModeMerge && opts.Mode != ImportModeReplace {
+		return nil, &ConfigError{Message: "无效的导入模式: " + opts.Mode + " (可选: merge, replace)"}
+	}
+
+	raw, fields, incoming, err := parseConfigDocument(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	// 直接导入本实例的加密配置文件时，用当前主密钥解密，并以解密后的内容作为文档字段
+	if incoming.Encryption != nil {
+		if incoming, _, err = cm.decryptConfigLocked(incoming); err != nil {
+			return nil, &ConfigError{Message: fmt.Sprintf("无法解密文档中的 API Key: %v", err)}
+		}
+		incoming.Encryption = nil
+		if raw, err = json.Marshal(incoming); err != nil {
+			return nil, err
+		}
+		fields = nil
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, err
+		}
+	}
+
+	current := cm.config
+	target, raw, err := buildImportTarget(&current, incoming, fields, raw, opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Mode: opts.Mode, DryRun: opts.DryRun, Warnings: []string{}}
+	errs := resolveImportedKeys(&current, &target)
+	errs = append(errs, resolveImportedSecrets(&current, &target)...)
+	errs = append(errs, validateImportedConfig(&target)...)
+	if len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+
+	// 按加载配置的流程补默认值并自检 Key；仅旧格式文档（含 currentUpstream）按旧规则迁移状态，
+	// 其余未填写状态的渠道与新建渠道一致默认为 active
+	scratch := &ConfigManager{config: target}
+	scratch.applyConfigDefaults(raw)
+	_, legacyMessages := fields["currentUpstream"]
+	_, legacyResponses := fields["currentResponsesUpstream"]
+	if legacyMessages || legacyResponses {
+		scratch.migrateOldFormat()
+	}
+	for _, kind := range channelKinds {
+		upstreams := scratch.config.UpstreamsForKind(kind)
+		for i := range upstreams {
+			if upstreams[i].Status == "" {
+				upstreams[i].Status = "active"
+			}
+		}
+	}
+	if errs := validateImportedSettings(&scratch.config); len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+	for _, kind := range channelKinds {
+		upstreams := scratch.config.UpstreamsForKind(kind)
+		for i := range upstreams {
+			if upstreams[i].Status == "active" && len(upstreams[i].APIKeys) == 0 {
+				result.Warnings = append(result.Warnings,
+					fmt.Sprintf("%s 渠道 [%d] %s 没有配置 API key，将自动暂停", kind, i, upstreams[i].Name))
+			}
+		}
+	}
+	scratch.validateChannelKeys()
+	scratch.ensureChannelIDs()
+	target = scratch.config
+	target.Encryption = current.Encryption
+
+	result.Channels, result.Settings = DiffConfigs(&current, &target)
+	if opts.DryRun {
+		return result, nil
+	}
+
+	cm.config = target
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		cm.config = current
+		return nil, err
+	}
+	result.Applied = true
+
+	log.Printf("[Config-Import] 已导入配置（%s 模式）: %d 个渠道变更, %d 项设置变更",
+		opts.Mode, len(result.Channels), len(result.Settings))
+	return result, nil
+}
+
+// buildImportTarget 按导入模式生成目标配置，返回目标配置与用于默认值检测的 JSON
+func buildImportTarget(current *Config, incoming Config, fields map[string]json.RawMessage, raw []byte, mode string) (Config, []byte, error) {
+	if mode == ImportModeReplace {
+		for _, kind := range channelKinds {
+			adoptChannelIDs(incoming.UpstreamsForKind(kind), current.UpstreamsForKind(kind))
+		}
+		return incoming, raw, nil
+	}
+
+	// 合并模式：文档中出现的顶层设置覆盖当前值（对象整体覆盖），渠道逐个合并
+	merged := toJSONMap(*current)
+	for field, value := range fields {
+		if _, isChannelList := channelListFields[field]; isChannelList || field == "encryption" {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(value, &v); err != nil {
+			return Config{}, nil, &ConfigError{Message: fmt.Sprintf("字段 %s 解析失败: %v", field, err)}
+		}
+		merged[field] = v
+	}
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	var target Config
+	if err := json.Unmarshal(mergedJSON, &target); err != nil {
+		return Config{}, nil, &ConfigError{Message: fmt.Sprintf("合并配置失败: %v", err)}
+	}
+	for field, kind := range channelListFields {
+		value, ok := fields[field]
+		if !ok {
+			continue
+		}
+		var items []map[string]json.RawMessage
+		if err := json.Unmarshal(value, &items); err != nil {
+			return Config{}, nil, &ConfigError{Message: fmt.Sprintf("字段 %s 解析失败: %v", field, err)}
+		}
+		merged, err := mergeUpstreams(current.UpstreamsForKind(kind), incoming.UpstreamsForKind(kind), items)
+		if err != nil {
+			return Config{}, nil, err
+		}
+		*target.upstreamListForKind(kind) = merged
+	}
+	return target, mergedJSON, nil
+}
+
+// mergeUpstreams 合并渠道列表：匹配到的渠道按文档中出现的字段覆盖（沿用原标识与位置），未匹配的追加到末尾
+// items 为文档中各渠道的原始字段，用于区分“未填写”与“填写为零值”
+func mergeUpstreams(current, incoming []UpstreamConfig, items []map[string]json.RawMessage) ([]UpstreamConfig, error) {
+	result := make([]UpstreamConfig, len(current))
+	for i := range current {
+		result[i] = *current[i].Clone()
+	}
+
+	used := make(map[int]bool)
+	for i := range incoming {
+		j := matchUpstream(&incoming[i], current, used)
+		if j < 0 {
+			result = append(result, incoming[i])
+			continue
+		}
+		used[j] = true
+
+		fields := toJSONMap(current[j])
+		for field, value := range items[i] {
+			var v interface{}
+			if err := json.Unmarshal(value, &v); err != nil {
+				return nil, &ConfigError{Message: fmt.Sprintf("渠道 %s 字段 %s 解析失败: %v", incoming[i].Name, field, err)}
+			}
+			fields[field] = v
+		}
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		var up UpstreamConfig
+		if err := json.Unmarshal(data, &up); err != nil {
+			return nil, &ConfigError{Message: fmt.Sprintf("合并渠道 %s 失败: %v", incoming[i].Name, err)}
+		}
+		up.ID = current[j].ID
+		result[j] = up
+	}
+	return result, nil
+}
+
+// upstreamListForKind 返回指定入口渠道列表的指针（用于整体替换）
+func (c *Config) upstreamListForKind(kind string) *[]UpstreamConfig {
+	switch kind {
+	case "responses":
+		return &c.ResponsesUpstream
+	case "gemini":
+		return &c.GeminiUpstream
+	case "chat":
+		return &c.ChatUpstream
+	default:
+		return &c.Upstream
+	}
+}
+
+// resolveImportedKeys 将文档中的掩码 Key（来自 redact 导出）还原为当前对应渠道的实际 Key
+// 无法唯一还原的掩码与无法解密的密文视为错误，避免写入不可用的 Key
+func resolveImportedKeys(current, target *Config) []string {
+	var errs []string
+	for _, kind := range channelKinds {
+		currentList := current.UpstreamsForKind(kind)
+		targetList := target.UpstreamsForKind(kind)
+		for i := range targetList {
+			up := &targetList[i]
+			var existing *UpstreamConfig
+			for j := range currentList {
+				if up.ID != "" && currentList[j].ID == up.ID {
+					existing = &currentList[j]
+					break
+				}
+			}
+
+			resolve := func(key string) string {
+				if strings.HasPrefix(key, encryptedValuePrefix) {
+					errs = append(errs, fmt.Sprintf("%s 渠道 [%d] %s: 文档包含无法解密的 Key", kind, i, up.Name))
+					return key
+				}
+				if !strings.Contains(key, "***") {
+					return key
+				}
+				if existing != nil {
+					if resolved, ok := resolveMaskedKey(existing, key); ok {
+						return resolved
+					}
+					for _, historical := range existing.HistoricalAPIKeys {
+						if utils.MaskAPIKey(historical) == key {
+							return historical
+						}
+					}
+				}
+				errs = append(errs, fmt.Sprintf("%s 渠道 [%d] %s: 无法还原掩码 Key %s", kind, i, up.Name, key))
+				return key
+			}
+			for k := range up.APIKeys {
+				up.APIKeys[k] = resolve(up.APIKeys[k])
+			}
+			for k := range up.HistoricalAPIKeys {
+				up.HistoricalAPIKeys[k] = resolve(up.HistoricalAPIKeys[k])
+			}
+			for k := range up.DisabledAPIKeys {
+				up.DisabledAPIKeys[k].Key = resolve(up.DisabledAPIKeys[k].Key)
+			}
+		}
+	}
+	return errs
+}
+
+// resolveImportedSecrets 将文档中掩码的告警 Webhook URL/签名密钥（按目标名称）与客户端 keyHash（按客户端标识）还原为当前值
+func resolveImportedSecrets(current, target *Config) []string {
+	var errs []string
+	if target.Alerts != nil {
+		for i := range target.Alerts.Targets {
+			resolved, err := resolveMaskedAlertTarget(current.Alerts, target.Alerts.Targets[i])
+			if err != nil {
+				errs = append(errs, "告警: "+err.Error())
+				continue
+			}
+			target.Alerts.Targets[i] = resolved
+		}
+	}
+
+	for i := range target.Clients {
+		client := &target.Clients[i]
+		if !strings.Contains(client.KeyHash, "***") {
+			continue
+		}
+		restored := false
+		for j := range current.Clients {
+			if current.Clients[j].ID == client.ID && utils.MaskAPIKey(current.Clients[j].KeyHash) == client.KeyHash {
+				client.KeyHash, restored = current.Clients[j].KeyHash, true
+				break
+			}
+		}
+		if !restored {
+			errs = append(errs, fmt.Sprintf("客户端 [%d] %s: 无法还原掩码 keyHash", i, client.Name))
+		}
+	}
+	return errs
+}
+
+// validateImportedConfig 校验渠道、客户端、价格、健康探测与告警配置，收集全部错误
+func validateImportedConfig(cfg *Config) []string {
+	var errs []string
+	addErr := func(prefix string, err error) {
+		if err != nil {
+			errs = append(errs, prefix+": "+err.Error())
+		}
+	}
+
+	ids := make(map[string]string)
+	for _, kind := range channelKinds {
+		upstreams := cfg.UpstreamsForKind(kind)
+		for i := range upstreams {
+			up := &upstreams[i]
+			prefix := fmt.Sprintf("%s 渠道 [%d] %s", kind, i, up.Name)
+
+			if strings.TrimSpace(up.Name) == "" {
+				errs = append(errs, prefix+": 缺少渠道名称")
+			}
+			for _, baseURL := range append([]string{up.BaseURL}, up.BaseURLs...) {
+				if baseURL == "" && len(up.BaseURLs) > 0 {
+					continue
+				}
+				if u, err := url.Parse(baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					errs = append(errs, prefix+": 无效的 baseUrl: "+baseURL)
+				}
+			}
+			switch up.Status {
+			case "", "active", "suspended", "disabled":
+			default:
+				errs = append(errs, prefix+": 无效的渠道状态: "+up.Status)
+			}
+			addErr(prefix, validateWeight(up.Weight))
+			addErr(prefix, validateStreamIdleTimeout(up.StreamIdleTimeout))
+			addErr(prefix, validateStreamFirstContentTimeout(up.StreamFirstContentTimeout))
+			addErr(prefix, validateSupportedModels(up.SupportedModels))
+			addErr(prefix, validateBalanceCheck(up.BalanceCheck))
+
+			if id := strings.TrimSpace(up.ID); id != "" {
+				if other, dup := ids[id]; dup {
+					errs = append(errs, fmt.Sprintf("%s: 渠道标识 %s 与 %s 重复", prefix, id, other))
+				}
+				ids[id] = prefix
+			}
+		}
+	}
+
+	clientIDs := make(map[string]bool)
+	for i := range cfg.Clients {
+		client := &cfg.Clients[i]
+		prefix := fmt.Sprintf("客户端 [%d] %s", i, client.Name)
+		if client.ID == "" || client.KeyHash == "" {
+			errs = append(errs, prefix+": 缺少 id 或 keyHash")
+		} else if clientIDs[client.ID] {
+			errs = append(errs, prefix+": 客户端标识重复: "+client.ID)
+		}
+		clientIDs[client.ID] = true
+		addErr(prefix, validateClientLimits(client.DailyTokenLimit, client.MonthlyTokenLimit, client.RPMLimit))
+		addErr(prefix, validateClientScopes(client.AllowedKinds, client.AllowedModels))
+	}
+
+	for pattern, price := range cfg.ModelPrices {
+		addErr("模型价格 "+pattern, pricing.ValidatePattern(pattern))
+		addErr("模型价格 "+pattern, price.Validate())
+	}
+	if cfg.HealthProbe != nil {
+		addErr("健康探测", cfg.HealthProbe.Validate())
+	}
+	if cfg.Alerts != nil {
+		addErr("告警", cfg.Alerts.Validate())
+	}
+	return errs
+}
+
+// validateImportedSettings 校验补默认值之后的负载均衡策略
+func validateImportedSettings(cfg *Config) []string {
+	var errs []string
+	strategies := []struct{ field, value string }{
+		{"loadBalance", cfg.LoadBalance},
+		{"responsesLoadBalance", cfg.ResponsesLoadBalance},
+		{"geminiLoadBalance", cfg.GeminiLoadBalance},
+		{"chatLoadBalance", cfg.ChatLoadBalance},
+	}
+	for _, s := range strategies {
+		if err := validateLoadBalanceStrategy(s.value); err != nil {
+			errs = append(errs, s.field+": "+err.Error())
+		}
+	}
+	return errs
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportImport_RedactedRoundTrip(t *testing.T) {
+	cm := setupBackupTest(t)
+	const hookURL = "https://hooks.slack.com/services/T000/B000/secret-token"
+	if err := cm.SetAlertConfig(&AlertConfig{Targets: []AlertTarget{{Name: "ops", Type: AlertTargetSlack, URL: hookURL, Secret: "sign-secret-value"}}}); err != nil {
+		t.Fatalf("SetAlertConfig 失败: %v", err)
+	}
+	client, _, err := cm.AddClient(ClientKey{Name: "team-a"}, "")
+	if err != nil {
+		t.Fatalf("AddClient 失败: %v", err)
+	}
+
+	data, err := MarshalConfigDocument(cm.ExportConfig(true), ConfigFormatYAML)
+	if err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	for _, secret := range []string{"sk-current-aaaaaaaaaaaa", "secret-token", "sign-secret-value", client.KeyHash} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("redacted export must not contain %q:\n%s", secret, data)
+		}
+	}
+
+	// 掩码 Key 按渠道还原，重新导入不产生任何变更
+	result, err := cm.ImportConfig(data, "", ImportOptions{Mode: ImportModeReplace})
+	if err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if len(result.Channels) != 0 || len(result.Settings) != 0 || !result.Applied {
+		t.Fatalf("round trip should be a no-op: %+v", result)
+	}
+	cfg := cm.GetConfig()
+	if got := cfg.Upstream[0].APIKeys[0]; got != "sk-current-aaaaaaaaaaaa" {
+		t.Fatalf("masked key should resolve to the original, got %q", got)
+	}
+	if got := cfg.Alerts.Targets[0]; got.URL != hookURL || got.Secret != "sign-secret-value" {
+		t.Fatalf("masked alert target should resolve to the original, got %+v", got)
+	}
+	if got := cfg.Clients[0].KeyHash; got != client.KeyHash {
+		t.Fatalf("masked keyHash should resolve to the original, got %q", got)
+	}
+}
+
+func TestExportConfig_AlwaysRedactedWithEncryption(t *testing.T) {
+	t.Setenv(EnvConfigEncryptionKey, "correct horse battery staple")
+	cm, err := NewConfigManager(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("初始化配置管理器失败: %v", err)
+	}
+	defer cm.Close()
+	if err := cm.AddUpstream(UpstreamConfig{Name: "a", ServiceType: "claude", BaseURL: "https://a.example.com", APIKeys: []string{testPlainKey}}); err != nil {
+		t.Fatalf("AddUpstream 失败: %v", err)
+	}
+
+	// 启用 Key 加密时即使请求明文导出也返回掩码
+	if got := cm.ExportConfig(false).Upstream[0].APIKeys[0]; got == testPlainKey || !strings.Contains(got, "***") {
+		t.Fatalf("export must stay redacted when key encryption is configured, got %q", got)
+	}
+}
+
+func TestImportConfig_MergeDryRunAndApply(t *testing.T) {
+	cm := setupBackupTest(t)
+	doc := `
+loadBalance: round-robin
+upstream:
+  - name: a
+    weight: 3
+  - name: d
+    serviceType: claude
+    baseUrl: https://d.example.com
+    apiKeys: [sk-new-dddddddddddd]
+`
+
+	plan, err := cm.ImportConfig([]byte(doc), ConfigFormatYAML, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry-run 失败: %v", err)
+	}
+	if plan.Applied || plan.Mode != ImportModeMerge {
+		t.Fatalf("unexpected dry-run result: %+v", plan)
+	}
+	if len(plan.Channels) != 2 || plan.Channels[0].Name != "a" || plan.Channels[0].Change != ChannelChangeChanged ||
+		plan.Channels[1].Name != "d" || plan.Channels[1].Change != ChannelChangeAdded {
+		t.Fatalf("unexpected planned channel changes: %+v", plan.Channels)
+	}
+	if cm.GetConfig().LoadBalance != "failover" {
+		t.Fatal("dry-run must not modify the config")
+	}
+
+	if _, err := cm.ImportConfig([]byte(doc), ConfigFormatYAML, ImportOptions{}); err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	cfg := cm.GetConfig()
+	if cfg.LoadBalance != "round-robin" || len(cfg.Upstream) != 3 {
+		t.Fatalf("merge not applied: %+v", cfg)
+	}
+	// 合并只覆盖文档中出现的字段，未列出的渠道保持不变
+	a := cfg.Upstream[0]
+	if a.ID != "id-a" || a.Weight != 3 || a.APIKeys[0] != "sk-current-aaaaaaaaaaaa" || a.BaseURL != "https://a.example.com" {
+		t.Fatalf("channel a should be merged field by field: %+v", a)
+	}
+	if cfg.Upstream[1].Name != "b" || cfg.Upstream[2].Name != "d" || cfg.Upstream[2].ID == "" {
+		t.Fatalf("unexpected upstreams after merge: %+v", cfg.Upstream)
+	}
+}
+
+func TestImportConfig_Replace(t *testing.T) {
+	cm := setupBackupTest(t)
+	doc := `{"upstream": [{"name": "a", "serviceType": "claude", "baseUrl": "https://a.example.com", "apiKeys": ["sk-replaced-aaaaaaaaaa"]}], "loadBalance": "failover"}`
+
+	result, err := cm.ImportConfig([]byte(doc), ConfigFormatJSON, ImportOptions{Mode: ImportModeReplace})
+	if err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	cfg := cm.GetConfig()
+	if len(cfg.Upstream) != 1 || cfg.Upstream[0].ID != "id-a" || cfg.Upstream[0].Status != "active" {
+		t.Fatalf("replace should keep only channel a with its id: %+v", cfg.Upstream)
+	}
+	removed := false
+	for _, ch := range result.Channels {
+		removed = removed || (ch.Name == "b" && ch.Change == ChannelChangeRemoved)
+	}
+	if !removed {
+		t.Fatalf("channel b should be reported as removed: %+v", result.Channels)
+	}
+}
+
+func TestImportConfig_Validation(t *testing.T) {
+	cm := setupBackupTest(t)
+	doc := `{
+		"loadBalance": "fastest",
+		"upstream": [
+			{"name": "x", "serviceType": "claude", "baseUrl": "ftp://x", "apiKeys": ["k"], "weight": -1},
+			{"id": "id-b", "name": "y", "serviceType": "claude", "baseUrl": "https://y.example.com", "apiKeys": ["sk-unknown***mask1"]}
+		],
+		"responsesUpstream": [{"id": "id-b", "name": "z", "serviceType": "responses", "baseUrl": "https://z.example.com"}]
+	}`
+
+	_, err := cm.ImportConfig([]byte(doc), "", ImportOptions{Mode: ImportModeReplace, DryRun: true})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	// 一次返回全部问题：无法还原的掩码、baseUrl、权重与重复标识
+	if len(validationErr.Errors) != 4 || !strings.Contains(validationErr.Errors[0], "掩码") {
+		t.Fatalf("unexpected errors: %v", validationErr.Errors)
+	}
+
+	doc = strings.NewReplacer(`sk-unknown***mask1`, `sk-plain-key`, `"ftp://x"`, `"https://x.example.com"`, `"weight": -1`, `"weight": 1`, `"id": "id-b", "name": "z"`, `"name": "z"`).Replace(doc)
+	_, err = cm.ImportConfig([]byte(doc), "", ImportOptions{Mode: ImportModeReplace, DryRun: true})
+	// responsesLoadBalance 未配置时沿用 loadBalance，同样报告
+	if !errors.As(err, &validationErr) || len(validationErr.Errors) != 2 || !strings.HasPrefix(validationErr.Errors[0], "loadBalance") {
+		t.Fatalf("expected load balance errors, got %v", err)
+	}
+
+	var cfgErr *ConfigError
+	if _, err := cm.ImportConfig([]byte(`{"loadBalanse": "failover"}`), "", ImportOptions{}); !errors.As(err, &cfgErr) {
+		t.Fatalf("unknown fields should be rejected, got %v", err)
+	}
+	if _, err := cm.ImportConfig([]byte(`{}`), "", ImportOptions{Mode: "upsert"}); !errors.As(err, &cfgErr) {
+		t.Fatalf("invalid mode should be rejected, got %v", err)
+	}
+	if cm.GetConfig().LoadBalance != "failover" {
+		t.Fatal("failed imports must not modify the config")
+	}
+}
@@ -19,18 +19,27 @@
 
 // NewConfigManager 创建配置管理器
 func NewConfigManager(configFile string) (*ConfigManager, error) {
+	masterKey, err := LoadMasterKeyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
 	cm := &ConfigManager{
 		configFile:      configFile,
 		failedKeysCache: make(map[string]*FailedKey),
 		keyRecoveryTime: keyRecoveryTime,
 		maxFailureCount: maxFailureCount,
 		stopChan:        make(chan struct{}),
+		masterKey:       masterKey,
 	}
 
 	// 加载配置
 	if err := cm.loadConfig(); err != nil {
 		return nil, err
 	}
+	if masterKey != nil && !cm.KeyEncryptionEnabled() {
+		log.Printf("[Config-Encryption] 警告: 已设置主密钥但配置文件中的 API Key 尚未加密，请运行 encrypt-config 子命令完成迁移")
+	}
 
 	// 启动文件监听
 	if err := cm.startWatcher(); err != nil {
@@ -48,6 +57,10 @@ func (cm *ConfigManager) loadConfig() error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
+	// 热重载带来的变更（含加载时的迁移保存）记为配置文件变更
+	defer cm.useChangeSourceLocked(ChangeSourceConfigFile)()
+	defer cm.notifyChangeLocked()
+
 	// 如果配置文件不存在，创建默认配置
 	if _, err := os.Stat(cm.configFile); os.IsNotExist(err) {
 		return cm.createDefaultConfig()
@@ -59,10 +72,19 @@ func (cm *ConfigManager) loadConfig() error {
 		return err
 	}
 
-	if err := json.Unmarshal(data, &cm.config); err != nil {
+	var loaded Config
+	if err := json.Unmarshal(data, &loaded); err != nil {
 		return err
 	}
 
+	// 解密 API Key（未加密的配置原样返回）
+	decrypted, keyCipher, err := cm.decryptConfigLocked(loaded)
+	if err != nil {
+		return err
+	}
+	cm.config = decrypted
+	cm.keyCipher = keyCipher
+
 	// 兼容旧配置：检查 FuzzyModeEnabled 字段是否存在
 	// 如果不存在，默认设为 true（新功能默认启用）
 	needSaveDefaults := cm.applyConfigDefaults(data)
@@ -70,6 +92,11 @@ func (cm *ConfigManager) loadConfig() error {
 	// 兼容旧格式：检测是否需要迁移
 	needMigration := cm.migrateOldFormat()
 
+	// 为缺少唯一标识的渠道分配 UUID
+	if cm.ensureChannelIDs() {
+		needMigration = true
+	}
+
 	// 如果有默认值迁移或格式迁移，保存配置
 	if needSaveDefaults || needMigration {
 		if err := cm.saveConfigLocked(cm.config); err != nil {
@@ -103,9 +130,21 @@ func (cm *ConfigManager) createDefaultConfig() error {
 		ResponsesLoadBalance:     "failover",
 		GeminiUpstream:           []UpstreamConfig{},
 		GeminiLoadBalance:        "failover",
+		ChatUpstream:             []UpstreamConfig{},
+		ChatLoadBalance:          "failover",
 		FuzzyModeEnabled:         true, // 默认启用 Fuzzy 模式
 	}
 
+	// 已设置主密钥时，新建的配置直接启用 Key 加密
+	if cm.masterKey != nil {
+		keyCipher, enc, err := newKeyCipher(cm.masterKey)
+		if err != nil {
+			return err
+		}
+		cm.keyCipher = keyCipher
+		defaultConfig.Encryption = enc
+	}
+
 	if err := os.MkdirAll(filepath.Dir(cm.configFile), 0755); err != nil {
 		return err
 	}
@@ -128,6 +167,9 @@ func (cm *ConfigManager) applyConfigDefaults(rawJSON []byte) bool {
 	if cm.config.GeminiLoadBalance == "" {
 		cm.config.GeminiLoadBalance = "failover"
 	}
+	if cm.config.ChatLoadBalance == "" {
+		cm.config.ChatLoadBalance = "failover"
+	}
 
 	// FuzzyModeEnabled 默认值处理：
 	// 由于 bool 零值是 false，无法区分"用户设为 false"和"字段不存在"
@@ -252,6 +294,22 @@ func (cm *ConfigManager) validateChannelKeys() bool {
 		}
 	}
 
+	// 检查 Chat 渠道
+	for i := range cm.config.ChatUpstream {
+		upstream := &cm.config.ChatUpstream[i]
+		status := upstream.Status
+		if status == "" {
+			status = "active"
+		}
+
+		// 如果是 active 状态但没有配置 key，自动设为 suspended
+		if status == "active" && len(upstream.APIKeys) == 0 {
+			upstream.Status = "suspended"
+			modified = true
+			log.Printf("[Config-Validate] 警告: Chat 渠道 [%d] %s 没有配置 API key，已自动暂停", i, upstream.Name)
+		}
+	}
+
 	return modified
 }
 
@@ -264,13 +322,18 @@ func (cm *ConfigManager) saveConfigLocked(config Config) error {
 	config.CurrentUpstream = 0
 	config.CurrentResponsesUpstream = 0
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	// 启用加密时文件中只保存 Key 密文，内存中保留明文
+	data, err := cm.marshalConfigLocked(config)
 	if err != nil {
 		return err
 	}
 
 	cm.config = config
-	return os.WriteFile(cm.configFile, data, 0644)
+	if err := os.WriteFile(cm.configFile, data, 0644); err != nil {
+		return err
+	}
+	cm.notifyChangeLocked()
+	return nil
 }
 
 // SaveConfig 保存配置
@@ -286,7 +349,7 @@ func (cm *ConfigManager) backupConfig() {
 		return
 	}
 
-	backupDir := filepath.Join(filepath.Dir(cm.configFile), "backups")
+	backupDir := cm.backupDir()
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
 		log.Printf("[Config-Backup] 警告: 创建备份目录失败: %v", err)
 		return
@@ -300,7 +363,7 @@ func (cm *ConfigManager) backupConfig() {
 	}
 
 	// 创建备份文件
-	timestamp := time.Now().Format("2006-01-02T15-04-05")
+	timestamp := time.Now().Format(backupTimeLayout)
 	backupFile := filepath.Join(backupDir, fmt.Sprintf("config-%s.json", timestamp))
 	if err := os.WriteFile(backupFile, data, 0644); err != nil {
 		log.Printf("[Config-Backup] 警告: 写入备份文件失败: %v", err)
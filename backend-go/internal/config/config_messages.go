@@ -38,11 +38,30 @@ func (cm *ConfigManager) AddUpstream(upstream UpstreamConfig) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
+	if err := validateSupportedModels(upstream.SupportedModels); err != nil {
+		return err
+	}
+	if err := validateWeight(upstream.Weight); err != nil {
+		return err
+	}
+	if err := validateStreamIdleTimeout(upstream.StreamIdleTimeout); err != nil {
+		return err
+	}
+	if err := validateStreamFirstContentTimeout(upstream.StreamFirstContentTimeout); err != nil {
+		return err
+	}
+	if err := validateBalanceCheck(upstream.BalanceCheck); err != nil {
+		return err
+	}
+
 	// 新建渠道默认设为 active
 	if upstream.Status == "" {
 		upstream.Status = "active"
 	}
 
+	// 分配渠道唯一标识
+	upstream.ID = cm.newChannelIDLocked(upstream.ID)
+
 	// 去重 API Keys 和 Base URLs
 	upstream.APIKeys = deduplicateStrings(upstream.APIKeys)
 	upstream.BaseURLs = deduplicateBaseURLs(upstream.BaseURLs)
@@ -59,12 +78,35 @@ func (cm *ConfigManager) AddUpstream(upstream UpstreamConfig) error {
 
 // UpdateUpstream 更新上游
 // 返回值：shouldResetMetrics 表示是否需要重置渠道指标（熔断状态）
-func (cm *ConfigManager) UpdateUpstream(index int, updates UpstreamUpdate) (shouldResetMetrics bool, err error) {
+func (cm *ConfigManager) UpdateUpstream(channelID string, updates UpstreamUpdate) (shouldResetMetrics bool, err error) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.Upstream) {
-		return false, fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("messages", channelID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := validateSupportedModels(updates.SupportedModels); err != nil {
+		return false, err
+	}
+	if updates.Weight != nil {
+		if err := validateWeight(*updates.Weight); err != nil {
+			return false, err
+		}
+	}
+	if updates.StreamIdleTimeout != nil {
+		if err := validateStreamIdleTimeout(*updates.StreamIdleTimeout); err != nil {
+			return false, err
+		}
+	}
+	if updates.StreamFirstContentTimeout != nil {
+		if err := validateStreamFirstContentTimeout(*updates.StreamFirstContentTimeout); err != nil {
+			return false, err
+		}
+	}
+	if err := validateBalanceCheck(updates.BalanceCheck); err != nil {
+		return false, err
 	}
 
 	upstream := &cm.config.Upstream[index]
@@ -112,7 +154,7 @@ func (cm *ConfigManager) UpdateUpstream(index int, updates UpstreamUpdate) (shou
 				}
 				if !alreadyInHistory {
 					upstream.HistoricalAPIKeys = append(upstream.HistoricalAPIKeys, key)
-					log.Printf("[Config-Upstream] 渠道 [%d] %s: Key %s 已移入历史列表", index, upstream.Name, utils.MaskAPIKey(key))
+					log.Printf("[Config-Upstream] 渠道 [%s] %s: Key %s 已移入历史列表", channelID, upstream.Name, utils.MaskAPIKey(key))
 				}
 			}
 		}
@@ -123,10 +165,11 @@ func (cm *ConfigManager) UpdateUpstream(index int, updates UpstreamUpdate) (shou
 			if !newKeys[hk] {
 				newHistoricalKeys = append(newHistoricalKeys, hk)
 			} else {
-				log.Printf("[Config-Upstream] 渠道 [%d] %s: Key %s 已从历史列表恢复", index, upstream.Name, utils.MaskAPIKey(hk))
+				log.Printf("[Config-Upstream] 渠道 [%s] %s: Key %s 已从历史列表恢复", channelID, upstream.Name, utils.MaskAPIKey(hk))
 			}
 		}
 		upstream.HistoricalAPIKeys = newHistoricalKeys
+		upstream.removeDisabledKeys(newKeys)
 
 		// 只有单 key 场景且 key 被更换时，才自动激活并重置熔断
 		if len(upstream.APIKeys) == 1 && len(updates.APIKeys) == 1 &&
@@ -134,7 +177,7 @@ func (cm *ConfigManager) UpdateUpstream(index int, updates UpstreamUpdate) (shou
 			shouldResetMetrics = true
 			if upstream.Status == "suspended" {
 				upstream.Status = "active"
-				log.Printf("[Config-Upstream] 渠道 [%d] %s 已从暂停状态自动激活（单 key 更换）", index, upstream.Name)
+				log.Printf("[Config-Upstream] 渠道 [%s] %s 已从暂停状态自动激活（单 key 更换）", channelID, upstream.Name)
 			}
 		}
 		upstream.APIKeys = deduplicateStrings(updates.APIKeys)
@@ -142,6 +185,12 @@ func (cm *ConfigManager) UpdateUpstream(index int, updates UpstreamUpdate) (shou
 	if updates.ModelMapping != nil {
 		upstream.ModelMapping = updates.ModelMapping
 	}
+	if updates.SupportedModels != nil {
+		upstream.SupportedModels = deduplicateStrings(updates.SupportedModels)
+	}
+	if updates.AutoDiscoverModels != nil {
+		upstream.AutoDiscoverModels = *updates.AutoDiscoverModels
+	}
 	if updates.InsecureSkipVerify != nil {
 		upstream.InsecureSkipVerify = *updates.InsecureSkipVerify
 	}
@@ -157,6 +206,18 @@ func (cm *ConfigManager) UpdateUpstream(index int, updates UpstreamUpdate) (shou
 	if updates.LowQuality != nil {
 		upstream.LowQuality = *updates.LowQuality
 	}
+	if updates.Weight != nil {
+		upstream.Weight = *updates.Weight
+	}
+	if updates.StreamIdleTimeout != nil {
+		upstream.StreamIdleTimeout = *updates.StreamIdleTimeout
+	}
+	if updates.StreamFirstContentTimeout != nil {
+		upstream.StreamFirstContentTimeout = *updates.StreamFirstContentTimeout
+	}
+	if updates.BalanceCheck != nil {
+		upstream.BalanceCheck = updates.BalanceCheck.Clone()
+	}
 	if updates.InjectDummyThoughtSignature != nil {
 		upstream.InjectDummyThoughtSignature = *updates.InjectDummyThoughtSignature
 	}
@@ -165,17 +226,18 @@ func (cm *ConfigManager) UpdateUpstream(index int, updates UpstreamUpdate) (shou
 		return false, err
 	}
 
-	log.Printf("[Config-Upstream] 已更新上游: [%d] %s", index, cm.config.Upstream[index].Name)
+	log.Printf("[Config-Upstream] 已更新上游: [%s] %s", channelID, cm.config.Upstream[index].Name)
 	return shouldResetMetrics, nil
 }
 
 // RemoveUpstream 删除上游
-func (cm *ConfigManager) RemoveUpstream(index int) (*UpstreamConfig, error) {
+func (cm *ConfigManager) RemoveUpstream(channelID string) (*UpstreamConfig, error) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.Upstream) {
-		return nil, fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("messages", channelID)
+	if err != nil {
+		return nil, err
 	}
 
 	removed := cm.config.Upstream[index]
@@ -193,12 +255,13 @@ func (cm *ConfigManager) RemoveUpstream(index int) (*UpstreamConfig, error) {
 }
 
 // AddAPIKey 添加API密钥
-func (cm *ConfigManager) AddAPIKey(index int, apiKey string) error {
+func (cm *ConfigManager) AddAPIKey(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.Upstream) {
-		return fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("messages", channelID)
+	if err != nil {
+		return err
 	}
 
 	// 检查密钥是否已存在
@@ -216,26 +279,28 @@ func (cm *ConfigManager) AddAPIKey(index int, apiKey string) error {
 		if hk != apiKey {
 			newHistoricalKeys = append(newHistoricalKeys, hk)
 		} else {
-			log.Printf("[Messages-Key] 上游 [%d] %s: Key %s 已从历史列表恢复", index, cm.config.Upstream[index].Name, utils.MaskAPIKey(hk))
+			log.Printf("[Messages-Key] 上游 [%s] %s: Key %s 已从历史列表恢复", channelID, cm.config.Upstream[index].Name, utils.MaskAPIKey(hk))
 		}
 	}
 	cm.config.Upstream[index].HistoricalAPIKeys = newHistoricalKeys
+	cm.config.Upstream[index].removeDisabledKeys(map[string]bool{apiKey: true})
 
 	if err := cm.saveConfigLocked(cm.config); err != nil {
 		return err
 	}
 
-	log.Printf("[Messages-Key] 已添加API密钥到上游 [%d] %s", index, cm.config.Upstream[index].Name)
+	log.Printf("[Messages-Key] 已添加API密钥到上游 [%s] %s", channelID, cm.config.Upstream[index].Name)
 	return nil
 }
 
 // RemoveAPIKey 删除API密钥
-func (cm *ConfigManager) RemoveAPIKey(index int, apiKey string) error {
+func (cm *ConfigManager) RemoveAPIKey(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.Upstream) {
-		return fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("messages", channelID)
+	if err != nil {
+		return err
 	}
 
 	// 查找并删除密钥
@@ -263,14 +328,14 @@ func (cm *ConfigManager) RemoveAPIKey(index int, apiKey string) error {
 	}
 	if !alreadyInHistory {
 		cm.config.Upstream[index].HistoricalAPIKeys = append(cm.config.Upstream[index].HistoricalAPIKeys, apiKey)
-		log.Printf("[Messages-Key] 上游 [%d] %s: Key %s 已移入历史列表", index, cm.config.Upstream[index].Name, utils.MaskAPIKey(apiKey))
+		log.Printf("[Messages-Key] 上游 [%s] %s: Key %s 已移入历史列表", channelID, cm.config.Upstream[index].Name, utils.MaskAPIKey(apiKey))
 	}
 
 	if err := cm.saveConfigLocked(cm.config); err != nil {
 		return err
 	}
 
-	log.Printf("[Messages-Key] 已从上游 [%d] %s 删除API密钥", index, cm.config.Upstream[index].Name)
+	log.Printf("[Messages-Key] 已从上游 [%s] %s 删除API密钥", channelID, cm.config.Upstream[index].Name)
 	return nil
 }
 
@@ -294,12 +359,13 @@ func (cm *ConfigManager) SetLoadBalance(strategy string) error {
 }
 
 // MoveAPIKeyToTop 将指定渠道的 API 密钥移到最前面
-func (cm *ConfigManager) MoveAPIKeyToTop(upstreamIndex int, apiKey string) error {
+func (cm *ConfigManager) MoveAPIKeyToTop(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if upstreamIndex < 0 || upstreamIndex >= len(cm.config.Upstream) {
-		return fmt.Errorf("无效的上游索引: %d", upstreamIndex)
+	upstreamIndex, err := cm.channelIndexLocked("messages", channelID)
+	if err != nil {
+		return err
 	}
 
 	upstream := &cm.config.Upstream[upstreamIndex]
@@ -321,12 +387,13 @@ func (cm *ConfigManager) MoveAPIKeyToTop(upstreamIndex int, apiKey string) error
 }
 
 // MoveAPIKeyToBottom 将指定渠道的 API 密钥移到最后面
-func (cm *ConfigManager) MoveAPIKeyToBottom(upstreamIndex int, apiKey string) error {
+func (cm *ConfigManager) MoveAPIKeyToBottom(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if upstreamIndex < 0 || upstreamIndex >= len(cm.config.Upstream) {
-		return fmt.Errorf("无效的上游索引: %d", upstreamIndex)
+	upstreamIndex, err := cm.channelIndexLocked("messages", channelID)
+	if err != nil {
+		return err
 	}
 
 	upstream := &cm.config.Upstream[upstreamIndex]
@@ -385,12 +452,13 @@ func (cm *ConfigManager) ReorderUpstreams(order []int) error {
 }
 
 // SetChannelStatus 设置 Messages 渠道状态
-func (cm *ConfigManager) SetChannelStatus(index int, status string) error {
+func (cm *ConfigManager) SetChannelStatus(channelID, status string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.Upstream) {
-		return fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("messages", channelID)
+	if err != nil {
+		return err
 	}
 
 	// 状态值转为小写，支持大小写不敏感
@@ -404,30 +472,31 @@ func (cm *ConfigManager) SetChannelStatus(index int, status string) error {
 	// 暂停时清除促销期
 	if status == "suspended" && cm.config.Upstream[index].PromotionUntil != nil {
 		cm.config.Upstream[index].PromotionUntil = nil
-		log.Printf("[Config-Status] 已清除渠道 [%d] %s 的促销期", index, cm.config.Upstream[index].Name)
+		log.Printf("[Config-Status] 已清除渠道 [%s] %s 的促销期", channelID, cm.config.Upstream[index].Name)
 	}
 
 	if err := cm.saveConfigLocked(cm.config); err != nil {
 		return err
 	}
 
-	log.Printf("[Config-Status] 已设置渠道 [%d] %s 状态为: %s", index, cm.config.Upstream[index].Name, status)
+	log.Printf("[Config-Status] 已设置渠道 [%s] %s 状态为: %s", channelID, cm.config.Upstream[index].Name, status)
 	return nil
 }
 
 // SetChannelPromotion 设置渠道促销期
 // duration 为促销持续时间，传入 0 表示清除促销期
-func (cm *ConfigManager) SetChannelPromotion(index int, duration time.Duration) error {
+func (cm *ConfigManager) SetChannelPromotion(channelID string, duration time.Duration) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.Upstream) {
-		return fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("messages", channelID)
+	if err != nil {
+		return err
 	}
 
 	if duration <= 0 {
 		cm.config.Upstream[index].PromotionUntil = nil
-		log.Printf("[Config-Promotion] 已清除渠道 [%d] %s 的促销期", index, cm.config.Upstream[index].Name)
+		log.Printf("[Config-Promotion] 已清除渠道 [%s] %s 的促销期", channelID, cm.config.Upstream[index].Name)
 	} else {
 		// 清除其他渠道的促销期（同一时间只允许一个促销渠道）
 		for i := range cm.config.Upstream {
@@ -437,7 +506,7 @@ func (cm *ConfigManager) SetChannelPromotion(index int, duration time.Duration)
 		}
 		promotionEnd := time.Now().Add(duration)
 		cm.config.Upstream[index].PromotionUntil = &promotionEnd
-		log.Printf("[Config-Promotion] 已设置渠道 [%d] %s 进入促销期，截止: %s", index, cm.config.Upstream[index].Name, promotionEnd.Format(time.RFC3339))
+		log.Printf("[Config-Promotion] 已设置渠道 [%s] %s 进入促销期，截止: %s", channelID, cm.config.Upstream[index].Name, promotionEnd.Format(time.RFC3339))
 	}
 
 	return cm.saveConfigLocked(cm.config)
@@ -456,50 +525,39 @@ func (cm *ConfigManager) GetPromotedChannel() (int, bool) {
 	return -1, false
 }
 
-// DeprioritizeAPIKey 降低API密钥优先级（在所有渠道中查找）
-func (cm *ConfigManager) DeprioritizeAPIKey(apiKey string) error {
+// DeprioritizeAPIKey 降低指定渠道中 API 密钥的优先级（移到 Key 列表末尾）
+// kind: messages/responses/gemini/chat；同一 Key 出现在多个渠道时只调整 channelID 对应的渠道
+func (cm *ConfigManager) DeprioritizeAPIKey(kind, channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
+	defer cm.useChangeSourceLocked(ChangeSourceKeyDeprioritize)()
 
-	// 遍历所有渠道查找该 API 密钥
-	for upstreamIdx := range cm.config.Upstream {
-		upstream := &cm.config.Upstream[upstreamIdx]
-		index := -1
-		for i, key := range upstream.APIKeys {
-			if key == apiKey {
-				index = i
-				break
-			}
-		}
-
-		if index != -1 && index != len(upstream.APIKeys)-1 {
-			// 移动到末尾
-			upstream.APIKeys = append(upstream.APIKeys[:index], upstream.APIKeys[index+1:]...)
-			upstream.APIKeys = append(upstream.APIKeys, apiKey)
-			log.Printf("[Messages-Key] 已将API密钥移动到末尾以降低优先级: %s (渠道: %s)", utils.MaskAPIKey(apiKey), upstream.Name)
-			return cm.saveConfigLocked(cm.config)
-		}
+	index, err := cm.channelIndexLocked(kind, channelID)
+	if err != nil {
+		return err
+	}
+	upstream := &cm.config.UpstreamsForKind(kind)[index]
+	if !moveAPIKeyToEnd(upstream, apiKey) {
+		return nil
 	}
 
-	// 同样遍历 Responses 渠道
-	for upstreamIdx := range cm.config.ResponsesUpstream {
-		upstream := &cm.config.ResponsesUpstream[upstreamIdx]
-		index := -1
-		for i, key := range upstream.APIKeys {
-			if key == apiKey {
-				index = i
-				break
-			}
-		}
+	log.Printf("[Config-Key] 已将API密钥移动到末尾以降低优先级: %s (%s 渠道 [%s] %s)", utils.MaskAPIKey(apiKey), kind, channelID, upstream.Name)
+	return cm.saveConfigLocked(cm.config)
+}
 
-		if index != -1 && index != len(upstream.APIKeys)-1 {
-			// 移动到末尾
-			upstream.APIKeys = append(upstream.APIKeys[:index], upstream.APIKeys[index+1:]...)
-			upstream.APIKeys = append(upstream.APIKeys, apiKey)
-			log.Printf("[Responses-Key] 已将API密钥移动到末尾以降低优先级: %s (Responses渠道: %s)", utils.MaskAPIKey(apiKey), upstream.Name)
-			return cm.saveConfigLocked(cm.config)
+// moveAPIKeyToEnd 将 Key 移到渠道 Key 列表末尾，Key 不存在或已在末尾时返回 false
+func moveAPIKeyToEnd(upstream *UpstreamConfig, apiKey string) bool {
+	index := -1
+	for i, key := range upstream.APIKeys {
+		if key == apiKey {
+			index = i
+			break
 		}
 	}
-
-	return nil
+	if index == -1 || index == len(upstream.APIKeys)-1 {
+		return false
+	}
+	upstream.APIKeys = append(upstream.APIKeys[:index], upstream.APIKeys[index+1:]...)
+	upstream.APIKeys = append(upstream.APIKeys, apiKey)
+	return true
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+)
+
+// ============== 渠道支持模型 ==============
+
+// ServesModel 判断渠道是否服务指定模型
+// 匹配规则：SupportedModels（精确名或 path.Match 通配符）+ 启用自动发现时的 DiscoveredModels；
+// 两者均为空时视为支持全部模型。请求模型与经 ModelMapping 重定向后的模型任一命中即可。
+func (u *UpstreamConfig) ServesModel(model string) bool {
+	if u == nil || model == "" {
+		return true
+	}
+
+	patterns := u.SupportedModels
+	if u.AutoDiscoverModels && len(u.DiscoveredModels) > 0 {
+		patterns = append(append([]string{}, patterns...), u.DiscoveredModels...)
+	}
+	if len(patterns) == 0 {
+		return true
+	}
+
+	candidates := []string{model}
+	if redirected := RedirectModel(model, u); redirected != model {
+		candidates = append(candidates, redirected)
+	}
+	for _, candidate := range candidates {
+		for _, pattern := range patterns {
+			if pattern == candidate {
+				return true
+			}
+			if matched, err := path.Match(pattern, candidate); err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateSupportedModels 校验支持模型列表中的通配符
+func validateSupportedModels(patterns []string) error {
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("支持模型不能为空字符串")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("无效的支持模型匹配规则: %s", pattern)
+		}
+	}
+	return nil
+}
+
+// SetDiscoveredModels 保存从上游 /models 拉取的模型列表
+// kind: messages/responses/gemini/chat
+func (cm *ConfigManager) SetDiscoveredModels(kind, channelID string, models []string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	index, err := cm.channelIndexLocked(kind, channelID)
+	if err != nil {
+		return err
+	}
+
+	upstream := &cm.config.UpstreamsForKind(kind)[index]
+	upstream.DiscoveredModels = deduplicateStrings(models)
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+
+	log.Printf("[Config-Models] 渠道 [%s] %s 已更新自动发现模型: %d 个", channelID, upstream.Name, len(upstream.DiscoveredModels))
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import "testing"
+
+// TestUpstreamConfig_ServesModel 测试渠道支持模型匹配（精确名、通配符、重定向、自动发现）
+func TestUpstreamConfig_ServesModel(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream UpstreamConfig
+		model    string
+		want     bool
+	}{
+		{"未配置时支持全部模型", UpstreamConfig{}, "gpt-4o", true},
+		{"空模型不过滤", UpstreamConfig{SupportedModels: []string{"gpt-4o"}}, "", true},
+		{"精确匹配", UpstreamConfig{SupportedModels: []string{"gpt-4o"}}, "gpt-4o", true},
+		{"通配符匹配", UpstreamConfig{SupportedModels: []string{"claude-*"}}, "claude-sonnet-4", true},
+		{"不匹配", UpstreamConfig{SupportedModels: []string{"claude-*"}}, "gpt-4o", false},
+		{
+			"重定向后的模型命中",
+			UpstreamConfig{SupportedModels: []string{"gpt-5"}, ModelMapping: map[string]string{"codex": "gpt-5"}},
+			"codex", true,
+		},
+		{
+			"自动发现列表生效",
+			UpstreamConfig{AutoDiscoverModels: true, DiscoveredModels: []string{"gemini-2.5-pro"}},
+			"gemini-2.5-pro", true,
+		},
+		{
+			"未启用自动发现时忽略发现列表",
+			UpstreamConfig{SupportedModels: []string{"gpt-4o"}, DiscoveredModels: []string{"gemini-2.5-pro"}},
+			"gemini-2.5-pro", false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.upstream.ServesModel(tt.model); got != tt.want {
+				t.Errorf("ServesModel(%q) = %v, want %v", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateSupportedModels 测试支持模型规则校验
+func TestValidateSupportedModels(t *testing.T) {
+	if err := validateSupportedModels([]string{"gpt-4o", "claude-*"}); err != nil {
+		t.Errorf("expected valid patterns, got %v", err)
+	}
+	if err := validateSupportedModels([]string{"[invalid"}); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+	if err := validateSupportedModels([]string{" "}); err == nil {
+		t.Error("expected error for empty pattern")
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/BenedictKing/claude-proxy/internal/pricing"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+// ============== 模型价格表相关方法 ==============
+
+// GetModelPrices 获取配置中的模型价格覆盖（副本）
+func (cm *ConfigManager) GetModelPrices() map[string]pricing.ModelPrice {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	result := make(map[string]pricing.ModelPrice, len(cm.config.ModelPrices))
+	for k, v := range cm.config.ModelPrices {
+		result[k] = v
+	}
+	return result
+}
+
+// SetModelPrices 整体替换模型价格覆盖（传入空 map 表示仅使用内置价格表）
+func (cm *ConfigManager) SetModelPrices(prices map[string]pricing.ModelPrice) error {
+	for pattern, price := range prices {
+		if err := pricing.ValidatePattern(pattern); err != nil {
+			return &ConfigError{Message: err.Error()}
+		}
+		if err := price.Validate(); err != nil {
+			return &ConfigError{Message: fmt.Sprintf("模型 %s: %v", pattern, err)}
+		}
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if len(prices) == 0 {
+		cm.config.ModelPrices = nil
+	} else {
+		cm.config.ModelPrices = make(map[string]pricing.ModelPrice, len(prices))
+		for k, v := range prices {
+			cm.config.ModelPrices[k] = v
+		}
+	}
+
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+
+	log.Printf("[Config-Pricing] 已更新模型价格覆盖: %d 项", len(prices))
+	return nil
+}
+
+// CalculateCost 按价格表计算单次请求费用（美元）；未知模型返回 0
+func (cm *ConfigManager) CalculateCost(model string, usage *types.Usage) float64 {
+	if usage == nil || model == "" {
+		return 0
+	}
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return pricing.Calculate(cm.config.ModelPrices, model, usage)
+}
@@ -38,11 +38,30 @@ func (cm *ConfigManager) AddResponsesUpstream(upstream UpstreamConfig) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
+	if err := validateSupportedModels(upstream.SupportedModels); err != nil {
+		return err
+	}
+	if err := validateWeight(upstream.Weight); err != nil {
+		return err
+	}
+	if err := validateStreamIdleTimeout(upstream.StreamIdleTimeout); err != nil {
+		return err
+	}
+	if err := validateStreamFirstContentTimeout(upstream.StreamFirstContentTimeout); err != nil {
+		return err
+	}
+	if err := validateBalanceCheck(upstream.BalanceCheck); err != nil {
+		return err
+	}
+
 	// 新建渠道默认设为 active
 	if upstream.Status == "" {
 		upstream.Status = "active"
 	}
 
+	// 分配渠道唯一标识
+	upstream.ID = cm.newChannelIDLocked(upstream.ID)
+
 	// 去重 API Keys 和 Base URLs
 	upstream.APIKeys = deduplicateStrings(upstream.APIKeys)
 	upstream.BaseURLs = deduplicateBaseURLs(upstream.BaseURLs)
@@ -59,12 +78,35 @@ func (cm *ConfigManager) AddResponsesUpstream(upstream UpstreamConfig) error {
 
 // UpdateResponsesUpstream 更新 Responses 上游
 // 返回值：shouldResetMetrics 表示是否需要重置渠道指标（熔断状态）
-func (cm *ConfigManager) UpdateResponsesUpstream(index int, updates UpstreamUpdate) (shouldResetMetrics bool, err error) {
+func (cm *ConfigManager) UpdateResponsesUpstream(channelID string, updates UpstreamUpdate) (shouldResetMetrics bool, err error) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.ResponsesUpstream) {
-		return false, fmt.Errorf("无效的 Responses 上游索引: %d", index)
+	index, err := cm.channelIndexLocked("responses", channelID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := validateSupportedModels(updates.SupportedModels); err != nil {
+		return false, err
+	}
+	if updates.Weight != nil {
+		if err := validateWeight(*updates.Weight); err != nil {
+			return false, err
+		}
+	}
+	if updates.StreamIdleTimeout != nil {
+		if err := validateStreamIdleTimeout(*updates.StreamIdleTimeout); err != nil {
+			return false, err
+		}
+	}
+	if updates.StreamFirstContentTimeout != nil {
+		if err := validateStreamFirstContentTimeout(*updates.StreamFirstContentTimeout); err != nil {
+			return false, err
+		}
+	}
+	if err := validateBalanceCheck(updates.BalanceCheck); err != nil {
+		return false, err
 	}
 
 	upstream := &cm.config.ResponsesUpstream[index]
@@ -112,7 +154,7 @@ func (cm *ConfigManager) UpdateResponsesUpstream(index int, updates UpstreamUpda
 				}
 				if !alreadyInHistory {
 					upstream.HistoricalAPIKeys = append(upstream.HistoricalAPIKeys, key)
-					log.Printf("[Config-Upstream] Responses 渠道 [%d] %s: Key %s 已移入历史列表", index, upstream.Name, utils.MaskAPIKey(key))
+					log.Printf("[Config-Upstream] Responses 渠道 [%s] %s: Key %s 已移入历史列表", channelID, upstream.Name, utils.MaskAPIKey(key))
 				}
 			}
 		}
@@ -123,10 +165,11 @@ func (cm *ConfigManager) UpdateResponsesUpstream(index int, updates UpstreamUpda
 			if !newKeys[hk] {
 				newHistoricalKeys = append(newHistoricalKeys, hk)
 			} else {
-				log.Printf("[Config-Upstream] Responses 渠道 [%d] %s: Key %s 已从历史列表恢复", index, upstream.Name, utils.MaskAPIKey(hk))
+				log.Printf("[Config-Upstream] Responses 渠道 [%s] %s: Key %s 已从历史列表恢复", channelID, upstream.Name, utils.MaskAPIKey(hk))
 			}
 		}
 		upstream.HistoricalAPIKeys = newHistoricalKeys
+		upstream.removeDisabledKeys(newKeys)
 
 		// 只有单 key 场景且 key 被更换时，才自动激活并重置熔断
 		if len(upstream.APIKeys) == 1 && len(updates.APIKeys) == 1 &&
@@ -134,7 +177,7 @@ func (cm *ConfigManager) UpdateResponsesUpstream(index int, updates UpstreamUpda
 			shouldResetMetrics = true
 			if upstream.Status == "suspended" {
 				upstream.Status = "active"
-				log.Printf("[Config-Upstream] Responses 渠道 [%d] %s 已从暂停状态自动激活（单 key 更换）", index, upstream.Name)
+				log.Printf("[Config-Upstream] Responses 渠道 [%s] %s 已从暂停状态自动激活（单 key 更换）", channelID, upstream.Name)
 			}
 		}
 		upstream.APIKeys = deduplicateStrings(updates.APIKeys)
@@ -142,6 +185,12 @@ func (cm *ConfigManager) UpdateResponsesUpstream(index int, updates UpstreamUpda
 	if updates.ModelMapping != nil {
 		upstream.ModelMapping = updates.ModelMapping
 	}
+	if updates.SupportedModels != nil {
+		upstream.SupportedModels = deduplicateStrings(updates.SupportedModels)
+	}
+	if updates.AutoDiscoverModels != nil {
+		upstream.AutoDiscoverModels = *updates.AutoDiscoverModels
+	}
 	if updates.InsecureSkipVerify != nil {
 		upstream.InsecureSkipVerify = *updates.InsecureSkipVerify
 	}
@@ -157,22 +206,35 @@ func (cm *ConfigManager) UpdateResponsesUpstream(index int, updates UpstreamUpda
 	if updates.LowQuality != nil {
 		upstream.LowQuality = *updates.LowQuality
 	}
+	if updates.Weight != nil {
+		upstream.Weight = *updates.Weight
+	}
+	if updates.StreamIdleTimeout != nil {
+		upstream.StreamIdleTimeout = *updates.StreamIdleTimeout
+	}
+	if updates.StreamFirstContentTimeout != nil {
+		upstream.StreamFirstContentTimeout = *updates.StreamFirstContentTimeout
+	}
+	if updates.BalanceCheck != nil {
+		upstream.BalanceCheck = updates.BalanceCheck.Clone()
+	}
 
 	if err := cm.saveConfigLocked(cm.config); err != nil {
 		return false, err
 	}
 
-	log.Printf("[Config-Upstream] 已更新 Responses 上游: [%d] %s", index, cm.config.ResponsesUpstream[index].Name)
+	log.Printf("[Config-Upstream] 已更新 Responses 上游: [%s] %s", channelID, cm.config.ResponsesUpstream[index].Name)
 	return shouldResetMetrics, nil
 }
 
 // RemoveResponsesUpstream 删除 Responses 上游
-func (cm *ConfigManager) RemoveResponsesUpstream(index int) (*UpstreamConfig, error) {
+func (cm *ConfigManager) RemoveResponsesUpstream(channelID string) (*UpstreamConfig, error) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.ResponsesUpstream) {
-		return nil, fmt.Errorf("无效的 Responses 上游索引: %d", index)
+	index, err := cm.channelIndexLocked("responses", channelID)
+	if err != nil {
+		return nil, err
 	}
 
 	removed := cm.config.ResponsesUpstream[index]
@@ -190,12 +252,13 @@ func (cm *ConfigManager) RemoveResponsesUpstream(index int) (*UpstreamConfig, er
 }
 
 // AddResponsesAPIKey 添加 Responses 上游的 API 密钥
-func (cm *ConfigManager) AddResponsesAPIKey(index int, apiKey string) error {
+func (cm *ConfigManager) AddResponsesAPIKey(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.ResponsesUpstream) {
-		return fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("responses", channelID)
+	if err != nil {
+		return err
 	}
 
 	// 检查密钥是否已存在
@@ -213,26 +276,28 @@ func (cm *ConfigManager) AddResponsesAPIKey(index int, apiKey string) error {
 		if hk != apiKey {
 			newHistoricalKeys = append(newHistoricalKeys, hk)
 		} else {
-			log.Printf("[Responses-Key] 上游 [%d] %s: Key %s 已从历史列表恢复", index, cm.config.ResponsesUpstream[index].Name, utils.MaskAPIKey(hk))
+			log.Printf("[Responses-Key] 上游 [%s] %s: Key %s 已从历史列表恢复", channelID, cm.config.ResponsesUpstream[index].Name, utils.MaskAPIKey(hk))
 		}
 	}
 	cm.config.ResponsesUpstream[index].HistoricalAPIKeys = newHistoricalKeys
+	cm.config.ResponsesUpstream[index].removeDisabledKeys(map[string]bool{apiKey: true})
 
 	if err := cm.saveConfigLocked(cm.config); err != nil {
 		return err
 	}
 
-	log.Printf("[Responses-Key] 已添加API密钥到 Responses 上游 [%d] %s", index, cm.config.ResponsesUpstream[index].Name)
+	log.Printf("[Responses-Key] 已添加API密钥到 Responses 上游 [%s] %s", channelID, cm.config.ResponsesUpstream[index].Name)
 	return nil
 }
 
 // RemoveResponsesAPIKey 删除 Responses 上游的 API 密钥
-func (cm *ConfigManager) RemoveResponsesAPIKey(index int, apiKey string) error {
+func (cm *ConfigManager) RemoveResponsesAPIKey(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.ResponsesUpstream) {
-		return fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("responses", channelID)
+	if err != nil {
+		return err
 	}
 
 	// 查找并删除密钥
@@ -260,14 +325,14 @@ func (cm *ConfigManager) RemoveResponsesAPIKey(index int, apiKey string) error {
 	}
 	if !alreadyInHistory {
 		cm.config.ResponsesUpstream[index].HistoricalAPIKeys = append(cm.config.ResponsesUpstream[index].HistoricalAPIKeys, apiKey)
-		log.Printf("[Responses-Key] 上游 [%d] %s: Key %s 已移入历史列表", index, cm.config.ResponsesUpstream[index].Name, utils.MaskAPIKey(apiKey))
+		log.Printf("[Responses-Key] 上游 [%s] %s: Key %s 已移入历史列表", channelID, cm.config.ResponsesUpstream[index].Name, utils.MaskAPIKey(apiKey))
 	}
 
 	if err := cm.saveConfigLocked(cm.config); err != nil {
 		return err
 	}
 
-	log.Printf("[Responses-Key] 已从 Responses 上游 [%d] %s 删除API密钥", index, cm.config.ResponsesUpstream[index].Name)
+	log.Printf("[Responses-Key] 已从 Responses 上游 [%s] %s 删除API密钥", channelID, cm.config.ResponsesUpstream[index].Name)
 	return nil
 }
 
@@ -296,12 +361,13 @@ func (cm *ConfigManager) SetResponsesLoadBalance(strategy string) error {
 }
 
 // MoveResponsesAPIKeyToTop 将指定 Responses 渠道的 API 密钥移到最前面
-func (cm *ConfigManager) MoveResponsesAPIKeyToTop(upstreamIndex int, apiKey string) error {
+func (cm *ConfigManager) MoveResponsesAPIKeyToTop(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if upstreamIndex < 0 || upstreamIndex >= len(cm.config.ResponsesUpstream) {
-		return fmt.Errorf("无效的上游索引: %d", upstreamIndex)
+	upstreamIndex, err := cm.channelIndexLocked("responses", channelID)
+	if err != nil {
+		return err
 	}
 
 	upstream := &cm.config.ResponsesUpstream[upstreamIndex]
@@ -322,12 +388,13 @@ func (cm *ConfigManager) MoveResponsesAPIKeyToTop(upstreamIndex int, apiKey stri
 }
 
 // MoveResponsesAPIKeyToBottom 将指定 Responses 渠道的 API 密钥移到最后面
-func (cm *ConfigManager) MoveResponsesAPIKeyToBottom(upstreamIndex int, apiKey string) error {
+func (cm *ConfigManager) MoveResponsesAPIKeyToBottom(channelID, apiKey string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if upstreamIndex < 0 || upstreamIndex >= len(cm.config.ResponsesUpstream) {
-		return fmt.Errorf("无效的上游索引: %d", upstreamIndex)
+	upstreamIndex, err := cm.channelIndexLocked("responses", channelID)
+	if err != nil {
+		return err
 	}
 
 	upstream := &cm.config.ResponsesUpstream[upstreamIndex]
@@ -384,12 +451,13 @@ func (cm *ConfigManager) ReorderResponsesUpstreams(order []int) error {
 }
 
 // SetResponsesChannelStatus 设置 Responses 渠道状态
-func (cm *ConfigManager) SetResponsesChannelStatus(index int, status string) error {
+func (cm *ConfigManager) SetResponsesChannelStatus(channelID, status string) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.ResponsesUpstream) {
-		return fmt.Errorf("无效的上游索引: %d", index)
+	index, err := cm.channelIndexLocked("responses", channelID)
+	if err != nil {
+		return err
 	}
 
 	// 状态值转为小写，支持大小写不敏感
@@ -403,29 +471,30 @@ func (cm *ConfigManager) SetResponsesChannelStatus(index int, status string) err
 	// 暂停时清除促销期
 	if status == "suspended" && cm.config.ResponsesUpstream[index].PromotionUntil != nil {
 		cm.config.ResponsesUpstream[index].PromotionUntil = nil
-		log.Printf("[Config-Status] 已清除 Responses 渠道 [%d] %s 的促销期", index, cm.config.ResponsesUpstream[index].Name)
+		log.Printf("[Config-Status] 已清除 Responses 渠道 [%s] %s 的促销期", channelID, cm.config.ResponsesUpstream[index].Name)
 	}
 
 	if err := cm.saveConfigLocked(cm.config); err != nil {
 		return err
 	}
 
-	log.Printf("[Config-Status] 已设置 Responses 渠道 [%d] %s 状态为: %s", index, cm.config.ResponsesUpstream[index].Name, status)
+	log.Printf("[Config-Status] 已设置 Responses 渠道 [%s] %s 状态为: %s", channelID, cm.config.ResponsesUpstream[index].Name, status)
 	return nil
 }
 
 // SetResponsesChannelPromotion 设置 Responses 渠道促销期
-func (cm *ConfigManager) SetResponsesChannelPromotion(index int, duration time.Duration) error {
+func (cm *ConfigManager) SetResponsesChannelPromotion(channelID string, duration time.Duration) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	if index < 0 || index >= len(cm.config.ResponsesUpstream) {
-		return fmt.Errorf("无效的 Responses 上游索引: %d", index)
+	index, err := cm.channelIndexLocked("responses", channelID)
+	if err != nil {
+		return err
 	}
 
 	if duration <= 0 {
 		cm.config.ResponsesUpstream[index].PromotionUntil = nil
-		log.Printf("[Config-Promotion] 已清除 Responses 渠道 [%d] %s 的促销期", index, cm.config.ResponsesUpstream[index].Name)
+		log.Printf("[Config-Promotion] 已清除 Responses 渠道 [%s] %s 的促销期", channelID, cm.config.ResponsesUpstream[index].Name)
 	} else {
 		// 清除其他渠道的促销期（同一时间只允许一个促销渠道）
 		for i := range cm.config.ResponsesUpstream {
@@ -435,7 +504,7 @@ func (cm *ConfigManager) SetResponsesChannelPromotion(index int, duration time.D
 		}
 		promotionEnd := time.Now().Add(duration)
 		cm.config.ResponsesUpstream[index].PromotionUntil = &promotionEnd
-		log.Printf("[Config-Promotion] 已设置 Responses 渠道 [%d] %s 进入促销期，截止: %s", index, cm.config.ResponsesUpstream[index].Name, promotionEnd.Format(time.RFC3339))
+		log.Printf("[Config-Promotion] 已设置 Responses 渠道 [%s] %s 进入促销期，截止: %s", channelID, cm.config.ResponsesUpstream[index].Name, promotionEnd.Format(time.RFC3339))
 	}
 
 	return cm.saveConfigLocked(cm.config)
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 	"time"
@@ -41,12 +42,54 @@ func deduplicateBaseURLs(urls []string) []string {
 	return result
 }
 
+// 负载均衡策略（同时作用于渠道选择与渠道内 Key 选择）
+const (
+	LoadBalanceFailover      = "failover"       // 按优先级顺序故障转移（默认）
+	LoadBalanceRoundRobin    = "round-robin"    // 按渠道权重平滑轮询
+	LoadBalanceRandom        = "random"         // 按渠道权重随机
+	LoadBalanceLeastInFlight = "least-inflight" // 选择进行中请求数最少的
+	LoadBalanceLowestLatency = "lowest-latency" // 选择平均请求耗时最低的
+)
+
 // validateLoadBalanceStrategy 验证负载均衡策略
 func validateLoadBalanceStrategy(strategy string) error {
-	// 只接受 failover 策略（round-robin 和 random 已移除）
-	// 为兼容旧配置，仍允许旧值但静默忽略
-	if strategy != "failover" && strategy != "round-robin" && strategy != "random" {
-		return &ConfigError{Message: "无效的负载均衡策略: " + strategy}
+	switch strategy {
+	case LoadBalanceFailover, LoadBalanceRoundRobin, LoadBalanceRandom, LoadBalanceLeastInFlight, LoadBalanceLowestLatency:
+		return nil
+	}
+	return &ConfigError{Message: "无效的负载均衡策略: " + strategy}
+}
+
+// validateWeight 验证渠道权重
+func validateWeight(weight int) error {
+	if weight < 0 {
+		return &ConfigError{Message: fmt.Sprintf("无效的渠道权重: %d", weight)}
+	}
+	return nil
+}
+
+// maxStreamIdleTimeout 渠道流式空闲超时上限（秒）
+const maxStreamIdleTimeout = 3600
+
+// validateStreamIdleTimeout 验证渠道流式空闲超时（秒）
+func validateStreamIdleTimeout(seconds int) error {
+	if seconds < 0 || seconds > maxStreamIdleTimeout {
+		return &ConfigError{Message: fmt.Sprintf("无效的流式空闲超时: %d（有效范围 0-%d 秒）", seconds, maxStreamIdleTimeout)}
+	}
+	return nil
+}
+
+// 渠道流式首个内容超时范围（秒），与全局 STREAM_FIRST_CONTENT_TIMEOUT 一致
+const (
+	minStreamFirstContentTimeout = 5
+	maxStreamFirstContentTimeout = 300
+)
+
+// validateStreamFirstContentTimeout 验证渠道流式首个内容超时（秒），0 表示使用全局值
+func validateStreamFirstContentTimeout(seconds int) error {
+	if seconds != 0 && (seconds < minStreamFirstContentTimeout || seconds > maxStreamFirstContentTimeout) {
+		return &ConfigError{Message: fmt.Sprintf("无效的流式首个内容超时: %d（0 表示使用全局值，有效范围 %d-%d 秒）",
+			seconds, minStreamFirstContentTimeout, maxStreamFirstContentTimeout)}
 	}
 	return nil
 }
@@ -98,6 +141,39 @@ type mapping struct {
 	return model
 }
 
+// UpstreamsForKind 按入口类型（messages/responses/gemini/chat）返回对应的渠道列表
+func (c *Config) UpstreamsForKind(kind string) []UpstreamConfig {
+	switch kind {
+	case "responses":
+		return c.ResponsesUpstream
+	case "gemini":
+		return c.GeminiUpstream
+	case "chat":
+		return c.ChatUpstream
+	default:
+		return c.Upstream
+	}
+}
+
+// LoadBalanceForKind 按入口类型返回负载均衡策略（未配置时为 failover）
+func (c *Config) LoadBalanceForKind(kind string) string {
+	var strategy string
+	switch kind {
+	case "responses":
+		strategy = c.ResponsesLoadBalance
+	case "gemini":
+		strategy = c.GeminiLoadBalance
+	case "chat":
+		strategy = c.ChatLoadBalance
+	default:
+		strategy = c.LoadBalance
+	}
+	if strategy == "" {
+		return LoadBalanceFailover
+	}
+	return strategy
+}
+
 // ============== 渠道状态与优先级辅助函数 ==============
 
 // GetChannelStatus 获取渠道状态（带默认值处理）
@@ -145,20 +221,54 @@ func (u *UpstreamConfig) Clone() *UpstreamConfig {
 		cloned.HistoricalAPIKeys = make([]string, len(u.HistoricalAPIKeys))
 		copy(cloned.HistoricalAPIKeys, u.HistoricalAPIKeys)
 	}
+	if u.DisabledAPIKeys != nil {
+		cloned.DisabledAPIKeys = append([]DisabledAPIKey(nil), u.DisabledAPIKeys...)
+	}
 	if u.ModelMapping != nil {
 		cloned.ModelMapping = make(map[string]string, len(u.ModelMapping))
 		for k, v := range u.ModelMapping {
 			cloned.ModelMapping[k] = v
 		}
 	}
+	if u.SupportedModels != nil {
+		cloned.SupportedModels = append([]string(nil), u.SupportedModels...)
+	}
+	if u.DiscoveredModels != nil {
+		cloned.DiscoveredModels = append([]string(nil), u.DiscoveredModels...)
+	}
 	if u.PromotionUntil != nil {
 		t := *u.PromotionUntil
 		cloned.PromotionUntil = &t
 	}
+	cloned.BalanceCheck = u.BalanceCheck.Clone()
 
 	return &cloned
 }
 
+// GetWeight 获取负载均衡权重（未配置时为 1）
+func (u *UpstreamConfig) GetWeight() int {
+	if u.Weight <= 0 {
+		return 1
+	}
+	return u.Weight
+}
+
+// GetStreamIdleTimeout 获取流式空闲超时（渠道未配置时使用全局默认值，0 表示不限制）
+func (u *UpstreamConfig) GetStreamIdleTimeout(defaultTimeout time.Duration) time.Duration {
+	if u.StreamIdleTimeout > 0 {
+		return time.Duration(u.StreamIdleTimeout) * time.Second
+	}
+	return defaultTimeout
+}
+
+// GetStreamFirstContentTimeout 获取流式首个内容超时（渠道未配置时使用全局默认值）
+func (u *UpstreamConfig) GetStreamFirstContentTimeout(defaultTimeout time.Duration) time.Duration {
+	if u.StreamFirstContentTimeout > 0 {
+		return time.Duration(u.StreamFirstContentTimeout) * time.Second
+	}
+	return defaultTimeout
+}
+
 // GetEffectiveBaseURL 获取当前应使用的 BaseURL（纯 failover 模式）
 // 优先使用 BaseURL 字段（支持调用方临时覆盖），否则从 BaseURLs 数组获取
 func (u *UpstreamConfig) GetEffectiveBaseURL() string {
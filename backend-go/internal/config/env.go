@@ -28,8 +28,26 @@ type EnvConfig struct {
 	// 指标持久化配置
 	MetricsPersistenceEnabled bool // 是否启用 SQLite 持久化
 	MetricsRetentionDays      int  // 数据保留天数（3-30）
+	// Prometheus 导出配置
+	PrometheusEnabled   bool   // 是否启用 /metrics 端点
+	PrometheusAccessKey string // /metrics 专用访问密钥（可选，管理员密钥始终可用）
+	PrometheusPublic    bool   // /metrics 是否免认证（仅建议在内网环境使用）
+	// 会话存储配置
+	SessionStore string // Responses API 会话存储：sqlite（复用指标数据库，默认）或 memory
+	// 请求审计日志配置
+	RequestLogEnabled       bool // 是否启用结构化请求日志（SQLite）
+	RequestLogCaptureBodies bool // 是否记录请求/响应体（截断后保存）
+	RequestLogMaxBodySize   int  // 单个请求/响应体最大保存大小 (字节)，由 KB 配置转换
+	RequestLogRetentionDays int  // 请求日志保留天数（1-90）
+	// 管理操作审计日志配置
+	AuditLogEnabled bool // 是否记录管理写操作（SQLite，只追加）
 	// HTTP 客户端配置
 	ResponseHeaderTimeout int // 等待响应头超时时间（秒）
+	// 流式 failover 配置
+	StreamFailoverEnabled     bool // 流式请求在首个内容事件前失败时是否切换到下一个 Key/渠道
+	StreamFirstContentTimeout int  // 等待首个内容事件的超时时间（秒），超时视为上游卡死
+	StreamIdleTimeout         int  // 流式响应相邻数据块之间的最大间隔（秒），0 表示不限制；可被渠道配置覆盖
+	StreamHeartbeatInterval   int  // 上游静默时向客户端注入心跳的间隔（秒），0 表示关闭
 	// 日志文件相关配置
 	LogDir        string
 	LogFile       string
@@ -71,8 +89,26 @@ func NewEnvConfig() *EnvConfig {
 		// 指标持久化配置
 		MetricsPersistenceEnabled: getEnv("METRICS_PERSISTENCE_ENABLED", "true") != "false",
 		MetricsRetentionDays:      clampInt(getEnvAsInt("METRICS_RETENTION_DAYS", 7), 3, 30),
+		// Prometheus 导出配置
+		PrometheusEnabled:   getEnv("PROMETHEUS_ENABLED", "true") != "false",
+		PrometheusAccessKey: getEnv("PROMETHEUS_ACCESS_KEY", ""),
+		PrometheusPublic:    getEnv("PROMETHEUS_PUBLIC", "false") == "true",
+		// 会话存储配置
+		SessionStore: getEnv("SESSION_STORE", "sqlite"),
+		// 请求审计日志配置
+		RequestLogEnabled:       getEnv("REQUEST_LOG_ENABLED", "false") == "true",
+		RequestLogCaptureBodies: getEnv("REQUEST_LOG_CAPTURE_BODIES", "false") == "true",
+		RequestLogMaxBodySize:   clampInt(getEnvAsInt("REQUEST_LOG_MAX_BODY_KB", 64), 1, 1024) * 1024,
+		RequestLogRetentionDays: clampInt(getEnvAsInt("REQUEST_LOG_RETENTION_DAYS", 7), 1, 90),
+		// 管理操作审计日志配置
+		AuditLogEnabled: getEnv("AUDIT_LOG_ENABLED", "true") != "false",
 		// HTTP 客户端配置
 		ResponseHeaderTimeout: clampInt(getEnvAsInt("RESPONSE_HEADER_TIMEOUT", 60), 30, 120), // 30-120 秒
+		// 流式 failover 配置
+		StreamFailoverEnabled:     getEnv("STREAM_FAILOVER_ENABLED", "true") != "false",
+		StreamFirstContentTimeout: clampInt(getEnvAsInt("STREAM_FIRST_CONTENT_TIMEOUT", 60), 5, 300), // 5-300 秒
+		StreamIdleTimeout:         clampInt(getEnvAsInt("STREAM_IDLE_TIMEOUT", 180), 0, 3600),        // 0-3600 秒
+		StreamHeartbeatInterval:   clampInt(getEnvAsInt("STREAM_HEARTBEAT_INTERVAL", 0), 0, 300),     // 0-300 秒
 		// 日志文件配置
 		LogDir:        getEnv("LOG_DIR", "logs"),
 		LogFile:       getEnv("LOG_FILE", "app.log"),
@@ -0,0 +1,836 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/google/uuid"
+)
+
+// ============== OpenAI Chat Completions -> Claude/Gemini/Responses 请求转换 ==============
+
+// defaultClaudeMaxTokens Claude 要求必须携带 max_tokens，客户端未提供时使用的默认值
+const defaultClaudeMaxTokens = 8192
+
+// OpenAIChatToClaudeRequest 将 OpenAI Chat Completions 请求转换为 Claude Messages API 格式
+// system/developer 消息合并为 system；tool 消息转换为 user 角色的 tool_result 块；
+// assistant.tool_calls 转换为 tool_use 块。
+func OpenAIChatToClaudeRequest(chatReq map[string]interface{}, model string) (map[string]interface{}, error) {
+	claudeReq := map[string]interface{}{
+		"model": model,
+	}
+
+	messagesRaw, _ := chatReq["messages"].([]interface{})
+	if len(messagesRaw) == 0 {
+		return nil, fmt.Errorf("messages 不能为空")
+	}
+
+	var systemParts []string
+	messages := []map[string]interface{}{}
+
+	// appendBlocks 将内容块追加到消息列表，相同角色的相邻消息合并（Claude 要求 user/assistant 交替）
+	appendBlocks := func(role string, blocks []map[string]interface{}) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(messages); n > 0 && messages[n-1]["role"] == role {
+			existing, _ := messages[n-1]["content"].([]map[string]interface{})
+			messages[n-1]["content"] = append(existing, blocks...)
+			return
+		}
+		messages = append(messages, map[string]interface{}{
+			"role":    role,
+			"content": blocks,
+		})
+	}
+
+	for _, raw := range messagesRaw {
+		msg, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := msg["role"].(string)
+
+		switch role {
+		case "system", "developer":
+			if text := extractChatText(msg["content"]); text != "" {
+				systemParts = append(systemParts, text)
+			}
+
+		case "user":
+			appendBlocks("user", chatContentToClaudeBlocks(msg["content"]))
+
+		case "assistant":
+			blocks := chatContentToClaudeBlocks(msg["content"])
+			if toolCalls, ok := msg["tool_calls"].([]interface{}); ok {
+				for _, tc := range toolCalls {
+					call, ok := tc.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					fn, _ := call["function"].(map[string]interface{})
+					name, _ := fn["name"].(string)
+					id, _ := call["id"].(string)
+					blocks = append(blocks, map[string]interface{}{
+						"type":  "tool_use",
+						"id":    id,
+						"name":  name,
+						"input": parseToolArguments(fn["arguments"]),
+					})
+				}
+			}
+			appendBlocks("assistant", blocks)
+
+		case "tool":
+			toolCallID, _ := msg["tool_call_id"].(string)
+			appendBlocks("user", []map[string]interface{}{
+				{
+					"type":        "tool_result",
+					"tool_use_id": toolCallID,
+					"content":     extractChatText(msg["content"]),
+				},
+			})
+		}
+	}
+
+	if len(systemParts) > 0 {
+		claudeReq["system"] = strings.Join(systemParts, "\n\n")
+	}
+	claudeReq["messages"] = messages
+
+	// 生成参数
+	maxTokens := defaultClaudeMaxTokens
+	if v, ok := getIntFromMap(chatReq, "max_completion_tokens"); ok && v > 0 {
+		maxTokens = v
+	} else if v, ok := getIntFromMap(chatReq, "max_tokens"); ok && v > 0 {
+		maxTokens = v
+	}
+	claudeReq["max_tokens"] = maxTokens
+
+	if v, ok := chatReq["temperature"].(float64); ok {
+		claudeReq["temperature"] = v
+	}
+	if v, ok := chatReq["top_p"].(float64); ok {
+		claudeReq["top_p"] = v
+	}
+	if stops := chatStopSequences(chatReq["stop"]); len(stops) > 0 {
+		claudeReq["stop_sequences"] = stops
+	}
+	// reasoning_effort → thinking（none 关闭思考，无法识别的取值沿用上游默认）
+	if effort, _ := chatReq["reasoning_effort"].(string); effort == "none" {
+		applyClaudeThinking(claudeReq, 0)
+	} else if budget := EffortToThinkingBudget(effort); budget > 0 {
+		applyClaudeThinking(claudeReq, budget)
+	}
+	if user, ok := chatReq["user"].(string); ok && user != "" {
+		claudeReq["metadata"] = map[string]interface{}{"user_id": user}
+	}
+
+	// 工具定义
+	if tools := chatToolsToFunctions(chatReq["tools"]); len(tools) > 0 {
+		claudeTools := make([]map[string]interface{}, 0, len(tools))
+		for _, fn := range tools {
+			claudeTool := map[string]interface{}{
+				"name": fn.Name,
+			}
+			if fn.Description != "" {
+				claudeTool["description"] = fn.Description
+			}
+			if fn.Parameters != nil {
+				claudeTool["input_schema"] = fn.Parameters
+			} else {
+				claudeTool["input_schema"] = map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{},
+				}
+			}
+			claudeTools = append(claudeTools, claudeTool)
+		}
+		claudeReq["tools"] = claudeTools
+	}
+
+	// tool_choice: auto / none / required / {"type":"function","function":{"name":...}}
+	switch tc := chatReq["tool_choice"].(type) {
+	case string:
+		switch tc {
+		case "auto":
+			claudeReq["tool_choice"] = map[string]interface{}{"type": "auto"}
+		case "required":
+			claudeReq["tool_choice"] = map[string]interface{}{"type": "any"}
+		case "none":
+			claudeReq["tool_choice"] = map[string]interface{}{"type": "none"}
+		}
+	case map[string]interface{}:
+		if fn, ok := tc["function"].(map[string]interface{}); ok {
+			if name, _ := fn["name"].(string); name != "" {
+				claudeReq["tool_choice"] = map[string]interface{}{"type": "tool", "name": name}
+			}
+		}
+	}
+
+	return claudeReq, nil
+}
+
+// OpenAIChatToGeminiRequest 将 OpenAI Chat Completions 请求转换为 Gemini generateContent 格式
+func OpenAIChatToGeminiRequest(chatReq map[string]interface{}) (*types.GeminiRequest, error) {
+	messagesRaw, _ := chatReq["messages"].([]interface{})
+	if len(messagesRaw) == 0 {
+		return nil, fmt.Errorf("messages 不能为空")
+	}
+
+	geminiReq := &types.GeminiRequest{}
+	var systemParts []types.GeminiPart
+	// tool_call_id -> function name（Gemini functionResponse 需要函数名）
+	toolNames := make(map[string]string)
+
+	appendParts := func(role string, parts []types.GeminiPart) {
+		if len(parts) == 0 {
+			return
+		}
+		if n := len(geminiReq.Contents); n > 0 && geminiReq.Contents[n-1].Role == role {
+			geminiReq.Contents[n-1].Parts = append(geminiReq.Contents[n-1].Parts, parts...)
+			return
+		}
+		geminiReq.Contents = append(geminiReq.Contents, types.GeminiContent{Role: role, Parts: parts})
+	}
+
+	for _, raw := range messagesRaw {
+		msg, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := msg["role"].(string)
+
+		switch role {
+		case "system", "developer":
+			if text := extractChatText(msg["content"]); text != "" {
+				systemParts = append(systemParts, types.GeminiPart{Text: text})
+			}
+
+		case "user":
+			appendParts("user", chatContentToGeminiParts(msg["content"]))
+
+		case "assistant":
+			parts := chatContentToGeminiParts(msg["content"])
+			if toolCalls, ok := msg["tool_calls"].([]interface{}); ok {
+				for _, tc := range toolCalls {
+					call, ok := tc.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					fn, _ := call["function"].(map[string]interface{})
+					name, _ := fn["name"].(string)
+					if id, _ := call["id"].(string); id != "" {
+						toolNames[id] = name
+					}
+					args, _ := parseToolArguments(fn["arguments"]).(map[string]interface{})
+					if args == nil {
+						args = map[string]interface{}{}
+					}
+					parts = append(parts, types.GeminiPart{
+						FunctionCall: &types.GeminiFunctionCall{Name: name, Args: args},
+					})
+				}
+			}
+			appendParts("model", parts)
+
+		case "tool":
+			toolCallID, _ := msg["tool_call_id"].(string)
+			name := toolNames[toolCallID]
+			if name == "" {
+				name = toolCallID
+			}
+			output := extractChatText(msg["content"])
+			response, ok := parseToolArguments(output).(map[string]interface{})
+			if !ok || response == nil {
+				response = map[string]interface{}{"content": output}
+			}
+			appendParts("user", []types.GeminiPart{
+				{FunctionResponse: &types.GeminiFunctionResponse{Name: name, Response: response}},
+			})
+		}
+	}
+
+	if len(systemParts) > 0 {
+		geminiReq.SystemInstruction = &types.GeminiContent{Parts: systemParts}
+	}
+
+	// 生成参数
+	genCfg := &types.GeminiGenerationConfig{}
+	hasGenCfg := false
+	if v, ok := getIntFromMap(chatReq, "max_completion_tokens"); ok && v > 0 {
+		genCfg.MaxOutputTokens = v
+		hasGenCfg = true
+	} else if v, ok := getIntFromMap(chatReq, "max_tokens"); ok && v > 0 {
+		genCfg.MaxOutputTokens = v
+		hasGenCfg = true
+	}
+	if v, ok := chatReq["temperature"].(float64); ok {
+		genCfg.Temperature = &v
+		hasGenCfg = true
+	}
+	if v, ok := chatReq["top_p"].(float64); ok {
+		genCfg.TopP = &v
+		hasGenCfg = true
+	}
+	if stops := chatStopSequences(chatReq["stop"]); len(stops) > 0 {
+		genCfg.StopSequences = stops
+		hasGenCfg = true
+	}
+	if effort, _ := chatReq["reasoning_effort"].(string); effort == "none" || EffortToThinkingBudget(effort) > 0 {
+		genCfg.ThinkingConfig = EffortToGeminiThinkingConfig(effort)
+		hasGenCfg = true
+	}
+	if hasGenCfg {
+		geminiReq.GenerationConfig = genCfg
+	}
+
+	// 工具定义
+	if tools := chatToolsToFunctions(chatReq["tools"]); len(tools) > 0 {
+		decls := make([]types.GeminiFunctionDeclaration, 0, len(tools))
+		for _, fn := range tools {
+			decls = append(decls, types.GeminiFunctionDeclaration{
+				Name:        fn.Name,
+				Description: fn.Description,
+				Parameters:  fn.Parameters,
+			})
+		}
+		geminiReq.Tools = []types.GeminiTool{{FunctionDeclarations: decls}}
+	}
+
+	return geminiReq, nil
+}
+
+// OpenAIChatToResponsesRequest 将 OpenAI Chat Completions 请求转换为 Responses API 格式
+// Chat 入口是无状态的，因此固定 store=false，避免上游保存会话。
+func OpenAIChatToResponsesRequest(chatReq map[string]interface{}, model string) (map[string]interface{}, error) {
+	messagesRaw, _ := chatReq["messages"].([]interface{})
+	if len(messagesRaw) == 0 {
+		return nil, fmt.Errorf("messages 不能为空")
+	}
+
+	responsesReq := map[string]interface{}{
+		"model": model,
+		"store": false,
+	}
+
+	var instructions []string
+	input := []map[string]interface{}{}
+
+	for _, raw := range messagesRaw {
+		msg, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := msg["role"].(string)
+
+		switch role {
+		case "system", "developer":
+			if text := extractChatText(msg["content"]); text != "" {
+				instructions = append(instructions, text)
+			}
+
+		case "user", "assistant":
+			textType := "input_text"
+			if role == "assistant" {
+				textType = "output_text"
+			}
+			if parts := chatContentToResponsesParts(msg["content"], textType); len(parts) > 0 {
+				input = append(input, map[string]interface{}{
+					"type":    "message",
+					"role":    role,
+					"content": parts,
+				})
+			}
+			if toolCalls, ok := msg["tool_calls"].([]interface{}); ok {
+				for _, tc := range toolCalls {
+					call, ok := tc.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					fn, _ := call["function"].(map[string]interface{})
+					name, _ := fn["name"].(string)
+					id, _ := call["id"].(string)
+					args, _ := fn["arguments"].(string)
+					input = append(input, map[string]interface{}{
+						"type":      "function_call",
+						"call_id":   id,
+						"name":      name,
+						"arguments": args,
+					})
+				}
+			}
+
+		case "tool":
+			toolCallID, _ := msg["tool_call_id"].(string)
+			input = append(input, map[string]interface{}{
+				"type":    "function_call_output",
+				"call_id": toolCallID,
+				"output":  extractChatText(msg["content"]),
+			})
+		}
+	}
+
+	if len(instructions) > 0 {
+		responsesReq["instructions"] = strings.Join(instructions, "\n\n")
+	}
+	responsesReq["input"] = input
+
+	if v, ok := getIntFromMap(chatReq, "max_completion_tokens"); ok && v > 0 {
+		responsesReq["max_output_tokens"] = v
+	} else if v, ok := getIntFromMap(chatReq, "max_tokens"); ok && v > 0 {
+		responsesReq["max_output_tokens"] = v
+	}
+	if v, ok := chatReq["temperature"].(float64); ok {
+		responsesReq["temperature"] = v
+	}
+	if v, ok := chatReq["top_p"].(float64); ok {
+		responsesReq["top_p"] = v
+	}
+	if v, ok := chatReq["parallel_tool_calls"].(bool); ok {
+		responsesReq["parallel_tool_calls"] = v
+	}
+	if user, ok := chatReq["user"].(string); ok && user != "" {
+		responsesReq["user"] = user
+	}
+	if effort, ok := chatReq["reasoning_effort"].(string); ok && effort != "" {
+		responsesReq["reasoning"] = map[string]interface{}{"effort": effort}
+	}
+
+	if tools := chatToolsToFunctions(chatReq["tools"]); len(tools) > 0 {
+		respTools := make([]map[string]interface{}, 0, len(tools))
+		for _, fn := range tools {
+			tool := map[string]interface{}{
+				"type": "function",
+				"name": fn.Name,
+			}
+			if fn.Description != "" {
+				tool["description"] = fn.Description
+			}
+			if fn.Parameters != nil {
+				tool["parameters"] = fn.Parameters
+			}
+			respTools = append(respTools, tool)
+		}
+		responsesReq["tools"] = respTools
+	}
+
+	switch tc := chatReq["tool_choice"].(type) {
+	case string:
+		responsesReq["tool_choice"] = tc
+	case map[string]interface{}:
+		if fn, ok := tc["function"].(map[string]interface{}); ok {
+			if name, _ := fn["name"].(string); name != "" {
+				responsesReq["tool_choice"] = map[string]interface{}{"type": "function", "name": name}
+			}
+		}
+	}
+
+	return responsesReq, nil
+}
+
+// ============== Claude/Gemini/Responses -> OpenAI Chat Completions 响应转换 ==============
+
+// ClaudeResponseToOpenAIChat 将 Claude Messages 响应转换为 Chat Completions 格式
+func ClaudeResponseToOpenAIChat(claudeResp map[string]interface{}, model string) (map[string]interface{}, error) {
+	content, ok := claudeResp["content"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Claude 响应缺少 content 字段")
+	}
+
+	var textParts []string
+	var reasoningParts []string
+	var toolCalls []map[string]interface{}
+	for _, c := range content {
+		block, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch block["type"] {
+		case "thinking":
+			if thinking, _ := block["thinking"].(string); thinking != "" {
+				reasoningParts = append(reasoningParts, thinking)
+			}
+		case "text":
+			if text, _ := block["text"].(string); text != "" {
+				textParts = append(textParts, text)
+			}
+		case "tool_use":
+			id, _ := block["id"].(string)
+			name, _ := block["name"].(string)
+			args, _ := json.Marshal(block["input"])
+			toolCalls = append(toolCalls, newChatToolCall(id, name, string(args)))
+		}
+	}
+
+	stopReason, _ := claudeResp["stop_reason"].(string)
+	finishReason := AnthropicStopReasonToOpenAI(stopReason)
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+
+	id, _ := claudeResp["id"].(string)
+	resp := newChatCompletion(id, model, strings.Join(textParts, ""), toolCalls, finishReason)
+	setChatReasoningContent(resp, strings.Join(reasoningParts, ""))
+
+	if usage := parseClaudeUsage(claudeResp["usage"]); usage.InputTokens > 0 || usage.OutputTokens > 0 {
+		// Claude 的 input_tokens 不含缓存读写，Chat 口径需要加回
+		promptTokens := usage.InputTokens + usage.CacheReadInputTokens + usage.CacheCreationInputTokens
+		resp["usage"] = newChatUsage(promptTokens, usage.OutputTokens, usage.CacheReadInputTokens)
+	}
+
+	return resp, nil
+}
+
+// GeminiResponseToOpenAIChat 将 Gemini generateContent 响应转换为 Chat Completions 格式
+func GeminiResponseToOpenAIChat(geminiResp *types.GeminiResponse, model string) map[string]interface{} {
+	var textParts []string
+	var reasoningParts []string
+	var toolCalls []map[string]interface{}
+	finishReason := "stop"
+
+	if len(geminiResp.Candidates) > 0 {
+		candidate := geminiResp.Candidates[0]
+		if candidate.Content != nil {
+			for _, part := range candidate.Content.Parts {
+				if part.Thought {
+					if part.Text != "" {
+						reasoningParts = append(reasoningParts, part.Text)
+					}
+					continue
+				}
+				if part.Text != "" {
+					textParts = append(textParts, part.Text)
+				}
+				if part.FunctionCall != nil {
+					args, _ := json.Marshal(part.FunctionCall.Args)
+					id := fmt.Sprintf("call_%s", strings.ReplaceAll(uuid.New().String(), "-", "")[:24])
+					toolCalls = append(toolCalls, newChatToolCall(id, part.FunctionCall.Name, string(args)))
+				}
+			}
+		}
+		if candidate.FinishReason != "" {
+			finishReason = geminiFinishReasonToOpenAI(candidate.FinishReason)
+		}
+	}
+	if len(toolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+
+	resp := newChatCompletion("", model, strings.Join(textParts, ""), toolCalls, finishReason)
+	setChatReasoningContent(resp, strings.Join(reasoningParts, ""))
+
+	if geminiResp.UsageMetadata != nil {
+		meta := geminiResp.UsageMetadata
+		resp["usage"] = newChatUsage(meta.PromptTokenCount, meta.CandidatesTokenCount+meta.ThoughtsTokenCount, meta.CachedContentTokenCount)
+	}
+
+	return resp
+}
+
+// ResponsesResponseToOpenAIChat 将 Responses API 响应转换为 Chat Completions 格式
+func ResponsesResponseToOpenAIChat(responsesResp map[string]interface{}, model string) (map[string]interface{}, error) {
+	output, ok := responsesResp["output"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Responses 响应缺少 output 字段")
+	}
+
+	var textParts []string
+	var toolCalls []map[string]interface{}
+	for _, o := range output {
+		item, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch item["type"] {
+		case "message":
+			if text := extractChatText(item["content"]); text != "" {
+				textParts = append(textParts, text)
+			}
+		case "function_call":
+			id, _ := item["call_id"].(string)
+			name, _ := item["name"].(string)
+			args, _ := item["arguments"].(string)
+			toolCalls = append(toolCalls, newChatToolCall(id, name, args))
+		}
+	}
+
+	finishReason := "stop"
+	if status, _ := responsesResp["status"].(string); status == "incomplete" {
+		finishReason = "length"
+	}
+	if len(toolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+
+	resp := newChatCompletion("", model, strings.Join(textParts, ""), toolCalls, finishReason)
+
+	if usage := parseResponsesUsage(responsesResp["usage"]); usage.InputTokens > 0 || usage.OutputTokens > 0 {
+		cached := 0
+		if usage.InputTokensDetails != nil {
+			cached = usage.InputTokensDetails.CachedTokens
+		}
+		resp["usage"] = newChatUsage(usage.InputTokens, usage.OutputTokens, cached)
+	}
+
+	return resp, nil
+}
+
+// ============== 内部辅助函数 ==============
+
+// newChatCompletion 构建 chat.completion 响应对象
+func newChatCompletion(id, model, content string, toolCalls []map[string]interface{}, finishReason string) map[string]interface{} {
+	if id == "" {
+		id = NewChatCompletionID()
+	} else if !strings.HasPrefix(id, "chatcmpl-") {
+		id = "chatcmpl-" + id
+	}
+
+	message := map[string]interface{}{
+		"role":    "assistant",
+		"content": content,
+	}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+		if content == "" {
+			message["content"] = nil
+		}
+	}
+
+	return map[string]interface{}{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": []map[string]interface{}{
+			{
+				"index":         0,
+				"message":       message,
+				"finish_reason": finishReason,
+			},
+		},
+	}
+}
+
+// setChatReasoningContent 为 chat.completion 响应的 message 设置 reasoning_content（思考内容）
+func setChatReasoningContent(resp map[string]interface{}, reasoning string) {
+	if reasoning == "" {
+		return
+	}
+	choices, _ := resp["choices"].([]map[string]interface{})
+	if len(choices) == 0 {
+		return
+	}
+	if message, ok := choices[0]["message"].(map[string]interface{}); ok {
+		message["reasoning_content"] = reasoning
+	}
+}
+
+// NewChatCompletionID 生成 chat.completion 响应 ID
+func NewChatCompletionID() string {
+	return "chatcmpl-" + strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// newChatToolCall 构建 Chat Completions 工具调用对象
+func newChatToolCall(id, name, arguments string) map[string]interface{} {
+	if arguments == "" || arguments == "null" {
+		arguments = "{}"
+	}
+	return map[string]interface{}{
+		"id":   id,
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":      name,
+			"arguments": arguments,
+		},
+	}
+}
+
+// newChatUsage 构建 Chat Completions usage 对象（prompt_tokens 包含缓存命中部分）
+func newChatUsage(promptTokens, completionTokens, cachedTokens int) map[string]interface{} {
+	usage := map[string]interface{}{
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"total_tokens":      promptTokens + completionTokens,
+	}
+	if cachedTokens > 0 {
+		usage["prompt_tokens_details"] = map[string]interface{}{"cached_tokens": cachedTokens}
+	}
+	return usage
+}
+
+// chatFunction Chat Completions 工具定义中的 function 部分
+type chatFunction struct {
+	Name        string
+	Description string
+	Parameters  interface{}
+}
+
+// chatToolsToFunctions 提取 Chat Completions tools 中的函数定义
+func chatToolsToFunctions(toolsRaw interface{}) []chatFunction {
+	tools, ok := toolsRaw.([]interface{})
+	if !ok {
+		return nil
+	}
+	var result []chatFunction
+	for _, t := range tools {
+		tool, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if toolType, _ := tool["type"].(string); toolType != "" && toolType != "function" {
+			continue
+		}
+		fn, ok := tool["function"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := fn["name"].(string)
+		if name == "" {
+			continue
+		}
+		desc, _ := fn["description"].(string)
+		result = append(result, chatFunction{Name: name, Description: desc, Parameters: fn["parameters"]})
+	}
+	return result
+}
+
+// chatStopSequences 解析 stop 字段（字符串或字符串数组）
+func chatStopSequences(stop interface{}) []string {
+	switch v := stop.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []interface{}:
+		var result []string
+		for _, s := range v {
+			if str, ok := s.(string); ok && str != "" {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// parseToolArguments 解析工具调用参数（JSON 字符串），失败时原样包装
+func parseToolArguments(args interface{}) interface{} {
+	switch v := args.(type) {
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return map[string]interface{}{}
+		}
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(v), &parsed); err == nil {
+			return parsed
+		}
+		return v
+	case nil:
+		return map[string]interface{}{}
+	default:
+		return v
+	}
+}
+
+// extractChatText 从 Chat 消息 content 中提取文本
+// 用于 system/developer 与 tool 消息（Chat Completions 中这两类消息只能携带文本 part）
+func extractChatText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []interface{}:
+		texts := []string{}
+		for _, c := range v {
+			part, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch part["type"] {
+			case "text", "input_text", "output_text":
+				if text, ok := part["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+	return ""
+}
+
+// walkChatContent 按顺序遍历 Chat 消息 content：相邻文本 part 合并后回调 onText，image_url/file part 回调 onMedia
+func walkChatContent(content interface{}, onText func(text string), onMedia func(part map[string]interface{})) {
+	parts, ok := content.([]interface{})
+	if !ok {
+		if text := extractChatText(content); text != "" {
+			onText(text)
+		}
+		return
+	}
+
+	var texts []interface{}
+	flushText := func() {
+		if text := extractChatText(texts); text != "" {
+			onText(text)
+		}
+		texts = nil
+	}
+	for _, raw := range parts {
+		part, ok := raw.(map[string]interface{})
+		if ok && IsOpenAIMediaPart(part) {
+			flushText()
+			onMedia(part)
+			continue
+		}
+		texts = append(texts, raw)
+	}
+	flushText()
+}
+
+// chatContentToClaudeBlocks 将 Chat 消息 content（字符串或 parts 数组）转换为 Claude 文本与 image/document 块
+func chatContentToClaudeBlocks(content interface{}) []map[string]interface{} {
+	var blocks []map[string]interface{}
+	walkChatContent(content, func(text string) {
+		blocks = append(blocks, map[string]interface{}{"type": "text", "text": text})
+	}, func(part map[string]interface{}) {
+		if block := OpenAIPartToClaudeMediaBlock(part); block != nil {
+			blocks = append(blocks, block)
+		}
+	})
+	return blocks
+}
+
+// chatContentToGeminiParts 将 Chat 消息 content 转换为 Gemini parts（图片/文件转为 inlineData 或 fileData）
+func chatContentToGeminiParts(content interface{}) []types.GeminiPart {
+	var parts []types.GeminiPart
+	walkChatContent(content, func(text string) {
+		parts = append(parts, types.GeminiPart{Text: text})
+	}, func(part map[string]interface{}) {
+		if block := OpenAIPartToClaudeMediaBlock(part); block != nil {
+			if geminiPart := ClaudeMediaBlockToGeminiPart(block); geminiPart != nil {
+				parts = append(parts, *geminiPart)
+			}
+		}
+	})
+	return parts
+}
+
+// chatContentToResponsesParts 将 Chat 消息 content 转换为 Responses content 数组
+// 图片/文件转为 input_image/input_file，仅用于输入消息（textType 为 input_text）
+func chatContentToResponsesParts(content interface{}, textType string) []map[string]interface{} {
+	var parts []map[string]interface{}
+	walkChatContent(content, func(text string) {
+		parts = append(parts, map[string]interface{}{"type": textType, "text": text})
+	}, func(part map[string]interface{}) {
+		if textType != "input_text" {
+			return
+		}
+		if responsesPart := OpenAIPartToResponsesPart(part); responsesPart != nil {
+			parts = append(parts, responsesPart)
+		}
+	})
+	return parts
+}
@@ -0,0 +1,351 @@
+package converters
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseChatRequest(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &req))
+	return req
+}
+
+// TestOpenAIChatToClaudeRequest_ToolRoundTrip 测试 system、tool_calls 与 tool 消息的转换
+func TestOpenAIChatToClaudeRequest_ToolRoundTrip(t *testing.T) {
+	req := parseChatRequest(t, `{
+		"model": "gpt-4o",
+		"max_tokens": 256,
+		"stop": "END",
+		"messages": [
+			{"role": "system", "content": "You are helpful."},
+			{"role": "user", "content": [{"type": "text", "text": "weather?"}]},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "sunny"},
+			{"role": "tool", "tool_call_id": "call_2", "content": "rainy"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}],
+		"tool_choice": "required"
+	}`)
+
+	claudeReq, err := OpenAIChatToClaudeRequest(req, "claude-sonnet")
+	require.NoError(t, err)
+
+	assert.Equal(t, "claude-sonnet", claudeReq["model"])
+	assert.Equal(t, "You are helpful.", claudeReq["system"])
+	assert.Equal(t, 256, claudeReq["max_tokens"])
+	assert.Equal(t, []string{"END"}, claudeReq["stop_sequences"])
+	assert.Equal(t, map[string]interface{}{"type": "any"}, claudeReq["tool_choice"])
+
+	messages := claudeReq["messages"].([]map[string]interface{})
+	require.Len(t, messages, 3)
+	assert.Equal(t, "user", messages[0]["role"])
+	assert.Equal(t, "assistant", messages[1]["role"])
+
+	toolUse := messages[1]["content"].([]map[string]interface{})[0]
+	assert.Equal(t, "tool_use", toolUse["type"])
+	assert.Equal(t, "call_1", toolUse["id"])
+	assert.Equal(t, map[string]interface{}{"city": "Paris"}, toolUse["input"])
+
+	// 连续的 tool 消息合并为同一条 user 消息
+	results := messages[2]["content"].([]map[string]interface{})
+	require.Len(t, results, 2)
+	assert.Equal(t, "tool_result", results[0]["type"])
+	assert.Equal(t, "call_2", results[1]["tool_use_id"])
+}
+
+// TestOpenAIChatToClaudeRequest_DefaultMaxTokens 测试未提供 max_tokens 时使用默认值
+func TestOpenAIChatToClaudeRequest_DefaultMaxTokens(t *testing.T) {
+	req := parseChatRequest(t, `{"messages": [{"role": "user", "content": "hi"}]}`)
+	claudeReq, err := OpenAIChatToClaudeRequest(req, "claude-sonnet")
+	require.NoError(t, err)
+	assert.Equal(t, defaultClaudeMaxTokens, claudeReq["max_tokens"])
+
+	_, err = OpenAIChatToClaudeRequest(parseChatRequest(t, `{"messages": []}`), "claude-sonnet")
+	assert.Error(t, err)
+}
+
+// TestOpenAIChatToGeminiRequest 测试 Chat 请求转换为 Gemini 格式（functionResponse 需要函数名）
+func TestOpenAIChatToGeminiRequest(t *testing.T) {
+	req := parseChatRequest(t, `{
+		"temperature": 0.3,
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": "weather?"},
+			{"role": "assistant", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "{\"temp\":20}"}
+		]
+	}`)
+
+	geminiReq, err := OpenAIChatToGeminiRequest(req)
+	require.NoError(t, err)
+
+	require.NotNil(t, geminiReq.SystemInstruction)
+	assert.Equal(t, "be brief", geminiReq.SystemInstruction.Parts[0].Text)
+	require.NotNil(t, geminiReq.GenerationConfig)
+	assert.Equal(t, 0.3, *geminiReq.GenerationConfig.Temperature)
+
+	require.Len(t, geminiReq.Contents, 3)
+	assert.Equal(t, "model", geminiReq.Contents[1].Role)
+	assert.Equal(t, "get_weather", geminiReq.Contents[1].Parts[0].FunctionCall.Name)
+
+	fr := geminiReq.Contents[2].Parts[0].FunctionResponse
+	require.NotNil(t, fr)
+	assert.Equal(t, "get_weather", fr.Name)
+	assert.Equal(t, float64(20), fr.Response["temp"])
+}
+
+// TestOpenAIChatToResponsesRequest 测试 Chat 请求转换为 Responses 格式
+func TestOpenAIChatToResponsesRequest(t *testing.T) {
+	req := parseChatRequest(t, `{
+		"max_completion_tokens": 100,
+		"reasoning_effort": "high",
+		"messages": [
+			{"role": "developer", "content": "rules"},
+			{"role": "user", "content": "hi"},
+			{"role": "assistant", "content": "calling", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "f", "arguments": "{}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "ok"}
+		]
+	}`)
+
+	respReq, err := OpenAIChatToResponsesRequest(req, "gpt-5")
+	require.NoError(t, err)
+
+	assert.Equal(t, false, respReq["store"])
+	assert.Equal(t, "rules", respReq["instructions"])
+	assert.Equal(t, 100, respReq["max_output_tokens"])
+	assert.Equal(t, map[string]interface{}{"effort": "high"}, respReq["reasoning"])
+
+	input := respReq["input"].([]map[string]interface{})
+	require.Len(t, input, 4)
+	assert.Equal(t, "message", input[0]["type"])
+	assert.Equal(t, "output_text", input[1]["content"].([]map[string]interface{})[0]["type"])
+	assert.Equal(t, "function_call", input[2]["type"])
+	assert.Equal(t, "function_call_output", input[3]["type"])
+	assert.Equal(t, "call_1", input[3]["call_id"])
+}
+
+// TestOpenAIChatMediaParts 测试 user 消息中的 image_url/file part 转换到各上游格式
+func TestOpenAIChatMediaParts(t *testing.T) {
+	raw := `{
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "text", "text": "describe"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw0", "detail": "high"}},
+				{"type": "image_url", "image_url": {"url": "https://example.com/cat.jpg"}},
+				{"type": "file", "file": {"filename": "a.pdf", "file_data": "data:application/pdf;base64,JVBERi0"}}
+			]}
+		]
+	}`
+
+	claudeReq, err := OpenAIChatToClaudeRequest(parseChatRequest(t, raw), "claude")
+	require.NoError(t, err)
+	blocks := claudeReq["messages"].([]map[string]interface{})[0]["content"].([]map[string]interface{})
+	require.Len(t, blocks, 4)
+	assert.Equal(t, "text", blocks[0]["type"])
+	assert.Equal(t, map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "iVBORw0"}, blocks[1]["source"])
+	assert.Equal(t, map[string]interface{}{"type": "url", "url": "https://example.com/cat.jpg"}, blocks[2]["source"])
+	assert.Equal(t, "document", blocks[3]["type"])
+	assert.Equal(t, "a.pdf", blocks[3]["title"])
+
+	geminiReq, err := OpenAIChatToGeminiRequest(parseChatRequest(t, raw))
+	require.NoError(t, err)
+	parts := geminiReq.Contents[0].Parts
+	require.Len(t, parts, 4)
+	require.NotNil(t, parts[1].InlineData)
+	assert.Equal(t, "image/png", parts[1].InlineData.MimeType)
+	require.NotNil(t, parts[2].FileData)
+	assert.Equal(t, "image/jpeg", parts[2].FileData.MimeType)
+	require.NotNil(t, parts[3].InlineData)
+	assert.Equal(t, "application/pdf", parts[3].InlineData.MimeType)
+
+	respReq, err := OpenAIChatToResponsesRequest(parseChatRequest(t, raw), "gpt-5")
+	require.NoError(t, err)
+	content := respReq["input"].([]map[string]interface{})[0]["content"].([]map[string]interface{})
+	require.Len(t, content, 4)
+	assert.Equal(t, map[string]interface{}{"type": "input_image", "image_url": "data:image/png;base64,iVBORw0", "detail": "high"}, content[1])
+	assert.Equal(t, "input_file", content[3]["type"])
+	assert.Equal(t, "a.pdf", content[3]["filename"])
+}
+
+// TestClaudeResponseToOpenAIChat 测试 Claude 响应转换（usage 需加回缓存 token）
+func TestClaudeResponseToOpenAIChat(t *testing.T) {
+	var claudeResp map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"id": "msg_1",
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "output_tokens": 5, "cache_read_input_tokens": 90}
+	}`), &claudeResp))
+
+	resp, err := ClaudeResponseToOpenAIChat(claudeResp, "gpt-4o")
+	require.NoError(t, err)
+
+	assert.Equal(t, "chatcmpl-msg_1", resp["id"])
+	assert.Equal(t, "chat.completion", resp["object"])
+	assert.Equal(t, "gpt-4o", resp["model"])
+
+	choice := resp["choices"].([]map[string]interface{})[0]
+	assert.Equal(t, "tool_calls", choice["finish_reason"])
+	message := choice["message"].(map[string]interface{})
+	assert.Equal(t, "Let me check.", message["content"])
+	toolCalls := message["tool_calls"].([]map[string]interface{})
+	require.Len(t, toolCalls, 1)
+	assert.Equal(t, `{"city":"Paris"}`, toolCalls[0]["function"].(map[string]interface{})["arguments"])
+
+	usage := resp["usage"].(map[string]interface{})
+	assert.Equal(t, 100, usage["prompt_tokens"])
+	assert.Equal(t, 5, usage["completion_tokens"])
+	assert.Equal(t, map[string]interface{}{"cached_tokens": 90}, usage["prompt_tokens_details"])
+}
+
+// TestResponsesResponseToOpenAIChat 测试 Responses 响应转换
+func TestResponsesResponseToOpenAIChat(t *testing.T) {
+	var responsesResp map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"status": "completed",
+		"output": [
+			{"type": "reasoning", "summary": []},
+			{"type": "message", "content": [{"type": "output_text", "text": "hello"}]}
+		],
+		"usage": {"input_tokens": 12, "output_tokens": 3}
+	}`), &responsesResp))
+
+	resp, err := ResponsesResponseToOpenAIChat(responsesResp, "gpt-5")
+	require.NoError(t, err)
+
+	choice := resp["choices"].([]map[string]interface{})[0]
+	assert.Equal(t, "stop", choice["finish_reason"])
+	assert.Equal(t, "hello", choice["message"].(map[string]interface{})["content"])
+	assert.Equal(t, 15, resp["usage"].(map[string]interface{})["total_tokens"])
+}
+
+// TestGeminiResponseToOpenAIChat 测试 Gemini 响应转换（忽略 thought 部分）
+func TestGeminiResponseToOpenAIChat(t *testing.T) {
+	geminiResp := &types.GeminiResponse{
+		Candidates: []types.GeminiCandidate{{
+			Content: &types.GeminiContent{Parts: []types.GeminiPart{
+				{Text: "thinking...", Thought: true},
+				{Text: "answer"},
+			}},
+			FinishReason: "MAX_TOKENS",
+		}},
+		UsageMetadata: &types.GeminiUsageMetadata{PromptTokenCount: 8, CandidatesTokenCount: 2},
+	}
+
+	resp := GeminiResponseToOpenAIChat(geminiResp, "gemini-2.5-pro")
+	choice := resp["choices"].([]map[string]interface{})[0]
+	assert.Equal(t, "length", choice["finish_reason"])
+	assert.Equal(t, "answer", choice["message"].(map[string]interface{})["content"])
+	assert.True(t, strings.HasPrefix(resp["id"].(string), "chatcmpl-"))
+}
+
+// TestChatStreamState_Claude 测试 Claude 流式事件转换为 Chat chunk
+func TestChatStreamState_Claude(t *testing.T) {
+	st := NewChatStreamState("gpt-4o", true)
+
+	var chunks []string
+	for _, ev := range []string{
+		`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10,"cache_read_input_tokens":5}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"f"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"a\":1}"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}`,
+		`{"type":"message_stop"}`,
+	} {
+		chunks = append(chunks, st.ConvertClaudeEvent([]byte(ev))...)
+	}
+	chunks = append(chunks, st.Finish()...)
+
+	// role + text + tool start + tool args + finish + usage
+	require.Len(t, chunks, 6)
+	assert.Contains(t, chunks[0], `"role":"assistant"`)
+	assert.Contains(t, chunks[1], `"content":"Hi"`)
+	assert.Contains(t, chunks[2], `"id":"toolu_1"`)
+	assert.Contains(t, chunks[3], `"arguments":"{\"a\":1}"`)
+	assert.Contains(t, chunks[4], `"finish_reason":"tool_calls"`)
+	assert.Contains(t, chunks[5], `"prompt_tokens":15`)
+	assert.Contains(t, chunks[0], `"id":"chatcmpl-msg_1"`)
+
+	usage := st.Usage()
+	require.NotNil(t, usage)
+	assert.Equal(t, 10, usage.InputTokens)
+	assert.Equal(t, 5, usage.CacheReadInputTokens)
+	assert.Equal(t, 7, usage.OutputTokens)
+
+	assert.Empty(t, st.Finish(), "Finish 只应生效一次")
+}
+
+// TestChatStreamState_Responses 测试 Responses 流式事件转换
+func TestChatStreamState_Responses(t *testing.T) {
+	st := NewChatStreamState("gpt-5", false)
+
+	var chunks []string
+	for _, ev := range []string{
+		`{"type":"response.created","response":{"id":"resp_abc"}}`,
+		`{"type":"response.output_item.added","output_index":1,"item":{"type":"function_call","call_id":"call_9","name":"f","arguments":""}}`,
+		`{"type":"response.function_call_arguments.delta","output_index":1,"delta":"{}"}`,
+		`{"type":"response.completed","response":{"status":"completed","usage":{"input_tokens":4,"output_tokens":2}}}`,
+	} {
+		chunks = append(chunks, st.ConvertResponsesEvent([]byte(ev))...)
+	}
+	chunks = append(chunks, st.Finish()...)
+
+	// 未请求 include_usage 时不输出 usage chunk
+	require.Len(t, chunks, 4)
+	assert.Contains(t, chunks[0], `"id":"chatcmpl-abc"`)
+	assert.Contains(t, chunks[1], `"id":"call_9"`)
+	assert.Contains(t, chunks[3], `"finish_reason":"tool_calls"`)
+	assert.Equal(t, 4, st.Usage().InputTokens)
+}
+
+// TestChatStreamState_ObserveOpenAIChunk 测试透传模式下的 usage 收集与过滤
+func TestChatStreamState_ObserveOpenAIChunk(t *testing.T) {
+	st := NewChatStreamState("gpt-4o", false)
+
+	assert.True(t, st.ObserveOpenAIChunk([]byte(`{"choices":[{"delta":{"content":"x"}}]}`)))
+	assert.False(t, st.ObserveOpenAIChunk([]byte(`{"choices":[],"usage":{"prompt_tokens":9,"completion_tokens":1,"prompt_tokens_details":{"cached_tokens":4}}}`)))
+
+	usage := st.Usage()
+	require.NotNil(t, usage)
+	assert.Equal(t, 5, usage.InputTokens)
+	assert.Equal(t, 4, usage.CacheReadInputTokens)
+}
+
+// TestChatStreamState_UpstreamError 测试上游错误事件的识别与错误负载
+func TestChatStreamState_UpstreamError(t *testing.T) {
+	st := NewChatStreamState("claude", false)
+	st.ConvertClaudeEvent([]byte(`{"type":"message_start","message":{"id":"msg_1"}}`))
+	st.ConvertClaudeEvent([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+	assert.Equal(t, "Overloaded", st.UpstreamError())
+
+	chunk := st.ErrorChunk("Overloaded")
+	assert.Contains(t, chunk, `"error":{`)
+	assert.Contains(t, chunk, `"message":"Overloaded"`)
+	// 错误后不再输出正常结束
+	assert.Empty(t, st.Finish())
+
+	st = NewChatStreamState("gpt-5", false)
+	st.ConvertResponsesEvent([]byte(`{"type":"response.failed","response":{"error":{"code":"server_error","message":"boom"}}}`))
+	assert.Equal(t, "boom", st.UpstreamError())
+
+	st = NewChatStreamState("gemini", false)
+	st.ConvertGeminiChunk([]byte(`{"error":{"code":500,"status":"INTERNAL","message":""}}`))
+	assert.Equal(t, "INTERNAL", st.UpstreamError())
+}
@@ -0,0 +1,411 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+)
+
+// ChatStreamState Claude/Gemini/Responses 流式响应转换为 Chat Completions chunk 的状态
+// 每个请求独立创建，在多次调用间保持工具调用索引、结束原因与 usage。
+type ChatStreamState struct {
+	ID           string
+	Model        string
+	Created      int64
+	IncludeUsage bool // 客户端是否请求了 stream_options.include_usage
+
+	roleSent     bool
+	toolIndex    map[string]int // 上游块标识 -> Chat tool_calls 索引
+	nextTool     int
+	finishReason string
+	finished     bool
+	upstreamErr  string // 上游流中返回的错误事件消息（非空表示流异常中断）
+
+	// usage（Chat 口径：promptTokens 包含缓存读写）
+	usageSeen           bool
+	promptTokens        int
+	completionTokens    int
+	cachedTokens        int
+	cacheCreationTokens int
+}
+
+// NewChatStreamState 创建流式转换状态
+func NewChatStreamState(model string, includeUsage bool) *ChatStreamState {
+	return &ChatStreamState{
+		ID:           NewChatCompletionID(),
+		Model:        model,
+		Created:      time.Now().Unix(),
+		IncludeUsage: includeUsage,
+		toolIndex:    make(map[string]int),
+	}
+}
+
+// ConvertClaudeEvent 转换一条 Claude SSE data 负载，返回 0 或多个 chunk JSON
+func (st *ChatStreamState) ConvertClaudeEvent(data []byte) []string {
+	event := gjson.ParseBytes(data)
+	var out []string
+
+	switch event.Get("type").String() {
+	case "message_start":
+		if id := event.Get("message.id").String(); id != "" {
+			st.ID = "chatcmpl-" + id
+		}
+		st.observeClaudeUsage(event.Get("message.usage"))
+		out = append(out, st.roleChunk()...)
+
+	case "content_block_start":
+		block := event.Get("content_block")
+		if block.Get("type").String() == "tool_use" {
+			key := "claude:" + event.Get("index").String()
+			out = append(out, st.roleChunk()...)
+			out = append(out, st.toolStartChunk(key, block.Get("id").String(), block.Get("name").String(), ""))
+		}
+
+	case "content_block_delta":
+		delta := event.Get("delta")
+		switch delta.Get("type").String() {
+		case "text_delta":
+			if text := delta.Get("text").String(); text != "" {
+				out = append(out, st.roleChunk()...)
+				out = append(out, st.contentChunk(text))
+			}
+		case "thinking_delta":
+			if thinking := delta.Get("thinking").String(); thinking != "" {
+				out = append(out, st.roleChunk()...)
+				out = append(out, st.reasoningChunk(thinking))
+			}
+		case "input_json_delta":
+			key := "claude:" + event.Get("index").String()
+			if partial := delta.Get("partial_json").String(); partial != "" {
+				out = append(out, st.toolArgsChunk(key, partial))
+			}
+		}
+
+	case "message_delta":
+		if reason := event.Get("delta.stop_reason").String(); reason != "" {
+			st.finishReason = AnthropicStopReasonToOpenAI(reason)
+		}
+		st.observeClaudeUsage(event.Get("usage"))
+
+	case "error":
+		st.setUpstreamError(event.Get("error.message").String(), event.Get("error.type").String())
+	}
+
+	return out
+}
+
+// ConvertGeminiChunk 转换一条 Gemini streamGenerateContent SSE data 负载
+func (st *ChatStreamState) ConvertGeminiChunk(data []byte) []string {
+	var chunk types.GeminiStreamChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil
+	}
+
+	if errResult := gjson.GetBytes(data, "error"); errResult.Exists() {
+		st.setUpstreamError(errResult.Get("message").String(), errResult.Get("status").String())
+		return nil
+	}
+
+	var out []string
+	if len(chunk.Candidates) > 0 {
+		candidate := chunk.Candidates[0]
+		if candidate.Content != nil {
+			for _, part := range candidate.Content.Parts {
+				if part.Thought {
+					if part.Text != "" {
+						out = append(out, st.roleChunk()...)
+						out = append(out, st.reasoningChunk(part.Text))
+					}
+					continue
+				}
+				if part.Text != "" {
+					out = append(out, st.roleChunk()...)
+					out = append(out, st.contentChunk(part.Text))
+				}
+				if part.FunctionCall != nil {
+					args, _ := json.Marshal(part.FunctionCall.Args)
+					key := fmt.Sprintf("gemini:%d", st.nextTool)
+					id := fmt.Sprintf("call_%s", strings.TrimPrefix(NewChatCompletionID(), "chatcmpl-")[:24])
+					out = append(out, st.roleChunk()...)
+					out = append(out, st.toolStartChunk(key, id, part.FunctionCall.Name, string(args)))
+				}
+			}
+		}
+		if candidate.FinishReason != "" {
+			st.finishReason = geminiFinishReasonToOpenAI(candidate.FinishReason)
+		}
+	}
+
+	if meta := chunk.UsageMetadata; meta != nil {
+		st.usageSeen = true
+		st.promptTokens = meta.PromptTokenCount
+		st.completionTokens = meta.CandidatesTokenCount + meta.ThoughtsTokenCount
+		st.cachedTokens = meta.CachedContentTokenCount
+	}
+
+	return out
+}
+
+// ConvertResponsesEvent 转换一条 Responses API SSE data 负载
+func (st *ChatStreamState) ConvertResponsesEvent(data []byte) []string {
+	event := gjson.ParseBytes(data)
+	var out []string
+
+	switch event.Get("type").String() {
+	case "response.created":
+		if id := event.Get("response.id").String(); id != "" {
+			st.ID = "chatcmpl-" + strings.TrimPrefix(id, "resp_")
+		}
+		out = append(out, st.roleChunk()...)
+
+	case "response.output_text.delta":
+		if text := event.Get("delta").String(); text != "" {
+			out = append(out, st.roleChunk()...)
+			out = append(out, st.contentChunk(text))
+		}
+
+	case "response.output_item.added":
+		item := event.Get("item")
+		if item.Get("type").String() == "function_call" {
+			key := "responses:" + event.Get("output_index").String()
+			out = append(out, st.roleChunk()...)
+			out = append(out, st.toolStartChunk(key, item.Get("call_id").String(), item.Get("name").String(), item.Get("arguments").String()))
+		}
+
+	case "response.function_call_arguments.delta":
+		key := "responses:" + event.Get("output_index").String()
+		if delta := event.Get("delta").String(); delta != "" {
+			out = append(out, st.toolArgsChunk(key, delta))
+		}
+
+	case "response.completed", "response.incomplete":
+		resp := event.Get("response")
+		if resp.Get("status").String() == "incomplete" {
+			st.finishReason = "length"
+		} else if st.finishReason == "" {
+			st.finishReason = "stop"
+		}
+		if usage := resp.Get("usage"); usage.Exists() {
+			st.usageSeen = true
+			st.promptTokens = int(usage.Get("input_tokens").Int())
+			st.completionTokens = int(usage.Get("output_tokens").Int())
+			st.cachedTokens = int(usage.Get("input_tokens_details.cached_tokens").Int())
+		}
+
+	case "response.failed":
+		st.setUpstreamError(event.Get("response.error.message").String(), event.Get("response.error.code").String())
+
+	case "error":
+		st.setUpstreamError(event.Get("message").String(), event.Get("code").String())
+	}
+
+	return out
+}
+
+// ObserveOpenAIChunk 观察透传的 OpenAI chunk，收集 usage
+// 返回 false 表示该 chunk 仅用于内部统计（客户端未请求 include_usage），不应转发
+func (st *ChatStreamState) ObserveOpenAIChunk(data []byte) bool {
+	chunk := gjson.ParseBytes(data)
+	if errResult := chunk.Get("error"); errResult.Exists() && errResult.Type != gjson.Null {
+		st.setUpstreamError(errResult.Get("message").String(), errResult.Get("type").String())
+		return true
+	}
+	usage := chunk.Get("usage")
+	if !usage.Exists() || usage.Type == gjson.Null {
+		return true
+	}
+
+	st.usageSeen = true
+	st.promptTokens = int(usage.Get("prompt_tokens").Int())
+	st.completionTokens = int(usage.Get("completion_tokens").Int())
+	st.cachedTokens = int(usage.Get("prompt_tokens_details.cached_tokens").Int())
+
+	if st.IncludeUsage {
+		return true
+	}
+	choices := chunk.Get("choices")
+	return choices.IsArray() && len(choices.Array()) > 0
+}
+
+// Finish 生成结束 chunk（finish_reason 与可选的 usage chunk）
+// 在上游流结束后调用一次；重复调用返回空。
+func (st *ChatStreamState) Finish() []string {
+	if st.finished {
+		return nil
+	}
+	st.finished = true
+
+	var out []string
+	out = append(out, st.roleChunk()...)
+
+	reason := st.finishReason
+	if reason == "" {
+		reason = "stop"
+	}
+	if st.nextTool > 0 && reason == "stop" {
+		reason = "tool_calls"
+	}
+	out = append(out, st.marshalChunk(map[string]interface{}{}, reason))
+
+	if st.IncludeUsage && st.usageSeen {
+		chunk := st.baseChunk()
+		chunk["choices"] = []interface{}{}
+		chunk["usage"] = newChatUsage(st.promptTokens, st.completionTokens, st.cachedTokens)
+		data, _ := json.Marshal(chunk)
+		out = append(out, string(data))
+	}
+
+	return out
+}
+
+// UpstreamError 返回上游流中的错误消息，流正常时为空
+func (st *ChatStreamState) UpstreamError() string {
+	return st.upstreamErr
+}
+
+// ErrorChunk 生成 OpenAI 风格的流内错误负载（{"error": {...}}），用于流中途异常时替代正常结束
+// 调用后 Finish 不再输出结束 chunk。
+func (st *ChatStreamState) ErrorChunk(message string) string {
+	st.finished = true
+	data, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    "server_error",
+			"code":    "upstream_stream_error",
+		},
+	})
+	return string(data)
+}
+
+// setUpstreamError 记录上游错误事件（仅保留第一条）
+func (st *ChatStreamState) setUpstreamError(message, errType string) {
+	if st.upstreamErr != "" {
+		return
+	}
+	if message == "" {
+		message = errType
+	}
+	if message == "" {
+		message = "upstream stream error"
+	}
+	st.upstreamErr = message
+}
+
+// Usage 返回内部统一口径的 usage（InputTokens 不含缓存读写），无 usage 时返回 nil
+func (st *ChatStreamState) Usage() *types.Usage {
+	if !st.usageSeen {
+		return nil
+	}
+	input := st.promptTokens - st.cachedTokens - st.cacheCreationTokens
+	if input < 0 {
+		input = 0
+	}
+	return &types.Usage{
+		InputTokens:              input,
+		OutputTokens:             st.completionTokens,
+		CacheReadInputTokens:     st.cachedTokens,
+		CacheCreationInputTokens: st.cacheCreationTokens,
+	}
+}
+
+// observeClaudeUsage 合并 Claude usage（message_start 携带输入，message_delta 携带输出）
+func (st *ChatStreamState) observeClaudeUsage(usage gjson.Result) {
+	if !usage.Exists() {
+		return
+	}
+	input := int(usage.Get("input_tokens").Int())
+	cacheRead := int(usage.Get("cache_read_input_tokens").Int())
+	cacheCreation := int(usage.Get("cache_creation_input_tokens").Int())
+	if input > 0 || cacheRead > 0 || cacheCreation > 0 {
+		st.promptTokens = input + cacheRead + cacheCreation
+		st.cachedTokens = cacheRead
+		st.cacheCreationTokens = cacheCreation
+		st.usageSeen = true
+	}
+	if output := int(usage.Get("output_tokens").Int()); output > 0 {
+		st.completionTokens = output
+		st.usageSeen = true
+	}
+}
+
+// roleChunk 首个 chunk 需要携带 role=assistant
+func (st *ChatStreamState) roleChunk() []string {
+	if st.roleSent {
+		return nil
+	}
+	st.roleSent = true
+	return []string{st.marshalChunk(map[string]interface{}{"role": "assistant", "content": ""}, "")}
+}
+
+func (st *ChatStreamState) contentChunk(text string) string {
+	return st.marshalChunk(map[string]interface{}{"content": text}, "")
+}
+
+func (st *ChatStreamState) reasoningChunk(text string) string {
+	return st.marshalChunk(map[string]interface{}{"reasoning_content": text}, "")
+}
+
+func (st *ChatStreamState) toolStartChunk(key, id, name, arguments string) string {
+	idx := st.nextTool
+	st.toolIndex[key] = idx
+	st.nextTool++
+	return st.marshalChunk(map[string]interface{}{
+		"tool_calls": []map[string]interface{}{
+			{
+				"index": idx,
+				"id":    id,
+				"type":  "function",
+				"function": map[string]interface{}{
+					"name":      name,
+					"arguments": arguments,
+				},
+			},
+		},
+	}, "")
+}
+
+func (st *ChatStreamState) toolArgsChunk(key, arguments string) string {
+	idx, ok := st.toolIndex[key]
+	if !ok {
+		idx = st.nextTool - 1
+		if idx < 0 {
+			idx = 0
+		}
+	}
+	return st.marshalChunk(map[string]interface{}{
+		"tool_calls": []map[string]interface{}{
+			{
+				"index":    idx,
+				"function": map[string]interface{}{"arguments": arguments},
+			},
+		},
+	}, "")
+}
+
+func (st *ChatStreamState) baseChunk() map[string]interface{} {
+	return map[string]interface{}{
+		"id":      st.ID,
+		"object":  "chat.completion.chunk",
+		"created": st.Created,
+		"model":   st.Model,
+	}
+}
+
+func (st *ChatStreamState) marshalChunk(delta map[string]interface{}, finishReason string) string {
+	choice := map[string]interface{}{
+		"index":         0,
+		"delta":         delta,
+		"finish_reason": nil,
+	}
+	if finishReason != "" {
+		choice["finish_reason"] = finishReason
+	}
+	chunk := st.baseChunk()
+	chunk["choices"] = []interface{}{choice}
+	data, _ := json.Marshal(chunk)
+	return string(data)
+}
@@ -55,6 +55,9 @@ func GeminiToClaudeRequest(geminiReq *types.GeminiRequest, model string) (map[st
 		if len(cfg.StopSequences) > 0 {
 			claudeReq["stop_sequences"] = cfg.StopSequences
 		}
+		if budget, ok := GeminiThinkingBudget(cfg.ThinkingConfig); ok {
+			applyClaudeThinking(claudeReq, budget)
+		}
 	}
 
 	// 4. 转换 tools -> tools
@@ -134,6 +137,10 @@ func GeminiToOpenAIRequest(geminiReq *types.GeminiRequest, model string) (map[st
 		if len(cfg.StopSequences) > 0 {
 			openaiReq["stop"] = cfg.StopSequences
 		}
+		// 关闭思考（thinkingBudget=0）时不传 reasoning_effort，保持上游默认
+		if budget, ok := GeminiThinkingBudget(cfg.ThinkingConfig); ok && budget > 0 {
+			openaiReq["reasoning_effort"] = ThinkingBudgetToEffort(budget)
+		}
 	}
 
 	// 4. 转换 tools -> tools
@@ -183,6 +190,14 @@ func ClaudeResponseToGemini(claudeResp map[string]interface{}) (*types.GeminiRes
 
 		blockType, _ := contentBlock["type"].(string)
 		switch blockType {
+		case "thinking":
+			thinking, _ := contentBlock["thinking"].(string)
+			signature, _ := contentBlock["signature"].(string)
+			parts = append(parts, types.GeminiPart{
+				Text:             thinking,
+				Thought:          true,
+				ThoughtSignature: signature,
+			})
 		case "text":
 			text, _ := contentBlock["text"].(string)
 			parts = append(parts, types.GeminiPart{
@@ -267,6 +282,14 @@ func OpenAIResponseToGemini(openaiResp map[string]interface{}) (*types.GeminiRes
 
 	// 处理 message
 	if message, ok := choice["message"].(map[string]interface{}); ok {
+		// 推理内容
+		if reasoning, ok := message["reasoning_content"].(string); ok && reasoning != "" {
+			parts = append(parts, types.GeminiPart{
+				Text:    reasoning,
+				Thought: true,
+			})
+		}
+
 		// 文本内容
 		if content, ok := message["content"].(string); ok && content != "" {
 			parts = append(parts, types.GeminiPart{
@@ -354,6 +377,18 @@ func geminiContentToClaudeMessage(content *types.GeminiContent) (map[string]inte
 	claudeContent := []map[string]interface{}{}
 
 	for i, part := range content.Parts {
+		if part.Thought {
+			// 仅携带 Claude 签名的思考内容可以回传（由 ClaudeResponseToGemini 生成），其余丢弃
+			if IsForwardableThinkingSignature(part.ThoughtSignature) {
+				claudeContent = append(claudeContent, map[string]interface{}{
+					"type":      "thinking",
+					"thinking":  part.Text,
+					"signature": part.ThoughtSignature,
+				})
+			}
+			continue
+		}
+
 		if part.Text != "" {
 			claudeContent = append(claudeContent, map[string]interface{}{
 				"type": "text",
@@ -427,6 +462,10 @@ func geminiContentToOpenAIMessage(content *types.GeminiContent) (map[string]inte
 	var toolResponseContent interface{}
 
 	for i, part := range content.Parts {
+		if part.Thought {
+			continue
+		}
+
 		if part.Text != "" {
 			textParts = append(textParts, part.Text)
 		}
@@ -0,0 +1,299 @@
+package converters
+
+import (
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+// ============== Claude image/document 块 -> OpenAI/Gemini 转换 ==============
+
+// IsClaudeMediaBlock 判断 Claude 内容块是否为 image/document 块
+func IsClaudeMediaBlock(block map[string]interface{}) bool {
+	blockType, _ := block["type"].(string)
+	return blockType == "image" || blockType == "document"
+}
+
+// ClaudeMediaBlockToOpenAIPart 将 Claude image/document 块转换为 OpenAI Chat content part
+// image → image_url（base64 转为 data URL）；document → file（file_data）；纯文本 document → text。
+// 无法识别的 source 返回 nil。
+func ClaudeMediaBlockToOpenAIPart(block map[string]interface{}) map[string]interface{} {
+	source, _ := block["source"].(map[string]interface{})
+	if source == nil {
+		return nil
+	}
+	sourceType, _ := source["type"].(string)
+	mediaType, _ := source["media_type"].(string)
+	data, _ := source["data"].(string)
+	url, _ := source["url"].(string)
+
+	blockType, _ := block["type"].(string)
+	if blockType == "image" {
+		switch sourceType {
+		case "base64":
+			url = fmt.Sprintf("data:%s;base64,%s", mediaType, data)
+		case "url":
+		default:
+			return nil
+		}
+		if url == "" {
+			return nil
+		}
+		return map[string]interface{}{
+			"type":      "image_url",
+			"image_url": map[string]interface{}{"url": url},
+		}
+	}
+
+	if blockType != "document" {
+		return nil
+	}
+	if sourceType == "text" {
+		return map[string]interface{}{"type": "text", "text": data}
+	}
+
+	filename := documentFilename(block, url, mediaType)
+	var fileData string
+	switch sourceType {
+	case "base64":
+		fileData = fmt.Sprintf("data:%s;base64,%s", mediaType, data)
+	case "url":
+		// 官方 Chat Completions 仅接受 data URL，兼容网关（如 OpenRouter）支持直接传 URL
+		fileData = url
+	default:
+		return nil
+	}
+	if fileData == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"type": "file",
+		"file": map[string]interface{}{
+			"filename":  filename,
+			"file_data": fileData,
+		},
+	}
+}
+
+// ClaudeMediaBlockToGeminiPart 将 Claude image/document 块转换为 Gemini part
+// base64 → inlineData；url → fileData；纯文本 document → text。无法识别的 source 返回 nil。
+func ClaudeMediaBlockToGeminiPart(block map[string]interface{}) *types.GeminiPart {
+	source, _ := block["source"].(map[string]interface{})
+	if source == nil {
+		return nil
+	}
+	sourceType, _ := source["type"].(string)
+	mediaType, _ := source["media_type"].(string)
+	data, _ := source["data"].(string)
+	url, _ := source["url"].(string)
+
+	switch sourceType {
+	case "base64":
+		if data == "" {
+			return nil
+		}
+		return &types.GeminiPart{InlineData: &types.GeminiInlineData{MimeType: mediaType, Data: data}}
+	case "url":
+		if url == "" {
+			return nil
+		}
+		if mediaType == "" {
+			blockType, _ := block["type"].(string)
+			mediaType = guessMediaType(url, blockType)
+		}
+		return &types.GeminiPart{FileData: &types.GeminiFileData{MimeType: mediaType, FileURI: url}}
+	case "text":
+		if data == "" {
+			return nil
+		}
+		return &types.GeminiPart{Text: data}
+	}
+	return nil
+}
+
+// SplitClaudeToolResultContent 拆分 tool_result 的 content 为文本与 image/document 块
+// OpenAI tool 消息与 Gemini functionResponse 只能携带文本，媒体块需由调用方单独追加。
+// content 为字符串时原样返回；数组中没有媒体块时返回 ok=false，调用方保持原有处理。
+func SplitClaudeToolResultContent(content interface{}) (text string, media []map[string]interface{}, ok bool) {
+	blocks, isArray := content.([]interface{})
+	if !isArray {
+		return "", nil, false
+	}
+
+	texts := []string{}
+	for _, b := range blocks {
+		block, isMap := b.(map[string]interface{})
+		if !isMap {
+			continue
+		}
+		if IsClaudeMediaBlock(block) {
+			media = append(media, block)
+			continue
+		}
+		if t, isText := block["text"].(string); isText && block["type"] == "text" {
+			texts = append(texts, t)
+		}
+	}
+	if len(media) == 0 {
+		return "", nil, false
+	}
+	return strings.Join(texts, "\n"), media, true
+}
+
+// GeminiInlineDataToClaudeBlock 将 Gemini inlineData 转换为 Claude image/document 块
+func GeminiInlineDataToClaudeBlock(mimeType, data string) types.ClaudeContent {
+	blockType := "document"
+	if strings.HasPrefix(mimeType, "image/") {
+		blockType = "image"
+	}
+	return types.ClaudeContent{
+		Type: blockType,
+		Source: &types.ClaudeContentSource{
+			Type:      "base64",
+			MediaType: mimeType,
+			Data:      data,
+		},
+	}
+}
+
+// documentFilename 生成 OpenAI file part 的文件名（优先使用 document 的 title）
+func documentFilename(block map[string]interface{}, url, mediaType string) string {
+	if title, _ := block["title"].(string); title != "" {
+		return title
+	}
+	if url != "" {
+		if base := path.Base(strings.SplitN(url, "?", 2)[0]); base != "" && base != "." && base != "/" {
+			return base
+		}
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return "document" + exts[0]
+	}
+	return "document.pdf"
+}
+
+// guessMediaType 根据 URL 扩展名推断 MIME 类型（Gemini fileData 需要 mimeType）
+func guessMediaType(url, blockType string) string {
+	ext := path.Ext(strings.SplitN(url, "?", 2)[0])
+	if mediaType := mime.TypeByExtension(ext); mediaType != "" {
+		return strings.SplitN(mediaType, ";", 2)[0]
+	}
+	if blockType == "image" {
+		return "image/jpeg"
+	}
+	return "application/pdf"
+}
+
+// ============== OpenAI Chat image_url/file part -> Claude/Responses 转换 ==============
+
+// IsOpenAIMediaPart 判断 OpenAI Chat content part 是否为 image_url/file part
+func IsOpenAIMediaPart(part map[string]interface{}) bool {
+	partType, _ := part["type"].(string)
+	return partType == "image_url" || partType == "file"
+}
+
+// OpenAIPartToClaudeMediaBlock 将 OpenAI Chat image_url/file part 转换为 Claude image/document 块
+// data URL → base64 source；其他 URL → url source。仅有 file_id 或无法识别时返回 nil。
+func OpenAIPartToClaudeMediaBlock(part map[string]interface{}) map[string]interface{} {
+	blockType := "image"
+	var url, filename string
+	switch part["type"] {
+	case "image_url":
+		url = openAIImageURL(part)
+	case "file":
+		blockType = "document"
+		file, _ := part["file"].(map[string]interface{})
+		url, _ = file["file_data"].(string)
+		filename, _ = file["filename"].(string)
+	default:
+		return nil
+	}
+	if url == "" {
+		return nil
+	}
+
+	source := map[string]interface{}{"type": "url", "url": url}
+	if mediaType, data, ok := parseBase64DataURL(url); ok {
+		source = map[string]interface{}{"type": "base64", "media_type": mediaType, "data": data}
+		if blockType == "document" && mediaType == "" {
+			source["media_type"] = "application/pdf"
+		}
+	} else if blockType == "document" && !strings.Contains(url, "://") {
+		// file_data 为裸 base64（部分客户端省略 data URL 前缀）
+		source = map[string]interface{}{"type": "base64", "media_type": guessMediaType(filename, blockType), "data": url}
+	}
+
+	block := map[string]interface{}{"type": blockType, "source": source}
+	if filename != "" {
+		block["title"] = filename
+	}
+	return block
+}
+
+// OpenAIPartToResponsesPart 将 OpenAI Chat image_url/file part 转换为 Responses input_image/input_file
+// 无法识别时返回 nil。
+func OpenAIPartToResponsesPart(part map[string]interface{}) map[string]interface{} {
+	switch part["type"] {
+	case "image_url":
+		url := openAIImageURL(part)
+		if url == "" {
+			return nil
+		}
+		result := map[string]interface{}{"type": "input_image", "image_url": url}
+		if imageURL, ok := part["image_url"].(map[string]interface{}); ok {
+			if detail, _ := imageURL["detail"].(string); detail != "" {
+				result["detail"] = detail
+			}
+		}
+		return result
+
+	case "file":
+		file, _ := part["file"].(map[string]interface{})
+		result := map[string]interface{}{"type": "input_file"}
+		if fileID, _ := file["file_id"].(string); fileID != "" {
+			result["file_id"] = fileID
+			return result
+		}
+		fileData, _ := file["file_data"].(string)
+		if fileData == "" {
+			return nil
+		}
+		if strings.HasPrefix(fileData, "data:") || !strings.Contains(fileData, "://") {
+			result["file_data"] = fileData
+		} else {
+			result["file_url"] = fileData
+		}
+		if filename, _ := file["filename"].(string); filename != "" {
+			result["filename"] = filename
+		}
+		return result
+	}
+	return nil
+}
+
+// openAIImageURL 提取 image_url part 的 URL（兼容 image_url 为字符串的写法）
+func openAIImageURL(part map[string]interface{}) string {
+	switch v := part["image_url"].(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		url, _ := v["url"].(string)
+		return url
+	}
+	return ""
+}
+
+// parseBase64DataURL 解析 data:<mediaType>;base64,<data> 形式的 URL
+func parseBase64DataURL(url string) (mediaType, data string, ok bool) {
+	if !strings.HasPrefix(url, "data:") {
+		return "", "", false
+	}
+	meta, data, found := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !found || !strings.HasSuffix(meta, ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(meta, ";base64"), data, true
+}
@@ -0,0 +1,127 @@
+package converters
+
+import (
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaudeMediaBlockToOpenAIPart 测试 image/document 块转换为 OpenAI image_url/file part
+func TestClaudeMediaBlockToOpenAIPart(t *testing.T) {
+	base64Image := map[string]interface{}{
+		"type":   "image",
+		"source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="},
+	}
+	part := ClaudeMediaBlockToOpenAIPart(base64Image)
+	require.NotNil(t, part)
+	assert.Equal(t, "image_url", part["type"])
+	assert.Equal(t, "data:image/png;base64,iVBORw0KGgo=", part["image_url"].(map[string]interface{})["url"])
+
+	urlImage := map[string]interface{}{
+		"type":   "image",
+		"source": map[string]interface{}{"type": "url", "url": "https://example.com/a.jpg"},
+	}
+	part = ClaudeMediaBlockToOpenAIPart(urlImage)
+	require.NotNil(t, part)
+	assert.Equal(t, "https://example.com/a.jpg", part["image_url"].(map[string]interface{})["url"])
+
+	pdf := map[string]interface{}{
+		"type":   "document",
+		"title":  "report.pdf",
+		"source": map[string]interface{}{"type": "base64", "media_type": "application/pdf", "data": "JVBERi0="},
+	}
+	part = ClaudeMediaBlockToOpenAIPart(pdf)
+	require.NotNil(t, part)
+	assert.Equal(t, "file", part["type"])
+	file := part["file"].(map[string]interface{})
+	assert.Equal(t, "report.pdf", file["filename"])
+	assert.Equal(t, "data:application/pdf;base64,JVBERi0=", file["file_data"])
+
+	urlPDF := map[string]interface{}{
+		"type":   "document",
+		"source": map[string]interface{}{"type": "url", "url": "https://example.com/docs/spec.pdf?v=2"},
+	}
+	part = ClaudeMediaBlockToOpenAIPart(urlPDF)
+	require.NotNil(t, part)
+	assert.Equal(t, "spec.pdf", part["file"].(map[string]interface{})["filename"])
+
+	textDoc := map[string]interface{}{
+		"type":   "document",
+		"source": map[string]interface{}{"type": "text", "media_type": "text/plain", "data": "plain text"},
+	}
+	assert.Equal(t, map[string]interface{}{"type": "text", "text": "plain text"}, ClaudeMediaBlockToOpenAIPart(textDoc))
+
+	assert.Nil(t, ClaudeMediaBlockToOpenAIPart(map[string]interface{}{"type": "image"}))
+	assert.Nil(t, ClaudeMediaBlockToOpenAIPart(map[string]interface{}{
+		"type":   "image",
+		"source": map[string]interface{}{"type": "file", "file_id": "file_1"},
+	}))
+}
+
+// TestClaudeMediaBlockToGeminiPart 测试 image/document 块转换为 Gemini inlineData/fileData part
+func TestClaudeMediaBlockToGeminiPart(t *testing.T) {
+	part := ClaudeMediaBlockToGeminiPart(map[string]interface{}{
+		"type":   "image",
+		"source": map[string]interface{}{"type": "base64", "media_type": "image/jpeg", "data": "/9j/4AAQ"},
+	})
+	require.NotNil(t, part)
+	require.NotNil(t, part.InlineData)
+	assert.Equal(t, "image/jpeg", part.InlineData.MimeType)
+	assert.Equal(t, "/9j/4AAQ", part.InlineData.Data)
+
+	part = ClaudeMediaBlockToGeminiPart(map[string]interface{}{
+		"type":   "document",
+		"source": map[string]interface{}{"type": "url", "url": "https://example.com/spec.pdf"},
+	})
+	require.NotNil(t, part)
+	require.NotNil(t, part.FileData)
+	assert.Equal(t, "application/pdf", part.FileData.MimeType)
+	assert.Equal(t, "https://example.com/spec.pdf", part.FileData.FileURI)
+
+	part = ClaudeMediaBlockToGeminiPart(map[string]interface{}{
+		"type":   "image",
+		"source": map[string]interface{}{"type": "url", "url": "https://example.com/photo"},
+	})
+	require.NotNil(t, part)
+	assert.Equal(t, "image/jpeg", part.FileData.MimeType, "无扩展名的图片 URL 使用默认 MIME 类型")
+
+	part = ClaudeMediaBlockToGeminiPart(map[string]interface{}{
+		"type":   "document",
+		"source": map[string]interface{}{"type": "text", "data": "plain text"},
+	})
+	require.NotNil(t, part)
+	assert.Equal(t, "plain text", part.Text)
+}
+
+// TestSplitClaudeToolResultContent 测试 tool_result content 的文本/媒体拆分
+func TestSplitClaudeToolResultContent(t *testing.T) {
+	content := []interface{}{
+		map[string]interface{}{"type": "text", "text": "screenshot taken"},
+		map[string]interface{}{
+			"type":   "image",
+			"source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "AAAA"},
+		},
+	}
+	text, media, ok := SplitClaudeToolResultContent(content)
+	require.True(t, ok)
+	assert.Equal(t, "screenshot taken", text)
+	require.Len(t, media, 1)
+	assert.Equal(t, "image", media[0]["type"])
+
+	// 无媒体块或字符串 content 时由调用方保持原有处理
+	_, _, ok = SplitClaudeToolResultContent([]interface{}{map[string]interface{}{"type": "text", "text": "ok"}})
+	assert.False(t, ok)
+	_, _, ok = SplitClaudeToolResultContent("ok")
+	assert.False(t, ok)
+}
+
+// TestGeminiInlineDataToClaudeBlock 测试 Gemini inlineData 转回 Claude 块
+func TestGeminiInlineDataToClaudeBlock(t *testing.T) {
+	block := GeminiInlineDataToClaudeBlock("image/png", "AAAA")
+	assert.Equal(t, "image", block.Type)
+	assert.Equal(t, &types.ClaudeContentSource{Type: "base64", MediaType: "image/png", Data: "AAAA"}, block.Source)
+
+	assert.Equal(t, "document", GeminiInlineDataToClaudeBlock("application/pdf", "JVBERi0=").Type)
+}
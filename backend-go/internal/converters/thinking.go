@@ -0,0 +1,157 @@
+package converters
+
+import "github.com/BenedictKing/claude-proxy/internal/types"
+
+// ============== 扩展思考（thinking / reasoning）跨协议映射 ==============
+//
+// Claude thinking.budget_tokens ↔ OpenAI reasoning_effort ↔ Gemini thinkingConfig.thinkingBudget
+// 三者之间没有精确对应关系，按以下档位近似换算：
+//
+//	budget_tokens <= 4096   → low
+//	budget_tokens <= 16384  → medium
+//	budget_tokens >  16384  → high
+
+const (
+	// minThinkingBudget Claude 要求的最小 budget_tokens
+	minThinkingBudget = 1024
+	// defaultThinkingBudget 未指定预算（Gemini 动态预算 -1 等）时使用的默认值
+	defaultThinkingBudget = 16384
+
+	// SyntheticThinkingSignature 由非 Claude 上游（OpenAI reasoning_content 等）生成的 thinking 块签名
+	// 客户端回传时会原样携带；转换回 Gemini 时需识别并丢弃，避免作为 thoughtSignature 发给上游
+	SyntheticThinkingSignature = "claude-proxy-synthetic-thinking"
+)
+
+// ThinkingBudgetToEffort 将 Claude thinking 预算换算为 OpenAI reasoning_effort
+func ThinkingBudgetToEffort(budget int) string {
+	switch {
+	case budget <= 0:
+		return "medium"
+	case budget <= 4096:
+		return "low"
+	case budget <= 16384:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// EffortToThinkingBudget 将 OpenAI reasoning_effort 换算为 thinking 预算
+// 返回 0 表示关闭思考（none）或无法识别
+func EffortToThinkingBudget(effort string) int {
+	switch effort {
+	case "minimal":
+		return minThinkingBudget
+	case "low":
+		return 4096
+	case "medium":
+		return 16384
+	case "high", "xhigh":
+		return 32768
+	default:
+		return 0
+	}
+}
+
+// ClaudeThinkingToEffort 将 Claude thinking 配置转换为 OpenAI reasoning_effort
+// 未启用思考时返回空字符串
+func ClaudeThinkingToEffort(thinking *types.ClaudeThinking) string {
+	if thinking == nil || thinking.Type != "enabled" {
+		return ""
+	}
+	return ThinkingBudgetToEffort(thinking.BudgetTokens)
+}
+
+// ClaudeThinkingToGeminiConfig 将 Claude thinking 配置转换为 Gemini thinkingConfig
+// thinking 未设置时返回 nil（沿用上游默认行为）；disabled 映射为 thinkingBudget=0
+func ClaudeThinkingToGeminiConfig(thinking *types.ClaudeThinking) *types.GeminiThinkingConfig {
+	if thinking == nil {
+		return nil
+	}
+	if thinking.Type != "enabled" {
+		zero := int32(0)
+		return &types.GeminiThinkingConfig{ThinkingBudget: &zero}
+	}
+	cfg := &types.GeminiThinkingConfig{IncludeThoughts: true}
+	if thinking.BudgetTokens > 0 {
+		budget := int32(thinking.BudgetTokens)
+		cfg.ThinkingBudget = &budget
+	}
+	return cfg
+}
+
+// EffortToGeminiThinkingConfig 将 OpenAI reasoning_effort 转换为 Gemini thinkingConfig
+func EffortToGeminiThinkingConfig(effort string) *types.GeminiThinkingConfig {
+	if effort == "" {
+		return nil
+	}
+	budget := int32(EffortToThinkingBudget(effort))
+	if budget == 0 {
+		return &types.GeminiThinkingConfig{ThinkingBudget: &budget}
+	}
+	return &types.GeminiThinkingConfig{IncludeThoughts: true, ThinkingBudget: &budget}
+}
+
+// GeminiThinkingBudget 解析 Gemini thinkingConfig 对应的 thinking 预算
+// 返回 (预算, 是否设置)；预算为 0 表示关闭思考，动态预算（-1）或仅指定 thinkingLevel 时使用默认值
+func GeminiThinkingBudget(cfg *types.GeminiThinkingConfig) (int, bool) {
+	if cfg == nil {
+		return 0, false
+	}
+	if cfg.ThinkingBudget == nil {
+		if cfg.ThinkingLevel != "" {
+			if budget := EffortToThinkingBudget(cfg.ThinkingLevel); budget > 0 {
+				return budget, true
+			}
+		}
+		if cfg.IncludeThoughts {
+			return defaultThinkingBudget, true
+		}
+		return 0, false
+	}
+	budget := int(*cfg.ThinkingBudget)
+	switch {
+	case budget == 0:
+		return 0, true
+	case budget < 0:
+		return defaultThinkingBudget, true
+	case budget < minThinkingBudget:
+		return minThinkingBudget, true
+	default:
+		return budget, true
+	}
+}
+
+// IsForwardableThinkingSignature 判断 thinking 签名是否可作为 Gemini thoughtSignature 回传上游
+func IsForwardableThinkingSignature(signature string) bool {
+	return signature != "" && signature != SyntheticThinkingSignature && signature != types.DummyThoughtSignature
+}
+
+// applyClaudeThinking 在 Claude 请求中设置扩展思考（budget 为 0 表示关闭）
+// Claude 要求 max_tokens 大于 budget_tokens，且启用思考时不支持自定义 temperature/top_k
+func applyClaudeThinking(claudeReq map[string]interface{}, budget int) {
+	if budget <= 0 {
+		claudeReq["thinking"] = map[string]interface{}{"type": "disabled"}
+		return
+	}
+	if budget < minThinkingBudget {
+		budget = minThinkingBudget
+	}
+	claudeReq["thinking"] = map[string]interface{}{
+		"type":          "enabled",
+		"budget_tokens": budget,
+	}
+
+	maxTokens, _ := getIntFromMap(claudeReq, "max_tokens")
+	if maxTokens <= budget {
+		if maxTokens <= 0 {
+			maxTokens = defaultClaudeMaxTokens
+		}
+		claudeReq["max_tokens"] = budget + maxTokens
+	}
+	delete(claudeReq, "temperature")
+	delete(claudeReq, "top_k")
+	if topP, ok := claudeReq["top_p"].(float64); ok && topP < 0.95 {
+		delete(claudeReq, "top_p")
+	}
+}
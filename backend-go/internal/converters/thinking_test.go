@@ -0,0 +1,134 @@
+package converters
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestThinkingBudgetEffortMapping 测试 thinking 预算与 reasoning_effort 的档位换算
+func TestThinkingBudgetEffortMapping(t *testing.T) {
+	assert.Equal(t, "low", ThinkingBudgetToEffort(2048))
+	assert.Equal(t, "medium", ThinkingBudgetToEffort(10000))
+	assert.Equal(t, "high", ThinkingBudgetToEffort(32000))
+
+	assert.Equal(t, 0, EffortToThinkingBudget("none"))
+	assert.Equal(t, 4096, EffortToThinkingBudget("low"))
+	assert.Equal(t, 32768, EffortToThinkingBudget("high"))
+
+	assert.Equal(t, "", ClaudeThinkingToEffort(&types.ClaudeThinking{Type: "disabled"}))
+	assert.Equal(t, "medium", ClaudeThinkingToEffort(&types.ClaudeThinking{Type: "enabled", BudgetTokens: 16384}))
+
+	dynamic := int32(-1)
+	budget, ok := GeminiThinkingBudget(&types.GeminiThinkingConfig{ThinkingBudget: &dynamic})
+	assert.True(t, ok)
+	assert.Equal(t, defaultThinkingBudget, budget)
+
+	off := int32(0)
+	budget, ok = GeminiThinkingBudget(&types.GeminiThinkingConfig{ThinkingBudget: &off})
+	assert.True(t, ok)
+	assert.Equal(t, 0, budget)
+
+	_, ok = GeminiThinkingBudget(nil)
+	assert.False(t, ok)
+}
+
+// TestGeminiToClaudeRequest_ThinkingConfig 测试 thinkingConfig 转换为 Claude thinking，并调整 max_tokens/temperature
+func TestGeminiToClaudeRequest_ThinkingConfig(t *testing.T) {
+	budget := int32(8000)
+	temperature := 0.2
+	geminiReq := &types.GeminiRequest{
+		Contents: []types.GeminiContent{
+			{Role: "user", Parts: []types.GeminiPart{{Text: "hi"}}},
+			{Role: "model", Parts: []types.GeminiPart{
+				{Text: "thought", Thought: true, ThoughtSignature: "claude_sig"},
+				{Text: "dropped", Thought: true},
+				{Text: "hello"},
+			}},
+		},
+		GenerationConfig: &types.GeminiGenerationConfig{
+			MaxOutputTokens: 4096,
+			Temperature:     &temperature,
+			ThinkingConfig:  &types.GeminiThinkingConfig{IncludeThoughts: true, ThinkingBudget: &budget},
+		},
+	}
+
+	claudeReq, err := GeminiToClaudeRequest(geminiReq, "claude-sonnet-4")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"type": "enabled", "budget_tokens": 8000}, claudeReq["thinking"])
+	assert.Equal(t, 12096, claudeReq["max_tokens"])
+	assert.NotContains(t, claudeReq, "temperature")
+
+	messages := claudeReq["messages"].([]map[string]interface{})
+	blocks := messages[1]["content"].([]map[string]interface{})
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "thinking", blocks[0]["type"])
+	assert.Equal(t, "claude_sig", blocks[0]["signature"])
+	assert.Equal(t, "text", blocks[1]["type"])
+
+	openaiReq, err := GeminiToOpenAIRequest(geminiReq, "gpt-5")
+	require.NoError(t, err)
+	assert.Equal(t, "medium", openaiReq["reasoning_effort"])
+}
+
+// TestClaudeResponseToGemini_Thinking 测试 Claude thinking 块转换为携带签名的 thought part
+func TestClaudeResponseToGemini_Thinking(t *testing.T) {
+	var claudeResp map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"content":[
+		{"type":"thinking","thinking":"plan","signature":"sig_abc"},
+		{"type":"text","text":"answer"}
+	],"stop_reason":"end_turn"}`), &claudeResp))
+
+	geminiResp, err := ClaudeResponseToGemini(claudeResp)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(geminiResp.Candidates[0].Content.Parts)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"text":"plan","thought":true,"thoughtSignature":"sig_abc"},{"text":"answer"}]`, string(data))
+}
+
+// TestOpenAIChatReasoningEffort 测试 Chat 入口 reasoning_effort 转换为 Claude thinking / Gemini thinkingConfig
+func TestOpenAIChatReasoningEffort(t *testing.T) {
+	chatReq := parseChatRequest(t, `{"model":"m","reasoning_effort":"low","max_tokens":1000,"messages":[{"role":"user","content":"hi"}]}`)
+
+	claudeReq, err := OpenAIChatToClaudeRequest(chatReq, "claude-sonnet-4")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"type": "enabled", "budget_tokens": 4096}, claudeReq["thinking"])
+	assert.Equal(t, 5096, claudeReq["max_tokens"])
+
+	geminiReq, err := OpenAIChatToGeminiRequest(chatReq)
+	require.NoError(t, err)
+	require.NotNil(t, geminiReq.GenerationConfig.ThinkingConfig)
+	assert.Equal(t, int32(4096), *geminiReq.GenerationConfig.ThinkingConfig.ThinkingBudget)
+
+	chatReq = parseChatRequest(t, `{"model":"m","reasoning_effort":"none","messages":[{"role":"user","content":"hi"}]}`)
+	claudeReq, err = OpenAIChatToClaudeRequest(chatReq, "claude-sonnet-4")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"type": "disabled"}, claudeReq["thinking"])
+}
+
+// TestChatReasoningContent 测试 Claude thinking / Gemini thought 转换为 Chat reasoning_content
+func TestChatReasoningContent(t *testing.T) {
+	var claudeResp map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"msg_1","content":[
+		{"type":"thinking","thinking":"plan","signature":"sig"},
+		{"type":"text","text":"answer"}
+	],"stop_reason":"end_turn"}`), &claudeResp))
+	resp, err := ClaudeResponseToOpenAIChat(claudeResp, "m")
+	require.NoError(t, err)
+	message := resp["choices"].([]map[string]interface{})[0]["message"].(map[string]interface{})
+	assert.Equal(t, "plan", message["reasoning_content"])
+	assert.Equal(t, "answer", message["content"])
+
+	st := NewChatStreamState("m", false)
+	chunks := st.ConvertClaudeEvent([]byte(`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"step"}}`))
+	require.Len(t, chunks, 2)
+	assert.Contains(t, chunks[1], `"reasoning_content":"step"`)
+
+	chunks = st.ConvertGeminiChunk([]byte(`{"candidates":[{"content":{"parts":[{"text":"idea","thought":true}]}}]}`))
+	require.Len(t, chunks, 1)
+	assert.Contains(t, chunks[0], `"reasoning_content":"idea"`)
+}
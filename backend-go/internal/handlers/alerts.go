@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"github.com/BenedictKing/claude-proxy/internal/alert"
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAlertConfig 获取告警通知配置（Webhook URL 与签名密钥已掩码）
+func GetAlertConfig(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(200, cfgManager.GetAlertConfig().Redacted())
+	}
+}
+
+// SetAlertConfig 整体替换告警通知配置
+// 目标的 URL/密钥回传掩码值时保留已保存的原值
+func SetAlertConfig(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req config.AlertConfig
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		if err := cfgManager.SetAlertConfig(&req); err != nil {
+			if _, ok := err.(*config.ConfigError); ok {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(500, gin.H{"error": "Failed to save config"})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"success": true,
+			"alerts":  cfgManager.GetAlertConfig().Redacted(),
+		})
+	}
+}
+
+// TestAlertTarget 向告警目标发送一条测试消息
+// 请求体为 {"name": "..."} 时测试已保存的目标，否则按请求体中的完整目标配置测试（便于保存前验证）
+func TestAlertTarget(cfgManager *config.ConfigManager, alertManager *alert.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req config.AlertTarget
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		target := req
+		if req.URL == "" {
+			found := false
+			for _, t := range cfgManager.GetAlertConfig().Targets {
+				if t.Name == req.Name {
+					target, found = t, true
+					break
+				}
+			}
+			if !found {
+				c.JSON(404, gin.H{"error": "Alert target not found"})
+				return
+			}
+		} else {
+			resolved, err := cfgManager.ResolveAlertTarget(req)
+			if err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			target = resolved
+		}
+		if err := (&config.AlertConfig{Targets: []config.AlertTarget{target}}).Validate(); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := alertManager.SendTest(c.Request.Context(), target); err != nil {
+			c.JSON(502, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"success": true})
+	}
+}
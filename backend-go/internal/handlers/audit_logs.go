@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/BenedictKing/claude-proxy/internal/audit"
+	"github.com/gin-gonic/gin"
+)
+
+// ListAuditLogs 查询管理操作审计日志
+// GET /api/audit?actor=&keyId=&kind=&channel=&action=&since=&until=&limit=&offset=
+// channel 匹配渠道标识或名称，since/until 支持 RFC3339 或 Unix 毫秒时间戳
+func ListAuditLogs(recorder *audit.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := recorder.Store()
+		if store == nil {
+			c.JSON(200, gin.H{
+				"enabled": false,
+				"items":   []audit.Entry{},
+				"total":   0,
+			})
+			return
+		}
+
+		filter := audit.Filter{
+			Actor:   c.Query("actor"),
+			KeyID:   c.Query("keyId"),
+			Kind:    c.Query("kind"),
+			Channel: c.Query("channel"),
+			Action:  c.Query("action"),
+		}
+		var err error
+		if filter.Since, err = parseTimeQuery(c.Query("since")); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if filter.Until, err = parseTimeQuery(c.Query("until")); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+		filter.Offset, _ = strconv.Atoi(c.Query("offset"))
+
+		items, total, err := store.List(filter)
+		if err != nil {
+			c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to query audit logs: %v", err)})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"enabled": true,
+			"items":   items,
+			"total":   total,
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"github.com/BenedictKing/claude-proxy/internal/balance"
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshBalances 立即查询所有启用了余额查询的渠道（忽略查询周期），返回各 Key 的余额
+// POST /api/balance/refresh
+func RefreshBalances(checker *balance.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		results := checker.RunOnce(c.Request.Context(), true)
+		if results == nil {
+			results = []balance.Result{}
+		}
+		c.JSON(200, gin.H{
+			"success": true,
+			"results": results,
+		})
+	}
+}
@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
 	"github.com/BenedictKing/claude-proxy/internal/metrics"
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/gin-gonic/gin"
@@ -25,7 +25,7 @@ func GetChannelMetricsWithConfig(metricsManager *metrics.MetricsManager, cfgMana
 		result := make([]gin.H, 0, len(upstreams))
 		for i, upstream := range upstreams {
 			// 使用多 URL 聚合方法获取渠道指标（支持 failover 多端点场景）
-			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0, upstream.HistoricalAPIKeys)
+			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0, upstream.InactiveAPIKeys())
 
 			item := gin.H{
 				"channelIndex":        i,
@@ -154,22 +154,25 @@ func GetResponsesChannelMetrics(metricsManager *metrics.MetricsManager) gin.Hand
 	return GetChannelMetrics(metricsManager)
 }
 
+// channelKindOf 按 isResponses 参数返回 Messages 或 Responses 渠道类型
+func channelKindOf(isResponses bool) scheduler.ChannelKind {
+	if isResponses {
+		return scheduler.ChannelKindResponses
+	}
+	return scheduler.ChannelKindMessages
+}
+
 // ResumeChannel 恢复熔断渠道（重置熔断状态，保留历史统计）
 // isResponses 参数指定是 Messages 渠道还是 Responses 渠道
-func ResumeChannel(sch *scheduler.ChannelScheduler, isResponses bool) gin.HandlerFunc {
+func ResumeChannel(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler, isResponses bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid channel ID"})
+		kind := channelKindOf(isResponses)
+		id, ok := common.ResolveChannelParam(c, cfgManager, kind)
+		if !ok {
 			return
 		}
 
 		// 重置渠道所有 Key 的熔断状态（保留历史统计）
-		kind := scheduler.ChannelKindMessages
-		if isResponses {
-			kind = scheduler.ChannelKindResponses
-		}
 		sch.ResetChannelMetrics(id, kind)
 
 		c.JSON(200, gin.H{
@@ -215,10 +218,8 @@ func GetSchedulerStats(sch *scheduler.ChannelScheduler) gin.HandlerFunc {
 // 促销期内的渠道会被优先选择，忽略 trace 亲和性
 func SetChannelPromotion(cfgManager ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "无效的渠道 ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindMessages)
+		if !ok {
 			return
 		}
 
@@ -255,10 +256,8 @@ func SetChannelPromotion(cfgManager ConfigManager) gin.HandlerFunc {
 // SetResponsesChannelPromotion 设置 Responses 渠道促销期
 func SetResponsesChannelPromotion(cfgManager ResponsesConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "无效的渠道 ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindResponses)
+		if !ok {
 			return
 		}
 
@@ -293,12 +292,14 @@ func SetResponsesChannelPromotion(cfgManager ResponsesConfigManager) gin.Handler
 
 // ConfigManager 促销期配置管理接口
 type ConfigManager interface {
-	SetChannelPromotion(index int, duration time.Duration) error
+	common.ChannelResolver
+	SetChannelPromotion(channelID string, duration time.Duration) error
 }
 
 // ResponsesConfigManager Responses 渠道促销期配置管理接口
 type ResponsesConfigManager interface {
-	SetResponsesChannelPromotion(index int, duration time.Duration) error
+	common.ChannelResolver
+	SetResponsesChannelPromotion(channelID string, duration time.Duration) error
 }
 
 // MetricsHistoryResponse 历史指标响应
@@ -306,6 +307,7 @@ type MetricsHistoryResponse struct {
 	ChannelIndex int                        `json:"channelIndex"`
 	ChannelName  string                     `json:"channelName"`
 	DataPoints   []metrics.HistoryDataPoint `json:"dataPoints"`
+	Models       []metrics.ModelStats       `json:"models"` // 查询范围内按模型拆分的统计
 }
 
 // GetChannelMetricsHistory 获取渠道指标历史数据（用于时间序列图表）
@@ -373,6 +375,7 @@ func GetChannelMetricsHistory(metricsManager *metrics.MetricsManager, cfgManager
 				ChannelIndex: i,
 				ChannelName:  upstream.Name,
 				DataPoints:   dataPoints,
+				Models:       metricsManager.GetModelStatsMultiURL(upstream.GetAllBaseURLs(), upstream.APIKeys, duration),
 			})
 		}
 
@@ -463,10 +466,8 @@ func GetChannelKeyMetricsHistory(metricsManager *metrics.MetricsManager, cfgMana
 		}
 
 		// 解析 channel ID
-		channelIDStr := c.Param("id")
-		channelID, err := strconv.Atoi(channelIDStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid channel ID"})
+		channelID, ok := common.ResolveChannelParam(c, cfgManager, channelKindOf(isResponses))
+		if !ok {
 			return
 		}
 
@@ -479,12 +480,13 @@ func GetChannelKeyMetricsHistory(metricsManager *metrics.MetricsManager, cfgMana
 		}
 
 		// 检查 channel ID 是否有效
-		if channelID < 0 || channelID >= len(upstreams) {
+		channelIndex := cfg.ChannelIndexByID(string(channelKindOf(isResponses)), channelID)
+		if channelIndex < 0 {
 			c.JSON(400, gin.H{"error": "Channel not found"})
 			return
 		}
 
-		upstream := upstreams[channelID]
+		upstream := upstreams[channelIndex]
 
 		// 获取所有 Key 的使用信息并筛选（最多显示 10 个）
 		const maxDisplayKeys = 10
@@ -494,7 +496,7 @@ func GetChannelKeyMetricsHistory(metricsManager *metrics.MetricsManager, cfgMana
 
 		// 构建响应
 		result := ChannelKeyMetricsHistoryResponse{
-			ChannelIndex: channelID,
+			ChannelIndex: channelIndex,
 			ChannelName:  upstream.Name,
 			Keys:         make([]KeyMetricsHistoryResult, 0, len(displayKeys)),
 		}
@@ -563,12 +565,14 @@ func GetChannelDashboard(cfgManager *config.ConfigManager, sch *scheduler.Channe
 			priority := config.GetChannelPriority(&up, i)
 
 			channels[i] = gin.H{
+				"id":                 up.ID,
 				"index":              i,
 				"name":               up.Name,
 				"serviceType":        up.ServiceType,
 				"baseUrl":            up.BaseURL,
 				"baseUrls":           up.BaseURLs,
-				"apiKeys":            up.APIKeys,
+				"apiKeys":            cfgManager.RedactAPIKeys(up.APIKeys),
+				"disabledApiKeys":    cfgManager.RedactDisabledAPIKeys(up.DisabledAPIKeys),
 				"description":        up.Description,
 				"website":            up.Website,
 				"insecureSkipVerify": up.InsecureSkipVerify,
@@ -584,7 +588,7 @@ func GetChannelDashboard(cfgManager *config.ConfigManager, sch *scheduler.Channe
 		// 2. 构建 metrics 数据
 		metricsResult := make([]gin.H, 0, len(upstreams))
 		for i, upstream := range upstreams {
-			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0, upstream.HistoricalAPIKeys)
+			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0, upstream.InactiveAPIKeys())
 
 			item := gin.H{
 				"channelIndex":        i,
@@ -697,6 +701,7 @@ func GetGeminiChannelMetricsHistory(metricsManager *metrics.MetricsManager, cfgM
 				ChannelIndex: i,
 				ChannelName:  upstream.Name,
 				DataPoints:   dataPoints,
+				Models:       metricsManager.GetModelStatsMultiURL(upstream.GetAllBaseURLs(), upstream.APIKeys, duration),
 			})
 		}
 
@@ -760,10 +765,8 @@ func GetGeminiChannelKeyMetricsHistory(metricsManager *metrics.MetricsManager, c
 		}
 
 		// 解析 channel ID
-		channelIDStr := c.Param("id")
-		channelID, err := strconv.Atoi(channelIDStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid channel ID"})
+		channelID, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindGemini)
+		if !ok {
 			return
 		}
 
@@ -771,12 +774,13 @@ func GetGeminiChannelKeyMetricsHistory(metricsManager *metrics.MetricsManager, c
 		upstreams := cfg.GeminiUpstream
 
 		// 检查 channel ID 是否有效
-		if channelID < 0 || channelID >= len(upstreams) {
+		channelIndex := cfg.ChannelIndexByID(string(scheduler.ChannelKindGemini), channelID)
+		if channelIndex < 0 {
 			c.JSON(400, gin.H{"error": "Channel not found"})
 			return
 		}
 
-		upstream := upstreams[channelID]
+		upstream := upstreams[channelIndex]
 
 		// 获取所有 Key 的使用信息并筛选（最多显示 10 个）
 		const maxDisplayKeys = 10
@@ -786,7 +790,7 @@ func GetGeminiChannelKeyMetricsHistory(metricsManager *metrics.MetricsManager, c
 
 		// 构建响应
 		result := ChannelKeyMetricsHistoryResponse{
-			ChannelIndex: channelID,
+			ChannelIndex: channelIndex,
 			ChannelName:  upstream.Name,
 			Keys:         make([]KeyMetricsHistoryResult, 0, len(displayKeys)),
 		}
@@ -822,7 +826,221 @@ func GetGeminiChannelMetrics(metricsManager *metrics.MetricsManager, cfgManager
 		result := make([]gin.H, 0, len(upstreams))
 		for i, upstream := range upstreams {
 			// 使用多 URL 聚合方法获取渠道指标（支持 failover 多端点场景）
-			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0, upstream.HistoricalAPIKeys)
+			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0, upstream.InactiveAPIKeys())
+
+			item := gin.H{
+				"channelIndex":        i,
+				"channelName":         upstream.Name,
+				"requestCount":        resp.RequestCount,
+				"successCount":        resp.SuccessCount,
+				"failureCount":        resp.FailureCount,
+				"successRate":         resp.SuccessRate,
+				"errorRate":           resp.ErrorRate,
+				"consecutiveFailures": resp.ConsecutiveFailures,
+				"latency":             resp.Latency,
+				"keyMetrics":          resp.KeyMetrics,  // 各 Key 的详细指标
+				"timeWindows":         resp.TimeWindows, // 分时段统计 (15m, 1h, 6h, 24h)
+			}
+
+			if resp.LastSuccessAt != nil {
+				item["lastSuccessAt"] = *resp.LastSuccessAt
+			}
+			if resp.LastFailureAt != nil {
+				item["lastFailureAt"] = *resp.LastFailureAt
+			}
+			if resp.CircuitBrokenAt != nil {
+				item["circuitBrokenAt"] = *resp.CircuitBrokenAt
+			}
+
+			result = append(result, item)
+		}
+
+		c.JSON(200, result)
+	}
+}
+
+// GetChatChannelMetricsHistory 获取 Chat 渠道指标历史数据（用于时间序列图表）
+// Query params:
+//   - duration: 时间范围 (1h, 6h, 24h)，默认 24h
+//   - interval: 时间间隔 (5m, 15m, 1h)，默认根据 duration 自动选择
+func GetChatChannelMetricsHistory(metricsManager *metrics.MetricsManager, cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 解析 duration 参数
+		durationStr := c.DefaultQuery("duration", "24h")
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid duration parameter"})
+			return
+		}
+
+		// 限制最大查询范围为 24 小时
+		if duration > 24*time.Hour {
+			duration = 24 * time.Hour
+		}
+
+		// 解析或自动选择 interval
+		intervalStr := c.Query("interval")
+		var interval time.Duration
+		if intervalStr != "" {
+			interval, err = time.ParseDuration(intervalStr)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid interval parameter"})
+				return
+			}
+			// 限制 interval 最小值为 1 分钟，防止生成过多 bucket
+			if interval < time.Minute {
+				interval = time.Minute
+			}
+		} else {
+			// 根据 duration 自动选择合适的聚合粒度
+			switch {
+			case duration <= time.Hour:
+				interval = time.Minute
+			case duration <= 6*time.Hour:
+				interval = 5 * time.Minute
+			default:
+				interval = 15 * time.Minute
+			}
+		}
+
+		cfg := cfgManager.GetConfig()
+		upstreams := cfg.ChatUpstream
+
+		result := make([]MetricsHistoryResponse, 0, len(upstreams))
+		for i, upstream := range upstreams {
+			// 使用多 URL 聚合方法获取历史数据（支持 failover 多端点场景）
+			dataPoints := metricsManager.GetHistoricalStatsMultiURL(upstream.GetAllBaseURLs(), upstream.APIKeys, duration, interval)
+
+			result = append(result, MetricsHistoryResponse{
+				ChannelIndex: i,
+				ChannelName:  upstream.Name,
+				DataPoints:   dataPoints,
+				Models:       metricsManager.GetModelStatsMultiURL(upstream.GetAllBaseURLs(), upstream.APIKeys, duration),
+			})
+		}
+
+		c.JSON(200, result)
+	}
+}
+
+// GetChatChannelKeyMetricsHistory 获取 Chat 渠道下各 Key 的历史数据（用于 Key 趋势图表）
+// GET /api/chat/channels/:id/keys/metrics/history?duration=6h
+func GetChatChannelKeyMetricsHistory(metricsManager *metrics.MetricsManager, cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 解析 duration 参数
+		durationStr := c.DefaultQuery("duration", "6h")
+
+		var duration time.Duration
+		var err error
+
+		// 特殊处理 "today" 参数
+		if durationStr == "today" {
+			duration = metrics.CalculateTodayDuration()
+			// 如果刚过零点，duration 可能非常小，设置最小值
+			if duration < time.Minute {
+				duration = time.Minute
+			}
+		} else {
+			duration, err = time.ParseDuration(durationStr)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid duration parameter. Use: 1h, 6h, 24h, or today"})
+				return
+			}
+		}
+
+		// 限制最大查询范围为 24 小时
+		if duration > 24*time.Hour {
+			duration = 24 * time.Hour
+		}
+
+		// 解析或自动选择 interval
+		intervalStr := c.Query("interval")
+		var interval time.Duration
+		if intervalStr != "" {
+			interval, err = time.ParseDuration(intervalStr)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid interval parameter"})
+				return
+			}
+			// 限制 interval 最小值为 1 分钟，防止生成过多 bucket
+			if interval < time.Minute {
+				interval = time.Minute
+			}
+		} else {
+			// 根据 duration 自动选择合适的聚合粒度
+			switch {
+			case duration <= time.Hour:
+				interval = time.Minute
+			case duration <= 6*time.Hour:
+				interval = 5 * time.Minute
+			default:
+				interval = 15 * time.Minute
+			}
+		}
+
+		// 解析 channel ID
+		channelID, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindChat)
+		if !ok {
+			return
+		}
+
+		cfg := cfgManager.GetConfig()
+		upstreams := cfg.ChatUpstream
+
+		// 检查 channel ID 是否有效
+		channelIndex := cfg.ChannelIndexByID(string(scheduler.ChannelKindChat), channelID)
+		if channelIndex < 0 {
+			c.JSON(400, gin.H{"error": "Channel not found"})
+			return
+		}
+
+		upstream := upstreams[channelIndex]
+
+		// 获取所有 Key 的使用信息并筛选（最多显示 10 个）
+		const maxDisplayKeys = 10
+		// 使用多 URL 聚合方法获取 Key 使用信息（支持 failover 多端点场景）
+		allKeyInfos := metricsManager.GetChannelKeyUsageInfoMultiURL(upstream.GetAllBaseURLs(), upstream.APIKeys)
+		displayKeys := metrics.SelectTopKeys(allKeyInfos, maxDisplayKeys)
+
+		// 构建响应
+		result := ChannelKeyMetricsHistoryResponse{
+			ChannelIndex: channelIndex,
+			ChannelName:  upstream.Name,
+			Keys:         make([]KeyMetricsHistoryResult, 0, len(displayKeys)),
+		}
+
+		// 为筛选后的 Key 获取历史数据
+		for i, keyInfo := range displayKeys {
+			// 使用多 URL 聚合方法获取单个 Key 的历史数据（支持 failover 多端点场景）
+			dataPoints := metricsManager.GetKeyHistoricalStatsMultiURL(upstream.GetAllBaseURLs(), keyInfo.APIKey, duration, interval)
+
+			// 获取 Key 的颜色
+			color := keyColors[i%len(keyColors)]
+
+			// 获取 Key 的脱敏显示（只取前 8 个字符）
+			keyMask := truncateKeyMask(keyInfo.KeyMask, 8)
+
+			result.Keys = append(result.Keys, KeyMetricsHistoryResult{
+				KeyMask:    keyMask,
+				Color:      color,
+				DataPoints: dataPoints,
+			})
+		}
+
+		c.JSON(200, result)
+	}
+}
+
+// GetChatChannelMetrics 获取 Chat 渠道指标
+func GetChatChannelMetrics(metricsManager *metrics.MetricsManager, cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := cfgManager.GetConfig()
+		upstreams := cfg.ChatUpstream
+
+		result := make([]gin.H, 0, len(upstreams))
+		for i, upstream := range upstreams {
+			// 使用多 URL 聚合方法获取渠道指标（支持 failover 多端点场景）
+			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0, upstream.InactiveAPIKeys())
 
 			item := gin.H{
 				"channelIndex":        i,
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/messages"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshChannelModels 从上游 /models 拉取渠道模型列表，保存为自动发现的支持模型
+// POST /api/{messages|responses|gemini|chat}/channels/:id/models/refresh
+func RefreshChannelModels(cfgManager *config.ConfigManager, kind scheduler.ChannelKind) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := common.ResolveChannelParam(c, cfgManager, kind)
+		if !ok {
+			return
+		}
+
+		cfg := cfgManager.GetConfig()
+		index := cfg.ChannelIndexByID(string(kind), id)
+		if index < 0 {
+			c.JSON(404, gin.H{"error": "渠道不存在"})
+			return
+		}
+		upstream := cfg.UpstreamsForKind(string(kind))[index].Clone()
+		if len(upstream.APIKeys) == 0 {
+			c.JSON(400, gin.H{"error": "渠道未配置 API 密钥"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+		models, err := messages.FetchChannelModels(ctx, upstream, upstream.APIKeys[0])
+		if err != nil {
+			c.JSON(502, gin.H{"error": "拉取模型列表失败: " + err.Error()})
+			return
+		}
+
+		if err := cfgManager.SetDiscoveredModels(string(kind), id, models); err != nil {
+			if strings.Contains(err.Error(), "未找到渠道") {
+				c.JSON(404, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"success":            true,
+			"models":             models,
+			"autoDiscoverModels": upstream.AutoDiscoverModels,
+		})
+	}
+}
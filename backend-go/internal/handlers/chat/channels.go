@@ -0,0 +1,357 @@
+// Package chat 提供 Chat Completions API 的渠道管理
+package chat
+
+import (
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUpstreams 获取 Chat 上游列表
+func GetUpstreams(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := cfgManager.GetConfig()
+
+		upstreams := make([]gin.H, len(cfg.ChatUpstream))
+		for i, up := range cfg.ChatUpstream {
+			status := config.GetChannelStatus(&up)
+			priority := config.GetChannelPriority(&up, i)
+
+			upstreams[i] = gin.H{
+				"id":                 up.ID,
+				"index":              i,
+				"name":               up.Name,
+				"serviceType":        up.ServiceType,
+				"baseUrl":            up.BaseURL,
+				"baseUrls":           up.BaseURLs,
+				"apiKeys":            cfgManager.RedactAPIKeys(up.APIKeys),
+				"disabledApiKeys":    cfgManager.RedactDisabledAPIKeys(up.DisabledAPIKeys),
+				"description":        up.Description,
+				"website":            up.Website,
+				"insecureSkipVerify": up.InsecureSkipVerify,
+				"modelMapping":       up.ModelMapping,
+				"latency":            nil,
+				"status":             status,
+				"priority":           priority,
+				"promotionUntil":     up.PromotionUntil,
+				"lowQuality":         up.LowQuality,
+			}
+		}
+
+		c.JSON(200, gin.H{
+			"channels":    upstreams,
+			"loadBalance": cfg.ChatLoadBalance,
+		})
+	}
+}
+
+// AddUpstream 添加 Chat 上游
+func AddUpstream(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var upstream config.UpstreamConfig
+		if err := c.ShouldBindJSON(&upstream); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := cfgManager.AddChatUpstream(upstream); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, gin.H{"message": "Chat upstream added successfully"})
+	}
+}
+
+// UpdateUpstream 更新 Chat 上游
+func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindChat)
+		if !ok {
+			return
+		}
+
+		var updates config.UpstreamUpdate
+		if err := c.ShouldBindJSON(&updates); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		// 管理界面回传的已掩码 Key 还原为实际 Key
+		apiKeys, err := cfgManager.ResolveAPIKeyRefs(string(scheduler.ChannelKindChat), id, updates.APIKeys)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		updates.APIKeys = apiKeys
+
+		shouldResetMetrics, err := cfgManager.UpdateChatUpstream(id, updates)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 单 key 更换时重置熔断状态
+		if shouldResetMetrics {
+			sch.ResetChannelMetrics(id, scheduler.ChannelKindChat)
+		}
+
+		c.JSON(200, gin.H{"message": "Chat upstream updated successfully"})
+	}
+}
+
+// DeleteUpstream 删除 Chat 上游
+func DeleteUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindChat)
+		if !ok {
+			return
+		}
+
+		removed, err := cfgManager.RemoveChatUpstream(id)
+		if err != nil {
+			if strings.Contains(err.Error(), "无效的") {
+				c.JSON(404, gin.H{"error": "Upstream not found"})
+			} else {
+				c.JSON(500, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		// 删除成功后清理指标数据（使用 RemoveChatUpstream 返回的渠道信息）
+		sch.DeleteChannelMetrics(removed, scheduler.ChannelKindChat)
+
+		c.JSON(200, gin.H{"message": "Chat upstream deleted successfully"})
+	}
+}
+
+// AddApiKey 添加 Chat 渠道 API 密钥
+func AddApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindChat)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			APIKey string `json:"apiKey"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		if err := cfgManager.AddChatAPIKey(id, req.APIKey); err != nil {
+			if strings.Contains(err.Error(), "未找到渠道") {
+				c.JSON(404, gin.H{"error": "Upstream not found"})
+			} else if strings.Contains(err.Error(), "API密钥已存在") {
+				c.JSON(400, gin.H{"error": "API密钥已存在"})
+			} else {
+				c.JSON(500, gin.H{"error": "Failed to save config"})
+			}
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "API密钥已添加",
+			"success": true,
+		})
+	}
+}
+
+// DeleteApiKey 删除 Chat 渠道 API 密钥
+func DeleteApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindChat)
+		if !ok {
+			return
+		}
+
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindChat), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if apiKey == "" {
+			c.JSON(400, gin.H{"error": "API key is required"})
+			return
+		}
+
+		if err := cfgManager.RemoveChatAPIKey(id, apiKey); err != nil {
+			if strings.Contains(err.Error(), "未找到渠道") {
+				c.JSON(404, gin.H{"error": "Upstream not found"})
+			} else if strings.Contains(err.Error(), "API密钥不存在") {
+				c.JSON(404, gin.H{"error": "API key not found"})
+			} else {
+				c.JSON(500, gin.H{"error": "Failed to save config"})
+			}
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"message": "API密钥已删除",
+		})
+	}
+}
+
+// MoveApiKeyToTop 将 Chat 渠道 API 密钥移到最前面
+func MoveApiKeyToTop(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindChat)
+		if !ok {
+			return
+		}
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindChat), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := cfgManager.MoveChatAPIKeyToTop(id, apiKey); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "API密钥已置顶"})
+	}
+}
+
+// MoveApiKeyToBottom 将 Chat 渠道 API 密钥移到最后面
+func MoveApiKeyToBottom(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindChat)
+		if !ok {
+			return
+		}
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindChat), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := cfgManager.MoveChatAPIKeyToBottom(id, apiKey); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "API密钥已置底"})
+	}
+}
+
+// ReorderChannels 重新排序 Chat 渠道优先级
+func ReorderChannels(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Order []int `json:"order"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		if err := cfgManager.ReorderChatUpstreams(req.Order); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"success": true,
+			"message": "Chat 渠道优先级已更新",
+		})
+	}
+}
+
+// SetChannelStatus 设置 Chat 渠道状态
+func SetChannelStatus(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindChat)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Status string `json:"status"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		if err := cfgManager.SetChatChannelStatus(id, req.Status); err != nil {
+			if strings.Contains(err.Error(), "未找到渠道") {
+				c.JSON(404, gin.H{"error": "Channel not found"})
+			} else {
+				c.JSON(400, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"success": true,
+			"message": "Chat 渠道状态已更新",
+			"status":  req.Status,
+		})
+	}
+}
+
+// SetChannelPromotion 设置 Chat 渠道促销期
+func SetChannelPromotion(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindChat)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Duration int `json:"duration"` // 促销期时长（秒），0 表示清除
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		duration := time.Duration(req.Duration) * time.Second
+		if err := cfgManager.SetChatChannelPromotion(id, duration); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		if req.Duration <= 0 {
+			c.JSON(200, gin.H{
+				"success": true,
+				"message": "Chat 渠道促销期已清除",
+			})
+		} else {
+			c.JSON(200, gin.H{
+				"success":  true,
+				"message":  "Chat 渠道促销期已设置",
+				"duration": req.Duration,
+			})
+		}
+	}
+}
+
+// UpdateLoadBalance 更新 Chat 负载均衡策略
+func UpdateLoadBalance(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Strategy string `json:"strategy"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		if err := cfgManager.SetChatLoadBalance(req.Strategy); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"success":  true,
+			"message":  "Chat 负载均衡策略已更新",
+			"strategy": req.Strategy,
+		})
+	}
+}
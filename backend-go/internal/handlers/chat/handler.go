@@ -0,0 +1,476 @@
+// Package chat 提供 OpenAI Chat Completions 兼容入口（/v1/chat/completions）的处理器
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/converters"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/quota"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// versionPattern 匹配 baseURL 末尾的版本号（/v1, /v2, /v1beta 等）
+var versionPattern = regexp.MustCompile(`/v\d+[a-z]*$`)
+
+// chatRequest 入站 Chat Completions 请求（保留原始 map 以便按上游类型转换）
+type chatRequest struct {
+	raw          map[string]interface{}
+	model        string
+	stream       bool
+	includeUsage bool
+}
+
+// Handler Chat Completions API 代理处理器
+// 支持多渠道调度：当配置多个渠道时自动启用
+func Handler(
+	envCfg *config.EnvConfig,
+	cfgManager *config.ConfigManager,
+	channelScheduler *scheduler.ChannelScheduler,
+	quotaManager *quota.Manager,
+) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		// 先进行认证
+		middleware.ProxyAuthMiddleware(envCfg, cfgManager)(c)
+		if c.IsAborted() {
+			return
+		}
+
+		startTime := time.Now()
+
+		// 读取原始请求体
+		maxBodySize := envCfg.MaxRequestBodySize
+		bodyBytes, err := common.ReadRequestBody(c, maxBodySize)
+		if err != nil {
+			return
+		}
+
+		// 解析 Chat Completions 请求
+		var raw map[string]interface{}
+		if err := json.Unmarshal(bodyBytes, &raw); err != nil {
+			writeChatError(c, 400, "invalid_request_error", fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		chatReq := &chatRequest{raw: raw}
+		chatReq.model, _ = raw["model"].(string)
+		chatReq.stream, _ = raw["stream"].(bool)
+		chatReq.includeUsage = gjson.GetBytes(bodyBytes, "stream_options.include_usage").Bool()
+		if chatReq.model == "" {
+			writeChatError(c, 400, "invalid_request_error", "model is required")
+			return
+		}
+
+		// 客户端访问范围校验（入口类型 + 模型）
+		if !middleware.CheckClientScope(c, string(scheduler.ChannelKindChat), chatReq.model) {
+			return
+		}
+
+		// 客户端配额校验（RPM + Token 预算），通过后绑定结算
+		if !middleware.CheckClientQuota(c, quotaManager, string(scheduler.ChannelKindChat)) {
+			return
+		}
+		common.SetRequestModel(c, chatReq.model)
+
+		// 提取对话标识用于 Trace 亲和性
+		userID := common.ExtractConversationID(c, bodyBytes)
+
+		// 记录原始请求信息（仅在入口处记录一次）
+		common.LogOriginalRequest(c, bodyBytes, envCfg, "Chat")
+
+		// 检查是否为多渠道模式
+		isMultiChannel := channelScheduler.IsMultiChannelMode(scheduler.ChannelKindChat)
+
+		if isMultiChannel {
+			handleMultiChannel(c, envCfg, cfgManager, channelScheduler, bodyBytes, chatReq, userID, startTime)
+		} else {
+			handleSingleChannel(c, envCfg, cfgManager, channelScheduler, bodyBytes, chatReq, startTime)
+		}
+	})
+}
+
+// handleMultiChannel 处理多渠道 Chat 请求
+func handleMultiChannel(
+	c *gin.Context,
+	envCfg *config.EnvConfig,
+	cfgManager *config.ConfigManager,
+	channelScheduler *scheduler.ChannelScheduler,
+	bodyBytes []byte,
+	chatReq *chatRequest,
+	userID string,
+	startTime time.Time,
+) {
+	metricsManager := channelScheduler.GetChatMetricsManager()
+	common.HandleMultiChannelFailover(
+		c,
+		envCfg,
+		channelScheduler,
+		scheduler.ChannelKindChat,
+		"Chat",
+		userID,
+		func(selection *scheduler.SelectionResult) common.MultiChannelAttemptResult {
+			upstream := selection.Upstream
+
+			if upstream == nil {
+				return common.MultiChannelAttemptResult{}
+			}
+
+			baseURLs := upstream.GetAllBaseURLs()
+			sortedURLResults := channelScheduler.GetSortedURLsForChannel(scheduler.ChannelKindChat, upstream.ID, baseURLs)
+
+			handled, successKey, successBaseURLIdx, failoverErr, usage, lastErr := common.TryUpstreamWithAllKeys(
+				c,
+				envCfg,
+				cfgManager,
+				channelScheduler,
+				scheduler.ChannelKindChat,
+				"Chat",
+				metricsManager,
+				upstream,
+				sortedURLResults,
+				bodyBytes,
+				chatReq.stream,
+				func(upstream *config.UpstreamConfig, failedKeys map[string]bool) (string, error) {
+					return channelScheduler.NextAPIKey(upstream, failedKeys, scheduler.ChannelKindChat)
+				},
+				func(c *gin.Context, upstreamCopy *config.UpstreamConfig, apiKey string) (*http.Request, error) {
+					return buildProviderRequest(c, upstreamCopy, apiKey, bodyBytes, chatReq)
+				},
+				func(apiKey string) {
+					_ = cfgManager.DeprioritizeAPIKey(string(scheduler.ChannelKindChat), upstream.ID, apiKey)
+				},
+				func(url string) {
+					channelScheduler.MarkURLFailure(scheduler.ChannelKindChat, upstream.ID, url)
+				},
+				func(url string) {
+					channelScheduler.MarkURLSuccess(scheduler.ChannelKindChat, upstream.ID, url)
+				},
+				func(c *gin.Context, resp *http.Response, upstreamCopy *config.UpstreamConfig, apiKey string) (*types.Usage, error) {
+					return handleSuccess(c, resp, upstreamCopy, envCfg, startTime, chatReq)
+				},
+			)
+
+			return common.MultiChannelAttemptResult{
+				Handled:           handled,
+				Attempted:         true,
+				SuccessKey:        successKey,
+				SuccessBaseURLIdx: successBaseURLIdx,
+				FailoverError:     failoverErr,
+				Usage:             usage,
+				LastError:         lastErr,
+			}
+		},
+		nil,
+		func(ctx *gin.Context, failoverErr *common.FailoverError, lastError error) {
+			common.HandleAllChannelsFailed(ctx, cfgManager.GetFuzzyModeEnabled(), failoverErr, lastError, "Chat")
+		},
+	)
+}
+
+// handleSingleChannel 处理单渠道 Chat 请求
+func handleSingleChannel(
+	c *gin.Context,
+	envCfg *config.EnvConfig,
+	cfgManager *config.ConfigManager,
+	channelScheduler *scheduler.ChannelScheduler,
+	bodyBytes []byte,
+	chatReq *chatRequest,
+	startTime time.Time,
+) {
+	upstream, err := cfgManager.GetCurrentChatUpstream()
+	if err != nil {
+		c.JSON(503, gin.H{
+			"error": "未配置任何 Chat 渠道，请先在管理界面添加渠道",
+			"code":  "NO_CHAT_UPSTREAM",
+		})
+		return
+	}
+
+	if len(upstream.APIKeys) == 0 {
+		c.JSON(503, gin.H{
+			"error": fmt.Sprintf("当前 Chat 渠道 \"%s\" 未配置API密钥", upstream.Name),
+			"code":  "NO_API_KEYS",
+		})
+		return
+	}
+
+	metricsManager := channelScheduler.GetChatMetricsManager()
+	baseURLs := upstream.GetAllBaseURLs()
+	urlResults := common.BuildDefaultURLResults(baseURLs)
+
+	handled, _, _, lastFailoverError, _, lastError := common.TryUpstreamWithAllKeys(
+		c,
+		envCfg,
+		cfgManager,
+		channelScheduler,
+		scheduler.ChannelKindChat,
+		"Chat",
+		metricsManager,
+		upstream,
+		urlResults,
+		bodyBytes,
+		chatReq.stream,
+		func(upstream *config.UpstreamConfig, failedKeys map[string]bool) (string, error) {
+			return channelScheduler.NextAPIKey(upstream, failedKeys, scheduler.ChannelKindChat)
+		},
+		func(c *gin.Context, upstreamCopy *config.UpstreamConfig, apiKey string) (*http.Request, error) {
+			return buildProviderRequest(c, upstreamCopy, apiKey, bodyBytes, chatReq)
+		},
+		func(apiKey string) {
+			if err := cfgManager.DeprioritizeAPIKey(string(scheduler.ChannelKindChat), upstream.ID, apiKey); err != nil {
+				log.Printf("[Chat-Key] 警告: 密钥降级失败: %v", err)
+			}
+		},
+		nil,
+		nil,
+		func(c *gin.Context, resp *http.Response, upstreamCopy *config.UpstreamConfig, apiKey string) (*types.Usage, error) {
+			return handleSuccess(c, resp, upstreamCopy, envCfg, startTime, chatReq)
+		},
+	)
+	if handled {
+		return
+	}
+
+	log.Printf("[Chat-Error] 所有 Chat API密钥都失败了")
+	common.HandleAllKeysFailed(c, cfgManager.GetFuzzyModeEnabled(), lastFailoverError, lastError, "Chat")
+}
+
+// buildUpstreamURL 按 baseURL 智能拼接端点
+// 规则与其他 provider 保持一致：以 # 结尾或已带版本号时不再追加版本前缀（OpenAI/Claude 为 /v1，Gemini 为 /v1beta）
+func buildUpstreamURL(baseURL, version, endpoint string) string {
+	skipVersionPrefix := strings.HasSuffix(baseURL, "#")
+	if skipVersionPrefix {
+		baseURL = strings.TrimSuffix(baseURL, "#")
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	if versionPattern.MatchString(baseURL) || skipVersionPrefix {
+		return baseURL + endpoint
+	}
+	return baseURL + version + endpoint
+}
+
+// buildProviderRequest 按上游类型构建请求
+func buildProviderRequest(
+	c *gin.Context,
+	upstream *config.UpstreamConfig,
+	apiKey string,
+	bodyBytes []byte,
+	chatReq *chatRequest,
+) (*http.Request, error) {
+	// 应用模型映射
+	mappedModel := config.RedirectModel(chatReq.model, upstream)
+
+	var requestBody []byte
+	var url string
+	var err error
+
+	switch upstream.ServiceType {
+	case "claude":
+		claudeReq, err := converters.OpenAIChatToClaudeRequest(chatReq.raw, mappedModel)
+		if err != nil {
+			return nil, err
+		}
+		claudeReq["stream"] = chatReq.stream
+		requestBody, err = json.Marshal(claudeReq)
+		if err != nil {
+			return nil, err
+		}
+		url = buildUpstreamURL(upstream.BaseURL, "/v1", "/messages")
+
+	case "gemini":
+		geminiReq, err := converters.OpenAIChatToGeminiRequest(chatReq.raw)
+		if err != nil {
+			return nil, err
+		}
+		requestBody, err = json.Marshal(geminiReq)
+		if err != nil {
+			return nil, err
+		}
+		action := "generateContent"
+		if chatReq.stream {
+			action = "streamGenerateContent"
+		}
+		url = buildUpstreamURL(upstream.BaseURL, "/v1beta", "/models/"+mappedModel+":"+action)
+		if chatReq.stream {
+			url += "?alt=sse"
+		}
+
+	case "responses":
+		responsesReq, err := converters.OpenAIChatToResponsesRequest(chatReq.raw, mappedModel)
+		if err != nil {
+			return nil, err
+		}
+		responsesReq["stream"] = chatReq.stream
+		requestBody, err = json.Marshal(responsesReq)
+		if err != nil {
+			return nil, err
+		}
+		url = buildUpstreamURL(upstream.BaseURL, "/v1", "/responses")
+
+	default:
+		// OpenAI 上游：透传，仅改写模型名；流式时强制 include_usage 以便统计 token
+		requestBody = bodyBytes
+		if mappedModel != chatReq.model {
+			if requestBody, err = sjson.SetBytes(requestBody, "model", mappedModel); err != nil {
+				return nil, err
+			}
+		}
+		if chatReq.stream && !chatReq.includeUsage {
+			if requestBody, err = sjson.SetBytes(requestBody, "stream_options.include_usage", true); err != nil {
+				return nil, err
+			}
+		}
+		url = buildUpstreamURL(upstream.BaseURL, "/v1", "/chat/completions")
+	}
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), "POST", url, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	// 使用统一的头部处理逻辑（透明代理）
+	req.Header = utils.PrepareUpstreamHeaders(c, req.URL.Host)
+	req.Header.Set("Content-Type", "application/json")
+
+	// 设置认证头
+	switch upstream.ServiceType {
+	case "gemini":
+		utils.SetGeminiAuthenticationHeader(req.Header, apiKey)
+	case "claude":
+		utils.SetAuthenticationHeader(req.Header, apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	default:
+		utils.SetAuthenticationHeader(req.Header, apiKey)
+	}
+
+	return req, nil
+}
+
+// handleSuccess 处理成功的响应
+func handleSuccess(
+	c *gin.Context,
+	resp *http.Response,
+	upstream *config.UpstreamConfig,
+	envCfg *config.EnvConfig,
+	startTime time.Time,
+	chatReq *chatRequest,
+) (*types.Usage, error) {
+	defer resp.Body.Close()
+
+	upstreamType := upstream.ServiceType
+	if chatReq.stream {
+		firstContentTimeout := upstream.GetStreamFirstContentTimeout(time.Duration(envCfg.StreamFirstContentTimeout) * time.Second)
+		return handleStreamSuccess(c, resp, upstreamType, envCfg, firstContentTimeout, startTime, chatReq)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		writeChatError(c, 500, "server_error", "Failed to read upstream response")
+		return nil, err
+	}
+	bodyBytes = utils.DecompressGzipIfNeeded(resp, bodyBytes)
+
+	if envCfg.EnableResponseLogs {
+		responseTime := time.Since(startTime).Milliseconds()
+		log.Printf("[Chat-Timing] 响应完成: %dms, 状态: %d", responseTime, resp.StatusCode)
+	}
+
+	var chatResp map[string]interface{}
+
+	switch upstreamType {
+	case "claude":
+		var claudeResp map[string]interface{}
+		if err := json.Unmarshal(bodyBytes, &claudeResp); err != nil {
+			c.Data(resp.StatusCode, "application/json", bodyBytes)
+			return nil, nil
+		}
+		chatResp, err = converters.ClaudeResponseToOpenAIChat(claudeResp, chatReq.model)
+		if err != nil {
+			c.Data(resp.StatusCode, "application/json", bodyBytes)
+			return nil, nil
+		}
+
+	case "gemini":
+		var geminiResp types.GeminiResponse
+		if err := json.Unmarshal(bodyBytes, &geminiResp); err != nil {
+			c.Data(resp.StatusCode, "application/json", bodyBytes)
+			return nil, nil
+		}
+		chatResp = converters.GeminiResponseToOpenAIChat(&geminiResp, chatReq.model)
+
+	case "responses":
+		var responsesResp map[string]interface{}
+		if err := json.Unmarshal(bodyBytes, &responsesResp); err != nil {
+			c.Data(resp.StatusCode, "application/json", bodyBytes)
+			return nil, nil
+		}
+		chatResp, err = converters.ResponsesResponseToOpenAIChat(responsesResp, chatReq.model)
+		if err != nil {
+			c.Data(resp.StatusCode, "application/json", bodyBytes)
+			return nil, nil
+		}
+
+	default:
+		// OpenAI 上游直接透传
+		utils.ForwardResponseHeaders(resp.Header, c.Writer)
+		c.Data(resp.StatusCode, "application/json", bodyBytes)
+		return extractChatUsage(gjson.GetBytes(bodyBytes, "usage")), nil
+	}
+
+	respBytes, err := json.Marshal(chatResp)
+	if err != nil {
+		c.Data(resp.StatusCode, "application/json", bodyBytes)
+		return nil, nil
+	}
+	c.Data(resp.StatusCode, "application/json", respBytes)
+
+	if usage, ok := chatResp["usage"]; ok {
+		usageBytes, _ := json.Marshal(usage)
+		return extractChatUsage(gjson.ParseBytes(usageBytes)), nil
+	}
+	return nil, nil
+}
+
+// extractChatUsage 将 Chat usage 转换为内部统一口径（InputTokens 不含缓存命中）
+func extractChatUsage(usage gjson.Result) *types.Usage {
+	if !usage.Exists() || usage.Type == gjson.Null {
+		return nil
+	}
+	prompt := int(usage.Get("prompt_tokens").Int())
+	cached := int(usage.Get("prompt_tokens_details.cached_tokens").Int())
+	input := prompt - cached
+	if input < 0 {
+		input = 0
+	}
+	return &types.Usage{
+		InputTokens:          input,
+		OutputTokens:         int(usage.Get("completion_tokens").Int()),
+		CacheReadInputTokens: cached,
+	}
+}
+
+// writeChatError 返回 OpenAI 格式的错误响应
+func writeChatError(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    errType,
+			"code":    nil,
+		},
+	})
+}
@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func TestHandler_RequiresProxyAccessKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	envCfg := &config.EnvConfig{
+		ProxyAccessKey:     "secret-key",
+		MaxRequestBodySize: 1024 * 1024,
+	}
+
+	r := gin.New()
+	r.POST("/v1/chat/completions", Handler(envCfg, nil, nil, nil))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader([]byte(`{"model":"gpt-4o"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestBuildUpstreamURL(t *testing.T) {
+	tests := []struct {
+		baseURL  string
+		version  string
+		endpoint string
+		want     string
+	}{
+		{"https://api.openai.com", "/v1", "/chat/completions", "https://api.openai.com/v1/chat/completions"},
+		{"https://api.openai.com/", "/v1", "/chat/completions", "https://api.openai.com/v1/chat/completions"},
+		{"https://example.com/api/v3", "/v1", "/chat/completions", "https://example.com/api/v3/chat/completions"},
+		{"https://example.com/custom#", "/v1", "/messages", "https://example.com/custom/messages"},
+		{"https://api.anthropic.com", "/v1", "/messages", "https://api.anthropic.com/v1/messages"},
+		{"https://generativelanguage.googleapis.com", "/v1beta", "/models/gemini-2.5-pro:generateContent", "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-pro:generateContent"},
+		{"https://example.com/gemini/v1", "/v1beta", "/models/gemini-2.5-pro:generateContent", "https://example.com/gemini/v1/models/gemini-2.5-pro:generateContent"},
+		{"https://example.com/gemini#", "/v1beta", "/models/gemini-2.5-pro:streamGenerateContent", "https://example.com/gemini/models/gemini-2.5-pro:streamGenerateContent"},
+	}
+
+	for _, tt := range tests {
+		if got := buildUpstreamURL(tt.baseURL, tt.version, tt.endpoint); got != tt.want {
+			t.Errorf("buildUpstreamURL(%q, %q, %q) = %q, want %q", tt.baseURL, tt.version, tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestExtractChatUsage(t *testing.T) {
+	usage := extractChatUsage(gjson.Parse(`{"prompt_tokens":120,"completion_tokens":30,"prompt_tokens_details":{"cached_tokens":100}}`))
+	if usage == nil {
+		t.Fatal("usage = nil")
+	}
+	if usage.InputTokens != 20 || usage.OutputTokens != 30 || usage.CacheReadInputTokens != 100 {
+		t.Fatalf("usage = %+v, want input=20 output=30 cacheRead=100", usage)
+	}
+
+	if extractChatUsage(gjson.Parse(`null`)) != nil {
+		t.Fatal("null usage should return nil")
+	}
+}
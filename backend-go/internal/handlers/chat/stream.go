@@ -0,0 +1,145 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/converters"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// handleStreamSuccess 处理流式响应，将各类上游 SSE 统一转换为 Chat Completions chunk
+func handleStreamSuccess(
+	c *gin.Context,
+	resp *http.Response,
+	upstreamType string,
+	envCfg *config.EnvConfig,
+	firstContentTimeout time.Duration,
+	startTime time.Time,
+	chatReq *chatRequest,
+) (*types.Usage, error) {
+	// 流式 failover：首个内容前只向客户端写出心跳，上游失败时由调用方切换 Key/渠道
+	heartbeatInterval := time.Duration(envCfg.StreamHeartbeatInterval) * time.Second
+	if envCfg.StreamFailoverEnabled {
+		stopPreludeHeartbeat := common.StartPreludeHeartbeat(c, heartbeatInterval, common.SSECommentHeartbeat)
+		body, err := common.AwaitSSEPrelude(c, resp.Body, upstreamType, firstContentTimeout)
+		stopPreludeHeartbeat()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = body
+	}
+
+	// 设置 SSE 响应头
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+
+	stopHeartbeat := common.StartStreamHeartbeat(c, heartbeatInterval, common.SSECommentHeartbeat)
+	defer stopHeartbeat()
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		log.Printf("[Chat-Stream] 警告: ResponseWriter 不支持 Flusher")
+	}
+
+	st := converters.NewChatStreamState(chatReq.model, chatReq.includeUsage)
+
+	// 按上游类型选择单条 data 负载的转换函数；OpenAI 上游透传
+	var convert func(data []byte) []string
+	switch upstreamType {
+	case "claude":
+		convert = st.ConvertClaudeEvent
+	case "gemini":
+		convert = st.ConvertGeminiChunk
+	case "responses":
+		convert = st.ConvertResponsesEvent
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer
+
+	sawDone := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		jsonData := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if jsonData == "" {
+			continue
+		}
+		if jsonData == "[DONE]" {
+			sawDone = true
+			break
+		}
+
+		if convert == nil {
+			if st.ObserveOpenAIChunk([]byte(jsonData)) {
+				fmt.Fprintf(c.Writer, "data: %s\n\n", jsonData)
+			}
+		} else {
+			for _, chunk := range convert([]byte(jsonData)) {
+				fmt.Fprintf(c.Writer, "data: %s\n\n", chunk)
+			}
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if st.UpstreamError() != "" {
+			break
+		}
+	}
+
+	streamErr := scanner.Err()
+	if streamErr != nil {
+		if ctxErr := c.Request.Context().Err(); ctxErr != nil {
+			return st.Usage(), ctxErr
+		}
+	} else if upstreamErr := st.UpstreamError(); upstreamErr != "" {
+		streamErr = fmt.Errorf("上游流返回错误: %s", upstreamErr)
+	}
+
+	// 流中途异常（读取失败、空闲超时或上游错误事件）：发送错误负载而非正常结束，
+	// 并向调用方返回错误以记录渠道失败
+	if streamErr != nil {
+		log.Printf("[Chat-Stream] 上游流异常中断: %v", streamErr)
+		// OpenAI 上游透传时错误负载已转发
+		if convert != nil || st.UpstreamError() == "" {
+			fmt.Fprintf(c.Writer, "data: %s\n\n", st.ErrorChunk(streamErr.Error()))
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return st.Usage(), streamErr
+	}
+
+	if convert != nil {
+		for _, chunk := range st.Finish() {
+			fmt.Fprintf(c.Writer, "data: %s\n\n", chunk)
+		}
+	} else if !sawDone {
+		log.Printf("[Chat-Stream] 警告: 上游流未以 [DONE] 结束")
+	}
+	fmt.Fprintf(c.Writer, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	if envCfg.EnableResponseLogs {
+		responseTime := time.Since(startTime).Milliseconds()
+		log.Printf("[Chat-Stream-Timing] 流式响应完成: %dms", responseTime)
+	}
+
+	return st.Usage(), nil
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/quota"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// clientStatsWindow 客户端列表中附带的统计时间窗口
+const clientStatsWindow = 24 * time.Hour
+
+// GetClients 获取客户端列表（附带最近 24 小时各入口的请求统计与当前配额用量）
+// GET /api/clients
+func GetClients(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler, quotaManager *quota.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clients := cfgManager.GetClients()
+
+		managers := map[string]*metrics.MetricsManager{
+			string(scheduler.ChannelKindMessages):  sch.GetMessagesMetricsManager(),
+			string(scheduler.ChannelKindResponses): sch.GetResponsesMetricsManager(),
+			string(scheduler.ChannelKindGemini):    sch.GetGeminiMetricsManager(),
+			string(scheduler.ChannelKindChat):      sch.GetChatMetricsManager(),
+		}
+		statsByKind := make(map[string]map[string]metrics.TimeWindowStats, len(managers))
+		for kind, m := range managers {
+			if m != nil {
+				statsByKind[kind] = m.GetClientTimeWindowStats(clientStatsWindow)
+			}
+		}
+
+		result := make([]gin.H, len(clients))
+		for i, client := range clients {
+			usage := gin.H{}
+			for kind, stats := range statsByKind {
+				if s, ok := stats[client.ID]; ok {
+					usage[kind] = s
+				}
+			}
+
+			item := clientView(&client)
+			item["usage24h"] = usage
+			if quotaManager != nil {
+				item["quotaUsage"] = quotaManager.GetUsage(client.ID)
+			}
+			result[i] = item
+		}
+
+		c.JSON(200, gin.H{"clients": result})
+	}
+}
+
+// clientView 构建客户端的 API 展示结构（不返回密钥摘要）
+func clientView(client *config.ClientKey) gin.H {
+	return gin.H{
+		"id":            client.ID,
+		"name":          client.Name,
+		"keyPrefix":     client.KeyPrefix,
+		"allowedKinds":  client.AllowedKinds,
+		"allowedModels": client.AllowedModels,
+		"expiresAt":     client.ExpiresAt,
+		"expired":       client.IsExpired(time.Now()),
+		"disabled":      client.Disabled,
+		"description":   client.Description,
+		"createdAt":     client.CreatedAt,
+		// 配额限制（0 表示不限制）
+		"dailyTokenLimit":   client.DailyTokenLimit,
+		"monthlyTokenLimit": client.MonthlyTokenLimit,
+		"rpmLimit":          client.RPMLimit,
+	}
+}
+
+// AddClient 创建客户端
+// POST /api/clients
+// 未提供 secret 时自动生成；明文密钥仅在响应中返回一次
+func AddClient(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			config.ClientKey
+			Secret string `json:"secret"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		client, secret, err := cfgManager.AddClient(req.ClientKey, req.Secret)
+		if err != nil {
+			if strings.Contains(err.Error(), "不能为空") || strings.Contains(err.Error(), "无效的") || strings.Contains(err.Error(), "已存在") {
+				c.JSON(400, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(500, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"success": true,
+			"message": "客户端已创建，请妥善保存密钥（仅显示一次）",
+			"client":  clientView(client),
+			"secret":  secret,
+		})
+	}
+}
+
+// UpdateClient 更新客户端
+// PUT /api/clients/:id
+func UpdateClient(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var updates config.ClientKeyUpdate
+		if err := c.ShouldBindJSON(&updates); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		client, err := cfgManager.UpdateClient(c.Param("id"), updates)
+		if err != nil {
+			if strings.Contains(err.Error(), "客户端不存在") {
+				c.JSON(404, gin.H{"error": "Client not found"})
+			} else if strings.Contains(err.Error(), "不能为空") || strings.Contains(err.Error(), "无效的") {
+				c.JSON(400, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(500, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"success": true,
+			"client":  clientView(client),
+		})
+	}
+}
+
+// RotateClientSecret 重新生成客户端密钥
+// POST /api/clients/:id/rotate
+func RotateClientSecret(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret, err := cfgManager.RotateClientSecret(c.Param("id"))
+		if err != nil {
+			if strings.Contains(err.Error(), "客户端不存在") {
+				c.JSON(404, gin.H{"error": "Client not found"})
+			} else {
+				c.JSON(500, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"success": true,
+			"message": "客户端密钥已轮换，旧密钥立即失效",
+			"secret":  secret,
+		})
+	}
+}
+
+// DeleteClient 删除客户端
+// DELETE /api/clients/:id
+func DeleteClient(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := cfgManager.RemoveClient(c.Param("id")); err != nil {
+			if strings.Contains(err.Error(), "客户端不存在") {
+				c.JSON(404, gin.H{"error": "Client not found"})
+			} else {
+				c.JSON(500, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(200, gin.H{"success": true, "message": "客户端已删除"})
+	}
+}
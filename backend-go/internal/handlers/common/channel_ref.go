@@ -0,0 +1,23 @@
+package common
+
+import (
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// ChannelResolver 将渠道引用（渠道标识或数字索引）解析为渠道标识
+type ChannelResolver interface {
+	ResolveChannelID(kind, ref string) (string, error)
+}
+
+// ResolveChannelParam 解析路由参数 :id 为渠道标识（推荐使用渠道标识，兼容旧的数字索引）
+// 修改类操作应直接把标识交给 ConfigManager，在其写锁内定位渠道；只读操作在 GetConfig 快照上用 ChannelIndexByID 查找
+// 渠道不存在时写入 404 响应并返回 false
+func ResolveChannelParam(c *gin.Context, resolver ChannelResolver, kind scheduler.ChannelKind) (string, bool) {
+	id, err := resolver.ResolveChannelID(string(kind), c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return id, true
+}
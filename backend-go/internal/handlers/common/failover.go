@@ -3,9 +3,14 @@
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"strings"
 
+	"github.com/BenedictKing/claude-proxy/internal/alert"
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/gin-gonic/gin"
 )
 
@@ -64,7 +69,7 @@ func shouldRetryWithNextKeyFuzzy(statusCode int, bodyBytes []byte, apiType strin
 	// 对于其他状态码，检查消息体是否包含配额相关关键词
 	// 这样 403 + "预扣费额度" 消息 → isQuotaRelated=true
 	if len(bodyBytes) > 0 {
-		_, msgQuota := classifyByErrorMessage(bodyBytes, apiType)
+		_, msgQuota, _ := classifyByErrorMessage(bodyBytes, apiType)
 		if msgQuota {
 			log.Printf("[%s-Failover-Fuzzy] 消息体包含配额相关关键词，标记为配额相关", apiType)
 			return true, true
@@ -96,7 +101,7 @@ func shouldRetryWithNextKeyNormal(statusCode int, bodyBytes []byte, apiType stri
 		// 否则，仍检查消息体是否包含 quota 相关关键词
 		// 这样 403 + "预扣费额度" 消息 → isQuotaRelated=true
 		log.Printf("[%s-Failover-Debug] 调用 classifyByErrorMessage, body=%s", apiType, string(bodyBytes))
-		_, msgQuota := classifyByErrorMessage(bodyBytes, apiType)
+		_, msgQuota, _ := classifyByErrorMessage(bodyBytes, apiType)
 		log.Printf("[%s-Failover-Debug] classifyByErrorMessage 返回: msgQuota=%v", apiType, msgQuota)
 		if msgQuota {
 			return true, true
@@ -105,7 +110,8 @@ func shouldRetryWithNextKeyNormal(statusCode int, bodyBytes []byte, apiType stri
 	}
 
 	// statusCode 不触发 failover 时，完全依赖消息体判断
-	return classifyByErrorMessage(bodyBytes, apiType)
+	msgFailover, msgQuota, _ := classifyByErrorMessage(bodyBytes, apiType)
+	return msgFailover, msgQuota
 }
 
 // classifyByStatusCode 基于 HTTP 状态码分类
@@ -156,24 +162,25 @@ func classifyByStatusCode(statusCode int) (bool, bool) {
 }
 
 // classifyByErrorMessage 基于错误消息内容分类
-func classifyByErrorMessage(bodyBytes []byte, apiType string) (bool, bool) {
+// 返回: (shouldFailover, isQuotaRelated, keyReason)；keyReason 非空表示消息指向 Key 本身不可用（config.DisabledReason*）
+func classifyByErrorMessage(bodyBytes []byte, apiType string) (bool, bool, string) {
 	var errResp map[string]interface{}
 	if err := json.Unmarshal(bodyBytes, &errResp); err != nil {
 		log.Printf("[%s-Failover-Debug] JSON解析失败: %v, body长度=%d", apiType, err, len(bodyBytes))
-		return false, false
+		return false, false, ""
 	}
 
 	errObj, ok := errResp["error"].(map[string]interface{})
 	if !ok {
 		log.Printf("[%s-Failover-Debug] 未找到error对象, keys=%v", apiType, getMapKeys(errResp))
-		return false, false
+		return false, false, ""
 	}
 
 	// 检查 error.code 字段，某些错误码不应重试（内容审核、无效请求等）
 	if errCode, ok := errObj["code"].(string); ok {
 		if isNonRetryableErrorCode(errCode) {
 			log.Printf("[%s-Failover-Debug] 检测到不可重试错误码: %s", apiType, errCode)
-			return false, false
+			return false, false, ""
 		}
 	}
 
@@ -182,9 +189,9 @@ func classifyByErrorMessage(bodyBytes []byte, apiType string) (bool, bool) {
 	for _, field := range messageFields {
 		if msg, ok := errObj[field].(string); ok {
 			log.Printf("[%s-Failover-Debug] 提取到消息 (字段: %s): %s", apiType, field, msg)
-			if failover, quota := classifyMessage(msg); failover {
+			if failover, quota, reason := classifyMessage(msg); failover {
 				log.Printf("[%s-Failover-Debug] 消息分类结果: failover=%v, quota=%v", apiType, failover, quota)
-				return true, quota
+				return true, quota, reason
 			}
 		}
 	}
@@ -193,108 +200,108 @@ func classifyByErrorMessage(bodyBytes []byte, apiType string) (bool, bool) {
 	if upstreamErr, ok := errObj["upstream_error"].(map[string]interface{}); ok {
 		if msg, ok := upstreamErr["message"].(string); ok {
 			log.Printf("[%s-Failover-Debug] 提取到嵌套 upstream_error.message: %s", apiType, msg)
-			if failover, quota := classifyMessage(msg); failover {
+			if failover, quota, reason := classifyMessage(msg); failover {
 				log.Printf("[%s-Failover-Debug] 消息分类结果: failover=%v, quota=%v", apiType, failover, quota)
-				return true, quota
+				return true, quota, reason
 			}
 		}
 	}
 
 	// 检查 type 字段
 	if errType, ok := errObj["type"].(string); ok {
-		if failover, quota := classifyErrorType(errType); failover {
-			return true, quota
+		if failover, quota, reason := classifyErrorType(errType); failover {
+			return true, quota, reason
 		}
 	}
 
 	log.Printf("[%s-Failover-Debug] 未匹配任何关键词, errObj keys=%v", apiType, getMapKeys(errObj))
-	return false, false
+	return false, false, ""
 }
 
-// classifyMessage 基于错误消息内容分类
-func classifyMessage(msg string) (bool, bool) {
-	msgLower := strings.ToLower(msg)
-
-	// 配额/余额相关关键词 (failover + quota)
-	quotaKeywords := []string{
-		"insufficient", "quota", "credit", "balance",
-		"rate limit", "limit exceeded", "exceeded",
-		"billing", "payment", "subscription",
-		"积分不足", "余额不足", "请求数限制", "额度", "预扣费",
-	}
-	for _, keyword := range quotaKeywords {
-		if strings.Contains(msgLower, keyword) {
-			return true, true
-		}
-	}
+// errorKeywordGroup 一组错误关键词及其分类
+type errorKeywordGroup struct {
+	keywords  []string
+	quota     bool   // 额度/配额相关
+	keyReason string // 指向 Key 本身不可用的原因（config.DisabledReason*），为空表示与 Key 无关或可自行恢复
+}
 
-	// 认证/授权相关关键词 (failover + 非 quota)
-	authKeywords := []string{
+// messageKeywordGroups 错误消息关键词（按顺序匹配，命中的所有分组都触发 failover）
+var messageKeywordGroups = []errorKeywordGroup{
+	// 余额/计费相关 (failover + quota)
+	{keywords: []string{
+		"insufficient", "credit", "balance", "billing", "payment", "quota is not enough",
+		"积分不足", "余额不足", "额度不足", "额度已用尽", "预扣费",
+	}, quota: true, keyReason: config.DisabledReasonInsufficientBalance},
+	// 限流/配额相关 (failover + quota，可自行恢复)
+	{keywords: []string{
+		"quota", "rate limit", "limit exceeded", "exceeded", "subscription",
+		"请求数限制", "额度",
+	}, quota: true},
+	// 账号封禁/停用 (failover + 非 quota)
+	{keywords: []string{
+		"account has been disabled", "account has been deactivated", "account_deactivated",
+		"account is disabled", "account has been suspended", "account suspended",
+		"organization has been disabled", "has been banned",
+		"已被封禁", "账号已禁用", "账户已禁用", "账号已停用", "账户已停用",
+	}, keyReason: config.DisabledReasonAccountBanned},
+	// Key 无效 (failover + 非 quota)
+	{keywords: []string{
+		"api key", "api-key", "api_key", "apikey",
+		"密钥无效", "令牌",
+	}, keyReason: config.DisabledReasonInvalidKey},
+	// 认证/授权相关 (failover + 非 quota)
+	{keywords: []string{
 		"invalid", "unauthorized", "authentication",
-		"api key", "apikey", "token", "expired",
+		"token", "expired",
 		"permission", "forbidden", "denied",
-		"密钥无效", "认证失败", "权限不足",
-	}
-	for _, keyword := range authKeywords {
-		if strings.Contains(msgLower, keyword) {
-			return true, false
-		}
-	}
-
-	// 临时错误关键词 (failover + 非 quota)
-	transientKeywords := []string{
+		"认证失败", "权限不足",
+	}},
+	// 临时错误 (failover + 非 quota)
+	{keywords: []string{
 		"timeout", "timed out", "temporarily",
 		"overloaded", "unavailable", "retry",
 		"server error", "internal error",
 		"超时", "暂时", "重试",
-	}
-	for _, keyword := range transientKeywords {
-		if strings.Contains(msgLower, keyword) {
-			return true, false
-		}
-	}
-
-	return false, false
+	}},
 }
 
-// classifyErrorType 基于错误类型分类
-func classifyErrorType(errType string) (bool, bool) {
-	typeLower := strings.ToLower(errType)
-
-	// 配额相关的错误类型 (failover + quota)
-	quotaTypes := []string{
-		"over_quota", "quota_exceeded", "rate_limit",
-		"billing", "insufficient", "payment",
-	}
-	for _, t := range quotaTypes {
-		if strings.Contains(typeLower, t) {
-			return true, true
-		}
-	}
-
+// errorTypeKeywordGroups 错误类型关键词（按顺序匹配）
+var errorTypeKeywordGroups = []errorKeywordGroup{
+	// 计费相关的错误类型 (failover + quota)
+	{keywords: []string{"billing", "insufficient", "payment"}, quota: true, keyReason: config.DisabledReasonInsufficientBalance},
+	// 配额/限流相关的错误类型 (failover + quota)
+	{keywords: []string{"over_quota", "quota_exceeded", "rate_limit"}, quota: true},
+	// Key 无效的错误类型 (failover + 非 quota)
+	{keywords: []string{"invalid_api_key"}, keyReason: config.DisabledReasonInvalidKey},
 	// 认证相关的错误类型 (failover + 非 quota)
-	authTypes := []string{
-		"authentication", "authorization", "permission",
-		"invalid_api_key", "invalid_token", "expired",
-	}
-	for _, t := range authTypes {
-		if strings.Contains(typeLower, t) {
-			return true, false
-		}
-	}
-
+	{keywords: []string{"authentication", "authorization", "permission", "invalid_token", "expired"}},
 	// 服务端错误类型 (failover + 非 quota)
-	serverTypes := []string{
-		"server_error", "internal_error", "service_unavailable",
-		"timeout", "overloaded",
-	}
-	for _, t := range serverTypes {
-		if strings.Contains(typeLower, t) {
-			return true, false
+	{keywords: []string{"server_error", "internal_error", "service_unavailable", "timeout", "overloaded"}},
+}
+
+// matchKeywordGroups 返回第一个命中的关键词分组
+func matchKeywordGroups(text string, groups []errorKeywordGroup) (bool, bool, string) {
+	lower := strings.ToLower(text)
+	for _, group := range groups {
+		for _, keyword := range group.keywords {
+			if strings.Contains(lower, keyword) {
+				return true, group.quota, group.keyReason
+			}
 		}
 	}
+	return false, false, ""
+}
 
-	return false, false
+// classifyMessage 基于错误消息内容分类
+// 返回: (shouldFailover, isQuotaRelated, keyReason)
+func classifyMessage(msg string) (bool, bool, string) {
+	return matchKeywordGroups(msg, messageKeywordGroups)
+}
+
+// classifyErrorType 基于错误类型分类
+// 返回: (shouldFailover, isQuotaRelated, keyReason)
+func classifyErrorType(errType string) (bool, bool, string) {
+	return matchKeywordGroups(errType, errorTypeKeywordGroups)
 }
 
 // HandleAllChannelsFailed 处理所有渠道都失败的情况
@@ -303,9 +310,27 @@ func classifyErrorType(errType string) (bool, bool) {
 // lastError: 最后一个错误
 // apiType: API 类型（用于错误消息）
 func HandleAllChannelsFailed(c *gin.Context, fuzzyMode bool, lastFailoverError *FailoverError, lastError error, apiType string) {
+	// 没有渠道服务请求模型属于请求错误，不受 Fuzzy 模式影响
+	var notServed *scheduler.ModelNotServedError
+	if lastFailoverError == nil && errors.As(lastError, &notServed) {
+		WriteModelNotServed(c, notServed, apiType)
+		return
+	}
+
+	alertMessage := "所有渠道均不可用"
+	if lastError != nil {
+		alertMessage += ": " + lastError.Error()
+	}
+	alert.Emit(alert.Event{
+		Type:    config.AlertEventAllChannelsFailed,
+		Level:   alert.LevelCritical,
+		Kind:    strings.ToLower(apiType),
+		Message: alertMessage,
+	})
+
 	// Fuzzy 模式下返回通用错误，不透传上游详情
 	if fuzzyMode {
-		c.JSON(503, gin.H{
+		WriteErrorJSON(c, 503, gin.H{
 			"type": "error",
 			"error": gin.H{
 				"type":    "service_unavailable",
@@ -323,27 +348,59 @@ func HandleAllChannelsFailed(c *gin.Context, fuzzyMode bool, lastFailoverError *
 		}
 		var errBody map[string]interface{}
 		if err := json.Unmarshal(lastFailoverError.Body, &errBody); err == nil {
-			c.JSON(status, errBody)
+			WriteErrorJSON(c, status, errBody)
 		} else {
-			c.JSON(status, gin.H{"error": string(lastFailoverError.Body)})
+			WriteErrorJSON(c, status, gin.H{"error": string(lastFailoverError.Body)})
 		}
 	} else {
 		errMsg := "所有渠道都不可用"
 		if lastError != nil {
 			errMsg = lastError.Error()
 		}
-		c.JSON(503, gin.H{
+		WriteErrorJSON(c, 503, gin.H{
 			"error":   "所有" + apiType + "渠道都不可用",
 			"details": errMsg,
 		})
 	}
 }
 
+// WriteModelNotServed 返回“没有渠道服务请求模型”错误（404），按入口协议输出对应的错误格式
+// apiType: Messages（Claude 格式）、Responses/Chat（OpenAI 格式）、Gemini（Gemini 格式）
+func WriteModelNotServed(c *gin.Context, err *scheduler.ModelNotServedError, apiType string) {
+	switch apiType {
+	case "Responses", "Chat":
+		c.JSON(404, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+				"param":   "model",
+				"code":    "model_not_found",
+			},
+		})
+	case "Gemini":
+		c.JSON(404, types.GeminiError{
+			Error: types.GeminiErrorDetail{
+				Code:    404,
+				Message: err.Error(),
+				Status:  "NOT_FOUND",
+			},
+		})
+	default:
+		c.JSON(404, gin.H{
+			"type": "error",
+			"error": gin.H{
+				"type":    "not_found_error",
+				"message": err.Error(),
+			},
+		})
+	}
+}
+
 // HandleAllKeysFailed 处理所有密钥都失败的情况（单渠道模式）
 func HandleAllKeysFailed(c *gin.Context, fuzzyMode bool, lastFailoverError *FailoverError, lastError error, apiType string) {
 	// Fuzzy 模式下返回通用错误
 	if fuzzyMode {
-		c.JSON(503, gin.H{
+		WriteErrorJSON(c, 503, gin.H{
 			"type": "error",
 			"error": gin.H{
 				"type":    "service_unavailable",
@@ -361,16 +418,16 @@ func HandleAllKeysFailed(c *gin.Context, fuzzyMode bool, lastFailoverError *Fail
 		}
 		var errBody map[string]interface{}
 		if err := json.Unmarshal(lastFailoverError.Body, &errBody); err == nil {
-			c.JSON(status, errBody)
+			WriteErrorJSON(c, status, errBody)
 		} else {
-			c.JSON(status, gin.H{"error": string(lastFailoverError.Body)})
+			WriteErrorJSON(c, status, gin.H{"error": string(lastFailoverError.Body)})
 		}
 	} else {
 		errMsg := "未知错误"
 		if lastError != nil {
 			errMsg = lastError.Error()
 		}
-		c.JSON(500, gin.H{
+		WriteErrorJSON(c, 500, gin.H{
 			"error":   "所有上游" + apiType + "API密钥都不可用",
 			"details": errMsg,
 		})
@@ -410,6 +467,50 @@ func isNonRetryableErrorCode(code string) bool {
 	return false
 }
 
+// keyErrorCodes Key 专属错误码（error.code/type/status 或 Gemini error.details[].reason）及对应的禁用原因
+// 这些错误码只会因 Key 本身不可用而返回，不依赖状态码即可判定
+var keyErrorCodes = map[string]string{
+	"invalid_api_key":      config.DisabledReasonInvalidKey,
+	"api_key_invalid":      config.DisabledReasonInvalidKey,
+	"account_deactivated":  config.DisabledReasonAccountBanned,
+	"insufficient_quota":   config.DisabledReasonInsufficientBalance,
+	"insufficient_balance": config.DisabledReasonInsufficientBalance,
+	"billing_not_active":   config.DisabledReasonInsufficientBalance,
+}
+
+// keyErrorCodeReason 返回响应体中 Key 专属错误码对应的禁用原因，未命中返回空
+func keyErrorCodeReason(bodyBytes []byte) string {
+	var errResp map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &errResp); err != nil {
+		return ""
+	}
+	errObj, ok := errResp["error"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	var codes []string
+	for _, field := range []string{"code", "type", "status"} {
+		if v, ok := errObj[field].(string); ok {
+			codes = append(codes, v)
+		}
+	}
+	if details, ok := errObj["details"].([]interface{}); ok {
+		for _, d := range details {
+			if detail, ok := d.(map[string]interface{}); ok {
+				if v, ok := detail["reason"].(string); ok {
+					codes = append(codes, v)
+				}
+			}
+		}
+	}
+	for _, code := range codes {
+		if reason, ok := keyErrorCodes[strings.ToLower(code)]; ok {
+			return reason
+		}
+	}
+	return ""
+}
+
 // isNonRetryableError 检查响应体是否包含不可重试的错误码
 func isNonRetryableError(bodyBytes []byte) bool {
 	var errResp map[string]interface{}
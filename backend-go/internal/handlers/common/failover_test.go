@@ -2,7 +2,12 @@
 
 import (
 	"encoding/json"
+	"net/http/httptest"
 	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
 )
 
 // TestClassifyByStatusCode 测试基于状态码的分类
@@ -99,7 +104,7 @@ func TestClassifyMessage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotFailover, gotQuota := classifyMessage(tt.message)
+			gotFailover, gotQuota, _ := classifyMessage(tt.message)
 			if gotFailover != tt.wantFailover {
 				t.Errorf("classifyMessage(%q) failover = %v, want %v", tt.message, gotFailover, tt.wantFailover)
 			}
@@ -143,7 +148,7 @@ func TestClassifyErrorType(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotFailover, gotQuota := classifyErrorType(tt.errType)
+			gotFailover, gotQuota, _ := classifyErrorType(tt.errType)
 			if gotFailover != tt.wantFailover {
 				t.Errorf("classifyErrorType(%q) failover = %v, want %v", tt.errType, gotFailover, tt.wantFailover)
 			}
@@ -283,7 +288,7 @@ func TestClassifyByErrorMessage(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			bodyBytes, _ := json.Marshal(tt.body)
-			gotFailover, gotQuota := classifyByErrorMessage(bodyBytes, "Messages")
+			gotFailover, gotQuota, _ := classifyByErrorMessage(bodyBytes, "Messages")
 			if gotFailover != tt.wantFailover {
 				t.Errorf("classifyByErrorMessage() failover = %v, want %v", gotFailover, tt.wantFailover)
 			}
@@ -304,7 +309,7 @@ func TestClassifyByErrorMessage_InvalidJSON(t *testing.T) {
 	}
 
 	for _, body := range invalidBodies {
-		gotFailover, gotQuota := classifyByErrorMessage(body, "Messages")
+		gotFailover, gotQuota, _ := classifyByErrorMessage(body, "Messages")
 		if gotFailover || gotQuota {
 			t.Errorf("classifyByErrorMessage(%q) should return (false, false) for invalid JSON", string(body))
 		}
@@ -342,7 +347,7 @@ func TestClassifyMessage_ChineseQuotaKeywords(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotFailover, gotQuota := classifyMessage(tt.message)
+			gotFailover, gotQuota, _ := classifyMessage(tt.message)
 			if gotFailover != tt.wantFailover {
 				t.Errorf("classifyMessage(%q) failover = %v, want %v", tt.message, gotFailover, tt.wantFailover)
 			}
@@ -736,3 +741,32 @@ func TestShouldRetryWithNextKey_SensitiveWordsDetected(t *testing.T) {
 		})
 	}
 }
+
+// TestWriteModelNotServed 测试模型无渠道服务时按入口协议输出错误格式
+func TestWriteModelNotServed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	notServed := &scheduler.ModelNotServedError{Kind: scheduler.ChannelKindChat, Model: "gpt-x"}
+
+	tests := []struct {
+		apiType string
+		path    string
+		want    string
+	}{
+		{"Messages", "error.type", "not_found_error"},
+		{"Chat", "error.code", "model_not_found"},
+		{"Responses", "error.type", "invalid_request_error"},
+		{"Gemini", "error.status", "NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		WriteModelNotServed(c, notServed, tt.apiType)
+
+		if w.Code != 404 {
+			t.Errorf("%s: status = %d, want 404", tt.apiType, w.Code)
+		}
+		if got := gjson.Get(w.Body.String(), tt.path).String(); got != tt.want {
+			t.Errorf("%s: %s = %q, want %q (body=%s)", tt.apiType, tt.path, got, tt.want, w.Body.String())
+		}
+	}
+}
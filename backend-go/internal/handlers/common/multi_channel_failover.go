@@ -5,6 +5,7 @@
 	"log"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/requestlog"
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/gin-gonic/gin"
@@ -52,6 +53,9 @@ func HandleMultiChannelFailover(
 		}
 	}
 
+	// Trace 亲和按客户端隔离
+	userID = ScopeTraceAffinityKey(c, userID)
+
 	failedChannels := make(map[int]bool)
 	var lastError error
 	var lastFailoverError *FailoverError
@@ -70,7 +74,7 @@ func HandleMultiChannelFailover(
 			// 继续正常流程
 		}
 
-		selection, err := channelScheduler.SelectChannel(c.Request.Context(), userID, failedChannels, kind)
+		selection, err := channelScheduler.SelectChannel(c.Request.Context(), userID, failedChannels, kind, GetRequestModel(c))
 		if err != nil {
 			lastError = err
 			break
@@ -84,14 +88,15 @@ func HandleMultiChannelFailover(
 				apiType, channelIndex, upstream.Name, selection.Reason, channelAttempt+1, maxChannelAttempts)
 		}
 
+		requestlog.FromContext(c).SetChannelIndex(channelIndex)
 		result := trySelectedChannel(selection)
 		if result.Handled {
 			if onHandled != nil {
 				onHandled(selection, result)
 			}
 			// 只有真正成功的请求才设置 Trace 亲和（客户端取消时 SuccessKey 为空）
-			if result.SuccessKey != "" {
-				channelScheduler.SetTraceAffinity(userID, channelIndex)
+			if result.SuccessKey != "" && upstream != nil {
+				channelScheduler.SetTraceAffinity(userID, upstream.ID)
 			}
 			return
 		}
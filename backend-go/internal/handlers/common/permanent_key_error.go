@@ -0,0 +1,80 @@
+package common
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// maxDisabledMessageRunes 记录到禁用列表的上游错误消息最大长度
+const maxDisabledMessageRunes = 200
+
+// ClassifyPermanentKeyError 判断上游错误是否表示 Key 永久失效（无效、账号封禁、余额不足），返回禁用原因与上游错误消息
+// Key 专属错误码（如 invalid_api_key、insufficient_quota）在 400/401/402/403/429 上均生效；
+// 仅凭消息关键词判断时要求 401/402/403，避免把 400/429 上的"余额不足""额度"等限流表述当作永久失效
+func ClassifyPermanentKeyError(statusCode int, bodyBytes []byte, apiType string) (reason string, message string) {
+	switch statusCode {
+	case 400, 401, 402, 403, 429:
+	default:
+		return "", ""
+	}
+
+	reason = keyErrorCodeReason(bodyBytes)
+	if reason == "" && statusCode != 400 && statusCode != 429 {
+		if json.Valid(bodyBytes) {
+			_, _, reason = classifyByErrorMessage(bodyBytes, apiType)
+		} else {
+			_, _, reason = classifyMessage(string(bodyBytes))
+		}
+	}
+	if reason == "" {
+		return "", ""
+	}
+	return reason, truncateRunes(firstNonEmpty(extractErrorStrings(bodyBytes)), maxDisabledMessageRunes)
+}
+
+// extractErrorStrings 提取错误响应中可能包含原因的字符串（消息优先，其次错误码与类型）
+func extractErrorStrings(bodyBytes []byte) []string {
+	var errResp map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &errResp); err != nil {
+		if text := strings.TrimSpace(string(bodyBytes)); text != "" {
+			return []string{text}
+		}
+		return nil
+	}
+
+	var result []string
+	collect := func(obj map[string]interface{}) {
+		for _, field := range []string{"message", "upstream_error", "detail", "code", "type", "status"} {
+			if v, ok := obj[field].(string); ok && v != "" {
+				result = append(result, v)
+			}
+		}
+		if upstreamErr, ok := obj["upstream_error"].(map[string]interface{}); ok {
+			if msg, ok := upstreamErr["message"].(string); ok && msg != "" {
+				result = append(result, msg)
+			}
+		}
+	}
+	if errObj, ok := errResp["error"].(map[string]interface{}); ok {
+		collect(errObj)
+	}
+	collect(errResp)
+	return result
+}
+
+func firstNonEmpty(values []string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "..."
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+func TestClassifyPermanentKeyError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		wantReason string
+	}{
+		{"OpenAI 无效 Key", 401, `{"error":{"message":"Incorrect API key provided: sk-xxx","type":"invalid_request_error","code":"invalid_api_key"}}`, config.DisabledReasonInvalidKey},
+		{"Claude 无效 Key", 401, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, config.DisabledReasonInvalidKey},
+		{"Gemini 无效 Key", 400, `{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.","status":"INVALID_ARGUMENT","details":[{"@type":"type.googleapis.com/google.rpc.ErrorInfo","reason":"API_KEY_INVALID"}]}}`, config.DisabledReasonInvalidKey},
+		{"OpenAI 额度用尽", 429, `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`, config.DisabledReasonInsufficientBalance},
+		{"402 余额过低", 402, `{"type":"error","error":{"type":"invalid_request_error","message":"Your credit balance is too low to access the Anthropic API."}}`, config.DisabledReasonInsufficientBalance},
+		{"中转站余额不足", 403, `{"error":{"message":"用户余额不足","type":"new_api_error"}}`, config.DisabledReasonInsufficientBalance},
+		{"账号封禁", 403, `{"error":{"message":"Your account has been disabled for violating our policies"}}`, config.DisabledReasonAccountBanned},
+		{"中文封禁", 401, `{"error":{"message":"该账号已被封禁"}}`, config.DisabledReasonAccountBanned},
+		{"纯文本响应", 401, `invalid api key`, config.DisabledReasonInvalidKey},
+		// 可自行恢复的错误不应禁用
+		{"普通限流", 429, `{"error":{"message":"Rate limit reached for requests","type":"rate_limit_error"}}`, ""},
+		{"服务端错误", 500, `{"error":{"message":"invalid api key"}}`, ""},
+		{"过载", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ""},
+		{"无原因的 401", 401, `{"error":{"message":"Unauthorized"}}`, ""},
+		// 400/429 上仅凭消息关键词不禁用（须有 Key 专属错误码）
+		{"429 余额不足限流", 429, `{"error":{"message":"当前分组余额不足，请稍后重试","type":"new_api_error"}}`, ""},
+		{"400 余额关键词", 400, `{"type":"error","error":{"type":"invalid_request_error","message":"Your credit balance is too low to access the Anthropic API."}}`, ""},
+		{"400 无 Key 错误码", 400, `{"error":{"code":400,"message":"API key not valid.","status":"INVALID_ARGUMENT"}}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, message := ClassifyPermanentKeyError(tt.statusCode, []byte(tt.body), "Messages")
+			if reason != tt.wantReason {
+				t.Errorf("ClassifyPermanentKeyError() reason = %q, want %q", reason, tt.wantReason)
+			}
+			if reason != "" && message == "" {
+				t.Error("ClassifyPermanentKeyError() should return the upstream message")
+			}
+		})
+	}
+}
@@ -13,6 +13,8 @@
 	"github.com/BenedictKing/claude-proxy/internal/config"
 	"github.com/BenedictKing/claude-proxy/internal/httpclient"
 	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/requestlog"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -46,7 +48,7 @@ func RestoreRequestBody(c *gin.Context, bodyBytes []byte) {
 }
 
 // SendRequest 发送 HTTP 请求到上游
-// isStream: 是否为流式请求（流式请求使用无超时客户端）
+// isStream: 是否为流式请求（流式请求使用无超时客户端，响应体按渠道/全局配置增加空闲超时）
 // apiType: 接口类型（Messages/Responses/Gemini），用于日志标签前缀
 func SendRequest(req *http.Request, upstream *config.UpstreamConfig, envCfg *config.EnvConfig, isStream bool, apiType string) (*http.Response, error) {
 	clientManager := httpclient.GetManager()
@@ -71,7 +73,17 @@ func SendRequest(req *http.Request, upstream *config.UpstreamConfig, envCfg *con
 		}
 	}
 
-	return client.Do(req)
+	resp, err := client.Do(req)
+	if err != nil || !isStream {
+		return resp, err
+	}
+
+	// 流式客户端无整体超时，为响应体增加相邻数据块之间的空闲超时
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		idleTimeout := upstream.GetStreamIdleTimeout(time.Duration(envCfg.StreamIdleTimeout) * time.Second)
+		resp.Body = WrapStreamIdleTimeout(resp.Body, idleTimeout)
+	}
+	return resp, nil
 }
 
 // logRequestDetails 记录请求详情（仅开发模式）
@@ -110,11 +122,18 @@ func logRequestDetails(req *http.Request, envCfg *config.EnvConfig, apiType stri
 
 // LogOriginalRequest 记录原始请求信息
 func LogOriginalRequest(c *gin.Context, bodyBytes []byte, envCfg *config.EnvConfig, apiType string) {
+	// 请求审计日志（独立于控制台日志开关）
+	requestlog.FromContext(c).SetRequestBody(bodyBytes)
+
 	if !envCfg.EnableRequestLogs {
 		return
 	}
 
-	log.Printf("[Request-Receive] 收到%s请求: %s %s", apiType, c.Request.Method, c.Request.URL.Path)
+	if clientName := middleware.GetClientName(c); clientName != "" {
+		log.Printf("[Request-Receive] 收到%s请求: %s %s (客户端: %s)", apiType, c.Request.Method, c.Request.URL.Path, clientName)
+	} else {
+		log.Printf("[Request-Receive] 收到%s请求: %s %s", apiType, c.Request.Method, c.Request.URL.Path)
+	}
 
 	if envCfg.IsDevelopment() {
 		var formattedBody string
@@ -142,6 +161,38 @@ func LogOriginalRequest(c *gin.Context, bodyBytes []byte, envCfg *config.EnvConf
 	}
 }
 
+// ScopeTraceAffinityKey 按客户端隔离 Trace 亲和键
+// 不同客户端可能使用相同的会话标识（如默认 user_id），加上客户端 ID 前缀避免互相命中亲和渠道。
+func ScopeTraceAffinityKey(c *gin.Context, userID string) string {
+	if userID == "" {
+		return ""
+	}
+	if clientID := middleware.GetClientID(c); clientID != "" {
+		return clientID + ":" + userID
+	}
+	return userID
+}
+
+// requestModelContextKey gin.Context 中保存客户端请求模型的键
+const requestModelContextKey = "requestModel"
+
+// SetRequestModel 记录客户端请求的模型（用于费用计算、请求审计日志等请求结束后的统计）
+func SetRequestModel(c *gin.Context, model string) {
+	if c == nil || model == "" {
+		return
+	}
+	c.Set(requestModelContextKey, model)
+	requestlog.FromContext(c).SetRequestedModel(model)
+}
+
+// GetRequestModel 获取客户端请求的模型；未记录时返回空字符串
+func GetRequestModel(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	return c.GetString(requestModelContextKey)
+}
+
 // AreAllKeysSuspended 检查渠道的所有 Key 是否都处于熔断状态
 // 用于判断是否需要启用强制探测模式
 func AreAllKeysSuspended(metricsManager *metrics.MetricsManager, baseURL string, apiKeys []string) bool {
@@ -411,8 +411,26 @@ func HandleStreamResponse(
 		return nil, err
 	}
 
+	// 流式 failover：首个内容事件前只向客户端写出心跳，上游失败时由调用方切换 Key/渠道
+	var prelude []string
+	preludeEnded := false
+	heartbeatInterval := time.Duration(envCfg.StreamHeartbeatInterval) * time.Second
+	if envCfg.StreamFailoverEnabled {
+		timeout := upstream.GetStreamFirstContentTimeout(time.Duration(envCfg.StreamFirstContentTimeout) * time.Second)
+		stopPreludeHeartbeat := StartPreludeHeartbeat(c, heartbeatInterval, ClaudePingHeartbeat)
+		prelude, preludeEnded, err = awaitStreamPrelude(c, eventChan, errChan, timeout)
+		stopPreludeHeartbeat()
+		if err != nil {
+			drainStreamEvents(eventChan)
+			return nil, err
+		}
+	}
+
 	SetupStreamHeaders(c, resp)
 
+	stopHeartbeat := StartStreamHeartbeat(c, heartbeatInterval, ClaudePingHeartbeat)
+	defer stopHeartbeat()
+
 	w := c.Writer
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -425,6 +443,13 @@ func HandleStreamResponse(
 	ctx.RequestModel = requestModel
 	ctx.LowQuality = upstream.LowQuality
 	seedSynthesizerFromRequest(ctx, requestBody)
+
+	for _, event := range prelude {
+		ProcessStreamEvent(c, w, flusher, event, ctx, envCfg, requestBody)
+	}
+	if preludeEnded {
+		return logStreamCompletion(ctx, envCfg, startTime), nil
+	}
 	return ProcessStreamEvents(c, w, flusher, eventChan, errChan, ctx, envCfg, startTime, requestBody)
 }
 
@@ -0,0 +1,272 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// StreamPreludeError 流式响应在首个内容事件前失败（上游报错、提前断开或长时间无内容）
+// 此时尚未向客户端写出任何数据，可以安全地切换到下一个 Key/渠道重试
+type StreamPreludeError struct {
+	Reason string
+	Err    error
+}
+
+func (e *StreamPreludeError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("流在首个内容前中断（%s）: %v", e.Reason, e.Err)
+	}
+	return fmt.Sprintf("流在首个内容前中断（%s）", e.Reason)
+}
+
+func (e *StreamPreludeError) Unwrap() error {
+	return e.Err
+}
+
+// IsStreamPreludeError 判断错误是否为可 failover 的流前置阶段错误
+func IsStreamPreludeError(err error) bool {
+	var preludeErr *StreamPreludeError
+	return errors.As(err, &preludeErr)
+}
+
+// streamPreludeErrorBody 构建流前置阶段失败的错误响应体（所有渠道失败时透传给客户端）
+func streamPreludeErrorBody(err error) []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"type": "error",
+		"error": map[string]interface{}{
+			"type":    "api_error",
+			"message": err.Error(),
+		},
+	})
+	return body
+}
+
+// WriteErrorJSON 写出错误响应；流式前置心跳已写出 SSE 响应头时改为 SSE error 事件，避免在事件流中混入 JSON
+func WriteErrorJSON(c *gin.Context, status int, obj interface{}) {
+	if !c.Writer.Written() {
+		c.JSON(status, obj)
+		return
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		body = streamPreludeErrorBody(err)
+	}
+	writeSSEError(c, body)
+}
+
+// WriteErrorData 写出原始 JSON 错误响应体（如透传的上游错误），规则同 WriteErrorJSON
+func WriteErrorData(c *gin.Context, status int, body []byte) {
+	if !c.Writer.Written() {
+		c.Data(status, "application/json", body)
+		return
+	}
+	writeSSEError(c, body)
+}
+
+// writeSSEError 以 SSE error 事件写出错误；data 必须为单行，非 JSON 负载包装为标准错误结构
+func writeSSEError(c *gin.Context, body []byte) {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, body); err != nil {
+		compacted.Reset()
+		compacted.Write(streamPreludeErrorBody(errors.New(truncateForLog(string(body), 500))))
+	}
+	if _, err := c.Writer.WriteString("event: error\ndata: " + compacted.String() + "\n\n"); err == nil {
+		c.Writer.Flush()
+	}
+}
+
+// awaitStreamPrelude 缓冲流事件直到出现首个内容事件
+// 返回:
+//   - events: 已缓冲的事件（需按顺序转发给客户端）
+//   - ended: 上游流已正常结束（事件通道关闭且收到 message_stop）
+//   - err: 首个内容前失败时返回 *StreamPreludeError；客户端取消时返回 context 错误
+func awaitStreamPrelude(c *gin.Context, eventChan <-chan string, errChan <-chan error, timeout time.Duration) (events []string, ended bool, err error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	sawMessageStop := false
+	for {
+		select {
+		case event, ok := <-eventChan:
+			if !ok {
+				// 上游流结束：收到 message_stop 视为正常的空响应，否则为异常断开
+				if sawMessageStop {
+					return events, true, nil
+				}
+				// 错误可能与通道关闭同时到达，优先使用具体错误
+				select {
+				case streamErr := <-errChan:
+					if streamErr != nil {
+						return nil, false, &StreamPreludeError{Reason: "上游错误", Err: streamErr}
+					}
+				default:
+				}
+				return nil, false, &StreamPreludeError{Reason: "上游提前结束"}
+			}
+
+			eventType, _, _ := extractSSEEventInfo(event)
+			switch eventType {
+			case "error":
+				return nil, false, &StreamPreludeError{Reason: "上游错误事件", Err: errors.New(truncateForLog(event, 300))}
+			case "content_block_delta", "message_delta":
+				return append(events, event), false, nil
+			case "message_stop":
+				sawMessageStop = true
+			}
+			events = append(events, event)
+
+		case streamErr, ok := <-errChan:
+			if !ok || streamErr == nil {
+				continue
+			}
+			return nil, false, &StreamPreludeError{Reason: "上游错误", Err: streamErr}
+
+		case <-timer.C:
+			return nil, false, &StreamPreludeError{Reason: fmt.Sprintf("%s 内无内容", timeout)}
+
+		case <-c.Request.Context().Done():
+			return nil, false, c.Request.Context().Err()
+		}
+	}
+}
+
+// drainStreamEvents 丢弃剩余事件，避免上游解析 goroutine 因通道写满而阻塞
+func drainStreamEvents(eventChan <-chan string) {
+	go func() {
+		for range eventChan {
+		}
+	}()
+}
+
+// preludeBody 先重放已缓冲的前置数据，再继续读取上游响应体
+type preludeBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (b *preludeBody) Close() error {
+	return b.body.Close()
+}
+
+// AwaitSSEPrelude 缓冲原始 SSE 响应体直到出现首个内容（供逐行解析上游流的 Responses/Gemini/Chat 接口使用）
+// upstreamType 为上游服务类型（claude/openai/gemini/responses），用于识别内容与错误事件。
+// 成功时返回重放已缓冲数据的响应体，调用方应以其替换 resp.Body；
+// 首个内容前失败返回 *StreamPreludeError（此时尚未写出响应头，可切换 Key/渠道），客户端取消时返回 context 错误。
+// 失败时调用方仍需关闭原响应体，以结束后台读取。
+func AwaitSSEPrelude(c *gin.Context, body io.ReadCloser, upstreamType string, timeout time.Duration) (io.ReadCloser, error) {
+	reader := bufio.NewReaderSize(body, 64*1024)
+	var buffered bytes.Buffer
+	done := make(chan error, 1)
+
+	go func() {
+		for {
+			line, readErr := reader.ReadString('\n')
+			buffered.WriteString(line)
+			if data, ok := sseDataPayload(line); ok {
+				content, eventErr := classifyPreludeData(upstreamType, data)
+				if eventErr != nil {
+					done <- &StreamPreludeError{Reason: "上游错误事件", Err: eventErr}
+					return
+				}
+				if content {
+					done <- nil
+					return
+				}
+			}
+			if readErr == io.EOF {
+				done <- &StreamPreludeError{Reason: "上游提前结束"}
+				return
+			}
+			if readErr != nil {
+				done <- &StreamPreludeError{Reason: "上游错误", Err: readErr}
+				return
+			}
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, err
+		}
+		return &preludeBody{Reader: io.MultiReader(&buffered, reader), body: body}, nil
+	case <-timer.C:
+		return nil, &StreamPreludeError{Reason: fmt.Sprintf("%s 内无内容", timeout)}
+	case <-c.Request.Context().Done():
+		return nil, c.Request.Context().Err()
+	}
+}
+
+// sseDataPayload 提取 SSE data 行的负载
+func sseDataPayload(line string) (string, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "data:") {
+		return "", false
+	}
+	data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+	return data, data != ""
+}
+
+// classifyPreludeData 判断一条上游 SSE data 负载是否为首个内容（含正常结束事件）或上游错误
+func classifyPreludeData(upstreamType, data string) (content bool, err error) {
+	if data == "[DONE]" {
+		return true, nil
+	}
+	event := gjson.Parse(data)
+
+	switch upstreamType {
+	case "claude":
+		switch event.Get("type").String() {
+		case "error":
+			return false, errors.New(truncateForLog(data, 300))
+		case "content_block_delta", "message_delta", "message_stop":
+			return true, nil
+		}
+		return false, nil
+
+	case "responses":
+		eventType := event.Get("type").String()
+		switch {
+		case eventType == "error", eventType == "response.failed":
+			return false, errors.New(truncateForLog(data, 300))
+		case strings.HasSuffix(eventType, ".delta"), eventType == "response.output_item.added",
+			eventType == "response.completed", eventType == "response.incomplete":
+			return true, nil
+		}
+		return false, nil
+
+	case "gemini":
+		if errResult := event.Get("error"); errResult.Exists() {
+			return false, errors.New(truncateForLog(data, 300))
+		}
+		candidate := event.Get("candidates.0")
+		return len(candidate.Get("content.parts").Array()) > 0 || candidate.Get("finishReason").String() != "", nil
+
+	case "openai":
+		if errResult := event.Get("error"); errResult.Exists() && errResult.Type != gjson.Null {
+			return false, errors.New(truncateForLog(data, 300))
+		}
+		choice := event.Get("choices.0")
+		if choice.Get("finish_reason").String() != "" {
+			return true, nil
+		}
+		delta := choice.Get("delta")
+		return delta.Get("content").String() != "" || delta.Get("reasoning_content").String() != "" ||
+			delta.Get("tool_calls").Exists(), nil
+	}
+
+	// 未知上游类型：任意数据即视为内容，不做前置缓冲
+	return true, nil
+}
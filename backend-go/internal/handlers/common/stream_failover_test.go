@@ -0,0 +1,141 @@
+package common
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newPreludeTestContext() *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/messages", nil)
+	return c
+}
+
+func feedStream(events []string, streamErr error, closeEvents bool) (chan string, chan error) {
+	eventChan := make(chan string, len(events)+1)
+	errChan := make(chan error, 1)
+	for _, e := range events {
+		eventChan <- e
+	}
+	if streamErr != nil {
+		errChan <- streamErr
+	}
+	if closeEvents {
+		close(eventChan)
+	}
+	return eventChan, errChan
+}
+
+const (
+	preludeMessageStart = "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\"}}\n\n"
+	preludeBlockStart   = "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n"
+	preludeTextDelta    = "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"hi\"}}\n\n"
+	preludeMessageStop  = "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+	preludeErrorEvent   = "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"
+)
+
+func TestAwaitStreamPrelude(t *testing.T) {
+	tests := []struct {
+		name        string
+		events      []string
+		streamErr   error
+		closeEvents bool
+		wantEvents  int
+		wantEnded   bool
+		wantPrelude bool
+	}{
+		{"首个内容到达后提交", []string{preludeMessageStart, preludeBlockStart, preludeTextDelta}, nil, false, 3, false, false},
+		{"内容前收到错误事件", []string{preludeMessageStart, preludeErrorEvent}, nil, false, 0, false, true},
+		{"内容前上游断开", []string{preludeMessageStart}, nil, true, 0, false, true},
+		{"内容前流错误", []string{preludeMessageStart}, errors.New("unexpected EOF"), false, 0, false, true},
+		{"空响应正常结束", []string{preludeMessageStart, preludeMessageStop}, nil, true, 2, true, false},
+		{"内容前超时", []string{preludeMessageStart}, nil, false, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventChan, errChan := feedStream(tt.events, tt.streamErr, tt.closeEvents)
+			events, ended, err := awaitStreamPrelude(newPreludeTestContext(), eventChan, errChan, 50*time.Millisecond)
+
+			if got := IsStreamPreludeError(err); got != tt.wantPrelude {
+				t.Fatalf("IsStreamPreludeError = %v, want %v (err=%v)", got, tt.wantPrelude, err)
+			}
+			if !tt.wantPrelude && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(events) != tt.wantEvents || ended != tt.wantEnded {
+				t.Errorf("events=%d ended=%v, want events=%d ended=%v", len(events), ended, tt.wantEvents, tt.wantEnded)
+			}
+		})
+	}
+}
+
+// blockingBody 返回预设数据后阻塞，模拟上游卡死
+type blockingBody struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (b *blockingBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		<-b.closed
+		return 0, errors.New("closed")
+	}
+	return n, err
+}
+
+func (b *blockingBody) Close() error {
+	close(b.closed)
+	return nil
+}
+
+func TestAwaitSSEPrelude(t *testing.T) {
+	tests := []struct {
+		name         string
+		upstreamType string
+		stream       string
+		block        bool
+		wantPrelude  bool
+	}{
+		{"Responses 首个内容", "responses", "event: response.created\ndata: {\"type\":\"response.created\"}\n\nevent: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"hi\"}\n\n", false, false},
+		{"Responses 内容前失败", "responses", "data: {\"type\":\"response.created\"}\n\ndata: {\"type\":\"response.failed\",\"response\":{\"error\":{\"message\":\"boom\"}}}\n\n", false, true},
+		{"Gemini 错误负载", "gemini", "data: {\"error\":{\"code\":503,\"status\":\"UNAVAILABLE\"}}\n\n", false, true},
+		{"Gemini 首个内容", "gemini", "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"hi\"}]}}]}\n\n", false, false},
+		{"OpenAI 仅 role 后断开", "openai", "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"\"}}]}\n\n", false, true},
+		{"OpenAI 空响应正常结束", "openai", "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n", false, false},
+		{"Claude 内容前超时", "claude", "data: {\"type\":\"message_start\"}\n\n", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.ReadCloser = io.NopCloser(strings.NewReader(tt.stream))
+			if tt.block {
+				body = &blockingBody{Reader: strings.NewReader(tt.stream), closed: make(chan struct{})}
+			}
+			replay, err := AwaitSSEPrelude(newPreludeTestContext(), body, tt.upstreamType, 50*time.Millisecond)
+			if tt.block {
+				body.Close()
+			}
+
+			if got := IsStreamPreludeError(err); got != tt.wantPrelude {
+				t.Fatalf("IsStreamPreludeError = %v, want %v (err=%v)", got, tt.wantPrelude, err)
+			}
+			if tt.wantPrelude {
+				return
+			}
+			// 已缓冲的数据需完整重放
+			data, _ := io.ReadAll(replay)
+			if string(data) != tt.stream {
+				t.Errorf("replayed stream = %q, want %q", data, tt.stream)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrStreamIdleTimeout 上游流式响应在中途长时间未发送任何数据
+var ErrStreamIdleTimeout = errors.New("上游流式响应空闲超时")
+
+// IsStreamIdleTimeout 判断错误是否为流式空闲超时
+func IsStreamIdleTimeout(err error) bool {
+	return errors.Is(err, ErrStreamIdleTimeout)
+}
+
+// 下游心跳内容：Messages 使用 Claude 原生 ping 事件，其余接口使用 SSE 注释（客户端解析时会忽略）
+const (
+	ClaudePingHeartbeat = "event: ping\ndata: {\"type\": \"ping\"}\n\n"
+	SSECommentHeartbeat = ": ping\n\n"
+)
+
+// idleTimeoutBody 为流式响应体增加相邻数据块之间的空闲超时
+// GetStreamClient 不设置整体超时，上游在流中途停止发送时读取会一直阻塞；
+// 超时后关闭底层连接，使阻塞的 Read 返回 ErrStreamIdleTimeout
+type idleTimeoutBody struct {
+	body     io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut atomic.Bool
+}
+
+// WrapStreamIdleTimeout 为流式响应体包装空闲超时（timeout<=0 时原样返回）
+func WrapStreamIdleTimeout(body io.ReadCloser, timeout time.Duration) io.ReadCloser {
+	if body == nil || timeout <= 0 {
+		return body
+	}
+	b := &idleTimeoutBody{body: body, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.timedOut.Store(true)
+		body.Close()
+	})
+	return b
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if b.timedOut.Load() {
+		return n, fmt.Errorf("%w（%s 内未收到数据）", ErrStreamIdleTimeout, b.timeout)
+	}
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.body.Close()
+}
+
+// heartbeatWriter 记录最近一次写出时间，并与心跳 goroutine 串行化写操作
+type heartbeatWriter struct {
+	gin.ResponseWriter
+	mu        sync.Mutex
+	lastWrite time.Time
+	// beforeBeat 首次写出心跳前调用（前置阶段用于补写 SSE 响应头），调用后置空
+	beforeBeat func(w gin.ResponseWriter)
+}
+
+func (w *heartbeatWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastWrite = time.Now()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *heartbeatWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastWrite = time.Now()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *heartbeatWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ResponseWriter.Flush()
+}
+
+// beatIfIdle 距上次写出已超过 interval 时写出心跳
+func (w *heartbeatWriter) beatIfIdle(interval time.Duration, payload string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if time.Since(w.lastWrite) < interval {
+		return
+	}
+	w.lastWrite = time.Now()
+	if w.beforeBeat != nil {
+		w.beforeBeat(w.ResponseWriter)
+		w.beforeBeat = nil
+	}
+	if _, err := w.ResponseWriter.WriteString(payload); err == nil {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// StartStreamHeartbeat 在上游长时间静默时向客户端注入心跳，避免企业代理/负载均衡器切断长思考流
+// 需在响应头写出后调用；调用后应通过 c.Writer 写出数据（会替换为带锁的包装），
+// 返回的 stop 函数会等待心跳 goroutine 退出并恢复原始 Writer。interval<=0 时不启用
+func StartStreamHeartbeat(c *gin.Context, interval time.Duration, payload string) (stop func()) {
+	return startHeartbeat(c, interval, payload, nil)
+}
+
+// StartPreludeHeartbeat 在流式 failover 等待首个内容期间注入心跳，避免长时间静默的思考阶段被客户端链路切断
+// 首次心跳时才写出 SSE 响应头：上游在 interval 内给出内容或失败时不会写出任何数据，仍可无感切换 Key/渠道；
+// 已写出心跳后的失败仍会切换，但最终的错误响应需通过 WriteErrorJSON/WriteErrorData 以 SSE 事件写出。
+// 调用方在前置阶段结束后、写出响应前应调用 stop。interval<=0 时不启用
+func StartPreludeHeartbeat(c *gin.Context, interval time.Duration, payload string) (stop func()) {
+	return startHeartbeat(c, interval, payload, func(w gin.ResponseWriter) {
+		if w.Written() {
+			return
+		}
+		header := w.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(200)
+	})
+}
+
+func startHeartbeat(c *gin.Context, interval time.Duration, payload string, beforeBeat func(w gin.ResponseWriter)) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	original := c.Writer
+	hw := &heartbeatWriter{ResponseWriter: original, lastWrite: time.Now(), beforeBeat: beforeBeat}
+	c.Writer = hw
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// 检查频率高于心跳间隔，确保静默时间不会明显超过 interval
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		ctxDone := c.Request.Context().Done()
+		for {
+			select {
+			case <-ticker.C:
+				hw.beatIfIdle(interval, payload)
+			case <-ctxDone:
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+			c.Writer = original
+		})
+	}
+}
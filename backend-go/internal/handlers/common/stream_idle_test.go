@@ -0,0 +1,114 @@
+package common
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWrapStreamIdleTimeout(t *testing.T) {
+	pr, pw := io.Pipe()
+	body := WrapStreamIdleTimeout(pr, 50*time.Millisecond)
+	defer body.Close()
+
+	go func() {
+		pw.Write([]byte("data: 1\n\n"))
+		// 之后不再发送任何数据，模拟上游中途卡死
+	}()
+
+	buf := make([]byte, 64)
+	n, err := body.Read(buf)
+	if err != nil || string(buf[:n]) != "data: 1\n\n" {
+		t.Fatalf("first read = %q, %v", buf[:n], err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := body.Read(buf)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !IsStreamIdleTimeout(err) {
+			t.Fatalf("expected idle timeout, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("read did not time out")
+	}
+}
+
+func TestWrapStreamIdleTimeout_Disabled(t *testing.T) {
+	body := io.NopCloser(strings.NewReader("x"))
+	if got := WrapStreamIdleTimeout(body, 0); got != body {
+		t.Fatal("timeout<=0 should return body unchanged")
+	}
+}
+
+func TestStartStreamHeartbeat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("POST", "/v1/responses", nil)
+	original := c.Writer
+
+	stop := StartStreamHeartbeat(c, 40*time.Millisecond, SSECommentHeartbeat)
+	c.Writer.WriteString("data: 1\n\n")
+	time.Sleep(150 * time.Millisecond)
+	stop()
+
+	if c.Writer != original {
+		t.Fatal("stop should restore original writer")
+	}
+	body := recorder.Body.String()
+	if !strings.HasPrefix(body, "data: 1\n\n") || !strings.Contains(body, SSECommentHeartbeat) {
+		t.Fatalf("unexpected body: %q", body)
+	}
+}
+
+func TestStartPreludeHeartbeat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("静默前置阶段写出响应头和心跳，失败后以 SSE 事件返回错误", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest("POST", "/v1/messages", nil)
+
+		stop := StartPreludeHeartbeat(c, 40*time.Millisecond, ClaudePingHeartbeat)
+		time.Sleep(150 * time.Millisecond)
+		stop()
+
+		if recorder.Code != 200 || recorder.Header().Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("status=%d content-type=%q", recorder.Code, recorder.Header().Get("Content-Type"))
+		}
+		if !strings.HasPrefix(recorder.Body.String(), ClaudePingHeartbeat) {
+			t.Fatalf("unexpected body: %q", recorder.Body.String())
+		}
+
+		WriteErrorJSON(c, 502, gin.H{"type": "error", "error": gin.H{"message": "all failed"}})
+		if !strings.HasSuffix(recorder.Body.String(), "event: error\ndata: {\"error\":{\"message\":\"all failed\"},\"type\":\"error\"}\n\n") {
+			t.Fatalf("error should be written as SSE event: %q", recorder.Body.String())
+		}
+	})
+
+	t.Run("间隔内结束前置阶段时不写出任何数据", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest("POST", "/v1/messages", nil)
+
+		stop := StartPreludeHeartbeat(c, time.Second, ClaudePingHeartbeat)
+		stop()
+		if c.Writer.Written() {
+			t.Fatal("no data should be written before the first heartbeat")
+		}
+
+		WriteErrorData(c, 502, []byte(`{"error":"upstream"}`))
+		if recorder.Code != 502 || recorder.Body.String() != `{"error":"upstream"}` {
+			t.Fatalf("status=%d body=%q", recorder.Code, recorder.Body.String())
+		}
+	})
+}
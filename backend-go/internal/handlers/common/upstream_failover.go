@@ -8,9 +8,14 @@
 	"io"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/BenedictKing/claude-proxy/internal/alert"
 	"github.com/BenedictKing/claude-proxy/internal/config"
 	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/quota"
+	"github.com/BenedictKing/claude-proxy/internal/requestlog"
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
@@ -83,6 +88,27 @@ func TryUpstreamWithAllKeys(
 	var lastFailoverError *FailoverError
 	deprioritizeCandidates := make(map[string]bool)
 
+	// 请求审计日志：记录每次上游尝试（未启用时为 no-op）
+	tracker := requestlog.FromContext(c)
+	tracker.SetStream(isStream)
+	recordAttempt := func(baseURL, apiKey, model string, statusCode int, attemptErr error, startedAt time.Time) {
+		if tracker == nil {
+			return
+		}
+		a := requestlog.Attempt{
+			Channel:    upstream.Name,
+			BaseURL:    baseURL,
+			KeyMask:    utils.MaskAPIKey(apiKey),
+			Model:      model,
+			StatusCode: statusCode,
+			DurationMs: time.Since(startedAt).Milliseconds(),
+		}
+		if attemptErr != nil {
+			a.Error = attemptErr.Error()
+		}
+		tracker.AddAttempt(a)
+	}
+
 	// 强制探测模式：基于本次优先尝试的 BaseURL 判断（避免 BaseURL/BaseURLs 不一致导致误判）
 	forceProbeMode := AreAllKeysSuspended(metricsManager, urlResults[0].URL, upstream.APIKeys)
 	if forceProbeMode {
@@ -120,9 +146,11 @@ func TryUpstreamWithAllKeys(
 			upstreamCopy := upstream.Clone()
 			upstreamCopy.BaseURL = currentBaseURL
 
+			attemptStart := time.Now()
 			req, err := buildRequest(c, upstreamCopy, apiKey)
 			if err != nil {
 				lastError = err
+				recordAttempt(currentBaseURL, apiKey, "", 0, err, attemptStart)
 				failedKeys[apiKey] = true
 				channelScheduler.RecordFailure(currentBaseURL, apiKey, kind)
 				continue
@@ -132,11 +160,21 @@ func TryUpstreamWithAllKeys(
 			channelScheduler.RecordRequestStart(currentBaseURL, apiKey, kind)
 
 			// TCP 建连开始即计数：将活跃度统计提前到发起上游请求之前
-			requestID := metricsManager.RecordRequestConnected(currentBaseURL, apiKey)
+			requestedModel := GetRequestModel(c)
+			redirectedModel := requestedModel
+			if requestedModel != "" {
+				redirectedModel = config.RedirectModel(requestedModel, upstreamCopy)
+			}
+			requestID := metricsManager.RecordRequestConnectedWithMeta(currentBaseURL, apiKey, metrics.RequestMeta{
+				ClientID:       middleware.GetClientID(c),
+				RequestedModel: requestedModel,
+				Model:          redirectedModel,
+			})
 
 			resp, err := SendRequest(req, upstream, envCfg, isStream, apiType)
 			if err != nil {
 				lastError = err
+				recordAttempt(currentBaseURL, apiKey, redirectedModel, 0, err, attemptStart)
 				// 区分客户端取消和真实渠道故障（统一口径）
 				if isClientSideError(err) {
 					// 客户端取消：不计入失败，不触发 failover
@@ -165,6 +203,7 @@ func TryUpstreamWithAllKeys(
 				shouldFailover, isQuotaRelated := ShouldRetryWithNextKey(resp.StatusCode, respBodyBytes, cfgManager.GetFuzzyModeEnabled(), apiType)
 				if shouldFailover {
 					lastError = fmt.Errorf("上游错误: %d", resp.StatusCode)
+					recordAttempt(currentBaseURL, apiKey, redirectedModel, resp.StatusCode, lastError, attemptStart)
 					failedKeys[apiKey] = true
 					cfgManager.MarkKeyAsFailed(apiKey, apiType)
 					metricsManager.RecordRequestFinalizeFailure(currentBaseURL, apiKey, requestID)
@@ -179,16 +218,29 @@ func TryUpstreamWithAllKeys(
 						Body:   respBodyBytes,
 					}
 
+					disableIfPermanentKeyError(cfgManager, kind, apiType, upstream.Name, currentBaseURL, apiKey, resp.StatusCode, respBodyBytes)
+
 					if isQuotaRelated {
 						deprioritizeCandidates[apiKey] = true
+						alert.Emit(alert.Event{
+							Type:    config.AlertEventQuotaExhausted,
+							Level:   alert.LevelWarning,
+							Kind:    string(kind),
+							Channel: upstream.Name,
+							BaseURL: currentBaseURL,
+							KeyMask: utils.MaskAPIKey(apiKey),
+							Message: fmt.Sprintf("Key 额度不足或被限流（状态: %d）", resp.StatusCode),
+							Details: map[string]interface{}{"statusCode": resp.StatusCode},
+						})
 					}
 					continue
 				}
 
 				// 非 failover 错误，记录失败指标后返回（请求已处理）
+				recordAttempt(currentBaseURL, apiKey, redirectedModel, resp.StatusCode, fmt.Errorf("上游错误: %d", resp.StatusCode), attemptStart)
 				metricsManager.RecordRequestFinalizeFailure(currentBaseURL, apiKey, requestID)
 				channelScheduler.RecordRequestEnd(currentBaseURL, apiKey, kind)
-				c.Data(resp.StatusCode, "application/json", respBodyBytes)
+				WriteErrorData(c, resp.StatusCode, respBodyBytes)
 				return true, "", 0, nil, nil, nil
 			}
 
@@ -204,8 +256,29 @@ func TryUpstreamWithAllKeys(
 			}
 
 			usage, err = handleSuccess(c, resp, upstreamCopy, apiKey)
+			if err != nil && IsStreamPreludeError(err) {
+				// 流在首个内容前失败：尚未向客户端写出数据，按可故障转移错误处理
+				lastError = err
+				recordAttempt(currentBaseURL, apiKey, redirectedModel, resp.StatusCode, err, attemptStart)
+				failedKeys[apiKey] = true
+				cfgManager.MarkKeyAsFailed(apiKey, apiType)
+				metricsManager.RecordRequestFinalizeFailure(currentBaseURL, apiKey, requestID)
+				channelScheduler.RecordRequestEnd(currentBaseURL, apiKey, kind)
+				if markURLFailure != nil {
+					markURLFailure(currentBaseURL)
+				}
+				log.Printf("[%s-Stream] 警告: %v，尝试下一个密钥", apiType, err)
+
+				lastFailoverError = &FailoverError{
+					Status: http.StatusBadGateway,
+					Body:   streamPreludeErrorBody(err),
+				}
+				continue
+			}
 			if err != nil {
 				lastError = err
+				recordAttempt(currentBaseURL, apiKey, redirectedModel, resp.StatusCode, err, attemptStart)
+				tracker.SetResult(redirectedModel, usage, cfgManager.CalculateCost(redirectedModel, usage))
 				// 区分客户端错误和渠道故障
 				if isClientSideError(err) {
 					// 客户端取消/断开：计入总请求数但不计入失败
@@ -219,10 +292,17 @@ func TryUpstreamWithAllKeys(
 					channelScheduler.RecordRequestEnd(currentBaseURL, apiKey, kind)
 					log.Printf("[%s-Key] 警告: 响应处理失败: %v", apiType, err)
 				}
+				// 已产生的 usage（如流式中途断开）仍需计入客户端配额
+				quota.SettleFromContext(c, usage)
 				return true, "", 0, nil, usage, err
 			}
 
-			metricsManager.RecordRequestFinalizeSuccess(currentBaseURL, apiKey, requestID, usage)
+			// 按重定向后的实际模型计费
+			cost := cfgManager.CalculateCost(redirectedModel, usage)
+			metricsManager.RecordRequestFinalizeSuccessWithCost(currentBaseURL, apiKey, requestID, usage, cost)
+			recordAttempt(currentBaseURL, apiKey, redirectedModel, resp.StatusCode, nil, attemptStart)
+			tracker.SetResult(redirectedModel, usage, cost)
+			quota.SettleFromContext(c, usage)
 			channelScheduler.RecordRequestEnd(currentBaseURL, apiKey, kind)
 			return true, apiKey, originalIdx, nil, usage, nil
 		}
@@ -233,6 +313,17 @@ func TryUpstreamWithAllKeys(
 		}
 	}
 
+	if lastError != nil {
+		alert.Emit(alert.Event{
+			Type:    config.AlertEventChannelUnhealthy,
+			Level:   alert.LevelWarning,
+			Kind:    string(kind),
+			Channel: upstream.Name,
+			Message: fmt.Sprintf("渠道所有 Key 均请求失败: %v", lastError),
+			Details: map[string]interface{}{"keys": len(upstream.APIKeys), "baseUrls": len(urlResults)},
+		})
+	}
+
 	return false, "", 0, lastFailoverError, nil, lastError
 }
 
@@ -248,3 +339,34 @@ func BuildDefaultURLResults(urls []string) []warmup.URLLatencyResult {
 	}
 	return results
 }
+
+// disableIfPermanentKeyError 上游错误表明 Key 永久失效时，将其移入渠道的禁用列表，避免每次请求都重试
+func disableIfPermanentKeyError(cfgManager *config.ConfigManager, kind scheduler.ChannelKind, apiType, channelName, baseURL, apiKey string, statusCode int, body []byte) {
+	reason, message := ClassifyPermanentKeyError(statusCode, body, apiType)
+	if reason == "" {
+		return
+	}
+	affected, err := cfgManager.DisableAPIKey(string(kind), baseURL, config.DisabledAPIKey{
+		Key:        apiKey,
+		Reason:     reason,
+		Message:    message,
+		StatusCode: statusCode,
+	})
+	if err != nil {
+		log.Printf("[%s-Key] 警告: 禁用永久失效的 Key %s 失败: %v", apiType, utils.MaskAPIKey(apiKey), err)
+		return
+	}
+	if len(affected) == 0 {
+		return
+	}
+	alert.Emit(alert.Event{
+		Type:    config.AlertEventKeyDisabled,
+		Level:   alert.LevelWarning,
+		Kind:    string(kind),
+		Channel: channelName,
+		BaseURL: baseURL,
+		KeyMask: utils.MaskAPIKey(apiKey),
+		Message: fmt.Sprintf("Key 因永久性错误已自动禁用（%s）: %s", reason, message),
+		Details: map[string]interface{}{"reason": reason, "statusCode": statusCode, "channels": affected},
+	})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+var channelKinds = []scheduler.ChannelKind{
+	scheduler.ChannelKindMessages,
+	scheduler.ChannelKindResponses,
+	scheduler.ChannelKindGemini,
+	scheduler.ChannelKindChat,
+}
+
+// ListConfigBackups 列出配置备份（按时间倒序）
+func ListConfigBackups(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		backups, err := cfgManager.ListBackups()
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to list backups"})
+			return
+		}
+		c.JSON(200, gin.H{"backups": backups})
+	}
+}
+
+// DiffConfigBackup 返回备份与当前配置的结构化差异（Key 已掩码）
+func DiffConfigBackup(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		diff, err := cfgManager.DiffBackup(c.Param("name"))
+		if err != nil {
+			writeBackupError(c, err)
+			return
+		}
+		c.JSON(200, diff)
+	}
+}
+
+// RestoreConfigBackup 将备份恢复为当前配置，并清理被移除渠道的调度状态
+func RestoreConfigBackup(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		diff, err := cfgManager.DiffBackup(name)
+		if err != nil {
+			writeBackupError(c, err)
+			return
+		}
+
+		before := cfgManager.GetConfig()
+		if err := cfgManager.RestoreBackup(name); err != nil {
+			writeBackupError(c, err)
+			return
+		}
+		after := cfgManager.GetConfig()
+
+		deleteRemovedChannelMetrics(sch, &before, &after)
+
+		c.JSON(200, gin.H{
+			"success": true,
+			"backup":  name,
+			"changes": diff,
+		})
+	}
+}
+
+// deleteRemovedChannelMetrics 清理整体替换配置后已不存在的渠道的调度指标
+func deleteRemovedChannelMetrics(sch *scheduler.ChannelScheduler, before, after *config.Config) {
+	for _, kind := range channelKinds {
+		remaining := make(map[string]bool)
+		for _, up := range after.UpstreamsForKind(string(kind)) {
+			remaining[up.ID] = true
+		}
+		previous := before.UpstreamsForKind(string(kind))
+		for i := range previous {
+			if !remaining[previous[i].ID] {
+				sch.DeleteChannelMetrics(&previous[i], kind)
+			}
+		}
+	}
+}
+
+// writeBackupError 按错误类型返回备份接口的错误响应
+func writeBackupError(c *gin.Context, err error) {
+	if errors.Is(err, config.ErrBackupNotFound) {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := err.(*config.ConfigError); ok {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(500, gin.H{"error": err.Error()})
+}
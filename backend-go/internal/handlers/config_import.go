@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// maxConfigImportSize 导入文档大小上限
+const maxConfigImportSize = 10 << 20
+
+// ExportConfig 导出声明式配置（?format=json|yaml）
+// 默认以掩码导出敏感字段，?redact=false 导出明文（启用 Key 加密时无效，始终掩码）
+func ExportConfig(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := config.NormalizeConfigFormat(c.DefaultQuery("format", config.ConfigFormatJSON))
+		if format == "" {
+			c.JSON(400, gin.H{"error": "Invalid format, expected json or yaml"})
+			return
+		}
+		redact, err := strconv.ParseBool(c.DefaultQuery("redact", "true"))
+		if err != nil {
+			redact = true
+		}
+
+		data, err := config.MarshalConfigDocument(cfgManager.ExportConfig(redact), format)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to export config"})
+			return
+		}
+
+		contentType := "application/json; charset=utf-8"
+		if format == config.ConfigFormatYAML {
+			contentType = "application/yaml; charset=utf-8"
+		}
+		c.Header("Content-Disposition", "attachment; filename=config-export."+format)
+		c.Data(200, contentType, data)
+	}
+}
+
+// ImportConfig 导入声明式配置（JSON/YAML）
+// ?mode=merge|replace 选择合并或整体替换，?dryRun=true 仅校验并返回计划变更
+func ImportConfig(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.Query("format")
+		if format == "" {
+			format = c.ContentType()
+		}
+		dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+
+		data, err := common.ReadRequestBody(c, maxConfigImportSize)
+		if err != nil {
+			return
+		}
+
+		before := cfgManager.GetConfig()
+		result, err := cfgManager.ImportConfig(data, config.NormalizeConfigFormat(format), config.ImportOptions{
+			Mode:   c.Query("mode"),
+			DryRun: dryRun,
+		})
+		if err != nil {
+			var validationErr *config.ValidationError
+			if errors.As(err, &validationErr) {
+				c.JSON(400, gin.H{"error": "Config validation failed", "errors": validationErr.Errors})
+				return
+			}
+			if _, ok := err.(*config.ConfigError); ok {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		if result.Applied {
+			after := cfgManager.GetConfig()
+			deleteRemovedChannelMetrics(sch, &before, &after)
+		}
+		c.JSON(200, result)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// disabledKeyKinds 列出禁用 Key 时遍历的入口类型
+var disabledKeyKinds = []scheduler.ChannelKind{
+	scheduler.ChannelKindMessages,
+	scheduler.ChannelKindResponses,
+	scheduler.ChannelKindGemini,
+	scheduler.ChannelKindChat,
+}
+
+// ListDisabledKeys 列出所有入口类型下被自动禁用的 Key
+// GET /api/disabled-keys
+func ListDisabledKeys(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := cfgManager.GetConfig()
+		redact := cfgManager.KeyEncryptionEnabled()
+		result := []gin.H{}
+		for _, kind := range disabledKeyKinds {
+			for i, upstream := range cfg.UpstreamsForKind(string(kind)) {
+				for _, dk := range upstream.DisabledAPIKeys {
+					key := dk.Key
+					if redact {
+						key = utils.MaskAPIKey(dk.Key)
+					}
+					result = append(result, gin.H{
+						"kind":         kind,
+						"channelIndex": i,
+						"channelId":    upstream.ID,
+						"channelName":  upstream.Name,
+						"key":          key,
+						"keyMask":      utils.MaskAPIKey(dk.Key),
+						"reason":       dk.Reason,
+						"message":      dk.Message,
+						"statusCode":   dk.StatusCode,
+						"disabledAt":   dk.DisabledAt,
+					})
+				}
+			}
+		}
+		c.JSON(200, gin.H{"disabledKeys": result})
+	}
+}
+
+// EnableDisabledKey 将被禁用的 Key 重新加入渠道轮换，并清除其熔断状态
+// POST /api/{kind}/channels/:id/disabled-keys/:apiKey/enable
+func EnableDisabledKey(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler, kind scheduler.ChannelKind) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, apiKey, ok := parseDisabledKeyParams(c, cfgManager, kind)
+		if !ok {
+			return
+		}
+
+		if err := cfgManager.EnableDisabledAPIKey(string(kind), id, apiKey); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		cfg := cfgManager.GetConfig()
+		if index := cfg.ChannelIndexByID(string(kind), id); index >= 0 {
+			metricsManager := sch.GetMetricsManager(kind)
+			for _, baseURL := range cfg.UpstreamsForKind(string(kind))[index].GetAllBaseURLs() {
+				metricsManager.ResetKeyFailureState(baseURL, apiKey)
+			}
+		}
+
+		c.JSON(200, gin.H{"message": "API密钥已重新启用"})
+	}
+}
+
+// DiscardDisabledKey 删除被禁用的 Key（移入历史列表，保留统计）
+// DELETE /api/{kind}/channels/:id/disabled-keys/:apiKey
+func DiscardDisabledKey(cfgManager *config.ConfigManager, kind scheduler.ChannelKind) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, apiKey, ok := parseDisabledKeyParams(c, cfgManager, kind)
+		if !ok {
+			return
+		}
+
+		if err := cfgManager.DiscardDisabledAPIKey(string(kind), id, apiKey); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, gin.H{"message": "被禁用的API密钥已删除"})
+	}
+}
+
+func parseDisabledKeyParams(c *gin.Context, cfgManager *config.ConfigManager, kind scheduler.ChannelKind) (string, string, bool) {
+	id, ok := common.ResolveChannelParam(c, cfgManager, kind)
+	if !ok {
+		return "", "", false
+	}
+	apiKey, err := cfgManager.ResolveAPIKeyRef(string(kind), id, c.Param("apiKey"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return "", "", false
+	}
+	if apiKey == "" {
+		c.JSON(400, gin.H{"error": "API key is required"})
+		return "", "", false
+	}
+	return id, apiKey, true
+}
@@ -0,0 +1,236 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// 除 generateContent/streamGenerateContent 外支持的模型动作
+const (
+	actionCountTokens        = "countTokens"
+	actionEmbedContent       = "embedContent"
+	actionBatchEmbedContents = "batchEmbedContents"
+)
+
+// extractModelAction 从 URL 参数提取动作名称
+// 输入: "gemini-2.0-flash:countTokens"，输出: "countTokens"；无动作时返回空字符串
+func extractModelAction(param string) string {
+	if idx := strings.Index(param, ":"); idx > 0 {
+		return param[idx+1:]
+	}
+	return ""
+}
+
+// isModelAction 判断是否为透传型模型动作（countTokens/embedContent/batchEmbedContents）
+func isModelAction(action string) bool {
+	switch action {
+	case actionCountTokens, actionEmbedContent, actionBatchEmbedContents:
+		return true
+	}
+	return false
+}
+
+// isGeminiUpstream 判断渠道是否为原生 Gemini 协议（未知类型与 buildProviderRequest 一致按 Gemini 处理）
+func isGeminiUpstream(upstream *config.UpstreamConfig) bool {
+	return upstream.ServiceType != "claude" && upstream.ServiceType != "openai"
+}
+
+// handleModelAction 通过 Gemini 渠道调度器处理 countTokens/embedContent/batchEmbedContents
+// Gemini 渠道直接透传；claude/openai 渠道的 countTokens 使用本地估算，embed 动作跳过该渠道
+func handleModelAction(
+	c *gin.Context,
+	envCfg *config.EnvConfig,
+	cfgManager *config.ConfigManager,
+	channelScheduler *scheduler.ChannelScheduler,
+	bodyBytes []byte,
+	model string,
+	action string,
+	userID string,
+) {
+	metricsManager := channelScheduler.GetGeminiMetricsManager()
+	common.HandleMultiChannelFailover(
+		c,
+		envCfg,
+		channelScheduler,
+		scheduler.ChannelKindGemini,
+		"Gemini",
+		userID,
+		func(selection *scheduler.SelectionResult) common.MultiChannelAttemptResult {
+			upstream := selection.Upstream
+			if upstream == nil {
+				return common.MultiChannelAttemptResult{}
+			}
+
+			if !isGeminiUpstream(upstream) {
+				if action == actionCountTokens {
+					writeLocalCountTokens(c, envCfg, bodyBytes, model, upstream.Name)
+					return common.MultiChannelAttemptResult{Handled: true}
+				}
+				return common.MultiChannelAttemptResult{
+					LastError: fmt.Errorf("渠道 [%d] %s (%s) 不支持 %s", selection.ChannelIndex, upstream.Name, upstream.ServiceType, action),
+				}
+			}
+
+			baseURLs := upstream.GetAllBaseURLs()
+			sortedURLResults := channelScheduler.GetSortedURLsForChannel(scheduler.ChannelKindGemini, upstream.ID, baseURLs)
+
+			handled, successKey, successBaseURLIdx, failoverErr, usage, lastErr := common.TryUpstreamWithAllKeys(
+				c,
+				envCfg,
+				cfgManager,
+				channelScheduler,
+				scheduler.ChannelKindGemini,
+				"Gemini",
+				metricsManager,
+				upstream,
+				sortedURLResults,
+				bodyBytes,
+				false,
+				func(upstream *config.UpstreamConfig, failedKeys map[string]bool) (string, error) {
+					return channelScheduler.NextAPIKey(upstream, failedKeys, scheduler.ChannelKindGemini)
+				},
+				func(c *gin.Context, upstreamCopy *config.UpstreamConfig, apiKey string) (*http.Request, error) {
+					return buildActionRequest(c, upstreamCopy, upstreamCopy.BaseURL, apiKey, bodyBytes, model, action)
+				},
+				func(apiKey string) {
+					_ = cfgManager.DeprioritizeAPIKey(string(scheduler.ChannelKindGemini), upstream.ID, apiKey)
+				},
+				func(url string) {
+					channelScheduler.MarkURLFailure(scheduler.ChannelKindGemini, upstream.ID, url)
+				},
+				func(url string) {
+					channelScheduler.MarkURLSuccess(scheduler.ChannelKindGemini, upstream.ID, url)
+				},
+				func(c *gin.Context, resp *http.Response, upstreamCopy *config.UpstreamConfig, apiKey string) (*types.Usage, error) {
+					return handleActionSuccess(c, resp)
+				},
+			)
+
+			return common.MultiChannelAttemptResult{
+				Handled:           handled,
+				Attempted:         true,
+				SuccessKey:        successKey,
+				SuccessBaseURLIdx: successBaseURLIdx,
+				FailoverError:     failoverErr,
+				Usage:             usage,
+				LastError:         lastErr,
+			}
+		},
+		nil,
+		func(ctx *gin.Context, failoverErr *common.FailoverError, lastError error) {
+			handleAllChannelsFailed(ctx, failoverErr, lastError)
+		},
+	)
+}
+
+// buildActionRequest 构建透传到 Gemini 上游的模型动作请求（应用模型映射）
+func buildActionRequest(
+	c *gin.Context,
+	upstream *config.UpstreamConfig,
+	baseURL string,
+	apiKey string,
+	bodyBytes []byte,
+	model string,
+	action string,
+) (*http.Request, error) {
+	mappedModel := config.RedirectModel(model, upstream)
+	requestBody := rewriteActionModel(bodyBytes, mappedModel, mappedModel != model)
+
+	url := fmt.Sprintf("%s/v1beta/models/%s:%s", strings.TrimRight(baseURL, "/"), mappedModel, action)
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, url, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = utils.PrepareUpstreamHeaders(c, req.URL.Host)
+	req.Header.Set("Content-Type", "application/json")
+	utils.SetGeminiAuthenticationHeader(req.Header, apiKey)
+	return req, nil
+}
+
+// rewriteActionModel 模型被映射时，同步改写请求体中内嵌的模型名
+// batchEmbedContents 的 requests[].model 与 countTokens 的 generateContentRequest.model 必须与 URL 中的模型一致
+func rewriteActionModel(bodyBytes []byte, mappedModel string, mapped bool) []byte {
+	if !mapped || len(bodyBytes) == 0 {
+		return bodyBytes
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		return bodyBytes
+	}
+
+	modelRef := "models/" + mappedModel
+	changed := false
+	if requests, ok := body["requests"].([]interface{}); ok {
+		for _, item := range requests {
+			if r, ok := item.(map[string]interface{}); ok {
+				if _, has := r["model"]; has {
+					r["model"] = modelRef
+					changed = true
+				}
+			}
+		}
+	}
+	if inner, ok := body["generateContentRequest"].(map[string]interface{}); ok {
+		if _, has := inner["model"]; has {
+			inner["model"] = modelRef
+			changed = true
+		}
+	}
+	if !changed {
+		return bodyBytes
+	}
+
+	rewritten, err := json.Marshal(body)
+	if err != nil {
+		return bodyBytes
+	}
+	return rewritten
+}
+
+// handleActionSuccess 透传上游模型动作响应
+func handleActionSuccess(c *gin.Context, resp *http.Response) (*types.Usage, error) {
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.JSON(500, types.GeminiError{
+			Error: types.GeminiErrorDetail{
+				Code:    500,
+				Message: "Failed to read response",
+				Status:  "INTERNAL",
+			},
+		})
+		return nil, err
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	c.Data(resp.StatusCode, contentType, bodyBytes)
+	return nil, nil
+}
+
+// writeLocalCountTokens 使用本地估算返回 countTokens 结果（用于 claude/openai 渠道）
+func writeLocalCountTokens(c *gin.Context, envCfg *config.EnvConfig, bodyBytes []byte, model, channelName string) {
+	totalTokens := utils.EstimateGeminiRequestTokens(bodyBytes)
+	c.JSON(200, gin.H{"totalTokens": totalTokens})
+
+	if envCfg.EnableResponseLogs {
+		log.Printf("[Gemini-Token] CountTokens本地估算: model=%s, channel=%s, totalTokens=%d", model, channelName, totalTokens)
+	}
+}
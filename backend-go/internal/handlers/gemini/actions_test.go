@@ -0,0 +1,173 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/warmup"
+	"github.com/gin-gonic/gin"
+)
+
+// newActionTestRouter 使用给定 Gemini 渠道配置搭建与 main.go 一致的 Gemini 路由
+func newActionTestRouter(t *testing.T, upstreams []config.UpstreamConfig) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	data, _ := json.Marshal(config.Config{GeminiUpstream: upstreams})
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	cfgManager, err := config.NewConfigManager(configFile)
+	if err != nil {
+		t.Fatalf("创建配置管理器失败: %v", err)
+	}
+	t.Cleanup(func() { cfgManager.Close() })
+
+	geminiMetrics := metrics.NewMetricsManager()
+	t.Cleanup(geminiMetrics.Stop)
+	sch := scheduler.NewChannelScheduler(cfgManager,
+		metrics.NewMetricsManager(), metrics.NewMetricsManager(), geminiMetrics, metrics.NewMetricsManager(),
+		session.NewTraceAffinityManager(), warmup.NewURLManager(30*time.Second, 3))
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret-key", MaxRequestBodySize: 1024 * 1024}
+	r := gin.New()
+	r.POST("/v1beta/models/*modelAction", Handler(envCfg, cfgManager, sch, nil))
+	r.GET("/v1beta/models", ModelsHandler(envCfg, cfgManager, sch))
+	r.GET("/v1beta/models/*modelAction", ModelDetailHandler(envCfg, cfgManager, sch))
+	return r
+}
+
+func doGeminiRequest(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "secret-key")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestModelActions_PassThroughWithFailover(t *testing.T) {
+	var gotPaths []string
+	var gotBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPaths = append(gotPaths, r.URL.Path)
+		if r.Header.Get("x-goog-api-key") != "good-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":401,"message":"API key not valid"}}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1beta/models/gemini-embedding-001:batchEmbedContents":
+			gotBody, _ = io.ReadAll(r.Body)
+			w.Write([]byte(`{"embeddings":[{"values":[0.1,0.2]}]}`))
+		case "/v1beta/models/gemini-2.0-flash:countTokens":
+			w.Write([]byte(`{"totalTokens":42}`))
+		case "/v1beta/models":
+			w.Write([]byte(`{"models":[{"name":"models/gemini-2.0-flash"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	r := newActionTestRouter(t, []config.UpstreamConfig{
+		{Name: "bad", ServiceType: "gemini", BaseURL: upstream.URL, APIKeys: []string{"bad-key"}, Status: "active", Priority: 1},
+		{
+			Name: "good", ServiceType: "gemini", BaseURL: upstream.URL, APIKeys: []string{"good-key"}, Status: "active", Priority: 2,
+			ModelMapping: map[string]string{"text-embedding-004": "gemini-embedding-001"},
+		},
+	})
+
+	w := doGeminiRequest(r, http.MethodPost, "/v1beta/models/text-embedding-004:batchEmbedContents",
+		`{"requests":[{"model":"models/text-embedding-004","content":{"parts":[{"text":"hi"}]}}]}`)
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte("embeddings")) {
+		t.Fatalf("batchEmbedContents = %d %s", w.Code, w.Body.String())
+	}
+	if !bytes.Contains(gotBody, []byte(`"models/gemini-embedding-001"`)) {
+		t.Fatalf("embedded model should follow model mapping: %s", gotBody)
+	}
+
+	w = doGeminiRequest(r, http.MethodPost, "/v1beta/models/gemini-2.0-flash:countTokens", `{"contents":[{"parts":[{"text":"hi"}]}]}`)
+	if w.Code != http.StatusOK || w.Body.String() != `{"totalTokens":42}` {
+		t.Fatalf("countTokens = %d %s", w.Code, w.Body.String())
+	}
+
+	w = doGeminiRequest(r, http.MethodGet, "/v1beta/models", "")
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte("models/gemini-2.0-flash")) {
+		t.Fatalf("list models = %d %s", w.Code, w.Body.String())
+	}
+	for _, p := range gotPaths {
+		if p == "/v1beta/models/gemini-2.0-flash:generateContent" {
+			t.Fatalf("model actions must not be sent as generateContent: %v", gotPaths)
+		}
+	}
+}
+
+func TestModelActions_NonGeminiChannels(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/models" {
+			w.Write([]byte(`{"data":[{"id":"claude-sonnet-4"}]}`))
+			return
+		}
+		t.Errorf("unexpected upstream request: %s", r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	r := newActionTestRouter(t, []config.UpstreamConfig{
+		{Name: "claude", ServiceType: "claude", BaseURL: upstream.URL, APIKeys: []string{"sk"}, Status: "active"},
+	})
+
+	// countTokens 使用本地估算
+	w := doGeminiRequest(r, http.MethodPost, "/v1beta/models/claude-sonnet-4:countTokens",
+		`{"contents":[{"role":"user","parts":[{"text":"Hello, how are you today?"}]}]}`)
+	var counted struct {
+		TotalTokens int `json:"totalTokens"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &counted) != nil || counted.TotalTokens <= 0 {
+		t.Fatalf("countTokens = %d %s", w.Code, w.Body.String())
+	}
+
+	// claude 渠道不支持 embedding
+	w = doGeminiRequest(r, http.MethodPost, "/v1beta/models/claude-sonnet-4:embedContent", `{"content":{"parts":[{"text":"hi"}]}}`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("embedContent on claude channel = %d %s, want 503", w.Code, w.Body.String())
+	}
+
+	// 模型列表转换为 Gemini 格式
+	w = doGeminiRequest(r, http.MethodGet, "/v1beta/models", "")
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"name":"models/claude-sonnet-4"`)) {
+		t.Fatalf("list models = %d %s", w.Code, w.Body.String())
+	}
+	w = doGeminiRequest(r, http.MethodGet, "/v1beta/models/claude-sonnet-4", "")
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"displayName":"claude-sonnet-4"`)) {
+		t.Fatalf("model detail = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRewriteActionModel(t *testing.T) {
+	body := []byte(`{"requests":[{"model":"models/a"},{"content":{}}]}`)
+	if got := rewriteActionModel(body, "b", false); !bytes.Equal(got, body) {
+		t.Fatalf("unmapped body should be untouched: %s", got)
+	}
+	got := rewriteActionModel(body, "b", true)
+	if !bytes.Contains(got, []byte(`"model":"models/b"`)) || bytes.Count(got, []byte(`"model"`)) != 1 {
+		t.Fatalf("rewriteActionModel = %s", got)
+	}
+	wrapped := rewriteActionModel([]byte(`{"generateContentRequest":{"model":"models/a","contents":[]}}`), "b", true)
+	if !bytes.Contains(wrapped, []byte(`"model":"models/b"`)) {
+		t.Fatalf("generateContentRequest model should be rewritten: %s", wrapped)
+	}
+}
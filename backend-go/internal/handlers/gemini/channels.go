@@ -4,11 +4,11 @@
 import (
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/gin-gonic/gin"
 )
@@ -24,12 +24,14 @@ func GetUpstreams(cfgManager *config.ConfigManager) gin.HandlerFunc {
 			priority := config.GetChannelPriority(&up, i)
 
 			upstreams[i] = gin.H{
+				"id":                          up.ID,
 				"index":                       i,
 				"name":                        up.Name,
 				"serviceType":                 up.ServiceType,
 				"baseUrl":                     up.BaseURL,
 				"baseUrls":                    up.BaseURLs,
-				"apiKeys":                     up.APIKeys,
+				"apiKeys":                     cfgManager.RedactAPIKeys(up.APIKeys),
+				"disabledApiKeys":             cfgManager.RedactDisabledAPIKeys(up.DisabledAPIKeys),
 				"description":                 up.Description,
 				"website":                     up.Website,
 				"insecureSkipVerify":          up.InsecureSkipVerify,
@@ -72,10 +74,8 @@ func AddUpstream(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // UpdateUpstream 更新 Gemini 上游
 func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindGemini)
+		if !ok {
 			return
 		}
 
@@ -84,6 +84,13 @@ func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
+		// 管理界面回传的已掩码 Key 还原为实际 Key
+		apiKeys, err := cfgManager.ResolveAPIKeyRefs(string(scheduler.ChannelKindGemini), id, updates.APIKeys)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		updates.APIKeys = apiKeys
 
 		shouldResetMetrics, err := cfgManager.UpdateGeminiUpstream(id, updates)
 		if err != nil {
@@ -103,10 +110,8 @@ func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 // DeleteUpstream 删除 Gemini 上游
 func DeleteUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindGemini)
+		if !ok {
 			return
 		}
 
@@ -130,10 +135,8 @@ func DeleteUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 // AddApiKey 添加 Gemini 渠道 API 密钥
 func AddApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindGemini)
+		if !ok {
 			return
 		}
 
@@ -146,7 +149,7 @@ func AddApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 		}
 
 		if err := cfgManager.AddGeminiAPIKey(id, req.APIKey); err != nil {
-			if strings.Contains(err.Error(), "无效的上游索引") {
+			if strings.Contains(err.Error(), "未找到渠道") {
 				c.JSON(404, gin.H{"error": "Upstream not found"})
 			} else if strings.Contains(err.Error(), "API密钥已存在") {
 				c.JSON(400, gin.H{"error": "API密钥已存在"})
@@ -166,21 +169,23 @@ func AddApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // DeleteApiKey 删除 Gemini 渠道 API 密钥
 func DeleteApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindGemini)
+		if !ok {
 			return
 		}
 
-		apiKey := c.Param("apiKey")
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindGemini), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 		if apiKey == "" {
 			c.JSON(400, gin.H{"error": "API key is required"})
 			return
 		}
 
 		if err := cfgManager.RemoveGeminiAPIKey(id, apiKey); err != nil {
-			if strings.Contains(err.Error(), "无效的上游索引") {
+			if strings.Contains(err.Error(), "未找到渠道") {
 				c.JSON(404, gin.H{"error": "Upstream not found"})
 			} else if strings.Contains(err.Error(), "API密钥不存在") {
 				c.JSON(404, gin.H{"error": "API key not found"})
@@ -199,8 +204,15 @@ func DeleteApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // MoveApiKeyToTop 将 Gemini 渠道 API 密钥移到最前面
 func MoveApiKeyToTop(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id, _ := strconv.Atoi(c.Param("id"))
-		apiKey := c.Param("apiKey")
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindGemini)
+		if !ok {
+			return
+		}
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindGemini), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 
 		if err := cfgManager.MoveGeminiAPIKeyToTop(id, apiKey); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
@@ -213,8 +225,15 @@ func MoveApiKeyToTop(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // MoveApiKeyToBottom 将 Gemini 渠道 API 密钥移到最后面
 func MoveApiKeyToBottom(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id, _ := strconv.Atoi(c.Param("id"))
-		apiKey := c.Param("apiKey")
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindGemini)
+		if !ok {
+			return
+		}
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindGemini), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 
 		if err := cfgManager.MoveGeminiAPIKeyToBottom(id, apiKey); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
@@ -250,10 +269,8 @@ func ReorderChannels(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // SetChannelStatus 设置 Gemini 渠道状态
 func SetChannelStatus(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid channel ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindGemini)
+		if !ok {
 			return
 		}
 
@@ -266,7 +283,7 @@ func SetChannelStatus(cfgManager *config.ConfigManager) gin.HandlerFunc {
 		}
 
 		if err := cfgManager.SetGeminiChannelStatus(id, req.Status); err != nil {
-			if strings.Contains(err.Error(), "无效的上游索引") {
+			if strings.Contains(err.Error(), "未找到渠道") {
 				c.JSON(404, gin.H{"error": "Channel not found"})
 			} else {
 				c.JSON(400, gin.H{"error": err.Error()})
@@ -285,10 +302,8 @@ func SetChannelStatus(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // SetChannelPromotion 设置 Gemini 渠道促销期
 func SetChannelPromotion(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid channel ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindGemini)
+		if !ok {
 			return
 		}
 
@@ -324,20 +339,19 @@ func SetChannelPromotion(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // PingChannel 测试 Gemini 渠道连通性
 func PingChannel(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid channel ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindGemini)
+		if !ok {
 			return
 		}
 
 		cfg := cfgManager.GetConfig()
-		if id < 0 || id >= len(cfg.GeminiUpstream) {
+		index := cfg.ChannelIndexByID(string(scheduler.ChannelKindGemini), id)
+		if index < 0 {
 			c.JSON(404, gin.H{"error": "Channel not found"})
 			return
 		}
 
-		upstream := cfg.GeminiUpstream[id]
+		upstream := cfg.GeminiUpstream[index]
 		baseURL := upstream.GetEffectiveBaseURL()
 		if baseURL == "" {
 			c.JSON(400, gin.H{"error": "No base URL configured"})
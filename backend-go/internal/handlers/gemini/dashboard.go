@@ -25,12 +25,14 @@ func GetDashboard(cfgManager *config.ConfigManager, sch *scheduler.ChannelSchedu
 			priority := config.GetChannelPriority(&up, i)
 
 			channels[i] = gin.H{
+				"id":                          up.ID,
 				"index":                       i,
 				"name":                        up.Name,
 				"serviceType":                 up.ServiceType,
 				"baseUrl":                     up.BaseURL,
 				"baseUrls":                    up.BaseURLs,
-				"apiKeys":                     up.APIKeys,
+				"apiKeys":                     cfgManager.RedactAPIKeys(up.APIKeys),
+				"disabledApiKeys":             cfgManager.RedactDisabledAPIKeys(up.DisabledAPIKeys),
 				"description":                 up.Description,
 				"website":                     up.Website,
 				"insecureSkipVerify":          up.InsecureSkipVerify,
@@ -48,7 +50,7 @@ func GetDashboard(cfgManager *config.ConfigManager, sch *scheduler.ChannelSchedu
 		// 2. 构建 metrics 数据
 		metricsResult := make([]gin.H, 0, len(upstreams))
 		for i, upstream := range upstreams {
-			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0, upstream.HistoricalAPIKeys)
+			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0, upstream.InactiveAPIKeys())
 
 			item := gin.H{
 				"channelIndex":        i,
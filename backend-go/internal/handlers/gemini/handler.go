@@ -4,6 +4,7 @@
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,6 +16,7 @@
 	"github.com/BenedictKing/claude-proxy/internal/converters"
 	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
 	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/quota"
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
@@ -27,10 +29,11 @@ func Handler(
 	envCfg *config.EnvConfig,
 	cfgManager *config.ConfigManager,
 	channelScheduler *scheduler.ChannelScheduler,
+	quotaManager *quota.Manager,
 ) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		// Gemini 代理端点统一使用代理访问密钥鉴权（x-api-key / Authorization: Bearer）
-		middleware.ProxyAuthMiddleware(envCfg)(c)
+		middleware.ProxyAuthMiddleware(envCfg, cfgManager)(c)
 		if c.IsAborted() {
 			return
 		}
@@ -61,6 +64,7 @@ func Handler(
 
 		// 从 URL 路径提取模型名称
 		// 格式: /v1/models/{model}:generateContent 或 /v1/models/{model}:streamGenerateContent
+		// 以及 :countTokens / :embedContent / :batchEmbedContents
 		// 使用 *modelAction 通配符捕获整个后缀，如 /gemini-pro:generateContent
 		modelAction := c.Param("modelAction")
 		// 移除前导斜杠（Gin 的 * 通配符会保留前导斜杠）
@@ -77,6 +81,17 @@ func Handler(
 			return
 		}
 
+		// 客户端访问范围校验（入口类型 + 模型）
+		if !middleware.CheckClientScope(c, string(scheduler.ChannelKindGemini), model) {
+			return
+		}
+
+		// 客户端配额校验（RPM + Token 预算），通过后绑定结算
+		if !middleware.CheckClientQuota(c, quotaManager, string(scheduler.ChannelKindGemini)) {
+			return
+		}
+		common.SetRequestModel(c, model)
+
 		// 判断是否流式
 		isStream := strings.Contains(c.Request.URL.Path, "streamGenerateContent")
 
@@ -86,6 +101,12 @@ func Handler(
 		// 记录原始请求信息
 		common.LogOriginalRequest(c, bodyBytes, envCfg, "Gemini")
 
+		// countTokens/embedContent/batchEmbedContents 统一经渠道调度器处理
+		if action := extractModelAction(modelAction); isModelAction(action) {
+			handleModelAction(c, envCfg, cfgManager, channelScheduler, bodyBytes, model, action, userID)
+			return
+		}
+
 		// 检查是否为多渠道模式
 		isMultiChannel := channelScheduler.IsMultiChannelMode(scheduler.ChannelKindGemini)
 
@@ -104,7 +125,7 @@ func extractModelName(param string) string {
 	if param == "" {
 		return ""
 	}
-	// 移除 :generateContent 或 :streamGenerateContent 后缀
+	// 移除 :generateContent、:countTokens 等动作后缀
 	if idx := strings.Index(param, ":"); idx > 0 {
 		return param[:idx]
 	}
@@ -134,14 +155,13 @@ func handleMultiChannel(
 		userID,
 		func(selection *scheduler.SelectionResult) common.MultiChannelAttemptResult {
 			upstream := selection.Upstream
-			channelIndex := selection.ChannelIndex
 
 			if upstream == nil {
 				return common.MultiChannelAttemptResult{}
 			}
 
 			baseURLs := upstream.GetAllBaseURLs()
-			sortedURLResults := channelScheduler.GetSortedURLsForChannel(scheduler.ChannelKindGemini, channelIndex, baseURLs)
+			sortedURLResults := channelScheduler.GetSortedURLsForChannel(scheduler.ChannelKindGemini, upstream.ID, baseURLs)
 
 			handled, successKey, successBaseURLIdx, failoverErr, usage, lastErr := common.TryUpstreamWithAllKeys(
 				c,
@@ -156,22 +176,22 @@ func(selection *scheduler.SelectionResult) common.MultiChannelAttemptResult {
 				bodyBytes,
 				isStream,
 				func(upstream *config.UpstreamConfig, failedKeys map[string]bool) (string, error) {
-					return cfgManager.GetNextGeminiAPIKey(upstream, failedKeys)
+					return channelScheduler.NextAPIKey(upstream, failedKeys, scheduler.ChannelKindGemini)
 				},
 				func(c *gin.Context, upstreamCopy *config.UpstreamConfig, apiKey string) (*http.Request, error) {
 					return buildProviderRequest(c, upstreamCopy, upstreamCopy.BaseURL, apiKey, geminiReq, model, isStream)
 				},
 				func(apiKey string) {
-					_ = cfgManager.DeprioritizeAPIKey(apiKey)
+					_ = cfgManager.DeprioritizeAPIKey(string(scheduler.ChannelKindGemini), upstream.ID, apiKey)
 				},
 				func(url string) {
-					channelScheduler.MarkURLFailure(scheduler.ChannelKindGemini, channelIndex, url)
+					channelScheduler.MarkURLFailure(scheduler.ChannelKindGemini, upstream.ID, url)
 				},
 				func(url string) {
-					channelScheduler.MarkURLSuccess(scheduler.ChannelKindGemini, channelIndex, url)
+					channelScheduler.MarkURLSuccess(scheduler.ChannelKindGemini, upstream.ID, url)
 				},
 				func(c *gin.Context, resp *http.Response, upstreamCopy *config.UpstreamConfig, apiKey string) (*types.Usage, error) {
-					return handleSuccess(c, resp, upstreamCopy.ServiceType, envCfg, startTime, geminiReq, model, isStream)
+					return handleSuccess(c, resp, upstreamCopy, envCfg, startTime, geminiReq, model, isStream)
 				},
 			)
 
@@ -244,18 +264,18 @@ func handleSingleChannel(
 		bodyBytes,
 		isStream,
 		func(upstream *config.UpstreamConfig, failedKeys map[string]bool) (string, error) {
-			return cfgManager.GetNextGeminiAPIKey(upstream, failedKeys)
+			return channelScheduler.NextAPIKey(upstream, failedKeys, scheduler.ChannelKindGemini)
 		},
 		func(c *gin.Context, upstreamCopy *config.UpstreamConfig, apiKey string) (*http.Request, error) {
 			return buildProviderRequest(c, upstreamCopy, upstreamCopy.BaseURL, apiKey, geminiReq, model, isStream)
 		},
 		func(apiKey string) {
-			_ = cfgManager.DeprioritizeAPIKey(apiKey)
+			_ = cfgManager.DeprioritizeAPIKey(string(scheduler.ChannelKindGemini), upstream.ID, apiKey)
 		},
 		nil,
 		nil,
 		func(c *gin.Context, resp *http.Response, upstreamCopy *config.UpstreamConfig, apiKey string) (*types.Usage, error) {
-			return handleSuccess(c, resp, upstreamCopy.ServiceType, envCfg, startTime, geminiReq, model, isStream)
+			return handleSuccess(c, resp, upstreamCopy, envCfg, startTime, geminiReq, model, isStream)
 		},
 	)
 	if handled {
@@ -288,12 +308,13 @@ func ensureThoughtSignatures(geminiReq *types.GeminiRequest) {
 	}
 }
 
-// stripThoughtSignature 移除所有 functionCall 的 thought_signature 字段
+// stripThoughtSignature 移除所有 part 的 thought_signature 字段
 // 用于兼容旧版 Gemini API（不支持该字段）
 func stripThoughtSignature(geminiReq *types.GeminiRequest) {
 	for i := range geminiReq.Contents {
 		for j := range geminiReq.Contents[i].Parts {
 			part := &geminiReq.Contents[i].Parts[j]
+			part.ThoughtSignature = ""
 			if part.FunctionCall != nil {
 				// 使用特殊标记表示需要完全移除字段
 				part.FunctionCall.ThoughtSignature = types.StripThoughtSignatureMarker
@@ -448,7 +469,7 @@ func buildProviderRequest(
 func handleSuccess(
 	c *gin.Context,
 	resp *http.Response,
-	upstreamType string,
+	upstream *config.UpstreamConfig,
 	envCfg *config.EnvConfig,
 	startTime time.Time,
 	geminiReq *types.GeminiRequest,
@@ -457,8 +478,10 @@ func handleSuccess(
 ) (*types.Usage, error) {
 	defer resp.Body.Close()
 
+	upstreamType := upstream.ServiceType
 	if isStream {
-		return handleStreamSuccess(c, resp, upstreamType, envCfg, startTime, model), nil
+		firstContentTimeout := upstream.GetStreamFirstContentTimeout(time.Duration(envCfg.StreamFirstContentTimeout) * time.Second)
+		return handleStreamSuccess(c, resp, upstreamType, envCfg, firstContentTimeout, startTime, model)
 	}
 
 	// 非流式响应处理
@@ -546,7 +569,13 @@ func handleSuccess(
 // handleAllChannelsFailed 处理所有渠道失败的情况
 func handleAllChannelsFailed(c *gin.Context, failoverErr *common.FailoverError, lastError error) {
 	if failoverErr != nil {
-		c.Data(failoverErr.Status, "application/json", failoverErr.Body)
+		common.WriteErrorData(c, failoverErr.Status, failoverErr.Body)
+		return
+	}
+
+	var notServed *scheduler.ModelNotServedError
+	if errors.As(lastError, &notServed) {
+		common.WriteModelNotServed(c, notServed, "Gemini")
 		return
 	}
 
@@ -555,7 +584,7 @@ func handleAllChannelsFailed(c *gin.Context, failoverErr *common.FailoverError,
 		errMsg = lastError.Error()
 	}
 
-	c.JSON(503, types.GeminiError{
+	common.WriteErrorJSON(c, 503, types.GeminiError{
 		Error: types.GeminiErrorDetail{
 			Code:    503,
 			Message: errMsg,
@@ -567,7 +596,7 @@ func handleAllChannelsFailed(c *gin.Context, failoverErr *common.FailoverError,
 // handleAllKeysFailed 处理所有 Key 失败的情况
 func handleAllKeysFailed(c *gin.Context, failoverErr *common.FailoverError, lastError error) {
 	if failoverErr != nil {
-		c.Data(failoverErr.Status, "application/json", failoverErr.Body)
+		common.WriteErrorData(c, failoverErr.Status, failoverErr.Body)
 		return
 	}
 
@@ -576,7 +605,7 @@ func handleAllKeysFailed(c *gin.Context, failoverErr *common.FailoverError, last
 		errMsg = lastError.Error()
 	}
 
-	c.JSON(503, types.GeminiError{
+	common.WriteErrorJSON(c, 503, types.GeminiError{
 		Error: types.GeminiErrorDetail{
 			Code:    503,
 			Message: errMsg,
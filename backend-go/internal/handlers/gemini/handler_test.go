@@ -22,7 +22,7 @@ func TestHandler_RequiresProxyAccessKeyEvenWhenGeminiKeyProvided(t *testing.T) {
 	}
 
 	r := gin.New()
-	r.POST("/v1beta/models/*modelAction", Handler(envCfg, nil, nil))
+	r.POST("/v1beta/models/*modelAction", Handler(envCfg, nil, nil, nil))
 
 	t.Run("x-goog-api-key does not bypass proxy auth", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-2.0-flash:generateContent", bytes.NewReader([]byte(`{}`)))
@@ -0,0 +1,215 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/messages"
+	"github.com/BenedictKing/claude-proxy/internal/httpclient"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const modelsRequestTimeout = 30 * time.Second
+
+// synthesizedGenerationMethods 由 claude/openai 渠道合成的模型条目支持的动作
+var synthesizedGenerationMethods = []string{"generateContent", "streamGenerateContent", "countTokens"}
+
+// ModelEntry Gemini 格式的模型条目（claude/openai 渠道合成时使用）
+type ModelEntry struct {
+	Name                       string   `json:"name"`
+	DisplayName                string   `json:"displayName"`
+	SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+}
+
+// ModelsHandler 处理 GET /v1beta/models，按 Gemini 渠道调度顺序获取模型列表
+// Gemini 渠道透传上游响应（保留分页参数）；claude/openai 渠道拉取 /models 后转换为 Gemini 格式
+func ModelsHandler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, channelScheduler *scheduler.ChannelScheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		middleware.ProxyAuthMiddleware(envCfg, cfgManager)(c)
+		if c.IsAborted() {
+			return
+		}
+		if !middleware.CheckClientScope(c, string(scheduler.ChannelKindGemini), "") {
+			return
+		}
+
+		tryGeminiModelsRequest(c, channelScheduler, "", "",
+			func(upstream *config.UpstreamConfig, apiKey string) (interface{}, error) {
+				models, err := messages.FetchChannelModels(c.Request.Context(), upstream, apiKey)
+				if err != nil {
+					return nil, err
+				}
+				entries := make([]ModelEntry, 0, len(models))
+				for _, id := range models {
+					entries = append(entries, newModelEntry(id))
+				}
+				return gin.H{"models": entries}, nil
+			})
+	}
+}
+
+// ModelDetailHandler 处理 GET /v1beta/models/{model}，返回单个模型信息
+func ModelDetailHandler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, channelScheduler *scheduler.ChannelScheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		middleware.ProxyAuthMiddleware(envCfg, cfgManager)(c)
+		if c.IsAborted() {
+			return
+		}
+
+		model := extractModelName(strings.TrimPrefix(c.Param("modelAction"), "/"))
+		if model == "" {
+			c.JSON(400, types.GeminiError{
+				Error: types.GeminiErrorDetail{
+					Code:    400,
+					Message: "Model name is required in URL path",
+					Status:  "INVALID_ARGUMENT",
+				},
+			})
+			return
+		}
+		if !middleware.CheckClientScope(c, string(scheduler.ChannelKindGemini), model) {
+			return
+		}
+
+		// claude/openai 渠道无 Gemini 模型详情接口，直接合成条目（渠道是否服务该模型由调度器按 supportedModels 过滤）
+		tryGeminiModelsRequest(c, channelScheduler, model, "/"+url.PathEscape(model),
+			func(upstream *config.UpstreamConfig, apiKey string) (interface{}, error) {
+				return newModelEntry(model), nil
+			})
+	}
+}
+
+// newModelEntry 由模型 ID 合成 Gemini 格式的模型条目
+func newModelEntry(id string) ModelEntry {
+	return ModelEntry{
+		Name:                       "models/" + id,
+		DisplayName:                id,
+		SupportedGenerationMethods: synthesizedGenerationMethods,
+	}
+}
+
+// tryGeminiModelsRequest 使用调度器选择 Gemini 渠道，按故障转移顺序请求 models 端点
+// model 非空时只选择服务该模型的渠道；非 Gemini 渠道由 synthesize 生成响应
+func tryGeminiModelsRequest(
+	c *gin.Context,
+	channelScheduler *scheduler.ChannelScheduler,
+	model string,
+	suffix string,
+	synthesize func(upstream *config.UpstreamConfig, apiKey string) (interface{}, error),
+) {
+	failedChannels := make(map[int]bool)
+	maxChannelAttempts := channelScheduler.GetActiveChannelCount(scheduler.ChannelKindGemini)
+	var lastErr error
+
+	for attempt := 0; attempt < maxChannelAttempts; attempt++ {
+		selection, err := channelScheduler.SelectChannel(c.Request.Context(), "", failedChannels, scheduler.ChannelKindGemini, model)
+		if err != nil {
+			lastErr = err
+			break
+		}
+		upstream := selection.Upstream
+		failedChannels[selection.ChannelIndex] = true
+
+		apiKey, err := channelScheduler.NextAPIKey(upstream, nil, scheduler.ChannelKindGemini)
+		if err != nil {
+			lastErr = err
+			log.Printf("[Gemini-Models] 获取 API Key 失败: channel=%s, error=%v", upstream.Name, err)
+			continue
+		}
+
+		if !isGeminiUpstream(upstream) {
+			result, err := synthesize(upstream, apiKey)
+			if err != nil {
+				lastErr = err
+				log.Printf("[Gemini-Models] 渠道模型列表获取失败: channel=%s, error=%v", upstream.Name, err)
+				continue
+			}
+			c.JSON(http.StatusOK, result)
+			return
+		}
+
+		body, status, err := fetchGeminiModels(c, upstream, apiKey, suffix)
+		if err != nil {
+			lastErr = err
+			log.Printf("[Gemini-Models] 请求失败: channel=%s, key=%s, error=%v", upstream.Name, utils.MaskAPIKey(apiKey), err)
+			continue
+		}
+		if status == http.StatusOK {
+			log.Printf("[Gemini-Models] 请求成功: channel=%s, key=%s, reason=%s", upstream.Name, utils.MaskAPIKey(apiKey), selection.Reason)
+			c.Data(http.StatusOK, "application/json", body)
+			return
+		}
+		lastErr = fmt.Errorf("渠道 %s 返回 %d", upstream.Name, status)
+		log.Printf("[Gemini-Models] 上游返回非 200: channel=%s, key=%s, status=%d", upstream.Name, utils.MaskAPIKey(apiKey), status)
+	}
+
+	var notServed *scheduler.ModelNotServedError
+	if model != "" || errors.As(lastErr, &notServed) {
+		c.JSON(http.StatusNotFound, types.GeminiError{
+			Error: types.GeminiErrorDetail{
+				Code:    404,
+				Message: fmt.Sprintf("model %q not found", model),
+				Status:  "NOT_FOUND",
+			},
+		})
+		return
+	}
+
+	errMsg := "models endpoint not available from any upstream"
+	if lastErr != nil {
+		errMsg = lastErr.Error()
+	}
+	c.JSON(http.StatusServiceUnavailable, types.GeminiError{
+		Error: types.GeminiErrorDetail{
+			Code:    503,
+			Message: errMsg,
+			Status:  "UNAVAILABLE",
+		},
+	})
+}
+
+// fetchGeminiModels 请求 Gemini 上游的 models 端点（透传 pageSize/pageToken 分页参数）
+func fetchGeminiModels(c *gin.Context, upstream *config.UpstreamConfig, apiKey, suffix string) ([]byte, int, error) {
+	baseURL := upstream.GetEffectiveBaseURL()
+	targetURL := strings.TrimRight(baseURL, "/") + "/v1beta/models" + suffix
+
+	query := url.Values{}
+	for _, name := range []string{"pageSize", "pageToken"} {
+		if v := c.Query(name); v != "" {
+			query.Set(name, v)
+		}
+	}
+	if len(query) > 0 {
+		targetURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	utils.SetGeminiAuthenticationHeader(req.Header, apiKey)
+
+	client := httpclient.GetManager().GetStandardClient(modelsRequestTimeout, upstream.InsecureSkipVerify)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, resp.StatusCode, nil
+}
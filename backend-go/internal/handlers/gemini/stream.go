@@ -10,6 +10,7 @@
 	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/gin-gonic/gin"
 )
@@ -20,32 +21,63 @@ func handleStreamSuccess(
 	resp *http.Response,
 	upstreamType string,
 	envCfg *config.EnvConfig,
+	firstContentTimeout time.Duration,
 	startTime time.Time,
 	model string,
-) *types.Usage {
+) (*types.Usage, error) {
+	// 流式 failover 前置缓冲按上游格式识别内容；未知类型按 Gemini 原生格式透传
+	preludeType := upstreamType
+	if preludeType != "claude" && preludeType != "openai" {
+		preludeType = "gemini"
+	}
+	// 首个内容前只向客户端写出心跳，上游失败时由调用方切换 Key/渠道
+	heartbeatInterval := time.Duration(envCfg.StreamHeartbeatInterval) * time.Second
+	if envCfg.StreamFailoverEnabled {
+		stopPreludeHeartbeat := common.StartPreludeHeartbeat(c, heartbeatInterval, common.SSECommentHeartbeat)
+		body, err := common.AwaitSSEPrelude(c, resp.Body, preludeType, firstContentTimeout)
+		stopPreludeHeartbeat()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = body
+	}
+
 	// 设置 SSE 响应头
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no")
 
+	stopHeartbeat := common.StartStreamHeartbeat(c, heartbeatInterval, common.SSECommentHeartbeat)
+	defer stopHeartbeat()
+
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
 		log.Printf("[Gemini-Stream] 警告: ResponseWriter 不支持 Flusher")
 	}
 
 	var totalUsage *types.Usage
+	var streamErr error
 
 	switch upstreamType {
 	case "gemini":
-		totalUsage = streamGeminiToGemini(c, resp, flusher, envCfg)
+		totalUsage, streamErr = streamGeminiToGemini(c, resp, flusher, envCfg)
 	case "claude":
-		totalUsage = streamClaudeToGemini(c, resp, flusher, envCfg, model)
+		totalUsage, streamErr = streamClaudeToGemini(c, resp, flusher, envCfg, model)
 	case "openai":
-		totalUsage = streamOpenAIToGemini(c, resp, flusher, envCfg, model)
+		totalUsage, streamErr = streamOpenAIToGemini(c, resp, flusher, envCfg, model)
 	default:
 		// 默认透传
-		totalUsage = streamGeminiToGemini(c, resp, flusher, envCfg)
+		totalUsage, streamErr = streamGeminiToGemini(c, resp, flusher, envCfg)
+	}
+
+	if streamErr != nil {
+		if ctxErr := c.Request.Context().Err(); ctxErr != nil {
+			return totalUsage, ctxErr
+		}
+		log.Printf("[Gemini-Stream] 读取上游流失败: %v", streamErr)
+		// 流中途异常（读取失败、空闲超时）需返回错误以记录渠道失败
+		return totalUsage, streamErr
 	}
 
 	if envCfg.EnableResponseLogs {
@@ -53,7 +85,7 @@ func handleStreamSuccess(
 		log.Printf("[Gemini-Stream-Timing] 流式响应完成: %dms", responseTime)
 	}
 
-	return totalUsage
+	return totalUsage, nil
 }
 
 // streamGeminiToGemini Gemini 上游直接透传
@@ -62,7 +94,7 @@ func streamGeminiToGemini(
 	resp *http.Response,
 	flusher http.Flusher,
 	envCfg *config.EnvConfig,
-) *types.Usage {
+) (*types.Usage, error) {
 	scanner := bufio.NewScanner(resp.Body)
 	scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer
 
@@ -98,7 +130,7 @@ func streamGeminiToGemini(
 		}
 	}
 
-	return totalUsage
+	return totalUsage, scanner.Err()
 }
 
 // streamClaudeToGemini Claude 流式响应转换为 Gemini 格式
@@ -108,7 +140,7 @@ func streamClaudeToGemini(
 	flusher http.Flusher,
 	envCfg *config.EnvConfig,
 	model string,
-) *types.Usage {
+) (*types.Usage, error) {
 	scanner := bufio.NewScanner(resp.Body)
 	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
 
@@ -142,6 +174,20 @@ func streamClaudeToGemini(
 				continue
 			}
 			deltaType, _ := delta["type"].(string)
+			switch deltaType {
+			case "thinking_delta":
+				// 思考增量 → thought part
+				if thinking, _ := delta["thinking"].(string); thinking != "" {
+					writeGeminiPartChunk(c, flusher, types.GeminiPart{Text: thinking, Thought: true})
+				}
+				continue
+			case "signature_delta":
+				// 思考签名 → 携带 thoughtSignature 的空 thought part，客户端回传时可还原 thinking 块
+				if signature, _ := delta["signature"].(string); signature != "" {
+					writeGeminiPartChunk(c, flusher, types.GeminiPart{Thought: true, ThoughtSignature: signature})
+				}
+				continue
+			}
 			if deltaType == "text_delta" {
 				text, _ := delta["text"].(string)
 				currentText.WriteString(text)
@@ -205,7 +251,7 @@ func streamClaudeToGemini(
 		}
 	}
 
-	return totalUsage
+	return totalUsage, scanner.Err()
 }
 
 // streamOpenAIToGemini OpenAI 流式响应转换为 Gemini 格式
@@ -215,7 +261,7 @@ func streamOpenAIToGemini(
 	flusher http.Flusher,
 	envCfg *config.EnvConfig,
 	model string,
-) *types.Usage {
+) (*types.Usage, error) {
 	scanner := bufio.NewScanner(resp.Body)
 	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
 
@@ -303,6 +349,15 @@ func streamOpenAIToGemini(
 			continue
 		}
 
+		// 推理内容 → thought part
+		reasoning, _ := delta["reasoning_content"].(string)
+		if reasoning == "" {
+			reasoning, _ = delta["reasoning"].(string)
+		}
+		if reasoning != "" {
+			writeGeminiPartChunk(c, flusher, types.GeminiPart{Text: reasoning, Thought: true})
+		}
+
 		// 提取文本内容
 		content, _ := delta["content"].(string)
 		if content != "" {
@@ -346,7 +401,26 @@ func streamOpenAIToGemini(
 		}
 	}
 
-	return totalUsage
+	return totalUsage, scanner.Err()
+}
+
+// writeGeminiPartChunk 输出仅包含单个 part 的 Gemini 流式块
+func writeGeminiPartChunk(c *gin.Context, flusher http.Flusher, part types.GeminiPart) {
+	geminiChunk := types.GeminiStreamChunk{
+		Candidates: []types.GeminiCandidate{
+			{
+				Content: &types.GeminiContent{
+					Parts: []types.GeminiPart{part},
+					Role:  "model",
+				},
+			},
+		},
+	}
+	chunkBytes, _ := json.Marshal(geminiChunk)
+	fmt.Fprintf(c.Writer, "data: %s\n\n", string(chunkBytes))
+	if flusher != nil {
+		flusher.Flush()
+	}
 }
 
 // openaiFinishReasonToGemini 将 OpenAI 停止原因转换为 Gemini 格式
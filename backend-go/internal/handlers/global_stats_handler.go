@@ -3,13 +3,15 @@
 import (
 	"time"
 
+	"github.com/BenedictKing/claude-proxy/internal/config"
 	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/gin-gonic/gin"
 )
 
-// GetGlobalStatsHistory 获取全局统计历史数据
-// GET /api/{messages|responses}/global/stats/history?duration={1h|6h|24h|today}
-func GetGlobalStatsHistory(metricsManager *metrics.MetricsManager) gin.HandlerFunc {
+// GetGlobalStatsHistory 获取全局统计历史数据（含按渠道/Key/客户端拆分的费用汇总）
+// GET /api/{messages|responses|gemini|chat}/global/stats/history?duration={1h|6h|24h|today}
+func GetGlobalStatsHistory(metricsManager *metrics.MetricsManager, cfgManager *config.ConfigManager, kind scheduler.ChannelKind) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 解析 duration 参数
 		durationStr := c.DefaultQuery("duration", "24h")
@@ -69,6 +71,11 @@ func GetGlobalStatsHistory(metricsManager *metrics.MetricsManager) gin.HandlerFu
 		// 获取全局统计数据
 		result := metricsManager.GetGlobalHistoricalStatsWithTokens(duration, interval)
 
+		if cfgManager != nil {
+			cfg := cfgManager.GetConfig()
+			result.ByChannel = aggregateChannelCosts(cfg.UpstreamsForKind(string(kind)), result.ByKey)
+		}
+
 		// 更新 duration 字符串（特别是 today 情况）
 		if durationStr == "today" {
 			result.Summary.Duration = "today"
@@ -77,3 +84,32 @@ func GetGlobalStatsHistory(metricsManager *metrics.MetricsManager) gin.HandlerFu
 		c.JSON(200, result)
 	}
 }
+
+// aggregateChannelCosts 按渠道聚合 Key 级别的统计（含历史 Key 与所有 BaseURL）
+func aggregateChannelCosts(upstreams []config.UpstreamConfig, byKey []metrics.KeyCostStats) []metrics.ChannelCostStats {
+	statsByMetricsKey := make(map[string]metrics.TimeWindowStats, len(byKey))
+	for _, k := range byKey {
+		statsByMetricsKey[k.MetricsKey] = k.TimeWindowStats
+	}
+
+	result := make([]metrics.ChannelCostStats, 0, len(upstreams))
+	for i, upstream := range upstreams {
+		channel := metrics.ChannelCostStats{ChannelIndex: i, ChannelName: upstream.Name}
+		seen := make(map[string]bool)
+		keys := append(append([]string{}, upstream.APIKeys...), upstream.InactiveAPIKeys()...)
+		for _, baseURL := range upstream.GetAllBaseURLs() {
+			for _, apiKey := range keys {
+				metricsKey := metrics.GenerateMetricsKey(baseURL, apiKey)
+				if seen[metricsKey] {
+					continue
+				}
+				seen[metricsKey] = true
+				if stats, ok := statsByMetricsKey[metricsKey]; ok {
+					channel.Merge(stats)
+				}
+			}
+		}
+		result = append(result, channel)
+	}
+	return result
+}
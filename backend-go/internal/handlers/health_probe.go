@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/healthprobe"
+	"github.com/gin-gonic/gin"
+)
+
+// GetHealthProbeConfig 获取后台健康探测配置
+func GetHealthProbeConfig(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(200, cfgManager.GetHealthProbeConfig())
+	}
+}
+
+// SetHealthProbeConfig 整体替换后台健康探测配置
+func SetHealthProbeConfig(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req config.HealthProbeConfig
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		if err := cfgManager.SetHealthProbeConfig(&req); err != nil {
+			if _, ok := err.(*config.ConfigError); ok {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(500, gin.H{"error": "Failed to save config"})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"success":     true,
+			"healthProbe": cfgManager.GetHealthProbeConfig(),
+		})
+	}
+}
+
+// GetHealthProbeHistory 查询探测记录
+// GET /api/health-probe/history?kind=messages&channel=<渠道标识或索引>&limit=100
+// 记录按渠道标识过滤，渠道排序或删除后仍能查到正确渠道的历史；按索引查询时需指定 kind
+func GetHealthProbeHistory(cfgManager *config.ConfigManager, prober *healthprobe.Prober) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		kind := c.Query("kind")
+		channelID := c.Query("channel")
+		if _, err := strconv.Atoi(channelID); err == nil {
+			// 索引只对当前配置有效，换算为渠道标识
+			cfg := cfgManager.GetConfig()
+			upstreams := cfg.UpstreamsForKind(kind)
+			index, err := cfgManager.ResolveChannelIndex(kind, channelID)
+			if kind == "" || err != nil || index >= len(upstreams) {
+				c.JSON(400, gin.H{"error": "Invalid channel"})
+				return
+			}
+			channelID = upstreams[index].ID
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+		var lastRun interface{}
+		if t := prober.LastRun(); !t.IsZero() {
+			lastRun = t
+		}
+		c.JSON(200, gin.H{
+			"lastRun": lastRun,
+			"history": prober.History(kind, channelID, limit),
+		})
+	}
+}
+
+// RunHealthProbe 立即执行一个探测周期（不受 enabled 开关限制），返回本周期结果
+func RunHealthProbe(prober *healthprobe.Prober) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		results := prober.RunOnce(c.Request.Context())
+		if results == nil {
+			results = []healthprobe.Result{}
+		}
+		c.JSON(200, gin.H{
+			"success": true,
+			"results": results,
+		})
+	}
+}
@@ -3,12 +3,12 @@
 
 import (
 	"net/http"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
 	"github.com/BenedictKing/claude-proxy/internal/httpclient"
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/gin-gonic/gin"
@@ -25,12 +25,14 @@ func GetUpstreams(cfgManager *config.ConfigManager) gin.HandlerFunc {
 			priority := config.GetChannelPriority(&up, i)
 
 			upstreams[i] = gin.H{
+				"id":                 up.ID,
 				"index":              i,
 				"name":               up.Name,
 				"serviceType":        up.ServiceType,
 				"baseUrl":            up.BaseURL,
 				"baseUrls":           up.BaseURLs,
-				"apiKeys":            up.APIKeys,
+				"apiKeys":            cfgManager.RedactAPIKeys(up.APIKeys),
+				"disabledApiKeys":    cfgManager.RedactDisabledAPIKeys(up.DisabledAPIKeys),
 				"description":        up.Description,
 				"website":            up.Website,
 				"insecureSkipVerify": up.InsecureSkipVerify,
@@ -74,10 +76,8 @@ func AddUpstream(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // UpdateUpstream 更新上游
 func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindMessages)
+		if !ok {
 			return
 		}
 
@@ -86,10 +86,17 @@ func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 			c.JSON(400, gin.H{"error": "Invalid request body"})
 			return
 		}
+		// 管理界面回传的已掩码 Key 还原为实际 Key
+		apiKeys, err := cfgManager.ResolveAPIKeyRefs(string(scheduler.ChannelKindMessages), id, updates.APIKeys)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		updates.APIKeys = apiKeys
 
 		shouldResetMetrics, err := cfgManager.UpdateUpstream(id, updates)
 		if err != nil {
-			if strings.Contains(err.Error(), "无效的上游索引") {
+			if strings.Contains(err.Error(), "未找到渠道") {
 				c.JSON(404, gin.H{"error": "Upstream not found"})
 			} else {
 				c.JSON(500, gin.H{"error": "Failed to save config"})
@@ -102,9 +109,13 @@ func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 		}
 
 		cfg := cfgManager.GetConfig()
+		var updated *config.UpstreamConfig
+		if index := cfg.ChannelIndexByID(string(scheduler.ChannelKindMessages), id); index >= 0 {
+			updated = &cfg.Upstream[index]
+		}
 		c.JSON(200, gin.H{
 			"message":  "上游已更新",
-			"upstream": cfg.Upstream[id],
+			"upstream": updated,
 		})
 	}
 }
@@ -112,16 +123,14 @@ func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 // DeleteUpstream 删除上游
 func DeleteUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindMessages)
+		if !ok {
 			return
 		}
 
 		removed, err := cfgManager.RemoveUpstream(id)
 		if err != nil {
-			if strings.Contains(err.Error(), "无效的上游索引") {
+			if strings.Contains(err.Error(), "未找到渠道") {
 				c.JSON(404, gin.H{"error": "Upstream not found"})
 			} else {
 				c.JSON(500, gin.H{"error": "Failed to save config"})
@@ -142,10 +151,8 @@ func DeleteUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 // AddApiKey 添加 API 密钥
 func AddApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindMessages)
+		if !ok {
 			return
 		}
 
@@ -158,7 +165,7 @@ func AddApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 		}
 
 		if err := cfgManager.AddAPIKey(id, req.APIKey); err != nil {
-			if strings.Contains(err.Error(), "无效的上游索引") {
+			if strings.Contains(err.Error(), "未找到渠道") {
 				c.JSON(404, gin.H{"error": "Upstream not found"})
 			} else if strings.Contains(err.Error(), "API密钥已存在") {
 				c.JSON(400, gin.H{"error": "API密钥已存在"})
@@ -178,21 +185,23 @@ func AddApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // DeleteApiKey 删除 API 密钥
 func DeleteApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindMessages)
+		if !ok {
 			return
 		}
 
-		apiKey := c.Param("apiKey")
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindMessages), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 		if apiKey == "" {
 			c.JSON(400, gin.H{"error": "API key is required"})
 			return
 		}
 
 		if err := cfgManager.RemoveAPIKey(id, apiKey); err != nil {
-			if strings.Contains(err.Error(), "无效的上游索引") {
+			if strings.Contains(err.Error(), "未找到渠道") {
 				c.JSON(404, gin.H{"error": "Upstream not found"})
 			} else if strings.Contains(err.Error(), "API密钥不存在") {
 				c.JSON(404, gin.H{"error": "API key not found"})
@@ -211,14 +220,16 @@ func DeleteApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // MoveApiKeyToTop 将 API 密钥移到顶部
 func MoveApiKeyToTop(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindMessages)
+		if !ok {
 			return
 		}
 
-		apiKey := c.Param("apiKey")
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindMessages), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 		if apiKey == "" {
 			c.JSON(400, gin.H{"error": "API key is required"})
 			return
@@ -236,14 +247,16 @@ func MoveApiKeyToTop(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // MoveApiKeyToBottom 将 API 密钥移到底部
 func MoveApiKeyToBottom(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindMessages)
+		if !ok {
 			return
 		}
 
-		apiKey := c.Param("apiKey")
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindMessages), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 		if apiKey == "" {
 			c.JSON(400, gin.H{"error": "API key is required"})
 			return
@@ -308,10 +321,8 @@ func ReorderChannels(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // SetChannelStatus 设置渠道状态
 func SetChannelStatus(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid channel ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindMessages)
+		if !ok {
 			return
 		}
 
@@ -336,10 +347,8 @@ func SetChannelStatus(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // 促销期内的渠道会被优先选择，忽略 trace 亲和性
 func SetChannelPromotion(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "无效的渠道 ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindMessages)
+		if !ok {
 			return
 		}
 
@@ -376,20 +385,19 @@ func SetChannelPromotion(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // PingChannel Ping单个渠道
 func PingChannel(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindMessages)
+		if !ok {
 			return
 		}
 
 		cfg := cfgManager.GetConfig()
-		if id < 0 || id >= len(cfg.Upstream) {
+		index := cfg.ChannelIndexByID(string(scheduler.ChannelKindMessages), id)
+		if index < 0 {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
 			return
 		}
 
-		channel := cfg.Upstream[id]
+		channel := cfg.Upstream[index]
 		result := pingChannelURLs(&channel)
 		c.JSON(http.StatusOK, result)
 	}
@@ -13,6 +13,7 @@
 	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
 	"github.com/BenedictKing/claude-proxy/internal/middleware"
 	"github.com/BenedictKing/claude-proxy/internal/providers"
+	"github.com/BenedictKing/claude-proxy/internal/quota"
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
@@ -21,10 +22,10 @@
 
 // Handler Messages API 代理处理器
 // 支持多渠道调度：当配置多个渠道时自动启用
-func Handler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, channelScheduler *scheduler.ChannelScheduler) gin.HandlerFunc {
+func Handler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, channelScheduler *scheduler.ChannelScheduler, quotaManager *quota.Manager) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		// 先进行认证
-		middleware.ProxyAuthMiddleware(envCfg)(c)
+		middleware.ProxyAuthMiddleware(envCfg, cfgManager)(c)
 		if c.IsAborted() {
 			return
 		}
@@ -48,6 +49,17 @@ func Handler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, channel
 			_ = json.Unmarshal(bodyBytes, &claudeReq)
 		}
 
+		// 客户端访问范围校验（入口类型 + 模型）
+		if !middleware.CheckClientScope(c, string(scheduler.ChannelKindMessages), claudeReq.Model) {
+			return
+		}
+
+		// 客户端配额校验（RPM + Token 预算），通过后绑定结算
+		if !middleware.CheckClientQuota(c, quotaManager, string(scheduler.ChannelKindMessages)) {
+			return
+		}
+		common.SetRequestModel(c, claudeReq.Model)
+
 		// 提取 user_id 用于 Trace 亲和性
 		userID := common.ExtractUserID(bodyBytes)
 
@@ -85,7 +97,6 @@ func handleMultiChannel(
 		userID,
 		func(selection *scheduler.SelectionResult) common.MultiChannelAttemptResult {
 			upstream := selection.Upstream
-			channelIndex := selection.ChannelIndex
 
 			if upstream == nil {
 				return common.MultiChannelAttemptResult{}
@@ -98,7 +109,7 @@ func(selection *scheduler.SelectionResult) common.MultiChannelAttemptResult {
 
 			metricsManager := channelScheduler.GetMessagesMetricsManager()
 			baseURLs := upstream.GetAllBaseURLs()
-			sortedURLResults := channelScheduler.GetSortedURLsForChannel(scheduler.ChannelKindMessages, channelIndex, baseURLs)
+			sortedURLResults := channelScheduler.GetSortedURLsForChannel(scheduler.ChannelKindMessages, upstream.ID, baseURLs)
 
 			handled, successKey, successBaseURLIdx, failoverErr, usage, lastErr := common.TryUpstreamWithAllKeys(
 				c,
@@ -113,22 +124,22 @@ func(selection *scheduler.SelectionResult) common.MultiChannelAttemptResult {
 				bodyBytes,
 				claudeReq.Stream,
 				func(upstream *config.UpstreamConfig, failedKeys map[string]bool) (string, error) {
-					return cfgManager.GetNextAPIKey(upstream, failedKeys, "Messages")
+					return channelScheduler.NextAPIKey(upstream, failedKeys, scheduler.ChannelKindMessages)
 				},
 				func(c *gin.Context, upstreamCopy *config.UpstreamConfig, apiKey string) (*http.Request, error) {
 					req, _, err := provider.ConvertToProviderRequest(c, upstreamCopy, apiKey)
 					return req, err
 				},
 				func(apiKey string) {
-					if err := cfgManager.DeprioritizeAPIKey(apiKey); err != nil {
+					if err := cfgManager.DeprioritizeAPIKey(string(scheduler.ChannelKindMessages), upstream.ID, apiKey); err != nil {
 						log.Printf("[Messages-Key] 警告: 密钥降级失败: %v", err)
 					}
 				},
 				func(url string) {
-					channelScheduler.MarkURLFailure(scheduler.ChannelKindMessages, channelIndex, url)
+					channelScheduler.MarkURLFailure(scheduler.ChannelKindMessages, upstream.ID, url)
 				},
 				func(url string) {
-					channelScheduler.MarkURLSuccess(scheduler.ChannelKindMessages, channelIndex, url)
+					channelScheduler.MarkURLSuccess(scheduler.ChannelKindMessages, upstream.ID, url)
 				},
 				func(c *gin.Context, resp *http.Response, upstreamCopy *config.UpstreamConfig, apiKey string) (*types.Usage, error) {
 					if claudeReq.Stream {
@@ -206,14 +217,14 @@ func handleSingleChannel(
 		bodyBytes,
 		claudeReq.Stream,
 		func(upstream *config.UpstreamConfig, failedKeys map[string]bool) (string, error) {
-			return cfgManager.GetNextAPIKey(upstream, failedKeys, "Messages")
+			return channelScheduler.NextAPIKey(upstream, failedKeys, scheduler.ChannelKindMessages)
 		},
 		func(c *gin.Context, upstreamCopy *config.UpstreamConfig, apiKey string) (*http.Request, error) {
 			req, _, err := provider.ConvertToProviderRequest(c, upstreamCopy, apiKey)
 			return req, err
 		},
 		func(apiKey string) {
-			if err := cfgManager.DeprioritizeAPIKey(apiKey); err != nil {
+			if err := cfgManager.DeprioritizeAPIKey(string(scheduler.ChannelKindMessages), upstream.ID, apiKey); err != nil {
 				log.Printf("[Messages-Key] 警告: 密钥降级失败: %v", err)
 			}
 		},
@@ -361,7 +372,7 @@ func handleNormalResponse(
 // CountTokensHandler 处理 /v1/messages/count_tokens 请求
 func CountTokensHandler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, channelScheduler *scheduler.ChannelScheduler) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		middleware.ProxyAuthMiddleware(envCfg)(c)
+		middleware.ProxyAuthMiddleware(envCfg, cfgManager)(c)
 		if c.IsAborted() {
 			return
 		}
@@ -384,6 +395,10 @@ func CountTokensHandler(envCfg *config.EnvConfig, cfgManager *config.ConfigManag
 			return
 		}
 
+		if !middleware.CheckClientScope(c, string(scheduler.ChannelKindMessages), req.Model) {
+			return
+		}
+
 		inputTokens := utils.EstimateRequestTokens(bodyBytes)
 
 		c.JSON(200, gin.H{
@@ -2,7 +2,9 @@
 package messages
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -37,7 +39,7 @@ type ModelEntry struct {
 // ModelsHandler 处理 /v1/models 请求，从 Messages 和 Responses 渠道获取并合并模型列表
 func ModelsHandler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, channelScheduler *scheduler.ChannelScheduler) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		middleware.ProxyAuthMiddleware(envCfg)(c)
+		middleware.ProxyAuthMiddleware(envCfg, cfgManager)(c)
 		if c.IsAborted() {
 			return
 		}
@@ -74,7 +76,7 @@ func ModelsHandler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, c
 // ModelsDetailHandler 处理 /v1/models/:model 请求，转发到上游
 func ModelsDetailHandler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, channelScheduler *scheduler.ChannelScheduler) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		middleware.ProxyAuthMiddleware(envCfg)(c)
+		middleware.ProxyAuthMiddleware(envCfg, cfgManager)(c)
 		if c.IsAborted() {
 			return
 		}
@@ -172,7 +174,7 @@ func tryModelsRequest(c *gin.Context, cfgManager *config.ConfigManager, channelS
 		}
 
 		// 使用调度器选择渠道
-		selection, err := channelScheduler.SelectChannel(c.Request.Context(), "", failedChannels, kind)
+		selection, err := channelScheduler.SelectChannel(c.Request.Context(), "", failedChannels, kind, "")
 		if err != nil {
 			log.Printf("[%s-Models] 渠道无可用: %v", channelType, err)
 			break
@@ -237,8 +239,77 @@ func tryModelsRequest(c *gin.Context, cfgManager *config.ConfigManager, channelS
 	return nil, false
 }
 
+// FetchChannelModels 从指定渠道的 /models 端点拉取模型 ID 列表（用于渠道支持模型自动发现）
+// 兼容 OpenAI/Claude 格式（data[].id）与 Gemini 格式（models[].name，去除 models/ 前缀）
+func FetchChannelModels(ctx context.Context, upstream *config.UpstreamConfig, apiKey string) ([]string, error) {
+	baseURL := upstream.GetEffectiveBaseURL()
+	url := buildModelsURL(baseURL)
+	if upstream.ServiceType == "gemini" {
+		url = buildModelsURLWithVersion(baseURL, "/v1beta")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if upstream.ServiceType == "gemini" {
+		utils.SetGeminiAuthenticationHeader(req.Header, apiKey)
+	} else {
+		utils.SetAuthenticationHeader(req.Header, apiKey)
+		if upstream.ServiceType == "claude" {
+			req.Header.Set("anthropic-version", "2023-06-01")
+		}
+	}
+
+	client := httpclient.GetManager().GetStandardClient(modelsRequestTimeout, upstream.InsecureSkipVerify)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(body) > 200 {
+			body = body[:200]
+		}
+		return nil, fmt.Errorf("上游返回 %d: %s", resp.StatusCode, string(body))
+	}
+
+	var parsed struct {
+		Data   []ModelEntry `json:"data"`
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("解析模型列表失败: %w", err)
+	}
+
+	models := make([]string, 0, len(parsed.Data)+len(parsed.Models))
+	for _, m := range parsed.Data {
+		if m.ID != "" {
+			models = append(models, m.ID)
+		}
+	}
+	for _, m := range parsed.Models {
+		if name := strings.TrimPrefix(m.Name, "models/"); name != "" {
+			models = append(models, name)
+		}
+	}
+	return models, nil
+}
+
 // buildModelsURL 构建 models 端点的 URL
 func buildModelsURL(baseURL string) string {
+	return buildModelsURLWithVersion(baseURL, "/v1")
+}
+
+// buildModelsURLWithVersion 构建 models 端点的 URL，baseURL 未带版本号时补充 version 前缀
+func buildModelsURLWithVersion(baseURL, version string) string {
 	skipVersionPrefix := strings.HasSuffix(baseURL, "#")
 	if skipVersionPrefix {
 		baseURL = strings.TrimSuffix(baseURL, "#")
@@ -250,7 +321,7 @@ func buildModelsURL(baseURL string) string {
 
 	endpoint := "/models"
 	if !hasVersionSuffix && !skipVersionPrefix {
-		endpoint = "/v1" + endpoint
+		endpoint = version + endpoint
 	}
 
 	return baseURL + endpoint
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// prometheusNamespace 导出指标名前缀
+const prometheusNamespace = "claude_proxy"
+
+// unknownChannelLabel 配置中已不存在的渠道（如已删除渠道的残留指标）
+const unknownChannelLabel = "unknown"
+
+// promKeySeries 单个 (kind, channel, key) 标签组合的聚合指标
+// 同一渠道的多个 BaseURL 共用同一个 Key 时合并为一条序列
+type promKeySeries struct {
+	kind    string
+	channel string
+	key     string
+
+	requests       int64
+	failures       int64
+	inFlight       int64
+	circuitOpen    bool
+	inputTokens    int64
+	outputTokens   int64
+	cacheCreation  int64
+	cacheRead      int64
+	cost           float64
+	latencyBuckets []uint64
+	latencyCount   uint64
+	latencySum     float64
+}
+
+func (s *promKeySeries) labels() string {
+	return formatPromLabels("kind", s.kind, "channel", s.channel, "key", s.key)
+}
+
+// PrometheusMetrics Prometheus 文本格式指标导出
+// GET /metrics
+func PrometheusMetrics(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler, sessionManager *session.SessionManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := cfgManager.GetConfig()
+
+		kinds := []struct {
+			kind    scheduler.ChannelKind
+			manager *metrics.MetricsManager
+		}{
+			{scheduler.ChannelKindMessages, sch.GetMessagesMetricsManager()},
+			{scheduler.ChannelKindResponses, sch.GetResponsesMetricsManager()},
+			{scheduler.ChannelKindGemini, sch.GetGeminiMetricsManager()},
+			{scheduler.ChannelKindChat, sch.GetChatMetricsManager()},
+		}
+
+		var series []*promKeySeries
+		var b strings.Builder
+
+		// 渠道状态
+		writePromHeader(&b, "channel_status", "Channel status (1 for the current status label)", "gauge")
+		for _, k := range kinds {
+			for _, upstream := range cfg.UpstreamsForKind(string(k.kind)) {
+				fmt.Fprintf(&b, "%s_channel_status%s 1\n", prometheusNamespace,
+					formatPromLabels("kind", string(k.kind), "channel", upstream.Name, "status", config.GetChannelStatus(&upstream)))
+			}
+		}
+
+		for _, k := range kinds {
+			if k.manager == nil {
+				continue
+			}
+			series = append(series, collectKeySeries(string(k.kind), cfg.UpstreamsForKind(string(k.kind)), k.manager.SnapshotKeys())...)
+		}
+
+		writeKeySeries(&b, series)
+
+		// 会话与 Trace 亲和
+		if sessionManager != nil {
+			stats := sessionManager.GetStats()
+			writePromHeader(&b, "sessions_active", "Active Responses API sessions", "gauge")
+			fmt.Fprintf(&b, "%s_sessions_active %v\n", prometheusNamespace, stats["total_sessions"])
+			writePromHeader(&b, "session_response_mappings", "Response ID to session mappings", "gauge")
+			fmt.Fprintf(&b, "%s_session_response_mappings %v\n", prometheusNamespace, stats["total_mappings"])
+		}
+		if affinity := sch.GetTraceAffinityManager(); affinity != nil {
+			writePromHeader(&b, "trace_affinity_entries", "Active trace affinity bindings", "gauge")
+			fmt.Fprintf(&b, "%s_trace_affinity_entries %d\n", prometheusNamespace, affinity.Size())
+		}
+
+		c.Data(200, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+	}
+}
+
+// collectKeySeries 将 Key 快照按 (channel, keyMask) 聚合为导出序列
+func collectKeySeries(kind string, upstreams []config.UpstreamConfig, snapshots []metrics.KeySnapshot) []*promKeySeries {
+	// metricsKey -> 渠道名（包含历史 Key 与所有 BaseURL）
+	channelByMetricsKey := make(map[string]string)
+	for _, upstream := range upstreams {
+		keys := append(append([]string{}, upstream.APIKeys...), upstream.InactiveAPIKeys()...)
+		for _, baseURL := range upstream.GetAllBaseURLs() {
+			for _, apiKey := range keys {
+				metricsKey := metrics.GenerateMetricsKey(baseURL, apiKey)
+				if _, exists := channelByMetricsKey[metricsKey]; !exists {
+					channelByMetricsKey[metricsKey] = upstream.Name
+				}
+			}
+		}
+	}
+
+	byLabels := make(map[string]*promKeySeries)
+	for _, snap := range snapshots {
+		channel, ok := channelByMetricsKey[snap.MetricsKey]
+		if !ok {
+			channel = unknownChannelLabel
+		}
+		id := channel + "\x00" + snap.KeyMask
+		s, exists := byLabels[id]
+		if !exists {
+			s = &promKeySeries{
+				kind:           kind,
+				channel:        channel,
+				key:            snap.KeyMask,
+				latencyBuckets: make([]uint64, len(metrics.LatencyBucketBounds)),
+			}
+			byLabels[id] = s
+		}
+		s.requests += snap.RequestCount
+		s.failures += snap.FailureCount
+		s.inFlight += snap.ActiveRequests
+		s.circuitOpen = s.circuitOpen || snap.CircuitBroken
+		s.inputTokens += snap.InputTokens
+		s.outputTokens += snap.OutputTokens
+		s.cacheCreation += snap.CacheCreationTokens
+		s.cacheRead += snap.CacheReadTokens
+		s.cost += snap.Cost
+		for i, v := range snap.LatencyBuckets {
+			s.latencyBuckets[i] += v
+		}
+		s.latencyCount += snap.LatencyCount
+		s.latencySum += snap.LatencySum
+	}
+
+	result := make([]*promKeySeries, 0, len(byLabels))
+	for _, s := range byLabels {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].channel != result[j].channel {
+			return result[i].channel < result[j].channel
+		}
+		return result[i].key < result[j].key
+	})
+	return result
+}
+
+// writeKeySeries 输出 Key 级别的指标族
+func writeKeySeries(b *strings.Builder, series []*promKeySeries) {
+	writePromHeader(b, "requests_total", "Total upstream requests", "counter")
+	for _, s := range series {
+		fmt.Fprintf(b, "%s_requests_total%s %d\n", prometheusNamespace, s.labels(), s.requests)
+	}
+
+	writePromHeader(b, "request_failures_total", "Total failed upstream requests", "counter")
+	for _, s := range series {
+		fmt.Fprintf(b, "%s_request_failures_total%s %d\n", prometheusNamespace, s.labels(), s.failures)
+	}
+
+	writePromHeader(b, "requests_in_flight", "Upstream requests currently in flight", "gauge")
+	for _, s := range series {
+		fmt.Fprintf(b, "%s_requests_in_flight%s %d\n", prometheusNamespace, s.labels(), s.inFlight)
+	}
+
+	writePromHeader(b, "key_circuit_open", "Whether the key circuit breaker is open (1) or closed (0)", "gauge")
+	for _, s := range series {
+		open := 0
+		if s.circuitOpen {
+			open = 1
+		}
+		fmt.Fprintf(b, "%s_key_circuit_open%s %d\n", prometheusNamespace, s.labels(), open)
+	}
+
+	writePromHeader(b, "tokens_total", "Total tokens by type", "counter")
+	for _, s := range series {
+		for _, t := range []struct {
+			name  string
+			value int64
+		}{
+			{"input", s.inputTokens},
+			{"output", s.outputTokens},
+			{"cache_creation", s.cacheCreation},
+			{"cache_read", s.cacheRead},
+		} {
+			fmt.Fprintf(b, "%s_tokens_total%s %d\n", prometheusNamespace,
+				formatPromLabels("kind", s.kind, "channel", s.channel, "key", s.key, "type", t.name), t.value)
+		}
+	}
+
+	writePromHeader(b, "cost_usd_total", "Total cost in USD computed from the model price table", "counter")
+	for _, s := range series {
+		fmt.Fprintf(b, "%s_cost_usd_total%s %s\n", prometheusNamespace, s.labels(), formatPromFloat(s.cost))
+	}
+
+	writePromHeader(b, "request_duration_seconds", "Upstream request duration in seconds (including streaming)", "histogram")
+	for _, s := range series {
+		for i, bound := range metrics.LatencyBucketBounds {
+			fmt.Fprintf(b, "%s_request_duration_seconds_bucket%s %d\n", prometheusNamespace,
+				formatPromLabels("kind", s.kind, "channel", s.channel, "key", s.key, "le", formatPromFloat(bound)), s.latencyBuckets[i])
+		}
+		fmt.Fprintf(b, "%s_request_duration_seconds_bucket%s %d\n", prometheusNamespace,
+			formatPromLabels("kind", s.kind, "channel", s.channel, "key", s.key, "le", "+Inf"), s.latencyCount)
+		fmt.Fprintf(b, "%s_request_duration_seconds_sum%s %s\n", prometheusNamespace, s.labels(), formatPromFloat(s.latencySum))
+		fmt.Fprintf(b, "%s_request_duration_seconds_count%s %d\n", prometheusNamespace, s.labels(), s.latencyCount)
+	}
+}
+
+// writePromHeader 输出指标族的 HELP 与 TYPE 行
+func writePromHeader(b *strings.Builder, name, help, typ string) {
+	fmt.Fprintf(b, "# HELP %s_%s %s\n", prometheusNamespace, name, help)
+	fmt.Fprintf(b, "# TYPE %s_%s %s\n", prometheusNamespace, name, typ)
+}
+
+// formatPromLabels 按成对的 name/value 生成标签字符串
+func formatPromLabels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(escapePromLabelValue(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// escapePromLabelValue 转义标签值中的反斜杠、双引号与换行
+func escapePromLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatPromFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
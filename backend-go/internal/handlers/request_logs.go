@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/requestlog"
+	"github.com/gin-gonic/gin"
+)
+
+// requestLogDisabledError 未启用请求日志时的错误信息
+const requestLogDisabledError = "Request log is disabled (set REQUEST_LOG_ENABLED=true)"
+
+// ListRequestLogs 查询请求审计日志
+// GET /api/requests?clientId=&kind=&model=&channel=&status=success|failure|<code>&since=&until=&q=&limit=&offset=
+// since/until 支持 RFC3339 或 Unix 毫秒时间戳
+func ListRequestLogs(recorder *requestlog.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := recorder.Store()
+		if store == nil {
+			c.JSON(200, gin.H{
+				"enabled": false,
+				"items":   []requestlog.Entry{},
+				"total":   0,
+			})
+			return
+		}
+
+		filter := requestlog.Filter{
+			ClientID: c.Query("clientId"),
+			Kind:     c.Query("kind"),
+			Model:    c.Query("model"),
+			Channel:  c.Query("channel"),
+			Status:   c.Query("status"),
+			Query:    c.Query("q"),
+		}
+		var err error
+		if filter.Since, err = parseTimeQuery(c.Query("since")); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if filter.Until, err = parseTimeQuery(c.Query("until")); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+		filter.Offset, _ = strconv.Atoi(c.Query("offset"))
+
+		items, total, err := store.List(filter)
+		if err != nil {
+			c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to query request logs: %v", err)})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"enabled":       true,
+			"items":         items,
+			"total":         total,
+			"retentionDays": store.RetentionDays(),
+		})
+	}
+}
+
+// GetRequestLog 获取单条请求日志详情（含 failover 尝试明细与请求/响应体）
+// GET /api/requests/:id
+func GetRequestLog(recorder *requestlog.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := recorder.Store()
+		if store == nil {
+			c.JSON(404, gin.H{"error": requestLogDisabledError})
+			return
+		}
+
+		entry, err := store.Get(c.Param("id"))
+		if err != nil {
+			c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to query request log: %v", err)})
+			return
+		}
+		if entry == nil {
+			c.JSON(404, gin.H{"error": "Request log not found"})
+			return
+		}
+		c.JSON(200, entry)
+	}
+}
+
+// PurgeRequestLogs 删除指定时间之前的请求日志（未指定 before 时清空全部）
+// DELETE /api/requests?before=
+func PurgeRequestLogs(recorder *requestlog.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := recorder.Store()
+		if store == nil {
+			c.JSON(404, gin.H{"error": requestLogDisabledError})
+			return
+		}
+
+		before, err := parseTimeQuery(c.Query("before"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if before.IsZero() {
+			before = time.Now().Add(time.Second)
+		}
+
+		deleted, err := store.DeleteBefore(before)
+		if err != nil {
+			c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to delete request logs: %v", err)})
+			return
+		}
+		c.JSON(200, gin.H{
+			"success": true,
+			"deleted": deleted,
+		})
+	}
+}
+
+// parseTimeQuery 解析时间查询参数（RFC3339 或 Unix 毫秒时间戳），空值返回零值
+func parseTimeQuery(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC3339 or unix milliseconds", value)
+	}
+	return t, nil
+}
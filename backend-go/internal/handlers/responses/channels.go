@@ -2,10 +2,10 @@
 package responses
 
 import (
-	"strconv"
 	"strings"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/gin-gonic/gin"
 )
@@ -21,12 +21,14 @@ func GetUpstreams(cfgManager *config.ConfigManager) gin.HandlerFunc {
 			priority := config.GetChannelPriority(&up, i)
 
 			upstreams[i] = gin.H{
+				"id":                 up.ID,
 				"index":              i,
 				"name":               up.Name,
 				"serviceType":        up.ServiceType,
 				"baseUrl":            up.BaseURL,
 				"baseUrls":           up.BaseURLs,
-				"apiKeys":            up.APIKeys,
+				"apiKeys":            cfgManager.RedactAPIKeys(up.APIKeys),
+				"disabledApiKeys":    cfgManager.RedactDisabledAPIKeys(up.DisabledAPIKeys),
 				"description":        up.Description,
 				"website":            up.Website,
 				"insecureSkipVerify": up.InsecureSkipVerify,
@@ -67,10 +69,8 @@ func AddUpstream(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // UpdateUpstream 更新 Responses 上游
 func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindResponses)
+		if !ok {
 			return
 		}
 
@@ -79,6 +79,13 @@ func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
+		// 管理界面回传的已掩码 Key 还原为实际 Key
+		apiKeys, err := cfgManager.ResolveAPIKeyRefs(string(scheduler.ChannelKindResponses), id, updates.APIKeys)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		updates.APIKeys = apiKeys
 
 		shouldResetMetrics, err := cfgManager.UpdateResponsesUpstream(id, updates)
 		if err != nil {
@@ -98,10 +105,8 @@ func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 // DeleteUpstream 删除 Responses 上游
 func DeleteUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindResponses)
+		if !ok {
 			return
 		}
 
@@ -125,10 +130,8 @@ func DeleteUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 // AddApiKey 添加 Responses 渠道 API 密钥
 func AddApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindResponses)
+		if !ok {
 			return
 		}
 
@@ -141,7 +144,7 @@ func AddApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 		}
 
 		if err := cfgManager.AddResponsesAPIKey(id, req.APIKey); err != nil {
-			if strings.Contains(err.Error(), "无效的上游索引") {
+			if strings.Contains(err.Error(), "未找到渠道") {
 				c.JSON(404, gin.H{"error": "Upstream not found"})
 			} else if strings.Contains(err.Error(), "API密钥已存在") {
 				c.JSON(400, gin.H{"error": "API密钥已存在"})
@@ -161,21 +164,23 @@ func AddApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // DeleteApiKey 删除 Responses 渠道 API 密钥
 func DeleteApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid upstream ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindResponses)
+		if !ok {
 			return
 		}
 
-		apiKey := c.Param("apiKey")
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindResponses), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 		if apiKey == "" {
 			c.JSON(400, gin.H{"error": "API key is required"})
 			return
 		}
 
 		if err := cfgManager.RemoveResponsesAPIKey(id, apiKey); err != nil {
-			if strings.Contains(err.Error(), "无效的上游索引") {
+			if strings.Contains(err.Error(), "未找到渠道") {
 				c.JSON(404, gin.H{"error": "Upstream not found"})
 			} else if strings.Contains(err.Error(), "API密钥不存在") {
 				c.JSON(404, gin.H{"error": "API key not found"})
@@ -194,8 +199,15 @@ func DeleteApiKey(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // MoveApiKeyToTop 将 Responses 渠道 API 密钥移到最前面
 func MoveApiKeyToTop(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id, _ := strconv.Atoi(c.Param("id"))
-		apiKey := c.Param("apiKey")
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindResponses)
+		if !ok {
+			return
+		}
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindResponses), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 
 		if err := cfgManager.MoveResponsesAPIKeyToTop(id, apiKey); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
@@ -208,8 +220,15 @@ func MoveApiKeyToTop(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // MoveApiKeyToBottom 将 Responses 渠道 API 密钥移到最后面
 func MoveApiKeyToBottom(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id, _ := strconv.Atoi(c.Param("id"))
-		apiKey := c.Param("apiKey")
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindResponses)
+		if !ok {
+			return
+		}
+		apiKey, err := cfgManager.ResolveAPIKeyRef(string(scheduler.ChannelKindResponses), id, c.Param("apiKey"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 
 		if err := cfgManager.MoveResponsesAPIKeyToBottom(id, apiKey); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
@@ -272,10 +291,8 @@ func ReorderChannels(cfgManager *config.ConfigManager) gin.HandlerFunc {
 // SetChannelStatus 设置 Responses 渠道状态
 func SetChannelStatus(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "Invalid channel ID"})
+		id, ok := common.ResolveChannelParam(c, cfgManager, scheduler.ChannelKindResponses)
+		if !ok {
 			return
 		}
 
@@ -288,7 +305,7 @@ func SetChannelStatus(cfgManager *config.ConfigManager) gin.HandlerFunc {
 		}
 
 		if err := cfgManager.SetResponsesChannelStatus(id, req.Status); err != nil {
-			if strings.Contains(err.Error(), "无效的上游索引") {
+			if strings.Contains(err.Error(), "未找到渠道") {
 				c.JSON(404, gin.H{"error": "Channel not found"})
 			} else {
 				c.JSON(400, gin.H{"error": err.Error()})
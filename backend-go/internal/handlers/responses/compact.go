@@ -3,6 +3,7 @@
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -12,10 +13,12 @@
 	"github.com/BenedictKing/claude-proxy/internal/config"
 	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
 	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/quota"
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/BenedictKing/claude-proxy/internal/session"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
 )
 
 // compactError 封装 compact 请求错误
@@ -32,10 +35,11 @@ func CompactHandler(
 	cfgManager *config.ConfigManager,
 	_ *session.SessionManager,
 	channelScheduler *scheduler.ChannelScheduler,
+	quotaManager *quota.Manager,
 ) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		// 认证
-		middleware.ProxyAuthMiddleware(envCfg)(c)
+		middleware.ProxyAuthMiddleware(envCfg, cfgManager)(c)
 		if c.IsAborted() {
 			return
 		}
@@ -47,6 +51,18 @@ func CompactHandler(
 			return
 		}
 
+		// 客户端访问范围校验（入口类型 + 模型）
+		model := gjson.GetBytes(bodyBytes, "model").String()
+		if !middleware.CheckClientScope(c, string(scheduler.ChannelKindResponses), model) {
+			return
+		}
+
+		// 客户端配额校验（RPM + Token 预算），通过后绑定结算
+		if !middleware.CheckClientQuota(c, quotaManager, string(scheduler.ChannelKindResponses)) {
+			return
+		}
+		common.SetRequestModel(c, model)
+
 		// 提取对话标识用于 Trace 亲和性
 		userID := common.ExtractConversationID(c, bodyBytes)
 
@@ -56,7 +72,7 @@ func CompactHandler(
 		if isMultiChannel {
 			handleMultiChannelCompact(c, envCfg, cfgManager, channelScheduler, bodyBytes, userID)
 		} else {
-			handleSingleChannelCompact(c, envCfg, cfgManager, bodyBytes)
+			handleSingleChannelCompact(c, envCfg, cfgManager, channelScheduler, bodyBytes)
 		}
 	})
 }
@@ -66,6 +82,7 @@ func handleSingleChannelCompact(
 	c *gin.Context,
 	envCfg *config.EnvConfig,
 	cfgManager *config.ConfigManager,
+	channelScheduler *scheduler.ChannelScheduler,
 	bodyBytes []byte,
 ) {
 	upstream, err := cfgManager.GetCurrentResponsesUpstream()
@@ -84,7 +101,7 @@ func handleSingleChannelCompact(
 	var lastErr *compactError
 
 	for attempt := 0; attempt < len(upstream.APIKeys); attempt++ {
-		apiKey, err := cfgManager.GetNextResponsesAPIKey(upstream, failedKeys)
+		apiKey, err := channelScheduler.NextAPIKey(upstream, failedKeys, scheduler.ChannelKindResponses)
 		if err != nil {
 			break
 		}
@@ -138,10 +155,16 @@ func handleMultiChannelCompact(
 	failedChannels := make(map[int]bool)
 	maxAttempts := channelScheduler.GetActiveChannelCount(scheduler.ChannelKindResponses)
 	var lastErr *compactError
+	model := gjson.GetBytes(bodyBytes, "model").String()
 
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		selection, err := channelScheduler.SelectChannel(c.Request.Context(), userID, failedChannels, scheduler.ChannelKindResponses)
+		selection, err := channelScheduler.SelectChannel(c.Request.Context(), userID, failedChannels, scheduler.ChannelKindResponses, model)
 		if err != nil {
+			var notServed *scheduler.ModelNotServedError
+			if errors.As(err, &notServed) {
+				common.WriteModelNotServed(c, notServed, "Responses")
+				return
+			}
 			break
 		}
 
@@ -156,7 +179,7 @@ func handleMultiChannelCompact(
 			if successKey != "" {
 				channelScheduler.RecordSuccessWithUsage(upstream.BaseURL, successKey, nil, scheduler.ChannelKindResponses)
 				// 只有真正成功的请求才设置 Trace 亲和
-				channelScheduler.SetTraceAffinity(userID, channelIndex)
+				channelScheduler.SetTraceAffinity(userID, upstream.ID)
 			}
 			return
 		}
@@ -211,7 +234,7 @@ func tryCompactChannelWithAllKeys(
 	}
 
 	for attempt := 0; attempt < len(upstream.APIKeys); attempt++ {
-		apiKey, err := cfgManager.GetNextResponsesAPIKey(upstream, failedKeys)
+		apiKey, err := channelScheduler.NextAPIKey(upstream, failedKeys, scheduler.ChannelKindResponses)
 		if err != nil {
 			break
 		}
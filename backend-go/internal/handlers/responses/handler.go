@@ -17,11 +17,13 @@
 	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
 	"github.com/BenedictKing/claude-proxy/internal/middleware"
 	"github.com/BenedictKing/claude-proxy/internal/providers"
+	"github.com/BenedictKing/claude-proxy/internal/quota"
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/BenedictKing/claude-proxy/internal/session"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
 )
 
 // Handler Responses API 代理处理器
@@ -31,10 +33,11 @@ func Handler(
 	cfgManager *config.ConfigManager,
 	sessionManager *session.SessionManager,
 	channelScheduler *scheduler.ChannelScheduler,
+	quotaManager *quota.Manager,
 ) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		// 先进行认证
-		middleware.ProxyAuthMiddleware(envCfg)(c)
+		middleware.ProxyAuthMiddleware(envCfg, cfgManager)(c)
 		if c.IsAborted() {
 			return
 		}
@@ -54,6 +57,17 @@ func Handler(
 			_ = json.Unmarshal(bodyBytes, &responsesReq)
 		}
 
+		// 客户端访问范围校验（入口类型 + 模型）
+		if !middleware.CheckClientScope(c, string(scheduler.ChannelKindResponses), responsesReq.Model) {
+			return
+		}
+
+		// 客户端配额校验（RPM + Token 预算），通过后绑定结算
+		if !middleware.CheckClientQuota(c, quotaManager, string(scheduler.ChannelKindResponses)) {
+			return
+		}
+		common.SetRequestModel(c, responsesReq.Model)
+
 		// 提取对话标识用于 Trace 亲和性
 		userID := common.ExtractConversationID(c, bodyBytes)
 
@@ -95,14 +109,13 @@ func handleMultiChannel(
 		userID,
 		func(selection *scheduler.SelectionResult) common.MultiChannelAttemptResult {
 			upstream := selection.Upstream
-			channelIndex := selection.ChannelIndex
 
 			if upstream == nil {
 				return common.MultiChannelAttemptResult{}
 			}
 
 			baseURLs := upstream.GetAllBaseURLs()
-			sortedURLResults := channelScheduler.GetSortedURLsForChannel(scheduler.ChannelKindResponses, channelIndex, baseURLs)
+			sortedURLResults := channelScheduler.GetSortedURLsForChannel(scheduler.ChannelKindResponses, upstream.ID, baseURLs)
 
 			handled, successKey, successBaseURLIdx, failoverErr, usage, lastErr := common.TryUpstreamWithAllKeys(
 				c,
@@ -117,23 +130,23 @@ func(selection *scheduler.SelectionResult) common.MultiChannelAttemptResult {
 				bodyBytes,
 				responsesReq.Stream,
 				func(upstream *config.UpstreamConfig, failedKeys map[string]bool) (string, error) {
-					return cfgManager.GetNextResponsesAPIKey(upstream, failedKeys)
+					return channelScheduler.NextAPIKey(upstream, failedKeys, scheduler.ChannelKindResponses)
 				},
 				func(c *gin.Context, upstreamCopy *config.UpstreamConfig, apiKey string) (*http.Request, error) {
 					req, _, err := provider.ConvertToProviderRequest(c, upstreamCopy, apiKey)
 					return req, err
 				},
 				func(apiKey string) {
-					_ = cfgManager.DeprioritizeAPIKey(apiKey)
+					_ = cfgManager.DeprioritizeAPIKey(string(scheduler.ChannelKindResponses), upstream.ID, apiKey)
 				},
 				func(url string) {
-					channelScheduler.MarkURLFailure(scheduler.ChannelKindResponses, channelIndex, url)
+					channelScheduler.MarkURLFailure(scheduler.ChannelKindResponses, upstream.ID, url)
 				},
 				func(url string) {
-					channelScheduler.MarkURLSuccess(scheduler.ChannelKindResponses, channelIndex, url)
+					channelScheduler.MarkURLSuccess(scheduler.ChannelKindResponses, upstream.ID, url)
 				},
 				func(c *gin.Context, resp *http.Response, upstreamCopy *config.UpstreamConfig, apiKey string) (*types.Usage, error) {
-					return handleSuccess(c, resp, provider, upstream.ServiceType, envCfg, sessionManager, startTime, &responsesReq, bodyBytes)
+					return handleSuccess(c, resp, provider, upstreamCopy, envCfg, sessionManager, startTime, &responsesReq, bodyBytes)
 				},
 			)
 
@@ -202,21 +215,21 @@ func handleSingleChannel(
 		bodyBytes,
 		responsesReq.Stream,
 		func(upstream *config.UpstreamConfig, failedKeys map[string]bool) (string, error) {
-			return cfgManager.GetNextResponsesAPIKey(upstream, failedKeys)
+			return channelScheduler.NextAPIKey(upstream, failedKeys, scheduler.ChannelKindResponses)
 		},
 		func(c *gin.Context, upstreamCopy *config.UpstreamConfig, apiKey string) (*http.Request, error) {
 			req, _, err := provider.ConvertToProviderRequest(c, upstreamCopy, apiKey)
 			return req, err
 		},
 		func(apiKey string) {
-			if err := cfgManager.DeprioritizeAPIKey(apiKey); err != nil {
+			if err := cfgManager.DeprioritizeAPIKey(string(scheduler.ChannelKindResponses), upstream.ID, apiKey); err != nil {
 				log.Printf("[Responses-Key] 警告: 密钥降级失败: %v", err)
 			}
 		},
 		nil,
 		nil,
 		func(c *gin.Context, resp *http.Response, upstreamCopy *config.UpstreamConfig, apiKey string) (*types.Usage, error) {
-			return handleSuccess(c, resp, provider, upstream.ServiceType, envCfg, sessionManager, startTime, &responsesReq, bodyBytes)
+			return handleSuccess(c, resp, provider, upstreamCopy, envCfg, sessionManager, startTime, &responsesReq, bodyBytes)
 		},
 	)
 	if handled {
@@ -232,7 +245,7 @@ func handleSuccess(
 	c *gin.Context,
 	resp *http.Response,
 	provider *providers.ResponsesProvider,
-	upstreamType string,
+	upstream *config.UpstreamConfig,
 	envCfg *config.EnvConfig,
 	sessionManager *session.SessionManager,
 	startTime time.Time,
@@ -241,10 +254,13 @@ func handleSuccess(
 ) (*types.Usage, error) {
 	defer resp.Body.Close()
 
+	upstreamType := upstream.ServiceType
+
 	isStream := originalReq != nil && originalReq.Stream
 
 	if isStream {
-		return handleStreamSuccess(c, resp, upstreamType, envCfg, startTime, originalReq, originalRequestJSON), nil
+		firstContentTimeout := upstream.GetStreamFirstContentTimeout(time.Duration(envCfg.StreamFirstContentTimeout) * time.Second)
+		return handleStreamSuccess(c, resp, upstreamType, envCfg, firstContentTimeout, sessionManager, startTime, originalReq, originalRequestJSON)
 	}
 
 	// 非流式响应处理
@@ -298,10 +314,13 @@ func handleSuccess(
 	// Token 补全逻辑
 	patchResponsesUsage(responsesResp, originalRequestJSON, envCfg)
 
-	// 更新会话
-	if originalReq.Store == nil || *originalReq.Store {
+	// 更新会话（store=false 时既不记录会话，也不保存 response）
+	storeEnabled := originalReq.Store == nil || *originalReq.Store
+	sessionID := ""
+	if storeEnabled {
 		sess, err := sessionManager.GetOrCreateSession(originalReq.PreviousResponseID)
 		if err == nil {
+			sessionID = sess.ID
 			inputItems, _ := parseInputToItems(originalReq.Input)
 			for _, item := range inputItems {
 				sessionManager.AppendMessage(sess.ID, item, 0)
@@ -320,6 +339,12 @@ func handleSuccess(
 		}
 	}
 
+	if storeEnabled {
+		if respJSON, err := json.Marshal(responsesResp); err == nil {
+			storeResponse(c, sessionManager, sessionID, respJSON, originalRequestJSON)
+		}
+	}
+
 	utils.ForwardResponseHeaders(resp.Header, c.Writer)
 	c.JSON(200, responsesResp)
 
@@ -459,15 +484,29 @@ func handleStreamSuccess(
 	resp *http.Response,
 	upstreamType string,
 	envCfg *config.EnvConfig,
+	firstContentTimeout time.Duration,
+	sessionManager *session.SessionManager,
 	startTime time.Time,
 	originalReq *types.ResponsesRequest,
 	originalRequestJSON []byte,
-) *types.Usage {
+) (*types.Usage, error) {
 	if envCfg.EnableResponseLogs {
 		responseTime := time.Since(startTime).Milliseconds()
 		log.Printf("[Responses-Stream] Responses 流式响应开始: %dms, 状态: %d", responseTime, resp.StatusCode)
 	}
 
+	// 流式 failover：首个内容前只向客户端写出心跳，上游失败时由调用方切换 Key/渠道
+	heartbeatInterval := time.Duration(envCfg.StreamHeartbeatInterval) * time.Second
+	if envCfg.StreamFailoverEnabled {
+		stopPreludeHeartbeat := common.StartPreludeHeartbeat(c, heartbeatInterval, common.SSECommentHeartbeat)
+		body, err := common.AwaitSSEPrelude(c, resp.Body, upstreamType, firstContentTimeout)
+		stopPreludeHeartbeat()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = body
+	}
+
 	utils.ForwardResponseHeaders(resp.Header, c.Writer)
 
 	c.Header("Content-Type", "text/event-stream")
@@ -487,6 +526,10 @@ func handleStreamSuccess(
 	var converterState any
 
 	c.Status(resp.StatusCode)
+
+	stopHeartbeat := common.StartStreamHeartbeat(c, heartbeatInterval, common.SSECommentHeartbeat)
+	defer stopHeartbeat()
+
 	flusher, _ := c.Writer.(http.Flusher)
 
 	scanner := bufio.NewScanner(resp.Body)
@@ -501,6 +544,7 @@ func handleStreamSuccess(
 	hasUsage := false
 	needTokenPatch := false
 	clientGone := false
+	var completedResponse []byte // response.completed 事件中的完整 response（用于 store）
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -566,6 +610,7 @@ func handleStreamSuccess(
 					// 需要修补虚假值
 					eventToSend = patchResponsesCompletedEventUsage(event, originalRequestJSON, outputTextBuffer.String(), &collectedUsage, envCfg)
 				}
+				completedResponse = extractCompletedResponse(eventToSend)
 			}
 
 			// 转发给客户端
@@ -585,8 +630,26 @@ func handleStreamSuccess(
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Printf("[Responses-Stream] 警告: 流式响应读取错误: %v", err)
+	streamErr := scanner.Err()
+	if streamErr != nil {
+		log.Printf("[Responses-Stream] 警告: 流式响应读取错误: %v", streamErr)
+		if !clientGone && c.Request.Context().Err() == nil {
+			// 告知客户端流异常中断，避免把截断的响应当作完整结果
+			errorEvent, _ := json.Marshal(map[string]interface{}{
+				"type":    "error",
+				"code":    "upstream_stream_error",
+				"message": streamErr.Error(),
+			})
+			fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", errorEvent)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+
+	// 流式响应不参与会话链路，仅保存完整结束的 response 供检索（store=false 或流异常中断时跳过）
+	if streamErr == nil && completedResponse != nil && (originalReq.Store == nil || *originalReq.Store) {
+		storeResponse(c, sessionManager, "", completedResponse, originalRequestJSON)
 	}
 
 	if envCfg.EnableResponseLogs {
@@ -618,7 +681,7 @@ func handleStreamSuccess(
 	}
 
 	// 返回收集到的 usage 数据
-	return &types.Usage{
+	usage := &types.Usage{
 		InputTokens:                collectedUsage.InputTokens,
 		OutputTokens:               collectedUsage.OutputTokens,
 		CacheCreationInputTokens:   collectedUsage.CacheCreationInputTokens,
@@ -627,6 +690,14 @@ func handleStreamSuccess(
 		CacheCreation1hInputTokens: collectedUsage.CacheCreation1hInputTokens,
 		CacheTTL:                   collectedUsage.CacheTTL,
 	}
+	// 流中途异常（读取失败、空闲超时）需返回错误以记录渠道失败；客户端主动取消不计入
+	if streamErr != nil {
+		if ctxErr := c.Request.Context().Err(); ctxErr != nil {
+			return usage, ctxErr
+		}
+		return usage, streamErr
+	}
+	return usage, nil
 }
 
 // responsesStreamUsage 流式响应 usage 收集结构
@@ -846,6 +917,20 @@ func updateResponsesStreamUsage(collected *responsesStreamUsage, usageData respo
 	}
 }
 
+// extractCompletedResponse 从 response.completed 事件中提取 response 对象
+func extractCompletedResponse(event string) []byte {
+	for _, line := range strings.Split(event, "\n") {
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if response := gjson.Get(data, "response"); response.IsObject() {
+			return []byte(response.Raw)
+		}
+	}
+	return nil
+}
+
 // isResponsesCompletedEvent 检测是否为 response.completed 事件
 func isResponsesCompletedEvent(event string) bool {
 	return strings.Contains(event, `"type":"response.completed"`) ||
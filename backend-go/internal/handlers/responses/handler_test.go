@@ -0,0 +1,62 @@
+package responses
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// truncatedBody 返回给定内容后以读取错误结束，模拟上游流中途断开
+type truncatedBody struct {
+	io.Reader
+	err error
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		return n, b.err
+	}
+	return n, err
+}
+
+func (b *truncatedBody) Close() error { return nil }
+
+func TestHandleStreamSuccess_TruncatedStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sm := session.NewSessionManagerWithStore(session.NewMemoryStore(), time.Hour, 100, 100000)
+	envCfg := &config.EnvConfig{}
+
+	readErr := errors.New("connection reset by peer")
+	events := "event: response.completed\n" +
+		`data: {"type":"response.completed","response":{"id":"resp_cut","object":"response","status":"completed","usage":{"input_tokens":3,"output_tokens":2,"total_tokens":5}}}` + "\n\n"
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       &truncatedBody{Reader: strings.NewReader(events), err: readErr},
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/responses", nil)
+
+	_, err := handleStreamSuccess(c, resp, "responses", envCfg, time.Minute, sm, time.Now(), &types.ResponsesRequest{Model: "gpt-5", Stream: true}, []byte(`{"model":"gpt-5"}`))
+	if !errors.Is(err, readErr) {
+		t.Fatalf("stream read errors should be returned, got %v", err)
+	}
+	if !strings.Contains(w.Body.String(), "event: error") {
+		t.Fatalf("client should receive an error event: %s", w.Body.String())
+	}
+	if _, err := sm.GetResponse("resp_cut"); err == nil {
+		t.Fatal("truncated stream must not be stored")
+	}
+}
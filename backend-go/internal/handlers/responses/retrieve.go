@@ -0,0 +1,265 @@
+package responses
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// input_items 分页参数
+const (
+	defaultInputItemsLimit = 20
+	maxInputItemsLimit     = 100
+)
+
+// RetrieveHandler 获取已存储的 response
+// GET /v1/responses/:id
+func RetrieveHandler(
+	envCfg *config.EnvConfig,
+	cfgManager *config.ConfigManager,
+	sessionManager *session.SessionManager,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stored, ok := loadStoredResponse(c, envCfg, cfgManager, sessionManager)
+		if !ok {
+			return
+		}
+		c.Data(200, "application/json; charset=utf-8", stored.Response)
+	}
+}
+
+// DeleteHandler 删除已存储的 response
+// DELETE /v1/responses/:id - 删除后该 ID 不能再作为 previous_response_id 使用
+func DeleteHandler(
+	envCfg *config.EnvConfig,
+	cfgManager *config.ConfigManager,
+	sessionManager *session.SessionManager,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stored, ok := loadStoredResponse(c, envCfg, cfgManager, sessionManager)
+		if !ok {
+			return
+		}
+
+		if err := sessionManager.DeleteResponse(stored.ID); err != nil {
+			if errors.Is(err, session.ErrResponseNotFound) {
+				writeResponseNotFound(c, stored.ID)
+				return
+			}
+			log.Printf("[Responses-Delete] 删除 response 失败: %s, %v", stored.ID, err)
+			writeResponsesAPIError(c, 500, "server_error", fmt.Sprintf("Failed to delete response: %v", err))
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"id":      stored.ID,
+			"object":  "response",
+			"deleted": true,
+		})
+	}
+}
+
+// InputItemsHandler 列出已存储 response 的输入项
+// GET /v1/responses/:id/input_items?limit=&order=asc|desc&after=
+func InputItemsHandler(
+	envCfg *config.EnvConfig,
+	cfgManager *config.ConfigManager,
+	sessionManager *session.SessionManager,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stored, ok := loadStoredResponse(c, envCfg, cfgManager, sessionManager)
+		if !ok {
+			return
+		}
+
+		limit := defaultInputItemsLimit
+		if raw := c.Query("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxInputItemsLimit {
+				writeResponsesAPIError(c, 400, "invalid_request_error",
+					fmt.Sprintf("Invalid 'limit': expected an integer between 1 and %d.", maxInputItemsLimit))
+				return
+			}
+			limit = n
+		}
+
+		order := c.DefaultQuery("order", "desc")
+		if order != "asc" && order != "desc" {
+			writeResponsesAPIError(c, 400, "invalid_request_error", "Invalid 'order': expected 'asc' or 'desc'.")
+			return
+		}
+
+		data, hasMore, err := paginateInputItems(stored.InputItems, order, c.Query("after"), limit)
+		if err != nil {
+			writeResponsesAPIError(c, 400, "invalid_request_error", err.Error())
+			return
+		}
+
+		var firstID, lastID interface{}
+		if len(data) > 0 {
+			firstID = gjson.GetBytes(data[0], "id").String()
+			lastID = gjson.GetBytes(data[len(data)-1], "id").String()
+		}
+
+		c.JSON(200, gin.H{
+			"object":   "list",
+			"data":     data,
+			"first_id": firstID,
+			"last_id":  lastID,
+			"has_more": hasMore,
+		})
+	}
+}
+
+// loadStoredResponse 认证并加载当前客户端可访问的 response
+// 下游客户端只能访问自己创建的 response，其他客户端的 response 一律按不存在处理
+func loadStoredResponse(
+	c *gin.Context,
+	envCfg *config.EnvConfig,
+	cfgManager *config.ConfigManager,
+	sessionManager *session.SessionManager,
+) (*session.StoredResponse, bool) {
+	middleware.ProxyAuthMiddleware(envCfg, cfgManager)(c)
+	if c.IsAborted() {
+		return nil, false
+	}
+
+	if !middleware.CheckClientScope(c, string(scheduler.ChannelKindResponses), "") {
+		return nil, false
+	}
+
+	responseID := c.Param("id")
+	stored, err := sessionManager.GetResponse(responseID)
+	if err != nil {
+		if errors.Is(err, session.ErrResponseNotFound) {
+			writeResponseNotFound(c, responseID)
+			return nil, false
+		}
+		log.Printf("[Responses-Retrieve] 查询 response 失败: %s, %v", responseID, err)
+		writeResponsesAPIError(c, 500, "server_error", fmt.Sprintf("Failed to retrieve response: %v", err))
+		return nil, false
+	}
+
+	if client := middleware.GetClient(c); client != nil && stored.ClientID != client.ID {
+		writeResponseNotFound(c, responseID)
+		return nil, false
+	}
+	return stored, true
+}
+
+// paginateInputItems 按 order/after/limit 分页输入项
+func paginateInputItems(items []json.RawMessage, order, after string, limit int) ([]json.RawMessage, bool, error) {
+	ordered := make([]json.RawMessage, len(items))
+	copy(ordered, items)
+	if order == "desc" {
+		for i, j := 0, len(ordered)-1; i < j; i, j = i+1, j-1 {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		}
+	}
+
+	start := 0
+	if after != "" {
+		found := false
+		for i, item := range ordered {
+			if gjson.GetBytes(item, "id").String() == after {
+				start = i + 1
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false, fmt.Errorf("Input item with id '%s' not found.", after)
+		}
+	}
+
+	end := start + limit
+	if end > len(ordered) {
+		end = len(ordered)
+	}
+	return ordered[start:end], end < len(ordered), nil
+}
+
+// buildStoredInputItems 将请求 input 转换为可存储的输入项（字符串 input 视为一条用户消息，缺失的 id 自动补全）
+func buildStoredInputItems(requestBody []byte) []json.RawMessage {
+	input := gjson.GetBytes(requestBody, "input")
+	items := []json.RawMessage{}
+
+	switch {
+	case input.Type == gjson.String:
+		item, _ := json.Marshal(map[string]interface{}{
+			"type": "message",
+			"role": "user",
+			"content": []map[string]string{
+				{"type": "input_text", "text": input.String()},
+			},
+		})
+		items = append(items, ensureInputItemID(item))
+	case input.IsArray():
+		for _, raw := range input.Array() {
+			if !raw.IsObject() {
+				continue
+			}
+			items = append(items, ensureInputItemID([]byte(raw.Raw)))
+		}
+	}
+	return items
+}
+
+// ensureInputItemID 为缺少 id 的输入项生成 msg_ 前缀的 ID
+func ensureInputItemID(item []byte) json.RawMessage {
+	if gjson.GetBytes(item, "id").String() != "" {
+		return item
+	}
+	id := "msg_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	if patched, err := sjson.SetBytes(item, "id", id); err == nil {
+		return patched
+	}
+	return item
+}
+
+// storeResponse 保存 response 供 GET/DELETE /v1/responses/{id} 查询（失败仅记录日志，不影响本次请求）
+func storeResponse(c *gin.Context, sessionManager *session.SessionManager, sessionID string, response []byte, requestBody []byte) {
+	responseID := gjson.GetBytes(response, "id").String()
+	if sessionManager == nil || responseID == "" {
+		return
+	}
+	err := sessionManager.StoreResponse(&session.StoredResponse{
+		ID:         responseID,
+		SessionID:  sessionID,
+		ClientID:   middleware.GetClientID(c),
+		Response:   response,
+		InputItems: buildStoredInputItems(requestBody),
+	})
+	if err != nil {
+		log.Printf("[Responses-Store] 保存 response 失败: %s, %v", responseID, err)
+	}
+}
+
+// writeResponseNotFound 返回 OpenAI 格式的 404 错误
+func writeResponseNotFound(c *gin.Context, responseID string) {
+	writeResponsesAPIError(c, 404, "invalid_request_error", fmt.Sprintf("Response with id '%s' not found.", responseID))
+}
+
+// writeResponsesAPIError 返回 OpenAI 格式的错误
+func writeResponsesAPIError(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    errType,
+			"param":   nil,
+			"code":    nil,
+		},
+	})
+}
@@ -0,0 +1,115 @@
+package responses
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func newRetrieveTestRouter(t *testing.T, client *config.ClientKey) (*gin.Engine, *session.SessionManager) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret"}
+	sm := session.NewSessionManagerWithStore(session.NewMemoryStore(), time.Hour, 100, 100000)
+
+	r := gin.New()
+	if client != nil {
+		r.Use(func(c *gin.Context) { c.Set(middleware.ClientContextKey, client) })
+	}
+	r.GET("/v1/responses/:id", RetrieveHandler(envCfg, nil, sm))
+	r.DELETE("/v1/responses/:id", DeleteHandler(envCfg, nil, sm))
+	r.GET("/v1/responses/:id/input_items", InputItemsHandler(envCfg, nil, sm))
+	return r, sm
+}
+
+func doRetrieveRequest(r *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("x-api-key", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRetrieveHandlers(t *testing.T) {
+	r, sm := newRetrieveTestRouter(t, nil)
+
+	requestBody := []byte(`{"model":"gpt-5","input":[
+		{"type":"message","role":"user","content":"a"},
+		{"id":"msg_b","type":"message","role":"user","content":"b"},
+		{"type":"message","role":"user","content":"c"}
+	]}`)
+	sm.StoreResponse(&session.StoredResponse{
+		ID:         "resp_1",
+		Response:   json.RawMessage(`{"id":"resp_1","object":"response","status":"completed"}`),
+		InputItems: buildStoredInputItems(requestBody),
+	})
+
+	w := doRetrieveRequest(r, http.MethodGet, "/v1/responses/resp_1")
+	if w.Code != 200 || gjson.Get(w.Body.String(), "status").String() != "completed" {
+		t.Fatalf("retrieve: status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	// 默认倒序，after 游标分页
+	w = doRetrieveRequest(r, http.MethodGet, "/v1/responses/resp_1/input_items?limit=1&after=msg_b")
+	body := w.Body.String()
+	if w.Code != 200 || gjson.Get(body, "data.#").Int() != 1 || gjson.Get(body, "data.0.content").String() != "a" || gjson.Get(body, "has_more").Bool() {
+		t.Fatalf("input_items desc: status=%d body=%s", w.Code, body)
+	}
+	w = doRetrieveRequest(r, http.MethodGet, "/v1/responses/resp_1/input_items?order=asc&limit=2")
+	body = w.Body.String()
+	if gjson.Get(body, "data.1.id").String() != "msg_b" || !gjson.Get(body, "has_more").Bool() || gjson.Get(body, "first_id").String() == "" {
+		t.Fatalf("input_items asc: body=%s", body)
+	}
+	if w = doRetrieveRequest(r, http.MethodGet, "/v1/responses/resp_1/input_items?limit=0"); w.Code != 400 {
+		t.Fatalf("expected 400 for invalid limit, got %d", w.Code)
+	}
+
+	w = doRetrieveRequest(r, http.MethodDelete, "/v1/responses/resp_1")
+	if w.Code != 200 || !gjson.Get(w.Body.String(), "deleted").Bool() {
+		t.Fatalf("delete: status=%d body=%s", w.Code, w.Body.String())
+	}
+	w = doRetrieveRequest(r, http.MethodGet, "/v1/responses/resp_1")
+	if w.Code != 404 || gjson.Get(w.Body.String(), "error.type").String() != "invalid_request_error" {
+		t.Fatalf("expected 404 after delete, got status=%d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestRetrieveHandler_ClientIsolation(t *testing.T) {
+	r, sm := newRetrieveTestRouter(t, &config.ClientKey{ID: "client-b", Name: "b"})
+	sm.StoreResponse(&session.StoredResponse{
+		ID:       "resp_a",
+		ClientID: "client-a",
+		Response: json.RawMessage(`{"id":"resp_a"}`),
+	})
+
+	if w := doRetrieveRequest(r, http.MethodGet, "/v1/responses/resp_a"); w.Code != 404 {
+		t.Fatalf("expected 404 for other client's response, got %d", w.Code)
+	}
+	if w := doRetrieveRequest(r, http.MethodDelete, "/v1/responses/resp_a"); w.Code != 404 {
+		t.Fatalf("expected 404 when deleting other client's response, got %d", w.Code)
+	}
+	if _, err := sm.GetResponse("resp_a"); err != nil {
+		t.Fatalf("response should not be deleted by another client: %v", err)
+	}
+}
+
+func TestBuildStoredInputItems_StringInput(t *testing.T) {
+	items := buildStoredInputItems([]byte(`{"input":"hello"}`))
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	item := gjson.ParseBytes(items[0])
+	if item.Get("role").String() != "user" || item.Get("content.0.type").String() != "input_text" ||
+		item.Get("content.0.text").String() != "hello" || item.Get("id").String() == "" {
+		t.Fatalf("unexpected item: %s", items[0])
+	}
+}
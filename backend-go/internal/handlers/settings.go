@@ -3,6 +3,7 @@
 
 import (
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/pricing"
 	"github.com/gin-gonic/gin"
 )
 
@@ -37,3 +38,40 @@ func SetFuzzyMode(cfgManager *config.ConfigManager) gin.HandlerFunc {
 		})
 	}
 }
+
+// GetModelPrices 获取模型价格表（内置价格 + 配置覆盖，单位：美元/百万 Token）
+func GetModelPrices(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"defaults":  pricing.DefaultPrices(),
+			"overrides": cfgManager.GetModelPrices(),
+		})
+	}
+}
+
+// SetModelPrices 整体替换模型价格覆盖
+func SetModelPrices(cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Overrides map[string]pricing.ModelPrice `json:"overrides"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		if err := cfgManager.SetModelPrices(req.Overrides); err != nil {
+			if _, ok := err.(*config.ConfigError); ok {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(500, gin.H{"error": "Failed to save config"})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"success":   true,
+			"overrides": cfgManager.GetModelPrices(),
+		})
+	}
+}
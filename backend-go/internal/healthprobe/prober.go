@@ -0,0 +1,414 @@
+// Package healthprobe 提供渠道与 Key 的后台主动健康探测
+//
+// 被动指标只有在真实流量经过时才会更新：暂停的渠道永远不会被调度，熔断的 Key 只能等
+// recoverExpiredCircuitBreakers 超时恢复。Prober 定期向暂停/熔断/空闲的渠道与 Key 发送
+// 一次低成本的真实请求，将结果写入 MetricsManager 与 URLManager，并在探测成功时自动恢复暂停的渠道。
+package healthprobe
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+)
+
+// 探测原因
+const (
+	ReasonSuspended     = "suspended"      // 渠道处于暂停状态
+	ReasonCircuitBroken = "circuit_broken" // Key 已熔断或失败率超过阈值
+	ReasonIdle          = "idle"           // Key 长时间无流量
+)
+
+// 探测结果
+const (
+	OutcomeSuccess      = "success"
+	OutcomeFailure      = "failure"
+	OutcomeInconclusive = "inconclusive" // 上游拒绝了探测请求本身（如模型不存在），不计入渠道指标
+)
+
+const (
+	maxHistory     = 500              // 保留的探测记录数
+	checkInterval  = 30 * time.Second // 检查是否到达探测周期的频率
+	maxConcurrency = 4                // 单个周期内并发探测数
+	probeMaxTokens = 16               // 探测请求的最大输出 token
+	probeLogPrefix = "HealthProbe"
+)
+
+// probeKinds 参与探测的入口类型
+var probeKinds = []scheduler.ChannelKind{
+	scheduler.ChannelKindMessages,
+	scheduler.ChannelKindResponses,
+	scheduler.ChannelKindGemini,
+	scheduler.ChannelKindChat,
+}
+
+// Result 单次探测记录
+type Result struct {
+	Time         time.Time `json:"time"`
+	Kind         string    `json:"kind"`
+	ChannelID    string    `json:"channelId"`    // 渠道唯一标识（索引会随排序/删除变化）
+	ChannelIndex int       `json:"channelIndex"` // 探测时的渠道索引
+	ChannelName  string    `json:"channelName"`
+	BaseURL      string    `json:"baseUrl"`
+	KeyMask      string    `json:"keyMask"`
+	Model        string    `json:"model"`
+	Reason       string    `json:"reason"`
+	Outcome      string    `json:"outcome"`
+	StatusCode   int       `json:"statusCode,omitempty"`
+	LatencyMs    int64     `json:"latencyMs"`
+	Error        string    `json:"error,omitempty"`
+	Restored     bool      `json:"restored,omitempty"` // 探测成功后渠道已从 suspended 恢复为 active
+}
+
+// job 一个探测任务：同一渠道的一组候选 Key，按顺序探测直到成功
+// 暂停渠道会依次尝试所有 Key，熔断/空闲 Key 各自独立成任务
+type job struct {
+	kind     scheduler.ChannelKind
+	index    int
+	upstream *config.UpstreamConfig
+	baseURL  string
+	apiKeys  []string
+	reason   string
+}
+
+// Prober 后台健康探测器
+type Prober struct {
+	cfgManager *config.ConfigManager
+	scheduler  *scheduler.ChannelScheduler
+	client     probeClient
+
+	mu      sync.RWMutex
+	history []Result
+	lastRun time.Time
+
+	runMu    sync.Mutex // 保证同一时间只有一个探测周期
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewProber 创建后台健康探测器
+func NewProber(cfgManager *config.ConfigManager, sch *scheduler.ChannelScheduler) *Prober {
+	return &Prober{
+		cfgManager: cfgManager,
+		scheduler:  sch,
+		client:     httpProbeClient{},
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Start 启动后台探测循环（是否实际探测由配置 healthProbe.enabled 控制，支持热更新）
+func (p *Prober) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				probeCfg := p.cfgManager.GetHealthProbeConfig()
+				if !probeCfg.Enabled || time.Since(p.LastRun()) < time.Duration(probeCfg.Interval())*time.Second {
+					continue
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				go func() {
+					select {
+					case <-p.stopCh:
+						cancel()
+					case <-ctx.Done():
+					}
+				}()
+				p.RunOnce(ctx)
+				cancel()
+			case <-p.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台探测循环并等待进行中的探测结束
+func (p *Prober) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+	})
+	p.wg.Wait()
+}
+
+// LastRun 最近一次探测周期的开始时间
+func (p *Prober) LastRun() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastRun
+}
+
+// RunOnce 立即执行一个探测周期，返回本周期的探测结果
+func (p *Prober) RunOnce(ctx context.Context) []Result {
+	p.runMu.Lock()
+	defer p.runMu.Unlock()
+
+	p.mu.Lock()
+	p.lastRun = time.Now()
+	p.mu.Unlock()
+
+	probeCfg := p.cfgManager.GetHealthProbeConfig()
+	jobs := p.collectJobs(probeCfg)
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	// 按任务顺序保存结果，保证记录顺序与探测优先级一致
+	jobResults := make([][]Result, len(jobs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrency)
+	for i, j := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, j job) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			jobResults[i] = p.runJob(ctx, j, probeCfg)
+		}(i, j)
+	}
+	wg.Wait()
+
+	var results []Result
+	for _, r := range jobResults {
+		results = append(results, r...)
+	}
+
+	p.appendHistory(results)
+
+	succeeded, restored := 0, 0
+	for _, r := range results {
+		if r.Outcome == OutcomeSuccess {
+			succeeded++
+		}
+		if r.Restored {
+			restored++
+		}
+	}
+	log.Printf("[%s] 探测周期完成: %d 次探测, %d 次成功, %d 个渠道已恢复", probeLogPrefix, len(results), succeeded, restored)
+	return results
+}
+
+// collectJobs 收集本周期需要探测的渠道与 Key（暂停渠道 > 熔断 Key > 空闲 Key），总探测次数受 MaxPerCycle 限制
+func (p *Prober) collectJobs(probeCfg *config.HealthProbeConfig) []job {
+	cfg := p.cfgManager.GetConfig()
+	idle := time.Duration(probeCfg.Idle()) * time.Second
+	now := time.Now()
+
+	var suspended, broken, idleJobs []job
+	for _, kind := range probeKinds {
+		metricsManager := p.scheduler.GetMetricsManager(kind)
+		for i, upstream := range cfg.UpstreamsForKind(string(kind)) {
+			if upstream.Status == "disabled" || len(upstream.APIKeys) == 0 {
+				continue
+			}
+			baseURLs := upstream.GetAllBaseURLs()
+			if len(baseURLs) == 0 {
+				continue
+			}
+			up := upstream.Clone()
+
+			if upstream.Status == "suspended" {
+				suspended = append(suspended, job{kind: kind, index: i, upstream: up, baseURL: baseURLs[0], apiKeys: up.APIKeys, reason: ReasonSuspended})
+				continue
+			}
+
+			for _, baseURL := range baseURLs {
+				for _, apiKey := range upstream.APIKeys {
+					j := job{kind: kind, index: i, upstream: up, baseURL: baseURL, apiKeys: []string{apiKey}}
+					switch {
+					case isKeyBroken(metricsManager, baseURL, apiKey):
+						j.reason = ReasonCircuitBroken
+						broken = append(broken, j)
+					case idle > 0 && isKeyIdle(metricsManager.GetKeyMetrics(baseURL, apiKey), now, idle):
+						j.reason = ReasonIdle
+						idleJobs = append(idleJobs, j)
+					}
+				}
+			}
+		}
+	}
+
+	budget := probeCfg.ProbesPerCycle()
+	var jobs []job
+	for _, group := range [][]job{suspended, broken, idleJobs} {
+		for _, j := range group {
+			if budget <= 0 {
+				return jobs
+			}
+			if len(j.apiKeys) > budget {
+				j.apiKeys = j.apiKeys[:budget]
+			}
+			budget -= len(j.apiKeys)
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs
+}
+
+// isKeyBroken Key 已熔断或失败率超过阈值
+func isKeyBroken(metricsManager *metrics.MetricsManager, baseURL, apiKey string) bool {
+	if km := metricsManager.GetKeyMetrics(baseURL, apiKey); km != nil && km.CircuitBrokenAt != nil {
+		return true
+	}
+	return !metricsManager.IsKeyHealthy(baseURL, apiKey)
+}
+
+// isKeyIdle Key 在 idle 时间内没有任何成功或失败记录
+func isKeyIdle(km *metrics.KeyMetrics, now time.Time, idle time.Duration) bool {
+	if km == nil {
+		return true
+	}
+	var lastActivity time.Time
+	if km.LastSuccessAt != nil {
+		lastActivity = *km.LastSuccessAt
+	}
+	if km.LastFailureAt != nil && km.LastFailureAt.After(lastActivity) {
+		lastActivity = *km.LastFailureAt
+	}
+	return now.Sub(lastActivity) >= idle
+}
+
+// runJob 执行一个探测任务并记录指标，暂停渠道探测成功时自动恢复
+func (p *Prober) runJob(ctx context.Context, j job, probeCfg *config.HealthProbeConfig) []Result {
+	target := probeCfg.TargetForKind(string(j.kind))
+	timeout := time.Duration(probeCfg.Timeout()) * time.Second
+	metricsManager := p.scheduler.GetMetricsManager(j.kind)
+
+	var results []Result
+	for _, apiKey := range j.apiKeys {
+		if ctx.Err() != nil {
+			break
+		}
+		r := p.probe(ctx, j, apiKey, target, timeout)
+		if ctx.Err() != nil {
+			// 服务关闭导致的中断不计入渠道指标
+			break
+		}
+
+		switch r.Outcome {
+		case OutcomeSuccess:
+			metricsManager.ResetKeyFailureState(j.baseURL, apiKey)
+			metricsManager.RecordSuccess(j.baseURL, apiKey)
+			p.scheduler.MarkURLSuccess(j.kind, j.upstream.ID, j.baseURL)
+		case OutcomeFailure:
+			metricsManager.RecordFailure(j.baseURL, apiKey)
+			p.scheduler.MarkURLFailure(j.kind, j.upstream.ID, j.baseURL)
+		}
+
+		if r.Outcome == OutcomeSuccess && j.reason == ReasonSuspended && probeCfg.AutoRestore {
+			r.Restored = p.restoreChannel(j)
+		}
+		results = append(results, r)
+
+		if r.Outcome == OutcomeSuccess {
+			break
+		}
+	}
+	return results
+}
+
+// restoreChannel 将暂停的渠道恢复为 active（按渠道标识定位，渠道在探测期间被删除或手动修改状态时跳过）
+func (p *Prober) restoreChannel(j job) bool {
+	index, err := p.cfgManager.RestoreSuspendedChannel(string(j.kind), j.upstream.ID)
+	if err != nil {
+		log.Printf("[%s-Restore] 警告: 恢复渠道 [%s] %s 失败: %v", probeLogPrefix, j.kind, j.upstream.Name, err)
+		return false
+	}
+	if index < 0 {
+		return false
+	}
+	p.scheduler.ResetChannelMetrics(j.upstream.ID, j.kind)
+	log.Printf("[%s-Restore] 渠道 [%s/%d] %s 探测成功，已自动恢复为 active", probeLogPrefix, j.kind, index, j.upstream.Name)
+	return true
+}
+
+// probe 向单个 BaseURL + Key 发送探测请求
+func (p *Prober) probe(ctx context.Context, j job, apiKey string, target config.HealthProbeTarget, timeout time.Duration) Result {
+	model := probeModel(j.upstream, target.Model)
+	r := Result{
+		Time:         time.Now(),
+		Kind:         string(j.kind),
+		ChannelID:    j.upstream.ID,
+		ChannelIndex: j.index,
+		ChannelName:  j.upstream.Name,
+		BaseURL:      j.baseURL,
+		KeyMask:      utils.MaskAPIKey(apiKey),
+		Model:        model,
+		Reason:       j.reason,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := buildProbeRequest(ctx, j.upstream, j.baseURL, apiKey, model, target.Prompt)
+	if err != nil {
+		r.Outcome = OutcomeInconclusive
+		r.Error = err.Error()
+		return r
+	}
+
+	start := time.Now()
+	statusCode, body, err := p.client.Do(req, j.upstream.InsecureSkipVerify, timeout)
+	r.LatencyMs = time.Since(start).Milliseconds()
+	r.StatusCode = statusCode
+	r.Outcome = classifyProbe(statusCode, err)
+	if err != nil {
+		r.Error = err.Error()
+	} else if r.Outcome != OutcomeSuccess {
+		r.Error = truncate(string(body), 300)
+	}
+	return r
+}
+
+// appendHistory 追加探测记录（超过上限时丢弃最旧的记录）
+func (p *Prober) appendHistory(results []Result) {
+	if len(results) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.history = append(p.history, results...)
+	if over := len(p.history) - maxHistory; over > 0 {
+		p.history = append([]Result(nil), p.history[over:]...)
+	}
+}
+
+// History 查询探测记录（按时间倒序）
+// kind 为空表示全部入口类型；channelID 为空表示全部渠道；limit <= 0 表示不限制
+func (p *Prober) History(kind, channelID string, limit int) []Result {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	results := make([]Result, 0)
+	for i := len(p.history) - 1; i >= 0; i-- {
+		r := p.history[i]
+		if kind != "" && r.Kind != kind {
+			continue
+		}
+		if channelID != "" && r.ChannelID != channelID {
+			continue
+		}
+		results = append(results, r)
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+	}
+	return results
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
@@ -0,0 +1,164 @@
+package healthprobe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/warmup"
+)
+
+func newTestProber(t *testing.T, cfg config.Config) (*Prober, *config.ConfigManager) {
+	t.Helper()
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	data, _ := json.Marshal(cfg)
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	cfgManager, err := config.NewConfigManager(configFile)
+	if err != nil {
+		t.Fatalf("创建配置管理器失败: %v", err)
+	}
+	t.Cleanup(func() { cfgManager.Close() })
+
+	sch := scheduler.NewChannelScheduler(cfgManager,
+		metrics.NewMetricsManager(), metrics.NewMetricsManager(), metrics.NewMetricsManager(), metrics.NewMetricsManager(),
+		session.NewTraceAffinityManager(), warmup.NewURLManager(time.Second, 3))
+	return NewProber(cfgManager, sch), cfgManager
+}
+
+func TestProber_RestoresSuspendedChannel(t *testing.T) {
+	var gotPath, gotModel atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotPath.Store(r.URL.Path)
+		gotModel.Store(body["model"])
+		if r.Header.Get("x-api-key") == "sk-ant-bad" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"type":"message"}`))
+	}))
+	defer upstream.Close()
+
+	prober, cfgManager := newTestProber(t, config.Config{
+		Upstream: []config.UpstreamConfig{{
+			Name:         "claude-a",
+			ServiceType:  "claude",
+			BaseURL:      upstream.URL,
+			APIKeys:      []string{"sk-ant-bad", "sk-ant-good"},
+			Status:       "suspended",
+			ModelMapping: map[string]string{"haiku": "claude-haiku-mapped"},
+		}},
+		HealthProbe: &config.HealthProbeConfig{
+			AutoRestore: true,
+			Targets:     map[string]config.HealthProbeTarget{"messages": {Model: "haiku"}},
+		},
+	})
+
+	results := prober.RunOnce(context.Background())
+	if len(results) != 2 {
+		t.Fatalf("expected 2 probes (bad key then good key), got %d: %+v", len(results), results)
+	}
+	if results[0].Outcome != OutcomeFailure || results[1].Outcome != OutcomeSuccess || !results[1].Restored {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if gotPath.Load() != "/v1/messages" || gotModel.Load() != "claude-haiku-mapped" {
+		t.Fatalf("unexpected probe request: path=%v model=%v", gotPath.Load(), gotModel.Load())
+	}
+	if status := cfgManager.GetConfig().Upstream[0].Status; status != "active" {
+		t.Fatalf("channel status = %q, want active", status)
+	}
+	if h := prober.History("messages", cfgManager.GetConfig().Upstream[0].ID, 1); len(h) != 1 || !h[0].Restored {
+		t.Fatalf("history should return latest result first: %+v", h)
+	}
+}
+
+func TestProber_ProbesBrokenAndIdleKeys(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer broken":
+			w.Write([]byte(`{"choices":[]}`))
+		case "Bearer bad-model":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer upstream.Close()
+
+	prober, _ := newTestProber(t, config.Config{
+		ChatUpstream: []config.UpstreamConfig{{
+			Name:        "openai-a",
+			ServiceType: "openai",
+			BaseURL:     upstream.URL,
+			APIKeys:     []string{"broken", "bad-model", "down", "busy"},
+			Status:      "active",
+		}},
+		HealthProbe: &config.HealthProbeConfig{MaxPerCycle: 3},
+	})
+	mm := prober.scheduler.GetMetricsManager(scheduler.ChannelKindChat)
+	for i := 0; i < 5; i++ {
+		mm.RecordFailure(upstream.URL, "broken")
+	}
+	mm.RecordSuccess(upstream.URL, "busy")
+
+	results := prober.RunOnce(context.Background())
+	outcomes := map[string]string{}
+	for _, r := range results {
+		outcomes[r.KeyMask+"/"+r.Reason] = r.Outcome
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 probes (busy key is recently active), got %+v", results)
+	}
+	if !mm.IsKeyHealthy(upstream.URL, "broken") {
+		t.Fatalf("broken key should recover after successful probe: %v", outcomes)
+	}
+	if results[0].Reason != ReasonCircuitBroken || results[0].Outcome != OutcomeSuccess {
+		t.Fatalf("broken key should be probed first: %+v", results[0])
+	}
+	for _, r := range results[1:] {
+		if r.Reason != ReasonIdle {
+			t.Fatalf("unexpected reason: %+v", r)
+		}
+	}
+	if km := mm.GetKeyMetrics(upstream.URL, "bad-model"); km != nil {
+		t.Fatalf("inconclusive probe should not be recorded: %+v", km)
+	}
+	if km := mm.GetKeyMetrics(upstream.URL, "down"); km == nil || km.FailureCount != 1 {
+		t.Fatalf("failed probe should be recorded: %+v", km)
+	}
+}
+
+func TestBuildProbeURL(t *testing.T) {
+	tests := []struct {
+		baseURL, version, endpoint, want string
+	}{
+		{"https://api.example.com", "/v1", "/messages", "https://api.example.com/v1/messages"},
+		{"https://api.example.com/v1/", "/v1", "/chat/completions", "https://api.example.com/v1/chat/completions"},
+		{"https://api.example.com/openai#", "/v1", "/responses", "https://api.example.com/openai/responses"},
+		{"https://generativelanguage.googleapis.com", "/v1beta", "/models/m:generateContent", "https://generativelanguage.googleapis.com/v1beta/models/m:generateContent"},
+	}
+	for _, tt := range tests {
+		if got := buildProbeURL(tt.baseURL, tt.version, tt.endpoint); got != tt.want {
+			t.Errorf("buildProbeURL(%q) = %q, want %q", tt.baseURL, got, tt.want)
+		}
+	}
+
+	if classifyProbe(0, errors.New("timeout")) != OutcomeFailure || classifyProbe(429, nil) != OutcomeFailure ||
+		classifyProbe(400, nil) != OutcomeInconclusive || classifyProbe(200, nil) != OutcomeSuccess {
+		t.Fatal("unexpected probe classification")
+	}
+}
@@ -0,0 +1,149 @@
+package healthprobe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/httpclient"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+)
+
+// versionPattern 匹配 baseURL 末尾的版本号（/v1, /v2, /v1beta 等）
+var versionPattern = regexp.MustCompile(`/v\d+[a-z]*$`)
+
+// probeClient 发送探测请求（便于测试替换）
+type probeClient interface {
+	Do(req *http.Request, insecureSkipVerify bool, timeout time.Duration) (statusCode int, body []byte, err error)
+}
+
+type httpProbeClient struct{}
+
+func (httpProbeClient) Do(req *http.Request, insecureSkipVerify bool, timeout time.Duration) (int, []byte, error) {
+	client := httpclient.GetManager().GetStandardClient(timeout, insecureSkipVerify)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return resp.StatusCode, body, nil
+}
+
+// classifyProbe 根据探测响应判定结果
+// 400/404/422 通常是探测请求本身不被接受（模型不存在、参数不支持），无法说明渠道是否可用
+func classifyProbe(statusCode int, err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return OutcomeSuccess
+	case statusCode == http.StatusBadRequest, statusCode == http.StatusNotFound, statusCode == http.StatusUnprocessableEntity:
+		return OutcomeInconclusive
+	default:
+		return OutcomeFailure
+	}
+}
+
+// probeModel 确定探测模型：渠道不服务配置的探测模型时改用其第一个具体的支持模型，再应用模型重定向
+func probeModel(upstream *config.UpstreamConfig, model string) string {
+	if !upstream.ServesModel(model) {
+		candidates := append(append([]string{}, upstream.SupportedModels...), upstream.DiscoveredModels...)
+		for _, candidate := range candidates {
+			if !strings.ContainsAny(candidate, "*?[") {
+				model = candidate
+				break
+			}
+		}
+	}
+	return config.RedirectModel(model, upstream)
+}
+
+// buildProbeURL 按 baseURL 智能拼接端点：以 # 结尾或已带版本号时不再追加版本前缀
+func buildProbeURL(baseURL, version, endpoint string) string {
+	skipVersionPrefix := strings.HasSuffix(baseURL, "#")
+	if skipVersionPrefix {
+		baseURL = strings.TrimSuffix(baseURL, "#")
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	if versionPattern.MatchString(baseURL) || skipVersionPrefix {
+		return baseURL + endpoint
+	}
+	return baseURL + version + endpoint
+}
+
+// buildProbeRequest 按上游服务类型构建最小的真实请求
+func buildProbeRequest(ctx context.Context, upstream *config.UpstreamConfig, baseURL, apiKey, model, prompt string) (*http.Request, error) {
+	if model == "" {
+		return nil, fmt.Errorf("未配置探测模型")
+	}
+
+	var (
+		targetURL string
+		payload   interface{}
+	)
+	switch upstream.ServiceType {
+	case "claude":
+		targetURL = buildProbeURL(baseURL, "/v1", "/messages")
+		payload = map[string]interface{}{
+			"model":      model,
+			"max_tokens": probeMaxTokens,
+			"messages":   []map[string]interface{}{{"role": "user", "content": prompt}},
+		}
+	case "openai":
+		targetURL = buildProbeURL(baseURL, "/v1", "/chat/completions")
+		payload = map[string]interface{}{
+			"model":      model,
+			"max_tokens": probeMaxTokens,
+			"messages":   []map[string]interface{}{{"role": "user", "content": prompt}},
+		}
+	case "responses":
+		targetURL = buildProbeURL(baseURL, "/v1", "/responses")
+		payload = map[string]interface{}{
+			"model":             model,
+			"input":             prompt,
+			"max_output_tokens": probeMaxTokens,
+			"store":             false,
+		}
+	case "gemini":
+		targetURL = buildProbeURL(baseURL, "/v1beta", "/models/"+url.PathEscape(model)+":generateContent")
+		payload = map[string]interface{}{
+			"contents": []map[string]interface{}{
+				{"role": "user", "parts": []map[string]interface{}{{"text": prompt}}},
+			},
+			"generationConfig": map[string]interface{}{"maxOutputTokens": probeMaxTokens},
+		}
+	default:
+		return nil, fmt.Errorf("不支持的服务类型: %s", upstream.ServiceType)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if upstream.ServiceType == "gemini" {
+		utils.SetGeminiAuthenticationHeader(req.Header, apiKey)
+	} else {
+		utils.SetAuthenticationHeader(req.Header, apiKey)
+	}
+	if upstream.ServiceType == "claude" {
+		req.Header.Set("anthropic-version", "2023-06-01")
+		utils.EnsureCompatibleUserAgent(req.Header, "claude")
+	}
+	return req, nil
+}
@@ -20,6 +20,17 @@ type RequestRecord struct {
 	OutputTokens             int64
 	CacheCreationInputTokens int64
 	CacheReadInputTokens     int64
+	ClientID                 string  // 发起请求的客户端 ID（管理员密钥访问时为空）
+	Cost                     float64 // 请求费用（美元，按模型价格表计算）
+	RequestedModel           string  // 客户端请求的模型
+	Model                    string  // 重定向后实际发送给上游的模型（ModelMapping 之后）
+}
+
+// RequestMeta 请求元信息（在发起上游请求时记录到请求历史）
+type RequestMeta struct {
+	ClientID       string // 发起请求的客户端 ID
+	RequestedModel string // 客户端请求的模型
+	Model          string // 重定向后的模型；为空时视为与 RequestedModel 相同
 }
 
 // KeyMetrics 单个 Key 的指标（绑定到 BaseURL + Key 组合）
@@ -41,6 +52,8 @@ type KeyMetrics struct {
 	requestHistory []RequestRecord
 	// 进行中请求在 requestHistory 中的索引（用于“连接即计数”，结束后回写成功/失败与 token）
 	pendingHistoryIdx map[uint64]int
+	// 累计 Token / 费用 / 耗时（用于 Prometheus 导出）
+	totals keyTotals
 }
 
 // ChannelMetrics 渠道聚合指标（用于 API 返回，兼容旧结构）
@@ -73,6 +86,8 @@ type TimeWindowStats struct {
 	// CacheHitRate 缓存命中率（Token口径），范围 0-100
 	// 定义：cacheReadTokens / (cacheReadTokens + inputTokens) * 100
 	CacheHitRate float64 `json:"cacheHitRate,omitempty"`
+	// Cost 费用（美元）
+	Cost float64 `json:"cost,omitempty"`
 }
 
 // MetricsManager 指标管理器
@@ -88,6 +103,50 @@ type MetricsManager struct {
 	// 持久化存储（可选）
 	store   PersistenceStore
 	apiType string // "messages"、"responses" 或 "gemini"
+
+	// 熔断状态变化回调（可选，异步调用）
+	circuitHook func(CircuitEvent)
+
+	// 中转渠道 Key 余额（key: hash(baseURL + apiKey)，独立于 keyMetrics，不随过期指标清理）
+	balances map[string]*KeyBalance
+}
+
+// 熔断状态变化原因
+const (
+	CircuitReasonFailureRate    = "failure_rate"    // 失败率超过阈值进入熔断
+	CircuitReasonRequestSuccess = "request_success" // 请求成功退出熔断
+	CircuitReasonTimeout        = "timeout"         // 超过恢复时间自动退出熔断
+	CircuitReasonReset          = "reset"           // 手动重置或探测成功退出熔断
+)
+
+// CircuitEvent Key 熔断状态变化事件
+type CircuitEvent struct {
+	BaseURL     string
+	KeyMask     string
+	Open        bool   // true 表示进入熔断，false 表示退出熔断
+	Reason      string // CircuitReason*
+	FailureRate float64
+}
+
+// SetCircuitHook 设置熔断状态变化回调（回调在独立 goroutine 中执行，不持有指标锁）
+func (m *MetricsManager) SetCircuitHook(hook func(CircuitEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.circuitHook = hook
+}
+
+// notifyCircuitLocked 触发熔断状态变化回调（调用方需持有 m.mu）
+func (m *MetricsManager) notifyCircuitLocked(metrics *KeyMetrics, open bool, reason string) {
+	if m.circuitHook == nil {
+		return
+	}
+	go m.circuitHook(CircuitEvent{
+		BaseURL:     metrics.BaseURL,
+		KeyMask:     metrics.KeyMask,
+		Open:        open,
+		Reason:      reason,
+		FailureRate: m.calculateKeyFailureRateInternal(metrics),
+	})
 }
 
 // NewMetricsManager 创建指标管理器
@@ -187,6 +246,10 @@ func (m *MetricsManager) loadFromStore() error {
 			OutputTokens:             r.OutputTokens,
 			CacheCreationInputTokens: r.CacheCreationTokens,
 			CacheReadInputTokens:     r.CacheReadTokens,
+			ClientID:                 r.ClientID,
+			Cost:                     r.Cost,
+			RequestedModel:           r.RequestedModel,
+			Model:                    r.Model,
 		})
 
 		// 更新聚合计数
@@ -289,10 +352,10 @@ func (m *MetricsManager) RecordSuccessWithUsage(baseURL, apiKey string, usage *t
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.recordSuccessWithUsageLocked(baseURL, apiKey, usage, time.Now())
+	m.recordSuccessWithUsageLocked(baseURL, apiKey, usage, 0, time.Now())
 }
 
-func (m *MetricsManager) recordSuccessWithUsageLocked(baseURL, apiKey string, usage *types.Usage, now time.Time) {
+func (m *MetricsManager) recordSuccessWithUsageLocked(baseURL, apiKey string, usage *types.Usage, cost float64, now time.Time) {
 	metrics := m.getOrCreateKey(baseURL, apiKey)
 	metrics.RequestCount++
 	metrics.SuccessCount++
@@ -304,6 +367,7 @@ func (m *MetricsManager) recordSuccessWithUsageLocked(baseURL, apiKey string, us
 	if metrics.CircuitBrokenAt != nil {
 		metrics.CircuitBrokenAt = nil
 		log.Printf("[Metrics-Circuit] Key [%s] (%s) 因请求成功退出熔断状态", metrics.KeyMask, metrics.BaseURL)
+		m.notifyCircuitLocked(metrics, false, CircuitReasonRequestSuccess)
 	}
 
 	// 更新滑动窗口
@@ -323,7 +387,8 @@ func (m *MetricsManager) recordSuccessWithUsageLocked(baseURL, apiKey string, us
 	}
 
 	// 记录带时间戳的请求
-	m.appendToHistoryKeyWithUsage(metrics, now, true, inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens)
+	m.appendToHistoryKeyWithUsage(metrics, now, true, inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens, cost)
+	metrics.totals.add(inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens, cost)
 
 	// 写入持久化存储（异步，不阻塞）
 	if m.store != nil {
@@ -337,6 +402,7 @@ func (m *MetricsManager) recordSuccessWithUsageLocked(baseURL, apiKey string, us
 			OutputTokens:        outputTokens,
 			CacheCreationTokens: cacheCreationTokens,
 			CacheReadTokens:     cacheReadTokens,
+			Cost:                cost,
 			APIType:             m.apiType,
 		})
 	}
@@ -365,6 +431,7 @@ func (m *MetricsManager) recordFailureLocked(baseURL, apiKey string, now time.Ti
 	if metrics.CircuitBrokenAt == nil && m.isKeyCircuitBroken(metrics) {
 		metrics.CircuitBrokenAt = &now
 		log.Printf("[Metrics-Circuit] Key [%s] (%s) 进入熔断状态（失败率: %.1f%%）", metrics.KeyMask, metrics.BaseURL, m.calculateKeyFailureRateInternal(metrics)*100)
+		m.notifyCircuitLocked(metrics, true, CircuitReasonFailureRate)
 	}
 
 	// 记录带时间戳的请求
@@ -393,8 +460,22 @@ func (m *MetricsManager) RecordRequestConnected(baseURL, apiKey string) uint64 {
 	return m.RecordRequestConnectedAt(baseURL, apiKey, time.Now())
 }
 
+// RecordRequestConnectedForClient 与 RecordRequestConnected 相同，同时记录发起请求的客户端 ID。
+func (m *MetricsManager) RecordRequestConnectedForClient(baseURL, apiKey, clientID string) uint64 {
+	return m.recordRequestConnected(baseURL, apiKey, RequestMeta{ClientID: clientID}, time.Now())
+}
+
+// RecordRequestConnectedWithMeta 与 RecordRequestConnected 相同，同时记录客户端与模型等请求元信息。
+func (m *MetricsManager) RecordRequestConnectedWithMeta(baseURL, apiKey string, meta RequestMeta) uint64 {
+	return m.recordRequestConnected(baseURL, apiKey, meta, time.Now())
+}
+
 // RecordRequestConnectedAt 与 RecordRequestConnected 相同，但允许注入时间戳（用于测试）。
 func (m *MetricsManager) RecordRequestConnectedAt(baseURL, apiKey string, timestamp time.Time) uint64 {
+	return m.recordRequestConnected(baseURL, apiKey, RequestMeta{}, timestamp)
+}
+
+func (m *MetricsManager) recordRequestConnected(baseURL, apiKey string, meta RequestMeta, timestamp time.Time) uint64 {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -408,9 +489,16 @@ func (m *MetricsManager) RecordRequestConnectedAt(baseURL, apiKey string, timest
 		metrics.pendingHistoryIdx = make(map[uint64]int)
 	}
 
+	model := meta.Model
+	if model == "" {
+		model = meta.RequestedModel
+	}
 	metrics.requestHistory = append(metrics.requestHistory, RequestRecord{
-		Timestamp: timestamp,
-		Success:   true, // 先按成功计数；结束时会回写真实结果
+		Timestamp:      timestamp,
+		Success:        true, // 先按成功计数；结束时会回写真实结果
+		ClientID:       meta.ClientID,
+		RequestedModel: meta.RequestedModel,
+		Model:          model,
 	})
 	metrics.pendingHistoryIdx[requestID] = len(metrics.requestHistory) - 1
 
@@ -422,19 +510,24 @@ func (m *MetricsManager) RecordRequestConnectedAt(baseURL, apiKey string, timest
 
 // RecordRequestFinalizeSuccess 回写成功结果与 token（requestID 来自 RecordRequestConnected）。
 func (m *MetricsManager) RecordRequestFinalizeSuccess(baseURL, apiKey string, requestID uint64, usage *types.Usage) {
+	m.RecordRequestFinalizeSuccessWithCost(baseURL, apiKey, requestID, usage, 0)
+}
+
+// RecordRequestFinalizeSuccessWithCost 与 RecordRequestFinalizeSuccess 相同，同时回写请求费用（美元）。
+func (m *MetricsManager) RecordRequestFinalizeSuccessWithCost(baseURL, apiKey string, requestID uint64, usage *types.Usage, cost float64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	metricsKey := generateMetricsKey(baseURL, apiKey)
 	metrics, exists := m.keyMetrics[metricsKey]
 	if !exists {
-		m.recordSuccessWithUsageLocked(baseURL, apiKey, usage, time.Now())
+		m.recordSuccessWithUsageLocked(baseURL, apiKey, usage, cost, time.Now())
 		return
 	}
 
 	idx, ok := metrics.pendingHistoryIdx[requestID]
 	if !ok || idx < 0 || idx >= len(metrics.requestHistory) {
-		m.recordSuccessWithUsageLocked(baseURL, apiKey, usage, time.Now())
+		m.recordSuccessWithUsageLocked(baseURL, apiKey, usage, cost, time.Now())
 		return
 	}
 	delete(metrics.pendingHistoryIdx, requestID)
@@ -451,6 +544,7 @@ func (m *MetricsManager) RecordRequestFinalizeSuccess(baseURL, apiKey string, re
 	if metrics.CircuitBrokenAt != nil {
 		metrics.CircuitBrokenAt = nil
 		log.Printf("[Metrics-Circuit] Key [%s] (%s) 因请求成功退出熔断状态", metrics.KeyMask, metrics.BaseURL)
+		m.notifyCircuitLocked(metrics, false, CircuitReasonRequestSuccess)
 	}
 
 	// 更新滑动窗口
@@ -476,6 +570,9 @@ func (m *MetricsManager) RecordRequestFinalizeSuccess(baseURL, apiKey string, re
 	record.OutputTokens = outputTokens
 	record.CacheCreationInputTokens = cacheCreationTokens
 	record.CacheReadInputTokens = cacheReadTokens
+	record.Cost = cost
+	metrics.totals.add(inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens, cost)
+	metrics.totals.latency.observe(now.Sub(record.Timestamp))
 
 	// 写入持久化存储（异步，不阻塞）
 	if m.store != nil {
@@ -489,6 +586,10 @@ func (m *MetricsManager) RecordRequestFinalizeSuccess(baseURL, apiKey string, re
 			OutputTokens:        outputTokens,
 			CacheCreationTokens: cacheCreationTokens,
 			CacheReadTokens:     cacheReadTokens,
+			ClientID:            record.ClientID,
+			Cost:                cost,
+			RequestedModel:      record.RequestedModel,
+			Model:               record.Model,
 			APIType:             m.apiType,
 		})
 	}
@@ -528,6 +629,7 @@ func (m *MetricsManager) RecordRequestFinalizeFailure(baseURL, apiKey string, re
 	if metrics.CircuitBrokenAt == nil && m.isKeyCircuitBroken(metrics) {
 		metrics.CircuitBrokenAt = &now
 		log.Printf("[Metrics-Circuit] Key [%s] (%s) 进入熔断状态（失败率: %.1f%%）", metrics.KeyMask, metrics.BaseURL, m.calculateKeyFailureRateInternal(metrics)*100)
+		m.notifyCircuitLocked(metrics, true, CircuitReasonFailureRate)
 	}
 
 	// 回写历史记录（时间戳保持为“请求开始（TCP 建连阶段）”时刻）
@@ -537,6 +639,8 @@ func (m *MetricsManager) RecordRequestFinalizeFailure(baseURL, apiKey string, re
 	record.OutputTokens = 0
 	record.CacheCreationInputTokens = 0
 	record.CacheReadInputTokens = 0
+	record.Cost = 0
+	metrics.totals.latency.observe(now.Sub(record.Timestamp))
 
 	// 写入持久化存储（异步，不阻塞）
 	if m.store != nil {
@@ -550,6 +654,9 @@ func (m *MetricsManager) RecordRequestFinalizeFailure(baseURL, apiKey string, re
 			OutputTokens:        0,
 			CacheCreationTokens: 0,
 			CacheReadTokens:     0,
+			ClientID:            record.ClientID,
+			RequestedModel:      record.RequestedModel,
+			Model:               record.Model,
 			APIType:             m.apiType,
 		})
 	}
@@ -646,7 +753,7 @@ func (m *MetricsManager) appendToWindowKey(metrics *KeyMetrics, success bool) {
 
 // appendToHistoryKey 向 Key 历史记录添加请求（保留24小时）
 func (m *MetricsManager) appendToHistoryKey(metrics *KeyMetrics, timestamp time.Time, success bool) {
-	m.appendToHistoryKeyWithUsage(metrics, timestamp, success, 0, 0, 0, 0)
+	m.appendToHistoryKeyWithUsage(metrics, timestamp, success, 0, 0, 0, 0, 0)
 }
 
 // cleanupHistoryLocked 清理超过 24 小时的历史记录，并同步修正 pendingHistoryIdx 索引。
@@ -693,7 +800,7 @@ func (m *MetricsManager) cleanupHistoryLocked(metrics *KeyMetrics) {
 }
 
 // appendToHistoryKeyWithUsage 向 Key 历史记录添加请求（带 Usage 数据）
-func (m *MetricsManager) appendToHistoryKeyWithUsage(metrics *KeyMetrics, timestamp time.Time, success bool, inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens int64) {
+func (m *MetricsManager) appendToHistoryKeyWithUsage(metrics *KeyMetrics, timestamp time.Time, success bool, inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens int64, cost float64) {
 	metrics.requestHistory = append(metrics.requestHistory, RequestRecord{
 		Timestamp:                timestamp,
 		Success:                  success,
@@ -701,6 +808,7 @@ func (m *MetricsManager) appendToHistoryKeyWithUsage(metrics *KeyMetrics, timest
 		OutputTokens:             outputTokens,
 		CacheCreationInputTokens: cacheCreationTokens,
 		CacheReadInputTokens:     cacheReadTokens,
+		Cost:                     cost,
 	})
 
 	// 清理超过 24 小时的记录
@@ -1086,6 +1194,7 @@ func (m *MetricsManager) GetTimeWindowStatsForKey(baseURL, apiKey string, durati
 
 	cutoff := time.Now().Add(-duration)
 	var requestCount, successCount, failureCount int64
+	var cost float64
 
 	for _, record := range metrics.requestHistory {
 		if record.Timestamp.After(cutoff) {
@@ -1095,6 +1204,7 @@ func (m *MetricsManager) GetTimeWindowStatsForKey(baseURL, apiKey string, durati
 			} else {
 				failureCount++
 			}
+			cost += record.Cost
 		}
 	}
 
@@ -1108,7 +1218,120 @@ func (m *MetricsManager) GetTimeWindowStatsForKey(baseURL, apiKey string, durati
 		SuccessCount: successCount,
 		FailureCount: failureCount,
 		SuccessRate:  successRate,
+		Cost:         cost,
+	}
+}
+
+// GetClientTimeWindowStats 按客户端聚合时间窗口内的请求与 Token 统计（跨所有渠道与 Key）
+// 返回 map[clientID]TimeWindowStats；管理员密钥发起的请求不计入。
+func (m *MetricsManager) GetClientTimeWindowStats(duration time.Duration) map[string]TimeWindowStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cutoff := time.Now().Add(-duration)
+	result := make(map[string]TimeWindowStats)
+
+	for _, metrics := range m.keyMetrics {
+		for _, record := range metrics.requestHistory {
+			if record.ClientID == "" || !record.Timestamp.After(cutoff) {
+				continue
+			}
+			stats := result[record.ClientID]
+			stats.RequestCount++
+			if record.Success {
+				stats.SuccessCount++
+			} else {
+				stats.FailureCount++
+			}
+			stats.InputTokens += record.InputTokens
+			stats.OutputTokens += record.OutputTokens
+			stats.CacheCreationTokens += record.CacheCreationInputTokens
+			stats.CacheReadTokens += record.CacheReadInputTokens
+			stats.Cost += record.Cost
+			result[record.ClientID] = stats
+		}
+	}
+
+	for clientID, stats := range result {
+		stats.SuccessRate = float64(stats.SuccessCount) / float64(stats.RequestCount) * 100
+		result[clientID] = stats
+	}
+	return result
+}
+
+// ModelStats 按模型拆分的统计（同一请求模型被重定向到不同上游模型时分开统计）
+// 迁移前的历史记录没有模型信息，模型名为空字符串。
+type ModelStats struct {
+	RequestedModel string `json:"requestedModel"`
+	Model          string `json:"model"`
+	TimeWindowStats
+}
+
+// modelStatsKey 模型统计的分组键
+type modelStatsKey struct {
+	requested string
+	model     string
+}
+
+// modelStatsAccumulator 按模型累加请求记录
+type modelStatsAccumulator map[modelStatsKey]*TimeWindowStats
+
+func (acc modelStatsAccumulator) add(record *RequestRecord) {
+	k := modelStatsKey{requested: record.RequestedModel, model: record.Model}
+	stats, ok := acc[k]
+	if !ok {
+		stats = &TimeWindowStats{}
+		acc[k] = stats
 	}
+	stats.addRecord(record)
+}
+
+// result 输出统计结果（请求数多的在前）
+func (acc modelStatsAccumulator) result() []ModelStats {
+	result := make([]ModelStats, 0, len(acc))
+	for k, stats := range acc {
+		stats.finalizeRates()
+		result = append(result, ModelStats{RequestedModel: k.requested, Model: k.model, TimeWindowStats: *stats})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].RequestCount != result[j].RequestCount {
+			return result[i].RequestCount > result[j].RequestCount
+		}
+		if result[i].Model != result[j].Model {
+			return result[i].Model < result[j].Model
+		}
+		return result[i].RequestedModel < result[j].RequestedModel
+	})
+	return result
+}
+
+// GetModelStatsMultiURL 按模型聚合渠道（所有 BaseURL × Key）在时间窗口内的统计
+func (m *MetricsManager) GetModelStatsMultiURL(baseURLs []string, apiKeys []string, duration time.Duration) []ModelStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cutoff := time.Now().Add(-duration)
+	acc := make(modelStatsAccumulator)
+	seen := make(map[string]bool)
+	for _, baseURL := range baseURLs {
+		for _, apiKey := range apiKeys {
+			metricsKey := generateMetricsKey(baseURL, apiKey)
+			if seen[metricsKey] {
+				continue
+			}
+			seen[metricsKey] = true
+			metrics, exists := m.keyMetrics[metricsKey]
+			if !exists {
+				continue
+			}
+			for i := range metrics.requestHistory {
+				if metrics.requestHistory[i].Timestamp.After(cutoff) {
+					acc.add(&metrics.requestHistory[i])
+				}
+			}
+		}
+	}
+	return acc.result()
 }
 
 // GetAllTimeWindowStatsForKey 获取单个 Key 所有时间窗口的统计
@@ -1131,8 +1354,12 @@ func (m *MetricsManager) ResetKeyFailureState(baseURL, apiKey string) {
 	if metrics, exists := m.keyMetrics[metricsKey]; exists {
 		metrics.ConsecutiveFailures = 0
 		metrics.recentResults = make([]bool, 0, m.windowSize)
+		wasBroken := metrics.CircuitBrokenAt != nil
 		metrics.CircuitBrokenAt = nil
 		log.Printf("[Metrics-Reset] Key [%s] (%s) 熔断状态已重置（保留历史统计）", metrics.KeyMask, metrics.BaseURL)
+		if wasBroken {
+			m.notifyCircuitLocked(metrics, false, CircuitReasonReset)
+		}
 	}
 }
 
@@ -1154,6 +1381,7 @@ func (m *MetricsManager) ResetKey(baseURL, apiKey string) {
 		metrics.CircuitBrokenAt = nil
 		metrics.recentResults = make([]bool, 0, m.windowSize)
 		metrics.requestHistory = nil
+		metrics.totals = keyTotals{}
 		if metrics.pendingHistoryIdx != nil {
 			for id := range metrics.pendingHistoryIdx {
 				delete(metrics.pendingHistoryIdx, id)
@@ -1195,6 +1423,7 @@ func (m *MetricsManager) DeleteKeysForChannel(baseURLs, apiKeys []string) []stri
 				delete(m.keyMetrics, metricsKey)
 				deletedFromMemory++
 			}
+			delete(m.balances, metricsKey)
 		}
 	}
 
@@ -1265,6 +1494,7 @@ func (m *MetricsManager) recoverExpiredCircuitBreakers() {
 				metrics.recentResults = make([]bool, 0, m.windowSize)
 				metrics.CircuitBrokenAt = nil
 				log.Printf("[Metrics-Circuit] Key [%s] (%s) 熔断自动恢复（已超过 %v）", metrics.KeyMask, metrics.BaseURL, m.circuitRecoveryTime)
+				m.notifyCircuitLocked(metrics, false, CircuitReasonTimeout)
 			}
 		}
 	}
@@ -1333,7 +1563,8 @@ type MetricsResponse struct {
 	LastFailureAt       *string                    `json:"lastFailureAt,omitempty"`
 	CircuitBrokenAt     *string                    `json:"circuitBrokenAt,omitempty"`
 	TimeWindows         map[string]TimeWindowStats `json:"timeWindows,omitempty"`
-	KeyMetrics          []*KeyMetricsResponse      `json:"keyMetrics,omitempty"` // 各 Key 的详细指标
+	KeyMetrics          []*KeyMetricsResponse      `json:"keyMetrics,omitempty"`  // 各 Key 的详细指标
+	KeyBalances         []KeyBalance               `json:"keyBalances,omitempty"` // 各 Key 的余额（仅配置了余额查询的渠道）
 }
 
 // KeyMetricsResponse 单个 Key 的 API 响应
@@ -1508,6 +1739,7 @@ type keyAggregation struct {
 	}
 
 	resp.KeyMetrics = keyResponses
+	resp.KeyBalances = m.keyBalancesLocked(baseURLs, activeKeys)
 
 	// 计算聚合的时间窗口统计（多 URL 版本）
 	resp.TimeWindows = m.calculateAggregatedTimeWindowsMultiURL(baseURLs, activeKeys)
@@ -1608,6 +1840,7 @@ func (m *MetricsManager) ToResponse(channelIndex int, baseURL string, activeKeys
 	}
 
 	resp.KeyMetrics = keyResponses
+	resp.KeyBalances = m.keyBalancesLocked([]string{baseURL}, activeKeys)
 
 	// 计算聚合的时间窗口统计
 	resp.TimeWindows = m.calculateAggregatedTimeWindowsInternal(baseURL, activeKeys)
@@ -1631,6 +1864,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsInternal(baseURL string,
 		cutoff := now.Add(-duration)
 		var requestCount, successCount, failureCount int64
 		var inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens int64
+		var cost float64
 
 		for _, apiKey := range activeKeys {
 			metricsKey := generateMetricsKey(baseURL, apiKey)
@@ -1647,6 +1881,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsInternal(baseURL string,
 						outputTokens += record.OutputTokens
 						cacheCreationTokens += record.CacheCreationInputTokens
 						cacheReadTokens += record.CacheReadInputTokens
+						cost += record.Cost
 					}
 				}
 			}
@@ -1673,6 +1908,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsInternal(baseURL string,
 			CacheCreationTokens: cacheCreationTokens,
 			CacheReadTokens:     cacheReadTokens,
 			CacheHitRate:        cacheHitRate,
+			Cost:                cost,
 		}
 	}
 
@@ -1695,6 +1931,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsMultiURL(baseURLs []strin
 		cutoff := now.Add(-duration)
 		var requestCount, successCount, failureCount int64
 		var inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens int64
+		var cost float64
 
 		// 遍历所有 BaseURL 和 Key 的组合
 		for _, baseURL := range baseURLs {
@@ -1713,6 +1950,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsMultiURL(baseURLs []strin
 							outputTokens += record.OutputTokens
 							cacheCreationTokens += record.CacheCreationInputTokens
 							cacheReadTokens += record.CacheReadInputTokens
+							cost += record.Cost
 						}
 					}
 				}
@@ -1740,6 +1978,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsMultiURL(baseURLs []strin
 			CacheCreationTokens: cacheCreationTokens,
 			CacheReadTokens:     cacheReadTokens,
 			CacheHitRate:        cacheHitRate,
+			Cost:                cost,
 		}
 	}
 
@@ -1830,6 +2069,7 @@ type HistoryDataPoint struct {
 	SuccessCount int64     `json:"successCount"`
 	FailureCount int64     `json:"failureCount"`
 	SuccessRate  float64   `json:"successRate"`
+	Cost         float64   `json:"cost"`
 }
 
 // KeyHistoryDataPoint Key 级别历史数据点（包含 Token 和 Cache 数据）
@@ -1843,6 +2083,7 @@ type KeyHistoryDataPoint struct {
 	OutputTokens             int64     `json:"outputTokens"`
 	CacheCreationInputTokens int64     `json:"cacheCreationTokens"`
 	CacheReadInputTokens     int64     `json:"cacheReadTokens"`
+	Cost                     float64   `json:"cost"`
 }
 
 // GetHistoricalStats 获取历史统计数据（按时间间隔聚合）
@@ -1893,6 +2134,7 @@ func (m *MetricsManager) GetHistoricalStats(baseURL string, activeKeys []string,
 						} else {
 							b.failureCount++
 						}
+						b.cost += record.Cost
 					}
 				}
 			}
@@ -1914,6 +2156,7 @@ func (m *MetricsManager) GetHistoricalStats(baseURL string, activeKeys []string,
 			SuccessCount: b.successCount,
 			FailureCount: b.failureCount,
 			SuccessRate:  successRate,
+			Cost:         b.cost,
 		}
 	}
 
@@ -1967,6 +2210,7 @@ func (m *MetricsManager) GetHistoricalStatsMultiURL(baseURLs []string, activeKey
 							} else {
 								b.failureCount++
 							}
+							b.cost += record.Cost
 						}
 					}
 				}
@@ -1989,6 +2233,7 @@ func (m *MetricsManager) GetHistoricalStatsMultiURL(baseURLs []string, activeKey
 			SuccessCount: b.successCount,
 			FailureCount: b.failureCount,
 			SuccessRate:  successRate,
+			Cost:         b.cost,
 		}
 	}
 
@@ -2000,6 +2245,7 @@ type bucketData struct {
 	requestCount int64
 	successCount int64
 	failureCount int64
+	cost         float64
 }
 
 func (m *MetricsManager) GetAllKeysHistoricalStats(duration, interval time.Duration) []HistoryDataPoint {
@@ -2043,6 +2289,7 @@ func (m *MetricsManager) GetAllKeysHistoricalStats(duration, interval time.Durat
 					} else {
 						b.failureCount++
 					}
+					b.cost += record.Cost
 				}
 			}
 		}
@@ -2063,6 +2310,7 @@ func (m *MetricsManager) GetAllKeysHistoricalStats(duration, interval time.Durat
 			SuccessCount: b.successCount,
 			FailureCount: b.failureCount,
 			SuccessRate:  successRate,
+			Cost:         b.cost,
 		}
 	}
 
@@ -2124,6 +2372,7 @@ func (m *MetricsManager) GetKeyHistoricalStats(baseURL, apiKey string, duration,
 				} else {
 					b.failureCount++
 				}
+				b.cost += record.Cost
 				// 累加 Token 数据
 				b.inputTokens += record.InputTokens
 				b.outputTokens += record.OutputTokens
@@ -2152,6 +2401,7 @@ func (m *MetricsManager) GetKeyHistoricalStats(baseURL, apiKey string, duration,
 			OutputTokens:             b.outputTokens,
 			CacheCreationInputTokens: b.cacheCreationTokens,
 			CacheReadInputTokens:     b.cacheReadTokens,
+			Cost:                     b.cost,
 		}
 	}
 
@@ -2209,6 +2459,7 @@ func (m *MetricsManager) GetKeyHistoricalStatsMultiURL(baseURLs []string, apiKey
 					} else {
 						b.failureCount++
 					}
+					b.cost += record.Cost
 					// 累加 Token 数据
 					b.inputTokens += record.InputTokens
 					b.outputTokens += record.OutputTokens
@@ -2249,6 +2500,7 @@ func (m *MetricsManager) GetKeyHistoricalStatsMultiURL(baseURLs []string, apiKey
 			OutputTokens:             b.outputTokens,
 			CacheCreationInputTokens: b.cacheCreationTokens,
 			CacheReadInputTokens:     b.cacheReadTokens,
+			Cost:                     b.cost,
 		}
 	}
 
@@ -2264,6 +2516,7 @@ type keyBucketData struct {
 	outputTokens        int64
 	cacheCreationTokens int64
 	cacheReadTokens     int64
+	cost                float64
 }
 
 // ============ 全局统计数据结构和方法（用于全局流量统计图表）============
@@ -2279,6 +2532,7 @@ type GlobalHistoryDataPoint struct {
 	OutputTokens        int64     `json:"outputTokens"`
 	CacheCreationTokens int64     `json:"cacheCreationTokens"`
 	CacheReadTokens     int64     `json:"cacheReadTokens"`
+	Cost                float64   `json:"cost"`
 }
 
 // GlobalStatsSummary 全局统计汇总
@@ -2290,14 +2544,76 @@ type GlobalStatsSummary struct {
 	TotalOutputTokens        int64   `json:"totalOutputTokens"`
 	TotalCacheCreationTokens int64   `json:"totalCacheCreationTokens"`
 	TotalCacheReadTokens     int64   `json:"totalCacheReadTokens"`
+	TotalCost                float64 `json:"totalCost"`
 	AvgSuccessRate           float64 `json:"avgSuccessRate"`
 	Duration                 string  `json:"duration"`
 }
 
+// KeyCostStats 单个 BaseURL + Key 在查询范围内的费用汇总
+type KeyCostStats struct {
+	MetricsKey string `json:"metricsKey"`
+	BaseURL    string `json:"baseUrl"`
+	KeyMask    string `json:"keyMask"`
+	TimeWindowStats
+}
+
 // GlobalStatsHistoryResponse 全局统计响应
 type GlobalStatsHistoryResponse struct {
 	DataPoints []GlobalHistoryDataPoint `json:"dataPoints"`
 	Summary    GlobalStatsSummary       `json:"summary"`
+	// 按 Key / 客户端拆分的汇总（按渠道的拆分由 handler 根据配置聚合 ByKey 得到）
+	ByKey    []KeyCostStats             `json:"byKey"`
+	ByClient map[string]TimeWindowStats `json:"byClient"`
+	ByModel  []ModelStats               `json:"byModel"`
+	// ByChannel 由 handler 填充
+	ByChannel []ChannelCostStats `json:"byChannel,omitempty"`
+}
+
+// ChannelCostStats 单个渠道在查询范围内的费用汇总
+type ChannelCostStats struct {
+	ChannelIndex int    `json:"channelIndex"`
+	ChannelName  string `json:"channelName"`
+	TimeWindowStats
+}
+
+// addRecord 累加单条请求记录到统计
+func (s *TimeWindowStats) addRecord(record *RequestRecord) {
+	s.RequestCount++
+	if record.Success {
+		s.SuccessCount++
+	} else {
+		s.FailureCount++
+	}
+	s.InputTokens += record.InputTokens
+	s.OutputTokens += record.OutputTokens
+	s.CacheCreationTokens += record.CacheCreationInputTokens
+	s.CacheReadTokens += record.CacheReadInputTokens
+	s.Cost += record.Cost
+}
+
+// Merge 合并另一份统计（成功率与缓存命中率重新计算）
+func (s *TimeWindowStats) Merge(other TimeWindowStats) {
+	s.RequestCount += other.RequestCount
+	s.SuccessCount += other.SuccessCount
+	s.FailureCount += other.FailureCount
+	s.InputTokens += other.InputTokens
+	s.OutputTokens += other.OutputTokens
+	s.CacheCreationTokens += other.CacheCreationTokens
+	s.CacheReadTokens += other.CacheReadTokens
+	s.Cost += other.Cost
+	s.finalizeRates()
+}
+
+// finalizeRates 根据计数计算成功率与缓存命中率
+func (s *TimeWindowStats) finalizeRates() {
+	s.SuccessRate = 0
+	if s.RequestCount > 0 {
+		s.SuccessRate = float64(s.SuccessCount) / float64(s.RequestCount) * 100
+	}
+	s.CacheHitRate = 0
+	if denom := s.CacheReadTokens + s.InputTokens; denom > 0 {
+		s.CacheHitRate = float64(s.CacheReadTokens) / float64(denom) * 100
+	}
 }
 
 // GetGlobalHistoricalStatsWithTokens 获取全局历史统计（包含 Token 数据）
@@ -2335,10 +2651,16 @@ func (m *MetricsManager) GetGlobalHistoricalStatsWithTokens(duration, interval t
 	// 汇总统计
 	var totalRequests, totalSuccess, totalFailure int64
 	var totalInputTokens, totalOutputTokens, totalCacheCreation, totalCacheRead int64
+	var totalCost float64
+	byKey := make([]KeyCostStats, 0)
+	byClient := make(map[string]TimeWindowStats)
+	byModel := make(modelStatsAccumulator)
 
 	// 遍历所有 Key 的请求历史
 	for _, metrics := range m.keyMetrics {
-		for _, record := range metrics.requestHistory {
+		keyStats := KeyCostStats{MetricsKey: metrics.MetricsKey, BaseURL: metrics.BaseURL, KeyMask: metrics.KeyMask}
+		for i := range metrics.requestHistory {
+			record := &metrics.requestHistory[i]
 			// 使用 Before(endTime) 排除恰好落在 endTime 的记录，避免 offset 越界
 			if record.Timestamp.After(startTime) && record.Timestamp.Before(endTime) {
 				offset := int64(record.Timestamp.Sub(startTime) / interval)
@@ -2350,6 +2672,7 @@ func (m *MetricsManager) GetGlobalHistoricalStatsWithTokens(duration, interval t
 					} else {
 						b.failureCount++
 					}
+					b.cost += record.Cost
 					b.inputTokens += record.InputTokens
 					b.outputTokens += record.OutputTokens
 					b.cacheCreationTokens += record.CacheCreationInputTokens
@@ -2366,10 +2689,35 @@ func (m *MetricsManager) GetGlobalHistoricalStatsWithTokens(duration, interval t
 					totalOutputTokens += record.OutputTokens
 					totalCacheCreation += record.CacheCreationInputTokens
 					totalCacheRead += record.CacheReadInputTokens
+					totalCost += record.Cost
+
+					keyStats.addRecord(record)
+					byModel.add(record)
+					if record.ClientID != "" {
+						clientStats := byClient[record.ClientID]
+						clientStats.addRecord(record)
+						byClient[record.ClientID] = clientStats
+					}
 				}
 			}
 		}
+		if keyStats.RequestCount > 0 {
+			keyStats.finalizeRates()
+			byKey = append(byKey, keyStats)
+		}
+	}
+
+	for clientID, stats := range byClient {
+		stats.finalizeRates()
+		byClient[clientID] = stats
 	}
+	// 费用高的 Key 在前
+	sort.Slice(byKey, func(i, j int) bool {
+		if byKey[i].Cost != byKey[j].Cost {
+			return byKey[i].Cost > byKey[j].Cost
+		}
+		return byKey[i].MetricsKey < byKey[j].MetricsKey
+	})
 
 	// 构建数据点结果
 	dataPoints := make([]GlobalHistoryDataPoint, numPoints)
@@ -2389,6 +2737,7 @@ func (m *MetricsManager) GetGlobalHistoricalStatsWithTokens(duration, interval t
 			OutputTokens:        b.outputTokens,
 			CacheCreationTokens: b.cacheCreationTokens,
 			CacheReadTokens:     b.cacheReadTokens,
+			Cost:                b.cost,
 		}
 	}
 
@@ -2406,6 +2755,7 @@ func (m *MetricsManager) GetGlobalHistoricalStatsWithTokens(duration, interval t
 		TotalOutputTokens:        totalOutputTokens,
 		TotalCacheCreationTokens: totalCacheCreation,
 		TotalCacheReadTokens:     totalCacheRead,
+		TotalCost:                totalCost,
 		AvgSuccessRate:           avgSuccessRate,
 		Duration:                 duration.String(),
 	}
@@ -2413,6 +2763,9 @@ func (m *MetricsManager) GetGlobalHistoricalStatsWithTokens(duration, interval t
 	return GlobalStatsHistoryResponse{
 		DataPoints: dataPoints,
 		Summary:    summary,
+		ByKey:      byKey,
+		ByClient:   byClient,
+		ByModel:    byModel.result(),
 	}
 }
 
@@ -2425,6 +2778,7 @@ type globalBucketData struct {
 	outputTokens        int64
 	cacheCreationTokens int64
 	cacheReadTokens     int64
+	cost                float64
 }
 
 // CalculateTodayDuration 计算"今日"时间范围（从今天 0 点到现在）
@@ -3,6 +3,7 @@
 import (
 	"math"
 	"testing"
+	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/types"
 )
@@ -79,3 +80,46 @@ func TestRecordSuccessWithUsage_CacheCreationFallbackFromTTLBreakdown(t *testing
 		t.Fatalf("expected cacheReadTokens=50, got %d", stats.CacheReadTokens)
 	}
 }
+
+func TestGlobalHistoricalStats_CostBreakdown(t *testing.T) {
+	m := NewMetricsManagerWithConfig(10, 0.5)
+	baseURL := "https://example.com"
+
+	id := m.RecordRequestConnectedForClient(baseURL, "k1", "client-a")
+	m.RecordRequestFinalizeSuccessWithCost(baseURL, "k1", id, &types.Usage{InputTokens: 10}, 0.25)
+	id = m.RecordRequestConnectedForClient(baseURL, "k1", "client-b")
+	m.RecordRequestFinalizeSuccessWithCost(baseURL, "k1", id, &types.Usage{InputTokens: 10}, 0.5)
+	id = m.RecordRequestConnectedForClient(baseURL, "k2", "client-a")
+	m.RecordRequestFinalizeSuccessWithCost(baseURL, "k2", id, &types.Usage{InputTokens: 10}, 1)
+	id = m.RecordRequestConnectedForClient(baseURL, "k2", "client-a")
+	m.RecordRequestFinalizeFailure(baseURL, "k2", id)
+
+	resp := m.GetGlobalHistoricalStatsWithTokens(time.Hour, time.Minute)
+
+	if math.Abs(resp.Summary.TotalCost-1.75) > 1e-9 {
+		t.Fatalf("expected totalCost=1.75, got %v", resp.Summary.TotalCost)
+	}
+
+	if len(resp.ByKey) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(resp.ByKey))
+	}
+	// 费用高的 Key 在前
+	if resp.ByKey[0].MetricsKey != GenerateMetricsKey(baseURL, "k2") || math.Abs(resp.ByKey[0].Cost-1) > 1e-9 {
+		t.Fatalf("unexpected first key: %+v", resp.ByKey[0])
+	}
+	if resp.ByKey[0].FailureCount != 1 {
+		t.Fatalf("expected k2 failureCount=1, got %d", resp.ByKey[0].FailureCount)
+	}
+
+	if math.Abs(resp.ByClient["client-a"].Cost-1.25) > 1e-9 || resp.ByClient["client-a"].RequestCount != 3 {
+		t.Fatalf("unexpected client-a stats: %+v", resp.ByClient["client-a"])
+	}
+	if math.Abs(resp.ByClient["client-b"].Cost-0.5) > 1e-9 {
+		t.Fatalf("unexpected client-b stats: %+v", resp.ByClient["client-b"])
+	}
+
+	windows := m.ToResponse(0, baseURL, []string{"k1", "k2"}, 0).TimeWindows
+	if math.Abs(windows["15m"].Cost-1.75) > 1e-9 {
+		t.Fatalf("expected timeWindows[15m].cost=1.75, got %v", windows["15m"].Cost)
+	}
+}
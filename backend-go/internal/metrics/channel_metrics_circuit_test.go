@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetCircuitHook_OpenAndRecover(t *testing.T) {
+	m := NewMetricsManager()
+	defer m.Stop()
+
+	events := make(chan CircuitEvent, 4)
+	m.SetCircuitHook(func(e CircuitEvent) { events <- e })
+
+	next := func() CircuitEvent {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for circuit event")
+			return CircuitEvent{}
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		m.RecordFailure("http://example.com", "sk-test-key-1234")
+	}
+	opened := next()
+	if !opened.Open || opened.Reason != CircuitReasonFailureRate || opened.BaseURL != "http://example.com" || opened.KeyMask == "" {
+		t.Fatalf("unexpected open event: %+v", opened)
+	}
+
+	m.RecordSuccess("http://example.com", "sk-test-key-1234")
+	recovered := next()
+	if recovered.Open || recovered.Reason != CircuitReasonRequestSuccess {
+		t.Fatalf("unexpected recover event: %+v", recovered)
+	}
+
+	// 未处于熔断状态时重置不触发回调
+	m.ResetKeyFailureState("http://example.com", "sk-test-key-1234")
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event after reset of healthy key: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+func TestModelStats_GroupByRequestedAndRedirectedModel(t *testing.T) {
+	m := NewMetricsManagerWithConfig(10, 0.5)
+	baseURL := "https://example.com"
+
+	record := func(key string, meta RequestMeta, success bool) {
+		id := m.RecordRequestConnectedWithMeta(baseURL, key, meta)
+		if success {
+			m.RecordRequestFinalizeSuccess(baseURL, key, id, &types.Usage{InputTokens: 100, OutputTokens: 10})
+		} else {
+			m.RecordRequestFinalizeFailure(baseURL, key, id)
+		}
+	}
+
+	opus := RequestMeta{RequestedModel: "claude-opus-4-1", Model: "claude-opus-4-1-20250805"}
+	haiku := RequestMeta{RequestedModel: "claude-haiku-4-5"} // 未重定向
+	record("k1", opus, true)
+	record("k1", opus, false)
+	record("k2", opus, false)
+	record("k2", haiku, true)
+
+	stats := m.GetModelStatsMultiURL([]string{baseURL}, []string{"k1", "k2"}, time.Hour)
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 model groups, got %d: %+v", len(stats), stats)
+	}
+
+	first := stats[0]
+	if first.RequestedModel != "claude-opus-4-1" || first.Model != "claude-opus-4-1-20250805" {
+		t.Fatalf("unexpected first group: %+v", first)
+	}
+	if first.RequestCount != 3 || first.FailureCount != 2 || first.InputTokens != 100 {
+		t.Fatalf("unexpected opus stats: %+v", first.TimeWindowStats)
+	}
+
+	second := stats[1]
+	if second.Model != "claude-haiku-4-5" || second.RequestedModel != "claude-haiku-4-5" {
+		t.Fatalf("redirected model should default to requested model, got %+v", second)
+	}
+	if second.SuccessRate != 100 {
+		t.Fatalf("expected haiku successRate=100, got %v", second.SuccessRate)
+	}
+
+	// 仅统计指定 Key
+	if got := m.GetModelStatsMultiURL([]string{baseURL}, []string{"k1"}, time.Hour); len(got) != 1 || got[0].RequestCount != 2 {
+		t.Fatalf("unexpected k1 stats: %+v", got)
+	}
+
+	global := m.GetGlobalHistoricalStatsWithTokens(time.Hour, time.Minute)
+	if len(global.ByModel) != 2 || global.ByModel[0].RequestCount != 3 {
+		t.Fatalf("unexpected global byModel: %+v", global.ByModel)
+	}
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import "time"
+
+// KeyBalance 中转渠道单个 Key 的余额查询结果（金额单位：USD）
+type KeyBalance struct {
+	KeyMask   string    `json:"keyMask"`
+	Remaining float64   `json:"remaining"`           // 剩余额度
+	Used      float64   `json:"used,omitempty"`      // 已用额度（上游未返回时为 0）
+	Total     float64   `json:"total,omitempty"`     // 总额度（上游未返回时为 0）
+	Unlimited bool      `json:"unlimited,omitempty"` // 上游标记为无限额度
+	Low       bool      `json:"low,omitempty"`       // 剩余额度低于渠道配置的阈值
+	CheckedAt time.Time `json:"checkedAt"`           // 最近一次查询时间
+	Error     string    `json:"error,omitempty"`     // 最近一次查询失败的原因（保留上次成功的额度）
+}
+
+// SetKeyBalance 记录 Key 的余额查询结果
+// 查询失败（Error 非空）时保留上一次成功查询到的额度，只更新错误与时间
+func (m *MetricsManager) SetKeyBalance(baseURL, apiKey string, balance KeyBalance) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.balances == nil {
+		m.balances = make(map[string]*KeyBalance)
+	}
+	metricsKey := generateMetricsKey(baseURL, apiKey)
+	if balance.Error != "" {
+		if prev, ok := m.balances[metricsKey]; ok {
+			prev.Error = balance.Error
+			prev.CheckedAt = balance.CheckedAt
+			return
+		}
+	}
+	b := balance
+	m.balances[metricsKey] = &b
+}
+
+// GetKeyBalance 获取 Key 的余额（未查询过时返回 nil）
+func (m *MetricsManager) GetKeyBalance(baseURL, apiKey string) *KeyBalance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if b, ok := m.balances[generateMetricsKey(baseURL, apiKey)]; ok {
+		cloned := *b
+		return &cloned
+	}
+	return nil
+}
+
+// keyBalancesLocked 按 activeKeys 顺序收集余额（多 BaseURL 时取第一个有记录的）
+// 调用方需持有 m.mu
+func (m *MetricsManager) keyBalancesLocked(baseURLs, activeKeys []string) []KeyBalance {
+	if len(m.balances) == 0 {
+		return nil
+	}
+	var result []KeyBalance
+	for _, apiKey := range activeKeys {
+		for _, baseURL := range baseURLs {
+			if b, ok := m.balances[generateMetricsKey(baseURL, apiKey)]; ok {
+				result = append(result, *b)
+				break
+			}
+		}
+	}
+	return result
+}
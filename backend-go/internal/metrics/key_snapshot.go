@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// LatencyBucketBounds 请求耗时直方图的桶上界（秒）
+// 耗时为发起上游请求到请求结束（流式请求包含完整传输时间）
+var LatencyBucketBounds = []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300}
+
+// latencyHistogram 请求耗时直方图（非累积计数，导出时再累加）
+type latencyHistogram struct {
+	counts []uint64 // len(LatencyBucketBounds)+1，最后一个为 +Inf 桶
+	sum    float64
+	count  uint64
+}
+
+// observe 记录一次耗时
+func (h *latencyHistogram) observe(d time.Duration) {
+	if d < 0 {
+		return
+	}
+	if h.counts == nil {
+		h.counts = make([]uint64, len(LatencyBucketBounds)+1)
+	}
+	seconds := d.Seconds()
+	idx := sort.SearchFloat64s(LatencyBucketBounds, seconds)
+	h.counts[idx]++
+	h.sum += seconds
+	h.count++
+}
+
+// keyTotals Key 级别累计计数（进程启动后单调递增，不随 24 小时历史清理而减少）
+type keyTotals struct {
+	inputTokens         int64
+	outputTokens        int64
+	cacheCreationTokens int64
+	cacheReadTokens     int64
+	cost                float64
+	latency             latencyHistogram
+}
+
+// add 累加一次成功请求的 Token 与费用
+func (t *keyTotals) add(inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens int64, cost float64) {
+	t.inputTokens += inputTokens
+	t.outputTokens += outputTokens
+	t.cacheCreationTokens += cacheCreationTokens
+	t.cacheReadTokens += cacheReadTokens
+	t.cost += cost
+}
+
+// KeySnapshot Key 级别累计指标快照（用于 Prometheus 等外部监控导出）
+type KeySnapshot struct {
+	MetricsKey          string
+	BaseURL             string
+	KeyMask             string
+	RequestCount        int64
+	SuccessCount        int64
+	FailureCount        int64
+	ActiveRequests      int64
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+	Cost                float64
+	CircuitBroken       bool      // 是否处于熔断状态（失败率达到阈值）
+	LatencyBuckets      []uint64  // 与 LatencyBucketBounds 对应的累积计数（不含 +Inf）
+	LatencyCount        uint64    // 耗时样本数（即 +Inf 桶）
+	LatencySum          float64   // 耗时总和（秒）
+	LastSuccessAt       time.Time // 零值表示从未成功
+}
+
+// SnapshotKeys 获取所有 Key 的累计指标快照
+func (m *MetricsManager) SnapshotKeys() []KeySnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]KeySnapshot, 0, len(m.keyMetrics))
+	for _, metrics := range m.keyMetrics {
+		snap := KeySnapshot{
+			MetricsKey:          metrics.MetricsKey,
+			BaseURL:             metrics.BaseURL,
+			KeyMask:             metrics.KeyMask,
+			RequestCount:        metrics.RequestCount,
+			SuccessCount:        metrics.SuccessCount,
+			FailureCount:        metrics.FailureCount,
+			ActiveRequests:      metrics.ActiveRequests,
+			InputTokens:         metrics.totals.inputTokens,
+			OutputTokens:        metrics.totals.outputTokens,
+			CacheCreationTokens: metrics.totals.cacheCreationTokens,
+			CacheReadTokens:     metrics.totals.cacheReadTokens,
+			Cost:                metrics.totals.cost,
+			CircuitBroken:       metrics.CircuitBrokenAt != nil || m.isKeyCircuitBroken(metrics),
+			LatencyBuckets:      make([]uint64, len(LatencyBucketBounds)),
+			LatencyCount:        metrics.totals.latency.count,
+			LatencySum:          metrics.totals.latency.sum,
+		}
+		if metrics.LastSuccessAt != nil {
+			snap.LastSuccessAt = *metrics.LastSuccessAt
+		}
+		var cumulative uint64
+		for i := range LatencyBucketBounds {
+			if metrics.totals.latency.counts != nil {
+				cumulative += metrics.totals.latency.counts[i]
+			}
+			snap.LatencyBuckets[i] = cumulative
+		}
+		result = append(result, snap)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].MetricsKey < result[j].MetricsKey
+	})
+	return result
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+func TestSnapshotKeys_TotalsAndLatency(t *testing.T) {
+	m := NewMetricsManagerWithConfig(10, 0.5)
+	baseURL := "https://example.com"
+
+	id := m.RecordRequestConnected(baseURL, "k1")
+	m.RecordRequestFinalizeSuccessWithCost(baseURL, "k1", id, &types.Usage{
+		InputTokens:              100,
+		OutputTokens:             20,
+		CacheCreationInputTokens: 30,
+		CacheReadInputTokens:     40,
+	}, 0.25)
+	id = m.RecordRequestConnected(baseURL, "k1")
+	m.RecordRequestFinalizeFailure(baseURL, "k1", id)
+	m.RecordRequestStart(baseURL, "k1") // 进行中
+
+	snaps := m.SnapshotKeys()
+	if len(snaps) != 1 {
+		t.Fatalf("expected 1 snapshot, got %d", len(snaps))
+	}
+	s := snaps[0]
+	if s.RequestCount != 2 || s.SuccessCount != 1 || s.FailureCount != 1 || s.ActiveRequests != 1 {
+		t.Fatalf("unexpected counts: %+v", s)
+	}
+	if s.InputTokens != 100 || s.OutputTokens != 20 || s.CacheCreationTokens != 30 || s.CacheReadTokens != 40 {
+		t.Fatalf("unexpected tokens: %+v", s)
+	}
+	if s.Cost != 0.25 {
+		t.Fatalf("cost = %v, want 0.25", s.Cost)
+	}
+	// 两个已结束的请求耗时都远小于 0.5s，应全部落入第一个桶
+	if s.LatencyCount != 2 || s.LatencyBuckets[0] != 2 || s.LatencyBuckets[len(s.LatencyBuckets)-1] != 2 {
+		t.Fatalf("unexpected latency histogram: count=%d buckets=%v", s.LatencyCount, s.LatencyBuckets)
+	}
+
+	// 重置 Key 后累计值清零
+	m.ResetKey(baseURL, "k1")
+	s = m.SnapshotKeys()[0]
+	if s.InputTokens != 0 || s.Cost != 0 || s.LatencyCount != 0 {
+		t.Fatalf("totals should be cleared after reset: %+v", s)
+	}
+}
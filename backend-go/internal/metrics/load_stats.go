@@ -0,0 +1,35 @@
+package metrics
+
+import "time"
+
+// LoadStats 实时负载数据（用于 least-inflight / lowest-latency 负载均衡策略）
+type LoadStats struct {
+	InFlight       int64         // 进行中请求数
+	AverageLatency time.Duration // 平均请求耗时（无样本时为 0）
+	LatencySamples uint64        // 耗时样本数
+}
+
+// GetLoadStats 聚合指定 BaseURL 与 Key 组合的负载数据
+// 进行中请求数来自 RecordRequestStart/RecordRequestEnd，耗时来自请求结束时记录的耗时直方图
+func (m *MetricsManager) GetLoadStats(baseURLs, apiKeys []string) LoadStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var stats LoadStats
+	var latencySum float64
+	for _, baseURL := range baseURLs {
+		for _, apiKey := range apiKeys {
+			metrics, exists := m.keyMetrics[generateMetricsKey(baseURL, apiKey)]
+			if !exists {
+				continue
+			}
+			stats.InFlight += metrics.ActiveRequests
+			latencySum += metrics.totals.latency.sum
+			stats.LatencySamples += metrics.totals.latency.count
+		}
+	}
+	if stats.LatencySamples > 0 {
+		stats.AverageLatency = time.Duration(latencySum / float64(stats.LatencySamples) * float64(time.Second))
+	}
+	return stats
+}
@@ -34,5 +34,27 @@ type PersistentRecord struct {
 	OutputTokens        int64     // 输出 Token 数
 	CacheCreationTokens int64     // 缓存创建 Token
 	CacheReadTokens     int64     // 缓存读取 Token
+	ClientID            string    // 发起请求的客户端 ID（管理员密钥访问时为空）
+	Cost                float64   // 请求费用（美元）
+	RequestedModel      string    // 客户端请求的模型
+	Model               string    // 重定向后的模型
 	APIType             string    // "messages"、"responses" 或 "gemini"
 }
+
+// ClientUsageStore 客户端用量持久化接口（用于配额结算，按天累计）
+type ClientUsageStore interface {
+	// AddClientUsage 累加客户端某一天的用量
+	AddClientUsage(records []ClientUsageRecord) error
+
+	// LoadClientUsage 加载 sinceDay（含）之后的每日用量
+	LoadClientUsage(sinceDay string) ([]ClientUsageRecord, error)
+}
+
+// ClientUsageRecord 客户端每日用量
+type ClientUsageRecord struct {
+	ClientID     string // 客户端 ID
+	Day          string // 日期（YYYY-MM-DD，本地时区）
+	Requests     int64  // 请求数
+	InputTokens  int64  // 输入 Token（含缓存读写）
+	OutputTokens int64  // 输出 Token
+}
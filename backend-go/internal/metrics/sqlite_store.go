@@ -122,7 +122,11 @@ func initSchema(db *sql.DB) error {
 			output_tokens INTEGER DEFAULT 0,
 			cache_creation_tokens INTEGER DEFAULT 0,
 			cache_read_tokens INTEGER DEFAULT 0,
-			api_type TEXT NOT NULL DEFAULT 'messages'
+			api_type TEXT NOT NULL DEFAULT 'messages',
+			client_id TEXT NOT NULL DEFAULT '',
+			cost REAL NOT NULL DEFAULT 0,
+			requested_model TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT ''
 		);
 
 		-- 索引：按 api_type 和时间查询
@@ -132,10 +136,79 @@ func initSchema(db *sql.DB) error {
 		-- 索引：按 metrics_key 查询
 		CREATE INDEX IF NOT EXISTS idx_records_metrics_key
 			ON request_records(metrics_key);
+
+		-- 客户端每日用量表（配额结算）
+		CREATE TABLE IF NOT EXISTS client_usage (
+			client_id TEXT NOT NULL,
+			day TEXT NOT NULL,
+			requests INTEGER DEFAULT 0,
+			input_tokens INTEGER DEFAULT 0,
+			output_tokens INTEGER DEFAULT 0,
+			PRIMARY KEY (client_id, day)
+		);
 	`
 
-	_, err := db.Exec(schema)
-	return err
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	return migrateSchema(db)
+}
+
+// schemaColumnMigrations 旧版数据库需要补充的列（表名 -> 列定义）
+var schemaColumnMigrations = []struct {
+	table  string
+	column string
+	ddl    string
+}{
+	{"request_records", "client_id", "ALTER TABLE request_records ADD COLUMN client_id TEXT NOT NULL DEFAULT ''"},
+	{"request_records", "cost", "ALTER TABLE request_records ADD COLUMN cost REAL NOT NULL DEFAULT 0"},
+	{"request_records", "requested_model", "ALTER TABLE request_records ADD COLUMN requested_model TEXT NOT NULL DEFAULT ''"},
+	{"request_records", "model", "ALTER TABLE request_records ADD COLUMN model TEXT NOT NULL DEFAULT ''"},
+}
+
+// migrateSchema 为旧版数据库补充新增列（幂等）
+func migrateSchema(db *sql.DB) error {
+	for _, m := range schemaColumnMigrations {
+		exists, err := columnExists(db, m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.Exec(m.ddl); err != nil {
+			return fmt.Errorf("迁移 %s.%s 失败: %w", m.table, m.column, err)
+		}
+		log.Printf("[SQLite-Migrate] 已为 %s 表新增列: %s", m.table, m.column)
+	}
+	return nil
+}
+
+// columnExists 检查表中是否存在指定列
+func columnExists(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
 }
 
 // AddRecord 添加记录到写入缓冲区（非阻塞）
@@ -203,8 +276,9 @@ func (s *SQLiteStore) batchInsertRecords(records []PersistentRecord) error {
 	stmt, err := tx.Prepare(`
 		INSERT INTO request_records
 		(metrics_key, base_url, key_mask, timestamp, success,
-		 input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, api_type)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, api_type,
+		 client_id, cost, requested_model, model)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return err
@@ -219,6 +293,7 @@ func (s *SQLiteStore) batchInsertRecords(records []PersistentRecord) error {
 		_, err := stmt.Exec(
 			r.MetricsKey, r.BaseURL, r.KeyMask, r.Timestamp.Unix(), success,
 			r.InputTokens, r.OutputTokens, r.CacheCreationTokens, r.CacheReadTokens, r.APIType,
+			r.ClientID, r.Cost, r.RequestedModel, r.Model,
 		)
 		if err != nil {
 			return err
@@ -232,7 +307,8 @@ func (s *SQLiteStore) batchInsertRecords(records []PersistentRecord) error {
 func (s *SQLiteStore) LoadRecords(since time.Time, apiType string) ([]PersistentRecord, error) {
 	rows, err := s.db.Query(`
 		SELECT metrics_key, base_url, key_mask, timestamp, success,
-		       input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens
+		       input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens,
+		       client_id, cost, requested_model, model
 		FROM request_records
 		WHERE timestamp >= ? AND api_type = ?
 		ORDER BY timestamp ASC
@@ -251,6 +327,7 @@ func (s *SQLiteStore) LoadRecords(since time.Time, apiType string) ([]Persistent
 		err := rows.Scan(
 			&r.MetricsKey, &r.BaseURL, &r.KeyMask, &ts, &success,
 			&r.InputTokens, &r.OutputTokens, &r.CacheCreationTokens, &r.CacheReadTokens,
+			&r.ClientID, &r.Cost, &r.RequestedModel, &r.Model,
 		)
 		if err != nil {
 			return nil, err
@@ -327,6 +404,65 @@ func (s *SQLiteStore) DeleteRecordsByMetricsKeys(metricsKeys []string, apiType s
 	return totalDeleted, nil
 }
 
+// AddClientUsage 累加客户端每日用量（UPSERT）
+func (s *SQLiteStore) AddClientUsage(records []ClientUsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO client_usage (client_id, day, requests, input_tokens, output_tokens)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(client_id, day) DO UPDATE SET
+			requests = requests + excluded.requests,
+			input_tokens = input_tokens + excluded.input_tokens,
+			output_tokens = output_tokens + excluded.output_tokens
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range records {
+		if _, err := stmt.Exec(r.ClientID, r.Day, r.Requests, r.InputTokens, r.OutputTokens); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadClientUsage 加载 sinceDay（含）之后的客户端每日用量
+func (s *SQLiteStore) LoadClientUsage(sinceDay string) ([]ClientUsageRecord, error) {
+	rows, err := s.db.Query(`
+		SELECT client_id, day, requests, input_tokens, output_tokens
+		FROM client_usage
+		WHERE day >= ?
+		ORDER BY day ASC
+	`, sinceDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []ClientUsageRecord
+	for rows.Next() {
+		var r ClientUsageRecord
+		if err := rows.Scan(&r.ClientID, &r.Day, &r.Requests, &r.InputTokens, &r.OutputTokens); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
 // flushLoop 定时刷新循环
 func (s *SQLiteStore) flushLoop() {
 	defer s.wg.Done()
@@ -379,6 +515,12 @@ func (s *SQLiteStore) doCleanup() {
 	} else if deleted > 0 {
 		log.Printf("[SQLite-Cleanup] 已清理 %d 条过期指标记录（超过 %d 天）", deleted, s.retentionDays)
 	}
+
+	// 客户端用量需覆盖整个自然月的配额结算，固定保留 62 天
+	usageCutoff := time.Now().AddDate(0, 0, -62).Format("2006-01-02")
+	if _, err := s.db.Exec("DELETE FROM client_usage WHERE day < ?", usageCutoff); err != nil {
+		log.Printf("[SQLite-Cleanup] 警告: 清理过期客户端用量失败: %v", err)
+	}
 }
 
 // Close 关闭存储
@@ -398,6 +540,11 @@ func (s *SQLiteStore) Close() error {
 	return s.db.Close()
 }
 
+// DB 返回底层数据库连接（供会话存储等模块复用同一数据库，连接由 Close 统一关闭）
+func (s *SQLiteStore) DB() *sql.DB {
+	return s.db
+}
+
 // GetRecordCount 获取记录总数（用于调试）
 func (s *SQLiteStore) GetRecordCount() (int64, error) {
 	var count int64
@@ -20,6 +20,12 @@ func WebAuthMiddleware(envCfg *config.EnvConfig, cfgManager *config.ConfigManage
 			return
 		}
 
+		// Prometheus 端点使用独立认证（MetricsAuthMiddleware）
+		if path == "/metrics" {
+			c.Next()
+			return
+		}
+
 		// 静态资源文件直接放行
 		if isStaticResource(path) {
 			c.Next()
@@ -120,6 +126,11 @@ func isStaticResource(path string) bool {
 	return false
 }
 
+// RequestAPIKey 返回请求携带的访问密钥（供审计等模块识别调用方凭据）
+func RequestAPIKey(c *gin.Context) string {
+	return getAPIKey(c)
+}
+
 // getAPIKey 获取 API 密钥
 func getAPIKey(c *gin.Context) string {
 	// 从 header 获取
@@ -140,24 +151,68 @@ func getAPIKey(c *gin.Context) string {
 	return ""
 }
 
+// MetricsAuthMiddleware Prometheus 端点访问控制中间件
+// 接受 PROMETHEUS_ACCESS_KEY（如已配置）或管理员密钥；PROMETHEUS_PUBLIC=true 时免认证。
+func MetricsAuthMiddleware(envCfg *config.EnvConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if envCfg.PrometheusPublic {
+			c.Next()
+			return
+		}
+
+		providedKey := getAPIKey(c)
+		if providedKey != "" && (providedKey == envCfg.ProxyAccessKey ||
+			(envCfg.PrometheusAccessKey != "" && providedKey == envCfg.PrometheusAccessKey)) {
+			c.Next()
+			return
+		}
+
+		log.Printf("[Auth-Failed] IP: %s | Path: %s | Time: %s | Reason: 指标端点密钥无效",
+			c.ClientIP(), c.Request.URL.Path, time.Now().Format(time.RFC3339))
+		c.String(401, "Unauthorized\n")
+		c.Abort()
+	}
+}
+
 // ProxyAuthMiddleware 代理访问控制中间件
-func ProxyAuthMiddleware(envCfg *config.EnvConfig) gin.HandlerFunc {
+// 先匹配管理员密钥（PROXY_ACCESS_KEY），再匹配多租户客户端密钥；
+// 客户端认证成功后将身份写入 gin.Context，供指标、日志与 Trace 亲和性使用。
+func ProxyAuthMiddleware(envCfg *config.EnvConfig, cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		providedKey := getAPIKey(c)
 		expectedKey := envCfg.ProxyAccessKey
 
-		if providedKey == "" || providedKey != expectedKey {
-			if envCfg.ShouldLog("warn") {
-				log.Printf("[Auth-Failed] 代理访问密钥验证失败 - IP: %s", c.ClientIP())
+		if providedKey != "" && providedKey == expectedKey {
+			c.Next()
+			return
+		}
+
+		if providedKey != "" && cfgManager != nil {
+			client, err := cfgManager.AuthenticateClient(providedKey)
+			if err != nil {
+				if envCfg.ShouldLog("warn") {
+					log.Printf("[Auth-Failed] 客户端密钥不可用 - IP: %s | Reason: %v", c.ClientIP(), err)
+				}
+				c.JSON(401, gin.H{
+					"error": err.Error(),
+				})
+				c.Abort()
+				return
+			}
+			if client != nil {
+				c.Set(ClientContextKey, client)
+				c.Next()
+				return
 			}
+		}
 
-			c.JSON(401, gin.H{
-				"error": "Invalid proxy access key",
-			})
-			c.Abort()
-			return
+		if envCfg.ShouldLog("warn") {
+			log.Printf("[Auth-Failed] 代理访问密钥验证失败 - IP: %s", c.ClientIP())
 		}
 
-		c.Next()
+		c.JSON(401, gin.H{
+			"error": "Invalid proxy access key",
+		})
+		c.Abort()
 	}
 }
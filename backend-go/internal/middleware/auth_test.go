@@ -185,3 +185,58 @@ func TestWebAuthMiddleware_AllowsV1BetaRoutesWhenWebUIDisabled(t *testing.T) {
 		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
 	}
 }
+
+func TestMetricsAuthMiddleware(t *testing.T) {
+	envCfg := &config.EnvConfig{
+		ProxyAccessKey:      "secret-key",
+		PrometheusAccessKey: "scrape-key",
+		EnableWebUI:         false,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(WebAuthMiddleware(envCfg, nil))
+	r.GET("/metrics", MetricsAuthMiddleware(envCfg), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	cases := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing key returns 401", "", http.StatusUnauthorized},
+		{"wrong key returns 401", "Bearer wrong", http.StatusUnauthorized},
+		{"scrape key allows access", "Bearer scrape-key", http.StatusOK},
+		{"admin key allows access", "Bearer secret-key", http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.want {
+				t.Fatalf("status = %d, want %d", w.Code, tc.want)
+			}
+		})
+	}
+
+	t.Run("public mode skips auth", func(t *testing.T) {
+		envCfg.PrometheusPublic = true
+		defer func() { envCfg.PrometheusPublic = false }()
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+}
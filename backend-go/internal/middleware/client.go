@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/quota"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientContextKey gin.Context 中保存已认证客户端的键
+const ClientContextKey = "proxyClient"
+
+// GetClient 获取当前请求的已认证客户端；使用管理员密钥访问时返回 nil
+func GetClient(c *gin.Context) *config.ClientKey {
+	if c == nil {
+		return nil
+	}
+	if v, ok := c.Get(ClientContextKey); ok {
+		if client, ok := v.(*config.ClientKey); ok {
+			return client
+		}
+	}
+	return nil
+}
+
+// GetClientID 获取当前请求的客户端 ID；管理员密钥访问时返回空字符串
+func GetClientID(c *gin.Context) string {
+	if client := GetClient(c); client != nil {
+		return client.ID
+	}
+	return ""
+}
+
+// GetClientName 获取当前请求的客户端名称（用于日志）
+func GetClientName(c *gin.Context) string {
+	if client := GetClient(c); client != nil {
+		return client.Name
+	}
+	return ""
+}
+
+// CheckClientScope 校验客户端对入口类型与模型的访问权限
+// kind 取值与调度器 ChannelKind 一致（messages/responses/gemini/chat）；model 为空时仅校验入口类型。
+// 校验失败时写入 403 响应并返回 false。
+func CheckClientScope(c *gin.Context, kind, model string) bool {
+	client := GetClient(c)
+	if client == nil {
+		return true
+	}
+
+	var reason string
+	switch {
+	case !client.AllowsKind(kind):
+		reason = fmt.Sprintf("client %q is not allowed to access %s API", client.Name, kind)
+	case !client.AllowsModel(model):
+		reason = fmt.Sprintf("client %q is not allowed to use model %q", client.Name, model)
+	default:
+		return true
+	}
+
+	log.Printf("[Auth-Scope] 客户端越权访问已拒绝 - Client: %s | IP: %s | Kind: %s | Model: %s", client.Name, c.ClientIP(), kind, model)
+	c.JSON(403, gin.H{
+		"error": reason,
+	})
+	c.Abort()
+	return false
+}
+
+// CheckClientQuota 在转发前校验客户端配额（RPM 与 Token 预算）
+// 超限时按入口协议写入 429 响应并返回 false；通过时将配额管理器绑定到请求上下文，供结束后结算 usage。
+func CheckClientQuota(c *gin.Context, quotaManager *quota.Manager, kind string) bool {
+	client := GetClient(c)
+	if client == nil || quotaManager == nil {
+		return true
+	}
+
+	if err := quotaManager.Check(client); err != nil {
+		var exceeded *quota.ExceededError
+		if !errors.As(err, &exceeded) {
+			return true
+		}
+		log.Printf("[Quota-Exceeded] 客户端配额超限 - Client: %s | Kind: %s | Limit: %s | Used: %d/%d",
+			client.Name, kind, exceeded.Limit, exceeded.Used, exceeded.Max)
+		writeQuotaExceeded(c, kind, exceeded)
+		c.Abort()
+		return false
+	}
+
+	quota.Bind(c, quotaManager, client.ID)
+	return true
+}
+
+// writeQuotaExceeded 按入口协议返回 429 错误
+func writeQuotaExceeded(c *gin.Context, kind string, exceeded *quota.ExceededError) {
+	retrySeconds := int64(math.Ceil(exceeded.RetryAfter.Seconds()))
+	if retrySeconds < 1 {
+		retrySeconds = 1
+	}
+	c.Header("Retry-After", strconv.FormatInt(retrySeconds, 10))
+
+	message := exceeded.Error()
+	switch kind {
+	case "gemini":
+		c.JSON(429, types.GeminiError{
+			Error: types.GeminiErrorDetail{
+				Code:    429,
+				Message: message,
+				Status:  "RESOURCE_EXHAUSTED",
+			},
+		})
+	case "responses", "chat":
+		errType, code := "rate_limit_exceeded", "rate_limit_exceeded"
+		if exceeded.Limit != quota.LimitRPM {
+			errType, code = "insufficient_quota", "insufficient_quota"
+		}
+		c.JSON(429, gin.H{
+			"error": gin.H{
+				"message": message,
+				"type":    errType,
+				"param":   nil,
+				"code":    code,
+			},
+		})
+	default:
+		c.JSON(429, gin.H{
+			"type": "error",
+			"error": gin.H{
+				"type":    "rate_limit_error",
+				"message": message,
+			},
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/quota"
+	"github.com/gin-gonic/gin"
+)
+
+func TestProxyAuthMiddleware_ClientKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"upstream": [], "loadBalance": "failover"}`), 0644); err != nil {
+		t.Fatalf("写入初始配置失败: %v", err)
+	}
+	cfgManager, err := config.NewConfigManager(configPath)
+	if err != nil {
+		t.Fatalf("创建配置管理器失败: %v", err)
+	}
+	t.Cleanup(func() { cfgManager.Close() })
+
+	_, secret, err := cfgManager.AddClient(config.ClientKey{
+		Name:          "team-a",
+		AllowedKinds:  []string{"messages"},
+		AllowedModels: []string{"claude-*"},
+	}, "")
+	if err != nil {
+		t.Fatalf("AddClient 失败: %v", err)
+	}
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "admin-key"}
+
+	r := gin.New()
+	r.POST("/v1/:kind/:model", func(c *gin.Context) {
+		ProxyAuthMiddleware(envCfg, cfgManager)(c)
+		if c.IsAborted() {
+			return
+		}
+		if !CheckClientScope(c, c.Param("kind"), c.Param("model")) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"client": GetClientName(c)})
+	})
+
+	tests := []struct {
+		name     string
+		key      string
+		path     string
+		wantCode int
+	}{
+		{"admin key bypasses scopes", "admin-key", "/v1/gemini/gemini-pro", http.StatusOK},
+		{"client key within scope", secret, "/v1/messages/claude-sonnet-4", http.StatusOK},
+		{"client key wrong kind", secret, "/v1/gemini/claude-sonnet-4", http.StatusForbidden},
+		{"client key wrong model", secret, "/v1/messages/gpt-4o", http.StatusForbidden},
+		{"unknown key", "nope", "/v1/messages/claude-sonnet-4", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.Header.Set("x-api-key", tt.key)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d, body=%s", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCheckClientQuota_ProtocolErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client := &config.ClientKey{ID: "c1", Name: "team-a", RPMLimit: 1}
+
+	tests := []struct {
+		kind     string
+		wantType string
+	}{
+		{"messages", "rate_limit_error"},
+		{"responses", "rate_limit_exceeded"},
+		{"chat", "rate_limit_exceeded"},
+		{"gemini", "RESOURCE_EXHAUSTED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			quotaManager := quota.NewManager(nil)
+			r := gin.New()
+			r.POST("/", func(c *gin.Context) {
+				c.Set(ClientContextKey, client)
+				if !CheckClientQuota(c, quotaManager, tt.kind) {
+					return
+				}
+				c.Status(http.StatusOK)
+			})
+
+			codes := make([]int, 0, 2)
+			var last *httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				last = httptest.NewRecorder()
+				r.ServeHTTP(last, httptest.NewRequest(http.MethodPost, "/", nil))
+				codes = append(codes, last.Code)
+			}
+
+			if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+				t.Fatalf("status = %v, want [200 429]", codes)
+			}
+			if last.Header().Get("Retry-After") == "" {
+				t.Fatal("429 响应缺少 Retry-After 头")
+			}
+			if !strings.Contains(last.Body.String(), tt.wantType) {
+				t.Fatalf("body = %s, want contains %q", last.Body.String(), tt.wantType)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// Package pricing 提供模型价格表与按 usage 计算请求费用
+package pricing
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+// tokensPerUnit 价格单位：每百万 Token
+const tokensPerUnit = 1_000_000
+
+// ModelPrice 单个模型的价格（美元 / 百万 Token）
+// 未设置（为 0）的缓存价格按以下规则兜底：
+//   - CacheRead 回退为 Input
+//   - CacheWrite5m 回退为 Input
+//   - CacheWrite1h 回退为 CacheWrite5m
+type ModelPrice struct {
+	Input        float64 `json:"input"`
+	Output       float64 `json:"output"`
+	CacheRead    float64 `json:"cacheRead,omitempty"`
+	CacheWrite5m float64 `json:"cacheWrite5m,omitempty"`
+	CacheWrite1h float64 `json:"cacheWrite1h,omitempty"`
+}
+
+// Validate 校验价格（不允许负数）
+func (p ModelPrice) Validate() error {
+	if p.Input < 0 || p.Output < 0 || p.CacheRead < 0 || p.CacheWrite5m < 0 || p.CacheWrite1h < 0 {
+		return fmt.Errorf("价格不能为负数")
+	}
+	return nil
+}
+
+// withFallbacks 填充未设置的缓存价格
+func (p ModelPrice) withFallbacks() ModelPrice {
+	if p.CacheRead == 0 {
+		p.CacheRead = p.Input
+	}
+	if p.CacheWrite5m == 0 {
+		p.CacheWrite5m = p.Input
+	}
+	if p.CacheWrite1h == 0 {
+		p.CacheWrite1h = p.CacheWrite5m
+	}
+	return p
+}
+
+// defaultPrices 内置价格表（官方公开价格，可在配置中按模型覆盖）
+// key 支持精确模型名或 glob 模式（path.Match 语法）
+var defaultPrices = map[string]ModelPrice{
+	// Anthropic
+	"claude-opus-4*":     {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite5m: 18.75, CacheWrite1h: 30},
+	"claude-opus-4-5*":   {Input: 5, Output: 25, CacheRead: 0.5, CacheWrite5m: 6.25, CacheWrite1h: 10},
+	"claude-sonnet-4*":   {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite5m: 3.75, CacheWrite1h: 6},
+	"claude-3-7-sonnet*": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite5m: 3.75, CacheWrite1h: 6},
+	"claude-3-5-sonnet*": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite5m: 3.75, CacheWrite1h: 6},
+	"claude-haiku-4-5*":  {Input: 1, Output: 5, CacheRead: 0.1, CacheWrite5m: 1.25, CacheWrite1h: 2},
+	"claude-3-5-haiku*":  {Input: 0.8, Output: 4, CacheRead: 0.08, CacheWrite5m: 1, CacheWrite1h: 1.6},
+	// OpenAI
+	"gpt-4o*":       {Input: 2.5, Output: 10, CacheRead: 1.25},
+	"gpt-4o-mini*":  {Input: 0.15, Output: 0.6, CacheRead: 0.075},
+	"gpt-4.1*":      {Input: 2, Output: 8, CacheRead: 0.5},
+	"gpt-4.1-mini*": {Input: 0.4, Output: 1.6, CacheRead: 0.1},
+	"gpt-4.1-nano*": {Input: 0.1, Output: 0.4, CacheRead: 0.025},
+	"gpt-5*":        {Input: 1.25, Output: 10, CacheRead: 0.125},
+	"gpt-5-mini*":   {Input: 0.25, Output: 2, CacheRead: 0.025},
+	"gpt-5-nano*":   {Input: 0.05, Output: 0.4, CacheRead: 0.005},
+	"o3*":           {Input: 2, Output: 8, CacheRead: 0.5},
+	"o4-mini*":      {Input: 1.1, Output: 4.4, CacheRead: 0.275},
+	// Google
+	"gemini-2.5-pro*":        {Input: 1.25, Output: 10, CacheRead: 0.31},
+	"gemini-2.5-flash*":      {Input: 0.3, Output: 2.5, CacheRead: 0.075},
+	"gemini-2.5-flash-lite*": {Input: 0.1, Output: 0.4, CacheRead: 0.025},
+	"gemini-2.0-flash*":      {Input: 0.1, Output: 0.4, CacheRead: 0.025},
+}
+
+// DefaultPrices 返回内置价格表的副本
+func DefaultPrices() map[string]ModelPrice {
+	result := make(map[string]ModelPrice, len(defaultPrices))
+	for k, v := range defaultPrices {
+		result[k] = v
+	}
+	return result
+}
+
+// ValidatePattern 校验价格表中的模型名/模式
+func ValidatePattern(pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("模型名不能为空")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("无效的模型模式 %q: %v", pattern, err)
+	}
+	return nil
+}
+
+// Lookup 查找模型价格：overrides 优先于内置价格表
+// 同一价格表内精确匹配优先，其次按模式长度从长到短匹配（越具体越优先）
+func Lookup(overrides map[string]ModelPrice, model string) (ModelPrice, bool) {
+	if model == "" {
+		return ModelPrice{}, false
+	}
+	if p, ok := lookupIn(overrides, model); ok {
+		return p, true
+	}
+	return lookupIn(defaultPrices, model)
+}
+
+func lookupIn(prices map[string]ModelPrice, model string) (ModelPrice, bool) {
+	if len(prices) == 0 {
+		return ModelPrice{}, false
+	}
+	if p, ok := prices[model]; ok {
+		return p, true
+	}
+
+	patterns := make([]string, 0, len(prices))
+	for pattern := range prices {
+		if strings.ContainsAny(pattern, "*?[") {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, model); ok {
+			return prices[pattern], true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+// Cost 按价格计算单次请求费用（美元）
+// usage.InputTokens 不含缓存读写（与 Claude 口径一致）；缓存写入优先按 5m/1h 细分计费，
+// 未细分的部分按 5m 价格计费。
+func (p ModelPrice) Cost(usage *types.Usage) float64 {
+	if usage == nil {
+		return 0
+	}
+	p = p.withFallbacks()
+
+	write5m := int64(usage.CacheCreation5mInputTokens)
+	write1h := int64(usage.CacheCreation1hInputTokens)
+	if rest := int64(usage.CacheCreationInputTokens) - write5m - write1h; rest > 0 {
+		write5m += rest
+	}
+
+	total := float64(usage.InputTokens)*p.Input +
+		float64(usage.OutputTokens)*p.Output +
+		float64(usage.CacheReadInputTokens)*p.CacheRead +
+		float64(write5m)*p.CacheWrite5m +
+		float64(write1h)*p.CacheWrite1h
+	return total / tokensPerUnit
+}
+
+// Calculate 查找模型价格并计算费用；未知模型返回 0
+func Calculate(overrides map[string]ModelPrice, model string, usage *types.Usage) float64 {
+	p, ok := Lookup(overrides, model)
+	if !ok {
+		return 0
+	}
+	return p.Cost(usage)
+}
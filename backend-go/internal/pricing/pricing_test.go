@@ -0,0 +1,87 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestLookup_Precedence(t *testing.T) {
+	overrides := map[string]ModelPrice{
+		"claude-sonnet-4-5-20250929": {Input: 1, Output: 2},
+		"my-*":                       {Input: 7, Output: 8},
+	}
+
+	tests := []struct {
+		model     string
+		wantInput float64
+		wantOK    bool
+	}{
+		{"claude-sonnet-4-5-20250929", 1, true}, // 覆盖精确匹配
+		{"claude-sonnet-4-20250514", 3, true},   // 内置模式
+		{"claude-opus-4-5-20251101", 5, true},   // 更长的内置模式优先
+		{"claude-opus-4-1-20250805", 15, true},
+		{"gpt-4o-mini-2024-07-18", 0.15, true},
+		{"my-model", 7, true},
+		{"unknown-model", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		p, ok := Lookup(overrides, tt.model)
+		if ok != tt.wantOK || !almostEqual(p.Input, tt.wantInput) {
+			t.Errorf("Lookup(%q) = (%+v, %v), want input=%v ok=%v", tt.model, p, ok, tt.wantInput, tt.wantOK)
+		}
+	}
+}
+
+func TestModelPrice_Cost(t *testing.T) {
+	p := ModelPrice{Input: 3, Output: 15, CacheRead: 0.3, CacheWrite5m: 3.75, CacheWrite1h: 6}
+
+	usage := &types.Usage{
+		InputTokens:                1_000_000,
+		OutputTokens:               100_000,
+		CacheReadInputTokens:       2_000_000,
+		CacheCreationInputTokens:   300_000,
+		CacheCreation1hInputTokens: 100_000, // 其余 200k 按 5m 价格计费
+	}
+	// 3 + 1.5 + 0.6 + 0.2*3.75 + 0.1*6
+	want := 3 + 1.5 + 0.6 + 0.75 + 0.6
+	if got := p.Cost(usage); !almostEqual(got, want) {
+		t.Fatalf("Cost = %v, want %v", got, want)
+	}
+
+	if got := p.Cost(nil); got != 0 {
+		t.Fatalf("Cost(nil) = %v, want 0", got)
+	}
+}
+
+func TestModelPrice_CostFallbacks(t *testing.T) {
+	// 仅配置输入/输出价格时，缓存读写按输入价格计费
+	p := ModelPrice{Input: 2, Output: 4}
+	usage := &types.Usage{
+		CacheReadInputTokens:       500_000,
+		CacheCreation5mInputTokens: 250_000,
+		CacheCreation1hInputTokens: 250_000,
+	}
+	if got := p.Cost(usage); !almostEqual(got, 2) {
+		t.Fatalf("Cost = %v, want 2", got)
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	if err := ValidatePattern("claude-*"); err != nil {
+		t.Fatalf("合法模式校验失败: %v", err)
+	}
+	if err := ValidatePattern("claude-["); err == nil {
+		t.Fatal("非法模式应返回错误")
+	}
+	if err := ValidatePattern("  "); err == nil {
+		t.Fatal("空模式应返回错误")
+	}
+}
@@ -10,6 +10,7 @@
 	"strings"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/converters"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
 	"github.com/gin-gonic/gin"
@@ -92,6 +93,11 @@ func (p *GeminiProvider) convertToGeminiRequest(claudeReq *types.ClaudeRequest,
 		genConfig["temperature"] = claudeReq.Temperature
 	}
 
+	// 扩展思考：thinking → thinkingConfig（disabled 映射为 thinkingBudget=0）
+	if thinkingConfig := converters.ClaudeThinkingToGeminiConfig(claudeReq.Thinking); thinkingConfig != nil {
+		genConfig["thinkingConfig"] = thinkingConfig
+	}
+
 	if len(genConfig) > 0 {
 		req["generationConfig"] = genConfig
 	}
@@ -148,6 +154,16 @@ func (p *GeminiProvider) convertMessage(msg types.ClaudeMessage) map[string]inte
 		return nil
 	}
 
+	// thinking 块的签名回传到紧随其后的 part（Gemini thoughtSignature 位于 part 层级）
+	pendingSignature := ""
+	withSignature := func(part map[string]interface{}) map[string]interface{} {
+		if pendingSignature != "" {
+			part["thoughtSignature"] = pendingSignature
+			pendingSignature = ""
+		}
+		return part
+	}
+
 	for _, c := range contents {
 		content, ok := c.(map[string]interface{})
 		if !ok {
@@ -157,31 +173,51 @@ func (p *GeminiProvider) convertMessage(msg types.ClaudeMessage) map[string]inte
 		contentType, _ := content["type"].(string)
 
 		switch contentType {
+		case "thinking":
+			// 思考内容本身不回传，仅保留可用于上游校验的签名
+			if signature, _ := content["signature"].(string); converters.IsForwardableThinkingSignature(signature) {
+				pendingSignature = signature
+			}
+
 		case "text":
 			if text, ok := content["text"].(string); ok {
-				parts = append(parts, map[string]string{
+				parts = append(parts, withSignature(map[string]interface{}{
 					"text": text,
-				})
+				}))
+			}
+
+		case "image", "document":
+			if part := converters.ClaudeMediaBlockToGeminiPart(content); part != nil {
+				parts = append(parts, part)
 			}
 
 		case "tool_use":
 			name, _ := content["name"].(string)
 			input := content["input"]
 
-			parts = append(parts, map[string]interface{}{
+			parts = append(parts, withSignature(map[string]interface{}{
 				"functionCall": map[string]interface{}{
 					"name": name,
 					"args": input,
 				},
-			})
+			}))
 
 		case "tool_result":
 			toolUseID, _ := content["tool_use_id"].(string)
 			resultContent := content["content"]
 
 			var response interface{}
+			var mediaParts []interface{}
 			if str, ok := resultContent.(string); ok {
 				response = map[string]string{"result": str}
+			} else if text, media, ok := converters.SplitClaudeToolResultContent(resultContent); ok {
+				// functionResponse 只能携带 JSON，图片/文档作为后续 part 追加
+				response = map[string]string{"result": text}
+				for _, block := range media {
+					if part := converters.ClaudeMediaBlockToGeminiPart(block); part != nil {
+						mediaParts = append(mediaParts, part)
+					}
+				}
 			} else {
 				response = resultContent
 			}
@@ -192,6 +228,7 @@ func (p *GeminiProvider) convertMessage(msg types.ClaudeMessage) map[string]inte
 					"response": response,
 				},
 			})
+			parts = append(parts, mediaParts...)
 		}
 	}
 
@@ -261,6 +298,31 @@ func (p *GeminiProvider) ConvertToClaudeResponse(providerResp *types.ProviderRes
 			continue
 		}
 
+		signature := geminiPartSignature(part)
+
+		// 思考内容 → thinking 块
+		if thought, _ := part["thought"].(bool); thought {
+			text, _ := part["text"].(string)
+			claudeResp.Content = append(claudeResp.Content, types.ClaudeContent{
+				Type:      "thinking",
+				Thinking:  text,
+				Signature: signature,
+			})
+			continue
+		}
+
+		// 普通 part 上的签名归属于前面的思考过程：补到最近的 thinking 块，没有则生成空 thinking 块承载
+		if signature != "" {
+			if n := len(claudeResp.Content); n > 0 && claudeResp.Content[n-1].Type == "thinking" && claudeResp.Content[n-1].Signature == "" {
+				claudeResp.Content[n-1].Signature = signature
+			} else {
+				claudeResp.Content = append(claudeResp.Content, types.ClaudeContent{
+					Type:      "thinking",
+					Signature: signature,
+				})
+			}
+		}
+
 		// 文本内容
 		if text, ok := part["text"].(string); ok {
 			claudeResp.Content = append(claudeResp.Content, types.ClaudeContent{
@@ -269,6 +331,15 @@ func (p *GeminiProvider) ConvertToClaudeResponse(providerResp *types.ProviderRes
 			})
 		}
 
+		// 内联数据（如图片生成结果）
+		if inlineData, ok := part["inlineData"].(map[string]interface{}); ok {
+			mimeType, _ := inlineData["mimeType"].(string)
+			data, _ := inlineData["data"].(string)
+			if data != "" {
+				claudeResp.Content = append(claudeResp.Content, converters.GeminiInlineDataToClaudeBlock(mimeType, data))
+			}
+		}
+
 		// 函数调用
 		if fc, ok := part["functionCall"].(map[string]interface{}); ok {
 			name, _ := fc["name"].(string)
@@ -340,6 +411,18 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 		textBlockStarted := false
 		textBlockIndex := 0
 
+		// thinking 块状态跟踪（与文本块共用索引计数）
+		thinkingBlockStarted := false
+		closeThinkingBlock := func() {
+			if !thinkingBlockStarted {
+				return
+			}
+			eventChan <- contentBlockStopEvent(textBlockIndex)
+			thinkingBlockStarted = false
+			textBlockIndex++
+			toolUseBlockIndex++
+		}
+
 		for scanner.Scan() {
 			line := scanner.Text()
 			line = strings.TrimSpace(line)
@@ -385,6 +468,38 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 					continue
 				}
 
+				signature := geminiPartSignature(part)
+
+				// 处理思考内容
+				if thought, _ := part["thought"].(bool); thought {
+					if textBlockStarted {
+						eventChan <- contentBlockStopEvent(textBlockIndex)
+						textBlockStarted = false
+						textBlockIndex++
+					}
+					if !thinkingBlockStarted {
+						eventChan <- thinkingStartEvent(textBlockIndex)
+						thinkingBlockStarted = true
+					}
+					if text, _ := part["text"].(string); text != "" {
+						eventChan <- thinkingDeltaEvent(textBlockIndex, text)
+					}
+					if signature != "" {
+						eventChan <- signatureDeltaEvent(textBlockIndex, signature)
+					}
+					continue
+				}
+
+				// 普通 part 上的签名：写入进行中的 thinking 块，没有则用空 thinking 块承载
+				if signature != "" && !textBlockStarted {
+					if !thinkingBlockStarted {
+						eventChan <- thinkingStartEvent(textBlockIndex)
+						thinkingBlockStarted = true
+					}
+					eventChan <- signatureDeltaEvent(textBlockIndex, signature)
+				}
+				closeThinkingBlock()
+
 				// 处理文本
 				if text, ok := part["text"].(string); ok {
 					// 如果是第一个文本块,发送 content_block_start
@@ -443,6 +558,8 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 
 			// 处理结束原因
 			if finishReason, ok := candidate["finishReason"].(string); ok {
+				closeThinkingBlock()
+
 				// 如果有未关闭的文本块,先关闭它
 				if textBlockStarted {
 					stopEvent := map[string]interface{}{
@@ -467,7 +584,8 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 			}
 		}
 
-		// 确保流结束时关闭任何未关闭的文本块
+		// 确保流结束时关闭任何未关闭的 thinking/文本块
+		closeThinkingBlock()
 		if textBlockStarted {
 			stopEvent := map[string]interface{}{
 				"type":  "content_block_stop",
@@ -484,3 +602,12 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 
 	return eventChan, errChan, nil
 }
+
+// geminiPartSignature 读取 part 层级的 thoughtSignature（兼容 snake_case）
+func geminiPartSignature(part map[string]interface{}) string {
+	if signature, _ := part["thoughtSignature"].(string); signature != "" {
+		return signature
+	}
+	signature, _ := part["thought_signature"].(string)
+	return signature
+}
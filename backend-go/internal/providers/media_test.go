@@ -0,0 +1,110 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+func parseClaudeMessage(t *testing.T, raw string) types.ClaudeMessage {
+	t.Helper()
+	var msg types.ClaudeMessage
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatalf("解析消息失败: %v", err)
+	}
+	return msg
+}
+
+func TestOpenAIProvider_ConvertMessage_Media(t *testing.T) {
+	p := &OpenAIProvider{}
+
+	msg := parseClaudeMessage(t, `{"role":"user","content":[
+		{"type":"text","text":"what is this?"},
+		{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}},
+		{"type":"document","source":{"type":"url","url":"https://example.com/a.pdf"}}
+	]}`)
+	messages := p.convertMessage(msg)
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	parts, ok := messages[0].Content.([]map[string]interface{})
+	if !ok || len(parts) != 3 {
+		t.Fatalf("expected 3 content parts, got %#v", messages[0].Content)
+	}
+	if parts[0]["type"] != "text" || parts[1]["type"] != "image_url" || parts[2]["type"] != "file" {
+		t.Fatalf("unexpected part order: %#v", parts)
+	}
+
+	// tool_result 中的图片以 user 消息补充
+	msg = parseClaudeMessage(t, `{"role":"user","content":[
+		{"type":"tool_result","tool_use_id":"toolu_1","content":[
+			{"type":"text","text":"done"},
+			{"type":"image","source":{"type":"url","url":"https://example.com/s.png"}}
+		]}
+	]}`)
+	messages = p.convertMessage(msg)
+	if len(messages) != 2 {
+		t.Fatalf("expected tool + user messages, got %d", len(messages))
+	}
+	if messages[0].Role != "tool" || messages[0].Content != "done" || messages[0].ToolCallID != "toolu_1" {
+		t.Fatalf("unexpected tool message: %#v", messages[0])
+	}
+	if parts, ok := messages[1].Content.([]map[string]interface{}); !ok || messages[1].Role != "user" || len(parts) != 1 {
+		t.Fatalf("unexpected media message: %#v", messages[1])
+	}
+
+	// 纯文本仍使用字符串 content
+	msg = parseClaudeMessage(t, `{"role":"user","content":[{"type":"text","text":"hi"}]}`)
+	if messages = p.convertMessage(msg); messages[0].Content != "hi" {
+		t.Fatalf("expected string content, got %#v", messages[0].Content)
+	}
+}
+
+func TestGeminiProvider_ConvertMessage_Media(t *testing.T) {
+	p := &GeminiProvider{}
+
+	msg := parseClaudeMessage(t, `{"role":"user","content":[
+		{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}},
+		{"type":"tool_result","tool_use_id":"screenshot","content":[
+			{"type":"text","text":"done"},
+			{"type":"document","source":{"type":"base64","media_type":"application/pdf","data":"JVBERi0="}}
+		]}
+	]}`)
+	converted := p.convertMessage(msg)
+	data, _ := json.Marshal(converted)
+
+	var got struct {
+		Parts []map[string]interface{} `json:"parts"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("解析转换结果失败: %v", err)
+	}
+	if len(got.Parts) != 3 {
+		t.Fatalf("expected 3 parts, got %s", data)
+	}
+	if _, ok := got.Parts[0]["inlineData"]; !ok {
+		t.Fatalf("expected inlineData part, got %s", data)
+	}
+	fr, ok := got.Parts[1]["functionResponse"].(map[string]interface{})
+	if !ok || fr["response"].(map[string]interface{})["result"] != "done" {
+		t.Fatalf("unexpected functionResponse: %s", data)
+	}
+	if inline, ok := got.Parts[2]["inlineData"].(map[string]interface{}); !ok || inline["mimeType"] != "application/pdf" {
+		t.Fatalf("expected document inlineData after functionResponse, got %s", data)
+	}
+}
+
+func TestGeminiProvider_ConvertToClaudeResponse_InlineData(t *testing.T) {
+	p := &GeminiProvider{}
+	resp, err := p.ConvertToClaudeResponse(&types.ProviderResponse{Body: []byte(`{"candidates":[{"content":{"parts":[
+		{"text":"here"},
+		{"inlineData":{"mimeType":"image/png","data":"AAAA"}}
+	]},"finishReason":"STOP"}]}`)})
+	if err != nil {
+		t.Fatalf("ConvertToClaudeResponse() error = %v", err)
+	}
+	if len(resp.Content) != 2 || resp.Content[1].Type != "image" || resp.Content[1].Source == nil || resp.Content[1].Source.Data != "AAAA" {
+		t.Fatalf("unexpected content: %#v", resp.Content)
+	}
+}
@@ -12,6 +12,7 @@
 	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/converters"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
 	"github.com/gin-gonic/gin"
@@ -49,6 +50,9 @@ func (p *OpenAIProvider) ConvertToProviderRequest(c *gin.Context, upstream *conf
 		openaiReq.MaxCompletionTokens = 65535
 	}
 
+	// 扩展思考：thinking.budget_tokens → reasoning_effort
+	openaiReq.ReasoningEffort = converters.ClaudeThinkingToEffort(claudeReq.Thinking)
+
 	// 转换工具
 	if len(claudeReq.Tools) > 0 {
 		openaiReq.Tools = p.convertTools(claudeReq.Tools)
@@ -144,6 +148,10 @@ func (p *OpenAIProvider) convertMessage(msg types.ClaudeMessage) []types.OpenAIM
 	textContents := []string{}
 	toolCalls := []types.OpenAIToolCall{}
 	toolResults := []types.OpenAIMessage{}
+	// 含 image/document 块时 content 改用 parts 数组（保持块顺序）
+	parts := []map[string]interface{}{}
+	hasMedia := false
+	toolResultMedia := []map[string]interface{}{}
 
 	for _, c := range contents {
 		content, ok := c.(map[string]interface{})
@@ -157,6 +165,13 @@ func (p *OpenAIProvider) convertMessage(msg types.ClaudeMessage) []types.OpenAIM
 		case "text":
 			if text, ok := content["text"].(string); ok {
 				textContents = append(textContents, text)
+				parts = append(parts, map[string]interface{}{"type": "text", "text": text})
+			}
+
+		case "image", "document":
+			if part := converters.ClaudeMediaBlockToOpenAIPart(content); part != nil {
+				parts = append(parts, part)
+				hasMedia = true
 			}
 
 		case "tool_use":
@@ -181,6 +196,14 @@ func (p *OpenAIProvider) convertMessage(msg types.ClaudeMessage) []types.OpenAIM
 			var contentStr string
 			if str, ok := resultContent.(string); ok {
 				contentStr = str
+			} else if text, media, ok := converters.SplitClaudeToolResultContent(resultContent); ok {
+				// tool 消息只能携带文本，图片/文档随后以 user 消息补充
+				contentStr = text
+				for _, block := range media {
+					if part := converters.ClaudeMediaBlockToOpenAIPart(block); part != nil {
+						toolResultMedia = append(toolResultMedia, part)
+					}
+				}
 			} else {
 				contentJSON, _ := json.Marshal(resultContent)
 				contentStr = string(contentJSON)
@@ -196,16 +219,24 @@ func (p *OpenAIProvider) convertMessage(msg types.ClaudeMessage) []types.OpenAIM
 
 	// 添加工具结果
 	messages = append(messages, toolResults...)
+	if len(toolResultMedia) > 0 {
+		messages = append(messages, types.OpenAIMessage{
+			Role:    "user",
+			Content: toolResultMedia,
+		})
+	}
 
 	// 添加文本和工具调用
-	if len(textContents) > 0 || len(toolCalls) > 0 {
+	if len(textContents) > 0 || len(toolCalls) > 0 || hasMedia {
 		role := normalizeRole(msg.Role)
 		if role != "tool" {
 			openaiMsg := types.OpenAIMessage{
 				Role: role,
 			}
 
-			if len(textContents) > 0 {
+			if hasMedia {
+				openaiMsg.Content = parts
+			} else if len(textContents) > 0 {
 				openaiMsg.Content = strings.Join(textContents, "\n")
 			} else {
 				openaiMsg.Content = nil
@@ -311,6 +342,15 @@ func (p *OpenAIProvider) ConvertToClaudeResponse(providerResp *types.ProviderRes
 		choice := openaiResp.Choices[0]
 		msg := choice.Message
 
+		// 推理内容（reasoning_content）→ thinking 块，签名为合成值以便客户端原样回传
+		if msg.ReasoningContent != "" {
+			claudeResp.Content = append(claudeResp.Content, types.ClaudeContent{
+				Type:      "thinking",
+				Thinking:  msg.ReasoningContent,
+				Signature: converters.SyntheticThinkingSignature,
+			})
+		}
+
 		// 添加文本内容
 		if str, ok := msg.Content.(string); ok && str != "" {
 			claudeResp.Content = append(claudeResp.Content, types.ClaudeContent{
@@ -376,6 +416,27 @@ func (p *OpenAIProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 		textBlockStarted := false
 		textBlockIndex := 0
 
+		// thinking 块状态跟踪（推理内容占用当前块索引，关闭后正文/工具块顺延）
+		thinkingBlockStarted := false
+		closeThinkingBlock := func() {
+			if !thinkingBlockStarted {
+				return
+			}
+			eventChan <- signatureDeltaEvent(textBlockIndex, converters.SyntheticThinkingSignature)
+			eventChan <- contentBlockStopEvent(textBlockIndex)
+			thinkingBlockStarted = false
+			textBlockIndex++
+			toolUseBlockIndex++
+		}
+		closeTextBlock := func() {
+			if !textBlockStarted {
+				return
+			}
+			eventChan <- contentBlockStopEvent(textBlockIndex)
+			textBlockStarted = false
+			textBlockIndex++
+		}
+
 		for scanner.Scan() {
 			line := scanner.Text()
 			line = strings.TrimSpace(line)
@@ -416,8 +477,25 @@ func (p *OpenAIProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 				continue
 			}
 
+			// 处理推理内容（reasoning_content 或 reasoning 字段）
+			reasoning, _ := delta["reasoning_content"].(string)
+			if reasoning == "" {
+				reasoning, _ = delta["reasoning"].(string)
+			}
+			if reasoning != "" {
+				// 正文开始后又出现推理内容（部分模型交替输出），关闭文本块后另起 thinking 块
+				closeTextBlock()
+				if !thinkingBlockStarted {
+					eventChan <- thinkingStartEvent(textBlockIndex)
+					thinkingBlockStarted = true
+				}
+				eventChan <- thinkingDeltaEvent(textBlockIndex, reasoning)
+			}
+
 			// 处理文本内容
 			if content, ok := delta["content"].(string); ok && content != "" {
+				closeThinkingBlock()
+
 				// 如果是第一个文本块,发送 content_block_start
 				if !textBlockStarted {
 					startEvent := map[string]interface{}{
@@ -448,17 +526,10 @@ func (p *OpenAIProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 
 			// 处理工具调用
 			if toolCalls, ok := delta["tool_calls"].([]interface{}); ok {
+				closeThinkingBlock()
+
 				// 如果有文本块正在进行,先关闭它
-				if textBlockStarted {
-					stopEvent := map[string]interface{}{
-						"type":  "content_block_stop",
-						"index": textBlockIndex,
-					}
-					stopJSON, _ := json.Marshal(stopEvent)
-					eventChan <- fmt.Sprintf("event: content_block_stop\ndata: %s\n\n", stopJSON)
-					textBlockStarted = false
-					textBlockIndex++
-				}
+				closeTextBlock()
 
 				for _, tc := range toolCalls {
 					toolCall, ok := tc.(map[string]interface{})
@@ -508,6 +579,8 @@ func (p *OpenAIProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 
 			// 处理结束原因
 			if finishReason, ok := choice["finish_reason"].(string); ok {
+				closeThinkingBlock()
+
 				// 如果有未关闭的文本块,先关闭它
 				if textBlockStarted {
 					stopEvent := map[string]interface{}{
@@ -533,7 +606,8 @@ func (p *OpenAIProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 			}
 		}
 
-		// 确保流结束时关闭任何未关闭的文本块
+		// 确保流结束时关闭任何未关闭的 thinking/文本块
+		closeThinkingBlock()
 		if textBlockStarted {
 			stopEvent := map[string]interface{}{
 				"type":  "content_block_stop",
@@ -567,6 +641,58 @@ type ToolCallAccumulator struct {
 	Arguments string
 }
 
+// thinkingStartEvent 构造 thinking 块的 content_block_start 事件
+func thinkingStartEvent(index int) string {
+	event := map[string]interface{}{
+		"type":  "content_block_start",
+		"index": index,
+		"content_block": map[string]string{
+			"type":     "thinking",
+			"thinking": "",
+		},
+	}
+	eventJSON, _ := json.Marshal(event)
+	return fmt.Sprintf("event: content_block_start\ndata: %s\n\n", eventJSON)
+}
+
+// thinkingDeltaEvent 构造 thinking_delta 事件
+func thinkingDeltaEvent(index int, thinking string) string {
+	event := map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": index,
+		"delta": map[string]string{
+			"type":     "thinking_delta",
+			"thinking": thinking,
+		},
+	}
+	eventJSON, _ := json.Marshal(event)
+	return fmt.Sprintf("event: content_block_delta\ndata: %s\n\n", eventJSON)
+}
+
+// signatureDeltaEvent 构造 thinking 块的 signature_delta 事件
+func signatureDeltaEvent(index int, signature string) string {
+	event := map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": index,
+		"delta": map[string]string{
+			"type":      "signature_delta",
+			"signature": signature,
+		},
+	}
+	eventJSON, _ := json.Marshal(event)
+	return fmt.Sprintf("event: content_block_delta\ndata: %s\n\n", eventJSON)
+}
+
+// contentBlockStopEvent 构造 content_block_stop 事件
+func contentBlockStopEvent(index int) string {
+	event := map[string]interface{}{
+		"type":  "content_block_stop",
+		"index": index,
+	}
+	eventJSON, _ := json.Marshal(event)
+	return fmt.Sprintf("event: content_block_stop\ndata: %s\n\n", eventJSON)
+}
+
 // processToolUsePart 处理工具使用部分
 func processToolUsePart(id, name string, input interface{}, index int) []string {
 	events := []string{}
@@ -0,0 +1,212 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/converters"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+// collectStreamEvents 读取 HandleStreamResponse 输出的全部事件
+func collectStreamEvents(t *testing.T, events <-chan string) []map[string]interface{} {
+	t.Helper()
+	var out []map[string]interface{}
+	for event := range events {
+		idx := strings.Index(event, "data: ")
+		if idx < 0 {
+			continue
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(event[idx+len("data: "):])), &payload); err != nil {
+			t.Fatalf("解析事件失败: %v (%s)", err, event)
+		}
+		out = append(out, payload)
+	}
+	return out
+}
+
+// eventSummary 将事件序列化为 "类型:索引:增量类型" 便于断言
+func eventSummary(events []map[string]interface{}) []string {
+	var out []string
+	for _, e := range events {
+		s := e["type"].(string)
+		if idx, ok := e["index"].(float64); ok {
+			s += fmt.Sprintf(":%d", int(idx))
+		}
+		if block, ok := e["content_block"].(map[string]interface{}); ok {
+			s += ":" + block["type"].(string)
+		}
+		if delta, ok := e["delta"].(map[string]interface{}); ok {
+			if dt, ok := delta["type"].(string); ok {
+				s += ":" + dt
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestOpenAIProvider_HandleStreamResponse_ReasoningContent(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"choices":[{"delta":{"reasoning_content":"let me "}}]}`,
+		`data: {"choices":[{"delta":{"reasoning_content":"think"}}]}`,
+		`data: {"choices":[{"delta":{"content":"answer"}}]}`,
+		`data: {"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+		`data: [DONE]`,
+	}, "\n")
+
+	p := &OpenAIProvider{}
+	events, _, err := p.HandleStreamResponse(io.NopCloser(strings.NewReader(body)))
+	if err != nil {
+		t.Fatalf("HandleStreamResponse() error = %v", err)
+	}
+
+	got := strings.Join(eventSummary(collectStreamEvents(t, events)), ",")
+	want := strings.Join([]string{
+		"content_block_start:0:thinking",
+		"content_block_delta:0:thinking_delta",
+		"content_block_delta:0:thinking_delta",
+		"content_block_delta:0:signature_delta",
+		"content_block_stop:0",
+		"content_block_start:1:text",
+		"content_block_delta:1:text_delta",
+		"content_block_stop:1",
+	}, ",")
+	if got != want {
+		t.Fatalf("事件序列不符\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestOpenAIProvider_HandleStreamResponse_ReasoningAfterText(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"choices":[{"delta":{"content":"first"}}]}`,
+		`data: {"choices":[{"delta":{"reasoning_content":"rethink"}}]}`,
+		`data: {"choices":[{"delta":{"content":"second"}}]}`,
+		`data: {"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+		`data: [DONE]`,
+	}, "\n")
+
+	p := &OpenAIProvider{}
+	events, _, err := p.HandleStreamResponse(io.NopCloser(strings.NewReader(body)))
+	if err != nil {
+		t.Fatalf("HandleStreamResponse() error = %v", err)
+	}
+
+	got := strings.Join(eventSummary(collectStreamEvents(t, events)), ",")
+	want := strings.Join([]string{
+		"content_block_start:0:text",
+		"content_block_delta:0:text_delta",
+		"content_block_stop:0",
+		"content_block_start:1:thinking",
+		"content_block_delta:1:thinking_delta",
+		"content_block_delta:1:signature_delta",
+		"content_block_stop:1",
+		"content_block_start:2:text",
+		"content_block_delta:2:text_delta",
+		"content_block_stop:2",
+	}, ",")
+	if got != want {
+		t.Fatalf("事件序列不符\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestOpenAIProvider_ConvertToClaudeResponse_ReasoningContent(t *testing.T) {
+	p := &OpenAIProvider{}
+	resp, err := p.ConvertToClaudeResponse(&types.ProviderResponse{Body: []byte(`{"choices":[{"message":{"role":"assistant","reasoning_content":"hmm","content":"hi"},"finish_reason":"stop"}]}`)})
+	if err != nil {
+		t.Fatalf("ConvertToClaudeResponse() error = %v", err)
+	}
+	if len(resp.Content) != 2 || resp.Content[0].Type != "thinking" || resp.Content[0].Thinking != "hmm" {
+		t.Fatalf("unexpected content: %#v", resp.Content)
+	}
+	if resp.Content[0].Signature != converters.SyntheticThinkingSignature {
+		t.Fatalf("expected synthetic signature, got %q", resp.Content[0].Signature)
+	}
+}
+
+func TestGeminiProvider_ConvertToGeminiRequest_Thinking(t *testing.T) {
+	p := &GeminiProvider{}
+	claudeReq := &types.ClaudeRequest{
+		Model:    "gemini-2.5-pro",
+		Thinking: &types.ClaudeThinking{Type: "enabled", BudgetTokens: 8000},
+		Messages: []types.ClaudeMessage{
+			{Role: "user", Content: "hi"},
+			{Role: "assistant", Content: []interface{}{
+				map[string]interface{}{"type": "thinking", "thinking": "...", "signature": "sig_gemini"},
+				map[string]interface{}{"type": "tool_use", "id": "toolu_0", "name": "ls", "input": map[string]interface{}{}},
+			}},
+		},
+	}
+
+	data, _ := json.Marshal(p.convertToGeminiRequest(claudeReq, nil))
+	var got struct {
+		Contents []struct {
+			Parts []map[string]interface{} `json:"parts"`
+		} `json:"contents"`
+		GenerationConfig struct {
+			ThinkingConfig map[string]interface{} `json:"thinkingConfig"`
+		} `json:"generationConfig"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("解析转换结果失败: %v", err)
+	}
+	if got.GenerationConfig.ThinkingConfig["thinkingBudget"] != float64(8000) || got.GenerationConfig.ThinkingConfig["includeThoughts"] != true {
+		t.Fatalf("unexpected thinkingConfig: %s", data)
+	}
+	modelParts := got.Contents[1].Parts
+	if len(modelParts) != 1 || modelParts[0]["thoughtSignature"] != "sig_gemini" || modelParts[0]["functionCall"] == nil {
+		t.Fatalf("expected signature on functionCall part, got %s", data)
+	}
+}
+
+func TestGeminiProvider_ConvertToClaudeResponse_Thought(t *testing.T) {
+	p := &GeminiProvider{}
+	resp, err := p.ConvertToClaudeResponse(&types.ProviderResponse{Body: []byte(`{"candidates":[{"content":{"parts":[
+		{"text":"planning","thought":true},
+		{"functionCall":{"name":"ls","args":{}},"thoughtSignature":"sig_1"}
+	]},"finishReason":"STOP"}]}`)})
+	if err != nil {
+		t.Fatalf("ConvertToClaudeResponse() error = %v", err)
+	}
+	if len(resp.Content) != 2 || resp.Content[0].Type != "thinking" || resp.Content[1].Type != "tool_use" {
+		t.Fatalf("unexpected content: %#v", resp.Content)
+	}
+	if resp.Content[0].Thinking != "planning" || resp.Content[0].Signature != "sig_1" {
+		t.Fatalf("signature should be attached to preceding thinking block: %#v", resp.Content[0])
+	}
+}
+
+func TestGeminiProvider_HandleStreamResponse_Thought(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"candidates":[{"content":{"parts":[{"text":"planning","thought":true}]}}]}`,
+		`data: {"candidates":[{"content":{"parts":[{"text":"done","thoughtSignature":"sig_1"}]}}]}`,
+		`data: {"candidates":[{"content":{"parts":[]},"finishReason":"STOP"}]}`,
+	}, "\n")
+
+	p := &GeminiProvider{}
+	events, _, err := p.HandleStreamResponse(io.NopCloser(strings.NewReader(body)))
+	if err != nil {
+		t.Fatalf("HandleStreamResponse() error = %v", err)
+	}
+
+	collected := collectStreamEvents(t, events)
+	got := strings.Join(eventSummary(collected), ",")
+	want := strings.Join([]string{
+		"content_block_start:0:thinking",
+		"content_block_delta:0:thinking_delta",
+		"content_block_delta:0:signature_delta",
+		"content_block_stop:0",
+		"content_block_start:1:text",
+		"content_block_delta:1:text_delta",
+	}, ",")
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("事件序列不符\n got: %s\nwant prefix: %s", got, want)
+	}
+	if sig := collected[2]["delta"].(map[string]interface{})["signature"]; sig != "sig_1" {
+		t.Fatalf("signature = %v, want sig_1", sig)
+	}
+}
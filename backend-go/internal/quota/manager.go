@@ -0,0 +1,356 @@
+// Package quota 提供多租户客户端的配额校验（Token 预算 + RPM）与用量结算
+package quota
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// LimitKind 超限的配额类型
+type LimitKind string
+
+const (
+	LimitRPM           LimitKind = "rpm"
+	LimitDailyTokens   LimitKind = "daily_tokens"
+	LimitMonthlyTokens LimitKind = "monthly_tokens"
+)
+
+const (
+	dayLayout     = "2006-01-02"
+	flushInterval = 10 * time.Second
+	rpmWindow     = time.Minute
+	contextKey    = "quotaBinding"
+)
+
+// ExceededError 配额超限错误
+type ExceededError struct {
+	ClientName string
+	Limit      LimitKind
+	Max        int64
+	Used       int64
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	switch e.Limit {
+	case LimitRPM:
+		return fmt.Sprintf("client %q exceeded rate limit: %d requests per minute", e.ClientName, e.Max)
+	case LimitDailyTokens:
+		return fmt.Sprintf("client %q exceeded daily token budget: %d/%d tokens used", e.ClientName, e.Used, e.Max)
+	default:
+		return fmt.Sprintf("client %q exceeded monthly token budget: %d/%d tokens used", e.ClientName, e.Used, e.Max)
+	}
+}
+
+// clientUsage 单个客户端的当前周期用量
+type clientUsage struct {
+	day          string
+	dayTokens    int64
+	dayRequests  int64
+	month        string
+	monthTokens  int64
+	recentStarts []time.Time // RPM 滑动窗口内的请求时间
+}
+
+// UsageSnapshot 客户端用量快照（用于管理 API 展示）
+type UsageSnapshot struct {
+	DailyTokens   int64 `json:"dailyTokens"`
+	DailyRequests int64 `json:"dailyRequests"`
+	MonthlyTokens int64 `json:"monthlyTokens"`
+	CurrentRPM    int   `json:"currentRpm"`
+}
+
+// Manager 配额管理器
+// 请求转发前通过 Check 校验 RPM 与 Token 预算，请求结束后通过 Settle 按实际 usage 结算。
+// Token 在请求结束后才结算，并发请求可能使预算小幅超出上限。
+type Manager struct {
+	mu      sync.Mutex
+	usage   map[string]*clientUsage               // key: clientID
+	pending map[string]*metrics.ClientUsageRecord // key: clientID|day，待写入持久化存储
+	store   metrics.ClientUsageStore              // 可选
+	now     func() time.Time
+
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewManager 创建配额管理器；store 为 nil 时仅在内存中统计（重启后清零）
+func NewManager(store metrics.ClientUsageStore) *Manager {
+	m := &Manager{
+		usage:   make(map[string]*clientUsage),
+		pending: make(map[string]*metrics.ClientUsageRecord),
+		store:   store,
+		now:     time.Now,
+		stopCh:  make(chan struct{}),
+	}
+
+	if store != nil {
+		if err := m.loadFromStore(); err != nil {
+			log.Printf("[Quota-Load] 警告: 加载客户端用量失败: %v", err)
+		}
+		m.wg.Add(1)
+		go m.flushLoop()
+	}
+	return m
+}
+
+// loadFromStore 从持久化存储恢复本月用量
+func (m *Manager) loadFromStore() error {
+	now := m.now()
+	today := now.Format(dayLayout)
+	month := today[:7]
+
+	records, err := m.store.LoadClientUsage(month + "-01")
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range records {
+		u := m.getOrCreateLocked(r.ClientID, today, month)
+		tokens := r.InputTokens + r.OutputTokens
+		if r.Day[:7] == month {
+			u.monthTokens += tokens
+		}
+		if r.Day == today {
+			u.dayTokens += tokens
+			u.dayRequests += r.Requests
+		}
+	}
+	if len(records) > 0 {
+		log.Printf("[Quota-Load] 已恢复 %d 条客户端用量记录", len(records))
+	}
+	return nil
+}
+
+// getOrCreateLocked 获取客户端用量并按自然日/月滚动（调用前需持有锁）
+func (m *Manager) getOrCreateLocked(clientID, today, month string) *clientUsage {
+	u, ok := m.usage[clientID]
+	if !ok {
+		u = &clientUsage{day: today, month: month}
+		m.usage[clientID] = u
+	}
+	if u.day != today {
+		u.day = today
+		u.dayTokens = 0
+		u.dayRequests = 0
+	}
+	if u.month != month {
+		u.month = month
+		u.monthTokens = 0
+	}
+	return u
+}
+
+// Check 校验客户端配额；通过时计入一次请求（RPM 与请求数）
+// 超限时返回 *ExceededError
+func (m *Manager) Check(client *config.ClientKey) error {
+	if client == nil {
+		return nil
+	}
+
+	now := m.now()
+	today := now.Format(dayLayout)
+	month := today[:7]
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u := m.getOrCreateLocked(client.ID, today, month)
+
+	if client.MonthlyTokenLimit > 0 && u.monthTokens >= client.MonthlyTokenLimit {
+		nextMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location())
+		return &ExceededError{ClientName: client.Name, Limit: LimitMonthlyTokens, Max: client.MonthlyTokenLimit, Used: u.monthTokens, RetryAfter: nextMonth.Sub(now)}
+	}
+	if client.DailyTokenLimit > 0 && u.dayTokens >= client.DailyTokenLimit {
+		tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		return &ExceededError{ClientName: client.Name, Limit: LimitDailyTokens, Max: client.DailyTokenLimit, Used: u.dayTokens, RetryAfter: tomorrow.Sub(now)}
+	}
+
+	// RPM 滑动窗口
+	cutoff := now.Add(-rpmWindow)
+	kept := u.recentStarts[:0]
+	for _, t := range u.recentStarts {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	u.recentStarts = kept
+	if client.RPMLimit > 0 && len(u.recentStarts) >= client.RPMLimit {
+		retryAfter := u.recentStarts[0].Add(rpmWindow).Sub(now)
+		return &ExceededError{ClientName: client.Name, Limit: LimitRPM, Max: int64(client.RPMLimit), Used: int64(len(u.recentStarts)), RetryAfter: retryAfter}
+	}
+
+	u.recentStarts = append(u.recentStarts, now)
+	u.dayRequests++
+	m.addPendingLocked(client.ID, today, 1, 0, 0)
+	return nil
+}
+
+// Settle 按实际 usage 结算客户端 Token 用量
+// 输入 Token 包含缓存读写，与上游计费口径保持一致
+func (m *Manager) Settle(clientID string, usage *types.Usage) {
+	if clientID == "" || usage == nil {
+		return
+	}
+
+	input := int64(usage.InputTokens + usage.CacheReadInputTokens)
+	cacheCreation := int64(usage.CacheCreationInputTokens)
+	if cacheCreation <= 0 {
+		cacheCreation = int64(usage.CacheCreation5mInputTokens + usage.CacheCreation1hInputTokens)
+	}
+	input += cacheCreation
+	output := int64(usage.OutputTokens)
+	if input+output <= 0 {
+		return
+	}
+
+	now := m.now()
+	today := now.Format(dayLayout)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u := m.getOrCreateLocked(clientID, today, today[:7])
+	u.dayTokens += input + output
+	u.monthTokens += input + output
+	m.addPendingLocked(clientID, today, 0, input, output)
+}
+
+// addPendingLocked 累加待持久化的用量（调用前需持有锁）
+func (m *Manager) addPendingLocked(clientID, day string, requests, input, output int64) {
+	if m.store == nil {
+		return
+	}
+	key := clientID + "|" + day
+	rec, ok := m.pending[key]
+	if !ok {
+		rec = &metrics.ClientUsageRecord{ClientID: clientID, Day: day}
+		m.pending[key] = rec
+	}
+	rec.Requests += requests
+	rec.InputTokens += input
+	rec.OutputTokens += output
+}
+
+// GetUsage 获取客户端当前用量快照
+func (m *Manager) GetUsage(clientID string) UsageSnapshot {
+	now := m.now()
+	today := now.Format(dayLayout)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.usage[clientID]
+	if !ok {
+		return UsageSnapshot{}
+	}
+	u = m.getOrCreateLocked(clientID, today, today[:7])
+
+	cutoff := now.Add(-rpmWindow)
+	rpm := 0
+	for _, t := range u.recentStarts {
+		if t.After(cutoff) {
+			rpm++
+		}
+	}
+	return UsageSnapshot{
+		DailyTokens:   u.dayTokens,
+		DailyRequests: u.dayRequests,
+		MonthlyTokens: u.monthTokens,
+		CurrentRPM:    rpm,
+	}
+}
+
+// flush 将待写入的用量刷入持久化存储
+func (m *Manager) flush() {
+	m.mu.Lock()
+	if len(m.pending) == 0 {
+		m.mu.Unlock()
+		return
+	}
+	records := make([]metrics.ClientUsageRecord, 0, len(m.pending))
+	for _, rec := range m.pending {
+		records = append(records, *rec)
+	}
+	m.pending = make(map[string]*metrics.ClientUsageRecord)
+	m.mu.Unlock()
+
+	if err := m.store.AddClientUsage(records); err != nil {
+		log.Printf("[Quota-Flush] 警告: 写入客户端用量失败: %v", err)
+		// 失败时合并回待写入队列，下次重试
+		m.mu.Lock()
+		for _, r := range records {
+			m.addPendingLocked(r.ClientID, r.Day, r.Requests, r.InputTokens, r.OutputTokens)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// flushLoop 定时刷新循环
+func (m *Manager) flushLoop() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.flush()
+		case <-m.stopCh:
+			m.flush()
+			return
+		}
+	}
+}
+
+// Stop 停止后台刷新（退出前写入剩余用量）；需在关闭持久化存储之前调用
+func (m *Manager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+		m.wg.Wait()
+	})
+}
+
+// ============== 请求上下文绑定 ==============
+
+// binding 绑定到单个请求的结算信息
+type binding struct {
+	manager  *Manager
+	clientID string
+	settled  bool
+}
+
+// Bind 将配额管理器与客户端绑定到请求上下文，供请求结束后结算
+func Bind(c *gin.Context, m *Manager, clientID string) {
+	if c == nil || m == nil || clientID == "" {
+		return
+	}
+	c.Set(contextKey, &binding{manager: m, clientID: clientID})
+}
+
+// SettleFromContext 使用请求上下文中绑定的配额管理器结算 usage（每个请求仅结算一次）
+func SettleFromContext(c *gin.Context, usage *types.Usage) {
+	if c == nil || usage == nil {
+		return
+	}
+	v, ok := c.Get(contextKey)
+	if !ok {
+		return
+	}
+	b, ok := v.(*binding)
+	if !ok || b.settled {
+		return
+	}
+	b.settled = true
+	b.manager.Settle(b.clientID, usage)
+}
@@ -0,0 +1,120 @@
+package quota
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+// memoryUsageStore 测试用内存用量存储
+type memoryUsageStore struct {
+	records []metrics.ClientUsageRecord
+}
+
+func (s *memoryUsageStore) AddClientUsage(records []metrics.ClientUsageRecord) error {
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *memoryUsageStore) LoadClientUsage(sinceDay string) ([]metrics.ClientUsageRecord, error) {
+	var out []metrics.ClientUsageRecord
+	for _, r := range s.records {
+		if r.Day >= sinceDay {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func newTestManager(now *time.Time) *Manager {
+	m := NewManager(nil)
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func assertLimit(t *testing.T, err error, want LimitKind) {
+	t.Helper()
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("err = %v, want ExceededError(%s)", err, want)
+	}
+	if exceeded.Limit != want {
+		t.Fatalf("limit = %s, want %s", exceeded.Limit, want)
+	}
+	if exceeded.RetryAfter <= 0 {
+		t.Fatalf("RetryAfter = %v, want > 0", exceeded.RetryAfter)
+	}
+}
+
+func TestManager_RPMSlidingWindow(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+	m := newTestManager(&now)
+	client := &config.ClientKey{ID: "c1", Name: "team-a", RPMLimit: 2}
+
+	for i := 0; i < 2; i++ {
+		if err := m.Check(client); err != nil {
+			t.Fatalf("第 %d 次请求不应被限流: %v", i+1, err)
+		}
+	}
+	assertLimit(t, m.Check(client), LimitRPM)
+
+	now = now.Add(61 * time.Second)
+	if err := m.Check(client); err != nil {
+		t.Fatalf("窗口滑出后应恢复: %v", err)
+	}
+}
+
+func TestManager_TokenBudgetsAndRollover(t *testing.T) {
+	now := time.Date(2025, 3, 31, 23, 0, 0, 0, time.Local)
+	m := newTestManager(&now)
+	client := &config.ClientKey{ID: "c1", Name: "team-a", DailyTokenLimit: 100, MonthlyTokenLimit: 150}
+
+	if err := m.Check(client); err != nil {
+		t.Fatalf("首次请求不应超限: %v", err)
+	}
+	m.Settle(client.ID, &types.Usage{InputTokens: 60, CacheReadInputTokens: 20, OutputTokens: 40})
+	assertLimit(t, m.Check(client), LimitDailyTokens)
+
+	if got := m.GetUsage(client.ID); got.DailyTokens != 120 || got.MonthlyTokens != 120 || got.DailyRequests != 1 {
+		t.Fatalf("usage = %+v, want daily=120 monthly=120 requests=1", got)
+	}
+
+	// 跨月：日/月用量均清零
+	now = now.Add(2 * time.Hour)
+	if err := m.Check(client); err != nil {
+		t.Fatalf("跨月后应恢复: %v", err)
+	}
+	m.Settle(client.ID, &types.Usage{InputTokens: 90})
+
+	// 跨日（同月）：日用量清零，月用量保留
+	now = now.Add(24 * time.Hour)
+	if err := m.Check(client); err != nil {
+		t.Fatalf("跨日后日预算应恢复: %v", err)
+	}
+	m.Settle(client.ID, &types.Usage{OutputTokens: 70})
+	assertLimit(t, m.Check(client), LimitMonthlyTokens)
+}
+
+func TestManager_PersistAndReload(t *testing.T) {
+	store := &memoryUsageStore{}
+	m := NewManager(store)
+	client := &config.ClientKey{ID: "c1", Name: "team-a"}
+
+	if err := m.Check(client); err != nil {
+		t.Fatalf("Check 失败: %v", err)
+	}
+	m.Settle(client.ID, &types.Usage{InputTokens: 10, CacheCreationInputTokens: 5, OutputTokens: 3})
+	m.Stop()
+
+	reloaded := NewManager(store)
+	defer reloaded.Stop()
+
+	got := reloaded.GetUsage(client.ID)
+	if got.DailyTokens != 18 || got.MonthlyTokens != 18 || got.DailyRequests != 1 {
+		t.Fatalf("reloaded usage = %+v, want daily=18 monthly=18 requests=1", got)
+	}
+}
@@ -0,0 +1,87 @@
+// Package requestlog 提供结构化的请求审计日志（SQLite 持久化 + 查询）
+// 每个代理请求记录一条：客户端、入口类型、模型、选中渠道与 Key、failover 尝试、状态、耗时、usage，
+// 以及可选的截断后请求/响应体。
+package requestlog
+
+import "time"
+
+// Attempt 单次上游尝试（渠道内 Key/BaseURL 轮转与跨渠道 failover 都会产生一条）
+type Attempt struct {
+	ChannelIndex int    `json:"channelIndex"`
+	Channel      string `json:"channel"`
+	BaseURL      string `json:"baseUrl"`
+	KeyMask      string `json:"keyMask"`
+	Model        string `json:"model,omitempty"`      // 重定向后的上游模型
+	StatusCode   int    `json:"statusCode,omitempty"` // 0 表示未收到上游响应
+	Error        string `json:"error,omitempty"`
+	DurationMs   int64  `json:"durationMs"`
+}
+
+// Entry 一条请求审计日志
+type Entry struct {
+	ID             string    `json:"id"`
+	Timestamp      time.Time `json:"timestamp"`
+	Kind           string    `json:"kind"` // messages/responses/gemini/chat
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	ClientID       string    `json:"clientId,omitempty"`
+	ClientName     string    `json:"clientName,omitempty"`
+	RequestedModel string    `json:"requestedModel,omitempty"`
+	Model          string    `json:"model,omitempty"` // 实际使用的上游模型（重定向后）
+	Stream         bool      `json:"stream"`
+
+	// 最终处理请求的渠道（最后一次尝试）
+	ChannelIndex int    `json:"channelIndex"`
+	Channel      string `json:"channel,omitempty"`
+	BaseURL      string `json:"baseUrl,omitempty"`
+	KeyMask      string `json:"keyMask,omitempty"`
+
+	AttemptCount  int       `json:"attemptCount"`
+	FailoverCount int       `json:"failoverCount"` // 换 Key/BaseURL/渠道的次数
+	Attempts      []Attempt `json:"attempts,omitempty"`
+
+	StatusCode int    `json:"statusCode"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+	LatencyMs  int64  `json:"latencyMs"`
+
+	InputTokens         int64   `json:"inputTokens"`
+	OutputTokens        int64   `json:"outputTokens"`
+	CacheCreationTokens int64   `json:"cacheCreationTokens"`
+	CacheReadTokens     int64   `json:"cacheReadTokens"`
+	Cost                float64 `json:"cost"`
+
+	RequestBody  string `json:"requestBody,omitempty"`
+	ResponseBody string `json:"responseBody,omitempty"`
+}
+
+// Filter 查询条件（零值字段不参与过滤）
+type Filter struct {
+	ClientID string
+	Kind     string
+	Model    string // 匹配请求模型或上游模型
+	Channel  string
+	Status   string // success / failure / 具体 HTTP 状态码
+	Since    time.Time
+	Until    time.Time
+	Query    string // 在错误信息与请求/响应体中模糊搜索
+	Limit    int
+	Offset   int
+}
+
+const (
+	defaultQueryLimit = 50
+	maxQueryLimit     = 500
+)
+
+// normalize 修正分页参数
+func (f *Filter) normalize() {
+	if f.Limit <= 0 {
+		f.Limit = defaultQueryLimit
+	} else if f.Limit > maxQueryLimit {
+		f.Limit = maxQueryLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+}
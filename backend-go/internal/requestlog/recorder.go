@@ -0,0 +1,284 @@
+package requestlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// contextKey gin.Context 中保存 Tracker 的键
+const contextKey = "requestLogTracker"
+
+// bodyTextMaxLength 请求/响应体中单个字符串字段的最大保留长度
+const bodyTextMaxLength = 1000
+
+// Options 请求日志采集选项
+type Options struct {
+	CaptureBodies bool // 是否记录请求/响应体
+	MaxBodyBytes  int  // 单个请求/响应体的最大保存字节数（截断后）
+}
+
+// Recorder 请求日志采集器
+// 通过 Middleware 挂载到代理入口，请求结束后将 Tracker 汇总为 Entry 写入存储。
+type Recorder struct {
+	store *SQLiteStore
+	opts  Options
+}
+
+// NewRecorder 创建请求日志采集器
+func NewRecorder(store *SQLiteStore, opts Options) *Recorder {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 64 * 1024
+	}
+	return &Recorder{store: store, opts: opts}
+}
+
+// Store 返回底层存储（用于查询 API）；Recorder 为 nil 时返回 nil
+func (r *Recorder) Store() *SQLiteStore {
+	if r == nil {
+		return nil
+	}
+	return r.store
+}
+
+// Middleware 为指定入口类型的代理请求采集审计日志
+// Recorder 为 nil（未启用请求日志）时直接放行。
+func (r *Recorder) Middleware(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if r == nil || r.store == nil {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		t := &Tracker{
+			captureBodies: r.opts.CaptureBodies,
+			channelIndex:  -1,
+		}
+		c.Set(contextKey, t)
+
+		var capture *captureWriter
+		if r.opts.CaptureBodies {
+			// 原始响应按 4 倍上限采集，JSON 截断长字段后通常可以压缩到上限以内
+			capture = &captureWriter{ResponseWriter: c.Writer, limit: r.opts.MaxBodyBytes * 4}
+			c.Writer = capture
+		}
+
+		c.Next()
+
+		entry := t.build(c, kind, start)
+		if r.opts.CaptureBodies {
+			entry.RequestBody = truncateBody(t.requestBody, r.opts.MaxBodyBytes)
+			entry.ResponseBody = truncateBody(capture.buf, r.opts.MaxBodyBytes)
+		}
+		r.store.Add(entry)
+	}
+}
+
+// Tracker 单个请求的日志采集状态（由 handler 与 failover 流程填充）
+// 所有方法均可在 nil 接收者上调用（未启用请求日志时为 no-op）。
+type Tracker struct {
+	mu            sync.Mutex
+	captureBodies bool
+	channelIndex  int // 当前尝试的渠道索引（单渠道模式为 -1）
+
+	requestBody    []byte
+	requestedModel string
+	stream         bool
+	attempts       []Attempt
+
+	model string
+	usage *types.Usage
+	cost  float64
+}
+
+// FromContext 获取当前请求的 Tracker；未启用请求日志时返回 nil
+func FromContext(c *gin.Context) *Tracker {
+	if c == nil {
+		return nil
+	}
+	if v, ok := c.Get(contextKey); ok {
+		if t, ok := v.(*Tracker); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// SetRequestBody 记录客户端原始请求体（仅在启用请求体采集时保留）
+func (t *Tracker) SetRequestBody(body []byte) {
+	if t == nil || !t.captureBodies {
+		return
+	}
+	t.mu.Lock()
+	t.requestBody = body
+	t.mu.Unlock()
+}
+
+// SetRequestedModel 记录客户端请求的模型
+func (t *Tracker) SetRequestedModel(model string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.requestedModel = model
+	t.mu.Unlock()
+}
+
+// SetStream 记录是否为流式请求
+func (t *Tracker) SetStream(stream bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.stream = stream
+	t.mu.Unlock()
+}
+
+// SetChannelIndex 记录当前尝试的渠道索引（后续 AddAttempt 使用）
+func (t *Tracker) SetChannelIndex(index int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.channelIndex = index
+	t.mu.Unlock()
+}
+
+// AddAttempt 记录一次上游尝试
+func (t *Tracker) AddAttempt(a Attempt) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	a.ChannelIndex = t.channelIndex
+	t.attempts = append(t.attempts, a)
+	t.mu.Unlock()
+}
+
+// SetResult 记录最终 usage、上游模型与费用
+func (t *Tracker) SetResult(model string, usage *types.Usage, cost float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.model = model
+	t.usage = usage
+	t.cost = cost
+	t.mu.Unlock()
+}
+
+// build 汇总为日志条目（请求体/响应体由调用方按选项填充）
+func (t *Tracker) build(c *gin.Context, kind string, start time.Time) Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry := Entry{
+		ID:             uuid.NewString(),
+		Timestamp:      start,
+		Kind:           kind,
+		Method:         c.Request.Method,
+		Path:           c.Request.URL.Path,
+		RequestedModel: t.requestedModel,
+		Model:          t.model,
+		Stream:         t.stream,
+		ChannelIndex:   -1,
+		AttemptCount:   len(t.attempts),
+		Attempts:       t.attempts,
+		StatusCode:     c.Writer.Status(),
+		Cost:           t.cost,
+		LatencyMs:      time.Since(start).Milliseconds(),
+	}
+
+	if client := middleware.GetClient(c); client != nil {
+		entry.ClientID = client.ID
+		entry.ClientName = client.Name
+	}
+
+	var lastErr string
+	if n := len(t.attempts); n > 0 {
+		last := t.attempts[n-1]
+		entry.ChannelIndex = last.ChannelIndex
+		entry.Channel = last.Channel
+		entry.BaseURL = last.BaseURL
+		entry.KeyMask = last.KeyMask
+		entry.FailoverCount = n - 1
+		if entry.Model == "" {
+			entry.Model = last.Model
+		}
+		lastErr = last.Error
+	}
+	if entry.Model == "" {
+		entry.Model = entry.RequestedModel
+	}
+
+	entry.Success = entry.StatusCode < http.StatusBadRequest && lastErr == ""
+	if !entry.Success {
+		entry.Error = lastErr
+		if entry.Error == "" {
+			entry.Error = http.StatusText(entry.StatusCode)
+		}
+	}
+
+	if t.usage != nil {
+		entry.InputTokens = int64(t.usage.InputTokens)
+		entry.OutputTokens = int64(t.usage.OutputTokens)
+		entry.CacheCreationTokens = int64(t.usage.CacheCreationInputTokens)
+		entry.CacheReadTokens = int64(t.usage.CacheReadInputTokens)
+	}
+	return entry
+}
+
+// captureWriter 在写回客户端的同时采集响应体（超过上限的部分丢弃）
+type captureWriter struct {
+	gin.ResponseWriter
+	buf   []byte
+	limit int
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *captureWriter) capture(data []byte) {
+	if remaining := w.limit - len(w.buf); remaining > 0 {
+		if len(data) > remaining {
+			data = data[:remaining]
+		}
+		w.buf = append(w.buf, data...)
+	}
+}
+
+// truncateBody 截断请求/响应体：JSON 按字段智能截断长文本，其余（如 SSE）按字节截断
+func truncateBody(body []byte, maxBytes int) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	text := string(body)
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err == nil {
+		if truncated, err := utils.MarshalJSONNoEscape(utils.TruncateJSONIntelligently(data, bodyTextMaxLength)); err == nil {
+			text = string(truncated)
+		}
+	}
+
+	if len(text) > maxBytes {
+		text = strings.ToValidUTF8(text[:maxBytes], "") + "...(truncated)"
+	}
+	return text
+}
@@ -0,0 +1,90 @@
+package requestlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecorderMiddleware_BuildsEntry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newTestStore(t)
+	recorder := NewRecorder(store, Options{CaptureBodies: true, MaxBodyBytes: 4096})
+
+	longText := strings.Repeat("x", 5000)
+	r := gin.New()
+	r.POST("/v1/messages", recorder.Middleware("messages"), func(c *gin.Context) {
+		c.Set(middleware.ClientContextKey, &config.ClientKey{ID: "c1", Name: "team-a"})
+		t := FromContext(c)
+		t.SetRequestBody([]byte(`{"model":"claude-sonnet-4","messages":[{"role":"user","content":"` + longText + `"}]}`))
+		t.SetRequestedModel("claude-sonnet-4")
+		t.SetStream(true)
+
+		t.SetChannelIndex(1)
+		t.AddAttempt(Attempt{Channel: "backup", KeyMask: "sk-***a", StatusCode: 503, Error: "上游错误: 503"})
+		t.SetChannelIndex(0)
+		t.AddAttempt(Attempt{Channel: "primary", KeyMask: "sk-***b", Model: "claude-sonnet-4-20250514", StatusCode: 200})
+		t.SetResult("claude-sonnet-4-20250514", &types.Usage{InputTokens: 12, OutputTokens: 3}, 0.01)
+
+		c.String(http.StatusOK, "event: message_stop\n\n")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	items, _, err := store.List(Filter{})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("List() = %+v, %v", items, err)
+	}
+	entry, err := store.Get(items[0].ID)
+	if err != nil || entry == nil {
+		t.Fatalf("Get() = %v, %v", entry, err)
+	}
+
+	if entry.Kind != "messages" || entry.ClientID != "c1" || entry.ClientName != "team-a" || !entry.Stream {
+		t.Fatalf("unexpected request info: %+v", entry)
+	}
+	if !entry.Success || entry.StatusCode != 200 || entry.Error != "" {
+		t.Fatalf("expected success, got status=%d error=%q", entry.StatusCode, entry.Error)
+	}
+	if entry.Channel != "primary" || entry.ChannelIndex != 0 || entry.KeyMask != "sk-***b" {
+		t.Fatalf("expected final channel primary, got %+v", entry)
+	}
+	if entry.AttemptCount != 2 || entry.FailoverCount != 1 || entry.Attempts[0].ChannelIndex != 1 {
+		t.Fatalf("unexpected attempts: %+v", entry.Attempts)
+	}
+	if entry.Model != "claude-sonnet-4-20250514" || entry.InputTokens != 12 || entry.Cost != 0.01 {
+		t.Fatalf("unexpected result: %+v", entry)
+	}
+	if strings.Contains(entry.RequestBody, longText) || !strings.Contains(entry.RequestBody, "claude-sonnet-4") {
+		t.Fatalf("请求体应按字段截断长文本: %d bytes", len(entry.RequestBody))
+	}
+	if entry.ResponseBody != "event: message_stop\n\n" {
+		t.Fatalf("unexpected response body: %q", entry.ResponseBody)
+	}
+}
+
+func TestRecorderMiddleware_NilRecorderPassesThrough(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var recorder *Recorder
+
+	r := gin.New()
+	r.POST("/v1/chat/completions", recorder.Middleware("chat"), func(c *gin.Context) {
+		// 未启用时 Tracker 为 nil，方法调用为 no-op
+		FromContext(c).AddAttempt(Attempt{Channel: "x"})
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
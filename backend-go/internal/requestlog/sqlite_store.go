@@ -0,0 +1,460 @@
+package requestlog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore 请求日志 SQLite 存储
+// 写入先进入内存缓冲区，由后台循环批量落盘，避免阻塞请求路径。
+type SQLiteStore struct {
+	db     *sql.DB
+	dbPath string
+
+	// 写入缓冲区
+	writeBuffer []Entry
+	bufferMu    sync.Mutex
+	closed      bool
+
+	retentionDays int
+
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	flushMu sync.Mutex // 串行化 flush 与删除操作
+}
+
+// SQLiteStoreConfig 请求日志存储配置
+type SQLiteStoreConfig struct {
+	DBPath        string // 数据库文件路径
+	RetentionDays int    // 数据保留天数（1-90）
+}
+
+const (
+	flushBatchSize     = 100 // 缓冲区达到该数量时立即刷新
+	maxBufferedEntries = flushBatchSize * 50
+	flushInterval      = 2 * time.Second // 定时刷新间隔
+)
+
+// NewSQLiteStore 创建请求日志存储
+func NewSQLiteStore(cfg *SQLiteStoreConfig) (*SQLiteStore, error) {
+	if cfg == nil {
+		cfg = &SQLiteStoreConfig{
+			DBPath:        ".config/requests.db",
+			RetentionDays: 7,
+		}
+	}
+	if cfg.RetentionDays < 1 {
+		cfg.RetentionDays = 1
+	} else if cfg.RetentionDays > 90 {
+		cfg.RetentionDays = 90
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.DBPath), 0755); err != nil {
+		return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+	}
+
+	dsn := cfg.DBPath + "?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+
+	if err := initSchema(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化数据库 schema 失败: %w", err)
+	}
+
+	s := &SQLiteStore{
+		db:            db,
+		dbPath:        cfg.DBPath,
+		writeBuffer:   make([]Entry, 0, flushBatchSize),
+		retentionDays: cfg.RetentionDays,
+		stopCh:        make(chan struct{}),
+	}
+
+	s.wg.Add(2)
+	go s.flushLoop()
+	go s.cleanupLoop()
+
+	log.Printf("[RequestLog-Init] 请求日志存储已初始化: %s (保留 %d 天)", cfg.DBPath, cfg.RetentionDays)
+	return s, nil
+}
+
+// initSchema 初始化表结构
+func initSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS request_logs (
+			id TEXT PRIMARY KEY,
+			timestamp INTEGER NOT NULL,
+			kind TEXT NOT NULL DEFAULT '',
+			method TEXT NOT NULL DEFAULT '',
+			path TEXT NOT NULL DEFAULT '',
+			client_id TEXT NOT NULL DEFAULT '',
+			client_name TEXT NOT NULL DEFAULT '',
+			requested_model TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			stream INTEGER NOT NULL DEFAULT 0,
+			channel_index INTEGER NOT NULL DEFAULT -1,
+			channel TEXT NOT NULL DEFAULT '',
+			base_url TEXT NOT NULL DEFAULT '',
+			key_mask TEXT NOT NULL DEFAULT '',
+			attempt_count INTEGER NOT NULL DEFAULT 0,
+			failover_count INTEGER NOT NULL DEFAULT 0,
+			attempts TEXT NOT NULL DEFAULT '',
+			status_code INTEGER NOT NULL DEFAULT 0,
+			success INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			latency_ms INTEGER NOT NULL DEFAULT 0,
+			input_tokens INTEGER NOT NULL DEFAULT 0,
+			output_tokens INTEGER NOT NULL DEFAULT 0,
+			cache_creation_tokens INTEGER NOT NULL DEFAULT 0,
+			cache_read_tokens INTEGER NOT NULL DEFAULT 0,
+			cost REAL NOT NULL DEFAULT 0,
+			request_body TEXT NOT NULL DEFAULT '',
+			response_body TEXT NOT NULL DEFAULT ''
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_request_logs_timestamp
+			ON request_logs(timestamp);
+
+		CREATE INDEX IF NOT EXISTS idx_request_logs_client_timestamp
+			ON request_logs(client_id, timestamp);
+	`)
+	return err
+}
+
+// Add 添加日志到写入缓冲区（非阻塞）
+func (s *SQLiteStore) Add(entry Entry) {
+	s.bufferMu.Lock()
+	if s.closed {
+		s.bufferMu.Unlock()
+		return
+	}
+	if len(s.writeBuffer) >= maxBufferedEntries {
+		s.bufferMu.Unlock()
+		log.Printf("[RequestLog-Buffer] 警告: 写入缓冲区已满，丢弃请求日志 %s", entry.ID)
+		return
+	}
+	s.writeBuffer = append(s.writeBuffer, entry)
+	shouldFlush := len(s.writeBuffer) >= flushBatchSize
+	if shouldFlush {
+		// 持锁计数，保证 Close 等待到该次异步刷新
+		s.wg.Add(1)
+	}
+	s.bufferMu.Unlock()
+
+	if shouldFlush {
+		go func() {
+			defer s.wg.Done()
+			s.Flush()
+		}()
+	}
+}
+
+// Flush 将缓冲区写入数据库
+func (s *SQLiteStore) Flush() {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.bufferMu.Lock()
+	if len(s.writeBuffer) == 0 {
+		s.bufferMu.Unlock()
+		return
+	}
+	entries := s.writeBuffer
+	s.writeBuffer = make([]Entry, 0, flushBatchSize)
+	s.bufferMu.Unlock()
+
+	if err := s.insertEntries(entries); err != nil {
+		log.Printf("[RequestLog-Flush] 警告: 写入 %d 条请求日志失败: %v", len(entries), err)
+		s.bufferMu.Lock()
+		if len(s.writeBuffer)+len(entries) <= maxBufferedEntries {
+			s.writeBuffer = append(entries, s.writeBuffer...)
+		}
+		s.bufferMu.Unlock()
+	}
+}
+
+// insertEntries 批量插入
+func (s *SQLiteStore) insertEntries(entries []Entry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO request_logs
+		(id, timestamp, kind, method, path, client_id, client_name, requested_model, model, stream,
+		 channel_index, channel, base_url, key_mask, attempt_count, failover_count, attempts,
+		 status_code, success, error, latency_ms,
+		 input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, cost,
+		 request_body, response_body)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		attempts := ""
+		if len(e.Attempts) > 0 {
+			data, err := json.Marshal(e.Attempts)
+			if err != nil {
+				return err
+			}
+			attempts = string(data)
+		}
+		_, err := stmt.Exec(
+			e.ID, e.Timestamp.UnixMilli(), e.Kind, e.Method, e.Path, e.ClientID, e.ClientName,
+			e.RequestedModel, e.Model, boolToInt(e.Stream),
+			e.ChannelIndex, e.Channel, e.BaseURL, e.KeyMask, e.AttemptCount, e.FailoverCount, attempts,
+			e.StatusCode, boolToInt(e.Success), e.Error, e.LatencyMs,
+			e.InputTokens, e.OutputTokens, e.CacheCreationTokens, e.CacheReadTokens, e.Cost,
+			e.RequestBody, e.ResponseBody,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// summaryColumns 列表查询字段（不含请求/响应体与尝试明细）
+const summaryColumns = `id, timestamp, kind, method, path, client_id, client_name, requested_model, model, stream,
+	channel_index, channel, base_url, key_mask, attempt_count, failover_count,
+	status_code, success, error, latency_ms,
+	input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens, cost`
+
+// List 按条件分页查询（按时间倒序），返回当前页与总数
+func (s *SQLiteStore) List(filter Filter) ([]Entry, int, error) {
+	filter.normalize()
+	// 先刷新缓冲区，保证刚结束的请求可查
+	s.Flush()
+
+	where, args := buildWhere(filter)
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM request_logs"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT " + summaryColumns + " FROM request_logs" + where + " ORDER BY timestamp DESC, id DESC LIMIT ? OFFSET ?"
+	rows, err := s.db.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := make([]Entry, 0)
+	for rows.Next() {
+		var e Entry
+		if err := scanSummary(rows, &e); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+// Get 查询单条日志详情；不存在时返回 nil
+func (s *SQLiteStore) Get(id string) (*Entry, error) {
+	s.Flush()
+
+	row := s.db.QueryRow("SELECT "+summaryColumns+", attempts, request_body, response_body FROM request_logs WHERE id = ?", id)
+	var e Entry
+	var attempts string
+	err := scanSummary(row, &e, &attempts, &e.RequestBody, &e.ResponseBody)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if attempts != "" {
+		if err := json.Unmarshal([]byte(attempts), &e.Attempts); err != nil {
+			return nil, fmt.Errorf("解析尝试记录失败: %w", err)
+		}
+	}
+	return &e, nil
+}
+
+// DeleteBefore 删除指定时间之前的日志，返回删除条数
+func (s *SQLiteStore) DeleteBefore(before time.Time) (int64, error) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	result, err := s.db.Exec("DELETE FROM request_logs WHERE timestamp < ?", before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RetentionDays 返回数据保留天数
+func (s *SQLiteStore) RetentionDays() int {
+	return s.retentionDays
+}
+
+// buildWhere 根据过滤条件构建 WHERE 子句
+func buildWhere(f Filter) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if f.ClientID != "" {
+		conds = append(conds, "client_id = ?")
+		args = append(args, f.ClientID)
+	}
+	if f.Kind != "" {
+		conds = append(conds, "kind = ?")
+		args = append(args, f.Kind)
+	}
+	if f.Model != "" {
+		conds = append(conds, "(requested_model = ? OR model = ?)")
+		args = append(args, f.Model, f.Model)
+	}
+	if f.Channel != "" {
+		conds = append(conds, "channel = ?")
+		args = append(args, f.Channel)
+	}
+	switch f.Status {
+	case "":
+	case "success":
+		conds = append(conds, "success = 1")
+	case "failure", "error":
+		conds = append(conds, "success = 0")
+	default:
+		if code, err := strconv.Atoi(f.Status); err == nil {
+			conds = append(conds, "status_code = ?")
+			args = append(args, code)
+		}
+	}
+	if !f.Since.IsZero() {
+		conds = append(conds, "timestamp >= ?")
+		args = append(args, f.Since.UnixMilli())
+	}
+	if !f.Until.IsZero() {
+		conds = append(conds, "timestamp < ?")
+		args = append(args, f.Until.UnixMilli())
+	}
+	if f.Query != "" {
+		like := "%" + escapeLike(f.Query) + "%"
+		conds = append(conds, `(error LIKE ? ESCAPE '\' OR request_body LIKE ? ESCAPE '\' OR response_body LIKE ? ESCAPE '\')`)
+		args = append(args, like, like, like)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSummary 扫描 summaryColumns，extra 为追加在其后的列
+func scanSummary(row rowScanner, e *Entry, extra ...interface{}) error {
+	var ts int64
+	var stream, success int
+	dest := []interface{}{
+		&e.ID, &ts, &e.Kind, &e.Method, &e.Path, &e.ClientID, &e.ClientName, &e.RequestedModel, &e.Model, &stream,
+		&e.ChannelIndex, &e.Channel, &e.BaseURL, &e.KeyMask, &e.AttemptCount, &e.FailoverCount,
+		&e.StatusCode, &success, &e.Error, &e.LatencyMs,
+		&e.InputTokens, &e.OutputTokens, &e.CacheCreationTokens, &e.CacheReadTokens, &e.Cost,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	e.Timestamp = time.UnixMilli(ts)
+	e.Stream = stream == 1
+	e.Success = success == 1
+	return nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// flushLoop 定时刷新循环
+func (s *SQLiteStore) flushLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.stopCh:
+			s.Flush()
+			return
+		}
+	}
+}
+
+// cleanupLoop 定期清理过期日志
+func (s *SQLiteStore) cleanupLoop() {
+	defer s.wg.Done()
+
+	s.doCleanup()
+
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.doCleanup()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// doCleanup 执行清理
+func (s *SQLiteStore) doCleanup() {
+	deleted, err := s.DeleteBefore(time.Now().AddDate(0, 0, -s.retentionDays))
+	if err != nil {
+		log.Printf("[RequestLog-Cleanup] 警告: 清理过期请求日志失败: %v", err)
+	} else if deleted > 0 {
+		log.Printf("[RequestLog-Cleanup] 已清理 %d 条过期请求日志（超过 %d 天）", deleted, s.retentionDays)
+	}
+}
+
+// Close 关闭存储（退出前写入剩余日志）
+func (s *SQLiteStore) Close() error {
+	s.bufferMu.Lock()
+	if s.closed {
+		s.bufferMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.bufferMu.Unlock()
+
+	close(s.stopCh)
+	s.wg.Wait()
+	return s.db.Close()
+}
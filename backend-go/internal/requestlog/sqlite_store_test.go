@@ -0,0 +1,90 @@
+package requestlog
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(&SQLiteStoreConfig{
+		DBPath:        filepath.Join(t.TempDir(), "requests.db"),
+		RetentionDays: 7,
+	})
+	if err != nil {
+		t.Fatalf("NewSQLiteStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteStore_ListFilterAndGet(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Now().Add(-time.Hour)
+
+	store.Add(Entry{
+		ID: "a", Timestamp: base, Kind: "messages", ClientID: "c1",
+		RequestedModel: "claude-sonnet-4", Model: "claude-sonnet-4-20250514",
+		Channel: "primary", StatusCode: 200, Success: true, InputTokens: 10,
+		Attempts: []Attempt{
+			{Channel: "backup", StatusCode: 500, Error: "上游错误: 500"},
+			{Channel: "primary", StatusCode: 200},
+		},
+		AttemptCount: 2, FailoverCount: 1,
+		RequestBody: `{"model":"claude-sonnet-4"}`,
+	})
+	store.Add(Entry{
+		ID: "b", Timestamp: base.Add(time.Minute), Kind: "chat", ClientID: "c2",
+		RequestedModel: "gpt-4o", Channel: "openai", StatusCode: 429, Error: "rate limited",
+	})
+
+	items, total, err := store.List(Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if total != 2 || len(items) != 2 || items[0].ID != "b" {
+		t.Fatalf("expected newest first with 2 items, got total=%d items=%+v", total, items)
+	}
+	if items[1].RequestBody != "" || items[1].Attempts != nil {
+		t.Fatalf("列表查询不应返回请求体与尝试明细: %+v", items[1])
+	}
+
+	cases := []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{"client", Filter{ClientID: "c1"}, "a"},
+		{"upstream model", Filter{Model: "claude-sonnet-4-20250514"}, "a"},
+		{"failure", Filter{Status: "failure"}, "b"},
+		{"status code", Filter{Status: "429"}, "b"},
+		{"search body", Filter{Query: "sonnet"}, "a"},
+		{"since", Filter{Since: base.Add(30 * time.Second)}, "b"},
+	}
+	for _, tc := range cases {
+		items, total, err := store.List(tc.filter)
+		if err != nil {
+			t.Fatalf("%s: List() error = %v", tc.name, err)
+		}
+		if total != 1 || len(items) != 1 || items[0].ID != tc.want {
+			t.Fatalf("%s: expected only %q, got total=%d items=%+v", tc.name, tc.want, total, items)
+		}
+	}
+
+	entry, err := store.Get("a")
+	if err != nil || entry == nil {
+		t.Fatalf("Get() = %v, %v", entry, err)
+	}
+	if len(entry.Attempts) != 2 || entry.Attempts[0].Channel != "backup" || entry.RequestBody == "" || !entry.Success {
+		t.Fatalf("unexpected detail: %+v", entry)
+	}
+	if missing, err := store.Get("missing"); err != nil || missing != nil {
+		t.Fatalf("Get(missing) = %v, %v", missing, err)
+	}
+
+	deleted, err := store.DeleteBefore(base.Add(30 * time.Second))
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteBefore() = %d, %v", deleted, err)
+	}
+}
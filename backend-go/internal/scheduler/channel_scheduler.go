@@ -7,6 +7,7 @@
 	"sort"
 	"sync"
 
+	"github.com/BenedictKing/claude-proxy/internal/alert"
 	"github.com/BenedictKing/claude-proxy/internal/config"
 	"github.com/BenedictKing/claude-proxy/internal/metrics"
 	"github.com/BenedictKing/claude-proxy/internal/session"
@@ -21,19 +22,26 @@ type ChannelScheduler struct {
 	messagesMetricsManager  *metrics.MetricsManager // Messages 渠道指标
 	responsesMetricsManager *metrics.MetricsManager // Responses 渠道指标
 	geminiMetricsManager    *metrics.MetricsManager // Gemini 渠道指标
+	chatMetricsManager      *metrics.MetricsManager // Chat Completions 渠道指标
 	traceAffinity           *session.TraceAffinityManager
 	urlManager              *warmup.URLManager // URL 管理器（非阻塞，动态排序）
+
+	// 负载均衡状态（独立锁，SelectChannel 持有 mu 读锁时也可更新）
+	balanceMu     sync.Mutex
+	rrWeights     map[ChannelKind]map[string]int // 平滑加权轮询的当前权重（key: 渠道标识）
+	keyRoundRobin map[string]int                 // Key 轮询游标（key: 入口类型|渠道标识）
 }
 
 // ChannelKind 标识调度器所处理的渠道类型
 // 注意：这里的 kind 与 upstream.ServiceType（openai/claude/gemini）不同，
-// kind 对应的是本代理对外暴露的三类入口：messages / responses / gemini。
+// kind 对应的是本代理对外暴露的入口：messages / responses / gemini / chat。
 type ChannelKind string
 
 const (
 	ChannelKindMessages  ChannelKind = "messages"
 	ChannelKindResponses ChannelKind = "responses"
 	ChannelKindGemini    ChannelKind = "gemini"
+	ChannelKindChat      ChannelKind = "chat"
 )
 
 // NewChannelScheduler 创建多渠道调度器
@@ -42,6 +50,7 @@ func NewChannelScheduler(
 	messagesMetrics *metrics.MetricsManager,
 	responsesMetrics *metrics.MetricsManager,
 	geminiMetrics *metrics.MetricsManager,
+	chatMetrics *metrics.MetricsManager,
 	traceAffinity *session.TraceAffinityManager,
 	urlMgr *warmup.URLManager,
 ) *ChannelScheduler {
@@ -50,8 +59,11 @@ func NewChannelScheduler(
 		messagesMetricsManager:  messagesMetrics,
 		responsesMetricsManager: responsesMetrics,
 		geminiMetricsManager:    geminiMetrics,
+		chatMetricsManager:      chatMetrics,
 		traceAffinity:           traceAffinity,
 		urlManager:              urlMgr,
+		rrWeights:               make(map[ChannelKind]map[string]int),
+		keyRoundRobin:           make(map[string]int),
 	}
 }
 
@@ -62,6 +74,8 @@ func (s *ChannelScheduler) getMetricsManager(kind ChannelKind) *metrics.MetricsM
 		return s.responsesMetricsManager
 	case ChannelKindGemini:
 		return s.geminiMetricsManager
+	case ChannelKindChat:
+		return s.chatMetricsManager
 	default:
 		return s.messagesMetricsManager
 	}
@@ -71,16 +85,28 @@ func (s *ChannelScheduler) getMetricsManager(kind ChannelKind) *metrics.MetricsM
 type SelectionResult struct {
 	Upstream     *config.UpstreamConfig
 	ChannelIndex int
-	Reason       string // 选择原因（用于日志）
+	Reason       string // 选择原因（用于日志），负载均衡策略选择时为策略名（如 round_robin）
+}
+
+// ModelNotServedError 没有任何活跃渠道服务请求的模型
+type ModelNotServedError struct {
+	Kind  ChannelKind
+	Model string
+}
+
+func (e *ModelNotServedError) Error() string {
+	return fmt.Sprintf("no %s channel serves model %q", e.Kind, e.Model)
 }
 
 // SelectChannel 选择最佳渠道
-// 优先级: 促销期渠道 > Trace亲和（促销渠道失败时回退） > 渠道优先级顺序
+// 优先级: 促销期渠道 > Trace亲和（促销渠道失败时回退） > 负载均衡策略（failover 时为渠道优先级顺序）
+// model 非空时仅在服务该模型的渠道中选择（见 UpstreamConfig.ServesModel），全部不匹配时返回 *ModelNotServedError
 func (s *ChannelScheduler) SelectChannel(
 	ctx context.Context,
 	userID string,
 	failedChannels map[int]bool,
 	kind ChannelKind,
+	model string,
 ) (*SelectionResult, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -91,6 +117,8 @@ func (s *ChannelScheduler) SelectChannel(
 		switch kind {
 		case ChannelKindGemini:
 			return nil, fmt.Errorf("没有可用的活跃 Gemini 渠道")
+		case ChannelKindChat:
+			return nil, fmt.Errorf("没有可用的活跃 Chat 渠道")
 		case ChannelKindResponses:
 			return nil, fmt.Errorf("没有可用的活跃 Responses 渠道")
 		default:
@@ -98,6 +126,16 @@ func (s *ChannelScheduler) SelectChannel(
 		}
 	}
 
+	// 按请求模型过滤渠道
+	if model != "" {
+		activeChannels = s.filterChannelsByModel(activeChannels, kind, model)
+		if len(activeChannels) == 0 {
+			prefix := kindSchedulerLogPrefix(kind)
+			log.Printf("[%s-Model] 警告: 没有渠道支持模型 %s", prefix, model)
+			return nil, &ModelNotServedError{Kind: kind, Model: model}
+		}
+	}
+
 	// 获取对应类型的指标管理器
 	metricsManager := s.getMetricsManager(kind)
 
@@ -126,9 +164,10 @@ func (s *ChannelScheduler) SelectChannel(
 
 	// 1. 检查 Trace 亲和性（促销渠道失败时或无促销渠道时）
 	if userID != "" {
-		if preferredIdx, ok := s.traceAffinity.GetPreferredChannel(userID); ok {
+		if preferredID, ok := s.traceAffinity.GetPreferredChannel(userID); ok {
 			for _, ch := range activeChannels {
-				if ch.Index == preferredIdx && !failedChannels[preferredIdx] {
+				preferredIdx := ch.Index
+				if ch.ID == preferredID && !failedChannels[preferredIdx] {
 					// 检查渠道状态：只有 active 状态才使用亲和性
 					if ch.Status != "active" {
 						prefix := kindSchedulerLogPrefix(kind)
@@ -151,7 +190,45 @@ func (s *ChannelScheduler) SelectChannel(
 		}
 	}
 
-	// 2. 按优先级遍历活跃渠道
+	// 2. 按负载均衡策略在健康渠道中选择（failover 策略按优先级取第一个健康渠道）
+	strategy := s.configManager.GetLoadBalanceStrategy(string(kind))
+	candidates := s.collectHealthyChannels(activeChannels, failedChannels, kind, strategy == config.LoadBalanceFailover)
+	if len(candidates) > 0 {
+		if strategy == config.LoadBalanceFailover {
+			ch := candidates[0]
+			prefix := kindSchedulerLogPrefix(kind)
+			log.Printf("[%s-Channel] 选择渠道: [%d] %s (优先级: %d)", prefix, ch.info.Index, ch.upstream.Name, ch.info.Priority)
+			return &SelectionResult{
+				Upstream:     ch.upstream,
+				ChannelIndex: ch.info.Index,
+				Reason:       "priority_order",
+			}, nil
+		}
+		return s.selectByStrategy(candidates, kind, strategy), nil
+	}
+
+	// 3. 所有健康渠道都失败，选择失败率最低的作为降级
+	return s.selectFallbackChannel(activeChannels, failedChannels, kind)
+}
+
+// channelCandidate 通过健康检查的候选渠道
+type channelCandidate struct {
+	info     ChannelInfo
+	upstream *config.UpstreamConfig
+}
+
+// collectHealthyChannels 按优先级收集可用的健康渠道
+// firstOnly 为 true 时找到第一个即返回（failover 策略无需遍历全部渠道）
+func (s *ChannelScheduler) collectHealthyChannels(
+	activeChannels []ChannelInfo,
+	failedChannels map[int]bool,
+	kind ChannelKind,
+	firstOnly bool,
+) []channelCandidate {
+	metricsManager := s.getMetricsManager(kind)
+	prefix := kindSchedulerLogPrefix(kind)
+
+	var candidates []channelCandidate
 	for _, ch := range activeChannels {
 		// 跳过本次请求已经失败的渠道
 		if failedChannels[ch.Index] {
@@ -160,7 +237,6 @@ func (s *ChannelScheduler) SelectChannel(
 
 		// 跳过非 active 状态的渠道（suspended 等）
 		if ch.Status != "active" {
-			prefix := kindSchedulerLogPrefix(kind)
 			log.Printf("[%s-Channel] 跳过非活跃渠道: [%d] %s (状态: %s)", prefix, ch.Index, ch.Name, ch.Status)
 			continue
 		}
@@ -173,22 +249,27 @@ func (s *ChannelScheduler) SelectChannel(
 		// 跳过失败率过高的渠道（已熔断或即将熔断）
 		if !metricsManager.IsChannelHealthyWithKeys(upstream.BaseURL, upstream.APIKeys) {
 			failureRate := metricsManager.CalculateChannelFailureRate(upstream.BaseURL, upstream.APIKeys)
-			prefix := kindSchedulerLogPrefix(kind)
 			log.Printf("[%s-Channel] 警告: 跳过不健康渠道: [%d] %s (失败率: %.1f%%)", prefix, ch.Index, ch.Name, failureRate*100)
 			continue
 		}
 
-		prefix := kindSchedulerLogPrefix(kind)
-		log.Printf("[%s-Channel] 选择渠道: [%d] %s (优先级: %d)", prefix, ch.Index, upstream.Name, ch.Priority)
-		return &SelectionResult{
-			Upstream:     upstream,
-			ChannelIndex: ch.Index,
-			Reason:       "priority_order",
-		}, nil
+		candidates = append(candidates, channelCandidate{info: ch, upstream: upstream})
+		if firstOnly {
+			break
+		}
 	}
+	return candidates
+}
 
-	// 3. 所有健康渠道都失败，选择失败率最低的作为降级
-	return s.selectFallbackChannel(activeChannels, failedChannels, kind)
+// filterChannelsByModel 过滤出服务指定模型的渠道（保持优先级顺序）
+func (s *ChannelScheduler) filterChannelsByModel(channels []ChannelInfo, kind ChannelKind, model string) []ChannelInfo {
+	filtered := make([]ChannelInfo, 0, len(channels))
+	for _, ch := range channels {
+		if upstream := s.getUpstreamByIndex(ch.Index, kind); upstream != nil && upstream.ServesModel(model) {
+			filtered = append(filtered, ch)
+		}
+	}
+	return filtered
 }
 
 // findPromotedChannel 查找处于促销期的渠道
@@ -248,6 +329,14 @@ func (s *ChannelScheduler) selectFallbackChannel(
 		prefix := kindSchedulerLogPrefix(kind)
 		log.Printf("[%s-Fallback] 警告: 降级选择渠道: [%d] %s (失败率: %.1f%%)",
 			prefix, bestChannel.Index, bestUpstream.Name, bestFailureRate*100)
+		alert.Emit(alert.Event{
+			Type:    config.AlertEventChannelUnhealthy,
+			Level:   alert.LevelWarning,
+			Kind:    string(kind),
+			Channel: bestUpstream.Name,
+			Message: fmt.Sprintf("所有健康渠道均已失败，降级使用失败率最低的渠道（失败率: %.1f%%）", bestFailureRate*100),
+			Details: map[string]interface{}{"fallback": true, "channelIndex": bestChannel.Index, "failureRate": bestFailureRate},
+		})
 		return &SelectionResult{
 			Upstream:     bestUpstream,
 			ChannelIndex: bestChannel.Index,
@@ -261,6 +350,7 @@ func (s *ChannelScheduler) selectFallbackChannel(
 // ChannelInfo 渠道信息（用于排序）
 type ChannelInfo struct {
 	Index    int
+	ID       string // 渠道唯一标识
 	Name     string
 	Priority int
 	Status   string
@@ -276,6 +366,8 @@ func (s *ChannelScheduler) getActiveChannels(kind ChannelKind) []ChannelInfo {
 		upstreams = cfg.ResponsesUpstream
 	case ChannelKindGemini:
 		upstreams = cfg.GeminiUpstream
+	case ChannelKindChat:
+		upstreams = cfg.ChatUpstream
 	default:
 		upstreams = cfg.Upstream
 	}
@@ -297,6 +389,7 @@ func (s *ChannelScheduler) getActiveChannels(kind ChannelKind) []ChannelInfo {
 
 			activeChannels = append(activeChannels, ChannelInfo{
 				Index:    i,
+				ID:       upstream.ID,
 				Name:     upstream.Name,
 				Priority: priority,
 				Status:   status,
@@ -323,6 +416,8 @@ func (s *ChannelScheduler) getUpstreamByIndex(index int, kind ChannelKind) *conf
 		upstreams = cfg.ResponsesUpstream
 	case ChannelKindGemini:
 		upstreams = cfg.GeminiUpstream
+	case ChannelKindChat:
+		upstreams = cfg.ChatUpstream
 	default:
 		upstreams = cfg.Upstream
 	}
@@ -360,10 +455,10 @@ func (s *ChannelScheduler) RecordRequestEnd(baseURL, apiKey string, kind Channel
 	s.getMetricsManager(kind).RecordRequestEnd(baseURL, apiKey)
 }
 
-// SetTraceAffinity 设置 Trace 亲和
-func (s *ChannelScheduler) SetTraceAffinity(userID string, channelIndex int) {
+// SetTraceAffinity 设置 Trace 亲和（按渠道标识记录，渠道排序或删除后不会指向其他渠道）
+func (s *ChannelScheduler) SetTraceAffinity(userID string, channelID string) {
 	if userID != "" {
-		s.traceAffinity.SetPreferredChannel(userID, channelIndex)
+		s.traceAffinity.SetPreferredChannel(userID, channelID)
 	}
 }
 
@@ -389,6 +484,16 @@ func (s *ChannelScheduler) GetGeminiMetricsManager() *metrics.MetricsManager {
 	return s.geminiMetricsManager
 }
 
+// GetChatMetricsManager 获取 Chat Completions 渠道指标管理器
+func (s *ChannelScheduler) GetChatMetricsManager() *metrics.MetricsManager {
+	return s.chatMetricsManager
+}
+
+// GetMetricsManager 按入口类型获取指标管理器
+func (s *ChannelScheduler) GetMetricsManager(kind ChannelKind) *metrics.MetricsManager {
+	return s.getMetricsManager(kind)
+}
+
 // GetTraceAffinityManager 获取 Trace 亲和性管理器
 func (s *ChannelScheduler) GetTraceAffinityManager() *session.TraceAffinityManager {
 	return s.traceAffinity
@@ -396,11 +501,13 @@ func (s *ChannelScheduler) GetTraceAffinityManager() *session.TraceAffinityManag
 
 // ResetChannelMetrics 重置渠道所有 Key 的熔断/失败状态（保留历史统计）
 // 用于：1) 手动恢复熔断 2) 更换 API Key 后重置熔断状态
-func (s *ChannelScheduler) ResetChannelMetrics(channelIndex int, kind ChannelKind) {
-	upstream := s.getUpstreamByIndex(channelIndex, kind)
-	if upstream == nil {
+func (s *ChannelScheduler) ResetChannelMetrics(channelID string, kind ChannelKind) {
+	cfg := s.configManager.GetConfig()
+	index := cfg.ChannelIndexByID(string(kind), channelID)
+	if index < 0 {
 		return
 	}
+	upstream := cfg.UpstreamsForKind(string(kind))[index]
 	metricsManager := s.getMetricsManager(kind)
 	for _, baseURL := range upstream.GetAllBaseURLs() {
 		for _, apiKey := range upstream.APIKeys {
@@ -408,7 +515,7 @@ func (s *ChannelScheduler) ResetChannelMetrics(channelIndex int, kind ChannelKin
 		}
 	}
 	prefix := kindSchedulerLogPrefix(kind)
-	log.Printf("[%s-Reset] 渠道 [%d] %s 的熔断状态已重置（保留历史统计）", prefix, channelIndex, upstream.Name)
+	log.Printf("[%s-Reset] 渠道 [%s] %s 的熔断状态已重置（保留历史统计）", prefix, channelID, upstream.Name)
 }
 
 // ResetKeyMetrics 重置单个 Key 的指标
@@ -423,11 +530,21 @@ func (s *ChannelScheduler) DeleteChannelMetrics(upstream *config.UpstreamConfig,
 		return
 	}
 	metricsManager := s.getMetricsManager(kind)
-	// 合并活跃 Key 和历史 Key，一起清理
+	// 合并活跃 Key、历史 Key 与被禁用的 Key，一起清理
 	allKeys := append([]string{}, upstream.APIKeys...)
-	allKeys = append(allKeys, upstream.HistoricalAPIKeys...)
+	allKeys = append(allKeys, upstream.InactiveAPIKeys()...)
 	// MetricsManager 内部已有 apiType，无需外部传递
 	metricsManager.DeleteChannelMetrics(upstream.GetAllBaseURLs(), allKeys)
+
+	// 清理按渠道标识记录的亲和性、URL 排序与轮询状态
+	if upstream.ID != "" {
+		s.traceAffinity.RemoveByChannel(upstream.ID)
+		s.InvalidateURLCache(kind, upstream.ID)
+		s.balanceMu.Lock()
+		delete(s.rrWeights[kind], upstream.ID)
+		delete(s.keyRoundRobin, keyCursorKey(kind, upstream.ID))
+		s.balanceMu.Unlock()
+	}
 	prefix := kindSchedulerLogPrefix(kind)
 	log.Printf("[%s-Delete] 渠道 %s 的指标数据已清理", prefix, upstream.Name)
 }
@@ -454,7 +571,7 @@ func maskUserID(userID string) string {
 // 返回按动态排序的 URL 结果列表，包含原始索引用于指标记录
 func (s *ChannelScheduler) GetSortedURLsForChannel(
 	kind ChannelKind,
-	channelIndex int,
+	channelID string,
 	urls []string,
 ) []warmup.URLLatencyResult {
 	if s.urlManager == nil || len(urls) <= 1 {
@@ -469,27 +586,27 @@ func (s *ChannelScheduler) GetSortedURLsForChannel(
 		}
 		return results
 	}
-	return s.urlManager.GetSortedURLs(urlManagerChannelKey(kind, channelIndex), urls)
+	return s.urlManager.GetSortedURLs(urlManagerChannelKey(kind, channelID), urls)
 }
 
 // MarkURLSuccess 标记 URL 成功
-func (s *ChannelScheduler) MarkURLSuccess(kind ChannelKind, channelIndex int, url string) {
+func (s *ChannelScheduler) MarkURLSuccess(kind ChannelKind, channelID string, url string) {
 	if s.urlManager != nil {
-		s.urlManager.MarkSuccess(urlManagerChannelKey(kind, channelIndex), url)
+		s.urlManager.MarkSuccess(urlManagerChannelKey(kind, channelID), url)
 	}
 }
 
 // MarkURLFailure 标记 URL 失败，触发动态排序
-func (s *ChannelScheduler) MarkURLFailure(kind ChannelKind, channelIndex int, url string) {
+func (s *ChannelScheduler) MarkURLFailure(kind ChannelKind, channelID string, url string) {
 	if s.urlManager != nil {
-		s.urlManager.MarkFailure(urlManagerChannelKey(kind, channelIndex), url)
+		s.urlManager.MarkFailure(urlManagerChannelKey(kind, channelID), url)
 	}
 }
 
 // InvalidateURLCache 使渠道 URL 状态失效
-func (s *ChannelScheduler) InvalidateURLCache(kind ChannelKind, channelIndex int) {
+func (s *ChannelScheduler) InvalidateURLCache(kind ChannelKind, channelID string) {
 	if s.urlManager != nil {
-		s.urlManager.InvalidateChannel(urlManagerChannelKey(kind, channelIndex))
+		s.urlManager.InvalidateChannel(urlManagerChannelKey(kind, channelID))
 	}
 }
 
@@ -507,23 +624,14 @@ func kindSchedulerLogPrefix(kind ChannelKind) string {
 		return "Scheduler-Responses"
 	case ChannelKindGemini:
 		return "Scheduler-Gemini"
+	case ChannelKindChat:
+		return "Scheduler-Chat"
 	default:
 		return "Scheduler"
 	}
 }
 
-func urlManagerChannelKey(kind ChannelKind, channelIndex int) int {
-	const stride = 1_000_000
-	return urlManagerChannelKeyOrdinal(kind)*stride + channelIndex
-}
-
-func urlManagerChannelKeyOrdinal(kind ChannelKind) int {
-	switch kind {
-	case ChannelKindResponses:
-		return 1
-	case ChannelKindGemini:
-		return 2
-	default:
-		return 0
-	}
+// urlManagerChannelKey URL 管理器状态键（入口类型 + 渠道标识）
+func urlManagerChannelKey(kind ChannelKind, channelID string) string {
+	return string(kind) + "/" + channelID
 }
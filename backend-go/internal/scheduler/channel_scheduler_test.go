@@ -3,6 +3,7 @@
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -60,15 +61,17 @@ func createTestScheduler(t *testing.T, cfg config.Config) (*ChannelScheduler, fu
 	messagesMetrics := metrics.NewMetricsManager()
 	responsesMetrics := metrics.NewMetricsManager()
 	geminiMetrics := metrics.NewMetricsManager()
+	chatMetrics := metrics.NewMetricsManager()
 	traceAffinity := session.NewTraceAffinityManager()
 	urlManager := warmup.NewURLManager(30*time.Second, 3)
 
-	scheduler := NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+	scheduler := NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, chatMetrics, traceAffinity, urlManager)
 
 	return scheduler, func() {
 		messagesMetrics.Stop()
 		responsesMetrics.Stop()
 		geminiMetrics.Stop()
+		chatMetrics.Stop()
 		cleanup()
 	}
 }
@@ -114,7 +117,7 @@ func TestPromotedChannelBypassesHealthCheck(t *testing.T) {
 	}
 
 	// 选择渠道 - 促销渠道应该被选中，即使它不健康
-	result, err := scheduler.SelectChannel(context.Background(), "test-user", make(map[int]bool), ChannelKindMessages)
+	result, err := scheduler.SelectChannel(context.Background(), "test-user", make(map[int]bool), ChannelKindMessages, "")
 	if err != nil {
 		t.Fatalf("选择渠道失败: %v", err)
 	}
@@ -165,7 +168,7 @@ func TestPromotedChannelSkippedAfterFailure(t *testing.T) {
 	}
 
 	// 选择渠道 - 应该跳过促销渠道，选择正常渠道
-	result, err := scheduler.SelectChannel(context.Background(), "test-user", failedChannels, ChannelKindMessages)
+	result, err := scheduler.SelectChannel(context.Background(), "test-user", failedChannels, ChannelKindMessages, "")
 	if err != nil {
 		t.Fatalf("选择渠道失败: %v", err)
 	}
@@ -210,7 +213,7 @@ func TestNonPromotedChannelStillChecksHealth(t *testing.T) {
 	}
 
 	// 选择渠道 - 应该跳过不健康的渠道，选择健康的渠道
-	result, err := scheduler.SelectChannel(context.Background(), "test-user", make(map[int]bool), ChannelKindMessages)
+	result, err := scheduler.SelectChannel(context.Background(), "test-user", make(map[int]bool), ChannelKindMessages, "")
 	if err != nil {
 		t.Fatalf("选择渠道失败: %v", err)
 	}
@@ -259,7 +262,7 @@ func TestExpiredPromotionNotBypassHealthCheck(t *testing.T) {
 	}
 
 	// 选择渠道 - 过期促销渠道不应该被优先选择，应该选择健康的渠道
-	result, err := scheduler.SelectChannel(context.Background(), "test-user", make(map[int]bool), ChannelKindMessages)
+	result, err := scheduler.SelectChannel(context.Background(), "test-user", make(map[int]bool), ChannelKindMessages, "")
 	if err != nil {
 		t.Fatalf("选择渠道失败: %v", err)
 	}
@@ -272,3 +275,131 @@ func TestExpiredPromotionNotBypassHealthCheck(t *testing.T) {
 		t.Errorf("期望选择 healthy-channel，实际选择了 %s", result.Upstream.Name)
 	}
 }
+
+// TestSelectChannelFiltersByModel 测试按请求模型过滤渠道
+func TestSelectChannelFiltersByModel(t *testing.T) {
+	promotionUntil := time.Now().Add(5 * time.Minute)
+
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{
+				Name:            "gpt-channel",
+				BaseURL:         "https://gpt.example.com",
+				APIKeys:         []string{"sk-gpt"},
+				Status:          "active",
+				Priority:        1,
+				SupportedModels: []string{"gpt-*"},
+				PromotionUntil:  &promotionUntil,
+			},
+			{
+				Name:            "claude-channel",
+				BaseURL:         "https://claude.example.com",
+				APIKeys:         []string{"sk-claude"},
+				Status:          "active",
+				Priority:        2,
+				SupportedModels: []string{"claude-opus-4", "claude-sonnet-*"},
+			},
+			{
+				Name:     "any-channel",
+				BaseURL:  "https://any.example.com",
+				APIKeys:  []string{"sk-any"},
+				Status:   "active",
+				Priority: 3,
+			},
+		},
+	}
+
+	scheduler, cleanup := createTestScheduler(t, cfg)
+	defer cleanup()
+
+	// 促销渠道不服务该模型，应跳过
+	result, err := scheduler.SelectChannel(context.Background(), "", make(map[int]bool), ChannelKindMessages, "claude-opus-4")
+	if err != nil {
+		t.Fatalf("选择渠道失败: %v", err)
+	}
+	if result.ChannelIndex != 1 {
+		t.Errorf("期望选择 claude-channel (index=1)，实际选择了 index=%d", result.ChannelIndex)
+	}
+
+	// 未限制模型的渠道作为后备
+	result, err = scheduler.SelectChannel(context.Background(), "", map[int]bool{1: true}, ChannelKindMessages, "claude-opus-4")
+	if err != nil {
+		t.Fatalf("选择渠道失败: %v", err)
+	}
+	if result.ChannelIndex != 2 {
+		t.Errorf("期望选择 any-channel (index=2)，实际选择了 index=%d", result.ChannelIndex)
+	}
+
+	// 空模型不过滤
+	result, err = scheduler.SelectChannel(context.Background(), "", make(map[int]bool), ChannelKindMessages, "")
+	if err != nil || result.ChannelIndex != 0 {
+		t.Fatalf("期望空模型选择促销渠道 index=0，实际: %+v, err=%v", result, err)
+	}
+}
+
+// TestSelectChannelModelNotServed 测试没有渠道服务请求模型时返回明确错误
+func TestSelectChannelModelNotServed(t *testing.T) {
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{
+				Name:            "gpt-channel",
+				BaseURL:         "https://gpt.example.com",
+				APIKeys:         []string{"sk-gpt"},
+				Status:          "active",
+				SupportedModels: []string{"gpt-*"},
+			},
+		},
+	}
+
+	scheduler, cleanup := createTestScheduler(t, cfg)
+	defer cleanup()
+
+	_, err := scheduler.SelectChannel(context.Background(), "", make(map[int]bool), ChannelKindMessages, "claude-opus-4")
+	var notServed *ModelNotServedError
+	if !errors.As(err, &notServed) {
+		t.Fatalf("期望 ModelNotServedError，实际: %v", err)
+	}
+	if notServed.Model != "claude-opus-4" || notServed.Error() != `no messages channel serves model "claude-opus-4"` {
+		t.Errorf("错误信息不符合预期: %v", notServed)
+	}
+}
+
+// TestTraceAffinitySurvivesChannelRemoval 删除前面的渠道后，Trace 亲和仍指向原渠道
+func TestTraceAffinitySurvivesChannelRemoval(t *testing.T) {
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "first", BaseURL: "https://first.example.com", APIKeys: []string{"sk-first"}, Status: "active", Priority: 1},
+			{Name: "second", BaseURL: "https://second.example.com", APIKeys: []string{"sk-second"}, Status: "active", Priority: 2},
+			{Name: "third", BaseURL: "https://third.example.com", APIKeys: []string{"sk-third"}, Status: "active", Priority: 3},
+		},
+	}
+
+	scheduler, cleanup := createTestScheduler(t, cfg)
+	defer cleanup()
+
+	upstreams := scheduler.configManager.GetConfig().Upstream
+	third := upstreams[2]
+	scheduler.SetTraceAffinity("affinity-user", third.ID)
+
+	if _, err := scheduler.configManager.RemoveUpstream(upstreams[0].ID); err != nil {
+		t.Fatalf("删除渠道失败: %v", err)
+	}
+
+	result, err := scheduler.SelectChannel(context.Background(), "affinity-user", make(map[int]bool), ChannelKindMessages, "")
+	if err != nil {
+		t.Fatalf("选择渠道失败: %v", err)
+	}
+	if result.Reason != "trace_affinity" || result.Upstream.Name != "third" || result.ChannelIndex != 1 {
+		t.Errorf("期望亲和选择 third (index=1)，实际: %s index=%d reason=%s", result.Upstream.Name, result.ChannelIndex, result.Reason)
+	}
+
+	// 删除渠道时清理其亲和记录
+	removed, err := scheduler.configManager.RemoveUpstream(third.ID)
+	if err != nil {
+		t.Fatalf("删除渠道失败: %v", err)
+	}
+	scheduler.DeleteChannelMetrics(removed, ChannelKindMessages)
+	if _, ok := scheduler.traceAffinity.GetPreferredChannel("affinity-user"); ok {
+		t.Error("删除渠道后应清理其亲和记录")
+	}
+}
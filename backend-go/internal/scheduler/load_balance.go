@@ -0,0 +1,209 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+)
+
+// strategyReason 负载均衡策略对应的 SelectionResult.Reason
+func strategyReason(strategy string) string {
+	switch strategy {
+	case config.LoadBalanceRoundRobin:
+		return "round_robin"
+	case config.LoadBalanceRandom:
+		return "random"
+	case config.LoadBalanceLeastInFlight:
+		return "least_inflight"
+	case config.LoadBalanceLowestLatency:
+		return "lowest_latency"
+	default:
+		return "priority_order"
+	}
+}
+
+// selectByStrategy 按负载均衡策略在健康候选渠道中选择（candidates 非空，按优先级排序）
+func (s *ChannelScheduler) selectByStrategy(candidates []channelCandidate, kind ChannelKind, strategy string) *SelectionResult {
+	metricsManager := s.getMetricsManager(kind)
+
+	var chosen channelCandidate
+	var detail string
+	switch strategy {
+	case config.LoadBalanceRoundRobin:
+		chosen = s.pickWeightedRoundRobin(candidates, kind)
+		detail = fmt.Sprintf("权重: %d", chosen.upstream.GetWeight())
+	case config.LoadBalanceRandom:
+		chosen = pickWeightedRandom(candidates)
+		detail = fmt.Sprintf("权重: %d", chosen.upstream.GetWeight())
+	case config.LoadBalanceLeastInFlight:
+		var stats metrics.LoadStats
+		chosen, stats = pickByLoad(candidates, metricsManager, func(a, b metrics.LoadStats) bool {
+			return a.InFlight < b.InFlight
+		})
+		detail = fmt.Sprintf("进行中: %d", stats.InFlight)
+	case config.LoadBalanceLowestLatency:
+		var stats metrics.LoadStats
+		chosen, stats = pickByLoad(candidates, metricsManager, lowerLatency)
+		detail = "平均耗时: " + formatLatency(stats)
+	default:
+		chosen = candidates[0]
+		detail = fmt.Sprintf("优先级: %d", chosen.info.Priority)
+	}
+
+	prefix := kindSchedulerLogPrefix(kind)
+	log.Printf("[%s-Balance] 负载均衡选择渠道: [%d] %s (策略: %s, 候选: %d, %s)",
+		prefix, chosen.info.Index, chosen.upstream.Name, strategy, len(candidates), detail)
+	return &SelectionResult{
+		Upstream:     chosen.upstream,
+		ChannelIndex: chosen.info.Index,
+		Reason:       strategyReason(strategy),
+	}
+}
+
+// keyCursorKey 返回渠道 Key 轮询游标的键（按渠道标识区分，改名或修改 BaseURL 不会重置游标）
+func keyCursorKey(kind ChannelKind, channelID string) string {
+	return string(kind) + "|" + channelID
+}
+
+// pickWeightedRoundRobin 平滑加权轮询（nginx smooth weighted round-robin）
+// 每轮所有候选的当前权重加上各自权重，选出当前权重最大者并减去总权重
+func (s *ChannelScheduler) pickWeightedRoundRobin(candidates []channelCandidate, kind ChannelKind) channelCandidate {
+	s.balanceMu.Lock()
+	defer s.balanceMu.Unlock()
+
+	current := s.rrWeights[kind]
+	if current == nil {
+		current = make(map[string]int)
+		s.rrWeights[kind] = current
+	}
+
+	total := 0
+	best := -1
+	for i, ch := range candidates {
+		weight := ch.upstream.GetWeight()
+		total += weight
+		current[ch.info.ID] += weight
+		if best < 0 || current[ch.info.ID] > current[candidates[best].info.ID] {
+			best = i
+		}
+	}
+	current[candidates[best].info.ID] -= total
+	return candidates[best]
+}
+
+// pickWeightedRandom 按权重随机选择
+func pickWeightedRandom(candidates []channelCandidate) channelCandidate {
+	total := 0
+	for _, ch := range candidates {
+		total += ch.upstream.GetWeight()
+	}
+	n := rand.Intn(total)
+	for _, ch := range candidates {
+		n -= ch.upstream.GetWeight()
+		if n < 0 {
+			return ch
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// pickByLoad 选择负载数据最优的候选（相同时保持优先级顺序）
+func pickByLoad(candidates []channelCandidate, metricsManager *metrics.MetricsManager, better func(a, b metrics.LoadStats) bool) (channelCandidate, metrics.LoadStats) {
+	chosen := candidates[0]
+	chosenStats := metricsManager.GetLoadStats(chosen.upstream.GetAllBaseURLs(), chosen.upstream.APIKeys)
+	for _, ch := range candidates[1:] {
+		stats := metricsManager.GetLoadStats(ch.upstream.GetAllBaseURLs(), ch.upstream.APIKeys)
+		if better(stats, chosenStats) {
+			chosen, chosenStats = ch, stats
+		}
+	}
+	return chosen, chosenStats
+}
+
+// lowerLatency 比较平均耗时；无耗时样本的视为最优，保证新渠道/新 Key 能获得流量以积累数据
+func lowerLatency(a, b metrics.LoadStats) bool {
+	if a.LatencySamples == 0 || b.LatencySamples == 0 {
+		return a.LatencySamples == 0 && b.LatencySamples > 0
+	}
+	return a.AverageLatency < b.AverageLatency
+}
+
+// NextAPIKey 按入口类型的负载均衡策略选择渠道内的 API 密钥
+// failover 策略或可用密钥不足两个时沿用 ConfigManager.GetNextAPIKey（按优先级顺序，含全部失效时的恢复尝试）
+func (s *ChannelScheduler) NextAPIKey(upstream *config.UpstreamConfig, failedKeys map[string]bool, kind ChannelKind) (string, error) {
+	apiType := kindAPIType(kind)
+	strategy := s.configManager.GetLoadBalanceStrategy(string(kind))
+	if strategy == config.LoadBalanceFailover || len(upstream.APIKeys) <= 1 {
+		return s.configManager.GetNextAPIKey(upstream, failedKeys, apiType)
+	}
+
+	availableKeys := make([]string, 0, len(upstream.APIKeys))
+	for _, key := range upstream.APIKeys {
+		if !failedKeys[key] && !s.configManager.IsKeyFailed(key) {
+			availableKeys = append(availableKeys, key)
+		}
+	}
+	if len(availableKeys) <= 1 {
+		return s.configManager.GetNextAPIKey(upstream, failedKeys, apiType)
+	}
+
+	var selectedKey string
+	switch strategy {
+	case config.LoadBalanceRoundRobin:
+		cursorKey := keyCursorKey(kind, upstream.ID)
+		s.balanceMu.Lock()
+		cursor := s.keyRoundRobin[cursorKey]
+		s.keyRoundRobin[cursorKey] = cursor + 1
+		s.balanceMu.Unlock()
+		selectedKey = availableKeys[cursor%len(availableKeys)]
+	case config.LoadBalanceRandom:
+		selectedKey = availableKeys[rand.Intn(len(availableKeys))]
+	case config.LoadBalanceLeastInFlight, config.LoadBalanceLowestLatency:
+		better := lowerLatency
+		if strategy == config.LoadBalanceLeastInFlight {
+			better = func(a, b metrics.LoadStats) bool { return a.InFlight < b.InFlight }
+		}
+		metricsManager := s.getMetricsManager(kind)
+		baseURLs := upstream.GetAllBaseURLs()
+		selectedKey = availableKeys[0]
+		selectedStats := metricsManager.GetLoadStats(baseURLs, []string{selectedKey})
+		for _, key := range availableKeys[1:] {
+			stats := metricsManager.GetLoadStats(baseURLs, []string{key})
+			if better(stats, selectedStats) {
+				selectedKey, selectedStats = key, stats
+			}
+		}
+	default:
+		return s.configManager.GetNextAPIKey(upstream, failedKeys, apiType)
+	}
+
+	log.Printf("[%s-Key] 负载均衡选择密钥 %s (策略: %s, 可用: %d/%d)",
+		apiType, utils.MaskAPIKey(selectedKey), strategy, len(availableKeys), len(upstream.APIKeys))
+	return selectedKey, nil
+}
+
+// kindAPIType 渠道类型对应的接口类型名（用于日志前缀，与 handlers 保持一致）
+func kindAPIType(kind ChannelKind) string {
+	switch kind {
+	case ChannelKindResponses:
+		return "Responses"
+	case ChannelKindGemini:
+		return "Gemini"
+	case ChannelKindChat:
+		return "Chat"
+	default:
+		return "Messages"
+	}
+}
+
+func formatLatency(stats metrics.LoadStats) string {
+	if stats.LatencySamples == 0 {
+		return "无数据"
+	}
+	return stats.AverageLatency.Round(time.Millisecond).String()
+}
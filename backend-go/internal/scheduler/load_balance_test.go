@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+func loadBalanceTestConfig(strategy string) config.Config {
+	return config.Config{
+		LoadBalance: strategy,
+		Upstream: []config.UpstreamConfig{
+			{Name: "a", BaseURL: "https://a.example.com", APIKeys: []string{"sk-a1", "sk-a2"}, Status: "active", Priority: 1, Weight: 3},
+			{Name: "b", BaseURL: "https://b.example.com", APIKeys: []string{"sk-b"}, Status: "active", Priority: 2},
+		},
+	}
+}
+
+// TestSelectChannelWeightedRoundRobin 测试平滑加权轮询按权重分配渠道
+func TestSelectChannelWeightedRoundRobin(t *testing.T) {
+	scheduler, cleanup := createTestScheduler(t, loadBalanceTestConfig(config.LoadBalanceRoundRobin))
+	defer cleanup()
+
+	counts := map[int]int{}
+	for i := 0; i < 8; i++ {
+		result, err := scheduler.SelectChannel(context.Background(), "", map[int]bool{}, ChannelKindMessages, "")
+		if err != nil {
+			t.Fatalf("选择渠道失败: %v", err)
+		}
+		if result.Reason != "round_robin" {
+			t.Fatalf("Reason = %q, want round_robin", result.Reason)
+		}
+		counts[result.ChannelIndex]++
+	}
+	if counts[0] != 6 || counts[1] != 2 {
+		t.Errorf("期望按 3:1 分配，实际: %v", counts)
+	}
+}
+
+// TestSelectChannelLeastInFlight 测试选择进行中请求最少的渠道
+func TestSelectChannelLeastInFlight(t *testing.T) {
+	scheduler, cleanup := createTestScheduler(t, loadBalanceTestConfig(config.LoadBalanceLeastInFlight))
+	defer cleanup()
+
+	scheduler.RecordRequestStart("https://a.example.com", "sk-a1", ChannelKindMessages)
+
+	result, err := scheduler.SelectChannel(context.Background(), "", map[int]bool{}, ChannelKindMessages, "")
+	if err != nil {
+		t.Fatalf("选择渠道失败: %v", err)
+	}
+	if result.ChannelIndex != 1 || result.Reason != "least_inflight" {
+		t.Errorf("期望选择空闲渠道 index=1 (least_inflight)，实际: index=%d reason=%s", result.ChannelIndex, result.Reason)
+	}
+
+	// 进行中的 Key 不会被优先选择
+	key, err := scheduler.NextAPIKey(scheduler.getUpstreamByIndex(0, ChannelKindMessages), map[string]bool{}, ChannelKindMessages)
+	if err != nil || key != "sk-a2" {
+		t.Errorf("期望选择空闲 Key sk-a2，实际: %s, err=%v", key, err)
+	}
+}
+
+// TestSelectChannelLowestLatency 测试选择平均耗时最低的渠道（无样本的渠道优先探索）
+func TestSelectChannelLowestLatency(t *testing.T) {
+	scheduler, cleanup := createTestScheduler(t, loadBalanceTestConfig(config.LoadBalanceLowestLatency))
+	defer cleanup()
+
+	metricsManager := scheduler.messagesMetricsManager
+	id := metricsManager.RecordRequestConnected("https://a.example.com", "sk-a1")
+	metricsManager.RecordRequestFinalizeFailure("https://a.example.com", "sk-a1", id)
+
+	result, err := scheduler.SelectChannel(context.Background(), "", map[int]bool{}, ChannelKindMessages, "")
+	if err != nil {
+		t.Fatalf("选择渠道失败: %v", err)
+	}
+	if result.ChannelIndex != 1 || result.Reason != "lowest_latency" {
+		t.Errorf("期望选择无耗时样本的渠道 index=1，实际: index=%d reason=%s", result.ChannelIndex, result.Reason)
+	}
+}
+
+// TestNextAPIKeyRoundRobin 测试 Key 级别轮询与 failover 默认行为
+func TestNextAPIKeyRoundRobin(t *testing.T) {
+	scheduler, cleanup := createTestScheduler(t, loadBalanceTestConfig(config.LoadBalanceRoundRobin))
+	defer cleanup()
+
+	upstream := scheduler.getUpstreamByIndex(0, ChannelKindMessages)
+	first, _ := scheduler.NextAPIKey(upstream, map[string]bool{}, ChannelKindMessages)
+	second, _ := scheduler.NextAPIKey(upstream, map[string]bool{}, ChannelKindMessages)
+	if first == second {
+		t.Errorf("期望轮询不同 Key，实际两次都是 %s", first)
+	}
+
+	// 本次请求已失败的 Key 不再被选择
+	if key, _ := scheduler.NextAPIKey(upstream, map[string]bool{"sk-a1": true}, ChannelKindMessages); key != "sk-a2" {
+		t.Errorf("期望跳过失败 Key，实际: %s", key)
+	}
+
+	// 游标按渠道标识区分：改名或修改 BaseURL 后继续轮询，同名同 BaseURL 的其他渠道独立轮询
+	third, _ := scheduler.NextAPIKey(upstream, map[string]bool{}, ChannelKindMessages)
+	renamed := upstream.Clone()
+	renamed.Name = "renamed"
+	renamed.BaseURL = "https://renamed.example.com"
+	if key, _ := scheduler.NextAPIKey(renamed, map[string]bool{}, ChannelKindMessages); key == third {
+		t.Errorf("改名后应沿用原游标，实际重复选择了 %s", key)
+	}
+	other := upstream.Clone()
+	other.ID = "other-channel"
+	if key, _ := scheduler.NextAPIKey(other, map[string]bool{}, ChannelKindMessages); key != upstream.APIKeys[0] {
+		t.Errorf("其他渠道应从第一个 Key 开始轮询，实际: %s", key)
+	}
+
+	failover, cleanup2 := createTestScheduler(t, loadBalanceTestConfig(config.LoadBalanceFailover))
+	defer cleanup2()
+	for i := 0; i < 3; i++ {
+		if key, _ := failover.NextAPIKey(upstream, map[string]bool{}, ChannelKindMessages); key != "sk-a1" {
+			t.Fatalf("failover 策略应始终选择第一个 Key，实际: %s", key)
+		}
+	}
+}
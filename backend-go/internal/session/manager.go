@@ -3,6 +3,7 @@
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -22,27 +23,40 @@ type Session struct {
 }
 
 // SessionManager 会话管理器
+// 会话数据由 Store 保存（内存或 SQLite），SessionManager 负责串行化访问与定期清理。
 type SessionManager struct {
-	sessions        map[string]*Session // sessionID → Session
-	responseMapping map[string]string   // responseID → sessionID
-	mu              sync.RWMutex
+	store Store
+	mu    sync.RWMutex
 
 	// 清理配置
 	maxAge      time.Duration // 24小时
 	maxMessages int           // 100条
 	maxTokens   int           // 100k
+
+	stopCh   chan struct{} // 用于停止清理 goroutine
+	stopOnce sync.Once
+	loopDone chan struct{} // 清理 goroutine 退出后关闭
 }
 
-// NewSessionManager 创建会话管理器
+// NewSessionManager 创建会话管理器（内存存储）
 func NewSessionManager(maxAge time.Duration, maxMessages int, maxTokens int) *SessionManager {
+	return NewSessionManagerWithStore(NewMemoryStore(), maxAge, maxMessages, maxTokens)
+}
+
+// NewSessionManagerWithStore 使用指定存储创建会话管理器
+func NewSessionManagerWithStore(store Store, maxAge time.Duration, maxMessages int, maxTokens int) *SessionManager {
 	sm := &SessionManager{
-		sessions:        make(map[string]*Session),
-		responseMapping: make(map[string]string),
-		maxAge:          maxAge,
-		maxMessages:     maxMessages,
-		maxTokens:       maxTokens,
+		store:       store,
+		maxAge:      maxAge,
+		maxMessages: maxMessages,
+		maxTokens:   maxTokens,
+		stopCh:      make(chan struct{}),
+		loopDone:    make(chan struct{}),
 	}
 
+	// 启动时先清理一次（持久化存储可能保留了重启前已过期的会话）
+	sm.cleanup()
+
 	// 启动定期清理
 	go sm.cleanupLoop()
 
@@ -56,10 +70,17 @@ func (sm *SessionManager) GetOrCreateSession(previousResponseID string) (*Sessio
 
 	// 如果提供了 previousResponseID，尝试查找对应的会话
 	if previousResponseID != "" {
-		if sessionID, ok := sm.responseMapping[previousResponseID]; ok {
-			if session, exists := sm.sessions[sessionID]; exists {
-				session.LastAccessAt = time.Now()
-				return session, nil
+		sessionID, ok, err := sm.store.GetResponseMapping(previousResponseID)
+		if err != nil {
+			return nil, fmt.Errorf("查询会话映射失败: %w", err)
+		}
+		if ok {
+			now := time.Now()
+			if err := sm.store.TouchSession(sessionID, now); err == nil {
+				session, err := sm.store.GetSession(sessionID)
+				if err == nil {
+					return session, nil
+				}
 			}
 		}
 		// 如果找不到对应会话，返回错误
@@ -68,15 +89,18 @@ func (sm *SessionManager) GetOrCreateSession(previousResponseID string) (*Sessio
 
 	// 创建新会话
 	sessionID := generateID("sess")
+	now := time.Now()
 	session := &Session{
 		ID:           sessionID,
 		Messages:     []types.ResponsesItem{},
-		CreatedAt:    time.Now(),
-		LastAccessAt: time.Now(),
+		CreatedAt:    now,
+		LastAccessAt: now,
 		TotalTokens:  0,
 	}
 
-	sm.sessions[sessionID] = session
+	if err := sm.store.CreateSession(session); err != nil {
+		return nil, fmt.Errorf("创建会话失败: %w", err)
+	}
 	log.Printf("[Session-Create] 创建新会话: %s", sessionID)
 
 	return session, nil
@@ -87,7 +111,10 @@ func (sm *SessionManager) RecordResponseMapping(responseID, sessionID string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	sm.responseMapping[responseID] = sessionID
+	if err := sm.store.PutResponseMapping(responseID, sessionID); err != nil {
+		log.Printf("[Session-Mapping] 警告: 记录映射失败: %s -> %s: %v", responseID, sessionID, err)
+		return
+	}
 	log.Printf("[Session-Mapping] 记录映射: %s -> %s", responseID, sessionID)
 }
 
@@ -96,15 +123,9 @@ func (sm *SessionManager) AppendMessage(sessionID string, item types.ResponsesIt
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	session, exists := sm.sessions[sessionID]
-	if !exists {
-		return fmt.Errorf("会话不存在: %s", sessionID)
+	if err := sm.store.AppendMessage(sessionID, item, tokensUsed, time.Now()); err != nil {
+		return wrapSessionError(err, sessionID)
 	}
-
-	session.Messages = append(session.Messages, item)
-	session.TotalTokens += tokensUsed
-	session.LastAccessAt = time.Now()
-
 	return nil
 }
 
@@ -113,12 +134,9 @@ func (sm *SessionManager) UpdateLastResponseID(sessionID, responseID string) err
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	session, exists := sm.sessions[sessionID]
-	if !exists {
-		return fmt.Errorf("会话不存在: %s", sessionID)
+	if err := sm.store.UpdateLastResponseID(sessionID, responseID); err != nil {
+		return wrapSessionError(err, sessionID)
 	}
-
-	session.LastResponseID = responseID
 	return nil
 }
 
@@ -127,72 +145,96 @@ func (sm *SessionManager) GetSession(sessionID string) (*Session, error) {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
-	session, exists := sm.sessions[sessionID]
-	if !exists {
-		return nil, fmt.Errorf("会话不存在: %s", sessionID)
+	session, err := sm.store.GetSession(sessionID)
+	if err != nil {
+		return nil, wrapSessionError(err, sessionID)
 	}
 
 	return session, nil
 }
 
-// cleanupLoop 定期清理过期会话
-func (sm *SessionManager) cleanupLoop() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
+// StoreResponse 保存 response（store=false 的请求不应调用）
+func (sm *SessionManager) StoreResponse(resp *StoredResponse) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
-	for range ticker.C {
-		sm.cleanup()
+	if resp.CreatedAt.IsZero() {
+		resp.CreatedAt = time.Now()
 	}
+	return sm.store.PutResponse(resp)
 }
 
-// cleanup 执行清理逻辑
-func (sm *SessionManager) cleanup() {
+// GetResponse 获取已存储的 response；不存在时返回 ErrResponseNotFound
+func (sm *SessionManager) GetResponse(responseID string) (*StoredResponse, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	return sm.store.GetResponse(responseID)
+}
+
+// DeleteResponse 删除已存储的 response；删除后该 ID 不能再作为 previous_response_id 使用
+func (sm *SessionManager) DeleteResponse(responseID string) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	now := time.Now()
-	removedSessions := 0
-	removedMappings := 0
+	if err := sm.store.DeleteResponse(responseID); err != nil {
+		return err
+	}
+	log.Printf("[Session-Response] 已删除 response: %s", responseID)
+	return nil
+}
 
-	// 清理过期会话
-	for sessionID, session := range sm.sessions {
-		shouldRemove := false
+// wrapSessionError 为存储错误补充会话 ID
+func wrapSessionError(err error, sessionID string) error {
+	if errors.Is(err, ErrSessionNotFound) {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	return err
+}
 
-		// 时间过期
-		if now.Sub(session.LastAccessAt) > sm.maxAge {
-			shouldRemove = true
-			log.Printf("[Session-Cleanup] 清理过期会话 (时间): %s (最后访问: %v 前)", sessionID, now.Sub(session.LastAccessAt))
-		}
+// cleanupLoop 定期清理过期会话
+func (sm *SessionManager) cleanupLoop() {
+	defer close(sm.loopDone)
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
 
-		// 消息数超限
-		if len(session.Messages) > sm.maxMessages {
-			shouldRemove = true
-			log.Printf("[Session-Cleanup] 清理过期会话 (消息数): %s (%d 条)", sessionID, len(session.Messages))
+	for {
+		select {
+		case <-ticker.C:
+			sm.cleanup()
+		case <-sm.stopCh:
+			return
 		}
+	}
+}
 
-		// Token 超限
-		if session.TotalTokens > sm.maxTokens {
-			shouldRemove = true
-			log.Printf("[Session-Cleanup] 清理过期会话 (Token): %s (%d tokens)", sessionID, session.TotalTokens)
-		}
+// Stop 停止清理 goroutine 并等待进行中的清理结束（需在关闭会话存储之前调用）
+func (sm *SessionManager) Stop() {
+	sm.stopOnce.Do(func() { close(sm.stopCh) })
+	<-sm.loopDone
+}
 
-		if shouldRemove {
-			delete(sm.sessions, sessionID)
-			removedSessions++
-		}
-	}
+// cleanup 执行清理逻辑（时间过期、消息数超限、Token 超限，以及孤立的 responseID 映射）
+func (sm *SessionManager) cleanup() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
-	// 清理孤立的 responseID 映射
-	for responseID, sessionID := range sm.responseMapping {
-		if _, exists := sm.sessions[sessionID]; !exists {
-			delete(sm.responseMapping, responseID)
-			removedMappings++
-		}
+	removedSessions, removedMappings, err := sm.store.Cleanup(CleanupPolicy{
+		Now:         time.Now(),
+		MaxAge:      sm.maxAge,
+		MaxMessages: sm.maxMessages,
+		MaxTokens:   sm.maxTokens,
+	})
+	if err != nil {
+		log.Printf("[Session-Cleanup] 警告: 清理会话失败: %v", err)
+		return
 	}
 
 	if removedSessions > 0 || removedMappings > 0 {
 		log.Printf("[Session-Cleanup] 清理完成: 删除 %d 个会话, %d 个映射", removedSessions, removedMappings)
-		log.Printf("[Session-Stats] 当前活跃会话: %d 个, 映射: %d 个", len(sm.sessions), len(sm.responseMapping))
+		if sessions, mappings, err := sm.store.Stats(); err == nil {
+			log.Printf("[Session-Stats] 当前活跃会话: %d 个, 映射: %d 个", sessions, mappings)
+		}
 	}
 }
 
@@ -201,9 +243,14 @@ func (sm *SessionManager) GetStats() map[string]interface{} {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
+	sessions, mappings, err := sm.store.Stats()
+	if err != nil {
+		log.Printf("[Session-Stats] 警告: 获取会话统计失败: %v", err)
+	}
+
 	return map[string]interface{}{
-		"total_sessions": len(sm.sessions),
-		"total_mappings": len(sm.responseMapping),
+		"total_sessions": sessions,
+		"total_mappings": mappings,
 	}
 }
 
@@ -0,0 +1,145 @@
+package session
+
+import (
+	"log"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+// MemoryStore 内存会话存储（进程重启后丢失）
+type MemoryStore struct {
+	sessions        map[string]*Session // sessionID → Session
+	responseMapping map[string]string   // responseID → sessionID
+	responses       map[string]*StoredResponse
+}
+
+// NewMemoryStore 创建内存会话存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions:        make(map[string]*Session),
+		responseMapping: make(map[string]string),
+		responses:       make(map[string]*StoredResponse),
+	}
+}
+
+// CreateSession 保存新会话
+func (s *MemoryStore) CreateSession(sess *Session) error {
+	s.sessions[sess.ID] = sess
+	return nil
+}
+
+// GetSession 获取会话
+func (s *MemoryStore) GetSession(sessionID string) (*Session, error) {
+	sess, exists := s.sessions[sessionID]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	return sess, nil
+}
+
+// TouchSession 更新会话最后访问时间
+func (s *MemoryStore) TouchSession(sessionID string, now time.Time) error {
+	sess, exists := s.sessions[sessionID]
+	if !exists {
+		return ErrSessionNotFound
+	}
+	sess.LastAccessAt = now
+	return nil
+}
+
+// AppendMessage 追加消息并累加 Token
+func (s *MemoryStore) AppendMessage(sessionID string, item types.ResponsesItem, tokensUsed int, now time.Time) error {
+	sess, exists := s.sessions[sessionID]
+	if !exists {
+		return ErrSessionNotFound
+	}
+	sess.Messages = append(sess.Messages, item)
+	sess.TotalTokens += tokensUsed
+	sess.LastAccessAt = now
+	return nil
+}
+
+// UpdateLastResponseID 更新会话的最后一个 responseID
+func (s *MemoryStore) UpdateLastResponseID(sessionID, responseID string) error {
+	sess, exists := s.sessions[sessionID]
+	if !exists {
+		return ErrSessionNotFound
+	}
+	sess.LastResponseID = responseID
+	return nil
+}
+
+// PutResponseMapping 记录 responseID → sessionID
+func (s *MemoryStore) PutResponseMapping(responseID, sessionID string) error {
+	s.responseMapping[responseID] = sessionID
+	return nil
+}
+
+// GetResponseMapping 查找 responseID 对应的会话 ID
+func (s *MemoryStore) GetResponseMapping(responseID string) (string, bool, error) {
+	sessionID, ok := s.responseMapping[responseID]
+	return sessionID, ok, nil
+}
+
+// PutResponse 保存 response 与其输入项
+func (s *MemoryStore) PutResponse(resp *StoredResponse) error {
+	s.responses[resp.ID] = resp
+	return nil
+}
+
+// GetResponse 获取已存储的 response
+func (s *MemoryStore) GetResponse(responseID string) (*StoredResponse, error) {
+	resp, exists := s.responses[responseID]
+	if !exists {
+		return nil, ErrResponseNotFound
+	}
+	return resp, nil
+}
+
+// DeleteResponse 删除已存储的 response 及其 responseID 映射
+func (s *MemoryStore) DeleteResponse(responseID string) error {
+	if _, exists := s.responses[responseID]; !exists {
+		return ErrResponseNotFound
+	}
+	delete(s.responses, responseID)
+	delete(s.responseMapping, responseID)
+	return nil
+}
+
+// Cleanup 按策略清理会话及孤立映射
+func (s *MemoryStore) Cleanup(policy CleanupPolicy) (int, int, error) {
+	removedSessions := 0
+	removedMappings := 0
+
+	for sessionID, sess := range s.sessions {
+		if reason := policy.shouldRemove(sess.LastAccessAt, len(sess.Messages), sess.TotalTokens); reason != "" {
+			log.Printf("[Session-Cleanup] 清理过期会话 (%s): %s (消息 %d 条, %d tokens, 最后访问: %v 前)",
+				reason, sessionID, len(sess.Messages), sess.TotalTokens, policy.Now.Sub(sess.LastAccessAt))
+			delete(s.sessions, sessionID)
+			removedSessions++
+		}
+	}
+
+	// 清理孤立的 responseID 映射
+	for responseID, sessionID := range s.responseMapping {
+		if _, exists := s.sessions[sessionID]; !exists {
+			delete(s.responseMapping, responseID)
+			removedMappings++
+		}
+	}
+
+	for responseID, resp := range s.responses {
+		_, sessionExists := s.sessions[resp.SessionID]
+		if policy.shouldRemoveResponse(resp, sessionExists) {
+			delete(s.responses, responseID)
+		}
+	}
+
+	return removedSessions, removedMappings, nil
+}
+
+// Stats 返回会话数与映射数
+func (s *MemoryStore) Stats() (int, int, error) {
+	return len(s.sessions), len(s.responseMapping), nil
+}
@@ -0,0 +1,330 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+// SQLiteStore SQLite 会话存储（进程重启后保留 previous_response_id 链路）
+// 复用指标数据库连接（metrics.SQLiteStore.DB()），不负责关闭连接。
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore 创建 SQLite 会话存储并初始化表结构
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("数据库连接为空")
+	}
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS responses_sessions (
+			id TEXT PRIMARY KEY,
+			last_response_id TEXT NOT NULL DEFAULT '',
+			created_at INTEGER NOT NULL,
+			last_access_at INTEGER NOT NULL,
+			total_tokens INTEGER NOT NULL DEFAULT 0,
+			message_count INTEGER NOT NULL DEFAULT 0
+		);
+
+		CREATE TABLE IF NOT EXISTS responses_session_messages (
+			session_id TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			item TEXT NOT NULL,
+			PRIMARY KEY (session_id, seq)
+		);
+
+		CREATE TABLE IF NOT EXISTS responses_mappings (
+			response_id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_responses_mappings_session
+			ON responses_mappings(session_id);
+
+		CREATE TABLE IF NOT EXISTS responses_stored (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL DEFAULT '',
+			client_id TEXT NOT NULL DEFAULT '',
+			response TEXT NOT NULL,
+			input_items TEXT NOT NULL DEFAULT '[]',
+			created_at INTEGER NOT NULL
+		);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("初始化会话表失败: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// CreateSession 保存新会话（含已有消息）
+func (s *SQLiteStore) CreateSession(sess *Session) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT OR REPLACE INTO responses_sessions
+		(id, last_response_id, created_at, last_access_at, total_tokens, message_count)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, sess.ID, sess.LastResponseID, sess.CreatedAt.UnixMilli(), sess.LastAccessAt.UnixMilli(), sess.TotalTokens, len(sess.Messages))
+	if err != nil {
+		return err
+	}
+	for i, item := range sess.Messages {
+		if err := insertMessage(tx, sess.ID, i, item); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetSession 获取会话（含完整消息历史）
+func (s *SQLiteStore) GetSession(sessionID string) (*Session, error) {
+	sess := &Session{ID: sessionID}
+	var createdAt, lastAccessAt int64
+	err := s.db.QueryRow(`
+		SELECT last_response_id, created_at, last_access_at, total_tokens
+		FROM responses_sessions WHERE id = ?
+	`, sessionID).Scan(&sess.LastResponseID, &createdAt, &lastAccessAt, &sess.TotalTokens)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	sess.CreatedAt = time.UnixMilli(createdAt)
+	sess.LastAccessAt = time.UnixMilli(lastAccessAt)
+
+	rows, err := s.db.Query(`
+		SELECT item FROM responses_session_messages
+		WHERE session_id = ? ORDER BY seq ASC
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sess.Messages = []types.ResponsesItem{}
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var item types.ResponsesItem
+		if err := json.Unmarshal([]byte(raw), &item); err != nil {
+			return nil, fmt.Errorf("解析会话消息失败: %w", err)
+		}
+		sess.Messages = append(sess.Messages, item)
+	}
+	return sess, rows.Err()
+}
+
+// TouchSession 更新会话最后访问时间
+func (s *SQLiteStore) TouchSession(sessionID string, now time.Time) error {
+	return s.execOnSession(`UPDATE responses_sessions SET last_access_at = ? WHERE id = ?`, now.UnixMilli(), sessionID)
+}
+
+// AppendMessage 追加消息并累加 Token
+func (s *SQLiteStore) AppendMessage(sessionID string, item types.ResponsesItem, tokensUsed int, now time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var seq int
+	err = tx.QueryRow(`SELECT message_count FROM responses_sessions WHERE id = ?`, sessionID).Scan(&seq)
+	if err == sql.ErrNoRows {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := insertMessage(tx, sessionID, seq, item); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE responses_sessions
+		SET message_count = message_count + 1, total_tokens = total_tokens + ?, last_access_at = ?
+		WHERE id = ?
+	`, tokensUsed, now.UnixMilli(), sessionID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateLastResponseID 更新会话的最后一个 responseID
+func (s *SQLiteStore) UpdateLastResponseID(sessionID, responseID string) error {
+	return s.execOnSession(`UPDATE responses_sessions SET last_response_id = ? WHERE id = ?`, responseID, sessionID)
+}
+
+// PutResponseMapping 记录 responseID → sessionID
+func (s *SQLiteStore) PutResponseMapping(responseID, sessionID string) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO responses_mappings (response_id, session_id) VALUES (?, ?)`, responseID, sessionID)
+	return err
+}
+
+// GetResponseMapping 查找 responseID 对应的会话 ID
+func (s *SQLiteStore) GetResponseMapping(responseID string) (string, bool, error) {
+	var sessionID string
+	err := s.db.QueryRow(`SELECT session_id FROM responses_mappings WHERE response_id = ?`, responseID).Scan(&sessionID)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return sessionID, true, nil
+}
+
+// PutResponse 保存 response 与其输入项
+func (s *SQLiteStore) PutResponse(resp *StoredResponse) error {
+	inputItems, err := json.Marshal(resp.InputItems)
+	if err != nil {
+		return fmt.Errorf("序列化输入项失败: %w", err)
+	}
+	if resp.InputItems == nil {
+		inputItems = []byte("[]")
+	}
+	_, err = s.db.Exec(`
+		INSERT OR REPLACE INTO responses_stored (id, session_id, client_id, response, input_items, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, resp.ID, resp.SessionID, resp.ClientID, string(resp.Response), string(inputItems), resp.CreatedAt.UnixMilli())
+	return err
+}
+
+// GetResponse 获取已存储的 response
+func (s *SQLiteStore) GetResponse(responseID string) (*StoredResponse, error) {
+	resp := &StoredResponse{ID: responseID}
+	var response, inputItems string
+	var createdAt int64
+	err := s.db.QueryRow(`
+		SELECT session_id, client_id, response, input_items, created_at
+		FROM responses_stored WHERE id = ?
+	`, responseID).Scan(&resp.SessionID, &resp.ClientID, &response, &inputItems, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrResponseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Response = json.RawMessage(response)
+	resp.CreatedAt = time.UnixMilli(createdAt)
+	if err := json.Unmarshal([]byte(inputItems), &resp.InputItems); err != nil {
+		return nil, fmt.Errorf("解析输入项失败: %w", err)
+	}
+	return resp, nil
+}
+
+// DeleteResponse 删除已存储的 response 及其 responseID 映射
+func (s *SQLiteStore) DeleteResponse(responseID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM responses_stored WHERE id = ?`, responseID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrResponseNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM responses_mappings WHERE response_id = ?`, responseID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Cleanup 按策略清理会话及孤立映射
+func (s *SQLiteStore) Cleanup(policy CleanupPolicy) (int, int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	const expired = `SELECT id FROM responses_sessions
+		WHERE last_access_at < ? OR message_count > ? OR total_tokens > ?`
+	args := []interface{}{policy.Now.Add(-policy.MaxAge).UnixMilli(), policy.MaxMessages, policy.MaxTokens}
+
+	if _, err := tx.Exec(`DELETE FROM responses_session_messages WHERE session_id IN (`+expired+`)`, args...); err != nil {
+		return 0, 0, err
+	}
+	result, err := tx.Exec(`DELETE FROM responses_sessions WHERE id IN (`+expired+`)`, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	removedSessions, _ := result.RowsAffected()
+
+	result, err = tx.Exec(`
+		DELETE FROM responses_mappings
+		WHERE session_id NOT IN (SELECT id FROM responses_sessions)
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+	removedMappings, _ := result.RowsAffected()
+
+	// 关联会话的 response 随会话清理，未关联会话的按创建时间过期
+	_, err = tx.Exec(`
+		DELETE FROM responses_stored
+		WHERE (session_id != '' AND session_id NOT IN (SELECT id FROM responses_sessions))
+		   OR (session_id = '' AND created_at < ?)
+	`, policy.Now.Add(-policy.MaxAge).UnixMilli())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	if removedSessions > 0 {
+		log.Printf("[Session-Cleanup] 清理过期会话 %d 个 (超过 %v 未访问或超出消息数/Token 上限)", removedSessions, policy.MaxAge)
+	}
+	return int(removedSessions), int(removedMappings), nil
+}
+
+// Stats 返回会话数与映射数
+func (s *SQLiteStore) Stats() (int, int, error) {
+	var sessions, mappings int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM responses_sessions`).Scan(&sessions); err != nil {
+		return 0, 0, err
+	}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM responses_mappings`).Scan(&mappings); err != nil {
+		return 0, 0, err
+	}
+	return sessions, mappings, nil
+}
+
+// execOnSession 执行针对单个会话的更新；会话不存在时返回 ErrSessionNotFound
+func (s *SQLiteStore) execOnSession(query string, args ...interface{}) error {
+	result, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func insertMessage(tx *sql.Tx, sessionID string, seq int, item types.ResponsesItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("序列化会话消息失败: %w", err)
+	}
+	_, err = tx.Exec(`INSERT OR REPLACE INTO responses_session_messages (session_id, seq, item) VALUES (?, ?, ?)`,
+		sessionID, seq, string(data))
+	return err
+}
@@ -0,0 +1,87 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+// ErrSessionNotFound 会话不存在
+var ErrSessionNotFound = errors.New("会话不存在")
+
+// ErrResponseNotFound 已存储的 response 不存在
+var ErrResponseNotFound = errors.New("response 不存在")
+
+// StoredResponse 已存储的 response（用于 GET/DELETE /v1/responses/{id} 与 input_items 查询）
+type StoredResponse struct {
+	ID         string
+	SessionID  string            // 所属会话（流式响应未关联会话时为空）
+	ClientID   string            // 创建该 response 的客户端（管理员密钥为空）
+	Response   json.RawMessage   // 返回给客户端的完整 response 对象
+	InputItems []json.RawMessage // 本轮请求的输入项（均带 id）
+	CreatedAt  time.Time
+}
+
+// CleanupPolicy 会话清理策略（任一条件满足即删除）
+type CleanupPolicy struct {
+	Now         time.Time
+	MaxAge      time.Duration // 最后访问时间超过该时长
+	MaxMessages int           // 消息数超过该值
+	MaxTokens   int           // 累计 Token 超过该值
+}
+
+// shouldRemoveResponse 判断已存储的 response 是否应被清理
+// 关联会话的 response 随会话一起清理；未关联会话的按创建时间过期。
+func (p CleanupPolicy) shouldRemoveResponse(resp *StoredResponse, sessionExists bool) bool {
+	if resp.SessionID != "" {
+		return !sessionExists
+	}
+	return p.Now.Sub(resp.CreatedAt) > p.MaxAge
+}
+
+// shouldRemove 判断会话是否应被清理，返回原因（空字符串表示保留）
+func (p CleanupPolicy) shouldRemove(lastAccessAt time.Time, messageCount, totalTokens int) string {
+	switch {
+	case p.Now.Sub(lastAccessAt) > p.MaxAge:
+		return "时间"
+	case messageCount > p.MaxMessages:
+		return "消息数"
+	case totalTokens > p.MaxTokens:
+		return "Token"
+	}
+	return ""
+}
+
+// Store 会话存储接口
+// SessionManager 负责串行化调用（持有互斥锁），实现方无需处理同一会话的并发读改写。
+type Store interface {
+	// CreateSession 保存新会话
+	CreateSession(sess *Session) error
+	// GetSession 获取会话；不存在时返回 ErrSessionNotFound
+	GetSession(sessionID string) (*Session, error)
+	// TouchSession 更新会话最后访问时间
+	TouchSession(sessionID string, now time.Time) error
+	// AppendMessage 追加消息并累加 Token
+	AppendMessage(sessionID string, item types.ResponsesItem, tokensUsed int, now time.Time) error
+	// UpdateLastResponseID 更新会话的最后一个 responseID
+	UpdateLastResponseID(sessionID, responseID string) error
+
+	// PutResponseMapping 记录 responseID → sessionID
+	PutResponseMapping(responseID, sessionID string) error
+	// GetResponseMapping 查找 responseID 对应的会话 ID
+	GetResponseMapping(responseID string) (string, bool, error)
+
+	// PutResponse 保存 response 与其输入项
+	PutResponse(resp *StoredResponse) error
+	// GetResponse 获取已存储的 response；不存在时返回 ErrResponseNotFound
+	GetResponse(responseID string) (*StoredResponse, error)
+	// DeleteResponse 删除已存储的 response 及其 responseID 映射；不存在时返回 ErrResponseNotFound
+	DeleteResponse(responseID string) error
+
+	// Cleanup 按策略清理会话、孤立映射与过期 response，返回删除的会话数与映射数
+	Cleanup(policy CleanupPolicy) (removedSessions, removedMappings int, err error)
+	// Stats 返回会话数与映射数
+	Stats() (sessions, mappings int, err error)
+}
@@ -0,0 +1,197 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+func newTestSQLiteStore(t *testing.T, db *sql.DB) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(db)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore() error = %v", err)
+	}
+	return store
+}
+
+func TestSessionManager_Stores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"sqlite": func(t *testing.T) Store {
+			db := openTestDB(t, filepath.Join(t.TempDir(), "sessions.db"))
+			t.Cleanup(func() { db.Close() })
+			return newTestSQLiteStore(t, db)
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			sm := NewSessionManagerWithStore(newStore(t), time.Hour, 3, 1000)
+
+			sess, err := sm.GetOrCreateSession("")
+			if err != nil {
+				t.Fatalf("GetOrCreateSession() error = %v", err)
+			}
+			if err := sm.AppendMessage(sess.ID, types.ResponsesItem{Type: "message", Role: "user", Content: "hi"}, 0); err != nil {
+				t.Fatalf("AppendMessage() error = %v", err)
+			}
+			if err := sm.AppendMessage(sess.ID, types.ResponsesItem{Type: "message", Role: "assistant", Content: "hello"}, 30); err != nil {
+				t.Fatalf("AppendMessage() error = %v", err)
+			}
+			if err := sm.UpdateLastResponseID(sess.ID, "resp_1"); err != nil {
+				t.Fatalf("UpdateLastResponseID() error = %v", err)
+			}
+			sm.RecordResponseMapping("resp_1", sess.ID)
+
+			got, err := sm.GetOrCreateSession("resp_1")
+			if err != nil {
+				t.Fatalf("GetOrCreateSession(resp_1) error = %v", err)
+			}
+			if got.ID != sess.ID || len(got.Messages) != 2 || got.TotalTokens != 30 || got.LastResponseID != "resp_1" {
+				t.Fatalf("unexpected session: %+v", got)
+			}
+			if got.Messages[1].Content != "hello" {
+				t.Fatalf("unexpected message content: %#v", got.Messages[1].Content)
+			}
+
+			if _, err := sm.GetOrCreateSession("resp_missing"); err == nil {
+				t.Fatal("expected error for unknown previous_response_id")
+			}
+			if err := sm.AppendMessage("sess_missing", types.ResponsesItem{}, 0); err == nil {
+				t.Fatal("expected error for unknown session")
+			}
+
+			// 消息数超过上限后被清理，映射一并删除
+			sm.AppendMessage(sess.ID, types.ResponsesItem{Type: "message", Content: "a"}, 0)
+			sm.AppendMessage(sess.ID, types.ResponsesItem{Type: "message", Content: "b"}, 0)
+			sm.cleanup()
+			stats := sm.GetStats()
+			if stats["total_sessions"] != 0 || stats["total_mappings"] != 0 {
+				t.Fatalf("expected session and mapping removed, got %v", stats)
+			}
+		})
+	}
+}
+
+func TestSQLiteStore_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+
+	db := openTestDB(t, path)
+	sm := NewSessionManagerWithStore(newTestSQLiteStore(t, db), time.Hour, 100, 100000)
+	sess, err := sm.GetOrCreateSession("")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession() error = %v", err)
+	}
+	sm.AppendMessage(sess.ID, types.ResponsesItem{Type: "message", Role: "user", Content: []types.ContentBlock{{Type: "input_text", Text: "hi"}}}, 10)
+	sm.RecordResponseMapping("resp_1", sess.ID)
+	sm.Stop()
+	sm.Stop() // 重复调用应安全返回
+	db.Close()
+
+	// 模拟进程重启：重新打开数据库
+	db = openTestDB(t, path)
+	defer db.Close()
+	sm = NewSessionManagerWithStore(newTestSQLiteStore(t, db), time.Hour, 100, 100000)
+	defer sm.Stop()
+
+	got, err := sm.GetOrCreateSession("resp_1")
+	if err != nil {
+		t.Fatalf("重启后应能通过 previous_response_id 找回会话: %v", err)
+	}
+	if got.ID != sess.ID || len(got.Messages) != 1 || got.TotalTokens != 10 {
+		t.Fatalf("unexpected session after restart: %+v", got)
+	}
+	blocks, ok := got.Messages[0].Content.([]interface{})
+	if !ok || len(blocks) != 1 {
+		t.Fatalf("unexpected content after restart: %#v", got.Messages[0].Content)
+	}
+}
+
+func TestSessionManager_StoredResponses(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"sqlite": func(t *testing.T) Store {
+			db := openTestDB(t, filepath.Join(t.TempDir(), "sessions.db"))
+			t.Cleanup(func() { db.Close() })
+			return newTestSQLiteStore(t, db)
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			sm := NewSessionManagerWithStore(newStore(t), time.Hour, 100, 100000)
+
+			sess, err := sm.GetOrCreateSession("")
+			if err != nil {
+				t.Fatalf("GetOrCreateSession() error = %v", err)
+			}
+			sm.RecordResponseMapping("resp_1", sess.ID)
+			err = sm.StoreResponse(&StoredResponse{
+				ID:         "resp_1",
+				SessionID:  sess.ID,
+				ClientID:   "client-a",
+				Response:   json.RawMessage(`{"id":"resp_1","object":"response"}`),
+				InputItems: []json.RawMessage{json.RawMessage(`{"id":"msg_1","type":"message"}`)},
+			})
+			if err != nil {
+				t.Fatalf("StoreResponse() error = %v", err)
+			}
+			// 未关联会话的流式 response，创建时间已超过 maxAge
+			sm.StoreResponse(&StoredResponse{
+				ID:        "resp_stream",
+				Response:  json.RawMessage(`{"id":"resp_stream"}`),
+				CreatedAt: time.Now().Add(-2 * time.Hour),
+			})
+
+			got, err := sm.GetResponse("resp_1")
+			if err != nil {
+				t.Fatalf("GetResponse() error = %v", err)
+			}
+			if got.SessionID != sess.ID || got.ClientID != "client-a" || string(got.Response) != `{"id":"resp_1","object":"response"}` {
+				t.Fatalf("unexpected stored response: %+v", got)
+			}
+			if len(got.InputItems) != 1 || string(got.InputItems[0]) != `{"id":"msg_1","type":"message"}` {
+				t.Fatalf("unexpected input items: %s", got.InputItems)
+			}
+
+			sm.cleanup()
+			if _, err := sm.GetResponse("resp_stream"); !errors.Is(err, ErrResponseNotFound) {
+				t.Fatalf("expected expired stream response removed, got err = %v", err)
+			}
+			if _, err := sm.GetResponse("resp_1"); err != nil {
+				t.Fatalf("response with live session should be kept: %v", err)
+			}
+
+			// 删除后不能再作为 previous_response_id 使用
+			if err := sm.DeleteResponse("resp_1"); err != nil {
+				t.Fatalf("DeleteResponse() error = %v", err)
+			}
+			if _, err := sm.GetResponse("resp_1"); !errors.Is(err, ErrResponseNotFound) {
+				t.Fatalf("expected ErrResponseNotFound after delete, got %v", err)
+			}
+			if _, err := sm.GetOrCreateSession("resp_1"); err == nil {
+				t.Fatal("expected error for deleted previous_response_id")
+			}
+			if err := sm.DeleteResponse("resp_1"); !errors.Is(err, ErrResponseNotFound) {
+				t.Fatalf("expected ErrResponseNotFound on second delete, got %v", err)
+			}
+		})
+	}
+}
@@ -13,8 +13,8 @@
 
 // TraceAffinity 记录 trace 与渠道的亲和关系
 type TraceAffinity struct {
-	ChannelIndex int
-	LastUsedAt   time.Time
+	ChannelID  string // 渠道唯一标识（不随渠道排序/删除变化）
+	LastUsedAt time.Time
 }
 
 // TraceAffinityManager 管理 trace 与渠道的亲和性
@@ -57,10 +57,10 @@ func NewTraceAffinityManagerWithTTL(ttl time.Duration) *TraceAffinityManager {
 }
 
 // GetPreferredChannel 获取 user_id 偏好的渠道
-// 返回渠道索引和是否存在
-func (m *TraceAffinityManager) GetPreferredChannel(userID string) (int, bool) {
+// 返回渠道标识和是否存在
+func (m *TraceAffinityManager) GetPreferredChannel(userID string) (string, bool) {
 	if userID == "" {
-		return -1, false
+		return "", false
 	}
 
 	m.mu.RLock()
@@ -68,44 +68,44 @@ func (m *TraceAffinityManager) GetPreferredChannel(userID string) (int, bool) {
 
 	affinity, exists := m.affinity[userID]
 	if !exists {
-		return -1, false
+		return "", false
 	}
 
 	// 检查是否过期
 	if time.Since(affinity.LastUsedAt) > m.ttl {
-		return -1, false
+		return "", false
 	}
 
-	return affinity.ChannelIndex, true
+	return affinity.ChannelID, true
 }
 
 // SetPreferredChannel 设置 user_id 偏好的渠道
-func (m *TraceAffinityManager) SetPreferredChannel(userID string, channelIndex int) {
-	if userID == "" {
+func (m *TraceAffinityManager) SetPreferredChannel(userID string, channelID string) {
+	if userID == "" || channelID == "" {
 		return
 	}
 
 	var logType int // 0=无, 1=新建, 2=变更
-	var oldChannel int
+	var oldChannel string
 
 	m.mu.Lock()
 	oldAffinity, existed := m.affinity[userID]
-	if existed && oldAffinity.ChannelIndex != channelIndex {
-		logType, oldChannel = 2, oldAffinity.ChannelIndex
+	if existed && oldAffinity.ChannelID != channelID {
+		logType, oldChannel = 2, oldAffinity.ChannelID
 	} else if !existed {
 		logType = 1
 	}
 	m.affinity[userID] = &TraceAffinity{
-		ChannelIndex: channelIndex,
-		LastUsedAt:   time.Now(),
+		ChannelID:  channelID,
+		LastUsedAt: time.Now(),
 	}
 	m.mu.Unlock()
 
 	if affinityDebug {
 		if logType == 2 {
-			log.Printf("[Affinity-Set] 用户亲和变更: %s -> 渠道[%d] (原渠道[%d])", maskUserID(userID), channelIndex, oldChannel)
+			log.Printf("[Affinity-Set] 用户亲和变更: %s -> 渠道[%s] (原渠道[%s])", maskUserID(userID), channelID, oldChannel)
 		} else if logType == 1 {
-			log.Printf("[Affinity-Set] 新建用户亲和: %s -> 渠道[%d]", maskUserID(userID), channelIndex)
+			log.Printf("[Affinity-Set] 新建用户亲和: %s -> 渠道[%s]", maskUserID(userID), channelID)
 		}
 	}
 }
@@ -126,28 +126,28 @@ func (m *TraceAffinityManager) UpdateLastUsed(userID string) {
 
 // Remove 移除 user_id 的亲和记录
 func (m *TraceAffinityManager) Remove(userID string) {
-	var oldChannel int
+	var oldChannel string
 	var existed bool
 
 	m.mu.Lock()
 	if affinity, exists := m.affinity[userID]; exists {
-		oldChannel, existed = affinity.ChannelIndex, true
+		oldChannel, existed = affinity.ChannelID, true
 		delete(m.affinity, userID)
 	}
 	m.mu.Unlock()
 
 	if affinityDebug && existed {
-		log.Printf("[Affinity-Remove] 移除用户亲和: %s (原渠道[%d])", maskUserID(userID), oldChannel)
+		log.Printf("[Affinity-Remove] 移除用户亲和: %s (原渠道[%s])", maskUserID(userID), oldChannel)
 	}
 }
 
 // RemoveByChannel 移除指定渠道的所有亲和记录
 // 用于渠道被禁用或删除时
-func (m *TraceAffinityManager) RemoveByChannel(channelIndex int) {
+func (m *TraceAffinityManager) RemoveByChannel(channelID string) {
 	m.mu.Lock()
 	removed := 0
 	for userID, affinity := range m.affinity {
-		if affinity.ChannelIndex == channelIndex {
+		if affinity.ChannelID == channelID {
 			delete(m.affinity, userID)
 			removed++
 		}
@@ -155,7 +155,7 @@ func (m *TraceAffinityManager) RemoveByChannel(channelIndex int) {
 	m.mu.Unlock()
 
 	if affinityDebug && removed > 0 {
-		log.Printf("[Affinity-RemoveByChannel] 渠道[%d]被移除，清理了 %d 条亲和记录", channelIndex, removed)
+		log.Printf("[Affinity-RemoveByChannel] 渠道[%s]被移除，清理了 %d 条亲和记录", channelID, removed)
 	}
 }
 
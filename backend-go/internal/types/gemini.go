@@ -41,6 +41,9 @@ type GeminiPart struct {
 	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
 	FileData         *GeminiFileData         `json:"fileData,omitempty"`
 	Thought          bool                    `json:"thought,omitempty"` // 是否为 thinking 内容
+	// ThoughtSignature 非 functionCall part（thought/text）的签名，序列化时位于 part 层级；
+	// functionCall 的签名统一存储在 FunctionCall.ThoughtSignature
+	ThoughtSignature string `json:"-"`
 }
 
 // UnmarshalJSON 自定义反序列化，兼容部分客户端将 thoughtSignature 放在 part 层级的情况（而非 functionCall 内部）
@@ -63,14 +66,20 @@ func (p *GeminiPart) UnmarshalJSON(data []byte) error {
 
 	*p = GeminiPart(raw.partAlias)
 
-	// 兼容：当签名出现在 part 层级时，将其归一化到 functionCall 内部（内部存储即可）
-	if p.FunctionCall == nil || p.FunctionCall.ThoughtSignature != "" {
+	signature := raw.ThoughtSignatureSnake
+	if signature == "" {
+		signature = raw.ThoughtSignatureCamel
+	}
+
+	// 非 functionCall part（thought/text）的签名保留在 part 上
+	if p.FunctionCall == nil {
+		p.ThoughtSignature = signature
 		return nil
 	}
-	if raw.ThoughtSignatureSnake != "" {
-		p.FunctionCall.ThoughtSignature = raw.ThoughtSignatureSnake
-	} else if raw.ThoughtSignatureCamel != "" {
-		p.FunctionCall.ThoughtSignature = raw.ThoughtSignatureCamel
+
+	// 兼容：当签名出现在 part 层级时，将其归一化到 functionCall 内部（内部存储即可）
+	if p.FunctionCall.ThoughtSignature == "" {
+		p.FunctionCall.ThoughtSignature = signature
 	}
 
 	return nil
@@ -86,11 +95,12 @@ func (p GeminiPart) MarshalJSON() ([]byte, error) {
 		partAlias: partAlias(p),
 	}
 
+	sig := p.ThoughtSignature
 	if p.FunctionCall != nil {
-		sig := p.FunctionCall.ThoughtSignature
-		if sig != "" && sig != StripThoughtSignatureMarker {
-			out.ThoughtSignature = sig
-		}
+		sig = p.FunctionCall.ThoughtSignature
+	}
+	if sig != "" && sig != StripThoughtSignatureMarker {
+		out.ThoughtSignature = sig
 	}
 
 	return json.Marshal(out)
@@ -211,3 +211,23 @@ func TestGeminiFunctionDeclaration_UnmarshalJSON_SanitizeParametersSchema(t *tes
 		t.Fatalf("输出不应包含 const: %v", outAgentName)
 	}
 }
+
+func TestGeminiPart_ThoughtSignatureOnTextPart(t *testing.T) {
+	input := `{"text":"planning","thought":true,"thoughtSignature":"sig_text"}`
+
+	var part GeminiPart
+	if err := json.Unmarshal([]byte(input), &part); err != nil {
+		t.Fatalf("UnmarshalJSON 失败: %v", err)
+	}
+	if part.ThoughtSignature != "sig_text" {
+		t.Fatalf("ThoughtSignature=%q, want=%q", part.ThoughtSignature, "sig_text")
+	}
+
+	outBytes, err := json.Marshal(part)
+	if err != nil {
+		t.Fatalf("Marshal 失败: %v", err)
+	}
+	if string(outBytes) != input {
+		t.Fatalf("输出=%s, want=%s", outBytes, input)
+	}
+}
@@ -10,6 +10,13 @@ type ClaudeRequest struct {
 	Stream      bool                   `json:"stream,omitempty"`
 	Tools       []ClaudeTool           `json:"tools,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"` // Claude Code CLI 等客户端发送的元数据
+	Thinking    *ClaudeThinking        `json:"thinking,omitempty"` // 扩展思考配置
+}
+
+// ClaudeThinking Claude 扩展思考配置
+type ClaudeThinking struct {
+	Type         string `json:"type"` // enabled, disabled
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
 }
 
 // ClaudeMessage Claude 消息
@@ -26,13 +33,24 @@ type CacheControl struct {
 
 // ClaudeContent Claude 内容块
 type ClaudeContent struct {
-	Type         string        `json:"type"` // text, tool_use, tool_result
-	Text         string        `json:"text,omitempty"`
-	ID           string        `json:"id,omitempty"`
-	Name         string        `json:"name,omitempty"`
-	Input        interface{}   `json:"input,omitempty"`
-	ToolUseID    string        `json:"tool_use_id,omitempty"`
-	CacheControl *CacheControl `json:"cache_control,omitempty"`
+	Type         string               `json:"type"` // text, thinking, tool_use, tool_result, image, document
+	Text         string               `json:"text,omitempty"`
+	Thinking     string               `json:"thinking,omitempty"`  // thinking 块的思考内容
+	Signature    string               `json:"signature,omitempty"` // thinking 块的签名（回传上游时需原样携带）
+	ID           string               `json:"id,omitempty"`
+	Name         string               `json:"name,omitempty"`
+	Input        interface{}          `json:"input,omitempty"`
+	ToolUseID    string               `json:"tool_use_id,omitempty"`
+	Source       *ClaudeContentSource `json:"source,omitempty"` // image/document 内容来源
+	CacheControl *CacheControl        `json:"cache_control,omitempty"`
+}
+
+// ClaudeContentSource image/document 块的内容来源
+type ClaudeContentSource struct {
+	Type      string `json:"type"`                 // base64, url, text
+	MediaType string `json:"media_type,omitempty"` // 如 image/png、application/pdf
+	Data      string `json:"data,omitempty"`       // base64 数据或纯文本（type=text）
+	URL       string `json:"url,omitempty"`
 }
 
 // ClaudeTool Claude 工具定义
@@ -62,6 +80,7 @@ type OpenAIRequest struct {
 	Stream              bool            `json:"stream,omitempty"`
 	Tools               []OpenAITool    `json:"tools,omitempty"`
 	ToolChoice          string          `json:"tool_choice,omitempty"`
+	ReasoningEffort     string          `json:"reasoning_effort,omitempty"` // minimal, low, medium, high
 }
 
 // OpenAIMessage OpenAI 消息
@@ -70,6 +89,8 @@ type OpenAIMessage struct {
 	Content    interface{}      `json:"content"` // string 或 null
 	ToolCalls  []OpenAIToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string           `json:"tool_call_id,omitempty"`
+	// ReasoningContent 推理内容（DeepSeek 等兼容上游的非标准字段）
+	ReasoningContent string `json:"reasoning_content,omitempty"`
 }
 
 // OpenAIToolCall OpenAI 工具调用
@@ -141,6 +141,79 @@ func isCJK(r rune) bool {
 		unicode.Is(unicode.Hangul, r)
 }
 
+// ============== Gemini API Token 估算 ==============
+
+// geminiMediaPartTokens 单个图片/文件 part 的估算 token（Gemini 图片按 258 tokens 计费）
+const geminiMediaPartTokens = 258
+
+// EstimateGeminiRequestTokens 从 Gemini 请求体估算输入 token
+// 支持 contents、systemInstruction、tools，以及 countTokens 的 generateContentRequest 包装格式
+func EstimateGeminiRequestTokens(bodyBytes []byte) int {
+	if len(bodyBytes) == 0 {
+		return 0
+	}
+
+	var req map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		return EstimateTokens(string(bodyBytes))
+	}
+	if inner, ok := req["generateContentRequest"].(map[string]interface{}); ok {
+		req = inner
+	}
+
+	total := 0
+
+	// systemInstruction (系统指令)
+	if system, ok := req["systemInstruction"].(map[string]interface{}); ok {
+		total += estimateGeminiContentTokens(system)
+	}
+
+	// contents
+	if contents, ok := req["contents"].([]interface{}); ok {
+		for _, item := range contents {
+			if content, ok := item.(map[string]interface{}); ok {
+				// 每条消息额外开销约 4 tokens
+				total += 4 + estimateGeminiContentTokens(content)
+			}
+		}
+	}
+
+	// tools (每个工具约 100-200 tokens)
+	if tools, ok := req["tools"].([]interface{}); ok {
+		total += len(tools) * 150
+	}
+
+	return total
+}
+
+// estimateGeminiContentTokens 估算单个 Gemini content（parts 数组）的 token
+func estimateGeminiContentTokens(content map[string]interface{}) int {
+	parts, ok := content["parts"].([]interface{})
+	if !ok {
+		return 0
+	}
+	total := 0
+	for _, item := range parts {
+		part, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch {
+		case part["text"] != nil:
+			if text, ok := part["text"].(string); ok {
+				total += EstimateTokens(text)
+			}
+		case part["inlineData"] != nil, part["fileData"] != nil:
+			total += geminiMediaPartTokens
+		default:
+			// functionCall / functionResponse 等结构化内容序列化后估算
+			data, _ := json.Marshal(part)
+			total += EstimateTokens(string(data))
+		}
+	}
+	return total
+}
+
 // ============== Responses API Token 估算 ==============
 
 // EstimateResponsesRequestTokens 从 Responses API 请求体估算输入 token
@@ -223,3 +223,29 @@ func TestEstimateRequestTokens(t *testing.T) {
 		})
 	}
 }
+
+func TestEstimateGeminiRequestTokens(t *testing.T) {
+	plain := []byte(`{"contents":[{"role":"user","parts":[{"text":"Hello, how are you today?"}]}]}`)
+	wrapped := []byte(`{"generateContentRequest":{"model":"models/gemini-2.0-flash","contents":[{"role":"user","parts":[{"text":"Hello, how are you today?"}]}]}}`)
+
+	base := EstimateGeminiRequestTokens(plain)
+	if base < 8 {
+		t.Fatalf("EstimateGeminiRequestTokens(plain) = %d, want >= 8", base)
+	}
+	if got := EstimateGeminiRequestTokens(wrapped); got != base {
+		t.Errorf("generateContentRequest wrapper = %d, want %d", got, base)
+	}
+
+	withExtras := []byte(`{
+		"systemInstruction": {"parts": [{"text": "You are helpful."}]},
+		"contents": [{"role": "user", "parts": [{"text": "Hello, how are you today?"}, {"inlineData": {"mimeType": "image/png", "data": "AAAA"}}]}],
+		"tools": [{"functionDeclarations": [{"name": "get_weather"}]}]
+	}`)
+	if got := EstimateGeminiRequestTokens(withExtras); got < base+geminiMediaPartTokens+150 {
+		t.Errorf("EstimateGeminiRequestTokens(withExtras) = %d, want >= %d", got, base+geminiMediaPartTokens+150)
+	}
+
+	if got := EstimateGeminiRequestTokens(nil); got != 0 {
+		t.Errorf("EstimateGeminiRequestTokens(nil) = %d, want 0", got)
+	}
+}
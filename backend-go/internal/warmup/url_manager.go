@@ -28,17 +28,17 @@ type URLState struct {
 
 // ChannelURLState 渠道 URL 状态
 type ChannelURLState struct {
-	ChannelIndex int
-	URLs         []*URLState
-	UpdatedAt    time.Time
+	ChannelKey string
+	URLs       []*URLState
+	UpdatedAt  time.Time
 }
 
 // URLManager URL 管理器（非阻塞，基于 failover 动态排序）
 type URLManager struct {
 	mu              sync.RWMutex
-	channelStates   map[int]*ChannelURLState // key: channelIndex
-	failureCooldown time.Duration            // 失败冷却时间（过后允许重试）
-	maxFailCount    int                      // 最大连续失败次数（超过则移到末尾）
+	channelStates   map[string]*ChannelURLState // key: 渠道标识（由调用方保证跨入口唯一）
+	failureCooldown time.Duration               // 失败冷却时间（过后允许重试）
+	maxFailCount    int                         // 最大连续失败次数（超过则移到末尾）
 }
 
 // NewURLManager 创建 URL 管理器
@@ -50,7 +50,7 @@ func NewURLManager(failureCooldown time.Duration, maxFailCount int) *URLManager
 		maxFailCount = 3 // 默认连续 3 次失败后移到末尾
 	}
 	return &URLManager{
-		channelStates:   make(map[int]*ChannelURLState),
+		channelStates:   make(map[string]*ChannelURLState),
 		failureCooldown: failureCooldown,
 		maxFailCount:    maxFailCount,
 	}
@@ -61,7 +61,7 @@ func NewURLManager(failureCooldown time.Duration, maxFailCount int) *URLManager
 // 1. 成功的 URL 优先
 // 2. 冷却期过后的失败 URL 可重试
 // 3. 仍在冷却期的失败 URL 放到最后
-func (m *URLManager) GetSortedURLs(channelIndex int, urls []string) []URLLatencyResult {
+func (m *URLManager) GetSortedURLs(channelKey string, urls []string) []URLLatencyResult {
 	if len(urls) == 0 {
 		return nil
 	}
@@ -73,7 +73,7 @@ func (m *URLManager) GetSortedURLs(channelIndex int, urls []string) []URLLatency
 	defer m.mu.Unlock()
 
 	// 确保渠道状态存在并同步 URL 列表
-	state := m.ensureChannelState(channelIndex, urls)
+	state := m.ensureChannelState(channelKey, urls)
 
 	// 每次获取时重新排序，确保冷却期过后的 URL 能被正确提升
 	m.sortURLs(state)
@@ -94,11 +94,11 @@ func (m *URLManager) GetSortedURLs(channelIndex int, urls []string) []URLLatency
 }
 
 // MarkSuccess 标记 URL 成功
-func (m *URLManager) MarkSuccess(channelIndex int, url string) {
+func (m *URLManager) MarkSuccess(channelKey string, url string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	state, ok := m.channelStates[channelIndex]
+	state, ok := m.channelStates[channelKey]
 	if !ok {
 		return
 	}
@@ -118,11 +118,11 @@ func (m *URLManager) MarkSuccess(channelIndex int, url string) {
 }
 
 // MarkFailure 标记 URL 失败
-func (m *URLManager) MarkFailure(channelIndex int, url string) {
+func (m *URLManager) MarkFailure(channelKey string, url string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	state, ok := m.channelStates[channelIndex]
+	state, ok := m.channelStates[channelKey]
 	if !ok {
 		return
 	}
@@ -134,7 +134,7 @@ func (m *URLManager) MarkFailure(channelIndex int, url string) {
 			urlState.LastFailTime = now
 			urlState.TotalRequests++
 			urlState.TotalFailures++
-			log.Printf("[URLManager] URL 失败: 渠道 [%d], URL: %s, 连续失败: %d", channelIndex, url, urlState.FailCount)
+			log.Printf("[URLManager] URL 失败: 渠道 [%s], URL: %s, 连续失败: %d", channelKey, url, urlState.FailCount)
 			break
 		}
 	}
@@ -145,15 +145,15 @@ func (m *URLManager) MarkFailure(channelIndex int, url string) {
 }
 
 // ensureChannelState 确保渠道状态存在，并同步 URL 列表
-func (m *URLManager) ensureChannelState(channelIndex int, urls []string) *ChannelURLState {
-	state, ok := m.channelStates[channelIndex]
+func (m *URLManager) ensureChannelState(channelKey string, urls []string) *ChannelURLState {
+	state, ok := m.channelStates[channelKey]
 
 	if !ok {
 		// 初始化新渠道状态
 		state = &ChannelURLState{
-			ChannelIndex: channelIndex,
-			URLs:         make([]*URLState, len(urls)),
-			UpdatedAt:    time.Now(),
+			ChannelKey: channelKey,
+			URLs:       make([]*URLState, len(urls)),
+			UpdatedAt:  time.Now(),
 		}
 		for i, url := range urls {
 			state.URLs[i] = &URLState{
@@ -161,17 +161,17 @@ func (m *URLManager) ensureChannelState(channelIndex int, urls []string) *Channe
 				OriginalIdx: i,
 			}
 		}
-		m.channelStates[channelIndex] = state
+		m.channelStates[channelKey] = state
 		return state
 	}
 
 	// 检查 URL 列表是否变化（配置热重载场景）
 	if !m.urlsMatch(state.URLs, urls) {
-		log.Printf("[URLManager] 检测到渠道 [%d] URL 配置变化，重置状态", channelIndex)
+		log.Printf("[URLManager] 检测到渠道 [%s] URL 配置变化，重置状态", channelKey)
 		state = &ChannelURLState{
-			ChannelIndex: channelIndex,
-			URLs:         make([]*URLState, len(urls)),
-			UpdatedAt:    time.Now(),
+			ChannelKey: channelKey,
+			URLs:       make([]*URLState, len(urls)),
+			UpdatedAt:  time.Now(),
 		}
 		for i, url := range urls {
 			state.URLs[i] = &URLState{
@@ -179,7 +179,7 @@ func (m *URLManager) ensureChannelState(channelIndex int, urls []string) *Channe
 				OriginalIdx: i,
 			}
 		}
-		m.channelStates[channelIndex] = state
+		m.channelStates[channelKey] = state
 	}
 
 	return state
@@ -248,18 +248,18 @@ func (m *URLManager) sortURLs(state *ChannelURLState) {
 }
 
 // InvalidateChannel 使渠道状态失效
-func (m *URLManager) InvalidateChannel(channelIndex int) {
+func (m *URLManager) InvalidateChannel(channelKey string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	delete(m.channelStates, channelIndex)
-	log.Printf("[URLManager] 渠道 [%d] 状态已清除", channelIndex)
+	delete(m.channelStates, channelKey)
+	log.Printf("[URLManager] 渠道 [%s] 状态已清除", channelKey)
 }
 
 // InvalidateAll 清除所有状态
 func (m *URLManager) InvalidateAll() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.channelStates = make(map[int]*ChannelURLState)
+	m.channelStates = make(map[string]*ChannelURLState)
 	log.Printf("[URLManager] 所有渠道状态已清除")
 }
 
@@ -268,8 +268,8 @@ func (m *URLManager) GetStats() map[string]interface{} {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	channelStats := make(map[int]interface{})
-	for idx, state := range m.channelStates {
+	channelStats := make(map[string]interface{})
+	for key, state := range m.channelStates {
 		urlStats := make([]map[string]interface{}, len(state.URLs))
 		for i, urlState := range state.URLs {
 			urlStats[i] = map[string]interface{}{
@@ -282,7 +282,7 @@ func (m *URLManager) GetStats() map[string]interface{} {
 				"last_success_time": urlState.LastSuccessTime,
 			}
 		}
-		channelStats[idx] = map[string]interface{}{
+		channelStats[key] = map[string]interface{}{
 			"urls":       urlStats,
 			"updated_at": state.UpdatedAt,
 		}
@@ -11,14 +11,21 @@
 	"syscall"
 	"time"
 
+	"github.com/BenedictKing/claude-proxy/internal/alert"
+	"github.com/BenedictKing/claude-proxy/internal/audit"
+	"github.com/BenedictKing/claude-proxy/internal/balance"
 	"github.com/BenedictKing/claude-proxy/internal/config"
 	"github.com/BenedictKing/claude-proxy/internal/handlers"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/chat"
 	"github.com/BenedictKing/claude-proxy/internal/handlers/gemini"
 	"github.com/BenedictKing/claude-proxy/internal/handlers/messages"
 	"github.com/BenedictKing/claude-proxy/internal/handlers/responses"
+	"github.com/BenedictKing/claude-proxy/internal/healthprobe"
 	"github.com/BenedictKing/claude-proxy/internal/logger"
 	"github.com/BenedictKing/claude-proxy/internal/metrics"
 	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/quota"
+	"github.com/BenedictKing/claude-proxy/internal/requestlog"
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/BenedictKing/claude-proxy/internal/session"
 	"github.com/BenedictKing/claude-proxy/internal/warmup"
@@ -35,6 +42,11 @@ func main() {
 		log.Println("没有找到 .env 文件，使用环境变量或默认值")
 	}
 
+	// 子命令：加密现有配置中的 API Key
+	if len(os.Args) > 1 && os.Args[1] == "encrypt-config" {
+		os.Exit(runEncryptConfig(os.Args[2:]))
+	}
+
 	// 设置版本信息到 handlers 包
 	handlers.SetVersionInfo(Version, BuildTime, GitCommit)
 
@@ -61,14 +73,6 @@ func main() {
 	}
 	defer cfgManager.Close()
 
-	// 初始化会话管理器（Responses API 专用）
-	sessionManager := session.NewSessionManager(
-		24*time.Hour, // 24小时过期
-		100,          // 最多100条消息
-		100000,       // 最多100k tokens
-	)
-	log.Printf("[Session-Init] 会话管理器已初始化")
-
 	// 初始化指标持久化存储（可选）
 	var metricsStore *metrics.SQLiteStore
 	if envCfg.MetricsPersistenceEnabled {
@@ -85,8 +89,30 @@ func main() {
 		log.Printf("[Metrics-Init] 指标持久化已禁用，使用纯内存模式")
 	}
 
-	// 初始化多渠道调度器（Messages、Responses 和 Gemini 使用独立的指标管理器）
-	var messagesMetricsManager, responsesMetricsManager, geminiMetricsManager *metrics.MetricsManager
+	// 初始化会话管理器（Responses API 专用）
+	// 启用指标持久化时默认将会话保存到同一 SQLite 数据库，重启后 previous_response_id 仍可用
+	var sessionStore session.Store = session.NewMemoryStore()
+	sessionStoreName := "memory"
+	if envCfg.SessionStore == "sqlite" {
+		if metricsStore == nil {
+			log.Printf("[Session-Init] 警告: 指标持久化未启用，会话存储回退为内存模式")
+		} else if sqliteSessionStore, err := session.NewSQLiteStore(metricsStore.DB()); err != nil {
+			log.Printf("[Session-Init] 警告: 初始化 SQLite 会话存储失败: %v，回退为内存模式", err)
+		} else {
+			sessionStore = sqliteSessionStore
+			sessionStoreName = "sqlite"
+		}
+	}
+	sessionManager := session.NewSessionManagerWithStore(
+		sessionStore,
+		24*time.Hour, // 24小时过期
+		100,          // 最多100条消息
+		100000,       // 最多100k tokens
+	)
+	log.Printf("[Session-Init] 会话管理器已初始化 (存储: %s)", sessionStoreName)
+
+	// 初始化多渠道调度器（Messages、Responses、Gemini 和 Chat 使用独立的指标管理器）
+	var messagesMetricsManager, responsesMetricsManager, geminiMetricsManager, chatMetricsManager *metrics.MetricsManager
 	if metricsStore != nil {
 		messagesMetricsManager = metrics.NewMetricsManagerWithPersistence(
 			envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold, metricsStore, "messages")
@@ -94,10 +120,13 @@ func main() {
 			envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold, metricsStore, "responses")
 		geminiMetricsManager = metrics.NewMetricsManagerWithPersistence(
 			envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold, metricsStore, "gemini")
+		chatMetricsManager = metrics.NewMetricsManagerWithPersistence(
+			envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold, metricsStore, "chat")
 	} else {
 		messagesMetricsManager = metrics.NewMetricsManagerWithConfig(envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold)
 		responsesMetricsManager = metrics.NewMetricsManagerWithConfig(envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold)
 		geminiMetricsManager = metrics.NewMetricsManagerWithConfig(envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold)
+		chatMetricsManager = metrics.NewMetricsManagerWithConfig(envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold)
 	}
 	traceAffinityManager := session.NewTraceAffinityManager()
 
@@ -105,10 +134,70 @@ func main() {
 	urlManager := warmup.NewURLManager(30*time.Second, 3) // 30秒冷却期，连续3次失败后移到末尾
 	log.Printf("[URLManager-Init] URL管理器已初始化 (冷却期: 30秒, 最大连续失败: 3)")
 
-	channelScheduler := scheduler.NewChannelScheduler(cfgManager, messagesMetricsManager, responsesMetricsManager, geminiMetricsManager, traceAffinityManager, urlManager)
+	// 初始化客户端配额管理器（用量持久化到指标数据库，未启用持久化时仅内存统计）
+	var clientUsageStore metrics.ClientUsageStore
+	if metricsStore != nil {
+		clientUsageStore = metricsStore
+	}
+	quotaManager := quota.NewManager(clientUsageStore)
+
+	// 初始化请求审计日志（可选）
+	var requestLogStore *requestlog.SQLiteStore
+	if envCfg.RequestLogEnabled {
+		var err error
+		requestLogStore, err = requestlog.NewSQLiteStore(&requestlog.SQLiteStoreConfig{
+			DBPath:        ".config/requests.db",
+			RetentionDays: envCfg.RequestLogRetentionDays,
+		})
+		if err != nil {
+			log.Printf("[RequestLog-Init] 警告: 初始化请求日志存储失败: %v，请求日志已禁用", err)
+			requestLogStore = nil
+		}
+	}
+	var requestRecorder *requestlog.Recorder
+	if requestLogStore != nil {
+		requestRecorder = requestlog.NewRecorder(requestLogStore, requestlog.Options{
+			CaptureBodies: envCfg.RequestLogCaptureBodies,
+			MaxBodyBytes:  envCfg.RequestLogMaxBodySize,
+		})
+	}
+
+	// 初始化管理操作审计日志（默认启用）
+	var auditStore *audit.SQLiteStore
+	if envCfg.AuditLogEnabled {
+		var err error
+		auditStore, err = audit.NewSQLiteStore(".config/audit.db")
+		if err != nil {
+			log.Printf("[Audit-Init] 警告: 初始化审计日志存储失败: %v，审计日志已禁用", err)
+			auditStore = nil
+		}
+	}
+	var auditRecorder *audit.Recorder
+	if auditStore != nil {
+		auditRecorder = audit.NewRecorder(auditStore, cfgManager)
+	}
+
+	channelScheduler := scheduler.NewChannelScheduler(cfgManager, messagesMetricsManager, responsesMetricsManager, geminiMetricsManager, chatMetricsManager, traceAffinityManager, urlManager)
 	log.Printf("[Scheduler-Init] 多渠道调度器已初始化 (失败率阈值: %.0f%%, 滑动窗口: %d)",
 		messagesMetricsManager.GetFailureThreshold()*100, messagesMetricsManager.GetWindowSize())
 
+	// 告警通知（是否启用由 config.json 的 alerts 控制，支持热更新）
+	alertManager := alert.NewManager(cfgManager)
+	alertManager.Start()
+	alert.SetDefault(alertManager)
+	messagesMetricsManager.SetCircuitHook(alertManager.CircuitHook("messages"))
+	responsesMetricsManager.SetCircuitHook(alertManager.CircuitHook("responses"))
+	geminiMetricsManager.SetCircuitHook(alertManager.CircuitHook("gemini"))
+	chatMetricsManager.SetCircuitHook(alertManager.CircuitHook("chat"))
+
+	// 后台健康探测（是否启用由 config.json 的 healthProbe 控制，支持热更新）
+	healthProber := healthprobe.NewProber(cfgManager, channelScheduler)
+	healthProber.Start()
+
+	// 中转渠道余额查询（按渠道 balanceCheck 配置，支持热更新）
+	balanceChecker := balance.NewChecker(cfgManager, channelScheduler)
+	balanceChecker.Start()
+
 	// 设置 Gin 模式
 	if envCfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
@@ -128,8 +217,13 @@ func main() {
 	// 健康检查端点（固定路径 /health，与 Dockerfile HEALTHCHECK 保持一致）
 	r.GET("/health", handlers.HealthCheck(envCfg, cfgManager))
 
+	// Prometheus 指标端点（独立认证）
+	if envCfg.PrometheusEnabled {
+		r.GET("/metrics", middleware.MetricsAuthMiddleware(envCfg), handlers.PrometheusMetrics(cfgManager, channelScheduler, sessionManager))
+	}
+
 	// 配置保存端点
-	r.POST("/admin/config/save", handlers.SaveConfigHandler(cfgManager))
+	r.POST("/admin/config/save", auditRecorder.Middleware(), handlers.SaveConfigHandler(cfgManager))
 
 	// 开发信息端点
 	if envCfg.IsDevelopment() {
@@ -137,7 +231,8 @@ func main() {
 	}
 
 	// Web 管理界面 API 路由
-	apiGroup := r.Group("/api")
+	// 管理写操作经审计中间件记录
+	apiGroup := r.Group("/api", auditRecorder.Middleware())
 	{
 		// Messages 渠道管理
 		apiGroup.GET("/messages/channels", messages.GetUpstreams(cfgManager))
@@ -148,17 +243,21 @@ func main() {
 		apiGroup.DELETE("/messages/channels/:id/keys/:apiKey", messages.DeleteApiKey(cfgManager))
 		apiGroup.POST("/messages/channels/:id/keys/:apiKey/top", messages.MoveApiKeyToTop(cfgManager))
 		apiGroup.POST("/messages/channels/:id/keys/:apiKey/bottom", messages.MoveApiKeyToBottom(cfgManager))
+		apiGroup.POST("/messages/channels/:id/disabled-keys/:apiKey/enable", handlers.EnableDisabledKey(cfgManager, channelScheduler, scheduler.ChannelKindMessages))
+		apiGroup.DELETE("/messages/channels/:id/disabled-keys/:apiKey", handlers.DiscardDisabledKey(cfgManager, scheduler.ChannelKindMessages))
 
 		// Messages 多渠道调度 API
 		apiGroup.POST("/messages/channels/reorder", messages.ReorderChannels(cfgManager))
 		apiGroup.PATCH("/messages/channels/:id/status", messages.SetChannelStatus(cfgManager))
-		apiGroup.POST("/messages/channels/:id/resume", handlers.ResumeChannel(channelScheduler, false))
+		apiGroup.POST("/messages/channels/:id/resume", handlers.ResumeChannel(cfgManager, channelScheduler, false))
 		apiGroup.POST("/messages/channels/:id/promotion", messages.SetChannelPromotion(cfgManager))
+		apiGroup.POST("/messages/channels/:id/models/refresh", handlers.RefreshChannelModels(cfgManager, scheduler.ChannelKindMessages))
+		apiGroup.PUT("/messages/loadbalance", messages.UpdateLoadBalance(cfgManager))
 		apiGroup.GET("/messages/channels/metrics", handlers.GetChannelMetricsWithConfig(messagesMetricsManager, cfgManager, false))
 		apiGroup.GET("/messages/channels/metrics/history", handlers.GetChannelMetricsHistory(messagesMetricsManager, cfgManager, false))
 		apiGroup.GET("/messages/channels/:id/keys/metrics/history", handlers.GetChannelKeyMetricsHistory(messagesMetricsManager, cfgManager, false))
 		apiGroup.GET("/messages/channels/scheduler/stats", handlers.GetSchedulerStats(channelScheduler))
-		apiGroup.GET("/messages/global/stats/history", handlers.GetGlobalStatsHistory(messagesMetricsManager))
+		apiGroup.GET("/messages/global/stats/history", handlers.GetGlobalStatsHistory(messagesMetricsManager, cfgManager, scheduler.ChannelKindMessages))
 		apiGroup.GET("/messages/channels/dashboard", handlers.GetChannelDashboard(cfgManager, channelScheduler))
 		apiGroup.GET("/messages/ping/:id", messages.PingChannel(cfgManager))
 		apiGroup.GET("/messages/ping", messages.PingAllChannels(cfgManager))
@@ -172,16 +271,20 @@ func main() {
 		apiGroup.DELETE("/responses/channels/:id/keys/:apiKey", responses.DeleteApiKey(cfgManager))
 		apiGroup.POST("/responses/channels/:id/keys/:apiKey/top", responses.MoveApiKeyToTop(cfgManager))
 		apiGroup.POST("/responses/channels/:id/keys/:apiKey/bottom", responses.MoveApiKeyToBottom(cfgManager))
+		apiGroup.POST("/responses/channels/:id/disabled-keys/:apiKey/enable", handlers.EnableDisabledKey(cfgManager, channelScheduler, scheduler.ChannelKindResponses))
+		apiGroup.DELETE("/responses/channels/:id/disabled-keys/:apiKey", handlers.DiscardDisabledKey(cfgManager, scheduler.ChannelKindResponses))
 
 		// Responses 多渠道调度 API
 		apiGroup.POST("/responses/channels/reorder", responses.ReorderChannels(cfgManager))
 		apiGroup.PATCH("/responses/channels/:id/status", responses.SetChannelStatus(cfgManager))
-		apiGroup.POST("/responses/channels/:id/resume", handlers.ResumeChannel(channelScheduler, true))
+		apiGroup.POST("/responses/channels/:id/resume", handlers.ResumeChannel(cfgManager, channelScheduler, true))
 		apiGroup.POST("/responses/channels/:id/promotion", handlers.SetResponsesChannelPromotion(cfgManager))
+		apiGroup.POST("/responses/channels/:id/models/refresh", handlers.RefreshChannelModels(cfgManager, scheduler.ChannelKindResponses))
+		apiGroup.PUT("/responses/loadbalance", responses.UpdateLoadBalance(cfgManager))
 		apiGroup.GET("/responses/channels/metrics", handlers.GetChannelMetricsWithConfig(responsesMetricsManager, cfgManager, true))
 		apiGroup.GET("/responses/channels/metrics/history", handlers.GetChannelMetricsHistory(responsesMetricsManager, cfgManager, true))
 		apiGroup.GET("/responses/channels/:id/keys/metrics/history", handlers.GetChannelKeyMetricsHistory(responsesMetricsManager, cfgManager, true))
-		apiGroup.GET("/responses/global/stats/history", handlers.GetGlobalStatsHistory(responsesMetricsManager))
+		apiGroup.GET("/responses/global/stats/history", handlers.GetGlobalStatsHistory(responsesMetricsManager, cfgManager, scheduler.ChannelKindResponses))
 
 		// Gemini 渠道管理
 		apiGroup.GET("/gemini/channels", gemini.GetUpstreams(cfgManager))
@@ -192,27 +295,99 @@ func main() {
 		apiGroup.DELETE("/gemini/channels/:id/keys/:apiKey", gemini.DeleteApiKey(cfgManager))
 		apiGroup.POST("/gemini/channels/:id/keys/:apiKey/top", gemini.MoveApiKeyToTop(cfgManager))
 		apiGroup.POST("/gemini/channels/:id/keys/:apiKey/bottom", gemini.MoveApiKeyToBottom(cfgManager))
+		apiGroup.POST("/gemini/channels/:id/disabled-keys/:apiKey/enable", handlers.EnableDisabledKey(cfgManager, channelScheduler, scheduler.ChannelKindGemini))
+		apiGroup.DELETE("/gemini/channels/:id/disabled-keys/:apiKey", handlers.DiscardDisabledKey(cfgManager, scheduler.ChannelKindGemini))
 
 		// Gemini 多渠道调度 API
 		apiGroup.POST("/gemini/channels/reorder", gemini.ReorderChannels(cfgManager))
 		apiGroup.PATCH("/gemini/channels/:id/status", gemini.SetChannelStatus(cfgManager))
 		apiGroup.POST("/gemini/channels/:id/promotion", gemini.SetChannelPromotion(cfgManager))
+		apiGroup.POST("/gemini/channels/:id/models/refresh", handlers.RefreshChannelModels(cfgManager, scheduler.ChannelKindGemini))
 		apiGroup.PUT("/gemini/loadbalance", gemini.UpdateLoadBalance(cfgManager))
 		apiGroup.GET("/gemini/channels/dashboard", gemini.GetDashboard(cfgManager, channelScheduler))
 		apiGroup.GET("/gemini/channels/metrics", handlers.GetGeminiChannelMetrics(geminiMetricsManager, cfgManager))
 		apiGroup.GET("/gemini/channels/metrics/history", handlers.GetGeminiChannelMetricsHistory(geminiMetricsManager, cfgManager))
 		apiGroup.GET("/gemini/channels/:id/keys/metrics/history", handlers.GetGeminiChannelKeyMetricsHistory(geminiMetricsManager, cfgManager))
-		apiGroup.GET("/gemini/global/stats/history", handlers.GetGlobalStatsHistory(geminiMetricsManager))
+		apiGroup.GET("/gemini/global/stats/history", handlers.GetGlobalStatsHistory(geminiMetricsManager, cfgManager, scheduler.ChannelKindGemini))
 		apiGroup.GET("/gemini/ping/:id", gemini.PingChannel(cfgManager))
 		apiGroup.GET("/gemini/ping", gemini.PingAllChannels(cfgManager))
 
+		// Chat Completions 渠道管理
+		apiGroup.GET("/chat/channels", chat.GetUpstreams(cfgManager))
+		apiGroup.POST("/chat/channels", chat.AddUpstream(cfgManager))
+		apiGroup.PUT("/chat/channels/:id", chat.UpdateUpstream(cfgManager, channelScheduler))
+		apiGroup.DELETE("/chat/channels/:id", chat.DeleteUpstream(cfgManager, channelScheduler))
+		apiGroup.POST("/chat/channels/:id/keys", chat.AddApiKey(cfgManager))
+		apiGroup.DELETE("/chat/channels/:id/keys/:apiKey", chat.DeleteApiKey(cfgManager))
+		apiGroup.POST("/chat/channels/:id/keys/:apiKey/top", chat.MoveApiKeyToTop(cfgManager))
+		apiGroup.POST("/chat/channels/:id/keys/:apiKey/bottom", chat.MoveApiKeyToBottom(cfgManager))
+		apiGroup.POST("/chat/channels/:id/disabled-keys/:apiKey/enable", handlers.EnableDisabledKey(cfgManager, channelScheduler, scheduler.ChannelKindChat))
+		apiGroup.DELETE("/chat/channels/:id/disabled-keys/:apiKey", handlers.DiscardDisabledKey(cfgManager, scheduler.ChannelKindChat))
+
+		// Chat Completions 多渠道调度 API
+		apiGroup.POST("/chat/channels/reorder", chat.ReorderChannels(cfgManager))
+		apiGroup.PATCH("/chat/channels/:id/status", chat.SetChannelStatus(cfgManager))
+		apiGroup.POST("/chat/channels/:id/promotion", chat.SetChannelPromotion(cfgManager))
+		apiGroup.POST("/chat/channels/:id/models/refresh", handlers.RefreshChannelModels(cfgManager, scheduler.ChannelKindChat))
+		apiGroup.PUT("/chat/loadbalance", chat.UpdateLoadBalance(cfgManager))
+		apiGroup.GET("/chat/channels/dashboard", chat.GetDashboard(cfgManager, channelScheduler))
+		apiGroup.GET("/chat/channels/metrics", handlers.GetChatChannelMetrics(chatMetricsManager, cfgManager))
+		apiGroup.GET("/chat/channels/metrics/history", handlers.GetChatChannelMetricsHistory(chatMetricsManager, cfgManager))
+		apiGroup.GET("/chat/channels/:id/keys/metrics/history", handlers.GetChatChannelKeyMetricsHistory(chatMetricsManager, cfgManager))
+		apiGroup.GET("/chat/global/stats/history", handlers.GetGlobalStatsHistory(chatMetricsManager, cfgManager, scheduler.ChannelKindChat))
+
+		// 多租户客户端密钥管理
+		apiGroup.GET("/clients", handlers.GetClients(cfgManager, channelScheduler, quotaManager))
+		apiGroup.POST("/clients", handlers.AddClient(cfgManager))
+		apiGroup.PUT("/clients/:id", handlers.UpdateClient(cfgManager))
+		apiGroup.DELETE("/clients/:id", handlers.DeleteClient(cfgManager))
+		apiGroup.POST("/clients/:id/rotate", handlers.RotateClientSecret(cfgManager))
+
+		// 请求审计日志
+		apiGroup.GET("/requests", handlers.ListRequestLogs(requestRecorder))
+		apiGroup.GET("/requests/:id", handlers.GetRequestLog(requestRecorder))
+		apiGroup.DELETE("/requests", handlers.PurgeRequestLogs(requestRecorder))
+
 		// Fuzzy 模式设置
 		apiGroup.GET("/settings/fuzzy-mode", handlers.GetFuzzyMode(cfgManager))
 		apiGroup.PUT("/settings/fuzzy-mode", handlers.SetFuzzyMode(cfgManager))
+
+		// 模型价格表（费用统计）
+		apiGroup.GET("/settings/model-prices", handlers.GetModelPrices(cfgManager))
+		apiGroup.PUT("/settings/model-prices", handlers.SetModelPrices(cfgManager))
+
+		// 后台健康探测
+		apiGroup.GET("/settings/health-probe", handlers.GetHealthProbeConfig(cfgManager))
+		apiGroup.PUT("/settings/health-probe", handlers.SetHealthProbeConfig(cfgManager))
+		apiGroup.GET("/health-probe/history", handlers.GetHealthProbeHistory(cfgManager, healthProber))
+		apiGroup.POST("/health-probe/run", handlers.RunHealthProbe(healthProber))
+
+		// 中转渠道余额
+		apiGroup.POST("/balance/refresh", handlers.RefreshBalances(balanceChecker))
+
+		// 被自动禁用的 Key（永久性错误）
+		apiGroup.GET("/disabled-keys", handlers.ListDisabledKeys(cfgManager))
+
+		// 告警通知
+		apiGroup.GET("/settings/alerts", handlers.GetAlertConfig(cfgManager))
+		apiGroup.PUT("/settings/alerts", handlers.SetAlertConfig(cfgManager))
+		apiGroup.POST("/settings/alerts/test", handlers.TestAlertTarget(cfgManager, alertManager))
+
+		// 配置备份历史
+		apiGroup.GET("/config/backups", handlers.ListConfigBackups(cfgManager))
+		apiGroup.GET("/config/backups/:name/diff", handlers.DiffConfigBackup(cfgManager))
+		apiGroup.POST("/config/backups/:name/restore", handlers.RestoreConfigBackup(cfgManager, channelScheduler))
+
+		// 声明式配置导入/导出
+		apiGroup.GET("/config/export", handlers.ExportConfig(cfgManager))
+		apiGroup.POST("/config/import", handlers.ImportConfig(cfgManager, channelScheduler))
+
+		// 管理操作审计日志
+		apiGroup.GET("/audit", handlers.ListAuditLogs(auditRecorder))
 	}
 
 	// 代理端点 - Messages API
-	r.POST("/v1/messages", messages.Handler(envCfg, cfgManager, channelScheduler))
+	r.POST("/v1/messages", requestRecorder.Middleware("messages"), messages.Handler(envCfg, cfgManager, channelScheduler, quotaManager))
 	r.POST("/v1/messages/count_tokens", messages.CountTokensHandler(envCfg, cfgManager, channelScheduler))
 
 	// 代理端点 - Models API（转发到上游）
@@ -220,13 +395,21 @@ func main() {
 	r.GET("/v1/models/:model", messages.ModelsDetailHandler(envCfg, cfgManager, channelScheduler))
 
 	// 代理端点 - Responses API
-	r.POST("/v1/responses", responses.Handler(envCfg, cfgManager, sessionManager, channelScheduler))
-	r.POST("/v1/responses/compact", responses.CompactHandler(envCfg, cfgManager, sessionManager, channelScheduler))
+	r.POST("/v1/responses", requestRecorder.Middleware("responses"), responses.Handler(envCfg, cfgManager, sessionManager, channelScheduler, quotaManager))
+	r.POST("/v1/responses/compact", requestRecorder.Middleware("responses"), responses.CompactHandler(envCfg, cfgManager, sessionManager, channelScheduler, quotaManager))
+	r.GET("/v1/responses/:id", responses.RetrieveHandler(envCfg, cfgManager, sessionManager))
+	r.DELETE("/v1/responses/:id", responses.DeleteHandler(envCfg, cfgManager, sessionManager))
+	r.GET("/v1/responses/:id/input_items", responses.InputItemsHandler(envCfg, cfgManager, sessionManager))
 
 	// 代理端点 - Gemini API (原生协议)
 	// 使用通配符捕获 model:action 格式，如 gemini-pro:generateContent
 	// 路径格式：/v1beta/models/{model}:generateContent (Gemini 原生格式)
-	r.POST("/v1beta/models/*modelAction", gemini.Handler(envCfg, cfgManager, channelScheduler))
+	r.POST("/v1beta/models/*modelAction", requestRecorder.Middleware("gemini"), gemini.Handler(envCfg, cfgManager, channelScheduler, quotaManager))
+	r.GET("/v1beta/models", gemini.ModelsHandler(envCfg, cfgManager, channelScheduler))
+	r.GET("/v1beta/models/*modelAction", gemini.ModelDetailHandler(envCfg, cfgManager, channelScheduler))
+
+	// 代理端点 - Chat Completions API (OpenAI 兼容入口)
+	r.POST("/v1/chat/completions", requestRecorder.Middleware("chat"), chat.Handler(envCfg, cfgManager, channelScheduler, quotaManager))
 
 	// 静态文件服务 (嵌入的前端)
 	if envCfg.EnableWebUI {
@@ -262,9 +445,16 @@ func main() {
 	fmt.Printf("[Server-Info] API 地址: http://localhost:%d/v1\n", envCfg.Port)
 	fmt.Printf("[Server-Info] Claude Messages: POST /v1/messages\n")
 	fmt.Printf("[Server-Info] Codex Responses: POST /v1/responses\n")
+	fmt.Printf("[Server-Info] Responses 检索: GET/DELETE /v1/responses/:id, GET /v1/responses/:id/input_items\n")
 	fmt.Printf("[Server-Info] Gemini API: POST /v1beta/models/{model}:generateContent\n")
 	fmt.Printf("[Server-Info] Gemini API: POST /v1beta/models/{model}:streamGenerateContent\n")
+	fmt.Printf("[Server-Info] Gemini API: POST /v1beta/models/{model}:countTokens|embedContent|batchEmbedContents\n")
+	fmt.Printf("[Server-Info] Gemini API: GET  /v1beta/models\n")
+	fmt.Printf("[Server-Info] OpenAI Chat: POST /v1/chat/completions\n")
 	fmt.Printf("[Server-Info] 健康检查: GET /health\n")
+	if envCfg.PrometheusEnabled {
+		fmt.Printf("[Server-Info] Prometheus 指标: GET /metrics\n")
+	}
 	fmt.Printf("[Server-Info] 环境: %s\n", envCfg.Env)
 	// 检查是否使用默认密码，给予提示
 	if envCfg.ProxyAccessKey == "your-proxy-access-key" {
@@ -302,6 +492,33 @@ func main() {
 			log.Println("[Server-Shutdown] 服务器已安全关闭")
 		}
 
+		// 写入剩余的客户端用量（需在关闭指标存储之前）
+		quotaManager.Stop()
+
+		// 停止后台健康探测与余额查询（需在关闭指标存储之前）
+		healthProber.Stop()
+		balanceChecker.Stop()
+
+		// 停止告警投递
+		alertManager.Stop()
+
+		// 停止会话清理（SQLite 会话存储与指标存储共用连接，需在关闭指标存储之前）
+		sessionManager.Stop()
+
+		// 关闭请求日志存储（写入剩余日志）
+		if requestLogStore != nil {
+			if err := requestLogStore.Close(); err != nil {
+				log.Printf("[RequestLog-Shutdown] 警告: 关闭请求日志存储时发生错误: %v", err)
+			}
+		}
+
+		// 关闭审计日志存储
+		if auditStore != nil {
+			if err := auditStore.Close(); err != nil {
+				log.Printf("[Audit-Shutdown] 警告: 关闭审计日志存储时发生错误: %v", err)
+			}
+		}
+
 		// 关闭指标持久化存储
 		if metricsStore != nil {
 			if err := metricsStore.Close(); err != nil {